@@ -3,19 +3,26 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	diskDevice string
-	diskAlgo   string
-	diskInit   bool
-	diskMount  string
-	diskLabel  string
+	diskDevice  string
+	diskAlgo    string
+	diskInit    bool
+	diskMount   string
+	diskLabel   string
+	diskDryRun  bool
+	diskYes     bool
+	diskForce   bool
+	diskStat    bool
+	diskUnmount bool
+	diskEnroll  bool
+	diskToken   string
 )
 
 // diskCmd represents the disk command
@@ -28,12 +35,17 @@ Supports:
 • Linux: LUKS with cryptsetup
 • macOS: APFS encryption with diskutil
 • Windows: BitLocker with PowerShell
-• Hardware key integration for unlock
+• Hardware key integration for unlock (FIDO2 and TPM2)
 
 Examples:
   crypto-kit disk --init --device /dev/sdb --algo XTS-AES-256 --label SecureData
-  crypto-kit disk --mount /dev/sdb --label SecureData  
-  crypto-kit disk --init --device /dev/disk2 --algo AES-XTS (macOS)`,
+  crypto-kit disk --device /dev/sdb --mount /mnt/secure --label SecureData
+  crypto-kit disk --device /dev/sdb --unmount --mount /mnt/secure --label SecureData
+  crypto-kit disk --init --device /dev/disk2 --algo AES-XTS (macOS)
+  crypto-kit disk --init --device /dev/sdb --dry-run
+  crypto-kit disk --init --device /dev/sdb --yes
+  crypto-kit disk --status --device /dev/sdb
+  crypto-kit disk --enroll-hwkey --device /dev/sdb --token fido2`,
 	RunE: runDisk,
 }
 
@@ -45,6 +57,13 @@ func init() {
 	diskCmd.Flags().BoolVar(&diskInit, "init", false, "initialize disk encryption")
 	diskCmd.Flags().StringVar(&diskMount, "mount", "", "mount point for encrypted disk")
 	diskCmd.Flags().StringVar(&diskLabel, "label", "CryptoKit", "volume label")
+	diskCmd.Flags().BoolVar(&diskDryRun, "dry-run", false, "print the commands that would run without executing them")
+	diskCmd.Flags().BoolVar(&diskYes, "yes", false, "skip interactive confirmation prompts, including cryptsetup's")
+	diskCmd.Flags().BoolVar(&diskForce, "force", false, "reformat a device that is already encrypted")
+	diskCmd.Flags().BoolVar(&diskStat, "status", false, "report whether the device is encrypted and its cipher")
+	diskCmd.Flags().BoolVar(&diskUnmount, "unmount", false, "unmount the encrypted disk and lock it")
+	diskCmd.Flags().BoolVar(&diskEnroll, "enroll-hwkey", false, "enroll a hardware key to unlock the encrypted disk")
+	diskCmd.Flags().StringVar(&diskToken, "token", tokenFIDO2, "hardware key type for --enroll-hwkey (fido2, tpm2)")
 }
 
 func runDisk(cmd *cobra.Command, args []string) error {
@@ -60,10 +79,26 @@ func runDisk(cmd *cobra.Command, args []string) error {
 
 	// Check if device exists (basic validation)
 	if _, err := os.Stat(diskDevice); os.IsNotExist(err) {
-		return fmt.Errorf("device does not exist: %s", diskDevice)
+		if !diskDryRun {
+			return fmt.Errorf("device does not exist: %s", diskDevice)
+		}
+		fmt.Printf("⚠️  Device does not exist: %s\n", diskDevice)
+	}
+
+	if diskStat {
+		return printDiskStatus(runtime.GOOS)
 	}
 
-	switch runtime.GOOS {
+	if diskEnroll {
+		return enrollHardwareKey(runtime.GOOS)
+	}
+
+	return handleDisk(runtime.GOOS)
+}
+
+// handleDisk runs the disk operation for the given operating system
+func handleDisk(goos string) error {
+	switch goos {
 	case "linux":
 		return handleLinuxDisk()
 	case "darwin":
@@ -71,153 +106,355 @@ func runDisk(cmd *cobra.Command, args []string) error {
 	case "windows":
 		return handleWindowsDisk()
 	default:
-		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+		return fmt.Errorf("unsupported operating system: %s", goos)
 	}
 }
 
-func handleLinuxDisk() error {
-	logVerbose("Using Linux cryptsetup for disk encryption")
+// diskCommand is an external command run as one step of a disk operation
+type diskCommand struct {
+	Step string // Describes the step for progress and error messages
+	Name string
+	Args []string
+	Undo *diskCommand // Reverts the step if a later step fails
+}
 
-	if diskInit {
-		fmt.Printf("🔒 Initializing LUKS encryption on %s...\n", diskDevice)
-		
-		// Check if cryptsetup is available
-		if _, err := exec.LookPath("cryptsetup"); err != nil {
-			return fmt.Errorf("cryptsetup not found. Install with: sudo apt install cryptsetup")
+// String returns the command line, quoting arguments that contain spaces
+func (c diskCommand) String() string {
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = strconv.Quote(arg)
 		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
 
-		// Warn user about data destruction
-		fmt.Printf("⚠️  WARNING: This will DESTROY all data on %s!\n", diskDevice)
-		fmt.Printf("Press CTRL+C to abort or ENTER to continue...")
-		fmt.Scanln()
-
-		// Create LUKS container
-		cmd := exec.Command("sudo", "cryptsetup", "luksFormat", 
-			"--type", "luks2",
-			"--cipher", strings.ToLower(diskAlgo),
-			"--hash", "sha256",
-			"--key-size", "256",
-			diskDevice)
-		
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to create LUKS container: %w", err)
-		}
+// linuxInitPlan returns the commands that initialize a LUKS volume, mounting
+// it at mountPoint when one is given. With batch set, luksFormat does not ask
+// for its own confirmation; it still asks for the passphrase twice, so a
+// mistyped passphrase cannot leave the volume unopenable.
+func linuxInitPlan(device, algo, label, mountPoint string, batch bool) []diskCommand {
+	containerName := label + "_encrypted"
+	format := []string{"cryptsetup", "luksFormat"}
+	if batch {
+		format = append(format, "--batch-mode", "--verify-passphrase")
+	}
+	format = append(format,
+		"--type", "luks2",
+		"--cipher", strings.ToLower(algo),
+		"--hash", "sha256",
+		"--key-size", "256",
+		device)
+
+	plan := []diskCommand{
+		{Step: "create LUKS container", Name: "sudo", Args: format},
+		linuxOpenCommand(device, containerName),
+		{Step: "create filesystem", Name: "sudo", Args: []string{"mkfs.ext4", "-L", label, "/dev/mapper/" + containerName}},
+	}
+	if mountPoint != "" {
+		plan = append(plan, linuxMountCommands(containerName, mountPoint)...)
+	}
+	return plan
+}
 
-		fmt.Printf("✅ LUKS container created successfully\n")
+// linuxMountPlan returns the commands that open a LUKS volume and mount it
+func linuxMountPlan(device, label, mountPoint string) []diskCommand {
+	containerName := label + "_encrypted"
+	return append([]diskCommand{linuxOpenCommand(device, containerName)}, linuxMountCommands(containerName, mountPoint)...)
+}
 
-		// Open the container
-		containerName := diskLabel + "_encrypted"
-		openCmd := exec.Command("sudo", "cryptsetup", "open", diskDevice, containerName)
-		openCmd.Stdin = os.Stdin
-		openCmd.Stdout = os.Stdout
-		openCmd.Stderr = os.Stderr
+// linuxUnmountPlan returns the commands that unmount a LUKS volume and close
+// its mapper device
+func linuxUnmountPlan(label, mountPoint string) []diskCommand {
+	containerName := label + "_encrypted"
+	target := mountPoint
+	if target == "" {
+		target = "/dev/mapper/" + containerName
+	}
+	return []diskCommand{
+		{Step: "unmount filesystem", Name: "sudo", Args: []string{"umount", target}},
+		linuxCloseCommand(containerName),
+	}
+}
 
-		if err := openCmd.Run(); err != nil {
-			return fmt.Errorf("failed to open LUKS container: %w", err)
+// linuxOpenCommand opens the LUKS container, closing it again on rollback
+func linuxOpenCommand(device, containerName string) diskCommand {
+	closeCommand := linuxCloseCommand(containerName)
+	return diskCommand{
+		Step: "open LUKS container",
+		Name: "sudo",
+		Args: []string{"cryptsetup", "open", device, containerName},
+		Undo: &closeCommand,
+	}
+}
+
+func linuxCloseCommand(containerName string) diskCommand {
+	return diskCommand{Step: "close LUKS container", Name: "sudo", Args: []string{"cryptsetup", "close", containerName}}
+}
+
+// linuxMountCommands mounts an open LUKS container at mountPoint
+func linuxMountCommands(containerName, mountPoint string) []diskCommand {
+	return []diskCommand{
+		{Step: "create mount point", Name: "sudo", Args: []string{"mkdir", "-p", mountPoint}},
+		{Step: "mount filesystem", Name: "sudo", Args: []string{"mount", "/dev/mapper/" + containerName, mountPoint}},
+	}
+}
+
+// macOSInitPlan returns the commands that create an encrypted APFS container
+func macOSInitPlan(device string) []diskCommand {
+	return []diskCommand{
+		{Step: "create encrypted APFS container", Name: "diskutil", Args: []string{"apfs", "createContainer", "-passphrase", "-", device}},
+	}
+}
+
+// macOSMountPlan returns the commands that unlock and mount an APFS volume
+func macOSMountPlan(device, mountPoint string) []diskCommand {
+	args := []string{"apfs", "unlockVolume", device}
+	if mountPoint != "" {
+		args = append(args, "-mountpoint", mountPoint)
+	}
+	return []diskCommand{{Step: "unlock APFS volume", Name: "diskutil", Args: args}}
+}
+
+// macOSUnmountPlan returns the commands that unmount and lock an APFS volume
+func macOSUnmountPlan(device string) []diskCommand {
+	return []diskCommand{{Step: "lock APFS volume", Name: "diskutil", Args: []string{"apfs", "lockVolume", device}}}
+}
+
+// windowsInitPlan returns the commands that enable BitLocker
+func windowsInitPlan(device string) []diskCommand {
+	psScript := fmt.Sprintf(`Enable-BitLocker -MountPoint "%s" -EncryptionMethod AES256 -UsedSpaceOnly`, device)
+	return []diskCommand{
+		{Step: "enable BitLocker", Name: "powershell", Args: []string{"-Command", psScript}},
+	}
+}
+
+// windowsMountPlan returns the commands that unlock a BitLocker volume
+func windowsMountPlan(device string) []diskCommand {
+	return []diskCommand{{Step: "unlock BitLocker volume", Name: "manage-bde", Args: []string{"-unlock", device, "-password"}}}
+}
+
+// windowsUnmountPlan returns the commands that lock a BitLocker volume
+func windowsUnmountPlan(device string) []diskCommand {
+	return []diskCommand{{Step: "lock BitLocker volume", Name: "manage-bde", Args: []string{"-lock", device}}}
+}
+
+// printPlan prints the commands a dry run would execute
+func printPlan(plan []diskCommand) {
+	fmt.Printf("📝 Dry run - the following commands would be executed:\n")
+	for i, command := range plan {
+		fmt.Printf("  %d. %s\n", i+1, command)
+	}
+}
+
+// runPlan executes each command of the plan in order, stopping at the first
+// failure and reverting the steps that already completed
+func runPlan(plan []diskCommand) error {
+	for i, command := range plan {
+		logVerbose("Running: %s", command)
+
+		if err := diskRunner.Run(command.Name, command.Args...); err != nil {
+			rollbackPlan(plan[:i])
+			return fmt.Errorf("failed to %s: %w", command.Step, err)
 		}
+	}
+	return nil
+}
 
-		// Create filesystem
-		mkfsCmd := exec.Command("sudo", "mkfs.ext4", "-L", diskLabel, "/dev/mapper/"+containerName)
-		if err := mkfsCmd.Run(); err != nil {
-			return fmt.Errorf("failed to create filesystem: %w", err)
+// rollbackPlan runs the undo commands of completed steps in reverse order so
+// a failed operation does not leave devices open
+func rollbackPlan(completed []diskCommand) {
+	for i := len(completed) - 1; i >= 0; i-- {
+		undo := completed[i].Undo
+		if undo == nil {
+			continue
 		}
 
-		fmt.Printf("✅ Encrypted disk initialized: /dev/mapper/%s\n", containerName)
-		
-		// Provide usage instructions
-		printLinuxInstructions(diskDevice, containerName)
+		fmt.Printf("↩️  Cleaning up: %s\n", undo.Step)
+		if err := diskRunner.Run(undo.Name, undo.Args...); err != nil {
+			fmt.Printf("⚠️  Failed to %s: %v\n", undo.Step, err)
+		}
+	}
+}
+
+// runMountPlan mounts or unmounts the device according to the flags
+func runMountPlan(plan []diskCommand, tool string) error {
+	if diskDryRun {
+		printPlan(plan)
+		return nil
+	}
+
+	if _, err := diskRunner.LookPath(tool); err != nil {
+		return fmt.Errorf("%s not found", tool)
+	}
+
+	if err := runPlan(plan); err != nil {
+		return err
+	}
 
+	if diskUnmount {
+		fmt.Printf("✅ Encrypted disk unmounted and locked: %s\n", diskDevice)
 	} else {
-		return fmt.Errorf("specify --init to initialize disk encryption")
+		fmt.Printf("✅ Encrypted disk unlocked: %s\n", diskDevice)
+	}
+	return nil
+}
+
+// confirmDestructive warns about the operation and waits for confirmation
+// unless --yes was given
+func confirmDestructive(warning string) {
+	fmt.Printf("⚠️  WARNING: %s\n", warning)
+	if diskYes {
+		return
+	}
+	fmt.Printf("Press CTRL+C to abort or ENTER to continue...")
+	fmt.Scanln()
+}
+
+func handleLinuxDisk() error {
+	logVerbose("Using Linux cryptsetup for disk encryption")
+
+	switch {
+	case diskUnmount:
+		fmt.Printf("🔒 Unmounting %s...\n", diskDevice)
+		return runMountPlan(linuxUnmountPlan(diskLabel, diskMount), "cryptsetup")
+	case !diskInit && diskMount != "":
+		fmt.Printf("🔓 Mounting %s at %s...\n", diskDevice, diskMount)
+		return runMountPlan(linuxMountPlan(diskDevice, diskLabel, diskMount), "cryptsetup")
+	case !diskInit:
+		return fmt.Errorf("specify --init, --mount or --unmount")
+	}
+
+	fmt.Printf("🔒 Initializing LUKS encryption on %s...\n", diskDevice)
+
+	plan := linuxInitPlan(diskDevice, diskAlgo, diskLabel, diskMount, diskYes)
+	if diskDryRun {
+		printPlan(plan)
+		return nil
+	}
+
+	// Check if cryptsetup is available
+	if _, err := diskRunner.LookPath("cryptsetup"); err != nil {
+		return fmt.Errorf("cryptsetup not found. Install with: sudo apt install cryptsetup")
+	}
+
+	// Refuse to destroy an existing LUKS container
+	if err := checkNotEncrypted("linux"); err != nil {
+		return err
+	}
+
+	// Warn user about data destruction
+	confirmDestructive(fmt.Sprintf("This will DESTROY all data on %s!", diskDevice))
+
+	if err := runPlan(plan); err != nil {
+		return err
+	}
+
+	containerName := diskLabel + "_encrypted"
+	fmt.Printf("✅ Encrypted disk initialized: /dev/mapper/%s\n", containerName)
+	if diskMount != "" {
+		fmt.Printf("📂 Mounted at %s\n", diskMount)
 	}
 
+	// Provide usage instructions
+	printLinuxInstructions(diskDevice, containerName)
+
 	return nil
 }
 
 func handleMacOSDisk() error {
 	logVerbose("Using macOS diskutil for disk encryption")
 
-	if diskInit {
-		fmt.Printf("🔒 Initializing APFS encryption on %s...\n", diskDevice)
-		
-		// Check if diskutil is available (should be on macOS)
-		if _, err := exec.LookPath("diskutil"); err != nil {
-			return fmt.Errorf("diskutil not found")
-		}
+	switch {
+	case diskUnmount:
+		fmt.Printf("🔒 Unmounting %s...\n", diskDevice)
+		return runMountPlan(macOSUnmountPlan(diskDevice), "diskutil")
+	case !diskInit && diskMount != "":
+		fmt.Printf("🔓 Mounting %s at %s...\n", diskDevice, diskMount)
+		return runMountPlan(macOSMountPlan(diskDevice, diskMount), "diskutil")
+	case !diskInit:
+		return fmt.Errorf("specify --init, --mount or --unmount")
+	}
 
-		// Warn user about data destruction
-		fmt.Printf("⚠️  WARNING: This will DESTROY all data on %s!\n", diskDevice)
-		fmt.Printf("Press CTRL+C to abort or ENTER to continue...")
-		fmt.Scanln()
-
-		// Erase and format as encrypted APFS
-		cmd := exec.Command("diskutil", "apfs", "createContainer",
-			"-passphrase", "-",
-			diskDevice)
-		
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to create encrypted APFS container: %w", err)
-		}
+	fmt.Printf("🔒 Initializing APFS encryption on %s...\n", diskDevice)
 
-		fmt.Printf("✅ Encrypted APFS container created successfully\n")
-		
-		// Provide usage instructions
-		printMacOSInstructions(diskDevice)
+	plan := macOSInitPlan(diskDevice)
+	if diskDryRun {
+		printPlan(plan)
+		return nil
+	}
 
-	} else {
-		return fmt.Errorf("specify --init to initialize disk encryption")
+	// Check if diskutil is available (should be on macOS)
+	if _, err := diskRunner.LookPath("diskutil"); err != nil {
+		return fmt.Errorf("diskutil not found")
+	}
+
+	// Refuse to destroy an existing encrypted volume
+	if err := checkNotEncrypted("darwin"); err != nil {
+		return err
+	}
+
+	// Warn user about data destruction
+	confirmDestructive(fmt.Sprintf("This will DESTROY all data on %s!", diskDevice))
+
+	if err := runPlan(plan); err != nil {
+		return err
 	}
 
+	fmt.Printf("✅ Encrypted APFS container created successfully\n")
+
+	// Provide usage instructions
+	printMacOSInstructions(diskDevice)
+
 	return nil
 }
 
 func handleWindowsDisk() error {
 	logVerbose("Using Windows BitLocker for disk encryption")
-	
-	if diskInit {
-		fmt.Printf("🔒 Initializing BitLocker encryption on %s...\n", diskDevice)
-		
-		// Check if PowerShell is available
-		if _, err := exec.LookPath("powershell"); err != nil {
-			return fmt.Errorf("PowerShell not found")
-		}
 
-		// Warn user about data destruction
-		fmt.Printf("⚠️  WARNING: This will encrypt %s with BitLocker!\n", diskDevice)
-		fmt.Printf("Press CTRL+C to abort or ENTER to continue...")
-		fmt.Scanln()
+	switch {
+	case diskUnmount:
+		fmt.Printf("🔒 Locking %s...\n", diskDevice)
+		return runMountPlan(windowsUnmountPlan(diskDevice), "manage-bde")
+	case !diskInit && diskMount != "":
+		// BitLocker volumes keep their drive letter when unlocked
+		fmt.Printf("🔓 Unlocking %s...\n", diskDevice)
+		return runMountPlan(windowsMountPlan(diskDevice), "manage-bde")
+	case !diskInit:
+		return fmt.Errorf("specify --init, --mount or --unmount")
+	}
 
-		// Enable BitLocker (this is a simplified example)
-		psScript := fmt.Sprintf(`
-			Enable-BitLocker -MountPoint "%s" -EncryptionMethod AES256 -UsedSpaceOnly
-		`, diskDevice)
+	fmt.Printf("🔒 Initializing BitLocker encryption on %s...\n", diskDevice)
 
-		cmd := exec.Command("powershell", "-Command", psScript)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	plan := windowsInitPlan(diskDevice)
+	if diskDryRun {
+		printPlan(plan)
+		return nil
+	}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to enable BitLocker: %w", err)
-		}
+	// Check if PowerShell is available
+	if _, err := diskRunner.LookPath("powershell"); err != nil {
+		return fmt.Errorf("PowerShell not found")
+	}
 
-		fmt.Printf("✅ BitLocker encryption initiated on %s\n", diskDevice)
-		
-		// Provide usage instructions
-		printWindowsInstructions(diskDevice)
+	// Refuse to re-encrypt a BitLocker volume
+	if err := checkNotEncrypted("windows"); err != nil {
+		return err
+	}
 
-	} else {
-		return fmt.Errorf("specify --init to initialize disk encryption")
+	// Warn user about data destruction
+	confirmDestructive(fmt.Sprintf("This will encrypt %s with BitLocker!", diskDevice))
+
+	if err := runPlan(plan); err != nil {
+		return err
 	}
 
+	fmt.Printf("✅ BitLocker encryption initiated on %s\n", diskDevice)
+
+	// Provide usage instructions
+	printWindowsInstructions(diskDevice)
+
 	return nil
 }
 
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hardware token types supported by --token
+const (
+	tokenFIDO2 = "fido2"
+	tokenTPM2  = "tpm2"
+)
+
+// hardwareTokenPresent reports whether a hardware token of the given type is
+// connected. A missing token is not an error.
+func hardwareTokenPresent(goos, tokenType string) (bool, error) {
+	switch goos {
+	case "linux":
+		output, err := diskRunner.Output("systemd-cryptenroll", "--"+tokenType+"-device=list")
+		if err != nil {
+			// systemd-cryptenroll exits non-zero when no device is found
+			if exitStatus(err) > 0 {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to list %s devices: %w", tokenType, err)
+		}
+
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "/dev/") {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "windows":
+		output, err := diskRunner.Output("powershell", "-Command", "(Get-Tpm).TpmPresent")
+		if err != nil {
+			return false, fmt.Errorf("failed to query TPM: %w", err)
+		}
+		return strings.EqualFold(strings.TrimSpace(string(output)), "True"), nil
+
+	default:
+		return false, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+// linuxEnrollPlan returns the commands that add a LUKS key slot unlocked by a
+// hardware token. systemd-cryptenroll asks for an existing passphrase, adds
+// the key slot and stores the token metadata in the LUKS2 header.
+func linuxEnrollPlan(device, tokenType string) []diskCommand {
+	args := []string{"systemd-cryptenroll", "--" + tokenType + "-device=auto"}
+	if tokenType == tokenTPM2 {
+		// Bind to the Secure Boot state
+		args = append(args, "--tpm2-pcrs=7")
+	}
+	args = append(args, device)
+
+	return []diskCommand{
+		{Step: "enroll " + tokenType + " key", Name: "sudo", Args: args},
+	}
+}
+
+// windowsEnrollPlan returns the commands that add a TPM protector to a
+// BitLocker volume
+func windowsEnrollPlan(device string) []diskCommand {
+	return []diskCommand{
+		{Step: "add TPM protector", Name: "manage-bde", Args: []string{"-protectors", "-add", device, "-tpm"}},
+	}
+}
+
+// enrollHardwareKey enrolls a hardware token so the encrypted device can be
+// unlocked without its passphrase
+func enrollHardwareKey(goos string) error {
+	if diskToken != tokenFIDO2 && diskToken != tokenTPM2 {
+		return fmt.Errorf("unsupported token type: %s (use %s or %s)", diskToken, tokenFIDO2, tokenTPM2)
+	}
+
+	var plan []diskCommand
+	var tool string
+	switch goos {
+	case "linux":
+		plan, tool = linuxEnrollPlan(diskDevice, diskToken), "systemd-cryptenroll"
+	case "windows":
+		if diskToken != tokenTPM2 {
+			return fmt.Errorf("BitLocker only supports %s hardware keys", tokenTPM2)
+		}
+		plan, tool = windowsEnrollPlan(diskDevice), "manage-bde"
+	case "darwin":
+		return fmt.Errorf("hardware key unlock is not supported for APFS volumes")
+	default:
+		return fmt.Errorf("unsupported operating system: %s", goos)
+	}
+
+	fmt.Printf("🔑 Enrolling %s hardware key for %s...\n", diskToken, diskDevice)
+
+	if diskDryRun {
+		printPlan(plan)
+		return nil
+	}
+
+	if _, err := diskRunner.LookPath(tool); err != nil {
+		return fmt.Errorf("%s not found", tool)
+	}
+
+	status, err := deviceEncryptionStatus(goos, diskDevice)
+	if err != nil {
+		return fmt.Errorf("failed to check existing encryption: %w", err)
+	}
+	if !status.Encrypted {
+		return fmt.Errorf("%s is not encrypted; run --init first", diskDevice)
+	}
+
+	present, err := hardwareTokenPresent(goos, diskToken)
+	if err != nil {
+		return err
+	}
+	if !present {
+		fmt.Printf("⚠️  No %s hardware key detected. Connect the key and try again.\n", diskToken)
+		return fmt.Errorf("no %s hardware key present", diskToken)
+	}
+
+	if err := runPlan(plan); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ %s hardware key enrolled for %s\n", diskToken, diskDevice)
+	if goos == "linux" {
+		fmt.Printf("Unlock: sudo cryptsetup open --token-only %s %s_encrypted\n", diskDevice, diskLabel)
+		fmt.Printf("Boot:   add '%s-device=auto' to the options of %s in /etc/crypttab\n", diskToken, diskDevice)
+	}
+
+	return nil
+}
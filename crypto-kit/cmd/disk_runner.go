@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// CommandRunner resolves and executes the external commands used by the
+// disk command, so they can be replaced in tests
+type CommandRunner interface {
+	// LookPath reports whether a command is installed
+	LookPath(file string) (string, error)
+	// Run executes a command attached to the terminal
+	Run(name string, args ...string) error
+	// Output executes a command and returns its standard output
+	Output(name string, args ...string) ([]byte, error)
+}
+
+// execRunner runs commands with os/exec
+type execRunner struct{}
+
+// LookPath searches for file in PATH
+func (execRunner) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+// Run executes the command with the terminal attached
+func (execRunner) Run(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Output executes the command and captures its standard output
+func (execRunner) Output(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// diskRunner executes the disk command's external commands
+var diskRunner CommandRunner = execRunner{}
+
+// exitStatus returns the exit code of a command that ran and failed, or -1
+// if the command could not be run at all
+func exitStatus(err error) int {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
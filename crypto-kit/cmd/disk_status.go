@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// diskStatus describes the encryption state of a device
+type diskStatus struct {
+	Encrypted bool
+	Scheme    string // LUKS, APFS or BitLocker
+	Cipher    string
+}
+
+var (
+	luksCipherPattern      = regexp.MustCompile(`(?m)^\s*cipher:\s*(\S+)`)
+	luks1CipherNamePattern = regexp.MustCompile(`(?m)^Cipher name:\s*(\S+)`)
+	luks1CipherModePattern = regexp.MustCompile(`(?m)^Cipher mode:\s*(\S+)`)
+	bitLockerMethodPattern = regexp.MustCompile(`(?m)^\s*Encryption Method:\s*(.+?)\s*$`)
+)
+
+// deviceEncryptionStatus inspects device to find whether it is already
+// encrypted on the given operating system
+func deviceEncryptionStatus(goos, device string) (*diskStatus, error) {
+	switch goos {
+	case "linux":
+		return linuxEncryptionStatus(device)
+	case "darwin":
+		return macOSEncryptionStatus(device)
+	case "windows":
+		return windowsEncryptionStatus(device)
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+}
+
+func linuxEncryptionStatus(device string) (*diskStatus, error) {
+	// isLuks exits 1 for devices without a LUKS header. Any other failure,
+	// such as a missing device or a denied sudo, says nothing about the
+	// device and must not be mistaken for an unencrypted one.
+	if err := diskRunner.Run("sudo", "cryptsetup", "isLuks", device); err != nil {
+		if exitStatus(err) == 1 {
+			return &diskStatus{}, nil
+		}
+		return nil, fmt.Errorf("failed to run cryptsetup isLuks: %w", err)
+	}
+
+	status := &diskStatus{Encrypted: true, Scheme: "LUKS"}
+
+	dump, err := diskRunner.Output("sudo", "cryptsetup", "luksDump", device)
+	if err != nil {
+		logVerbose("luksDump failed: %v", err)
+		return status, nil
+	}
+
+	if match := luksCipherPattern.FindSubmatch(dump); match != nil {
+		status.Cipher = string(match[1])
+	} else if name := luks1CipherNamePattern.FindSubmatch(dump); name != nil {
+		status.Cipher = string(name[1])
+		if mode := luks1CipherModePattern.FindSubmatch(dump); mode != nil {
+			status.Cipher += "-" + string(mode[1])
+		}
+	}
+
+	return status, nil
+}
+
+func macOSEncryptionStatus(device string) (*diskStatus, error) {
+	info, err := diskRunner.Output("diskutil", "info", device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run diskutil info: %w", err)
+	}
+
+	for _, line := range strings.Split(string(info), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if (key == "FileVault" || key == "Encrypted") && strings.HasPrefix(value, "Yes") {
+			// Encrypted APFS volumes always use AES-XTS
+			return &diskStatus{Encrypted: true, Scheme: "APFS", Cipher: "AES-XTS"}, nil
+		}
+	}
+
+	return &diskStatus{}, nil
+}
+
+func windowsEncryptionStatus(device string) (*diskStatus, error) {
+	output, err := diskRunner.Output("manage-bde", "-status", device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run manage-bde: %w", err)
+	}
+
+	method := bitLockerMethodPattern.FindSubmatch(output)
+	if method == nil || strings.EqualFold(string(method[1]), "None") {
+		return &diskStatus{}, nil
+	}
+
+	return &diskStatus{Encrypted: true, Scheme: "BitLocker", Cipher: string(method[1])}, nil
+}
+
+// checkNotEncrypted refuses to initialize a device that is already encrypted
+// unless --force was given
+func checkNotEncrypted(goos string) error {
+	status, err := deviceEncryptionStatus(goos, diskDevice)
+	if err != nil {
+		return fmt.Errorf("failed to check existing encryption: %w", err)
+	}
+	if !status.Encrypted {
+		return nil
+	}
+
+	if diskForce {
+		fmt.Printf("⚠️  %s already contains a %s volume; reformatting because --force was given\n", diskDevice, status.Scheme)
+		return nil
+	}
+	return fmt.Errorf("%s already contains a %s volume; use --force to reformat it and destroy its data", diskDevice, status.Scheme)
+}
+
+func printDiskStatus(goos string) error {
+	status, err := deviceEncryptionStatus(goos, diskDevice)
+	if err != nil {
+		return err
+	}
+
+	if !status.Encrypted {
+		fmt.Printf("🔓 %s is not encrypted\n", diskDevice)
+		return nil
+	}
+
+	fmt.Printf("🔐 %s is encrypted (%s)\n", diskDevice, status.Scheme)
+	if status.Cipher != "" {
+		fmt.Printf("   Cipher: %s\n", status.Cipher)
+	}
+	return nil
+}
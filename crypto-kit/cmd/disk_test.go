@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setDiskFlags sets the disk command flags for a test and restores them after
+func setDiskFlags(t *testing.T, device string, init, dryRun bool) {
+	t.Helper()
+
+	diskDevice, diskInit, diskDryRun = device, init, dryRun
+	diskAlgo, diskLabel = "XTS-AES-256", "SecureData"
+	t.Cleanup(func() {
+		diskDevice, diskInit, diskDryRun, diskYes = "", false, false, false
+		diskForce, diskStat, diskMount, diskUnmount = false, false, "", false
+		diskToken = tokenFIDO2
+	})
+}
+
+func TestDiskInitPlans(t *testing.T) {
+	linux := linuxInitPlan("/dev/sdb", "XTS-AES-256", "SecureData", "", false)
+	expectedLinux := []string{
+		"sudo cryptsetup luksFormat --type luks2 --cipher xts-aes-256 --hash sha256 --key-size 256 /dev/sdb",
+		"sudo cryptsetup open /dev/sdb SecureData_encrypted",
+		"sudo mkfs.ext4 -L SecureData /dev/mapper/SecureData_encrypted",
+	}
+	if got := planStrings(linux); !reflect.DeepEqual(got, expectedLinux) {
+		t.Errorf("unexpected Linux plan:\n%v\nwant:\n%v", got, expectedLinux)
+	}
+
+	expectedMacOS := []string{"diskutil apfs createContainer -passphrase - /dev/disk2"}
+	if got := planStrings(macOSInitPlan("/dev/disk2")); !reflect.DeepEqual(got, expectedMacOS) {
+		t.Errorf("unexpected macOS plan: %v", got)
+	}
+
+	expectedWindows := []string{`powershell -Command "Enable-BitLocker -MountPoint \"E:\" -EncryptionMethod AES256 -UsedSpaceOnly"`}
+	if got := planStrings(windowsInitPlan("E:")); !reflect.DeepEqual(got, expectedWindows) {
+		t.Errorf("unexpected Windows plan: %v", got)
+	}
+}
+
+func TestDiskDryRunDoesNotExecute(t *testing.T) {
+	// Any executed command would fail to resolve with an empty PATH
+	t.Setenv("PATH", "")
+
+	for _, goos := range []string{"linux", "darwin", "windows"} {
+		setDiskFlags(t, "/dev/does-not-exist", true, true)
+		if err := handleDisk(goos); err != nil {
+			t.Errorf("%s: dry run failed: %v", goos, err)
+		}
+	}
+}
+
+func planStrings(plan []diskCommand) []string {
+	lines := make([]string, 0, len(plan))
+	for _, command := range plan {
+		lines = append(lines, command.String())
+	}
+	return lines
+}
+
+// exitError simulates a command that ran and exited with a non-zero status
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// mockRunner records commands and answers them from canned results keyed by
+// the command line
+type mockRunner struct {
+	calls   []string
+	outputs map[string]string
+	errors  map[string]error
+}
+
+func (m *mockRunner) LookPath(file string) (string, error) {
+	return "/usr/bin/" + file, nil
+}
+
+func (m *mockRunner) Run(name string, args ...string) error {
+	line := strings.Join(append([]string{name}, args...), " ")
+	m.calls = append(m.calls, line)
+	return m.errors[line]
+}
+
+func (m *mockRunner) Output(name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	m.calls = append(m.calls, line)
+	return []byte(m.outputs[line]), m.errors[line]
+}
+
+// useMockRunner replaces diskRunner for a test
+func useMockRunner(t *testing.T, runner *mockRunner) {
+	t.Helper()
+
+	previous := diskRunner
+	diskRunner = runner
+	t.Cleanup(func() { diskRunner = previous })
+}
+
+const luks2Dump = `LUKS header information
+Version:       	2
+
+Data segments:
+  0: crypt
+	offset: 16777216 [bytes]
+	cipher: aes-xts-plain64
+	sector: 512 [bytes]
+`
+
+func TestDiskInitRefusesExistingLUKS(t *testing.T) {
+	runner := &mockRunner{outputs: map[string]string{
+		"sudo cryptsetup luksDump /dev/sdb": luks2Dump,
+	}}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", true, false)
+	diskYes = true
+
+	err := handleDisk("linux")
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected init to be refused, got %v", err)
+	}
+	for _, call := range runner.calls {
+		if strings.Contains(call, "luksFormat") {
+			t.Fatalf("device was reformatted: %v", runner.calls)
+		}
+	}
+
+	// --force reformats anyway
+	runner.calls = nil
+	diskForce = true
+	if err := handleDisk("linux"); err != nil {
+		t.Fatalf("forced init failed: %v", err)
+	}
+	if !reflect.DeepEqual(runner.calls[2:], planStrings(linuxInitPlan("/dev/sdb", diskAlgo, diskLabel, "", true))) {
+		t.Errorf("unexpected commands: %v", runner.calls)
+	}
+}
+
+func TestDiskInitProceedsOnUnencryptedDevice(t *testing.T) {
+	runner := &mockRunner{errors: map[string]error{
+		"sudo cryptsetup isLuks /dev/sdb": exitError(1),
+	}}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", true, false)
+	diskYes = true
+
+	if err := handleDisk("linux"); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if !reflect.DeepEqual(runner.calls[1:], planStrings(linuxInitPlan("/dev/sdb", diskAlgo, diskLabel, "", true))) {
+		t.Errorf("unexpected commands: %v", runner.calls)
+	}
+}
+
+func TestDeviceEncryptionStatus(t *testing.T) {
+	runner := &mockRunner{
+		outputs: map[string]string{
+			"sudo cryptsetup luksDump /dev/sdb": luks2Dump,
+			"sudo cryptsetup luksDump /dev/sdc": "Version:        1\nCipher name:    aes\nCipher mode:    cbc-essiv:sha256\n",
+			"diskutil info /dev/disk2":          "   Device Node:    /dev/disk2\n   FileVault:      Yes (Unlocked)\n",
+			"manage-bde -status E:":             "Volume E: [Data]\n    Encryption Method:    XTS-AES 256\n",
+			"manage-bde -status F:":             "Volume F: [Data]\n    Encryption Method:    None\n",
+		},
+		errors: map[string]error{
+			"sudo cryptsetup isLuks /dev/sdd": exitError(1),
+		},
+	}
+	useMockRunner(t, runner)
+
+	tests := []struct {
+		goos, device string
+		expected     diskStatus
+	}{
+		{"linux", "/dev/sdb", diskStatus{Encrypted: true, Scheme: "LUKS", Cipher: "aes-xts-plain64"}},
+		{"linux", "/dev/sdc", diskStatus{Encrypted: true, Scheme: "LUKS", Cipher: "aes-cbc-essiv:sha256"}},
+		{"linux", "/dev/sdd", diskStatus{}},
+		{"darwin", "/dev/disk2", diskStatus{Encrypted: true, Scheme: "APFS", Cipher: "AES-XTS"}},
+		{"windows", "E:", diskStatus{Encrypted: true, Scheme: "BitLocker", Cipher: "XTS-AES 256"}},
+		{"windows", "F:", diskStatus{}},
+	}
+
+	for _, tt := range tests {
+		status, err := deviceEncryptionStatus(tt.goos, tt.device)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.goos, tt.device, err)
+			continue
+		}
+		if *status != tt.expected {
+			t.Errorf("%s %s: got %+v, want %+v", tt.goos, tt.device, *status, tt.expected)
+		}
+	}
+}
+
+func TestDeviceEncryptionStatusReportsIsLuksFailures(t *testing.T) {
+	runner := &mockRunner{errors: map[string]error{
+		"sudo cryptsetup isLuks /dev/sdb": exitError(4),
+		"sudo cryptsetup isLuks /dev/sdc": errors.New("sudo: not found"),
+	}}
+	useMockRunner(t, runner)
+
+	for _, device := range []string{"/dev/sdb", "/dev/sdc"} {
+		if status, err := deviceEncryptionStatus("linux", device); err == nil {
+			t.Errorf("%s: expected an error, got %+v", device, *status)
+		}
+	}
+}
+
+func TestDiskInitAndMountSequence(t *testing.T) {
+	runner := &mockRunner{errors: map[string]error{
+		"sudo cryptsetup isLuks /dev/sdb": exitError(1),
+	}}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", true, false)
+	diskYes, diskMount = true, "/mnt/secure"
+
+	if err := handleDisk("linux"); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	expected := []string{
+		"sudo cryptsetup isLuks /dev/sdb",
+		"sudo cryptsetup luksFormat --batch-mode --verify-passphrase --type luks2 --cipher xts-aes-256 --hash sha256 --key-size 256 /dev/sdb",
+		"sudo cryptsetup open /dev/sdb SecureData_encrypted",
+		"sudo mkfs.ext4 -L SecureData /dev/mapper/SecureData_encrypted",
+		"sudo mkdir -p /mnt/secure",
+		"sudo mount /dev/mapper/SecureData_encrypted /mnt/secure",
+	}
+	if !reflect.DeepEqual(runner.calls, expected) {
+		t.Errorf("unexpected commands:\n%v\nwant:\n%v", runner.calls, expected)
+	}
+}
+
+func TestDiskMountFailureClosesMapper(t *testing.T) {
+	runner := &mockRunner{errors: map[string]error{
+		"sudo mount /dev/mapper/SecureData_encrypted /mnt/secure": exitError(32),
+	}}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", false, false)
+	diskMount = "/mnt/secure"
+
+	err := handleDisk("linux")
+	if err == nil || !strings.Contains(err.Error(), "mount filesystem") {
+		t.Fatalf("expected mount failure, got %v", err)
+	}
+
+	expected := []string{
+		"sudo cryptsetup open /dev/sdb SecureData_encrypted",
+		"sudo mkdir -p /mnt/secure",
+		"sudo mount /dev/mapper/SecureData_encrypted /mnt/secure",
+		"sudo cryptsetup close SecureData_encrypted",
+	}
+	if !reflect.DeepEqual(runner.calls, expected) {
+		t.Errorf("unexpected commands:\n%v\nwant:\n%v", runner.calls, expected)
+	}
+}
+
+func TestDiskUnmount(t *testing.T) {
+	runner := &mockRunner{}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", false, false)
+	diskUnmount, diskMount = true, "/mnt/secure"
+
+	if err := handleDisk("linux"); err != nil {
+		t.Fatalf("unmount failed: %v", err)
+	}
+
+	expected := []string{
+		"sudo umount /mnt/secure",
+		"sudo cryptsetup close SecureData_encrypted",
+	}
+	if !reflect.DeepEqual(runner.calls, expected) {
+		t.Errorf("unexpected commands: %v", runner.calls)
+	}
+}
+
+func TestEnrollHardwareKey(t *testing.T) {
+	runner := &mockRunner{outputs: map[string]string{
+		"sudo cryptsetup luksDump /dev/sdb":       luks2Dump,
+		"systemd-cryptenroll --fido2-device=list": "PATH         MANUFACTURER PRODUCT\n/dev/hidraw4 Yubico       YubiKey OTP+FIDO+CCID\n",
+	}}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", false, false)
+
+	if err := enrollHardwareKey("linux"); err != nil {
+		t.Fatalf("enroll failed: %v", err)
+	}
+
+	expected := []string{
+		"sudo cryptsetup isLuks /dev/sdb",
+		"sudo cryptsetup luksDump /dev/sdb",
+		"systemd-cryptenroll --fido2-device=list",
+		"sudo systemd-cryptenroll --fido2-device=auto /dev/sdb",
+	}
+	if !reflect.DeepEqual(runner.calls, expected) {
+		t.Errorf("unexpected commands:\n%v\nwant:\n%v", runner.calls, expected)
+	}
+}
+
+func TestEnrollHardwareKeyWithoutToken(t *testing.T) {
+	runner := &mockRunner{
+		outputs: map[string]string{
+			"sudo cryptsetup luksDump /dev/sdb": luks2Dump,
+		},
+		errors: map[string]error{
+			"systemd-cryptenroll --tpm2-device=list": exitError(1),
+		},
+	}
+	useMockRunner(t, runner)
+	setDiskFlags(t, "/dev/sdb", false, false)
+	diskToken = tokenTPM2
+
+	err := enrollHardwareKey("linux")
+	if err == nil || !strings.Contains(err.Error(), "no tpm2 hardware key present") {
+		t.Fatalf("expected missing token error, got %v", err)
+	}
+	for _, call := range runner.calls {
+		if strings.HasPrefix(call, "sudo systemd-cryptenroll") {
+			t.Fatalf("enrollment ran without a token: %v", runner.calls)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// reencryptResult summarizes the re-encryption of a directory
+type reencryptResult struct {
+	Succeeded []string
+	Failed    map[string]error
+}
+
+// reencryptDirectory re-encrypts every .age file under dir from oldIdentity
+// to recipients. Each file is only replaced once its re-encrypted copy has
+// been verified to decrypt to the same plaintext with newIdentity.
+func reencryptDirectory(dir string, oldIdentity, newIdentity age.Identity, recipients []age.Recipient) (*reencryptResult, error) {
+	result := &reencryptResult{Failed: make(map[string]error)}
+
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			result.Failed[path] = err
+			return nil
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".age") {
+			return nil
+		}
+
+		if err := reencryptFile(path, oldIdentity, newIdentity, recipients); err != nil {
+			result.Failed[path] = err
+		} else {
+			result.Succeeded = append(result.Succeeded, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// reencryptFile re-encrypts a single file to recipients
+func reencryptFile(path string, oldIdentity, newIdentity age.Identity, recipients []age.Recipient) error {
+	input, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer input.Close()
+
+	r, err := age.Decrypt(input, oldIdentity)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt with old key: %w", err)
+	}
+
+	info, err := input.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Write the re-encrypted copy next to the original
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".reencrypt-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	w, err := age.Encrypt(tmp, recipients...)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to create age writer: %w", err)
+	}
+
+	plaintextHash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, plaintextHash), r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to re-encrypt data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to finalize encryption: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write re-encrypted file: %w", err)
+	}
+
+	// Verify the copy decrypts with the new key before replacing the original
+	verifyHash, err := decryptedHash(tmpPath, newIdentity)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	if !bytes.Equal(verifyHash, plaintextHash.Sum(nil)) {
+		return fmt.Errorf("verification failed: plaintext mismatch")
+	}
+
+	// Release the original before replacing it
+	input.Close()
+
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace original: %w", err)
+	}
+
+	return nil
+}
+
+// decryptedHash returns the SHA-256 of the plaintext of an age file
+func decryptedHash(path string, identity age.Identity) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r, err := age.Decrypt(file, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+func printReencryptReport(result *reencryptResult) {
+	fmt.Printf("🔁 Re-encrypted %d file(s) to the new key\n", len(result.Succeeded))
+	for _, path := range result.Succeeded {
+		logVerbose("Re-encrypted: %s", path)
+	}
+
+	if len(result.Failed) == 0 {
+		return
+	}
+
+	failed := make([]string, 0, len(result.Failed))
+	for path := range result.Failed {
+		failed = append(failed, path)
+	}
+	sort.Strings(failed)
+
+	fmt.Printf("⚠️  %d file(s) could not be re-encrypted and were left unchanged:\n", len(failed))
+	for _, path := range failed {
+		fmt.Printf("  • %s: %v\n", path, result.Failed[path])
+	}
+}
@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"filippo.io/age"
@@ -11,12 +17,37 @@ import (
 )
 
 var (
-	rotatePolicy string
-	rotateVault  string
-	rotateBackup string
-	rotateForce  bool
+	rotatePolicy    string
+	rotateVault     string
+	rotateBackup    string
+	rotateForce     bool
+	rotateHistory   bool
+	rotateReencrypt string
+	rotateKeepOld   bool
+	rotateWindow    string
 )
 
+// minRotationInterval is the shortest accepted rotation policy
+const minRotationInterval = time.Hour
+
+// RotationRecord is an entry in the append-only rotation history
+type RotationRecord struct {
+	Timestamp       time.Time  `json:"timestamp"`
+	Policy          string     `json:"policy"`
+	OldFingerprint  string     `json:"old_fingerprint,omitempty"`
+	NewFingerprint  string     `json:"new_fingerprint"`
+	BackupLocation  string     `json:"backup_location,omitempty"`
+	VaultTarget     string     `json:"vault_target,omitempty"`
+	TransitionUntil *time.Time `json:"transition_until,omitempty"` // Old key stays a recipient until then
+}
+
+// previousRecipient is the replaced public key kept as a recipient during a
+// rotation transition window
+type previousRecipient struct {
+	PublicKey string    `json:"public_key"`
+	Until     time.Time `json:"until"`
+}
+
 // rotateCmd represents the rotate command
 var rotateCmd = &cobra.Command{
 	Use:   "rotate",
@@ -24,7 +55,7 @@ var rotateCmd = &cobra.Command{
 	Long: `Automated key rotation with configurable policies.
 
 Supports:
-• Time-based rotation policies (90d, 180d, 365d)
+• Time-based rotation policies (90d, 180d, 365d, or custom such as 14d, 168h)
 • Vault integration (HashiCorp Vault, Bitwarden)
 • Secure key archival and backup
 • Audit logging and compliance reporting
@@ -32,20 +63,31 @@ Supports:
 Examples:
   crypto-kit rotate --policy 90d --vault bitwarden
   crypto-kit rotate --policy 180d --backup /secure/keystore
-  crypto-kit rotate --force --policy 30d`,
+  crypto-kit rotate --force --policy 30d
+  crypto-kit rotate --history
+  crypto-kit rotate --force --backup /secure/keystore --reencrypt ~/shared
+  crypto-kit rotate --force --keep-old-recipient --transition-window 30d --backup /secure/keystore --reencrypt ~/shared`,
 	RunE: runRotate,
 }
 
 func init() {
 	rootCmd.AddCommand(rotateCmd)
 
-	rotateCmd.Flags().StringVar(&rotatePolicy, "policy", "90d", "rotation policy (30d, 90d, 180d, 365d)")
+	rotateCmd.Flags().StringVar(&rotatePolicy, "policy", "90d", "rotation policy: 30d, 90d, 180d, 365d, or a custom interval such as 14d or 168h")
 	rotateCmd.Flags().StringVar(&rotateVault, "vault", "", "vault backend (vault, bitwarden)")
 	rotateCmd.Flags().StringVar(&rotateBackup, "backup", "", "backup directory for old keys")
 	rotateCmd.Flags().BoolVar(&rotateForce, "force", false, "force rotation even if policy not met")
+	rotateCmd.Flags().BoolVar(&rotateHistory, "history", false, "print the rotation history and exit")
+	rotateCmd.Flags().StringVar(&rotateReencrypt, "reencrypt", "", "directory of .age files to re-encrypt to the new key (requires --backup)")
+	rotateCmd.Flags().BoolVar(&rotateKeepOld, "keep-old-recipient", false, "keep encrypting to the old key during a transition window")
+	rotateCmd.Flags().StringVar(&rotateWindow, "transition-window", "14d", "how long the old key stays a recipient with --keep-old-recipient")
 }
 
 func runRotate(cmd *cobra.Command, args []string) error {
+	if rotateHistory {
+		return printRotationHistory()
+	}
+
 	logVerbose("Starting key rotation process")
 
 	// Parse rotation policy
@@ -72,9 +114,45 @@ func runRotate(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("🔄 Starting key rotation (policy: %s)\n", rotatePolicy)
 
+	// Fingerprint the key being replaced for the rotation history
+	oldFingerprint := ""
+	oldPublicKey, err := os.ReadFile(getKeyPath("public"))
+	if err == nil {
+		oldFingerprint = keyFingerprint(string(oldPublicKey))
+	}
+
+	// The old key can stay a recipient for a transition window
+	var transitionUntil *time.Time
+	if rotateKeepOld {
+		if oldFingerprint == "" {
+			return fmt.Errorf("--keep-old-recipient requires an existing public key")
+		}
+		window, err := parseRotationPolicy(rotateWindow)
+		if err != nil {
+			return fmt.Errorf("invalid transition window: %w", err)
+		}
+		until := time.Now().UTC().Add(window)
+		transitionUntil = &until
+	}
+
+	// Load the key being replaced so existing files can be migrated. The old
+	// key must be archived first so files that fail to migrate stay readable.
+	var oldIdentity age.Identity
+	if rotateReencrypt != "" {
+		if rotateBackup == "" {
+			return fmt.Errorf("--reencrypt requires --backup so the old key is archived before files are migrated")
+		}
+		oldIdentity, err = loadIdentity(getKeyPath("private"))
+		if err != nil {
+			return fmt.Errorf("re-encryption requires the current private key: %w", err)
+		}
+	}
+
 	// Backup existing keys if backup directory specified
+	backupLocation := ""
 	if rotateBackup != "" {
-		if err := backupExistingKeys(rotateBackup); err != nil {
+		backupLocation, err = backupExistingKeys(rotateBackup)
+		if err != nil {
 			return fmt.Errorf("failed to backup existing keys: %w", err)
 		}
 		fmt.Printf("💾 Existing keys backed up to: %s\n", rotateBackup)
@@ -86,11 +164,49 @@ func runRotate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to generate new key pair: %w", err)
 	}
 
+	// Re-encrypt files from the old key to the new one before the old key
+	// is replaced
+	if rotateReencrypt != "" {
+		newIdentity, err := age.ParseX25519Identity(privateKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse new private key: %w", err)
+		}
+
+		recipients := []age.Recipient{newIdentity.Recipient()}
+		if transitionUntil != nil {
+			old, err := age.ParseX25519Recipient(strings.TrimSpace(string(oldPublicKey)))
+			if err != nil {
+				return fmt.Errorf("failed to parse old public key: %w", err)
+			}
+			recipients = append(recipients, old)
+		}
+
+		result, err := reencryptDirectory(rotateReencrypt, oldIdentity, newIdentity, recipients)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %s: %w", rotateReencrypt, err)
+		}
+		printReencryptReport(result)
+		if len(result.Failed) > 0 {
+			fmt.Printf("💾 Files left unchanged can still be decrypted with the backup key: %s\n", backupLocation)
+		}
+	}
+
 	// Save new keys
 	if err := saveKeyPair(publicKey, privateKey); err != nil {
 		return fmt.Errorf("failed to save new key pair: %w", err)
 	}
 
+	// Keep or drop the previous recipient
+	if transitionUntil != nil {
+		previous := previousRecipient{PublicKey: strings.TrimSpace(string(oldPublicKey)), Until: *transitionUntil}
+		if err := savePreviousRecipient(previous); err != nil {
+			return fmt.Errorf("failed to keep old recipient: %w", err)
+		}
+		fmt.Printf("🤝 Old key remains a recipient until %s\n", transitionUntil.Format("2006-01-02 15:04:05"))
+	} else if err := os.Remove(getConfigPath("previous_recipient")); err == nil {
+		fmt.Printf("🧹 Old recipient from previous transition window dropped\n")
+	}
+
 	// Update vault if configured
 	if rotateVault != "" {
 		if err := updateVault(rotateVault, publicKey, privateKey); err != nil {
@@ -105,6 +221,20 @@ func runRotate(cmd *cobra.Command, args []string) error {
 		fmt.Printf("⚠️  Warning: failed to update rotation timestamp: %v\n", err)
 	}
 
+	// Record the rotation for auditing
+	record := RotationRecord{
+		Timestamp:       time.Now().UTC(),
+		Policy:          rotatePolicy,
+		OldFingerprint:  oldFingerprint,
+		NewFingerprint:  keyFingerprint(publicKey),
+		BackupLocation:  backupLocation,
+		VaultTarget:     rotateVault,
+		TransitionUntil: transitionUntil,
+	}
+	if err := appendRotationHistory(record); err != nil {
+		fmt.Printf("⚠️  Warning: failed to record rotation history: %v\n", err)
+	}
+
 	fmt.Printf("✅ Key rotation completed successfully\n")
 	fmt.Printf("📄 New public key: %s\n", getKeyPath("public"))
 	fmt.Printf("🔑 New private key: %s\n", getKeyPath("private"))
@@ -127,9 +257,29 @@ func parseRotationPolicy(policy string) (time.Duration, error) {
 		return 180 * 24 * time.Hour, nil
 	case "365d":
 		return 365 * 24 * time.Hour, nil
-	default:
-		return 0, fmt.Errorf("unsupported policy: %s (supported: 30d, 90d, 180d, 365d)", policy)
 	}
+
+	// Custom intervals: day-suffixed values or Go durations
+	var interval time.Duration
+	if days, ok := strings.CutSuffix(policy, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("unsupported policy: %s (use e.g. 45d or 168h)", policy)
+		}
+		interval = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(policy)
+		if err != nil {
+			return 0, fmt.Errorf("unsupported policy: %s (use e.g. 45d or 168h)", policy)
+		}
+		interval = d
+	}
+
+	if interval < minRotationInterval {
+		return 0, fmt.Errorf("rotation interval %s is shorter than the minimum of %v", policy, minRotationInterval)
+	}
+
+	return interval, nil
 }
 
 func isRotationNeeded(interval time.Duration) (bool, time.Time, error) {
@@ -156,9 +306,11 @@ func isRotationNeeded(interval time.Duration) (bool, time.Time, error) {
 	return needed, nextRotation, nil
 }
 
-func backupExistingKeys(backupDir string) error {
+// backupExistingKeys copies the current keys to backupDir and returns the
+// path of the backed-up private key, if there was one
+func backupExistingKeys(backupDir string) (string, error) {
 	if err := os.MkdirAll(backupDir, 0700); err != nil {
-		return err
+		return "", err
 	}
 
 	timestamp := time.Now().Format("20060102_150405")
@@ -168,20 +320,21 @@ func backupExistingKeys(backupDir string) error {
 	if _, err := os.Stat(publicPath); err == nil {
 		backupPublic := filepath.Join(backupDir, fmt.Sprintf("public_%s.age", timestamp))
 		if err := copyFile(publicPath, backupPublic); err != nil {
-			return fmt.Errorf("failed to backup public key: %w", err)
+			return "", fmt.Errorf("failed to backup public key: %w", err)
 		}
 	}
 
 	// Backup private key if exists
+	backupPrivate := ""
 	privatePath := getKeyPath("private")
 	if _, err := os.Stat(privatePath); err == nil {
-		backupPrivate := filepath.Join(backupDir, fmt.Sprintf("private_%s.age", timestamp))
+		backupPrivate = filepath.Join(backupDir, fmt.Sprintf("private_%s.age", timestamp))
 		if err := copyFile(privatePath, backupPrivate); err != nil {
-			return fmt.Errorf("failed to backup private key: %w", err)
+			return "", fmt.Errorf("failed to backup private key: %w", err)
 		}
 	}
 
-	return nil
+	return backupPrivate, nil
 }
 
 func generateNewKeyPair() (string, string, error) {
@@ -244,6 +397,108 @@ func updateRotationTimestamp() error {
 	return os.WriteFile(timestampPath, []byte(timestamp), 0644)
 }
 
+// keyFingerprint returns the SHA-256 fingerprint of a public key
+func keyFingerprint(publicKey string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(publicKey)))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// appendRotationHistory appends a record to the rotation history log
+func appendRotationHistory(record RotationRecord) error {
+	historyPath := getConfigPath("rotation_history")
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// readRotationHistory returns the recorded rotations, oldest first
+func readRotationHistory() ([]RotationRecord, error) {
+	file, err := os.Open(getConfigPath("rotation_history"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []RotationRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record RotationRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("corrupt rotation history entry: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+func printRotationHistory() error {
+	records, err := readRotationHistory()
+	if err != nil {
+		return fmt.Errorf("failed to read rotation history: %w", err)
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("📜 No key rotations recorded\n")
+		return nil
+	}
+
+	fmt.Printf("📜 Key Rotation History (%d rotations)\n", len(records))
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	for _, record := range records {
+		fmt.Printf("%s  policy=%s\n", record.Timestamp.Format("2006-01-02 15:04:05"), record.Policy)
+		if record.OldFingerprint != "" {
+			fmt.Printf("  Old key: %s\n", record.OldFingerprint)
+		}
+		fmt.Printf("  New key: %s\n", record.NewFingerprint)
+		if record.BackupLocation != "" {
+			fmt.Printf("  Backup:  %s\n", record.BackupLocation)
+		}
+		if record.VaultTarget != "" {
+			fmt.Printf("  Vault:   %s\n", record.VaultTarget)
+		}
+		if record.TransitionUntil != nil {
+			fmt.Printf("  Old key kept as recipient until %s\n", record.TransitionUntil.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	return nil
+}
+
+// savePreviousRecipient records the old public key kept during a transition
+func savePreviousRecipient(previous previousRecipient) error {
+	path := getConfigPath("previous_recipient")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(previous)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
 func getKeyPath(keyType string) string {
 	home, _ := os.UserHomeDir()
 	keyDir := filepath.Join(home, ".crypto-kit", "keys")
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+// setupTestHome points the key and config directories at a temporary home
+func setupTestHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return home
+}
+
+func TestRotationHistoryRecordsEachRotation(t *testing.T) {
+	home := setupTestHome(t)
+
+	rotatePolicy = "90d"
+	rotateForce = true
+	rotateBackup = home + "/backup"
+	rotateVault = ""
+	t.Cleanup(func() {
+		rotateForce = false
+		rotateBackup = ""
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := runRotate(rotateCmd, nil); err != nil {
+			t.Fatalf("rotation %d failed: %v", i+1, err)
+		}
+	}
+
+	records, err := readRotationHistory()
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 history records, got %d", len(records))
+	}
+
+	first, second := records[0], records[1]
+	if first.OldFingerprint != "" || first.NewFingerprint == "" {
+		t.Errorf("unexpected first record: %+v", first)
+	}
+	if second.OldFingerprint != first.NewFingerprint {
+		t.Errorf("expected second rotation to replace %s, got %s", first.NewFingerprint, second.OldFingerprint)
+	}
+	if second.NewFingerprint == first.NewFingerprint {
+		t.Errorf("expected distinct fingerprints, both %s", first.NewFingerprint)
+	}
+	if second.BackupLocation == "" || second.Policy != "90d" {
+		t.Errorf("expected backup location and policy in second record: %+v", second)
+	}
+}
+
+func TestParseRotationPolicy(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30d":   30 * 24 * time.Hour,
+		"90d":   90 * 24 * time.Hour,
+		"180d":  180 * 24 * time.Hour,
+		"365d":  365 * 24 * time.Hour,
+		"14d":   14 * 24 * time.Hour,
+		"45d":   45 * 24 * time.Hour,
+		"168h":  168 * time.Hour,
+		"2160h": 2160 * time.Hour,
+		"1h":    time.Hour,
+	}
+	for policy, expected := range valid {
+		got, err := parseRotationPolicy(policy)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", policy, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("%s: expected %v, got %v", policy, expected, got)
+		}
+	}
+
+	for _, policy := range []string{"", "abc", "d", "-5d", "0d", "1.5d", "30m", "90x", "-1h"} {
+		if _, err := parseRotationPolicy(policy); err == nil {
+			t.Errorf("%q: expected error", policy)
+		}
+	}
+}
+
+func TestRotateReencryptsFilesToNewKey(t *testing.T) {
+	home := setupTestHome(t)
+
+	rotatePolicy = "90d"
+	rotateForce = true
+	rotateBackup = filepath.Join(home, "backup")
+	t.Cleanup(func() {
+		rotateForce = false
+		rotateBackup = ""
+		rotateReencrypt = ""
+	})
+
+	// Initial key pair
+	if err := runRotate(rotateCmd, nil); err != nil {
+		t.Fatalf("initial rotation failed: %v", err)
+	}
+	oldRecipient, err := loadRecipient(getKeyPath("public"))
+	if err != nil {
+		t.Fatalf("failed to load old public key: %v", err)
+	}
+
+	dataDir := filepath.Join(home, "shared")
+	if err := os.MkdirAll(filepath.Join(dataDir, "nested"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	samples := map[string]string{
+		filepath.Join(dataDir, "report.txt"):          "quarterly report",
+		filepath.Join(dataDir, "nested", "notes.txt"): "field notes",
+	}
+	for path, content := range samples {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := encryptFile(path, path+".age", oldRecipient); err != nil {
+			t.Fatalf("failed to encrypt sample: %v", err)
+		}
+		os.Remove(path)
+	}
+	// A file that is not encrypted to the old key is reported and left alone
+	corrupt := filepath.Join(dataDir, "corrupt.age")
+	if err := os.WriteFile(corrupt, []byte("not an age file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rotateReencrypt = dataDir
+	if err := runRotate(rotateCmd, nil); err != nil {
+		t.Fatalf("rotation with re-encryption failed: %v", err)
+	}
+
+	newIdentity, err := loadIdentity(getKeyPath("private"))
+	if err != nil {
+		t.Fatalf("failed to load new private key: %v", err)
+	}
+	for path, content := range samples {
+		if err := decryptFileContent(path+".age", path, newIdentity); err != nil {
+			t.Fatalf("failed to decrypt %s with new key: %v", path, err)
+		}
+		data, _ := os.ReadFile(path)
+		if string(data) != content {
+			t.Errorf("%s: expected %q, got %q", path, content, data)
+		}
+	}
+
+	if data, _ := os.ReadFile(corrupt); string(data) != "not an age file" {
+		t.Errorf("expected unreadable file to be left unchanged, got %q", data)
+	}
+}
+
+func TestRotateReencryptRequiresBackup(t *testing.T) {
+	home := setupTestHome(t)
+
+	rotatePolicy = "90d"
+	rotateForce = true
+	t.Cleanup(func() {
+		rotateForce = false
+		rotateReencrypt = ""
+	})
+
+	if err := runRotate(rotateCmd, nil); err != nil {
+		t.Fatalf("initial rotation failed: %v", err)
+	}
+	before, err := os.ReadFile(getKeyPath("private"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotateReencrypt = filepath.Join(home, "shared")
+	if err := runRotate(rotateCmd, nil); err == nil {
+		t.Fatal("expected --reencrypt without --backup to be rejected")
+	}
+
+	after, err := os.ReadFile(getKeyPath("private"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("expected the old private key to be kept when rotation is rejected")
+	}
+}
+
+func TestKeepOldRecipientDuringTransitionWindow(t *testing.T) {
+	home := setupTestHome(t)
+
+	rotatePolicy = "90d"
+	rotateForce = true
+	rotateBackup = filepath.Join(home, "backup")
+	t.Cleanup(func() {
+		rotateForce = false
+		rotateBackup = ""
+		rotateKeepOld = false
+		rotateWindow = "14d"
+		rotateReencrypt = ""
+	})
+
+	if err := runRotate(rotateCmd, nil); err != nil {
+		t.Fatalf("initial rotation failed: %v", err)
+	}
+	oldIdentity, err := loadIdentity(getKeyPath("private"))
+	if err != nil {
+		t.Fatalf("failed to load old private key: %v", err)
+	}
+	oldRecipient, _ := loadRecipient(getKeyPath("public"))
+
+	dataDir := filepath.Join(home, "shared")
+	os.MkdirAll(dataDir, 0700)
+	plain := filepath.Join(dataDir, "secret.txt")
+	os.WriteFile(plain, []byte("transition data"), 0600)
+	if err := encryptFile(plain, plain+".age", oldRecipient); err != nil {
+		t.Fatalf("failed to encrypt sample: %v", err)
+	}
+
+	// Rotate keeping the old key as a recipient
+	rotateKeepOld = true
+	rotateWindow = "7d"
+	rotateReencrypt = dataDir
+	if err := runRotate(rotateCmd, nil); err != nil {
+		t.Fatalf("rotation with transition window failed: %v", err)
+	}
+	newIdentity, err := loadIdentity(getKeyPath("private"))
+	if err != nil {
+		t.Fatalf("failed to load new private key: %v", err)
+	}
+
+	for name, identity := range map[string]age.Identity{"old": oldIdentity, "new": newIdentity} {
+		out := filepath.Join(dataDir, name+".txt")
+		if err := decryptFileContent(plain+".age", out, identity); err != nil {
+			t.Fatalf("failed to decrypt with %s key during window: %v", name, err)
+		}
+		if data, _ := os.ReadFile(out); string(data) != "transition data" {
+			t.Errorf("%s key decrypted %q", name, data)
+		}
+	}
+
+	records, err := readRotationHistory()
+	if err != nil || len(records) != 2 || records[1].TransitionUntil == nil {
+		t.Fatalf("expected transition window in history, got %+v (%v)", records, err)
+	}
+	if window := records[1].TransitionUntil.Sub(records[1].Timestamp); window < 6*24*time.Hour || window > 8*24*time.Hour {
+		t.Errorf("expected a 7 day window, got %v", window)
+	}
+
+	// A follow-up rotation drops the old recipient
+	rotateKeepOld = false
+	rotateReencrypt = ""
+	if err := runRotate(rotateCmd, nil); err != nil {
+		t.Fatalf("follow-up rotation failed: %v", err)
+	}
+	if _, err := os.Stat(getConfigPath("previous_recipient")); !os.IsNotExist(err) {
+		t.Fatalf("expected the old recipient to be dropped, got %v", err)
+	}
+}
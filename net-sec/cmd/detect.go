@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -18,6 +19,9 @@ var (
 	userAgent       string
 	followRedirects bool
 	checkDNS        bool
+	attemptBypass   bool
+	httpsProbe      bool
+	trustedDoH      string
 )
 
 // NewDetectCommand creates the 'detect' command for captive portal detection
@@ -59,6 +63,9 @@ Detection Methods:
 	cmd.Flags().StringVar(&userAgent, "user-agent", "Mozilla/5.0 (compatible; net-sec/1.0)", "HTTP User-Agent header")
 	cmd.Flags().BoolVar(&followRedirects, "follow-redirects", false, "Follow HTTP redirects")
 	cmd.Flags().BoolVar(&checkDNS, "check-dns", true, "Validate DNS resolution")
+	cmd.Flags().StringVar(&trustedDoH, "trusted-doh", "", "DNS-over-HTTPS endpoint to cross-check DNS answers against, e.g. "+captive.DefaultDoHEndpoint+" (disabled when empty)")
+	cmd.Flags().BoolVar(&httpsProbe, "https-probe", false, "Detect HTTPS interception via a TLS certificate check")
+	cmd.Flags().BoolVar(&attemptBypass, "attempt-bypass", false, "Attempt to bypass a detected captive portal")
 
 	return cmd
 }
@@ -68,6 +75,12 @@ func runDetectCommand(cmd *cobra.Command, args []string) error {
 
 	// Create captive portal detector
 	detector := captive.NewDetector()
+	if attemptBypass {
+		detector.EnableBypass(nil)
+	}
+	if trustedDoH != "" {
+		detector.SetTrustedResolver(captive.NewDoHResolver(trustedDoH))
+	}
 
 	// Configure detection options
 	opts := &captive.DetectorOptions{
@@ -78,28 +91,38 @@ func runDetectCommand(cmd *cobra.Command, args []string) error {
 		FollowRedirects: followRedirects,
 		CheckDNS:        checkDNS,
 	}
+	if httpsProbe {
+		opts.HTTPSProbe = captive.DefaultHTTPSProbeOptions()
+	}
 
 	// Run detection based on retry configuration
 	if retries == 0 {
 		// Continuous monitoring mode
 		log.Printf("🔄 Starting continuous captive portal monitoring (interval: %v)", interval)
-		return runContinuousDetection(detector, opts, interval)
+		return runContinuousDetection(cmd.Context(), detector, opts, interval)
 	} else {
 		// Single detection with retries
-		return runSingleDetection(detector, opts, retries, interval)
+		return runSingleDetection(cmd.Context(), detector, opts, retries, interval)
 	}
 }
 
-func runSingleDetection(detector *captive.Detector, opts *captive.DetectorOptions, retries int, interval time.Duration) error {
+func runSingleDetection(ctx context.Context, detector *captive.Detector, opts *captive.DetectorOptions, retries int, interval time.Duration) error {
 	for attempt := 1; attempt <= retries; attempt++ {
 		log.Printf("🔍 Detection attempt %d/%d", attempt, retries)
 
-		result, err := detector.Detect(opts)
+		result, err := detector.DetectContext(ctx, opts)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			log.Printf("❌ Detection failed: %v", err)
 			if attempt < retries {
 				log.Printf("⏳ Waiting %v before retry...", interval)
-				time.Sleep(interval)
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 				continue
 			}
 			return fmt.Errorf("all detection attempts failed: %w", err)
@@ -108,6 +131,10 @@ func runSingleDetection(detector *captive.Detector, opts *captive.DetectorOption
 		// Display results
 		displayDetectionResult(result)
 
+		if attemptBypass && result.CaptivePortalDetected {
+			runBypassAttempt(ctx, detector, result)
+		}
+
 		// Success - no need to retry
 		return nil
 	}
@@ -115,12 +142,16 @@ func runSingleDetection(detector *captive.Detector, opts *captive.DetectorOption
 	return nil
 }
 
-func runContinuousDetection(detector *captive.Detector, opts *captive.DetectorOptions, interval time.Duration) error {
+func runContinuousDetection(ctx context.Context, detector *captive.Detector, opts *captive.DetectorOptions, interval time.Duration) error {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// Track status transitions between checks; every tick still probes
+	detector.EnableCache(interval)
+	opts.Force = true
+
 	// Run initial detection
-	result, err := detector.Detect(opts)
+	result, err := detector.DetectContext(ctx, opts)
 	if err != nil {
 		log.Printf("❌ Initial detection failed: %v", err)
 	} else {
@@ -131,7 +162,10 @@ func runContinuousDetection(detector *captive.Detector, opts *captive.DetectorOp
 	for {
 		select {
 		case <-ticker.C:
-			result, err := detector.Detect(opts)
+			result, err := detector.DetectContext(ctx, opts)
+			if ctx.Err() != nil {
+				continue
+			}
 			if err != nil {
 				log.Printf("❌ Detection failed: %v", err)
 				continue
@@ -141,10 +175,28 @@ func runContinuousDetection(detector *captive.Detector, opts *captive.DetectorOp
 			if verbose || result.StatusChanged {
 				displayDetectionResult(result)
 			}
+
+		case <-ctx.Done():
+			log.Printf("🛑 Captive portal monitoring stopped")
+			return nil
 		}
 	}
 }
 
+func runBypassAttempt(ctx context.Context, detector *captive.Detector, result *captive.DetectionResult) {
+	log.Printf("🔓 Attempting captive portal bypass...")
+
+	ok, err := detector.AttemptBypass(ctx, result)
+	switch {
+	case err != nil:
+		log.Printf("❌ Bypass attempt failed: %v", err)
+	case ok:
+		fmt.Printf("✅ Captive portal bypassed using %s\n\n", result.BypassTechnique)
+	default:
+		fmt.Printf("🚫 Captive portal bypass unsuccessful - authentication required\n\n")
+	}
+}
+
 func displayDetectionResult(result *captive.DetectionResult) {
 	// Display main status
 	if result.CaptivePortalDetected {
@@ -164,10 +216,24 @@ func displayDetectionResult(result *captive.DetectionResult) {
 		fmt.Printf("  Redirect URL: %s\n", result.RedirectURL)
 	}
 
+	if len(result.RedirectChain) > 1 {
+		fmt.Printf("  Redirect Chain:\n")
+		for i, hop := range result.RedirectChain {
+			fmt.Printf("    %d. %s\n", i+1, hop)
+		}
+	}
+
 	if result.DNSResolution != nil {
 		fmt.Printf("  DNS Resolution: %s → %v\n", result.TestURL, result.DNSResolution.IPs)
 		if result.DNSResolution.Hijacked {
-			fmt.Printf("  ⚠️  DNS Hijacking Detected\n")
+			fmt.Printf("  ⚠️  DNS Hijacking Detected: %s\n", result.DNSResolution.HijackReason)
+		}
+	}
+
+	if result.TLSProbe != nil {
+		fmt.Printf("  TLS Probe: %s (issuer: %s)\n", result.TLSProbe.Address, result.TLSProbe.Issuer)
+		if result.TLSProbe.Interference {
+			fmt.Printf("  ⚠️  HTTPS Interception Detected: %s\n", result.TLSProbe.Reason)
 		}
 	}
 
@@ -176,6 +242,12 @@ func displayDetectionResult(result *captive.DetectionResult) {
 		fmt.Printf("🌐 Portal Information:\n")
 		fmt.Printf("  Portal URL: %s\n", result.PortalInfo.URL)
 		fmt.Printf("  Portal Title: %s\n", result.PortalInfo.Title)
+		if result.PortalInfo.Vendor != "" {
+			fmt.Printf("  Portal Vendor: %s\n", result.PortalInfo.Vendor)
+		}
+		if result.PortalInfo.Provider != "" {
+			fmt.Printf("  Provider: %s\n", result.PortalInfo.Provider)
+		}
 		if result.PortalInfo.LoginRequired {
 			fmt.Printf("  Authentication: Login required\n")
 		}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/captive"
+)
+
+func TestContinuousDetectionStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opts := &captive.DetectorOptions{
+		TestURL:        server.URL,
+		ExpectedStatus: http.StatusNoContent,
+		Timeout:        time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runContinuousDetection(ctx, captive.NewDetector(), opts, 10*time.Millisecond)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("continuous detection did not stop after cancellation")
+	}
+}
+
+func TestSingleDetectionStopsRetryingOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	opts := &captive.DetectorOptions{
+		TestURL:        "http://127.0.0.1:1/generate_204",
+		ExpectedStatus: http.StatusNoContent,
+		Timeout:        time.Second,
+	}
+
+	start := time.Now()
+	err := runSingleDetection(ctx, captive.NewDetector(), opts, 3, time.Minute)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected retries to be abandoned, took %v", elapsed)
+	}
+}
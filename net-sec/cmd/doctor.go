@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stealthguard/net-sec/internal/config"
+	"github.com/stealthguard/net-sec/internal/doctor"
+)
+
+var doctorTimeout time.Duration
+
+// NewDoctorCommand creates the 'doctor' command for environment self-checks
+func NewDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the environment is ready to run net-sec",
+		Long: `Run a series of self-checks and report each as passed or failed with a
+remediation hint.
+
+Checks:
+• Configuration validity
+• Data, key, config and template directories are writable
+• Required binaries (wg) and optional ones (wg-quick, cryptsetup)
+• System resolver and configured DNS servers are reachable
+• Internet connectivity through the captive portal test URL
+
+Exits non-zero if any critical check fails.`,
+		Example: `  # Check the environment
+  net-sec doctor
+
+  # Allow slow networks more time per check
+  net-sec doctor --timeout 15s`,
+		RunE: runDoctorCommand,
+	}
+
+	cmd.Flags().DurationVar(&doctorTimeout, "timeout", 5*time.Second, "Timeout for each check")
+
+	return cmd
+}
+
+func runDoctorCommand(cmd *cobra.Command, args []string) error {
+	fmt.Printf("🩺 net-sec Doctor\n")
+	fmt.Printf("=================\n\n")
+
+	d := doctor.New(doctor.Options{
+		Config:  config.Get(),
+		Timeout: doctorTimeout,
+	})
+	report := d.Run(cmd.Context())
+
+	warnings := 0
+	for _, result := range report.Results {
+		switch {
+		case result.Passed:
+			fmt.Printf("✅ %s\n", result.Name)
+		case result.Severity == doctor.SeverityCritical:
+			fmt.Printf("❌ %s: %s\n", result.Name, result.Error)
+			fmt.Printf("   💡 %s\n", result.Hint)
+		default:
+			warnings++
+			fmt.Printf("⚠️  %s: %s\n", result.Name, result.Error)
+			fmt.Printf("   💡 %s\n", result.Hint)
+		}
+	}
+	fmt.Println()
+
+	if failed := report.CriticalFailures(); len(failed) > 0 {
+		// The report already explains the failure
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d critical check(s) failed", len(failed))
+	}
+
+	if warnings > 0 {
+		fmt.Printf("✅ All critical checks passed (%d warning(s))\n", warnings)
+	} else {
+		fmt.Printf("✅ All checks passed\n")
+	}
+	return nil
+}
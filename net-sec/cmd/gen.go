@@ -18,6 +18,8 @@ var (
 	keepalive      int
 	outputPath     string
 	generateKeys   bool
+	excludedIPs    []string
+	resolveServer  bool
 )
 
 // NewGenCommand creates the 'gen' command for WireGuard configuration generation
@@ -42,6 +44,9 @@ Supports:
   net-sec gen --server vpn.enterprise.com:51820 --client laptop-001 \
     --ip 10.1.0.50/24 --dns 1.1.1.1 --dns 9.9.9.9 --mtu 1420
 
+  # Split tunnel: keep the local network outside the VPN
+  net-sec gen --server wg.company.com:51820 --ip 10.0.0.100/24 --exclude 192.168.0.0/16
+
   # Generate keys only
   net-sec gen --generate-keys --output /etc/wireguard/`,
 		RunE: runGenCommand,
@@ -56,6 +61,8 @@ Supports:
 	cmd.Flags().IntVar(&mtu, "mtu", 1420, "Interface MTU size")
 	cmd.Flags().IntVar(&keepalive, "keepalive", 25, "Persistent keepalive interval (seconds)")
 	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output file path (default: stdout)")
+	cmd.Flags().StringSliceVar(&excludedIPs, "exclude", nil, "CIDRs to route outside the tunnel (split tunnel)")
+	cmd.Flags().BoolVar(&resolveServer, "resolve-endpoint", false, "Check that the server endpoint hostname resolves")
 	cmd.Flags().BoolVar(&generateKeys, "generate-keys", false, "Generate new key pair only")
 
 	// Required flags
@@ -80,6 +87,8 @@ func runGenCommand(cmd *cobra.Command, args []string) error {
 		MTU:             mtu,
 		Keepalive:       keepalive,
 		GenerateKeys:    generateKeys || serverKey == "",
+		ExcludedIPs:     excludedIPs,
+		ResolveEndpoint: resolveServer,
 	}
 
 	// Generate configuration
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stealthguard/net-sec/internal/config"
 	"github.com/stealthguard/net-sec/internal/monitor"
 )
 
@@ -48,6 +52,8 @@ Monitoring Features:
 		RunE: runMonitorCommand,
 	}
 
+	cmd.AddCommand(newMonitorExportCommand())
+
 	// Monitor configuration flags
 	cmd.Flags().StringVar(&monitorType, "type", "all", "Monitor type (connectivity/security/performance/all)")
 	cmd.Flags().DurationVar(&monitorInterval, "interval", 30*time.Second, "Monitoring check interval")
@@ -58,12 +64,12 @@ Monitoring Features:
 	return cmd
 }
 
-func runMonitorCommand(cmd *cobra.Command, args []string) error {
-	fmt.Printf("📊 StealthGuard Network Monitor\n")
-	fmt.Printf("===============================\n\n")
+// monitorShutdownTimeout bounds how long the monitor may take to stop
+const monitorShutdownTimeout = 5 * time.Second
 
-	// Create and initialize monitor
-	mon := monitor.NewMonitor()
+// newMonitorConfig returns the monitor configuration shared by the monitor
+// subcommands
+func newMonitorConfig() *monitor.MonitorConfig {
 	monitorConfig := &monitor.MonitorConfig{
 		CheckInterval:        30 * time.Second,
 		NetworkCheckInterval: 10 * time.Second,
@@ -77,6 +83,32 @@ func runMonitorCommand(cmd *cobra.Command, args []string) error {
 		MetricsRetention:     24 * time.Hour,
 	}
 
+	// Probe connectivity and DNS against the captive portal test URL
+	if cfg := config.Get(); len(cfg.Captive.TestURLs) > 0 {
+		monitorConfig.ConnectivityURL = cfg.Captive.TestURLs[0]
+		monitorConfig.ExpectedStatus = cfg.Captive.ExpectedStatus
+		if u, err := url.Parse(monitorConfig.ConnectivityURL); err == nil && u.Hostname() != "" {
+			monitorConfig.DNSTestDomains = []string{u.Hostname()}
+		}
+		for _, testURL := range cfg.Captive.TestURLs[1:] {
+			if strings.HasPrefix(testURL, "https://") && !strings.HasPrefix(monitorConfig.ConnectivityURL, "https://") {
+				monitorConfig.HTTPSConnectivityURL = testURL
+				break
+			}
+		}
+	}
+
+	return monitorConfig
+}
+
+func runMonitorCommand(cmd *cobra.Command, args []string) error {
+	fmt.Printf("📊 StealthGuard Network Monitor\n")
+	fmt.Printf("===============================\n\n")
+
+	// Create and initialize monitor
+	mon := monitor.NewMonitor()
+	monitorConfig := newMonitorConfig()
+
 	if err := mon.Initialize(monitorConfig); err != nil {
 		fmt.Printf("❌ Failed to initialize monitor: %v\n", err)
 		os.Exit(1)
@@ -91,22 +123,27 @@ func runMonitorCommand(cmd *cobra.Command, args []string) error {
 	fmt.Printf("✅ Network security monitor started\n")
 	fmt.Printf("📊 Monitoring network interfaces, VPN, DNS, and captive portals...\n\n")
 
-	// Handle graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// Handle graceful shutdown on Ctrl-C, SIGTERM or cancellation of the
+	// command context
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Monitor event stream
+	events, unsubscribe := mon.Subscribe()
+	defer unsubscribe()
 	go func() {
-		for event := range mon.GetEventStream() {
+		for event := range events {
 			displayMonitoringEvent(event)
 		}
 	}()
 
 	// Wait for shutdown signal
-	<-c
+	<-ctx.Done()
 	fmt.Printf("\n🛑 Shutting down monitor...\n")
 
-	if err := mon.Stop(); err != nil {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), monitorShutdownTimeout)
+	defer cancel()
+	if err := mon.Shutdown(shutdownCtx); err != nil {
 		fmt.Printf("❌ Error stopping monitor: %v\n", err)
 	}
 
@@ -151,6 +188,8 @@ func displayMonitoringEvent(event *monitor.MonitorEvent) {
 		fmt.Printf("[%s] � SYSTEM SHUTDOWN: %s\n", timestamp, event.Message)
 	case monitor.EventAlert:
 		fmt.Printf("[%s] 🔔 ALERT: %s\n", timestamp, event.Message)
+	case monitor.EventStatusChange:
+		fmt.Printf("[%s] 📊 STATUS CHANGE: %s\n", timestamp, event.Message)
 	default:
 		fmt.Printf("[%s] ℹ️  %s\n", timestamp, event.Message)
 	}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/stealthguard/net-sec/internal/monitor"
+)
+
+var (
+	exportJSON   bool
+	exportStream bool
+	exportListen string
+)
+
+func newMonitorExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export monitor status and events for external dashboards",
+		Long: `Export the monitor's status for consumption by external dashboards.
+
+With --json the subsystems are checked once and the resulting SystemStatus is
+written to stdout. With --stream the monitor runs continuously and serves its
+events as Server-Sent Events on /events, and the current status on /status.`,
+		Example: `  # Print a JSON status snapshot
+  net-sec monitor export --json
+
+  # Stream events over HTTP
+  net-sec monitor export --stream --listen 127.0.0.1:9108`,
+		RunE: runMonitorExportCommand,
+	}
+
+	cmd.Flags().BoolVar(&exportJSON, "json", false, "Write a JSON status snapshot to stdout (default)")
+	cmd.Flags().BoolVar(&exportStream, "stream", false, "Serve monitor events as Server-Sent Events")
+	cmd.Flags().StringVar(&exportListen, "listen", "127.0.0.1:9108", "Listen address for --stream")
+	cmd.MarkFlagsMutuallyExclusive("json", "stream")
+
+	return cmd
+}
+
+func runMonitorExportCommand(cmd *cobra.Command, args []string) error {
+	mon := monitor.NewMonitor()
+	if err := mon.Initialize(newMonitorConfig()); err != nil {
+		return fmt.Errorf("failed to initialize monitor: %w", err)
+	}
+
+	if !exportStream {
+		mon.RunChecks(cmd.Context())
+		return monitor.WriteStatusJSON(cmd.OutOrStdout(), mon.GetStatus())
+	}
+	return streamMonitorEvents(cmd, mon)
+}
+
+// streamMonitorEvents serves the monitor's events until interrupted. The
+// monitor is shut down first so open event streams end before the server
+// stops.
+func streamMonitorEvents(cmd *cobra.Command, mon *monitor.Monitor) error {
+	listener, err := net.Listen("tcp", exportListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", exportListen, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/events", mon.EventsHandler())
+	mux.Handle("/status", mon.StatusHandler())
+	server := &http.Server{Handler: mux}
+
+	if err := mon.Start(); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to start monitor: %w", err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "📡 Streaming monitor events on http://%s/events\n", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "❌ Event server failed: %v\n", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), monitorShutdownTimeout)
+	defer cancel()
+	if err := mon.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "❌ Error stopping monitor: %v\n", err)
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop event server: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stealthguard/net-sec/internal/config"
 	"github.com/stealthguard/net-sec/internal/multipath"
 )
 
@@ -170,6 +171,7 @@ func runMultipathStart(cmd *cobra.Command, args []string) error {
 		DNSServers:        []string{"1.1.1.1", "1.0.0.1"},
 		RoutingTable:      "main",
 	}
+	resolveMultipathInterfaces(opts)
 
 	// Initialize manager
 	if err := manager.Initialize(opts); err != nil {
@@ -195,8 +197,55 @@ func runMultipathStart(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	// Keep running (in real implementation, this would handle signals)
-	select {}
+	// Run until interrupted, then stop monitoring
+	<-cmd.Context().Done()
+	log.Printf("🛑 Stopping multipath networking...")
+	if err := manager.Stop(); err != nil {
+		return fmt.Errorf("failed to stop multipath manager: %w", err)
+	}
+	return nil
+}
+
+// resolveMultipathInterfaces fills interfaces not given as flags from the
+// config, then from the host's interfaces. Anything still empty is left to
+// the manager's name-based detection.
+func resolveMultipathInterfaces(opts *multipath.Options) {
+	cfg := config.Get().Multipath
+	if opts.PrimaryInterface == "" {
+		opts.PrimaryInterface = cfg.PrimaryInterface
+	}
+	if opts.BackupInterface == "" {
+		opts.BackupInterface = cfg.BackupInterface
+	}
+	if opts.PrimaryInterface != "" && opts.BackupInterface != "" {
+		return
+	}
+
+	primary, backup, err := multipath.DetectInterfaces()
+	if err != nil {
+		log.Printf("⚠️  Interface detection failed: %v", err)
+		return
+	}
+
+	// Take the detected interfaces in order, skipping one already in use
+	pick := func(other string) string {
+		for _, candidate := range []string{primary, backup} {
+			if candidate != "" && candidate != other {
+				return candidate
+			}
+		}
+		return ""
+	}
+	if opts.PrimaryInterface == "" {
+		if opts.PrimaryInterface = pick(opts.BackupInterface); opts.PrimaryInterface != "" {
+			log.Printf("🔍 Detected primary interface: %s", opts.PrimaryInterface)
+		}
+	}
+	if opts.BackupInterface == "" {
+		if opts.BackupInterface = pick(opts.PrimaryInterface); opts.BackupInterface != "" {
+			log.Printf("🔍 Detected backup interface: %s", opts.BackupInterface)
+		}
+	}
 }
 
 func runMultipathStop(cmd *cobra.Command, args []string) error {
@@ -249,6 +298,7 @@ func runMultipathDaemon(cmd *cobra.Command, args []string) error {
 		DNSServers:        []string{"1.1.1.1", "1.0.0.1"},
 		RoutingTable:      "main",
 	}
+	resolveMultipathInterfaces(opts)
 
 	// Initialize and start daemon
 	if err := manager.Initialize(opts); err != nil {
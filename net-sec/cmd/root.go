@@ -48,6 +48,8 @@ Part of the StealthGuard Enterprise Security Ecosystem.`,
 	rootCmd.AddCommand(NewMultipathCommand())
 	rootCmd.AddCommand(NewTestCommand())
 	rootCmd.AddCommand(NewMonitorCommand())
+	rootCmd.AddCommand(NewDoctorCommand())
+	rootCmd.AddCommand(NewVersionCommand(version, commit, date))
 
 	// Initialize config on startup
 	cobra.OnInitialize(initConfig)
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -16,8 +17,15 @@ var (
 	simulateFailure bool
 	testKillSwitch  bool
 	testInterface   string
+	testConcurrency int
+	testLoadRate    int
+	testRampUp      time.Duration
+	testOutput      string
+	testSaveFile    string
 )
 
+const outputFormatText = "text"
+
 // TestOptions contains test configuration options
 type TestOptions struct {
 	TestType             string
@@ -61,6 +69,12 @@ in controlled environments before production deployment.`,
   # Test kill-switch functionality
   net-sec test --type kill-switch --interface en0
 
+  # Benchmark detection at 50 requests per second for 30 seconds
+  net-sec test --type load --rate 50 --duration 30s --ramp-up 5s
+
+  # Write JUnit XML results for CI
+  net-sec test --type full --output junit --save results.xml
+
   # Comprehensive network security test suite
   net-sec test --type full --duration 5m`,
 		RunE: runTestCommand,
@@ -68,7 +82,7 @@ in controlled environments before production deployment.`,
 
 	// Add command flags
 	cmd.Flags().StringVar(&testType, "type", "full",
-		"Test type: captive-portal, failover, kill-switch, dns-leak, wireguard, full")
+		"Test type: captive-portal, failover, kill-switch, dns-leak, wireguard, load, full")
 	cmd.Flags().DurationVar(&testDuration, "duration", 60*time.Second,
 		"Test duration for continuous monitoring tests")
 	cmd.Flags().BoolVar(&simulatePortal, "simulate-portal", false,
@@ -79,13 +93,31 @@ in controlled environments before production deployment.`,
 		"Test kill-switch activation and traffic blocking")
 	cmd.Flags().StringVar(&testInterface, "interface", "",
 		"Specific network interface to test (auto-detect if empty)")
+	cmd.Flags().IntVar(&testConcurrency, "concurrency", 1,
+		"Number of test suites to run in parallel")
+	cmd.Flags().IntVar(&testLoadRate, "rate", 10,
+		"Target requests per second for load tests")
+	cmd.Flags().DurationVar(&testRampUp, "ramp-up", 0,
+		"Time to ramp up to the target load test rate")
+	cmd.Flags().StringVar(&testOutput, "output", outputFormatText,
+		"Output format: text, json, junit")
+	cmd.Flags().StringVar(&testSaveFile, "save", "",
+		"Save results to file (json unless --output is junit)")
 
 	return cmd
 }
 
 func runTestCommand(cmd *cobra.Command, args []string) error {
-	fmt.Printf("🧪 StealthGuard Network Security Test Suite\n")
-	fmt.Printf("==========================================\n\n")
+	switch testOutput {
+	case outputFormatText, testing.OutputFormatJSON, testing.OutputFormatJUnit:
+	default:
+		return fmt.Errorf("unsupported output format: %s", testOutput)
+	}
+
+	if testOutput == outputFormatText {
+		fmt.Printf("🧪 StealthGuard Network Security Test Suite\n")
+		fmt.Printf("==========================================\n\n")
+	}
 
 	return runTests(testType)
 }
@@ -105,7 +137,11 @@ func runTests(testType string) error {
 		IncludeWireGuard:     testType == "wireguard" || testType == "full",
 		IncludeDNSLeak:       testType == "dns-leak" || testType == "full",
 		IncludeKillSwitch:    testType == "kill-switch" || testType == "full",
+		ConcurrentTests:      testConcurrency,
 		Timeout:              testDuration,
+		OutputFormat:         testOutput,
+		SaveResults:          testSaveFile != "",
+		ResultsFile:          testSaveFile,
 	}
 
 	// Initialize test framework
@@ -115,7 +151,12 @@ func runTests(testType string) error {
 		WireGuardTests:       opts.IncludeWireGuard,
 		DNSTests:             opts.IncludeDNSLeak,
 		IntegrationTests:     testType == "full",
+		LoadTests:            testType == "load",
 		Timeout:              testDuration,
+		ConcurrentTests:      opts.ConcurrentTests,
+		LoadRequestRate:      testLoadRate,
+		LoadDuration:         testDuration,
+		LoadRampUp:           testRampUp,
 	}
 
 	if err := framework.Initialize(testConfig); err != nil {
@@ -134,6 +175,8 @@ func runTests(testType string) error {
 		return runDNSLeakTest(framework, opts)
 	case "kill-switch":
 		return runKillSwitchTest(framework, opts)
+	case "load":
+		return runLoadTest(framework, opts)
 	case "full":
 		return runFullTestSuite(framework, opts)
 	default:
@@ -142,8 +185,10 @@ func runTests(testType string) error {
 }
 
 func runCaptivePortalTest(framework *testing.TestFramework, opts *TestOptions) error {
-	fmt.Printf("🔍 Testing Captive Portal Detection\n")
-	fmt.Printf("=====================================\n\n")
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("🔍 Testing Captive Portal Detection\n")
+		fmt.Printf("=====================================\n\n")
+	}
 
 	ctx := context.Background()
 	results, err := framework.RunAllTests(ctx)
@@ -151,13 +196,14 @@ func runCaptivePortalTest(framework *testing.TestFramework, opts *TestOptions) e
 		return fmt.Errorf("captive portal tests failed: %w", err)
 	}
 
-	displayTestResults("Captive Portal Detection", results)
-	return nil
+	return reportTestResults("Captive Portal Detection", results, opts)
 }
 
 func runFailoverTest(framework *testing.TestFramework, opts *TestOptions) error {
-	fmt.Printf("🔄 Testing Network Failover\n")
-	fmt.Printf("============================\n\n")
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("🔄 Testing Network Failover\n")
+		fmt.Printf("============================\n\n")
+	}
 
 	ctx := context.Background()
 	results, err := framework.RunAllTests(ctx)
@@ -165,13 +211,14 @@ func runFailoverTest(framework *testing.TestFramework, opts *TestOptions) error
 		return fmt.Errorf("failover tests failed: %w", err)
 	}
 
-	displayTestResults("Network Failover", results)
-	return nil
+	return reportTestResults("Network Failover", results, opts)
 }
 
 func runWireGuardTest(framework *testing.TestFramework, opts *TestOptions) error {
-	fmt.Printf("🔐 Testing WireGuard Configuration\n")
-	fmt.Printf("==================================\n\n")
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("🔐 Testing WireGuard Configuration\n")
+		fmt.Printf("==================================\n\n")
+	}
 
 	ctx := context.Background()
 	results, err := framework.RunAllTests(ctx)
@@ -179,13 +226,14 @@ func runWireGuardTest(framework *testing.TestFramework, opts *TestOptions) error
 		return fmt.Errorf("wireguard tests failed: %w", err)
 	}
 
-	displayTestResults("WireGuard", results)
-	return nil
+	return reportTestResults("WireGuard", results, opts)
 }
 
 func runDNSLeakTest(framework *testing.TestFramework, opts *TestOptions) error {
-	fmt.Printf("🔍 Testing DNS Leak Prevention\n")
-	fmt.Printf("===============================\n\n")
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("🔍 Testing DNS Leak Prevention\n")
+		fmt.Printf("===============================\n\n")
+	}
 
 	ctx := context.Background()
 	results, err := framework.RunAllTests(ctx)
@@ -193,13 +241,14 @@ func runDNSLeakTest(framework *testing.TestFramework, opts *TestOptions) error {
 		return fmt.Errorf("DNS leak tests failed: %w", err)
 	}
 
-	displayTestResults("DNS Leak Prevention", results)
-	return nil
+	return reportTestResults("DNS Leak Prevention", results, opts)
 }
 
 func runKillSwitchTest(framework *testing.TestFramework, opts *TestOptions) error {
-	fmt.Printf("🛑 Testing Kill-Switch Functionality\n")
-	fmt.Printf("=====================================\n\n")
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("🛑 Testing Kill-Switch Functionality\n")
+		fmt.Printf("=====================================\n\n")
+	}
 
 	ctx := context.Background()
 	results, err := framework.RunAllTests(ctx)
@@ -207,13 +256,29 @@ func runKillSwitchTest(framework *testing.TestFramework, opts *TestOptions) erro
 		return fmt.Errorf("kill-switch tests failed: %w", err)
 	}
 
-	displayTestResults("Kill-Switch", results)
-	return nil
+	return reportTestResults("Kill-Switch", results, opts)
+}
+
+func runLoadTest(framework *testing.TestFramework, opts *TestOptions) error {
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("📈 Running Load Tests\n")
+		fmt.Printf("======================\n\n")
+	}
+
+	ctx := context.Background()
+	results, err := framework.RunAllTests(ctx)
+	if err != nil {
+		return fmt.Errorf("load tests failed: %w", err)
+	}
+
+	return reportTestResults("Load Test", results, opts)
 }
 
 func runFullTestSuite(framework *testing.TestFramework, opts *TestOptions) error {
-	fmt.Printf("🎯 Running Full Test Suite\n")
-	fmt.Printf("===========================\n\n")
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("🎯 Running Full Test Suite\n")
+		fmt.Printf("===========================\n\n")
+	}
 
 	ctx := context.Background()
 	results, err := framework.RunAllTests(ctx)
@@ -221,7 +286,40 @@ func runFullTestSuite(framework *testing.TestFramework, opts *TestOptions) error
 		return fmt.Errorf("full test suite failed: %w", err)
 	}
 
-	displayTestResults("Full Test Suite", results)
+	return reportTestResults("Full Test Suite", results, opts)
+}
+
+// reportTestResults prints the results in the selected output format and
+// saves them if requested
+func reportTestResults(testName string, results *testing.TestResults, opts *TestOptions) error {
+	if opts.OutputFormat == outputFormatText {
+		displayTestResults(testName, results)
+	} else if err := results.WriteResults(os.Stdout, opts.OutputFormat); err != nil {
+		return err
+	}
+
+	if !opts.SaveResults {
+		return nil
+	}
+
+	format := opts.OutputFormat
+	if format == outputFormatText {
+		format = testing.OutputFormatJSON
+	}
+
+	file, err := os.Create(opts.ResultsFile)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %w", err)
+	}
+	defer file.Close()
+
+	if err := results.WriteResults(file, format); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+
+	if opts.OutputFormat == outputFormatText {
+		fmt.Printf("\n💾 Results saved to %s\n", opts.ResultsFile)
+	}
 	return nil
 }
 
@@ -251,6 +349,12 @@ func displayTestResults(testName string, results *testing.TestResults) {
 				fmt.Printf("    Error: %s\n", test.Error)
 			}
 		}
+		if load := suite.LoadTest; load != nil {
+			fmt.Printf("  Requests: %d (%.1f req/s), Error Rate: %.2f%%\n",
+				load.Requests, load.Throughput, load.ErrorRate*100)
+			fmt.Printf("  Latency: p50=%v p95=%v p99=%v\n",
+				load.P50Latency, load.P95Latency, load.P99Latency)
+		}
 	}
 
 	if len(results.Summary.Recommendations) > 0 {
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+var versionJSON bool
+
+// BuildInfo describes the running net-sec build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// NewBuildInfo returns the build information for the given release values
+// and the current runtime
+func NewBuildInfo(version, commit, date string) BuildInfo {
+	return BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
+
+// String returns the single-line form printed by 'net-sec version'
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("net-sec %s (commit: %s, built: %s, %s %s/%s)",
+		b.Version, b.Commit, b.Date, b.GoVersion, b.OS, b.Arch)
+}
+
+// NewVersionCommand creates the 'version' command reporting build information
+func NewVersionCommand(version, commit, date string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print build information",
+		Long: `Print the version, commit and build date of this net-sec binary along with
+the Go version and platform it was built for.
+
+The plain output is a single stable line:
+
+  net-sec <version> (commit: <commit>, built: <date>, <go version> <os>/<arch>)
+
+With --json a JSON object with the fields version, commit, date, goVersion,
+os and arch is printed instead, for use by support tooling.`,
+		Example: `  # Print the version
+  net-sec version
+
+  # Print build information as JSON
+  net-sec version --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := NewBuildInfo(version, commit, date)
+
+			if !versionJSON {
+				fmt.Fprintln(cmd.OutOrStdout(), info)
+				return nil
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(info); err != nil {
+				return fmt.Errorf("failed to encode build info: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&versionJSON, "json", false, "Print build information as JSON")
+
+	return cmd
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func executeVersion(t *testing.T, args ...string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	rootCmd := NewRootCommand("2.3.4", "abc1234", "2024-06-01T12:00:00Z")
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(append([]string{"version"}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("version command failed: %v\n%s", err, out.String())
+	}
+	return out.String()
+}
+
+func TestVersionCommandJSON(t *testing.T) {
+	out := executeVersion(t, "--json")
+
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(out), &fields); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+
+	want := map[string]string{
+		"version":   "2.3.4",
+		"commit":    "abc1234",
+		"date":      "2024-06-01T12:00:00Z",
+		"goVersion": runtime.Version(),
+		"os":        runtime.GOOS,
+		"arch":      runtime.GOARCH,
+	}
+	if len(fields) != len(want) {
+		t.Errorf("expected fields %v, got %v", want, fields)
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, fields[key])
+		}
+	}
+}
+
+func TestVersionCommandPlain(t *testing.T) {
+	out := executeVersion(t)
+
+	want := "net-sec 2.3.4 (commit: abc1234, built: 2024-06-01T12:00:00Z, " + runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH + ")\n"
+	if out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+	if strings.Count(out, "\n") != 1 {
+		t.Errorf("expected a single line, got %q", out)
+	}
+}
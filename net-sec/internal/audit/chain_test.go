@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/rbac"
+)
+
+// writeChainedLog writes count access events to a hash chained audit log
+func writeChainedLog(t *testing.T, path string, count int) {
+	t.Helper()
+
+	sink, err := NewFileAuditLogger(path, &FileAuditOptions{HashChain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: fmt.Sprintf("evt-%d", i), UserID: "alice", Success: true})
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func verifyLines(t *testing.T, lines []string) (bool, int) {
+	t.Helper()
+
+	ok, index, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok, index
+}
+
+func TestVerifyChainValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeChainedLog(t, path, 10)
+
+	ok, index, err := VerifyChainFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || index != -1 {
+		t.Fatalf("expected a valid chain, broken at %d", index)
+	}
+
+	// The anchor plus every event
+	if lines := readLines(t, path); len(lines) != 11 {
+		t.Errorf("expected 11 records, got %d", len(lines))
+	}
+}
+
+func TestVerifyChainDetectsModification(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeChainedLog(t, path, 10)
+
+	lines := readLines(t, path)
+	const tampered = 5
+
+	// Flip a byte inside the event of a record in the middle
+	line := []byte(lines[tampered])
+	i := bytes.Index(line, []byte(`"alice"`)) + 1
+	line[i] ^= 0x01
+	lines[tampered] = string(line)
+
+	if ok, index := verifyLines(t, lines); ok || index != tampered {
+		t.Fatalf("expected verification to fail at %d, got ok=%v index=%d", tampered, ok, index)
+	}
+}
+
+func TestVerifyChainDetectsRemoval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeChainedLog(t, path, 10)
+
+	lines := readLines(t, path)
+	const removed = 4
+	lines = append(lines[:removed], lines[removed+1:]...)
+
+	if ok, index := verifyLines(t, lines); ok || index != removed {
+		t.Fatalf("expected verification to fail at %d, got ok=%v index=%d", removed, ok, index)
+	}
+}
+
+func TestChainResumesAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeChainedLog(t, path, 3)
+	writeChainedLog(t, path, 3)
+
+	ok, index, err := VerifyChainFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("chain broken at %d after reopening", index)
+	}
+
+	// Reopening on the same day must not write a second anchor
+	if lines := readLines(t, path); len(lines) != 7 {
+		t.Errorf("expected 7 records, got %d", len(lines))
+	}
+}
+
+func TestChainWritesDailyAnchors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	now := time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)
+
+	sink, err := NewFileAuditLogger(path, &FileAuditOptions{HashChain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.now = func() time.Time { return now }
+
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "day1-a", Success: true})
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "day1-b", Success: true})
+	now = now.Add(2 * time.Hour)
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "day2-a", Success: true})
+	sink.Close()
+
+	page, err := NewAuditReader(path).Query(AuditFilter{Category: CategoryAudit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 2 {
+		t.Fatalf("expected 2 anchors, got %d", len(page.Records))
+	}
+
+	event, err := page.Records[1].DecodeEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor := event.(*ChainAnchor)
+	if anchor.Date != "2024-06-02" || anchor.PrevAnchor != "2024-06-01" || anchor.Records != 2 {
+		t.Errorf("unexpected anchor: %+v", anchor)
+	}
+
+	// Anchors are hidden from ordinary queries
+	page, err = NewAuditReader(path).Query(AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 {
+		t.Errorf("expected 3 event records, got %d", page.Total)
+	}
+
+	if ok, index, err := VerifyChainFile(path); err != nil || !ok {
+		t.Errorf("chain broken at %d: %v", index, err)
+	}
+}
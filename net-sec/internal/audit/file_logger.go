@@ -0,0 +1,508 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/erasure"
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// Audit record categories, one per subsystem
+const (
+	CategoryPrivacy       = "privacy"
+	CategoryRBAC          = "rbac"
+	CategoryRetention     = "retention"
+	CategoryIntegrations  = "integrations"
+	CategoryErasure       = "erasure"
+	CategorySubjectAccess = "subject_access"
+)
+
+// Audit record severities. High severity records are fsynced before the
+// logging call returns.
+const (
+	SeverityNormal = "normal"
+	SeverityHigh   = "high"
+)
+
+// rotationTimeFormat is the timestamp suffix of rotated audit files
+const rotationTimeFormat = "20060102T150405.000000000Z"
+
+// Record is a single line of the audit log. It wraps the subsystem event
+// with the fields needed to search the log without decoding every event.
+type Record struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Category  string          `json:"category"`
+	Type      string          `json:"type"`
+	Severity  string          `json:"severity"`
+	ID        string          `json:"id,omitempty"`
+	UserID    string          `json:"user_id,omitempty"`
+	SubjectID string          `json:"subject_id,omitempty"` // Data subject the event concerns
+	Success   bool            `json:"success"`
+	Event     json.RawMessage `json:"event"`
+	PrevHash  string          `json:"prev_hash,omitempty"` // Hash of the previous record when chained
+	Hash      string          `json:"hash,omitempty"`      // Hash of this record when chained; always last
+}
+
+// FileAuditOptions configures rotation of a FileAuditLogger
+type FileAuditOptions struct {
+	MaxSize          int64         // Rotate before the file exceeds this many bytes; 0 disables
+	RotationInterval time.Duration // Rotate files older than this; 0 disables
+	FileMode         os.FileMode
+	HashChain        bool // Chain records by hash so tampering is detectable
+}
+
+// DefaultFileAuditOptions returns the default rotation settings
+func DefaultFileAuditOptions() *FileAuditOptions {
+	return &FileAuditOptions{
+		MaxSize:          100 * 1024 * 1024,
+		RotationInterval: 24 * time.Hour,
+		FileMode:         0600,
+	}
+}
+
+// FileAuditLogger persists audit events from every subsystem as JSON lines,
+// providing the records of processing activities required by GDPR Article
+// 30. It implements the privacy, rbac, retention and integrations
+// AuditLogger interfaces and is safe for concurrent use.
+type FileAuditLogger struct {
+	path     string
+	opts     FileAuditOptions
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	mu       sync.Mutex
+	logger   logger.StructuredLogger
+	now      func() time.Time
+	chain    chainState
+}
+
+var (
+	_ privacy.AuditLogger      = (*FileAuditLogger)(nil)
+	_ rbac.AuditLogger         = (*FileAuditLogger)(nil)
+	_ retention.AuditLogger    = (*FileAuditLogger)(nil)
+	_ integrations.AuditLogger = (*FileAuditLogger)(nil)
+)
+
+// NewFileAuditLogger opens the audit log at path for appending, creating it
+// if needed. Default options are used when opts is nil.
+func NewFileAuditLogger(path string, opts *FileAuditOptions) (*FileAuditLogger, error) {
+	if opts == nil {
+		opts = DefaultFileAuditOptions()
+	}
+
+	l := &FileAuditLogger{
+		path:   path,
+		opts:   *opts,
+		logger: logger.Component("audit"),
+		now:    time.Now,
+	}
+	if l.opts.FileMode == 0 {
+		l.opts.FileMode = 0600
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	if l.opts.HashChain {
+		if err := l.loadChainState(); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Path returns the path of the active audit log file
+func (l *FileAuditLogger) Path() string {
+	return l.path
+}
+
+// Sync flushes the active audit log file to disk
+func (l *FileAuditLogger) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Sync()
+}
+
+// Close syncs and closes the audit log
+func (l *FileAuditLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return l.file.Close()
+}
+
+// Write appends a record for event to the audit log
+func (l *FileAuditLogger) Write(record Record, event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	record.Event = data
+	if record.Timestamp.IsZero() {
+		record.Timestamp = l.now()
+	}
+	if record.Severity == "" {
+		record.Severity = SeverityNormal
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.opts.HashChain {
+		if err := l.writeAnchorIfDue(); err != nil {
+			return err
+		}
+	}
+	return l.writeRecord(record)
+}
+
+// writeRecord encodes and appends a record. The caller must hold l.mu.
+func (l *FileAuditLogger) writeRecord(record Record) error {
+	var line []byte
+	var hash string
+	var err error
+
+	if l.opts.HashChain {
+		line, hash, err = encodeChained(record, l.chain.head)
+	} else {
+		line, err = json.Marshal(record)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.shouldRotate(int64(len(line))) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if l.opts.HashChain {
+		l.chain.head = hash
+		l.chain.sinceAnchor++
+	}
+
+	if record.Severity == SeverityHigh {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync audit log: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// open opens the audit log file and records its current size
+func (l *FileAuditLogger) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, l.opts.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	l.openedAt = l.now()
+	return nil
+}
+
+// shouldRotate reports whether writing n more bytes requires a new file.
+// A record is never split, so a single record larger than MaxSize is still
+// written to an empty file.
+func (l *FileAuditLogger) shouldRotate(n int64) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.opts.MaxSize > 0 && l.size+n > l.opts.MaxSize {
+		return true
+	}
+	return l.opts.RotationInterval > 0 && l.now().Sub(l.openedAt) >= l.opts.RotationInterval
+}
+
+// rotate renames the active file with a timestamp suffix and opens a new one
+func (l *FileAuditLogger) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	rotated := l.path + "." + l.now().UTC().Format(rotationTimeFormat)
+	for i := 1; fileExists(rotated); i++ {
+		rotated = fmt.Sprintf("%s.%s.%d", l.path, l.now().UTC().Format(rotationTimeFormat), i)
+	}
+	if err := os.Rename(l.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return l.open()
+}
+
+// record writes an event for an interface method without an error return,
+// reporting failures through the structured logger
+func (l *FileAuditLogger) record(record Record, event interface{}) {
+	if err := l.Write(record, event); err != nil {
+		l.logger.Error("Failed to write audit record", logger.Fields{
+			"event_type": record.Type,
+			"audit_id":   record.ID,
+			"error":      err,
+		})
+	}
+}
+
+// severity returns the severity of an event by its outcome
+func severity(success bool) string {
+	if success {
+		return SeverityNormal
+	}
+	return SeverityHigh
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// LogPseudonymization records a pseudonymization event
+func (l *FileAuditLogger) LogPseudonymization(event privacy.PseudonymizationEvent) error {
+	return l.Write(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryPrivacy,
+		Type:      "pseudonymization",
+		Severity:  severity(event.Success),
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogKeyRotation records a key rotation event
+func (l *FileAuditLogger) LogKeyRotation(event privacy.KeyRotationEvent) error {
+	return l.Write(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryPrivacy,
+		Type:      "key_rotation",
+		Severity:  SeverityHigh,
+		ID:        event.ID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogDataAccess records a personal data access event
+func (l *FileAuditLogger) LogDataAccess(event privacy.DataAccessEvent) error {
+	return l.Write(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryPrivacy,
+		Type:      "data_access",
+		Severity:  severity(event.Success),
+		ID:        event.ID,
+		UserID:    event.UserID,
+		SubjectID: event.DataSubject,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogAccessAttempt records an access control decision
+func (l *FileAuditLogger) LogAccessAttempt(event rbac.AccessAuditEvent) {
+	sev := severity(event.Success)
+	if event.RiskLevel == "high" || event.RiskLevel == "critical" {
+		sev = SeverityHigh
+	}
+
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryRBAC,
+		Type:      "access_attempt",
+		Severity:  sev,
+		ID:        event.ID,
+		UserID:    event.UserID,
+		SubjectID: event.DataSubjectID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogPermissionCheck records a permission check
+func (l *FileAuditLogger) LogPermissionCheck(event rbac.PermissionAuditEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryRBAC,
+		Type:      "permission_check",
+		Severity:  severity(event.Granted),
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Success:   event.Granted,
+	}, event)
+}
+
+// LogPrivilegeEscalation records a privilege escalation
+func (l *FileAuditLogger) LogPrivilegeEscalation(event rbac.PrivilegeEscalationEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryRBAC,
+		Type:      "privilege_escalation",
+		Severity:  SeverityHigh,
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogSessionEvent records a session lifecycle event
+func (l *FileAuditLogger) LogSessionEvent(event rbac.SessionAuditEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryRBAC,
+		Type:      "session_" + event.EventType,
+		Severity:  SeverityNormal,
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Success:   true,
+	}, event)
+}
+
+// LogRetentionEvent records a retention policy or purge event
+func (l *FileAuditLogger) LogRetentionEvent(event retention.RetentionAuditEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryRetention,
+		Type:      event.EventType,
+		Severity:  severity(event.Success),
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogPurgeJob records the state of a purge job. Purges delete personal data
+// and are always high severity.
+func (l *FileAuditLogger) LogPurgeJob(job *retention.PurgeJob) {
+	l.record(Record{
+		Category: CategoryRetention,
+		Type:     "purge_job_" + job.Status,
+		Severity: SeverityHigh,
+		ID:       job.ID,
+		Success:  job.Status != "failed",
+	}, job)
+}
+
+// LogLegalHold records an action on a legal hold
+func (l *FileAuditLogger) LogLegalHold(hold *retention.LegalHold, action string) {
+	l.record(Record{
+		Category: CategoryRetention,
+		Type:     "legal_hold_" + action,
+		Severity: SeverityHigh,
+		ID:       hold.ID,
+		UserID:   hold.CreatedBy,
+		Success:  true,
+	}, hold)
+}
+
+// LogIntegrationEvent records an integration operation
+func (l *FileAuditLogger) LogIntegrationEvent(event integrations.IntegrationAuditEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryIntegrations,
+		Type:      "integration_" + event.Operation,
+		Severity:  severity(event.Success),
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogDataTransfer records a data transfer between integrations
+func (l *FileAuditLogger) LogDataTransfer(event integrations.DataTransferEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryIntegrations,
+		Type:      "data_transfer",
+		Severity:  severity(event.Success),
+		ID:        event.ID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogPersonalDataAccess records personal data access by an external system
+func (l *FileAuditLogger) LogPersonalDataAccess(event integrations.PersonalDataAccessEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategoryIntegrations,
+		Type:      "personal_data_" + event.AccessType,
+		Severity:  severity(event.Success),
+		ID:        event.ID,
+		UserID:    event.UserID,
+		SubjectID: event.DataSubjectID,
+		Success:   event.Success,
+	}, event)
+}
+
+// LogErasureRequest records the consolidated outcome of an erasure request
+func (l *FileAuditLogger) LogErasureRequest(report *erasure.ErasureReport) {
+	l.record(Record{
+		Timestamp: report.CompletedAt,
+		Category:  CategoryErasure,
+		Type:      "erasure_" + report.Status,
+		Severity:  SeverityHigh,
+		ID:        report.ID,
+		UserID:    report.RequestedBy,
+		SubjectID: report.SubjectID,
+		Success:   report.Status == erasure.StatusCompleted,
+	}, report)
+}
+
+// SubjectAccessEvent records that a subject access request (GDPR Article
+// 15) was processed and what it disclosed
+type SubjectAccessEvent struct {
+	ID                 string            `json:"id"`
+	Timestamp          time.Time         `json:"timestamp"`
+	SubjectID          string            `json:"subject_id"`
+	RequestedBy        string            `json:"requested_by"`
+	ConsentRecords     int               `json:"consent_records"`
+	RetentionPolicies  int               `json:"retention_policies"`
+	AuditEvents        int               `json:"audit_events"`
+	IntegrationRecords int               `json:"integration_records"`
+	StoredRecords      int               `json:"stored_records"`
+	SourceErrors       map[string]string `json:"source_errors,omitempty"`
+}
+
+// LogSubjectAccessRequest records a processed subject access request
+func (l *FileAuditLogger) LogSubjectAccessRequest(event SubjectAccessEvent) {
+	l.record(Record{
+		Timestamp: event.Timestamp,
+		Category:  CategorySubjectAccess,
+		Type:      "subject_access_request",
+		Severity:  SeverityHigh,
+		ID:        event.ID,
+		UserID:    event.RequestedBy,
+		SubjectID: event.SubjectID,
+		Success:   len(event.SourceErrors) == 0,
+	}, event)
+}
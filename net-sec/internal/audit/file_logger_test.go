@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// readAllRecords reads the records of every audit file in dir
+func readAllRecords(t *testing.T, dir string) []Record {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "audit.log*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []Record
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("%s: invalid record %q: %v", path, scanner.Text(), err)
+			}
+			records = append(records, record)
+		}
+		file.Close()
+	}
+	return records
+}
+
+func TestFileAuditLoggerConcurrentWriters(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileAuditLogger(filepath.Join(dir, "audit.log"), &FileAuditOptions{MaxSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				id := fmt.Sprintf("w%d-%d", w, i)
+				switch i % 4 {
+				case 0:
+					sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: id, UserID: "user", Success: i%8 == 0})
+				case 1:
+					sink.LogRetentionEvent(retention.RetentionAuditEvent{ID: id, EventType: "job_scheduled", Success: true})
+				case 2:
+					sink.LogPersonalDataAccess(integrations.PersonalDataAccessEvent{ID: id, AccessType: "read", Success: true})
+				default:
+					if err := sink.LogPseudonymization(privacy.PseudonymizationEvent{ID: id, Success: true}); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readAllRecords(t, dir)
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if seen[record.ID] {
+			t.Errorf("duplicate record %s", record.ID)
+		}
+		seen[record.ID] = true
+	}
+	if len(seen) != writers*perWriter {
+		t.Fatalf("expected %d records, got %d", writers*perWriter, len(seen))
+	}
+}
+
+func TestFileAuditLoggerRotatesAtMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+
+	const maxSize = 1024
+	sink, err := NewFileAuditLogger(path, &FileAuditOptions{MaxSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 40; i++ {
+		sink.LogSessionEvent(rbac.SessionAuditEvent{ID: fmt.Sprintf("evt-%d", i), UserID: "user", EventType: "created"})
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(path + "*")
+	if len(files) < 2 {
+		t.Fatalf("expected rotated files, got %v", files)
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > maxSize {
+			t.Errorf("%s is %d bytes, exceeding MaxSize %d", file, info.Size(), maxSize)
+		}
+		if file != path && !strings.HasPrefix(filepath.Base(file), "audit.log.") {
+			t.Errorf("unexpected rotated file name %s", file)
+		}
+	}
+
+	if records := readAllRecords(t, dir); len(records) != 40 {
+		t.Errorf("expected 40 records across files, got %d", len(records))
+	}
+}
+
+func TestFileAuditLoggerRotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	sink, err := NewFileAuditLogger(filepath.Join(dir, "audit.log"), &FileAuditOptions{RotationInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.now = func() time.Time { return now }
+	sink.openedAt = now
+
+	sink.LogLegalHold(&retention.LegalHold{ID: "hold-1"}, "created")
+	now = now.Add(2 * time.Hour)
+	sink.LogLegalHold(&retention.LegalHold{ID: "hold-2"}, "created")
+	sink.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit.log*"))
+	if len(files) != 2 {
+		t.Fatalf("expected one rotation, got %v", files)
+	}
+}
+
+func TestFileAuditLoggerRecordFields(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileAuditLogger(filepath.Join(dir, "audit.log"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{
+		ID:           "audit_1",
+		Timestamp:    timestamp,
+		UserID:       "user_1",
+		Resource:     "personal_data",
+		Success:      false,
+		DenialReason: "mfa_required",
+	})
+	sink.Close()
+
+	records := readAllRecords(t, dir)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	record := records[0]
+	if record.Category != CategoryRBAC || record.Type != "access_attempt" || record.Severity != SeverityHigh {
+		t.Errorf("unexpected record header: %+v", record)
+	}
+	if !record.Timestamp.Equal(timestamp) || record.UserID != "user_1" || record.Success {
+		t.Errorf("unexpected record fields: %+v", record)
+	}
+
+	var event rbac.AccessAuditEvent
+	if err := json.Unmarshal(record.Event, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.DenialReason != "mfa_required" {
+		t.Errorf("event not preserved: %+v", event)
+	}
+}
@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/erasure"
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// maxRecordSize bounds the length of a single audit log line
+const maxRecordSize = 1024 * 1024
+
+// AuditFilter selects audit records. Zero-valued fields match everything,
+// except that hash chain anchors are only returned when Category is
+// CategoryAudit.
+type AuditFilter struct {
+	Since     time.Time // Inclusive
+	Until     time.Time // Exclusive
+	UserID    string
+	SubjectID string
+	Category  string
+	Types     []string
+	Success   *bool
+
+	Offset int
+	Limit  int // 0 returns every remaining record
+}
+
+// AuditPage is one page of query results in chronological order
+type AuditPage struct {
+	Records    []Record `json:"records"`
+	Total      int      `json:"total"` // Matching records across all pages
+	NextOffset int      `json:"next_offset,omitempty"`
+	HasMore    bool     `json:"has_more"`
+}
+
+// AuditReader searches the audit log written by a FileAuditLogger,
+// including its rotated files
+type AuditReader struct {
+	path string
+}
+
+// NewAuditReader creates a reader for the audit log at path
+func NewAuditReader(path string) *AuditReader {
+	return &AuditReader{path: path}
+}
+
+// Query returns the records matching filter, oldest first
+func (r *AuditReader) Query(filter AuditFilter) (*AuditPage, error) {
+	files, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []Record
+	for _, path := range files {
+		records, err := readRecords(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if filter.matches(record) {
+				matched = append(matched, record)
+			}
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	page := &AuditPage{Total: len(matched)}
+	if filter.Offset >= len(matched) {
+		return page, nil
+	}
+
+	end := len(matched)
+	if filter.Limit > 0 && filter.Offset+filter.Limit < end {
+		end = filter.Offset + filter.Limit
+		page.HasMore = true
+		page.NextOffset = end
+	}
+	page.Records = matched[filter.Offset:end]
+
+	return page, nil
+}
+
+// files returns the rotated audit files followed by the active one
+func (r *AuditReader) files() ([]string, error) {
+	rotated, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit files: %w", err)
+	}
+	sort.Strings(rotated)
+
+	if fileExists(r.path) {
+		rotated = append(rotated, r.path)
+	}
+	return rotated, nil
+}
+
+// readRecords parses every record of an audit file. A torn last record, left
+// by a crash mid-write, is skipped; an invalid record anywhere else is an
+// error.
+func readRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	var torn error
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if torn != nil {
+			return nil, torn
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			torn = fmt.Errorf("%s:%d: invalid audit record: %w", path, line, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+
+	return records, nil
+}
+
+// matches reports whether record satisfies the filter
+func (f *AuditFilter) matches(record Record) bool {
+	if !f.Since.IsZero() && record.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !record.Timestamp.Before(f.Until) {
+		return false
+	}
+	if f.UserID != "" && record.UserID != f.UserID {
+		return false
+	}
+	if f.SubjectID != "" && record.SubjectID != f.SubjectID {
+		return false
+	}
+	if f.Category != "" && record.Category != f.Category {
+		return false
+	}
+	if f.Category == "" && record.Category == CategoryAudit {
+		return false
+	}
+	if f.Success != nil && record.Success != *f.Success {
+		return false
+	}
+	if len(f.Types) > 0 {
+		for _, t := range f.Types {
+			if record.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// DecodeEvent decodes the wrapped event into the subsystem type it was
+// logged as, returned as a pointer such as *rbac.AccessAuditEvent
+func (r Record) DecodeEvent() (interface{}, error) {
+	var event interface{}
+
+	switch {
+	case r.Category == CategoryAudit && r.Type == TypeChainAnchor:
+		event = &ChainAnchor{}
+	case r.Category == CategoryPrivacy && r.Type == "pseudonymization":
+		event = &privacy.PseudonymizationEvent{}
+	case r.Category == CategoryPrivacy && r.Type == "key_rotation":
+		event = &privacy.KeyRotationEvent{}
+	case r.Category == CategoryPrivacy && r.Type == "data_access":
+		event = &privacy.DataAccessEvent{}
+	case r.Category == CategoryRBAC && r.Type == "access_attempt":
+		event = &rbac.AccessAuditEvent{}
+	case r.Category == CategoryRBAC && r.Type == "permission_check":
+		event = &rbac.PermissionAuditEvent{}
+	case r.Category == CategoryRBAC && r.Type == "privilege_escalation":
+		event = &rbac.PrivilegeEscalationEvent{}
+	case r.Category == CategoryRBAC && strings.HasPrefix(r.Type, "session_"):
+		event = &rbac.SessionAuditEvent{}
+	case r.Category == CategoryRetention && strings.HasPrefix(r.Type, "purge_job_"):
+		event = &retention.PurgeJob{}
+	case r.Category == CategoryRetention && strings.HasPrefix(r.Type, "legal_hold_"):
+		event = &retention.LegalHold{}
+	case r.Category == CategoryRetention:
+		event = &retention.RetentionAuditEvent{}
+	case r.Category == CategoryIntegrations && r.Type == "data_transfer":
+		event = &integrations.DataTransferEvent{}
+	case r.Category == CategoryIntegrations && strings.HasPrefix(r.Type, "personal_data_"):
+		event = &integrations.PersonalDataAccessEvent{}
+	case r.Category == CategoryIntegrations && strings.HasPrefix(r.Type, "integration_"):
+		event = &integrations.IntegrationAuditEvent{}
+	case r.Category == CategoryErasure:
+		event = &erasure.ErasureReport{}
+	case r.Category == CategorySubjectAccess:
+		event = &SubjectAccessEvent{}
+	default:
+		return nil, fmt.Errorf("unknown audit record type %s/%s", r.Category, r.Type)
+	}
+
+	if err := json.Unmarshal(r.Event, event); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event: %w", r.Type, err)
+	}
+	return event, nil
+}
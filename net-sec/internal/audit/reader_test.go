@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+var base = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// seedAuditLog writes mixed events, deliberately out of chronological order
+// and across rotated files
+func seedAuditLog(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditLogger(path, &FileAuditOptions{MaxSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a3", Timestamp: at(30), UserID: "alice", Resource: "personal_data", Success: true})
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a1", Timestamp: at(0), UserID: "alice", Resource: "personal_data", Success: true})
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a2", Timestamp: at(10), UserID: "bob", Resource: "audit_logs", Success: false})
+	sink.LogSessionEvent(rbac.SessionAuditEvent{ID: "s1", Timestamp: at(5), UserID: "alice", EventType: "created"})
+	sink.LogRetentionEvent(retention.RetentionAuditEvent{ID: "r1", Timestamp: at(20), EventType: "purge_completed", Success: true})
+	sink.LogPersonalDataAccess(integrations.PersonalDataAccessEvent{ID: "p1", Timestamp: at(25), UserID: "bob", DataSubjectID: "subject-1", AccessType: "read", Success: true})
+	sink.LogPersonalDataAccess(integrations.PersonalDataAccessEvent{ID: "p2", Timestamp: at(40), UserID: "alice", DataSubjectID: "subject-2", AccessType: "delete", Success: true})
+	if err := sink.LogDataAccess(privacy.DataAccessEvent{ID: "d1", Timestamp: at(15), UserID: "carol", DataSubject: "subject-1", Operation: "read", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func recordIDs(page *AuditPage) []string {
+	ids := make([]string, 0, len(page.Records))
+	for _, record := range page.Records {
+		ids = append(ids, record.ID)
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, name string, page *AuditPage, expected ...string) {
+	t.Helper()
+
+	got := recordIDs(page)
+	if len(got) != len(expected) {
+		t.Errorf("%s: got %v, want %v", name, got, expected)
+		return
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("%s: got %v, want %v", name, got, expected)
+			return
+		}
+	}
+}
+
+func TestAuditReaderFilters(t *testing.T) {
+	reader := NewAuditReader(seedAuditLog(t))
+	failed := false
+
+	tests := []struct {
+		name     string
+		filter   AuditFilter
+		expected []string
+	}{
+		{"all", AuditFilter{}, []string{"a1", "s1", "a2", "d1", "r1", "p1", "a3", "p2"}},
+		{"user", AuditFilter{UserID: "alice"}, []string{"a1", "s1", "a3", "p2"}},
+		{"subject", AuditFilter{SubjectID: "subject-1"}, []string{"d1", "p1"}},
+		{"type", AuditFilter{Types: []string{"access_attempt"}}, []string{"a1", "a2", "a3"}},
+		{"category", AuditFilter{Category: CategoryIntegrations}, []string{"p1", "p2"}},
+		{"failures", AuditFilter{Success: &failed}, []string{"a2"}},
+		{"time range", AuditFilter{Since: base.Add(10 * time.Minute), Until: base.Add(30 * time.Minute)}, []string{"a2", "d1", "r1", "p1"}},
+		{"combined", AuditFilter{UserID: "alice", Types: []string{"access_attempt", "personal_data_delete"}, Since: base.Add(time.Minute)}, []string{"a3", "p2"}},
+	}
+
+	for _, tt := range tests {
+		page, err := reader.Query(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		assertIDs(t, tt.name, page, tt.expected...)
+		if page.Total != len(tt.expected) {
+			t.Errorf("%s: total %d, want %d", tt.name, page.Total, len(tt.expected))
+		}
+	}
+}
+
+func TestAuditReaderPagination(t *testing.T) {
+	reader := NewAuditReader(seedAuditLog(t))
+
+	page, err := reader.Query(AuditFilter{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, "first page", page, "a1", "s1", "a2")
+	if !page.HasMore || page.NextOffset != 3 || page.Total != 8 {
+		t.Errorf("unexpected first page metadata: %+v", page)
+	}
+
+	page, err = reader.Query(AuditFilter{Limit: 3, Offset: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, "last page", page, "a3", "p2")
+	if page.HasMore {
+		t.Error("last page should not have more records")
+	}
+
+	page, err = reader.Query(AuditFilter{Offset: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 0 || page.Total != 8 {
+		t.Errorf("expected an empty page past the end, got %+v", page)
+	}
+}
+
+func TestAuditReaderSkipsTornLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditLogger(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a1", Timestamp: base, UserID: "alice", Success: true})
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a2", Timestamp: base.Add(time.Minute), UserID: "bob", Success: true})
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// A crash mid-write leaves the last record cut short
+	torn := lines[0] + lines[1][:len(lines[1])/2]
+	if err := os.WriteFile(path, []byte(torn), 0600); err != nil {
+		t.Fatal(err)
+	}
+	page, err := NewAuditReader(path).Query(AuditFilter{})
+	if err != nil {
+		t.Fatalf("expected the torn record to be skipped, got %v", err)
+	}
+	assertIDs(t, "torn", page, "a1")
+
+	// Corruption followed by further records is still an error
+	corrupt := lines[0][:len(lines[0])/2] + "\n" + lines[1]
+	if err := os.WriteFile(path, []byte(corrupt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAuditReader(path).Query(AuditFilter{}); err == nil || !strings.Contains(err.Error(), "audit.log:1: invalid audit record") {
+		t.Errorf("expected corruption mid-file to be reported, got %v", err)
+	}
+}
+
+func TestRecordDecodeEvent(t *testing.T) {
+	reader := NewAuditReader(seedAuditLog(t))
+
+	page, err := reader.Query(AuditFilter{SubjectID: "subject-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, record := range page.Records {
+		event, err := record.DecodeEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e := event.(type) {
+		case *privacy.DataAccessEvent:
+			if e.UserID != "carol" {
+				t.Errorf("unexpected event: %+v", e)
+			}
+		case *integrations.PersonalDataAccessEvent:
+			if e.AccessType != "read" {
+				t.Errorf("unexpected event: %+v", e)
+			}
+		default:
+			t.Errorf("unexpected event type %T", event)
+		}
+	}
+}
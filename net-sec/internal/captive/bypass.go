@@ -0,0 +1,122 @@
+package captive
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// BypassOptions configures the opt-in captive portal bypass techniques
+type BypassOptions struct {
+	// SpoofHeaders are header sets tried in order, e.g. a whitelisted device
+	// identifier or a forwarded-for address the portal trusts
+	SpoofHeaders []map[string]string
+	// WalledGardenHosts are hosts the portal lets through unauthenticated;
+	// each is tried as the Host header of the probe
+	WalledGardenHosts []string
+	// UseDoH connects directly to the address returned by the trusted
+	// resolver, bypassing portals that rely on DNS interception
+	UseDoH    bool
+	Timeout   time.Duration
+	UserAgent string
+}
+
+// DefaultBypassOptions returns bypass options with common walled-garden hosts
+func DefaultBypassOptions() *BypassOptions {
+	return &BypassOptions{
+		WalledGardenHosts: []string{
+			"captive.apple.com",
+			"connectivitycheck.gstatic.com",
+			"www.msftconnecttest.com",
+		},
+		UseDoH:    true,
+		Timeout:   10 * time.Second,
+		UserAgent: "Mozilla/5.0 (compatible; net-sec/1.0)",
+	}
+}
+
+// EnableBypass turns on captive portal bypass attempts. Bypass is disabled
+// unless this is called.
+func (d *Detector) EnableBypass(opts *BypassOptions) {
+	if opts == nil {
+		opts = DefaultBypassOptions()
+	}
+	d.bypass = opts
+}
+
+// bypassTechnique is a named variation of the detection probe
+type bypassTechnique struct {
+	name string
+	opts *DetectorOptions
+}
+
+// AttemptBypass tries each configured bypass technique against the portal
+// that produced result. A technique succeeds when re-running detection with
+// it no longer observes a captive portal. The result is updated with the
+// attempt and the technique that worked.
+func (d *Detector) AttemptBypass(ctx context.Context, result *DetectionResult) (bool, error) {
+	if d.bypass == nil {
+		return false, fmt.Errorf("captive portal bypass is not enabled")
+	}
+	if result == nil || !result.CaptivePortalDetected {
+		return false, fmt.Errorf("no captive portal detected to bypass")
+	}
+
+	result.BypassAttempted = true
+
+	for _, technique := range d.bypassTechniques(ctx, result) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		check, err := d.detect(ctx, technique.opts)
+		if err != nil || check.CaptivePortalDetected {
+			continue
+		}
+
+		result.BypassTechnique = technique.name
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// bypassTechniques builds the probes to try for the configured techniques
+func (d *Detector) bypassTechniques(ctx context.Context, result *DetectionResult) []bypassTechnique {
+	base := DetectorOptions{
+		TestURL:        result.TestURL,
+		ExpectedStatus: result.ExpectedStatus,
+		Timeout:        d.bypass.Timeout,
+		UserAgent:      d.bypass.UserAgent,
+	}
+	if base.Timeout == 0 {
+		base.Timeout = 10 * time.Second
+	}
+
+	var techniques []bypassTechnique
+
+	for _, headers := range d.bypass.SpoofHeaders {
+		opts := base
+		opts.Headers = headers
+		techniques = append(techniques, bypassTechnique{name: "header_spoofing", opts: &opts})
+	}
+
+	for _, host := range d.bypass.WalledGardenHosts {
+		opts := base
+		opts.Host = host
+		techniques = append(techniques, bypassTechnique{name: "walled_garden:" + host, opts: &opts})
+	}
+
+	if d.bypass.UseDoH && d.trustedResolver != nil {
+		if u, err := url.Parse(result.TestURL); err == nil {
+			if ips, err := d.trustedResolver.LookupHost(ctx, u.Hostname()); err == nil && len(ips) > 0 {
+				opts := base
+				opts.DialAddress = ips[0]
+				techniques = append(techniques, bypassTechnique{name: "doh_direct", opts: &opts})
+			}
+		}
+	}
+
+	return techniques
+}
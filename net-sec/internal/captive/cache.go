@@ -0,0 +1,72 @@
+package captive
+
+import (
+	"sync"
+	"time"
+)
+
+// cachedResult is a detection result with its expiry time
+type cachedResult struct {
+	result    DetectionResult
+	expiresAt time.Time
+}
+
+// resultCache stores detection results keyed by test URL
+type resultCache struct {
+	ttl        time.Duration
+	entries    map[string]cachedResult
+	lastStatus map[string]bool
+	mu         sync.Mutex
+}
+
+// newResultCache creates a result cache with the given TTL
+func newResultCache(ttl time.Duration) *resultCache {
+	return &resultCache{
+		ttl:        ttl,
+		entries:    make(map[string]cachedResult),
+		lastStatus: make(map[string]bool),
+	}
+}
+
+// get returns a copy of the cached result for testURL, or nil if there is no
+// unexpired entry
+func (c *resultCache) get(testURL string) *DetectionResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[testURL]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, testURL)
+		return nil
+	}
+
+	result := entry.result
+	result.FromCache = true
+	result.StatusChanged = false
+	return &result
+}
+
+// update records a fresh result, setting its StatusChanged flag. A status
+// transition drops the cached entry so the next check re-probes and confirms
+// the new state instead of serving it from cache.
+func (c *resultCache) update(result *DetectionResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, seen := c.lastStatus[result.TestURL]
+	result.StatusChanged = seen && previous != result.CaptivePortalDetected
+	c.lastStatus[result.TestURL] = result.CaptivePortalDetected
+
+	if result.StatusChanged {
+		delete(c.entries, result.TestURL)
+		return
+	}
+
+	c.entries[result.TestURL] = cachedResult{
+		result:    *result,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
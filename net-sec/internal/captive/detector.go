@@ -2,6 +2,7 @@ package captive
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -9,13 +10,19 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 // Detector handles captive portal detection
 type Detector struct {
-	client    *http.Client
-	dnsClient *net.Resolver
+	client          *http.Client
+	dnsClient       Resolver
+	trustedResolver Resolver
+	bypass          *BypassOptions
+	cache           *resultCache
+	fingerprints    []PortalFingerprint
 }
 
 // DetectorOptions contains detection configuration options
@@ -26,28 +33,73 @@ type DetectorOptions struct {
 	UserAgent       string
 	FollowRedirects bool
 	CheckDNS        bool
+	Headers         map[string]string  // Extra request headers
+	Host            string             // Overrides the Host header when set
+	DialAddress     string             // Connect to this IP instead of resolving the test URL host
+	Force           bool               // Skip the result cache and always probe the network
+	HTTPSProbe      *HTTPSProbeOptions // Optional TLS interception check
 }
 
 // DetectionResult contains the results of captive portal detection
 type DetectionResult struct {
-	CaptivePortalDetected bool          `json:"captive_portal_detected"`
-	TestURL               string        `json:"test_url"`
-	HTTPStatus            int           `json:"http_status"`
-	ExpectedStatus        int           `json:"expected_status"`
-	ResponseTime          time.Duration `json:"response_time"`
-	ContentLength         int64         `json:"content_length"`
-	RedirectURL           string        `json:"redirect_url,omitempty"`
-	DNSResolution         *DNSResult    `json:"dns_resolution,omitempty"`
-	PortalInfo            *PortalInfo   `json:"portal_info,omitempty"`
-	StatusChanged         bool          `json:"status_changed"`
-	Error                 string        `json:"error,omitempty"`
+	CaptivePortalDetected bool               `json:"captive_portal_detected"`
+	TestURL               string             `json:"test_url"`
+	HTTPStatus            int                `json:"http_status"`
+	ExpectedStatus        int                `json:"expected_status"`
+	ResponseTime          time.Duration      `json:"response_time"`
+	ContentLength         int64              `json:"content_length"`
+	RedirectURL           string             `json:"redirect_url,omitempty"`
+	RedirectChain         []string           `json:"redirect_chain,omitempty"`
+	DNSResolution         *DNSResult         `json:"dns_resolution,omitempty"`
+	PortalInfo            *PortalInfo        `json:"portal_info,omitempty"`
+	StatusChanged         bool               `json:"status_changed"`
+	Error                 string             `json:"error,omitempty"`
+	ErrorCategory         ErrorCategory      `json:"error_category,omitempty"`
+	BypassAttempted       bool               `json:"bypass_attempted,omitempty"`
+	BypassTechnique       string             `json:"bypass_technique,omitempty"`
+	FromCache             bool               `json:"from_cache,omitempty"`
+	TLSProbe              *TLSProbeResult    `json:"tls_probe,omitempty"`
+	URLResults            []*DetectionResult `json:"url_results,omitempty"`
+}
+
+// ErrorCategory classifies why a detection probe could not complete
+type ErrorCategory string
+
+const (
+	ErrorTimeout            ErrorCategory = "timeout"
+	ErrorConnectionRefused  ErrorCategory = "connection_refused"
+	ErrorDNSFailure         ErrorCategory = "dns_failure"
+	ErrorNetworkUnreachable ErrorCategory = "network_unreachable"
+	ErrorCanceled           ErrorCategory = "canceled"
+	ErrorRequestFailed      ErrorCategory = "request_failed"
+)
+
+// DetectionError is returned when a probe fails before any HTTP response is
+// received. Such failures indicate a connectivity problem, not a captive portal.
+type DetectionError struct {
+	URL      string
+	Category ErrorCategory
+	Err      error
+}
+
+// Error implements the error interface
+func (e *DetectionError) Error() string {
+	return fmt.Sprintf("captive portal probe %s failed (%s): %v", e.URL, e.Category, e.Err)
+}
+
+// Unwrap returns the underlying network error
+func (e *DetectionError) Unwrap() error {
+	return e.Err
 }
 
 // DNSResult contains DNS resolution information
 type DNSResult struct {
-	IPs      []string      `json:"ips"`
-	Hijacked bool          `json:"hijacked"`
-	Duration time.Duration `json:"duration"`
+	IPs          []string      `json:"ips"`
+	TrustedIPs   []string      `json:"trusted_ips,omitempty"`
+	Diverged     bool          `json:"diverged,omitempty"` // Answers share no network with TrustedIPs
+	Hijacked     bool          `json:"hijacked"`
+	HijackReason string        `json:"hijack_reason,omitempty"`
+	Duration     time.Duration `json:"duration"`
 }
 
 // PortalInfo contains information about detected captive portal
@@ -56,8 +108,12 @@ type PortalInfo struct {
 	Title         string `json:"title"`
 	LoginRequired bool   `json:"login_required"`
 	Provider      string `json:"provider,omitempty"`
+	Vendor        string `json:"vendor,omitempty"`
 }
 
+// maxRedirectHops limits how many redirects are recorded for a single probe
+const maxRedirectHops = 10
+
 // NewDetector creates a new captive portal detector
 func NewDetector() *Detector {
 	// Create HTTP client with custom transport
@@ -90,35 +146,172 @@ func NewDetector() *Detector {
 	}
 
 	return &Detector{
-		client:    client,
-		dnsClient: dnsClient,
+		client:       client,
+		dnsClient:    &netResolver{resolver: dnsClient},
+		fingerprints: DefaultFingerprints(),
 	}
 }
 
+// SetSystemResolver replaces the resolver used for the local DNS lookup
+func (d *Detector) SetSystemResolver(r Resolver) {
+	d.dnsClient = r
+}
+
+// SetTrustedResolver sets the resolver that system DNS answers are compared
+// against, such as NewDoHResolver(DefaultDoHEndpoint). The cross-check is
+// opt-in: with a nil resolver, the default, no lookup is sent to a third
+// party.
+func (d *Detector) SetTrustedResolver(r Resolver) {
+	d.trustedResolver = r
+}
+
 // Detect performs captive portal detection
 func (d *Detector) Detect(opts *DetectorOptions) (*DetectionResult, error) {
+	return d.DetectContext(context.Background(), opts)
+}
+
+// DetectContext performs captive portal detection, abandoning in-flight
+// probes when ctx is cancelled
+func (d *Detector) DetectContext(ctx context.Context, opts *DetectorOptions) (*DetectionResult, error) {
+	if d.cache == nil {
+		return d.detect(ctx, opts)
+	}
+
+	if !opts.Force {
+		if cached := d.cache.get(opts.TestURL); cached != nil {
+			return cached, nil
+		}
+	}
+
+	result, err := d.detect(ctx, opts)
+	if err != nil {
+		return result, err
+	}
+
+	d.cache.update(result)
+	return result, nil
+}
+
+// EnableCache caches detection results per test URL for ttl so repeated
+// checks don't re-probe the network. Results also report StatusChanged
+// relative to the previous observation of the same URL.
+func (d *Detector) EnableCache(ttl time.Duration) {
+	d.cache = newResultCache(ttl)
+}
+
+// DetectMulti probes every URL concurrently and decides by consensus: a
+// captive portal is reported only when a majority of the endpoints disagree
+// with the expected result, so a single blocked or misbehaving endpoint
+// cannot flip the outcome. Per-URL results are kept in URLResults.
+func (d *Detector) DetectMulti(ctx context.Context, urls []string, opts *DetectorOptions) (*DetectionResult, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no test URLs provided")
+	}
+
+	results := make([]*DetectionResult, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+
+	for i, testURL := range urls {
+		urlOpts := *opts
+		urlOpts.TestURL = testURL
+
+		wg.Add(1)
+		go func(i int, urlOpts *DetectorOptions) {
+			defer wg.Done()
+			results[i], errs[i] = d.detect(ctx, urlOpts)
+		}(i, &urlOpts)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	detected, failed := 0, 0
+	for i, r := range results {
+		if errs[i] != nil {
+			failed++
+		}
+		if r.CaptivePortalDetected {
+			detected++
+		}
+	}
+	if failed == len(results) {
+		return results[0], errs[0]
+	}
+	portal := detected*2 > len(results)
+
+	// Report the first responding endpoint that agrees with the consensus.
+	// When none does, such as a lone portal redirect outvoted by unreachable
+	// endpoints, only the aggregate verdict is reported.
+	consensus := DetectionResult{ExpectedStatus: opts.ExpectedStatus}
+	for i, r := range results {
+		if r.CaptivePortalDetected == portal && errs[i] == nil {
+			consensus = *r
+			break
+		}
+	}
+
+	consensus.CaptivePortalDetected = portal
+	consensus.URLResults = results
+
+	return &consensus, nil
+}
+
+// detect performs captive portal detection for a single test URL
+func (d *Detector) detect(ctx context.Context, opts *DetectorOptions) (*DetectionResult, error) {
 	result := &DetectionResult{
 		TestURL:        opts.TestURL,
 		ExpectedStatus: opts.ExpectedStatus,
 	}
 
-	// Configure HTTP client
-	d.client.Timeout = opts.Timeout
+	// Configure a per-call HTTP client so concurrent probes don't interfere
+	client := *d.client
+	client.Timeout = opts.Timeout
+
+	// Pin the connection to a specific address, keeping the original host
+	if opts.DialAddress != "" {
+		if transport, ok := d.client.Transport.(*http.Transport); ok {
+			pinned := transport.Clone()
+			dialer := &net.Dialer{Timeout: 5 * time.Second}
+			pinned.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				_, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(opts.DialAddress, port))
+			}
+			client.Transport = pinned
+		}
+	}
 
 	// Configure redirect policy
 	if !opts.FollowRedirects {
-		d.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			// Store redirect URL but don't follow
-			result.RedirectURL = req.URL.String()
+			if result.RedirectURL == "" {
+				result.RedirectURL = req.URL.String()
+			}
 			return http.ErrUseLastResponse
 		}
 	} else {
-		d.client.CheckRedirect = nil
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirectHops {
+				return fmt.Errorf("stopped after %d redirects", maxRedirectHops)
+			}
+			if result.RedirectURL == "" {
+				result.RedirectURL = req.URL.String()
+			}
+			result.RedirectChain = append(result.RedirectChain, req.URL.String())
+			return nil
+		}
 	}
 
 	// Perform DNS check if requested
 	if opts.CheckDNS {
-		dnsResult, err := d.checkDNS(opts.TestURL)
+		dnsResult, err := d.checkDNS(ctx, opts.TestURL)
 		if err == nil {
 			result.DNSResolution = dnsResult
 		}
@@ -126,13 +319,20 @@ func (d *Detector) Detect(opts *DetectorOptions) (*DetectionResult, error) {
 
 	// Perform HTTP test
 	start := time.Now()
-	resp, err := d.performHTTPTest(opts)
+	resp, err := d.performHTTPTest(ctx, &client, opts)
 	result.ResponseTime = time.Since(start)
 
 	if err != nil {
+		// A failed request means no portal response was observed
+		detectionErr := &DetectionError{
+			URL:      opts.TestURL,
+			Category: classifyError(err),
+			Err:      err,
+		}
 		result.Error = err.Error()
-		result.CaptivePortalDetected = true // Assume captive portal if request fails
-		return result, nil
+		result.ErrorCategory = detectionErr.Category
+		result.CaptivePortalDetected = false
+		return result, detectionErr
 	}
 	defer resp.Body.Close()
 
@@ -146,9 +346,23 @@ func (d *Detector) Detect(opts *DetectorOptions) (*DetectionResult, error) {
 	} else {
 		result.CaptivePortalDetected = true
 
+		// Walk the remaining redirect hops so the final portal page can be
+		// inspected without changing the status that was analyzed
+		portalResp := resp
+		if !opts.FollowRedirects && isRedirect(resp.StatusCode) {
+			if finalResp := d.walkRedirectChain(ctx, &client, resp, opts, result); finalResp != nil {
+				defer finalResp.Body.Close()
+				portalResp = finalResp
+			}
+		}
+
 		// Try to get portal information
-		if portalInfo := d.extractPortalInfo(resp); portalInfo != nil {
+		if portalInfo := d.extractPortalInfo(portalResp); portalInfo != nil {
 			result.PortalInfo = portalInfo
+		} else if len(result.RedirectChain) > 0 {
+			result.PortalInfo = &PortalInfo{
+				URL: result.RedirectChain[len(result.RedirectChain)-1],
+			}
 		}
 	}
 
@@ -160,13 +374,51 @@ func (d *Detector) Detect(opts *DetectorOptions) (*DetectionResult, error) {
 		}
 	}
 
+	// Portals that intercept HTTPS substitute their own certificate
+	if opts.HTTPSProbe != nil {
+		result.TLSProbe = d.probeTLS(ctx, opts.HTTPSProbe)
+		if result.TLSProbe.Interference {
+			result.CaptivePortalDetected = true
+		}
+	}
+
+	// DNS answers diverging from the trusted resolver's are only attributed
+	// to hijacking when a portal was also observed
+	if dns := result.DNSResolution; dns != nil && dns.Diverged && !dns.Hijacked && result.CaptivePortalDetected {
+		dns.Hijacked = true
+		dns.HijackReason = fmt.Sprintf("captive portal detected and system DNS answers %v diverge from trusted resolver answers %v", dns.IPs, dns.TrustedIPs)
+	}
+
 	return result, nil
 }
 
+// classifyError maps a request error to an ErrorCategory
+func classifyError(err error) ErrorCategory {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.As(err, &dnsErr):
+		return ErrorDNSFailure
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorConnectionRefused
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return ErrorNetworkUnreachable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	default:
+		return ErrorRequestFailed
+	}
+}
+
 // performHTTPTest performs the actual HTTP test
-func (d *Detector) performHTTPTest(opts *DetectorOptions) (*http.Response, error) {
+func (d *Detector) performHTTPTest(ctx context.Context, client *http.Client, opts *DetectorOptions) (*http.Response, error) {
 	// Create request
-	req, err := http.NewRequest("GET", opts.TestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", opts.TestURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -175,13 +427,61 @@ func (d *Detector) performHTTPTest(opts *DetectorOptions) (*http.Response, error
 	req.Header.Set("User-Agent", opts.UserAgent)
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Pragma", "no-cache")
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	if opts.Host != "" {
+		req.Host = opts.Host
+	}
 
 	// Perform request
-	return d.client.Do(req)
+	return client.Do(req)
+}
+
+// walkRedirectChain records every Location seen starting from a redirect
+// response and returns the response of the final hop, or nil if no hop could
+// be fetched. The caller owns the returned response body.
+func (d *Detector) walkRedirectChain(ctx context.Context, client *http.Client, resp *http.Response, opts *DetectorOptions, result *DetectionResult) *http.Response {
+	var final *http.Response
+	current := resp
+
+	for hop := 0; hop < maxRedirectHops && isRedirect(current.StatusCode); hop++ {
+		location, err := current.Location()
+		if err != nil {
+			break
+		}
+		result.RedirectChain = append(result.RedirectChain, location.String())
+
+		next, err := d.performHTTPTest(ctx, client, &DetectorOptions{
+			TestURL:   location.String(),
+			UserAgent: opts.UserAgent,
+		})
+		if final != nil {
+			final.Body.Close()
+		}
+		if err != nil {
+			return nil
+		}
+		final = next
+		current = next
+	}
+
+	return final
+}
+
+// isRedirect reports whether the status code is an HTTP redirect
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
 }
 
 // checkDNS performs DNS resolution check
-func (d *Detector) checkDNS(testURL string) (*DNSResult, error) {
+func (d *Detector) checkDNS(ctx context.Context, testURL string) (*DNSResult, error) {
 	// Parse URL to get hostname
 	u, err := url.Parse(testURL)
 	if err != nil {
@@ -195,67 +495,59 @@ func (d *Detector) checkDNS(testURL string) (*DNSResult, error) {
 
 	// Resolve DNS
 	start := time.Now()
-	ips, err := d.dnsClient.LookupIPAddr(context.Background(), host)
+	ips, err := d.dnsClient.LookupHost(ctx, host)
 	duration := time.Since(start)
 
 	if err != nil {
 		return nil, fmt.Errorf("DNS resolution failed: %w", err)
 	}
 
-	// Convert IPs to strings
-	var ipStrings []string
-	for _, ip := range ips {
-		ipStrings = append(ipStrings, ip.IP.String())
+	result := &DNSResult{
+		IPs:      ips,
+		Duration: duration,
 	}
 
-	// Check for DNS hijacking (simple heuristic)
-	hijacked := d.isDNSHijacked(host, ipStrings)
+	// Cross-check the answer against the trusted resolver. CDNs and geo-DNS
+	// routinely answer differently per resolver, so divergence alone is only
+	// a supporting signal.
+	if d.trustedResolver != nil {
+		if trusted, err := d.trustedResolver.LookupHost(ctx, host); err == nil {
+			result.TrustedIPs = trusted
+			result.Diverged = len(trusted) > 0 && !sharesNetwork(ips, trusted)
+		}
+	}
 
-	return &DNSResult{
-		IPs:      ipStrings,
-		Hijacked: hijacked,
-		Duration: duration,
-	}, nil
-}
+	result.Hijacked, result.HijackReason = d.isDNSHijacked(host, ips)
 
-// isDNSHijacked checks if DNS appears to be hijacked
-func (d *Detector) isDNSHijacked(hostname string, ips []string) bool {
-	// Known good IPs for common test endpoints
-	knownGoodIPs := map[string][]string{
-		"clients3.google.com": {
-			"142.250.191.14", "172.217.164.14", "216.58.194.14",
-		},
-		"detectportal.firefox.com": {
-			"34.117.118.64", "35.186.224.25",
-		},
-		"www.msftconnecttest.com": {
-			"13.107.4.52", "204.79.197.200",
-		},
-	}
+	return result, nil
+}
 
-	if goodIPs, exists := knownGoodIPs[hostname]; exists {
-		// Check if any returned IP matches known good IPs
-		for _, returnedIP := range ips {
-			for _, goodIP := range goodIPs {
-				if returnedIP == goodIP {
-					return false // Not hijacked
-				}
+// isDNSHijacked checks if DNS appears to be hijacked and returns the reason
+func (d *Detector) isDNSHijacked(hostname string, ips []string) (bool, string) {
+	// A public hostname should never resolve to a private or CGNAT address
+	if isPublicHostname(hostname) {
+		for _, ip := range ips {
+			if d.isLikelyPortalIP(ip) {
+				return true, fmt.Sprintf("public hostname %s resolved to non-public address %s", hostname, ip)
 			}
 		}
-
-		// If none match, likely hijacked
-		return true
 	}
 
-	// For unknown hostnames, check for obvious hijacking
-	for _, ip := range ips {
-		// Check for common captive portal IP ranges
-		if d.isLikelyPortalIP(ip) {
-			return true
-		}
-	}
+	return false, ""
+}
 
-	return false
+// nonPublicRanges are address ranges that public hostnames never resolve to
+var nonPublicRanges = []string{
+	"0.0.0.0/8",      // "This" network
+	"10.0.0.0/8",     // RFC1918
+	"100.64.0.0/10",  // CGNAT (RFC6598)
+	"127.0.0.0/8",    // Loopback
+	"169.254.0.0/16", // Link-local
+	"172.16.0.0/12",  // RFC1918
+	"192.168.0.0/16", // RFC1918
+	"::1/128",        // IPv6 loopback
+	"fc00::/7",       // IPv6 unique local
+	"fe80::/10",      // IPv6 link-local
 }
 
 // isLikelyPortalIP checks if an IP is likely from a captive portal
@@ -265,16 +557,7 @@ func (d *Detector) isLikelyPortalIP(ip string) bool {
 		return false
 	}
 
-	// Common captive portal IP ranges
-	portalRanges := []string{
-		"192.168.1.1/32", // Common router IP
-		"10.0.0.1/32",    // Common router IP
-		"172.16.0.1/32",  // Common router IP
-		"192.168.0.1/32", // Common router IP
-		"1.1.1.1/32",     // Sometimes used by portals
-	}
-
-	for _, cidr := range portalRanges {
+	for _, cidr := range nonPublicRanges {
 		_, network, err := net.ParseCIDR(cidr)
 		if err != nil {
 			continue
@@ -287,6 +570,60 @@ func (d *Detector) isLikelyPortalIP(ip string) bool {
 	return false
 }
 
+// isPublicHostname reports whether hostname is expected to resolve publicly
+func isPublicHostname(hostname string) bool {
+	if net.ParseIP(hostname) != nil {
+		return false
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if !strings.Contains(host, ".") {
+		return false
+	}
+
+	for _, suffix := range []string{".local", ".localhost", ".lan", ".home.arpa", ".internal"} {
+		if strings.HasSuffix(host, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// sharesNetwork reports whether any address in a shares a /24 (IPv4) or /48
+// (IPv6) network with any address in b. Comparing networks rather than exact
+// addresses tolerates CDN load balancing across resolvers.
+func sharesNetwork(a, b []string) bool {
+	for _, ipA := range a {
+		parsedA := net.ParseIP(ipA)
+		if parsedA == nil {
+			continue
+		}
+		for _, ipB := range b {
+			parsedB := net.ParseIP(ipB)
+			if parsedB == nil {
+				continue
+			}
+
+			bits := 48
+			if parsedA.To4() != nil {
+				if parsedB.To4() == nil {
+					continue
+				}
+				bits = 24
+				parsedA, parsedB = parsedA.To4(), parsedB.To4()
+			} else if parsedB.To4() != nil {
+				continue
+			}
+
+			mask := net.CIDRMask(bits, len(parsedA)*8)
+			if parsedA.Mask(mask).Equal(parsedB.Mask(mask)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // extractPortalInfo extracts information about the captive portal
 func (d *Detector) extractPortalInfo(resp *http.Response) *PortalInfo {
 	// Only extract info from HTML responses
@@ -313,30 +650,11 @@ func (d *Detector) extractPortalInfo(resp *http.Response) *PortalInfo {
 		portalInfo.Title = strings.TrimSpace(matches[1])
 	}
 
-	// Check for login requirements
-	loginIndicators := []string{
-		"login", "sign in", "authenticate", "username", "password",
-		"credentials", "access code", "authorization",
-	}
-
-	bodyLower := strings.ToLower(bodyStr)
-	for _, indicator := range loginIndicators {
-		if strings.Contains(bodyLower, indicator) {
-			portalInfo.LoginRequired = true
-			break
-		}
-	}
+	// A portal requires login when it presents a form with a password field
+	portalInfo.LoginRequired = hasLoginForm(bodyStr)
 
-	// Try to identify provider
-	if strings.Contains(bodyLower, "starbucks") {
-		portalInfo.Provider = "Starbucks"
-	} else if strings.Contains(bodyLower, "mcdonalds") {
-		portalInfo.Provider = "McDonald's"
-	} else if strings.Contains(bodyLower, "airport") {
-		portalInfo.Provider = "Airport WiFi"
-	} else if strings.Contains(bodyLower, "hotel") {
-		portalInfo.Provider = "Hotel WiFi"
-	}
+	// Identify the portal vendor and venue provider
+	d.identifyPortal(portalInfo, resp.Header, bodyStr)
 
 	return portalInfo
 }
@@ -0,0 +1,599 @@
+package captive
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testOptions(url string) *DetectorOptions {
+	return &DetectorOptions{
+		TestURL:        url,
+		ExpectedStatus: http.StatusNoContent,
+		Timeout:        5 * time.Second,
+		UserAgent:      "net-sec-test",
+	}
+}
+
+func newRedirectChainServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate_204", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gateway", http.StatusFound)
+	})
+	mux.HandleFunc("/gateway", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/portal/login", http.StatusFound)
+	})
+	mux.HandleFunc("/portal/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Guest WiFi</title></head>
+<body><form method="post"><input type="password" name="code"></form></body></html>`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestDetectRecordsRedirectChain(t *testing.T) {
+	server := newRedirectChainServer()
+	defer server.Close()
+
+	for _, follow := range []bool{false, true} {
+		opts := testOptions(server.URL + "/generate_204")
+		opts.FollowRedirects = follow
+
+		result, err := NewDetector().Detect(opts)
+		if err != nil {
+			t.Fatalf("follow=%t: Detect returned error: %v", follow, err)
+		}
+
+		if !result.CaptivePortalDetected {
+			t.Errorf("follow=%t: expected captive portal to be detected", follow)
+		}
+
+		expectedChain := []string{server.URL + "/gateway", server.URL + "/portal/login"}
+		if len(result.RedirectChain) != len(expectedChain) {
+			t.Fatalf("follow=%t: expected chain %v, got %v", follow, expectedChain, result.RedirectChain)
+		}
+		for i, hop := range expectedChain {
+			if result.RedirectChain[i] != hop {
+				t.Errorf("follow=%t: hop %d: expected %s, got %s", follow, i, hop, result.RedirectChain[i])
+			}
+		}
+
+		if result.RedirectURL != server.URL+"/gateway" {
+			t.Errorf("follow=%t: expected first redirect URL to be kept, got %s", follow, result.RedirectURL)
+		}
+
+		if result.PortalInfo == nil {
+			t.Fatalf("follow=%t: expected portal info from the final hop", follow)
+		}
+		if result.PortalInfo.URL != server.URL+"/portal/login" {
+			t.Errorf("follow=%t: expected portal URL from final hop, got %s", follow, result.PortalInfo.URL)
+		}
+		if !result.PortalInfo.LoginRequired {
+			t.Errorf("follow=%t: expected login to be required on the final hop", follow)
+		}
+	}
+
+	// Without following, the analyzed status is still the first response
+	result, _ := NewDetector().Detect(testOptions(server.URL + "/generate_204"))
+	if result.HTTPStatus != http.StatusFound {
+		t.Errorf("expected non-follow mode to report status %d, got %d", http.StatusFound, result.HTTPStatus)
+	}
+}
+
+func newStatusServer(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+}
+
+func TestDetectMultiConsensusIgnoresSingleFalsePositive(t *testing.T) {
+	clean1 := newStatusServer(http.StatusNoContent)
+	defer clean1.Close()
+	clean2 := newStatusServer(http.StatusNoContent)
+	defer clean2.Close()
+	falsePositive := newStatusServer(http.StatusOK)
+	defer falsePositive.Close()
+
+	urls := []string{falsePositive.URL, clean1.URL, clean2.URL}
+	result, err := NewDetector().DetectMulti(context.Background(), urls, testOptions(""))
+	if err != nil {
+		t.Fatalf("DetectMulti returned error: %v", err)
+	}
+
+	if result.CaptivePortalDetected {
+		t.Error("expected consensus to report no captive portal")
+	}
+	if len(result.URLResults) != 3 {
+		t.Fatalf("expected 3 per-URL results, got %d", len(result.URLResults))
+	}
+	if !result.URLResults[0].CaptivePortalDetected {
+		t.Error("expected the false-positive endpoint to be recorded as detecting a portal")
+	}
+	if result.TestURL == falsePositive.URL {
+		t.Error("expected the representative result to agree with the consensus")
+	}
+}
+
+func TestDetectMultiConsensusReportsPortal(t *testing.T) {
+	portal1 := newStatusServer(http.StatusOK)
+	defer portal1.Close()
+	portal2 := newStatusServer(http.StatusOK)
+	defer portal2.Close()
+	clean := newStatusServer(http.StatusNoContent)
+	defer clean.Close()
+
+	urls := []string{clean.URL, portal1.URL, portal2.URL}
+	result, err := NewDetector().DetectMulti(context.Background(), urls, testOptions(""))
+	if err != nil {
+		t.Fatalf("DetectMulti returned error: %v", err)
+	}
+	if !result.CaptivePortalDetected {
+		t.Error("expected consensus to report a captive portal")
+	}
+}
+
+func TestDetectMultiWithoutAgreeingEndpoint(t *testing.T) {
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer redirect.Close()
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	deadURL := dead.URL
+	dead.Close()
+
+	// Neither endpoint both responded and agrees with the "no portal" verdict
+	result, err := NewDetector().DetectMulti(context.Background(), []string{redirect.URL, deadURL}, testOptions(""))
+	if err != nil {
+		t.Fatalf("DetectMulti returned error: %v", err)
+	}
+	if result.CaptivePortalDetected {
+		t.Error("expected a single redirect not to outweigh the consensus")
+	}
+	if result.TestURL != "" || result.ExpectedStatus != http.StatusNoContent {
+		t.Errorf("expected an aggregate result, got %+v", result)
+	}
+	if len(result.URLResults) != 2 || !result.URLResults[0].CaptivePortalDetected || result.URLResults[1].ErrorCategory != ErrorConnectionRefused {
+		t.Errorf("unexpected per-URL results %+v", result.URLResults)
+	}
+}
+
+func TestDetectMultiRespectsCancellation(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewDetector().DetectMulti(ctx, []string{slow.URL, slow.URL}, testOptions(""))
+	if err == nil {
+		t.Fatal("expected a context error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected cancellation to stop probes promptly, took %v", elapsed)
+	}
+}
+
+// staticResolver returns fixed answers per hostname
+type staticResolver map[string][]string
+
+func (r staticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if ips, ok := r[host]; ok {
+		return ips, nil
+	}
+	return nil, fmt.Errorf("no such host: %s", host)
+}
+
+func TestCheckDNSDetectsHijacking(t *testing.T) {
+	tests := []struct {
+		name     string
+		system   []string
+		trusted  []string
+		diverged bool
+		hijacked bool
+	}{
+		{"private answer for public host", []string{"10.0.0.5"}, []string{"142.250.191.14"}, true, true},
+		{"CGNAT answer for public host", []string{"100.64.12.1"}, nil, false, true},
+		{"public answer diverging from trusted resolver", []string{"203.0.113.7"}, []string{"142.250.191.14"}, true, false},
+		{"answer in same network as trusted resolver", []string{"142.250.191.78"}, []string{"142.250.191.14"}, false, false},
+		{"trusted resolver unavailable", []string{"142.250.191.14"}, nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := NewDetector()
+			detector.SetSystemResolver(staticResolver{"clients3.google.com": tt.system})
+			if tt.trusted != nil {
+				detector.SetTrustedResolver(staticResolver{"clients3.google.com": tt.trusted})
+			} else {
+				detector.SetTrustedResolver(staticResolver{})
+			}
+
+			result, err := detector.checkDNS(context.Background(), "http://clients3.google.com/generate_204")
+			if err != nil {
+				t.Fatalf("checkDNS returned error: %v", err)
+			}
+			if result.Diverged != tt.diverged {
+				t.Errorf("expected diverged=%t, got %t", tt.diverged, result.Diverged)
+			}
+			if result.Hijacked != tt.hijacked {
+				t.Errorf("expected hijacked=%t, got %t (%s)", tt.hijacked, result.Hijacked, result.HijackReason)
+			}
+			if tt.hijacked && result.HijackReason == "" {
+				t.Error("expected a hijack reason to be recorded")
+			}
+		})
+	}
+}
+
+func TestCheckDNSSkipsTrustedLookupByDefault(t *testing.T) {
+	detector := NewDetector()
+	detector.SetSystemResolver(staticResolver{"clients3.google.com": {"203.0.113.7"}})
+
+	result, err := detector.checkDNS(context.Background(), "http://clients3.google.com/generate_204")
+	if err != nil {
+		t.Fatalf("checkDNS returned error: %v", err)
+	}
+	if result.TrustedIPs != nil || result.Diverged || result.Hijacked {
+		t.Errorf("expected no trusted resolver cross-check, got %+v", result)
+	}
+}
+
+func TestDetectFlagsDivergentDNSOnlyWithPortal(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		status   int
+		hijacked bool
+	}{
+		{"portal", http.StatusFound, true},
+		{"open network", http.StatusNoContent, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStatusServer(tt.status)
+			defer server.Close()
+			_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+			detector := NewDetector()
+			detector.SetSystemResolver(staticResolver{"clients3.google.com": {"203.0.113.7"}})
+			detector.SetTrustedResolver(staticResolver{"clients3.google.com": {"142.250.191.14"}})
+
+			opts := testOptions(fmt.Sprintf("http://clients3.google.com:%s/generate_204", port))
+			opts.CheckDNS = true
+			opts.DialAddress = "127.0.0.1"
+
+			result, err := detector.Detect(opts)
+			if err != nil {
+				t.Fatalf("Detect returned error: %v", err)
+			}
+			if result.DNSResolution == nil || !result.DNSResolution.Diverged {
+				t.Fatalf("expected divergent DNS answers, got %+v", result.DNSResolution)
+			}
+			if result.DNSResolution.Hijacked != tt.hijacked {
+				t.Errorf("expected hijacked=%t, got %t (%s)", tt.hijacked, result.DNSResolution.Hijacked, result.DNSResolution.HijackReason)
+			}
+		})
+	}
+}
+
+func TestDoHResolverParsesAnswers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/dns-json" {
+			t.Errorf("unexpected Accept header %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "application/dns-json")
+		if r.URL.Query().Get("type") == "A" {
+			w.Write([]byte(`{"Status":0,"Answer":[{"type":5,"data":"alias.example."},{"type":1,"data":"93.184.216.34"}]}`))
+			return
+		}
+		w.Write([]byte(`{"Status":0}`))
+	}))
+	defer server.Close()
+
+	ips, err := NewDoHResolver(server.URL).LookupHost(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("LookupHost returned error: %v", err)
+	}
+	if len(ips) != 1 || ips[0] != "93.184.216.34" {
+		t.Errorf("expected [93.184.216.34], got %v", ips)
+	}
+}
+
+func TestDetectDistinguishesOutageFromPortal(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	deadURL := dead.URL
+	dead.Close()
+
+	result, err := NewDetector().Detect(testOptions(deadURL))
+	var detectionErr *DetectionError
+	if !errors.As(err, &detectionErr) {
+		t.Fatalf("expected a DetectionError for a dead host, got %v", err)
+	}
+	if detectionErr.Category != ErrorConnectionRefused {
+		t.Errorf("expected category %s, got %s", ErrorConnectionRefused, detectionErr.Category)
+	}
+	if result.CaptivePortalDetected {
+		t.Error("a dead host must not be reported as a captive portal")
+	}
+	if result.ErrorCategory != ErrorConnectionRefused {
+		t.Errorf("expected result category %s, got %s", ErrorConnectionRefused, result.ErrorCategory)
+	}
+
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	result, err = NewDetector().Detect(testOptions(redirect.URL))
+	if err != nil {
+		t.Fatalf("expected no error for a redirecting portal, got %v", err)
+	}
+	if !result.CaptivePortalDetected || result.ErrorCategory != "" {
+		t.Errorf("expected a redirect to be reported as a portal without error category, got detected=%t category=%q",
+			result.CaptivePortalDetected, result.ErrorCategory)
+	}
+
+	clean := newStatusServer(http.StatusNoContent)
+	defer clean.Close()
+
+	result, err = NewDetector().Detect(testOptions(clean.URL))
+	if err != nil {
+		t.Fatalf("expected no error for a clean 204, got %v", err)
+	}
+	if result.CaptivePortalDetected {
+		t.Error("expected a clean 204 to report no captive portal")
+	}
+}
+
+func newHeaderGatedPortal() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Whitelisted-Device") == "aa:bb:cc:dd:ee:ff" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+}
+
+func TestAttemptBypass(t *testing.T) {
+	portal := newHeaderGatedPortal()
+	defer portal.Close()
+
+	detector := NewDetector()
+	result, err := detector.Detect(testOptions(portal.URL))
+	if err != nil || !result.CaptivePortalDetected {
+		t.Fatalf("expected portal to be detected first, got detected=%t err=%v", result.CaptivePortalDetected, err)
+	}
+
+	if _, err := detector.AttemptBypass(context.Background(), result); err == nil {
+		t.Error("expected bypass to be refused while not enabled")
+	}
+
+	detector.EnableBypass(&BypassOptions{
+		SpoofHeaders: []map[string]string{
+			{"X-Whitelisted-Device": "00:00:00:00:00:00"},
+			{"X-Whitelisted-Device": "aa:bb:cc:dd:ee:ff"},
+		},
+	})
+	ok, err := detector.AttemptBypass(context.Background(), result)
+	if err != nil {
+		t.Fatalf("AttemptBypass returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected bypass with the whitelisted header to succeed")
+	}
+	if !result.BypassAttempted || result.BypassTechnique != "header_spoofing" {
+		t.Errorf("expected header spoofing to be recorded, got attempted=%t technique=%q",
+			result.BypassAttempted, result.BypassTechnique)
+	}
+
+	detector.EnableBypass(&BypassOptions{
+		SpoofHeaders: []map[string]string{{"X-Whitelisted-Device": "00:00:00:00:00:00"}},
+	})
+	result.BypassTechnique = ""
+	ok, err = detector.AttemptBypass(context.Background(), result)
+	if err != nil {
+		t.Fatalf("AttemptBypass returned error: %v", err)
+	}
+	if ok {
+		t.Error("expected bypass to fail without the whitelisted header")
+	}
+}
+
+func TestDetectCachesResultsWithinTTL(t *testing.T) {
+	var hits int32
+	status := int32(http.StatusNoContent)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	detector := NewDetector()
+	detector.EnableCache(time.Minute)
+	opts := testOptions(server.URL)
+
+	first, err := detector.Detect(opts)
+	if err != nil {
+		t.Fatalf("Detect returned error: %v", err)
+	}
+	second, err := detector.Detect(opts)
+	if err != nil {
+		t.Fatalf("Detect returned error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("expected the second call to be served from cache, server saw %d requests", got)
+	}
+	if first.FromCache || !second.FromCache {
+		t.Errorf("expected only the second result to come from cache, got %t/%t", first.FromCache, second.FromCache)
+	}
+
+	// A forced check observes the portal and reports the transition
+	atomic.StoreInt32(&status, http.StatusOK)
+	forced := *opts
+	forced.Force = true
+	changed, err := detector.Detect(&forced)
+	if err != nil {
+		t.Fatalf("Detect returned error: %v", err)
+	}
+	if !changed.CaptivePortalDetected || !changed.StatusChanged {
+		t.Errorf("expected a status change to a captive portal, got detected=%t changed=%t",
+			changed.CaptivePortalDetected, changed.StatusChanged)
+	}
+
+	// The transition cleared the cache, so the next call probes again
+	next, err := detector.Detect(opts)
+	if err != nil {
+		t.Fatalf("Detect returned error: %v", err)
+	}
+	if next.FromCache {
+		t.Error("expected the cache to be cleared after a status change")
+	}
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("expected 3 network probes, got %d", got)
+	}
+	if next.StatusChanged {
+		t.Error("expected no status change when the portal is still present")
+	}
+}
+
+func TestExtractPortalInfoIdentifiesVendors(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        http.Header
+		body          string
+		vendor        string
+		provider      string
+		loginRequired bool
+	}{
+		{
+			name: "Meraki splash page with click-through",
+			body: `<html><head><title>Welcome to Starbucks WiFi</title></head><body>
+<form action="https://n123.network-auth.com/splash/grant" method="get">
+<input type="checkbox" name="terms"><input type="submit" value="Accept"></form></body></html>`,
+			vendor:        "Cisco Meraki",
+			provider:      "Starbucks",
+			loginRequired: false,
+		},
+		{
+			name:   "FortiGate authentication page",
+			header: http.Header{"Location": []string{"http://10.0.0.1:1000/fgtauth?0a1b2c"}},
+			body: `<html><head><title>Authentication Required</title></head><body>
+<form action="/" method="post"><input type="text" name="username">
+<input type="password" name="password"><input type="hidden" name="magic" value="0a1b2c"></form></body></html>`,
+			vendor:        "Fortinet FortiGate",
+			loginRequired: true,
+		},
+		{
+			name: "login keyword without a password form",
+			body: `<html><head><title>Hotel Portal</title></head><body>
+<p>Please login at the front desk to get your access code.</p></body></html>`,
+			provider:      "Hotel WiFi",
+			loginRequired: false,
+		},
+	}
+
+	detector := NewDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, values := range tt.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			info := detector.extractPortalInfo(resp)
+			if info == nil {
+				t.Fatal("expected portal info")
+			}
+			if info.Vendor != tt.vendor {
+				t.Errorf("expected vendor %q, got %q", tt.vendor, info.Vendor)
+			}
+			if info.Provider != tt.provider {
+				t.Errorf("expected provider %q, got %q", tt.provider, info.Provider)
+			}
+			if info.LoginRequired != tt.loginRequired {
+				t.Errorf("expected login required %t, got %t", tt.loginRequired, info.LoginRequired)
+			}
+		})
+	}
+}
+
+func TestHTTPSProbeDetectsCertificateSubstitution(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	clean := newStatusServer(http.StatusNoContent)
+	defer clean.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+	address := strings.TrimPrefix(tlsServer.URL, "https://")
+
+	tests := []struct {
+		name         string
+		probe        *HTTPSProbeOptions
+		interference bool
+	}{
+		{"trusted certificate", &HTTPSProbeOptions{Address: address, ServerName: "example.com", RootCAs: roots}, false},
+		{"untrusted certificate", &HTTPSProbeOptions{Address: address, ServerName: "example.com"}, true},
+		{"SAN mismatch", &HTTPSProbeOptions{Address: address, ServerName: "www.google.com", RootCAs: roots}, true},
+		{"pin mismatch", &HTTPSProbeOptions{Address: address, ServerName: "example.com", RootCAs: roots,
+			PinnedSPKI: []string{strings.Repeat("ab", 32)}}, true},
+		{"unexpected issuer", &HTTPSProbeOptions{Address: address, ServerName: "example.com", RootCAs: roots,
+			ExpectedIssuers: []string{"Google Trust Services"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(clean.URL)
+			opts.HTTPSProbe = tt.probe
+
+			result, err := NewDetector().Detect(opts)
+			if err != nil {
+				t.Fatalf("Detect returned error: %v", err)
+			}
+			if result.TLSProbe == nil {
+				t.Fatal("expected TLS probe details in the result")
+			}
+			if result.TLSProbe.Interference != tt.interference {
+				t.Errorf("expected interference=%t, got %t (%s)", tt.interference, result.TLSProbe.Interference, result.TLSProbe.Reason)
+			}
+			if result.CaptivePortalDetected != tt.interference {
+				t.Errorf("expected portal detected=%t, got %t", tt.interference, result.CaptivePortalDetected)
+			}
+			if result.TLSProbe.Issuer == "" || result.TLSProbe.SPKIHash == "" || len(result.TLSProbe.SANs) == 0 {
+				t.Errorf("expected certificate details to be recorded, got %+v", result.TLSProbe)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package captive
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// PortalFingerprint identifies a captive portal vendor or venue provider from
+// response headers and page content. A fingerprint matches when any of its
+// header or body patterns match.
+type PortalFingerprint struct {
+	Vendor         string                    // Portal software vendor, e.g. "Cisco Meraki"
+	Provider       string                    // Venue or operator, e.g. "Starbucks"
+	HeaderPatterns map[string]*regexp.Regexp // Header name to value pattern
+	BodyPatterns   []*regexp.Regexp
+}
+
+// DefaultFingerprints returns the built-in portal fingerprint table
+func DefaultFingerprints() []PortalFingerprint {
+	return []PortalFingerprint{
+		{
+			Vendor: "Cisco Meraki",
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)splash\.meraki\.com|n\d+\.network-auth\.com`),
+			},
+		},
+		{
+			Vendor: "Aruba ClearPass",
+			HeaderPatterns: map[string]*regexp.Regexp{
+				"Location": regexp.MustCompile(`(?i)/guest/[\w-]+\.php|cppm`),
+			},
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)clearpass|arubanetworks\.com|/cgi-bin/login\?cmd=login`),
+			},
+		},
+		{
+			Vendor: "Ubiquiti UniFi",
+			HeaderPatterns: map[string]*regexp.Regexp{
+				"Location": regexp.MustCompile(`(?i)/guest/s/[\w-]+/`),
+			},
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)unifi|/guest/s/[\w-]+/`),
+			},
+		},
+		{
+			Vendor: "Fortinet FortiGate",
+			HeaderPatterns: map[string]*regexp.Regexp{
+				"Location": regexp.MustCompile(`(?i)/fgtauth\?`),
+			},
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)fgtauth|fortinet`),
+			},
+		},
+		{
+			Vendor: "Cisco ISE",
+			HeaderPatterns: map[string]*regexp.Regexp{
+				"Location": regexp.MustCompile(`(?i)/portal/(PortalSetup|gateway)\.action`),
+			},
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)PortalSetup\.action|cisco identity services engine`),
+			},
+		},
+		{
+			Vendor: "MikroTik Hotspot",
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)mikrotik|\$\(link-login(-only)?\)|name="dst"`),
+			},
+		},
+		{
+			Vendor: "Nomadix",
+			HeaderPatterns: map[string]*regexp.Regexp{
+				"Server": regexp.MustCompile(`(?i)nomadix`),
+			},
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)nomadix`),
+			},
+		},
+		{
+			Vendor: "pfSense Captive Portal",
+			BodyPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`(?i)pfsense|name="zone"`),
+			},
+		},
+		{Provider: "Starbucks", BodyPatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)starbucks`)}},
+		{Provider: "McDonald's", BodyPatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)mcdonald'?s`)}},
+		{Provider: "Airport WiFi", BodyPatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)\bairport\b`)}},
+		{Provider: "Hotel WiFi", BodyPatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)\bhotel\b`)}},
+	}
+}
+
+// SetFingerprints replaces the fingerprint table used to identify portals
+func (d *Detector) SetFingerprints(fingerprints []PortalFingerprint) {
+	d.fingerprints = fingerprints
+}
+
+// matches reports whether the fingerprint matches the response
+func (f *PortalFingerprint) matches(header http.Header, body string) bool {
+	for name, pattern := range f.HeaderPatterns {
+		for _, value := range header.Values(name) {
+			if pattern.MatchString(value) {
+				return true
+			}
+		}
+	}
+
+	for _, pattern := range f.BodyPatterns {
+		if pattern.MatchString(body) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// identifyPortal fills the vendor and provider of portalInfo from the first
+// matching fingerprint of each kind
+func (d *Detector) identifyPortal(portalInfo *PortalInfo, header http.Header, body string) {
+	for i := range d.fingerprints {
+		fingerprint := &d.fingerprints[i]
+		if (fingerprint.Vendor == "" || portalInfo.Vendor != "") &&
+			(fingerprint.Provider == "" || portalInfo.Provider != "") {
+			continue
+		}
+		if !fingerprint.matches(header, body) {
+			continue
+		}
+
+		if portalInfo.Vendor == "" {
+			portalInfo.Vendor = fingerprint.Vendor
+		}
+		if portalInfo.Provider == "" {
+			portalInfo.Provider = fingerprint.Provider
+		}
+	}
+}
+
+// hasLoginForm reports whether the page contains a form with a password input
+func hasLoginForm(body string) bool {
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	inForm := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "form":
+				inForm = true
+			case "input":
+				if inForm && strings.EqualFold(attr(token, "type"), "password") {
+					return true
+				}
+			}
+		case html.EndTagToken:
+			if token := tokenizer.Token(); token.Data == "form" {
+				inForm = false
+			}
+		}
+	}
+}
+
+// attr returns the value of the named attribute of token
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
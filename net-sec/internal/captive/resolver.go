@@ -0,0 +1,115 @@
+package captive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultDoHEndpoint is a public DNS-over-HTTPS resolver that can be set as
+// the trusted resolver system DNS answers are cross-checked against
+const DefaultDoHEndpoint = "https://cloudflare-dns.com/dns-query"
+
+// Resolver resolves a hostname to its IP addresses
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// netResolver adapts a net.Resolver to the Resolver interface
+type netResolver struct {
+	resolver *net.Resolver
+}
+
+// LookupHost resolves host using the wrapped net.Resolver
+func (r *netResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP.String())
+	}
+	return ips, nil
+}
+
+// DoHResolver resolves hostnames using the DNS-over-HTTPS JSON API, bypassing
+// any DNS interception performed by the local network
+type DoHResolver struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewDoHResolver creates a DoH resolver for the given endpoint
+func NewDoHResolver(endpoint string) *DoHResolver {
+	return &DoHResolver{
+		Endpoint: endpoint,
+		Client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// dohResponse is the subset of the DoH JSON answer format that is used
+type dohResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// LookupHost resolves the A and AAAA records of host over DoH
+func (r *DoHResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	var ips []string
+	for _, recordType := range []string{"A", "AAAA"} {
+		answers, err := r.query(ctx, host, recordType)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, answers...)
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no DoH answers for %s", host)
+	}
+	return ips, nil
+}
+
+// query performs a single DoH lookup for the given record type
+func (r *DoHResolver) query(ctx context.Context, host, recordType string) ([]string, error) {
+	endpoint := r.Endpoint + "?name=" + url.QueryEscape(host) + "&type=" + recordType
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH request failed with status %d", resp.StatusCode)
+	}
+
+	var answer dohResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("failed to decode DoH response: %w", err)
+	}
+
+	var ips []string
+	for _, record := range answer.Answer {
+		// Skip CNAMEs and other non-address records
+		if ip := net.ParseIP(record.Data); ip != nil {
+			ips = append(ips, ip.String())
+		}
+	}
+	return ips, nil
+}
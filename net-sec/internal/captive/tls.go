@@ -0,0 +1,168 @@
+package captive
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// HTTPSProbeOptions configures the TLS handshake used to detect portals that
+// intercept HTTPS with a substituted certificate
+type HTTPSProbeOptions struct {
+	Address         string         // host:port of the pinned endpoint
+	ServerName      string         // SNI and expected SAN; defaults to the Address host
+	PinnedSPKI      []string       // Accepted hex SHA-256 hashes of the leaf public key
+	ExpectedIssuers []string       // Accepted leaf issuer common names or organizations
+	RootCAs         *x509.CertPool // Roots used for chain verification; system roots when nil
+	Timeout         time.Duration
+}
+
+// DefaultHTTPSProbeOptions probes a well-known HTTPS endpoint and relies on
+// chain and SAN verification against the system roots
+func DefaultHTTPSProbeOptions() *HTTPSProbeOptions {
+	return &HTTPSProbeOptions{
+		Address: "www.google.com:443",
+		Timeout: 5 * time.Second,
+	}
+}
+
+// TLSProbeResult contains the certificate presented during the HTTPS probe
+type TLSProbeResult struct {
+	Address      string    `json:"address"`
+	ServerName   string    `json:"server_name"`
+	Subject      string    `json:"subject,omitempty"`
+	Issuer       string    `json:"issuer,omitempty"`
+	SANs         []string  `json:"sans,omitempty"`
+	SPKIHash     string    `json:"spki_hash,omitempty"`
+	NotAfter     time.Time `json:"not_after,omitempty"`
+	Interference bool      `json:"interference"`
+	Reason       string    `json:"reason,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// probeTLS performs the TLS handshake and flags certificate substitution.
+// Connection failures are recorded but not treated as interference.
+func (d *Detector) probeTLS(ctx context.Context, opts *HTTPSProbeOptions) *TLSProbeResult {
+	result := &TLSProbeResult{
+		Address:    opts.Address,
+		ServerName: opts.ServerName,
+	}
+
+	if result.ServerName == "" {
+		host, _, err := net.SplitHostPort(opts.Address)
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid probe address: %v", err)
+			return result
+		}
+		result.ServerName = host
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			ServerName: result.ServerName,
+			// Verification is done below so the presented certificate can be
+			// recorded even when it is not trusted
+			InsecureSkipVerify: true,
+		},
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(dialCtx, "tcp", opts.Address)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		result.Interference = true
+		result.Reason = "no certificate presented"
+		return result
+	}
+
+	leaf := state.PeerCertificates[0]
+	spki := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	result.Subject = leaf.Subject.String()
+	result.Issuer = leaf.Issuer.String()
+	result.SANs = append(append([]string{}, leaf.DNSNames...), ipStrings(leaf.IPAddresses)...)
+	result.SPKIHash = hex.EncodeToString(spki[:])
+	result.NotAfter = leaf.NotAfter
+
+	result.Interference, result.Reason = verifyCertificate(opts, result, state.PeerCertificates)
+	return result
+}
+
+// verifyCertificate checks the presented chain against the probe expectations
+func verifyCertificate(opts *HTTPSProbeOptions, result *TLSProbeResult, chain []*x509.Certificate) (bool, string) {
+	leaf := chain[0]
+
+	if err := leaf.VerifyHostname(result.ServerName); err != nil {
+		return true, fmt.Sprintf("certificate SAN mismatch: %v", err)
+	}
+
+	if len(opts.PinnedSPKI) > 0 && !containsFold(opts.PinnedSPKI, result.SPKIHash) {
+		return true, fmt.Sprintf("certificate public key %s does not match pinned keys", result.SPKIHash)
+	}
+
+	if len(opts.ExpectedIssuers) > 0 {
+		issuers := append([]string{leaf.Issuer.CommonName}, leaf.Issuer.Organization...)
+		matched := false
+		for _, issuer := range issuers {
+			if containsFold(opts.ExpectedIssuers, issuer) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return true, fmt.Sprintf("unexpected certificate issuer %q", result.Issuer)
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       result.ServerName,
+		Roots:         opts.RootCAs,
+		Intermediates: intermediates,
+	}); err != nil {
+		return true, fmt.Sprintf("certificate chain not trusted: %v", err)
+	}
+
+	return false, ""
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipStrings converts IP addresses to strings
+func ipStrings(ips []net.IP) []string {
+	out := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		out = append(out, ip.String())
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package compliance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// BundleSchemaVersion identifies the layout of compliance bundles
+const BundleSchemaVersion = "net-sec/compliance-bundle/v1"
+
+// Bundle is a point-in-time snapshot of the policies, roles and holds in
+// force, for auditors
+type Bundle struct {
+	SchemaVersion     string                      `json:"schema_version"`
+	GeneratedAt       time.Time                   `json:"generated_at"`
+	RetentionPolicies []retention.RetentionPolicy `json:"retention_policies"`
+	LegalHolds        []retention.LegalHold       `json:"legal_holds"` // Active holds only
+	Roles             []rbac.Role                 `json:"roles"`
+	Permissions       []rbac.Permission           `json:"permissions"`
+	Pseudonymization  *PseudonymizationSettings   `json:"pseudonymization,omitempty"`
+}
+
+// PseudonymizationSettings is the pseudonymization configuration with key
+// material redacted
+type PseudonymizationSettings struct {
+	Algorithm           privacy.PseudoAlgorithm          `json:"algorithm"`
+	KeyRotationInterval time.Duration                    `json:"key_rotation_interval"`
+	SaltLength          int                              `json:"salt_length"`
+	IterationCount      int                              `json:"iteration_count"`
+	KeyDerivationFunc   privacy.KeyDerivationFunc        `json:"key_derivation_func"`
+	PreservationRules   []privacy.FormatPreservationRule `json:"preservation_rules"`
+	AuditEnabled        bool                             `json:"audit_enabled"`
+	Keys                []KeyInfo                        `json:"keys"`
+}
+
+// KeyInfo describes a pseudonymization key without its material
+type KeyInfo struct {
+	ID        int               `json:"id"`
+	Algorithm string            `json:"algorithm"`
+	Status    privacy.KeyStatus `json:"status"`
+	Purpose   string            `json:"purpose"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// BundleExporter assembles compliance bundles. It only reads from the
+// components it is given, so bundles can be exported at any time.
+type BundleExporter struct {
+	scheduler     *retention.RetentionScheduler
+	access        *rbac.AccessController
+	pseudonymizer *privacy.PseudonymizationEngine
+	now           func() time.Time
+}
+
+// NewBundleExporter creates a bundle exporter. Any component may be nil, in
+// which case its section of the bundle is left empty.
+func NewBundleExporter(scheduler *retention.RetentionScheduler, access *rbac.AccessController, pseudonymizer *privacy.PseudonymizationEngine) *BundleExporter {
+	return &BundleExporter{
+		scheduler:     scheduler,
+		access:        access,
+		pseudonymizer: pseudonymizer,
+		now:           time.Now,
+	}
+}
+
+// Bundle takes a snapshot of the current policies, roles and holds
+func (e *BundleExporter) Bundle() *Bundle {
+	bundle := &Bundle{
+		SchemaVersion:     BundleSchemaVersion,
+		GeneratedAt:       e.now().UTC(),
+		RetentionPolicies: []retention.RetentionPolicy{},
+		LegalHolds:        []retention.LegalHold{},
+		Roles:             []rbac.Role{},
+		Permissions:       []rbac.Permission{},
+	}
+
+	if e.scheduler != nil {
+		bundle.RetentionPolicies = e.scheduler.Policies()
+		bundle.LegalHolds = e.scheduler.ListActiveLegalHolds()
+	}
+
+	if e.access != nil {
+		bundle.Roles = e.access.Roles()
+		bundle.Permissions = e.access.Permissions()
+	}
+
+	if e.pseudonymizer != nil {
+		config := e.pseudonymizer.Config()
+		settings := &PseudonymizationSettings{
+			Algorithm:           config.Algorithm,
+			KeyRotationInterval: config.KeyRotationInterval,
+			SaltLength:          config.SaltLength,
+			IterationCount:      config.IterationCount,
+			KeyDerivationFunc:   config.KeyDerivationFunc,
+			PreservationRules:   config.PreservationRules,
+			AuditEnabled:        config.AuditEnabled,
+			Keys:                []KeyInfo{},
+		}
+		for _, key := range e.pseudonymizer.RedactedKeys() {
+			settings.Keys = append(settings.Keys, KeyInfo{
+				ID:        key.ID,
+				Algorithm: key.Algorithm,
+				Status:    key.Status,
+				Purpose:   key.Purpose,
+				CreatedAt: key.CreatedAt,
+				ExpiresAt: key.ExpiresAt,
+			})
+		}
+		bundle.Pseudonymization = settings
+	}
+
+	return bundle
+}
+
+// ExportComplianceBundle writes a snapshot of the current policies, roles
+// and holds to w as indented JSON
+func (e *BundleExporter) ExportComplianceBundle(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(e.Bundle()); err != nil {
+		return fmt.Errorf("failed to write compliance bundle: %w", err)
+	}
+	return nil
+}
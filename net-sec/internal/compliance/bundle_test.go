@@ -0,0 +1,134 @@
+package compliance
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+func TestExportComplianceBundle(t *testing.T) {
+	discard := logger.New("error", "text", io.Discard)
+
+	scheduler := retention.NewRetentionScheduler(nil)
+	defer scheduler.Shutdown()
+	scheduler.SetLogger(discard.Component("retention"))
+	policies := retention.DefaultPolicies()
+	for _, policy := range policies {
+		if err := scheduler.AddRetentionPolicy(policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Replacing a policy bumps its version
+	updated := *policies[0]
+	updated.RetentionPeriod = 3 * 365 * 24 * time.Hour
+	if err := scheduler.AddRetentionPolicy(&updated); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	for _, hold := range []*retention.LegalHold{
+		{ID: "hold-litigation", Name: "Litigation", DataQuery: map[string]interface{}{"subject_id": "subject_1"}},
+		{ID: "hold-expired", Name: "Expired", DataQuery: map[string]interface{}{"subject_id": "subject_2"}, ExpiresAt: &expired},
+	} {
+		if err := scheduler.CreateLegalHold(hold); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	access := rbac.NewAccessController(&rbac.RBACConfig{SessionTimeout: time.Hour}, nil)
+	access.SetLogger(discard.Component("rbac"))
+
+	engine, err := privacy.NewPseudonymizationEngine(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := NewBundleExporter(scheduler, access, engine)
+	exporter.now = func() time.Time { return time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC) }
+
+	var buf bytes.Buffer
+	if err := exporter.ExportComplianceBundle(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle Bundle
+	decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&bundle); err != nil {
+		t.Fatalf("bundle does not match schema: %v", err)
+	}
+
+	if bundle.SchemaVersion != BundleSchemaVersion || !bundle.GeneratedAt.Equal(exporter.now()) {
+		t.Errorf("unexpected bundle header: %s %s", bundle.SchemaVersion, bundle.GeneratedAt)
+	}
+
+	if len(bundle.RetentionPolicies) != len(policies) {
+		t.Fatalf("expected %d policies, got %d", len(policies), len(bundle.RetentionPolicies))
+	}
+	versions := make(map[string]int)
+	for _, policy := range bundle.RetentionPolicies {
+		versions[policy.ID] = policy.Version
+	}
+	for _, policy := range policies {
+		want := 1
+		if policy.ID == updated.ID {
+			want = 2
+		}
+		if versions[policy.ID] != want {
+			t.Errorf("policy %s: expected version %d, got %d", policy.ID, want, versions[policy.ID])
+		}
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range bundle.Roles {
+		roles[role.ID] = true
+	}
+	for _, role := range access.Roles() {
+		if !roles[role.ID] {
+			t.Errorf("bundle is missing role %s", role.ID)
+		}
+	}
+	if len(bundle.Permissions) != len(access.Permissions()) {
+		t.Errorf("expected %d permissions, got %d", len(access.Permissions()), len(bundle.Permissions))
+	}
+
+	if len(bundle.LegalHolds) != 1 || bundle.LegalHolds[0].ID != "hold-litigation" {
+		t.Errorf("expected only the active legal hold, got %+v", bundle.LegalHolds)
+	}
+
+	settings := bundle.Pseudonymization
+	if settings == nil || settings.Algorithm != privacy.AES256Encryption || len(settings.Keys) != 1 {
+		t.Fatalf("unexpected pseudonymization settings: %+v", settings)
+	}
+	if strings.Contains(buf.String(), "salt\"") {
+		t.Error("bundle discloses key salts")
+	}
+}
+
+func TestExportComplianceBundleWithoutComponents(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewBundleExporter(nil, nil, nil).ExportComplianceBundle(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range []string{"retention_policies", "legal_holds", "roles", "permissions"} {
+		if items, ok := bundle[section].([]interface{}); !ok || len(items) != 0 {
+			t.Errorf("expected empty %s section, got %v", section, bundle[section])
+		}
+	}
+	if _, ok := bundle["pseudonymization"]; ok {
+		t.Error("expected no pseudonymization section")
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// AgeSecretPrefix marks a config value as age-encrypted; the rest of the
+// value is the base64-encoded age ciphertext
+const AgeSecretPrefix = "secret:age:"
+
+// Environment variables holding the age identity used to decrypt secrets.
+// Without either, the crypto-kit private key in ~/.crypto-kit/keys is used.
+const (
+	AgeIdentityEnv     = "NETSEC_AGE_IDENTITY"
+	AgeIdentityFileEnv = "NETSEC_AGE_IDENTITY_FILE"
+)
+
+// NewAgeSecretEncryptor returns a SecretEncryptor that encrypts secrets to
+// the given age recipients, for use with SetSecretEncryptor
+func NewAgeSecretEncryptor(recipients ...age.Recipient) SecretEncryptor {
+	return func(plaintext string) (string, error) {
+		var buf bytes.Buffer
+		w, err := age.Encrypt(&buf, recipients...)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(w, plaintext); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		return AgeSecretPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+	}
+}
+
+// isEncryptedSecret reports whether value is an age-encrypted secret
+func isEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, AgeSecretPrefix)
+}
+
+// decryptSecrets replaces every age-encrypted string field of cfg with its
+// plaintext. The identity is only loaded when an encrypted value is found.
+func decryptSecrets(cfg *Config) error {
+	var identities []age.Identity
+	return decryptFields(reflect.ValueOf(cfg).Elem(), "", func(key, value string) (string, error) {
+		if identities == nil {
+			var err error
+			if identities, err = loadAgeIdentities(); err != nil {
+				return "", err
+			}
+		}
+
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, AgeSecretPrefix))
+		if err != nil {
+			return "", fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		r, err := age.Decrypt(bytes.NewReader(ciphertext), identities...)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt %s: %w", key, err)
+		}
+		plaintext, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt %s: %w", key, err)
+		}
+		return string(plaintext), nil
+	})
+}
+
+// decryptFields walks v and passes each encrypted string field, with its
+// dotted mapstructure key, to decrypt
+func decryptFields(v reflect.Value, prefix string, decrypt func(key, value string) (string, error)) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		key := prefix + v.Type().Field(i).Tag.Get("mapstructure")
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			if err := decryptFields(field, key+".", decrypt); err != nil {
+				return err
+			}
+		case field.Kind() == reflect.String && isEncryptedSecret(field.String()):
+			plaintext, err := decrypt(key, field.String())
+			if err != nil {
+				return err
+			}
+			field.SetString(plaintext)
+		}
+	}
+	return nil
+}
+
+// loadAgeIdentities reads the age identity from AgeIdentityEnv,
+// AgeIdentityFileEnv or the crypto-kit private key, in that order
+func loadAgeIdentities() ([]age.Identity, error) {
+	var source string
+	var data []byte
+
+	if identity := os.Getenv(AgeIdentityEnv); identity != "" {
+		source, data = AgeIdentityEnv, []byte(identity)
+	} else {
+		path := os.Getenv(AgeIdentityFileEnv)
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get user home directory: %w", err)
+			}
+			path = filepath.Join(home, ".crypto-kit", "keys", "private.age")
+		}
+
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read age identity: %w", err)
+		}
+		source = path
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identity from %s: %w", source, err)
+	}
+	return identities, nil
+}
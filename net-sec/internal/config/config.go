@@ -1,10 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
@@ -19,6 +23,7 @@ type Config struct {
 	Multipath  MultipathConfig  `mapstructure:"multipath"`
 	Monitoring MonitoringConfig `mapstructure:"monitoring"`
 	Export     ExportConfig     `mapstructure:"export"`
+	Vault      VaultConfig      `mapstructure:"vault"`
 }
 
 // WireGuardConfig contains WireGuard-specific configuration
@@ -66,6 +71,7 @@ type MonitoringConfig struct {
 	LogOutput       string  `mapstructure:"log_output"`
 	EnableAlerts    bool    `mapstructure:"enable_alerts"`
 	MetricsEndpoint string  `mapstructure:"metrics_endpoint"`
+	MetricsToken    string  `mapstructure:"metrics_token" secret:"true"`
 }
 
 // ExportConfig contains export configuration
@@ -76,121 +82,257 @@ type ExportConfig struct {
 	TemplatesDir    string `mapstructure:"templates_dir"`
 }
 
-var globalConfig *Config
+// VaultConfig contains secrets vault connection settings
+type VaultConfig struct {
+	Address  string `mapstructure:"address"`
+	Token    string `mapstructure:"token" secret:"true"`
+	RoleID   string `mapstructure:"role_id"`
+	SecretID string `mapstructure:"secret_id" secret:"true"`
+}
+
+var (
+	globalConfig *Config
+	configMu     sync.RWMutex
+)
+
+// configName is the base name of the config file
+const configName = ".net-sec"
+
+// DefaultFormat is the config format used when no config file exists
+const DefaultFormat = "yaml"
+
+// SupportedFormats lists the config file formats in lookup order
+var SupportedFormats = []string{"yaml", "yml", "json", "toml"}
 
 // Init initializes the configuration system
 func Init() error {
-	// Set default values
-	setDefaults()
+	return loadConfig()
+}
+
+// Reload re-reads the configuration file and replaces the global
+// configuration. The running configuration, viper settings and directories
+// are left untouched if the new one fails to load or validate.
+func Reload() error {
+	if err := loadConfig(); err != nil {
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+	return nil
+}
 
-	// Set up viper
-	viper.SetConfigName(".net-sec")
-	viper.SetConfigType("yaml")
+// Watch reloads the configuration whenever the config file changes. onReload
+// is called after each reload attempt with the resulting error, if any.
+func Watch(onReload func(error)) {
+	// A separate viper watches the file, as it reads every change before
+	// Reload can validate it
+	watcher := viper.New()
+	watcher.SetConfigFile(viper.ConfigFileUsed())
+	watcher.SetConfigType(configType(viper.ConfigFileUsed()))
+	watcher.OnConfigChange(func(event fsnotify.Event) {
+		err := Reload()
+		if onReload != nil {
+			onReload(err)
+		}
+	})
+	watcher.WatchConfig()
+}
 
-	// Add config paths
+// loadConfig reads and validates the config file in a fresh viper. Only a
+// valid configuration is applied: its directories are created, and it
+// replaces the global viper settings and configuration.
+func loadConfig() error {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("failed to get user home directory: %w", err)
 	}
 
-	viper.AddConfigPath(home)
-	viper.AddConfigPath("/etc/net-sec/")
-	viper.AddConfigPath(".")
+	// Read the file once so the validated and applied settings match
+	path, format := findConfigFile([]string{home, "/etc/net-sec/", "."})
+	var data []byte
+	if path != "" {
+		if data, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	v := viper.New()
+	configure(v, path, format)
+	cfg, err := load(v, data)
+	if err != nil {
+		return err
+	}
+
+	// Ensure data directories exist
+	if err := ensureDirectories(cfg); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	global := viper.GetViper()
+	configure(global, path, format)
+	if err := global.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	setGlobal(cfg)
+	return nil
+}
+
+// configure sets up v with the defaults, the config file at path in format,
+// if one was found, and NETSEC_ environment overrides
+func configure(v *viper.Viper, path, format string) {
+	setDefaults(v)
+	if path != "" {
+		v.SetConfigFile(path)
+	}
+	v.SetConfigType(format)
 
 	// Set environment variable prefix
-	viper.SetEnvPrefix("NETSEC")
-	viper.AutomaticEnv()
+	v.SetEnvPrefix("NETSEC")
+	v.AutomaticEnv()
+}
 
-	// Try to read config file
-	if err := viper.ReadInConfig(); err != nil {
-		// Config file not found is OK, we'll use defaults
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return fmt.Errorf("failed to read config file: %w", err)
+// configType returns the config format of path, as found by findConfigFile
+func configType(path string) string {
+	if filepath.Base(path) == configName {
+		return DefaultFormat
+	}
+	return strings.TrimPrefix(filepath.Ext(path), ".")
+}
+
+// findConfigFile returns the first config file found in dirs and its format.
+// Formats are tried in SupportedFormats order within each directory; an
+// extensionless file is read as YAML.
+func findConfigFile(dirs []string) (string, string) {
+	for _, dir := range dirs {
+		for _, format := range SupportedFormats {
+			path := filepath.Join(dir, configName+"."+format)
+			if fileExists(path) {
+				return path, format
+			}
+		}
+
+		if path := filepath.Join(dir, configName); fileExists(path) {
+			return path, DefaultFormat
 		}
 	}
 
+	return "", DefaultFormat
+}
+
+// fileExists reports whether path is an existing regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// load reads data, the contents of the config file, into v and unmarshals
+// and validates the configuration. Empty data leaves the defaults in place.
+func load(v *viper.Viper, data []byte) (*Config, error) {
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
 	// Unmarshal config
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	// Ensure data directories exist
-	if err := ensureDirectories(&cfg); err != nil {
-		return fmt.Errorf("failed to create directories: %w", err)
+	// Decrypt age-encrypted secrets; viper keeps the encrypted form
+	if err := decryptSecrets(&cfg); err != nil {
+		return nil, err
 	}
 
-	globalConfig = &cfg
-	return nil
+	if err := Validate(&cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// setGlobal replaces the global configuration
+func setGlobal(cfg *Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	globalConfig = cfg
 }
 
 // Get returns the global configuration
 func Get() *Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
 	if globalConfig == nil {
 		panic("configuration not initialized")
 	}
 	return globalConfig
 }
 
-// setDefaults sets default configuration values
-func setDefaults() {
+// setDefaults sets default configuration values on v
+func setDefaults(v *viper.Viper) {
 	home, _ := os.UserHomeDir()
 	dataDir := filepath.Join(home, ".net-sec")
 
 	// General defaults
-	viper.SetDefault("log_level", "info")
-	viper.SetDefault("log_format", "text")
-	viper.SetDefault("data_dir", dataDir)
-	viper.SetDefault("config_dir", dataDir)
+	v.SetDefault("log_level", "info")
+	v.SetDefault("log_format", "text")
+	v.SetDefault("data_dir", dataDir)
+	v.SetDefault("config_dir", dataDir)
 
 	// WireGuard defaults
-	viper.SetDefault("wireguard.keys_dir", filepath.Join(dataDir, "keys"))
-	viper.SetDefault("wireguard.configs_dir", filepath.Join(dataDir, "configs"))
-	viper.SetDefault("wireguard.default_dns", []string{"1.1.1.1", "9.9.9.9"})
-	viper.SetDefault("wireguard.default_mtu", 1420)
-	viper.SetDefault("wireguard.default_port", 51820)
-	viper.SetDefault("wireguard.allowed_ips", []string{"0.0.0.0/0", "::/0"})
-	viper.SetDefault("wireguard.keepalive", 25)
-	viper.SetDefault("wireguard.post_up", []string{})
-	viper.SetDefault("wireguard.post_down", []string{})
+	v.SetDefault("wireguard.keys_dir", filepath.Join(dataDir, "keys"))
+	v.SetDefault("wireguard.configs_dir", filepath.Join(dataDir, "configs"))
+	v.SetDefault("wireguard.default_dns", []string{"1.1.1.1", "9.9.9.9"})
+	v.SetDefault("wireguard.default_mtu", 1420)
+	v.SetDefault("wireguard.default_port", 51820)
+	v.SetDefault("wireguard.allowed_ips", []string{"0.0.0.0/0", "::/0"})
+	v.SetDefault("wireguard.keepalive", 25)
+	v.SetDefault("wireguard.post_up", []string{})
+	v.SetDefault("wireguard.post_down", []string{})
 
 	// Captive portal defaults
-	viper.SetDefault("captive.test_urls", []string{
+	v.SetDefault("captive.test_urls", []string{
 		"http://clients3.google.com/generate_204",
 		"http://detectportal.firefox.com/canonical.html",
 		"http://www.msftconnecttest.com/connecttest.txt",
 	})
-	viper.SetDefault("captive.expected_status", 204)
-	viper.SetDefault("captive.timeout", 10)
-	viper.SetDefault("captive.retries", 3)
-	viper.SetDefault("captive.interval", 5)
-	viper.SetDefault("captive.user_agent", "Mozilla/5.0 (compatible; net-sec/1.0)")
-	viper.SetDefault("captive.follow_redirects", false)
-	viper.SetDefault("captive.check_dns", true)
+	v.SetDefault("captive.expected_status", 204)
+	v.SetDefault("captive.timeout", 10)
+	v.SetDefault("captive.retries", 3)
+	v.SetDefault("captive.interval", 5)
+	v.SetDefault("captive.user_agent", "Mozilla/5.0 (compatible; net-sec/1.0)")
+	v.SetDefault("captive.follow_redirects", false)
+	v.SetDefault("captive.check_dns", true)
 
 	// Multipath defaults
-	viper.SetDefault("multipath.primary_interface", "")
-	viper.SetDefault("multipath.backup_interface", "")
-	viper.SetDefault("multipath.failover_threshold", 3)
-	viper.SetDefault("multipath.recovery_threshold", 5)
-	viper.SetDefault("multipath.check_interval", 5)
-	viper.SetDefault("multipath.enable_kill_switch", false)
-	viper.SetDefault("multipath.dns_servers", []string{"1.1.1.1", "9.9.9.9"})
-	viper.SetDefault("multipath.routing_table", "main")
+	v.SetDefault("multipath.primary_interface", "")
+	v.SetDefault("multipath.backup_interface", "")
+	v.SetDefault("multipath.failover_threshold", 3)
+	v.SetDefault("multipath.recovery_threshold", 5)
+	v.SetDefault("multipath.check_interval", 5)
+	v.SetDefault("multipath.enable_kill_switch", false)
+	v.SetDefault("multipath.dns_servers", []string{"1.1.1.1", "9.9.9.9"})
+	v.SetDefault("multipath.routing_table", "main")
 
 	// Monitoring defaults
-	viper.SetDefault("monitoring.enabled", false)
-	viper.SetDefault("monitoring.interval", 30)
-	viper.SetDefault("monitoring.alert_threshold", 0.9)
-	viper.SetDefault("monitoring.log_output", "")
-	viper.SetDefault("monitoring.enable_alerts", false)
-	viper.SetDefault("monitoring.metrics_endpoint", "")
+	v.SetDefault("monitoring.enabled", false)
+	v.SetDefault("monitoring.interval", 30)
+	v.SetDefault("monitoring.alert_threshold", 0.9)
+	v.SetDefault("monitoring.log_output", "")
+	v.SetDefault("monitoring.enable_alerts", false)
+	v.SetDefault("monitoring.metrics_endpoint", "")
+	v.SetDefault("monitoring.metrics_token", "")
 
 	// Export defaults
-	viper.SetDefault("export.ios_organization", "StealthGuard Technologies")
-	viper.SetDefault("export.ios_identifier", "com.stealthguard.wireguard")
-	viper.SetDefault("export.android_package", "com.wireguard.android")
-	viper.SetDefault("export.templates_dir", filepath.Join(dataDir, "templates"))
+	v.SetDefault("export.ios_organization", "StealthGuard Technologies")
+	v.SetDefault("export.ios_identifier", "com.stealthguard.wireguard")
+	v.SetDefault("export.android_package", "com.wireguard.android")
+	v.SetDefault("export.templates_dir", filepath.Join(dataDir, "templates"))
+
+	// Vault defaults
+	v.SetDefault("vault.address", "")
+	v.SetDefault("vault.token", "")
+	v.SetDefault("vault.role_id", "")
+	v.SetDefault("vault.secret_id", "")
 }
 
 // ensureDirectories creates necessary directories
@@ -214,12 +356,26 @@ func ensureDirectories(cfg *Config) error {
 	return nil
 }
 
-// WriteConfig writes the current configuration to file
+// WriteConfig writes the current configuration to the file it was loaded
+// from, or to a new YAML file in the home directory if none was found.
+// Secret fields are encrypted if a SecretEncryptor is set.
 func WriteConfig() error {
-	return viper.WriteConfig()
+	if viper.ConfigFileUsed() != "" && fileExists(viper.ConfigFileUsed()) {
+		return writeWithEncryptedSecrets(viper.WriteConfig)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return WriteConfigAs(filepath.Join(home, configName+"."+DefaultFormat))
 }
 
-// WriteConfigAs writes the current configuration to a specific file
+// WriteConfigAs writes the current configuration to a specific file. The
+// format follows the file extension, falling back to the loaded config's
+// format for files without one.
 func WriteConfigAs(filename string) error {
-	return viper.WriteConfigAs(filename)
+	return writeWithEncryptedSecrets(func() error {
+		return viper.WriteConfigAs(filename)
+	})
 }
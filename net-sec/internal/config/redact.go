@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/spf13/viper"
+)
+
+// redactedValue replaces secret values in redacted configs
+const redactedValue = "[REDACTED]"
+
+// SecretEncryptor encrypts a secret config value before it is written to disk
+type SecretEncryptor func(plaintext string) (string, error)
+
+var secretEncryptor SecretEncryptor
+
+// SetSecretEncryptor sets the function used by WriteConfig and WriteConfigAs
+// to encrypt fields tagged secret. Secrets are written as-is when nil.
+func SetSecretEncryptor(encryptor SecretEncryptor) {
+	secretEncryptor = encryptor
+}
+
+// Redacted returns a deep copy of the config with secret fields masked, safe
+// for logging and display
+func (c *Config) Redacted() *Config {
+	redacted := deepCopy(reflect.ValueOf(c).Elem()).Addr().Interface().(*Config)
+	redactSecrets(reflect.ValueOf(redacted).Elem())
+
+	// Credentials embedded in URLs are secret too
+	redacted.Monitoring.MetricsEndpoint = redactURL(redacted.Monitoring.MetricsEndpoint)
+	redacted.Vault.Address = redactURL(redacted.Vault.Address)
+
+	return redacted
+}
+
+// String returns the redacted config as JSON
+func (c *Config) String() string {
+	data, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("<config: %v>", err)
+	}
+	return string(data)
+}
+
+// deepCopy returns an addressable copy of v that shares no slices or maps
+func deepCopy(v reflect.Value) reflect.Value {
+	copied := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return copied
+		}
+		copied.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return copied
+		}
+		copied.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+	default:
+		copied.Set(v)
+	}
+
+	return copied
+}
+
+// redactSecrets masks every non-empty string field tagged secret:"true"
+func redactSecrets(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		structField := v.Type().Field(i)
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			redactSecrets(field)
+		case structField.Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "":
+			field.SetString(redactedValue)
+		}
+	}
+}
+
+// redactURL masks the password of a URL with embedded credentials
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); !ok {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// secretKeys returns the viper keys of the fields tagged secret
+func secretKeys() []string {
+	return collectSecretKeys(reflect.TypeOf(Config{}), "")
+}
+
+// collectSecretKeys walks t and returns the dotted mapstructure keys of its
+// secret fields
+func collectSecretKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, collectSecretKeys(field.Type, key+".")...)
+		} else if field.Tag.Get("secret") == "true" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// writeWithEncryptedSecrets runs write with the secret values in viper
+// replaced by their encrypted form, restoring the plaintext afterwards
+func writeWithEncryptedSecrets(write func() error) error {
+	if secretEncryptor == nil {
+		return write()
+	}
+
+	plaintext := make(map[string]string)
+	defer func() {
+		for key, value := range plaintext {
+			viper.Set(key, value)
+		}
+	}()
+
+	for _, key := range secretKeys() {
+		value := viper.GetString(key)
+		if value == "" || isEncryptedSecret(value) {
+			continue
+		}
+
+		encrypted, err := secretEncryptor(value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		plaintext[key] = value
+		viper.Set(key, encrypted)
+	}
+
+	return write()
+}
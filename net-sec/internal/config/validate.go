@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+// Error returns all validation problems as a single message
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// Validate checks configuration values for problems that would otherwise
+// surface as confusing failures in the subsystems using them. All problems
+// are reported in a single *ValidationError.
+func Validate(cfg *Config) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// WireGuard
+	if cfg.WireGuard.DefaultMTU < 1280 || cfg.WireGuard.DefaultMTU > 1500 {
+		addf("wireguard.default_mtu must be between 1280 and 1500, got %d", cfg.WireGuard.DefaultMTU)
+	}
+	if cfg.WireGuard.DefaultPort < 1 || cfg.WireGuard.DefaultPort > 65535 {
+		addf("wireguard.default_port must be between 1 and 65535, got %d", cfg.WireGuard.DefaultPort)
+	}
+	if cfg.WireGuard.Keepalive < 0 || cfg.WireGuard.Keepalive > 65535 {
+		addf("wireguard.keepalive must be between 0 and 65535, got %d", cfg.WireGuard.Keepalive)
+	}
+	for _, dns := range cfg.WireGuard.DefaultDNS {
+		if net.ParseIP(dns) == nil {
+			addf("wireguard.default_dns contains invalid IP %q", dns)
+		}
+	}
+
+	// Captive portal detection
+	if cfg.Captive.ExpectedStatus < 100 || cfg.Captive.ExpectedStatus > 599 {
+		addf("captive.expected_status must be a valid HTTP status code, got %d", cfg.Captive.ExpectedStatus)
+	}
+	if cfg.Captive.Timeout <= 0 {
+		addf("captive.timeout must be positive, got %d", cfg.Captive.Timeout)
+	}
+
+	// Multipath
+	if cfg.Multipath.FailoverThreshold <= 0 {
+		addf("multipath.failover_threshold must be positive, got %d", cfg.Multipath.FailoverThreshold)
+	}
+	if cfg.Multipath.RecoveryThreshold <= 0 {
+		addf("multipath.recovery_threshold must be positive, got %d", cfg.Multipath.RecoveryThreshold)
+	}
+	if cfg.Multipath.CheckInterval <= 0 {
+		addf("multipath.check_interval must be positive, got %d", cfg.Multipath.CheckInterval)
+	}
+	for _, dns := range cfg.Multipath.DNSServers {
+		if net.ParseIP(dns) == nil {
+			addf("multipath.dns_servers contains invalid IP %q", dns)
+		}
+	}
+
+	// Directories
+	dirs := []struct{ key, path string }{
+		{"data_dir", cfg.DataDir},
+		{"config_dir", cfg.ConfigDir},
+		{"wireguard.keys_dir", cfg.WireGuard.KeysDir},
+		{"wireguard.configs_dir", cfg.WireGuard.ConfigsDir},
+		{"export.templates_dir", cfg.Export.TemplatesDir},
+	}
+	for _, dir := range dirs {
+		if dir.path == "" {
+			continue
+		}
+		if err := checkCreatable(dir.path); err != nil {
+			addf("%s %s is not writable: %v", dir.key, dir.path, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// CheckWritable verifies a file can be created in dir
+func CheckWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".net-sec-write-check-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// checkCreatable verifies dir is writable or, when it does not exist yet,
+// that it can be created in its nearest existing parent, without creating it
+func checkCreatable(dir string) error {
+	for path := filepath.Clean(dir); ; path = filepath.Dir(path) {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) && filepath.Dir(path) != path {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", path)
+		}
+		return CheckWritable(path)
+	}
+}
@@ -0,0 +1,277 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/config"
+	"github.com/stealthguard/net-sec/internal/monitor"
+)
+
+// Severity determines whether a failed check makes the environment unusable
+type Severity int
+
+const (
+	SeverityCritical Severity = iota
+	SeverityWarning
+)
+
+// String returns the string representation of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityCritical:
+		return "CRITICAL"
+	case SeverityWarning:
+		return "WARNING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Check is a single environment check
+type Check struct {
+	Name     string
+	Severity Severity
+	Hint     string // Remediation shown when the check fails
+	Run      func(ctx context.Context) error
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string        `json:"name"`
+	Severity Severity      `json:"severity"`
+	Passed   bool          `json:"passed"`
+	Error    string        `json:"error,omitempty"`
+	Hint     string        `json:"hint,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report contains the results of all checks
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// CriticalFailures returns the failed critical checks
+func (r *Report) CriticalFailures() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if !result.Passed && result.Severity == SeverityCritical {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Binary is an external program the tool depends on
+type Binary struct {
+	Name     string
+	Severity Severity
+	Hint     string
+}
+
+// DefaultBinaries lists the external programs checked by default
+func DefaultBinaries() []Binary {
+	return []Binary{
+		{Name: "wg", Severity: SeverityCritical, Hint: "install wireguard-tools (e.g. sudo apt install wireguard-tools)"},
+		{Name: "wg-quick", Severity: SeverityWarning, Hint: "install wireguard-tools to bring tunnels up with wg-quick"},
+		{Name: "cryptsetup", Severity: SeverityWarning, Hint: "install cryptsetup to use encrypted key storage (e.g. sudo apt install cryptsetup)"},
+	}
+}
+
+// Options configures the checks run by a Doctor
+type Options struct {
+	Config   *config.Config
+	Binaries []Binary
+	Timeout  time.Duration // Per-check timeout
+
+	// LookPath resolves binaries; exec.LookPath when nil
+	LookPath func(file string) (string, error)
+	// Resolver is the system resolver; net.DefaultResolver when nil
+	Resolver monitor.Resolver
+	// ServerResolver returns a resolver querying a configured DNS server;
+	// monitor.ServerResolver when nil
+	ServerResolver func(server string) monitor.Resolver
+	// HTTPClient performs connectivity probes
+	HTTPClient *http.Client
+}
+
+// Doctor runs environment self-checks
+type Doctor struct {
+	opts Options
+}
+
+// New creates a Doctor, filling unset options with their defaults
+func New(opts Options) *Doctor {
+	if opts.Binaries == nil {
+		opts.Binaries = DefaultBinaries()
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.LookPath == nil {
+		opts.LookPath = exec.LookPath
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = net.DefaultResolver
+	}
+	if opts.ServerResolver == nil {
+		opts.ServerResolver = func(server string) monitor.Resolver {
+			return monitor.ServerResolver(server)
+		}
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{
+			Timeout: opts.Timeout,
+			// A redirect means a captive portal, not connectivity
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return &Doctor{opts: opts}
+}
+
+// Checks returns the checks in the order they run
+func (d *Doctor) Checks() []Check {
+	cfg := d.opts.Config
+	checks := []Check{{
+		Name:     "configuration",
+		Severity: SeverityCritical,
+		Hint:     "fix the reported values in your config file",
+		Run: func(context.Context) error {
+			return config.Validate(cfg)
+		},
+	}}
+
+	// Directories
+	dirs := []struct{ key, path string }{
+		{"data_dir", cfg.DataDir},
+		{"config_dir", cfg.ConfigDir},
+		{"wireguard.keys_dir", cfg.WireGuard.KeysDir},
+		{"wireguard.configs_dir", cfg.WireGuard.ConfigsDir},
+		{"export.templates_dir", cfg.Export.TemplatesDir},
+	}
+	for _, dir := range dirs {
+		if dir.path == "" {
+			continue
+		}
+		path := dir.path
+		checks = append(checks, Check{
+			Name:     fmt.Sprintf("directory %s (%s)", dir.key, path),
+			Severity: SeverityCritical,
+			Hint:     fmt.Sprintf("create %s and make it writable by the current user, or change %s", path, dir.key),
+			Run: func(context.Context) error {
+				return config.CheckWritable(path)
+			},
+		})
+	}
+
+	// Binaries
+	for _, binary := range d.opts.Binaries {
+		name := binary.Name
+		checks = append(checks, Check{
+			Name:     "binary " + name,
+			Severity: binary.Severity,
+			Hint:     binary.Hint,
+			Run: func(context.Context) error {
+				_, err := d.opts.LookPath(name)
+				return err
+			},
+		})
+	}
+
+	// DNS resolution through the system resolver
+	var testURL, testHost string
+	if len(cfg.Captive.TestURLs) > 0 {
+		testURL = cfg.Captive.TestURLs[0]
+		if u, err := url.Parse(testURL); err == nil {
+			testHost = u.Hostname()
+		}
+	}
+	if testHost != "" {
+		checks = append(checks, Check{
+			Name:     "system resolver",
+			Severity: SeverityCritical,
+			Hint:     "check /etc/resolv.conf and that a network connection is available",
+			Run: func(ctx context.Context) error {
+				return dnsError(monitor.ProbeDNS(ctx, d.opts.Resolver, []string{testHost}))
+			},
+		})
+	}
+
+	// Configured DNS servers
+	for _, server := range cfg.Multipath.DNSServers {
+		server := server
+		checks = append(checks, Check{
+			Name:     "dns server " + server,
+			Severity: SeverityWarning,
+			Hint:     fmt.Sprintf("make sure UDP port 53 to %s is not blocked, or change multipath.dns_servers", server),
+			Run: func(ctx context.Context) error {
+				if testHost == "" {
+					return nil
+				}
+				return dnsError(monitor.ProbeDNS(ctx, d.opts.ServerResolver(server), []string{testHost}))
+			},
+		})
+	}
+
+	// Internet connectivity
+	if testURL != "" {
+		checks = append(checks, Check{
+			Name:     "connectivity " + testURL,
+			Severity: SeverityWarning,
+			Hint:     "run 'net-sec detect' to check for a captive portal",
+			Run: func(ctx context.Context) error {
+				result := monitor.ProbeHTTP(ctx, d.opts.HTTPClient, testURL, cfg.Captive.ExpectedStatus)
+				if !result.InternetAccess {
+					return errors.New(strings.Join(result.ErrorDetails, "; "))
+				}
+				return nil
+			},
+		})
+	}
+
+	return checks
+}
+
+// Run runs every check and reports the results
+func (d *Doctor) Run(ctx context.Context) *Report {
+	report := &Report{}
+
+	for _, check := range d.Checks() {
+		checkCtx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+		start := time.Now()
+		err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{
+			Name:     check.Name,
+			Severity: check.Severity,
+			Passed:   err == nil,
+			Duration: time.Since(start),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.Hint = check.Hint
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
+
+// dnsError converts a failed DNS probe to an error
+func dnsError(result monitor.DNSTestResult) error {
+	if result.Successful {
+		return nil
+	}
+	return fmt.Errorf("failed to resolve %v", result.FailedDomains)
+}
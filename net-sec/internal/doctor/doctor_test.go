@@ -0,0 +1,167 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stealthguard/net-sec/internal/config"
+	"github.com/stealthguard/net-sec/internal/monitor"
+)
+
+// staticResolver answers every lookup with the same result
+type staticResolver struct {
+	err error
+}
+
+func (r staticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []string{"192.0.2.1"}, nil
+}
+
+// healthyConfig returns a valid config whose checks all pass against server
+func healthyConfig(t *testing.T, server *httptest.Server) *config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := &config.Config{DataDir: dir, ConfigDir: dir}
+	cfg.WireGuard.DefaultMTU = 1420
+	cfg.WireGuard.DefaultPort = 51820
+	cfg.Captive.TestURLs = []string{server.URL + "/generate_204"}
+	cfg.Captive.ExpectedStatus = http.StatusNoContent
+	cfg.Captive.Timeout = 5
+	cfg.Multipath.FailoverThreshold = 3
+	cfg.Multipath.RecoveryThreshold = 5
+	cfg.Multipath.CheckInterval = 5
+	cfg.Multipath.DNSServers = []string{"192.0.2.53"}
+	return cfg
+}
+
+func newTestDoctor(cfg *config.Config, missing ...string) *Doctor {
+	return New(Options{
+		Config: cfg,
+		LookPath: func(file string) (string, error) {
+			for _, name := range missing {
+				if name == file {
+					return "", errors.New("executable file not found in $PATH")
+				}
+			}
+			return "/usr/bin/" + file, nil
+		},
+		Resolver: staticResolver{},
+		ServerResolver: func(string) monitor.Resolver {
+			return staticResolver{}
+		},
+	})
+}
+
+func newConnectivityServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+// failedChecks returns the names of the failed checks
+func failedChecks(report *Report) map[string]Severity {
+	failed := make(map[string]Severity)
+	for _, result := range report.Results {
+		if !result.Passed {
+			failed[result.Name] = result.Severity
+		}
+	}
+	return failed
+}
+
+func TestDoctorHealthyEnvironment(t *testing.T) {
+	server := newConnectivityServer()
+	defer server.Close()
+
+	report := newTestDoctor(healthyConfig(t, server)).Run(context.Background())
+
+	if failed := failedChecks(report); len(failed) > 0 {
+		t.Fatalf("expected all checks to pass, failed: %v", report.Results)
+	}
+	if len(report.CriticalFailures()) != 0 {
+		t.Fatal("expected no critical failures")
+	}
+}
+
+func TestDoctorMissingBinary(t *testing.T) {
+	server := newConnectivityServer()
+	defer server.Close()
+
+	report := newTestDoctor(healthyConfig(t, server), "wg", "cryptsetup").Run(context.Background())
+
+	failed := failedChecks(report)
+	if len(failed) != 2 {
+		t.Fatalf("expected 2 failed checks, got %v", failed)
+	}
+	if failed["binary wg"] != SeverityCritical {
+		t.Errorf("expected missing wg to be critical, got %v", failed)
+	}
+	if severity, ok := failed["binary cryptsetup"]; !ok || severity != SeverityWarning {
+		t.Errorf("expected missing cryptsetup to be a warning, got %v", failed)
+	}
+
+	critical := report.CriticalFailures()
+	if len(critical) != 1 || critical[0].Hint == "" {
+		t.Errorf("expected one critical failure with a hint, got %+v", critical)
+	}
+}
+
+func TestDoctorUnwritableDirectory(t *testing.T) {
+	server := newConnectivityServer()
+	defer server.Close()
+
+	cfg := healthyConfig(t, server)
+
+	// A directory below a regular file can never be created
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.WireGuard.KeysDir = filepath.Join(file, "keys")
+
+	report := newTestDoctor(cfg).Run(context.Background())
+
+	failed := failedChecks(report)
+	dirCheck := "directory wireguard.keys_dir (" + cfg.WireGuard.KeysDir + ")"
+	if failed[dirCheck] != SeverityCritical {
+		t.Errorf("expected %q to fail, got %v", dirCheck, failed)
+	}
+	if _, ok := failed["configuration"]; !ok {
+		t.Errorf("expected configuration validation to fail, got %v", failed)
+	}
+	if len(failed) != 2 {
+		t.Errorf("expected only the directory and configuration checks to fail, got %v", failed)
+	}
+}
+
+func TestDoctorNetworkFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/portal", http.StatusFound)
+	}))
+	defer server.Close()
+
+	cfg := healthyConfig(t, server)
+	d := newTestDoctor(cfg)
+	d.opts.Resolver = staticResolver{err: errors.New("no such host")}
+
+	failed := failedChecks(d.Run(context.Background()))
+
+	if failed["system resolver"] != SeverityCritical {
+		t.Errorf("expected system resolver failure, got %v", failed)
+	}
+	if severity, ok := failed["connectivity "+cfg.Captive.TestURLs[0]]; !ok || severity != SeverityWarning {
+		t.Errorf("expected connectivity warning for redirect, got %v", failed)
+	}
+	if _, ok := failed["dns server 192.0.2.53"]; ok {
+		t.Errorf("configured DNS server should still resolve, got %v", failed)
+	}
+}
@@ -0,0 +1,258 @@
+package erasure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// DataStoreSystem is the system name of the retention datastore in reports
+const DataStoreSystem = "datastore"
+
+// Erasure request outcomes
+const (
+	StatusCompleted = "completed" // Erased from every system
+	StatusPartial   = "partial"   // Erased from some systems; see the per-system results
+	StatusFailed    = "failed"    // Erased from no system
+	StatusBlocked   = "blocked"   // Refused because of an active legal hold
+	StatusDenied    = "denied"    // Refused by access control
+)
+
+// ErasureCoordinator carries out right-to-erasure requests (GDPR Article 17)
+// across the retention datastore and every registered integration
+type ErasureCoordinator struct {
+	retention    *retention.RetentionScheduler
+	integrations *integrations.IntegrationManager
+	access       *rbac.AccessController
+	auditLog     AuditLogger
+	logger       logger.StructuredLogger
+}
+
+// AuditLogger interface for erasure audit events
+type AuditLogger interface {
+	LogErasureRequest(report *ErasureReport)
+}
+
+// ErasureRequest asks for all data about a subject to be erased
+type ErasureRequest struct {
+	SubjectID     string `json:"subject_id"`
+	RequestedBy   string `json:"requested_by"`
+	SessionID     string `json:"session_id,omitempty"` // Checked for personal_data delete permission when access control is set
+	Justification string `json:"justification"`
+}
+
+// SystemResult is the outcome of an erasure request in a single system
+type SystemResult struct {
+	System            string `json:"system"`
+	RecordsDeleted    int    `json:"records_deleted"`
+	RecordsAnonymized int    `json:"records_anonymized,omitempty"`
+	Success           bool   `json:"success"`
+	Error             string `json:"error,omitempty"`
+}
+
+// ErasureReport is the consolidated outcome of an erasure request
+type ErasureReport struct {
+	ID            string         `json:"id"`
+	SubjectID     string         `json:"subject_id"`
+	RequestedBy   string         `json:"requested_by"`
+	Justification string         `json:"justification"`
+	Status        string         `json:"status"`
+	Reason        string         `json:"reason,omitempty"`         // Why a request was blocked, denied or failed
+	BlockingHolds []string       `json:"blocking_holds,omitempty"` // IDs of the legal holds blocking the request
+	Systems       []SystemResult `json:"systems"`
+	StartedAt     time.Time      `json:"started_at"`
+	CompletedAt   time.Time      `json:"completed_at"`
+}
+
+// NewErasureCoordinator creates a coordinator erasing data through the
+// scheduler's datastore and the manager's integrations. Either may be nil to
+// skip that side; access may be nil to skip the permission check.
+func NewErasureCoordinator(scheduler *retention.RetentionScheduler, manager *integrations.IntegrationManager, access *rbac.AccessController, auditLog AuditLogger) *ErasureCoordinator {
+	return &ErasureCoordinator{
+		retention:    scheduler,
+		integrations: manager,
+		access:       access,
+		auditLog:     auditLog,
+		logger:       logger.Component("erasure"),
+	}
+}
+
+// SetLogger sets the logger used for erasure requests
+func (ec *ErasureCoordinator) SetLogger(l logger.StructuredLogger) {
+	ec.logger = l
+}
+
+// Erase erases all data about req.SubjectID. Nothing is erased if access
+// control denies the request or any record is under an active legal hold.
+// Otherwise erasure continues past failures in individual systems, so the
+// report status and per-system results describe the outcome; the error is
+// only set for invalid requests.
+func (ec *ErasureCoordinator) Erase(ctx context.Context, req ErasureRequest) (*ErasureReport, error) {
+	if req.SubjectID == "" {
+		return nil, fmt.Errorf("data subject ID required for erasure")
+	}
+
+	report := &ErasureReport{
+		ID:            generateErasureID(),
+		SubjectID:     req.SubjectID,
+		RequestedBy:   req.RequestedBy,
+		Justification: req.Justification,
+		Systems:       make([]SystemResult, 0),
+		StartedAt:     time.Now(),
+	}
+	defer ec.finish(report)
+
+	if ec.access != nil {
+		accessContext := map[string]interface{}{
+			"justification": req.Justification,
+			"data_subject":  req.SubjectID,
+		}
+		if !ec.access.CheckAccess(req.SessionID, "personal_data", "delete", accessContext) {
+			report.Status = StatusDenied
+			report.Reason = "personal_data delete permission denied"
+			return report, nil
+		}
+	}
+
+	var records []retention.DataRecord
+	var store retention.DataStore
+	if ec.retention != nil {
+		store = ec.retention.DataStore()
+	}
+	if store != nil {
+		var err error
+		records, err = store.FindRecords(ctx, map[string]interface{}{"subject_id": req.SubjectID})
+		if err != nil {
+			// Without the records the legal holds on them cannot be checked
+			report.Status = StatusFailed
+			report.Reason = fmt.Sprintf("failed to find records: %v", err)
+			return report, nil
+		}
+	}
+
+	if holds := ec.blockingHolds(req.SubjectID, records); len(holds) > 0 {
+		report.Status = StatusBlocked
+		report.Reason = "data is under legal hold"
+		report.BlockingHolds = holds
+		return report, nil
+	}
+
+	if store != nil {
+		result := SystemResult{System: DataStoreSystem}
+		deleted, anonymized, err := ec.retention.PurgeRecords(ctx, records, req.RequestedBy)
+		result.RecordsDeleted = deleted
+		result.RecordsAnonymized = anonymized
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Systems = append(report.Systems, result)
+	}
+
+	if ec.integrations != nil {
+		for _, name := range ec.integrations.IntegrationNames() {
+			result := SystemResult{System: name}
+			deleted, err := ec.integrations.EraseSubjectData(ctx, name, req.SubjectID, req.RequestedBy)
+			result.RecordsDeleted = deleted
+			result.Success = err == nil
+			if err != nil {
+				result.Error = err.Error()
+			}
+			report.Systems = append(report.Systems, result)
+		}
+	}
+
+	report.Status = overallStatus(report.Systems)
+	if report.Status == StatusFailed {
+		report.Reason = "erasure failed in every system"
+	}
+	return report, nil
+}
+
+// blockingHolds returns the IDs of the legal holds covering the subject or
+// any category of their records
+func (ec *ErasureCoordinator) blockingHolds(subjectID string, records []retention.DataRecord) []string {
+	if ec.retention == nil {
+		return nil
+	}
+
+	queries := []map[string]interface{}{{"subject_id": subjectID}}
+	seenCategories := make(map[string]bool)
+	for _, record := range records {
+		if seenCategories[record.DataCategory] {
+			continue
+		}
+		seenCategories[record.DataCategory] = true
+		queries = append(queries, map[string]interface{}{
+			"subject_id":    subjectID,
+			"data_category": record.DataCategory,
+		})
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, query := range queries {
+		for _, hold := range ec.retention.ActiveLegalHolds(query) {
+			if !seen[hold.ID] {
+				seen[hold.ID] = true
+				ids = append(ids, hold.ID)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// finish completes the report and writes the consolidated audit record
+func (ec *ErasureCoordinator) finish(report *ErasureReport) {
+	report.CompletedAt = time.Now()
+
+	fields := logger.Fields{
+		"event_type":   "erasure_" + report.Status,
+		"erasure_id":   report.ID,
+		"subject_id":   report.SubjectID,
+		"requested_by": report.RequestedBy,
+		"systems":      len(report.Systems),
+	}
+	if report.Reason != "" {
+		fields["reason"] = report.Reason
+	}
+	if report.Status == StatusCompleted {
+		ec.logger.Info("Erasure request completed", fields)
+	} else {
+		ec.logger.Warn("Erasure request not completed", fields)
+	}
+
+	if ec.auditLog != nil {
+		ec.auditLog.LogErasureRequest(report)
+	}
+}
+
+// overallStatus derives the request status from the per-system results
+func overallStatus(results []SystemResult) string {
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	switch {
+	case succeeded == len(results):
+		return StatusCompleted
+	case succeeded > 0:
+		return StatusPartial
+	default:
+		return StatusFailed
+	}
+}
+
+func generateErasureID() string {
+	return fmt.Sprintf("erasure_%d", time.Now().UnixNano())
+}
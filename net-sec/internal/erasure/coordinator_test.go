@@ -0,0 +1,287 @@
+package erasure
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// memoryStore is an in-memory retention.DataStore
+type memoryStore struct {
+	mu         sync.Mutex
+	records    map[string]retention.DataRecord
+	anonymized map[string]bool
+}
+
+func newMemoryStore(records ...retention.DataRecord) *memoryStore {
+	store := &memoryStore{
+		records:    make(map[string]retention.DataRecord),
+		anonymized: make(map[string]bool),
+	}
+	for _, record := range records {
+		store.records[record.ID] = record
+	}
+	return store
+}
+
+func (s *memoryStore) FindRecords(ctx context.Context, query map[string]interface{}) ([]retention.DataRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []retention.DataRecord
+	for _, record := range s.records {
+		if record.SubjectID == query["subject_id"] && !s.anonymized[record.ID] {
+			found = append(found, record)
+		}
+	}
+	return found, nil
+}
+
+func (s *memoryStore) DeleteRecords(ctx context.Context, ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.records, id)
+	}
+	return len(ids), nil
+}
+
+func (s *memoryStore) AnonymizeRecords(ctx context.Context, ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		s.anonymized[id] = true
+	}
+	return len(ids), nil
+}
+
+// mockIntegration is an integration holding records per data subject
+type mockIntegration struct {
+	name     string
+	mu       sync.Mutex
+	subjects map[string]int
+	erased   []string
+}
+
+func (m *mockIntegration) Name() string                                     { return m.name }
+func (m *mockIntegration) Authenticate(credentials map[string]string) error { return nil }
+func (m *mockIntegration) SendData(ctx context.Context, data *integrations.IntegrationData) error {
+	return nil
+}
+func (m *mockIntegration) RetrieveData(ctx context.Context, query *integrations.DataQuery) (*integrations.IntegrationData, error) {
+	return nil, nil
+}
+func (m *mockIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	return nil
+}
+func (m *mockIntegration) ValidateConnection() error                    { return nil }
+func (m *mockIntegration) GetMetrics() *integrations.IntegrationMetrics { return nil }
+
+func (m *mockIntegration) EraseSubjectData(ctx context.Context, subjectID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.erased = append(m.erased, subjectID)
+	count := m.subjects[subjectID]
+	delete(m.subjects, subjectID)
+	return count, nil
+}
+
+// recordingAuditLog collects erasure reports
+type recordingAuditLog struct {
+	reports []*ErasureReport
+}
+
+func (r *recordingAuditLog) LogErasureRequest(report *ErasureReport) {
+	r.reports = append(r.reports, report)
+}
+
+// newTestCoordinator returns a coordinator over store and two integrations
+// holding records about subject_1
+func newTestCoordinator(t *testing.T, store *memoryStore) (*ErasureCoordinator, *retention.RetentionScheduler, []*mockIntegration, *recordingAuditLog) {
+	t.Helper()
+
+	scheduler := retention.NewRetentionScheduler(nil)
+	t.Cleanup(scheduler.Shutdown)
+	scheduler.SetLogger(logger.New("error", "text", io.Discard).Component("retention"))
+	for _, policy := range retention.DefaultPolicies() {
+		if err := scheduler.AddRetentionPolicy(policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scheduler.SetDataStore(store)
+
+	manager := integrations.NewIntegrationManager(&integrations.IntegrationConfig{}, nil, nil)
+	mocks := []*mockIntegration{
+		{name: "crm", subjects: map[string]int{"subject_1": 3}},
+		{name: "helpdesk", subjects: map[string]int{"subject_1": 2, "subject_2": 1}},
+	}
+	for _, mock := range mocks {
+		if err := manager.RegisterIntegration(mock); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auditLog := &recordingAuditLog{}
+	coordinator := NewErasureCoordinator(scheduler, manager, nil, auditLog)
+	coordinator.SetLogger(logger.New("error", "text", io.Discard).Component("erasure"))
+	return coordinator, scheduler, mocks, auditLog
+}
+
+func TestEraseBlockedByLegalHold(t *testing.T) {
+	store := newMemoryStore(
+		retention.DataRecord{ID: "r1", SubjectID: "subject_1", DataCategory: "personal"},
+		retention.DataRecord{ID: "r2", SubjectID: "subject_1", DataCategory: "transaction"},
+	)
+	coordinator, scheduler, mocks, auditLog := newTestCoordinator(t, store)
+
+	hold := &retention.LegalHold{
+		ID:        "hold_1",
+		Name:      "Tax audit",
+		DataQuery: map[string]interface{}{"data_category": "transaction"},
+		CreatedBy: "legal",
+	}
+	if err := scheduler.CreateLegalHold(hold); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := coordinator.Erase(context.Background(), ErasureRequest{SubjectID: "subject_1", RequestedBy: "dpo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Status != StatusBlocked {
+		t.Fatalf("expected blocked erasure, got %s (%s)", report.Status, report.Reason)
+	}
+	if len(report.BlockingHolds) != 1 || report.BlockingHolds[0] != "hold_1" {
+		t.Errorf("expected hold_1 to block erasure, got %v", report.BlockingHolds)
+	}
+	if len(report.Systems) != 0 {
+		t.Errorf("no system should be touched, got %v", report.Systems)
+	}
+	if len(store.records) != 2 || len(store.anonymized) != 0 {
+		t.Errorf("datastore was modified: %v, anonymized %v", store.records, store.anonymized)
+	}
+	for _, mock := range mocks {
+		if len(mock.erased) != 0 {
+			t.Errorf("%s was asked to erase despite the hold", mock.name)
+		}
+	}
+
+	if len(auditLog.reports) != 1 || auditLog.reports[0].Status != StatusBlocked {
+		t.Errorf("expected one blocked audit record, got %v", auditLog.reports)
+	}
+}
+
+func TestEraseBlockedByBlanketAndListHolds(t *testing.T) {
+	for name, query := range map[string]map[string]interface{}{
+		"blanket": {},
+		"list":    {"subject_id": []interface{}{"subject_1", "subject_3"}},
+	} {
+		store := newMemoryStore(retention.DataRecord{ID: "r1", SubjectID: "subject_1", DataCategory: "personal"})
+		coordinator, scheduler, _, _ := newTestCoordinator(t, store)
+
+		if err := scheduler.CreateLegalHold(&retention.LegalHold{ID: "hold_" + name, DataQuery: query}); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := coordinator.Erase(context.Background(), ErasureRequest{SubjectID: "subject_1", RequestedBy: "dpo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Status != StatusBlocked || len(report.BlockingHolds) != 1 || report.BlockingHolds[0] != "hold_"+name {
+			t.Errorf("%s: expected the hold to block erasure, got %s with holds %v", name, report.Status, report.BlockingHolds)
+		}
+		if len(store.records) != 1 {
+			t.Errorf("%s: datastore was modified: %v", name, store.records)
+		}
+	}
+}
+
+func TestEraseAcrossSystems(t *testing.T) {
+	store := newMemoryStore(
+		retention.DataRecord{ID: "r1", SubjectID: "subject_1", DataCategory: "personal"},
+		retention.DataRecord{ID: "r2", SubjectID: "subject_1", DataCategory: "log"},
+		retention.DataRecord{ID: "r3", SubjectID: "subject_1", DataCategory: "transaction"},
+		retention.DataRecord{ID: "r4", SubjectID: "subject_2", DataCategory: "personal"},
+	)
+	coordinator, scheduler, mocks, auditLog := newTestCoordinator(t, store)
+
+	// A hold on another subject does not block the request
+	hold := &retention.LegalHold{
+		ID:        "hold_other",
+		DataQuery: map[string]interface{}{"subject_id": "subject_2"},
+	}
+	if err := scheduler.CreateLegalHold(hold); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := coordinator.Erase(context.Background(), ErasureRequest{SubjectID: "subject_1", RequestedBy: "dpo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Status != StatusCompleted {
+		t.Fatalf("expected completed erasure, got %s (%s): %+v", report.Status, report.Reason, report.Systems)
+	}
+
+	want := []SystemResult{
+		{System: DataStoreSystem, RecordsDeleted: 2, RecordsAnonymized: 1, Success: true},
+		{System: "crm", RecordsDeleted: 3, Success: true},
+		{System: "helpdesk", RecordsDeleted: 2, Success: true},
+	}
+	if len(report.Systems) != len(want) {
+		t.Fatalf("expected %d system results, got %+v", len(want), report.Systems)
+	}
+	for i := range want {
+		if report.Systems[i] != want[i] {
+			t.Errorf("system %d: expected %+v, got %+v", i, want[i], report.Systems[i])
+		}
+	}
+
+	// Transaction records are anonymized under their policy, the rest deleted
+	if _, ok := store.records["r1"]; ok {
+		t.Error("personal record r1 was not deleted")
+	}
+	if !store.anonymized["r3"] {
+		t.Error("transaction record r3 was not anonymized")
+	}
+	if _, ok := store.records["r4"]; !ok {
+		t.Error("record of another subject was deleted")
+	}
+	if mocks[1].subjects["subject_2"] != 1 {
+		t.Error("integration records of another subject were erased")
+	}
+
+	if len(auditLog.reports) != 1 || auditLog.reports[0] != report {
+		t.Errorf("expected the report as the single audit record, got %v", auditLog.reports)
+	}
+}
+
+func TestEraseReportsUnsupportedIntegration(t *testing.T) {
+	coordinator, _, _, _ := newTestCoordinator(t, newMemoryStore())
+	if err := coordinator.integrations.RegisterIntegration(integrations.NewNotionIntegration("token")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := coordinator.Erase(context.Background(), ErasureRequest{SubjectID: "subject_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Status != StatusPartial {
+		t.Fatalf("expected partial erasure, got %s", report.Status)
+	}
+	for _, result := range report.Systems {
+		if result.System == "notion" && (result.Success || result.Error == "") {
+			t.Errorf("unsupported integration should fail with an error: %+v", result)
+		}
+	}
+}
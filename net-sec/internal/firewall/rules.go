@@ -0,0 +1,333 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Platforms a RuleBuilder can target
+const (
+	PlatformLinux   = "linux"   // iptables and ip6tables
+	PlatformDarwin  = "darwin"  // pf
+	PlatformWindows = "windows" // netsh advfirewall
+)
+
+// Rule actions
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+var (
+	// ErrUnsupportedPlatform is returned for platforms without a rule syntax
+	ErrUnsupportedPlatform = errors.New("unsupported firewall platform")
+	// ErrInvalidRule is returned when a spec cannot be expressed as rules
+	ErrInvalidRule = errors.New("invalid firewall rule")
+)
+
+// lanRanges are the private and link-local ranges allowed by
+// KillSwitch.AllowLAN
+var lanRanges = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"169.254.0.0/16",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// namePattern matches spec and interface names. "%i" is accepted so
+// wg-quick can substitute the tunnel interface.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9%][A-Za-z0-9_.%-]*$`)
+
+// Rule allows or denies outbound traffic to a destination
+type Rule struct {
+	Action      string // ActionAllow or ActionDeny
+	Destination string // IP or CIDR; empty matches any destination
+	Protocol    string // "tcp" or "udp"; empty matches any protocol
+	Port        int    // Destination port; requires Protocol
+}
+
+// KillSwitch blocks outbound traffic that does not leave through Interface
+type KillSwitch struct {
+	Interface      string   // Interface traffic is confined to
+	AllowLAN       bool     // Also allow traffic to private and link-local ranges
+	FWMark         string   // Linux: packets with this mark are exempt; inserted verbatim
+	LocalAddresses []string // Windows: addresses of Interface, as netsh has no interface filter
+}
+
+// Spec declares an outbound rule set. Rules are evaluated in order and the
+// first match wins, except on Windows where deny rules always take
+// precedence over allow rules. The kill switch, when set, applies to
+// traffic no rule matched.
+type Spec struct {
+	Name       string // Identifies the rule set when it is removed
+	Rules      []Rule
+	KillSwitch *KillSwitch
+}
+
+// RuleSet holds the shell commands that apply and remove a spec. On macOS
+// and Windows, Apply saves the state Remove restores in a file named after
+// the spec, so a rule set must be removed before it is applied again.
+type RuleSet struct {
+	Platform string
+	Apply    []string
+	Remove   []string
+}
+
+// RuleBuilder renders specs as platform firewall commands
+type RuleBuilder struct {
+	platform string
+}
+
+// NewRuleBuilder creates a builder for platform
+func NewRuleBuilder(platform string) (*RuleBuilder, error) {
+	switch platform {
+	case PlatformLinux, PlatformDarwin, PlatformWindows:
+		return &RuleBuilder{platform: platform}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platform)
+	}
+}
+
+// Build returns the commands applying and removing spec
+func (b *RuleBuilder) Build(spec Spec) (*RuleSet, error) {
+	rules, err := specRules(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &RuleSet{Platform: b.platform}
+	switch b.platform {
+	case PlatformLinux:
+		buildIPTables(set, rules, spec.KillSwitch)
+	case PlatformDarwin:
+		buildPF(set, spec.Name, rules, spec.KillSwitch)
+	case PlatformWindows:
+		if err := buildNetsh(set, spec.Name, rules, spec.KillSwitch); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// specRules validates spec and returns its rules followed by the kill
+// switch's LAN exceptions
+func specRules(spec Spec) ([]Rule, error) {
+	if !namePattern.MatchString(spec.Name) {
+		return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidRule, spec.Name)
+	}
+
+	rules := append([]Rule(nil), spec.Rules...)
+	if ks := spec.KillSwitch; ks != nil {
+		if !namePattern.MatchString(ks.Interface) {
+			return nil, fmt.Errorf("%w: invalid kill-switch interface %q", ErrInvalidRule, ks.Interface)
+		}
+		if ks.AllowLAN {
+			for _, cidr := range lanRanges {
+				rules = append(rules, Rule{Action: ActionAllow, Destination: cidr})
+			}
+		}
+	}
+
+	for i, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return rules, nil
+}
+
+func validateRule(rule Rule) error {
+	if rule.Action != ActionAllow && rule.Action != ActionDeny {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidRule, rule.Action)
+	}
+	if rule.Protocol != "" && rule.Protocol != "tcp" && rule.Protocol != "udp" {
+		return fmt.Errorf("%w: unknown protocol %q", ErrInvalidRule, rule.Protocol)
+	}
+	if rule.Port < 0 || rule.Port > 65535 || (rule.Port != 0 && rule.Protocol == "") {
+		return fmt.Errorf("%w: port %d needs a protocol and must be at most 65535", ErrInvalidRule, rule.Port)
+	}
+	if rule.Destination != "" {
+		if _, err := parseDestination(rule.Destination); err != nil {
+			return fmt.Errorf("%w: invalid destination %q", ErrInvalidRule, rule.Destination)
+		}
+	}
+	return nil
+}
+
+// parseDestination parses an IP or CIDR destination
+func parseDestination(destination string) (netip.Prefix, error) {
+	if strings.Contains(destination, "/") {
+		return netip.ParsePrefix(destination)
+	}
+	addr, err := netip.ParseAddr(destination)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// families returns the IP families rule applies to, "4" and/or "6"
+func families(rule Rule) []string {
+	if rule.Destination == "" {
+		return []string{"4", "6"}
+	}
+	prefix, _ := parseDestination(rule.Destination)
+	if prefix.Addr().Is4() {
+		return []string{"4"}
+	}
+	return []string{"6"}
+}
+
+// buildIPTables renders rules as iptables and ip6tables commands. Rules are
+// inserted at the top of OUTPUT, so the kill switch goes in first and the
+// rules in reverse to keep the first rule on top.
+func buildIPTables(set *RuleSet, rules []Rule, ks *KillSwitch) {
+	add := func(family, spec string) {
+		set.Apply = append(set.Apply, iptablesCommand(family)+" -I OUTPUT"+spec)
+		set.Remove = append(set.Remove, iptablesCommand(family)+" -D OUTPUT"+spec)
+	}
+
+	if ks != nil {
+		spec := " ! -o " + ks.Interface
+		if ks.FWMark != "" {
+			spec += " -m mark ! --mark " + ks.FWMark
+		}
+		spec += " -m addrtype ! --dst-type LOCAL -j REJECT"
+		add("4", spec)
+		add("6", spec)
+	}
+
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		var spec strings.Builder
+		if rule.Destination != "" {
+			spec.WriteString(" -d " + rule.Destination)
+		}
+		if rule.Protocol != "" {
+			spec.WriteString(" -p " + rule.Protocol)
+		}
+		if rule.Port != 0 {
+			spec.WriteString(fmt.Sprintf(" --dport %d", rule.Port))
+		}
+		if rule.Action == ActionDeny {
+			spec.WriteString(" -j REJECT")
+		} else {
+			spec.WriteString(" -j ACCEPT")
+		}
+		for _, family := range families(rule) {
+			add(family, spec.String())
+		}
+	}
+}
+
+func iptablesCommand(family string) string {
+	if family == "6" {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+// buildPF renders rules into a pf anchor. Anchors under com.apple/ are
+// evaluated by the default macOS ruleset without editing pf.conf. pf is
+// enabled with a reference token, saved so Remove releases only this
+// reference and leaves pf as other users of it expect.
+func buildPF(set *RuleSet, name string, rules []Rule, ks *KillSwitch) {
+	var lines []string
+	if ks != nil {
+		lines = append(lines, "pass out quick on lo0 all")
+	}
+	for _, rule := range rules {
+		action := "pass out quick"
+		if rule.Action == ActionDeny {
+			action = "block drop out quick"
+		}
+		line := action
+		if rule.Protocol != "" {
+			line += " proto " + rule.Protocol
+		}
+		destination := "any"
+		if rule.Destination != "" {
+			destination = rule.Destination
+		}
+		line += " to " + destination
+		if rule.Port != 0 {
+			line += fmt.Sprintf(" port %d", rule.Port)
+		}
+		lines = append(lines, line)
+	}
+	if ks != nil {
+		lines = append(lines,
+			"pass out quick on "+ks.Interface+" all",
+			"block drop out quick all",
+		)
+	}
+
+	anchor := "com.apple/stealthguard." + name
+	tokenFile := "/var/run/stealthguard." + name + ".pftoken"
+	set.Apply = []string{
+		fmt.Sprintf("printf '%%s\\n' '%s' | pfctl -a %s -f -", strings.Join(lines, "' '"), anchor),
+		fmt.Sprintf("pfctl -E 2>&1 | sed -n 's/^Token : //p' > %s", tokenFile),
+	}
+	set.Remove = []string{
+		fmt.Sprintf("pfctl -a %s -F rules", anchor),
+		fmt.Sprintf(`pfctl -X "$(cat %s)" && rm -f %s`, tokenFile, tokenFile),
+	}
+}
+
+// buildNetsh renders rules as Windows Firewall rules named after the spec.
+// The kill switch blocks outbound traffic by default and allows the
+// interface's local addresses. netsh can only set both default actions at
+// once, so the outbound default is saved and restored per profile with the
+// NetSecurity PowerShell module, leaving the inbound default untouched.
+func buildNetsh(set *RuleSet, name string, rules []Rule, ks *KillSwitch) error {
+	for i, rule := range rules {
+		ruleName := fmt.Sprintf("%s-%d", name, i)
+		action := "allow"
+		if rule.Action == ActionDeny {
+			action = "block"
+		}
+		command := fmt.Sprintf(`netsh advfirewall firewall add rule name="%s" dir=out action=%s`, ruleName, action)
+		if rule.Destination != "" {
+			command += " remoteip=" + rule.Destination
+		}
+		if rule.Protocol != "" {
+			command += " protocol=" + strings.ToUpper(rule.Protocol)
+		}
+		if rule.Port != 0 {
+			command += fmt.Sprintf(" remoteport=%d", rule.Port)
+		}
+		set.Apply = append(set.Apply, command)
+		set.Remove = append(set.Remove, fmt.Sprintf(`netsh advfirewall firewall delete rule name="%s"`, ruleName))
+	}
+
+	if ks == nil {
+		return nil
+	}
+	if len(ks.LocalAddresses) == 0 {
+		return fmt.Errorf("%w: a Windows kill switch needs the local addresses of %s", ErrInvalidRule, ks.Interface)
+	}
+	for _, address := range ks.LocalAddresses {
+		if _, err := parseDestination(address); err != nil {
+			return fmt.Errorf("%w: invalid local address %q", ErrInvalidRule, address)
+		}
+	}
+
+	ruleName := name + "-tunnel"
+	policyFile := `$env:ProgramData\stealthguard.` + name + ".policy.xml"
+	set.Apply = append(set.Apply,
+		fmt.Sprintf(`netsh advfirewall firewall add rule name="%s" dir=out action=allow localip=%s`, ruleName, strings.Join(ks.LocalAddresses, ",")),
+		fmt.Sprintf(`powershell -NoProfile -Command "Get-NetFirewallProfile | Select-Object Name,DefaultOutboundAction | Export-Clixml -Path %s; `+
+			`Set-NetFirewallProfile -All -DefaultOutboundAction Block"`, policyFile),
+	)
+	set.Remove = append(set.Remove,
+		fmt.Sprintf(`powershell -NoProfile -Command "Import-Clixml -Path %s | ForEach-Object { `+
+			`Set-NetFirewallProfile -Name $_.Name -DefaultOutboundAction $_.DefaultOutboundAction }; Remove-Item -Path %s"`, policyFile, policyFile),
+		fmt.Sprintf(`netsh advfirewall firewall delete rule name="%s"`, ruleName),
+	)
+	return nil
+}
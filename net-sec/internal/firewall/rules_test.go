@@ -0,0 +1,144 @@
+package firewall
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// killSwitchSpec allows the VPN endpoint outside the tunnel and confines
+// everything else to wg0
+var killSwitchSpec = Spec{
+	Name: "vpn",
+	Rules: []Rule{
+		{Action: ActionAllow, Destination: "203.0.113.7", Protocol: "udp", Port: 51820},
+		{Action: ActionDeny, Destination: "2001:db8::/32"},
+	},
+	KillSwitch: &KillSwitch{
+		Interface:      "wg0",
+		FWMark:         "51820",
+		LocalAddresses: []string{"10.8.0.2", "fd00::2"},
+	},
+}
+
+func build(t *testing.T, platform string, spec Spec) *RuleSet {
+	t.Helper()
+
+	builder, err := NewRuleBuilder(platform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := builder.Build(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func expectCommands(t *testing.T, name string, got, want []string) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s commands:\n got %q\nwant %q", name, got, want)
+	}
+}
+
+func TestBuildIPTablesKillSwitch(t *testing.T) {
+	set := build(t, PlatformLinux, killSwitchSpec)
+
+	// The kill switch is inserted first so the rules end up above it in order
+	expectCommands(t, "apply", set.Apply, []string{
+		"iptables -I OUTPUT ! -o wg0 -m mark ! --mark 51820 -m addrtype ! --dst-type LOCAL -j REJECT",
+		"ip6tables -I OUTPUT ! -o wg0 -m mark ! --mark 51820 -m addrtype ! --dst-type LOCAL -j REJECT",
+		"ip6tables -I OUTPUT -d 2001:db8::/32 -j REJECT",
+		"iptables -I OUTPUT -d 203.0.113.7 -p udp --dport 51820 -j ACCEPT",
+	})
+	expectCommands(t, "remove", set.Remove, []string{
+		"iptables -D OUTPUT ! -o wg0 -m mark ! --mark 51820 -m addrtype ! --dst-type LOCAL -j REJECT",
+		"ip6tables -D OUTPUT ! -o wg0 -m mark ! --mark 51820 -m addrtype ! --dst-type LOCAL -j REJECT",
+		"ip6tables -D OUTPUT -d 2001:db8::/32 -j REJECT",
+		"iptables -D OUTPUT -d 203.0.113.7 -p udp --dport 51820 -j ACCEPT",
+	})
+}
+
+func TestBuildPFKillSwitch(t *testing.T) {
+	set := build(t, PlatformDarwin, killSwitchSpec)
+
+	expectCommands(t, "apply", set.Apply, []string{
+		"printf '%s\\n' 'pass out quick on lo0 all' 'pass out quick proto udp to 203.0.113.7 port 51820' " +
+			"'block drop out quick to 2001:db8::/32' 'pass out quick on wg0 all' 'block drop out quick all' " +
+			"| pfctl -a com.apple/stealthguard.vpn -f -",
+		"pfctl -E 2>&1 | sed -n 's/^Token : //p' > /var/run/stealthguard.vpn.pftoken",
+	})
+	// Only the reference taken by Apply is released, so pf stays enabled
+	// for anyone else who enabled it
+	expectCommands(t, "remove", set.Remove, []string{
+		"pfctl -a com.apple/stealthguard.vpn -F rules",
+		`pfctl -X "$(cat /var/run/stealthguard.vpn.pftoken)" && rm -f /var/run/stealthguard.vpn.pftoken`,
+	})
+}
+
+func TestBuildNetshKillSwitch(t *testing.T) {
+	set := build(t, PlatformWindows, killSwitchSpec)
+
+	expectCommands(t, "apply", set.Apply, []string{
+		`netsh advfirewall firewall add rule name="vpn-0" dir=out action=allow remoteip=203.0.113.7 protocol=UDP remoteport=51820`,
+		`netsh advfirewall firewall add rule name="vpn-1" dir=out action=block remoteip=2001:db8::/32`,
+		`netsh advfirewall firewall add rule name="vpn-tunnel" dir=out action=allow localip=10.8.0.2,fd00::2`,
+		`powershell -NoProfile -Command "Get-NetFirewallProfile | Select-Object Name,DefaultOutboundAction | ` +
+			`Export-Clixml -Path $env:ProgramData\stealthguard.vpn.policy.xml; Set-NetFirewallProfile -All -DefaultOutboundAction Block"`,
+	})
+	// The outbound default each profile had before Apply is restored
+	expectCommands(t, "remove", set.Remove, []string{
+		`netsh advfirewall firewall delete rule name="vpn-0"`,
+		`netsh advfirewall firewall delete rule name="vpn-1"`,
+		`powershell -NoProfile -Command "Import-Clixml -Path $env:ProgramData\stealthguard.vpn.policy.xml | ForEach-Object { ` +
+			`Set-NetFirewallProfile -Name $_.Name -DefaultOutboundAction $_.DefaultOutboundAction }; ` +
+			`Remove-Item -Path $env:ProgramData\stealthguard.vpn.policy.xml"`,
+		`netsh advfirewall firewall delete rule name="vpn-tunnel"`,
+	})
+}
+
+func TestBuildAllowLAN(t *testing.T) {
+	set := build(t, PlatformLinux, Spec{Name: "lan", KillSwitch: &KillSwitch{Interface: "wg0", AllowLAN: true}})
+
+	// Two kill-switch rules plus one per LAN range
+	if len(set.Apply) != 2+len(lanRanges) {
+		t.Fatalf("expected %d rules, got %q", 2+len(lanRanges), set.Apply)
+	}
+	if set.Apply[len(set.Apply)-1] != "iptables -I OUTPUT -d 10.0.0.0/8 -j ACCEPT" {
+		t.Errorf("expected the first LAN range on top, got %q", set.Apply[len(set.Apply)-1])
+	}
+	if set.Apply[0] != "iptables -I OUTPUT ! -o wg0 -m addrtype ! --dst-type LOCAL -j REJECT" {
+		t.Errorf("expected a kill switch without fwmark, got %q", set.Apply[0])
+	}
+}
+
+func TestBuildRejectsInvalidSpecs(t *testing.T) {
+	if _, err := NewRuleBuilder("plan9"); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("expected ErrUnsupportedPlatform, got %v", err)
+	}
+
+	specs := map[string]Spec{
+		"name":        {Name: "bad name"},
+		"interface":   {Name: "vpn", KillSwitch: &KillSwitch{Interface: "wg0; reboot"}},
+		"action":      {Name: "vpn", Rules: []Rule{{Action: "drop"}}},
+		"protocol":    {Name: "vpn", Rules: []Rule{{Action: ActionAllow, Protocol: "icmp"}}},
+		"port":        {Name: "vpn", Rules: []Rule{{Action: ActionAllow, Port: 53}}},
+		"destination": {Name: "vpn", Rules: []Rule{{Action: ActionAllow, Destination: "example.com"}}},
+	}
+	for name, spec := range specs {
+		for _, platform := range []string{PlatformLinux, PlatformDarwin, PlatformWindows} {
+			builder, _ := NewRuleBuilder(platform)
+			if _, err := builder.Build(spec); !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("%s on %s: expected ErrInvalidRule, got %v", name, platform, err)
+			}
+		}
+	}
+
+	// netsh cannot filter by interface name
+	builder, _ := NewRuleBuilder(PlatformWindows)
+	if _, err := builder.Build(Spec{Name: "vpn", KillSwitch: &KillSwitch{Interface: "wg0"}}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected a Windows kill switch without addresses to fail, got %v", err)
+	}
+}
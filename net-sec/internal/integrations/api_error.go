@@ -0,0 +1,70 @@
+package integrations
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxAPIErrorBody is the number of response body bytes an APIError keeps
+const maxAPIErrorBody = 4 << 10
+
+// APIError is returned when an integration's API answers with a non-2xx
+// status
+type APIError struct {
+	Integration string
+	StatusCode  int
+	Body        string        // Start of the response body, at most maxAPIErrorBody bytes
+	RetryAfter  time.Duration // From the Retry-After header; 0 when absent
+}
+
+// newAPIError reads the status, Retry-After header and the start of the body
+// of a failed response from integration
+func newAPIError(integration string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxAPIErrorBody))
+
+	return &APIError{
+		Integration: integration,
+		StatusCode:  resp.StatusCode,
+		Body:        strings.TrimSpace(string(body)),
+		RetryAfter:  parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s API request failed with status %d", e.Integration, e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Transient reports whether the request may succeed if retried: the API
+// was rate limiting or failed on its side
+func (e *APIError) Transient() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// parseRetryAfter parses a Retry-After header given either as seconds or as
+// an HTTP date, returning 0 when it is absent, invalid or in the past
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+	return date.Sub(now)
+}
@@ -0,0 +1,118 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusServer answers every request with status, the Retry-After header
+// retryAfter when set, and body
+func statusServer(t *testing.T, status int, retryAfter, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNotionReturnsAPIErrors(t *testing.T) {
+	for _, tc := range []struct {
+		status     int
+		retryAfter string
+		transient  bool
+		wait       time.Duration
+	}{
+		{status: http.StatusTooManyRequests, retryAfter: "7", transient: true, wait: 7 * time.Second},
+		{status: http.StatusUnauthorized},
+		{status: http.StatusInternalServerError, transient: true},
+	} {
+		server := statusServer(t, tc.status, tc.retryAfter, `{"code":"failed"}`)
+		notion := NewNotionIntegration("token")
+		notion.baseURL = server.URL
+
+		calls := map[string]error{
+			"SendData":           notion.SendData(context.Background(), &IntegrationData{Content: map[string]interface{}{}}),
+			"ValidateConnection": notion.ValidateConnection(),
+		}
+		_, calls["RetrieveData"] = notion.RetrieveData(context.Background(), &DataQuery{
+			Filters: map[string]interface{}{"database_id": "1a2b3c4d-5e6f-4a8b-9c0d-1e2f3a4b5c6d"},
+		})
+
+		for call, err := range calls {
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("%d: %s: expected an APIError, got %v", tc.status, call, err)
+			}
+			if apiErr.StatusCode != tc.status || apiErr.Integration != "notion" || apiErr.Body != `{"code":"failed"}` {
+				t.Errorf("%d: %s: unexpected error %+v", tc.status, call, apiErr)
+			}
+			if apiErr.RetryAfter != tc.wait {
+				t.Errorf("%d: %s: RetryAfter = %v, want %v", tc.status, call, apiErr.RetryAfter, tc.wait)
+			}
+			if isTransient(err) != tc.transient {
+				t.Errorf("%d: %s: transient = %v, want %v", tc.status, call, isTransient(err), tc.transient)
+			}
+		}
+	}
+}
+
+func TestJiraReturnsAPIErrors(t *testing.T) {
+	server := statusServer(t, http.StatusServiceUnavailable, "", strings.Repeat("x", 2*maxAPIErrorBody))
+	jira := NewJiraIntegration("user", "token", server.URL)
+
+	_, err := jira.RetrieveData(context.Background(), &DataQuery{Limit: 10})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Integration != jira.Name() {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if len(apiErr.Body) != maxAPIErrorBody {
+		t.Errorf("expected the body truncated to %d bytes, got %d", maxAPIErrorBody, len(apiErr.Body))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-5":                            0,
+		"soon":                          0,
+		"Fri, 01 Mar 2024 12:00:30 GMT": 30 * time.Second,
+		"Fri, 01 Mar 2024 11:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	crm := &flakyIntegration{
+		mockIntegration: mockIntegration{name: "crm"},
+		failures:        []error{&APIError{Integration: "crm", StatusCode: http.StatusTooManyRequests, RetryAfter: 10 * time.Second}},
+	}
+	manager, delays := newRetryManager(t, crm, 1, nil)
+
+	data := &IntegrationData{Type: "contact", Content: map[string]interface{}{}}
+	if err := manager.SendDataWithCompliance(context.Background(), "crm", data, "user1"); err != nil {
+		t.Fatalf("SendDataWithCompliance: %v", err)
+	}
+	if len(*delays) != 1 || (*delays)[0] != 10*time.Second {
+		t.Errorf("delays = %v, want [10s]", *delays)
+	}
+}
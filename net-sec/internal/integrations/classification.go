@@ -0,0 +1,129 @@
+package integrations
+
+import (
+	"sort"
+	"strings"
+)
+
+// classificationLevels orders the record classifications from least to most
+// restrictive
+var classificationLevels = map[string]int{
+	"public":       0,
+	"internal":     1,
+	"confidential": 2,
+	"restricted":   3,
+}
+
+// personalClassifications are the field classifications treated as personal
+// data, and therefore audited and pseudonymized
+var personalClassifications = map[string]bool{
+	"personal":     true,
+	"sensitive":    true,
+	"special":      true,
+	"confidential": true,
+	"restricted":   true,
+}
+
+// FieldClassificationKey returns the IntegrationConfig.DataClassification key
+// pinning the classification of field in integration. The integration name on
+// its own pins the classification of the whole record.
+func FieldClassificationKey(integration, field string) string {
+	return integration + "." + field
+}
+
+// classifyData sets the classification of a retrieved record and its fields.
+// Each field's classification comes from the DataClassification config if
+// set, then the data minimizer's classifier, then the integration's own
+// default. Fields classified as personal data are listed in PersonalData,
+// and a field pinned to a record classification raises the record's
+// classification to at least that level.
+func (im *IntegrationManager) classifyData(integrationName string, data *IntegrationData) {
+	classifications := make(map[string]string)
+	if im.dataMinimizer != nil && im.config.DataMinimization {
+		for field, classification := range im.dataMinimizer.ClassifyData(data.Content) {
+			classifications[field] = classification
+		}
+	}
+
+	pinned := make(map[string]bool)
+	for field := range data.Content {
+		if classification, ok := im.config.DataClassification[FieldClassificationKey(integrationName, field)]; ok {
+			classifications[field] = classification
+			pinned[field] = true
+		}
+	}
+
+	// Reclassify the fields the integration flagged itself
+	personalData := make([]PersonalDataField, 0, len(data.PersonalData))
+	listed := make(map[string]bool)
+	for _, field := range data.PersonalData {
+		if classification, ok := classifications[field.Field]; ok {
+			if !personalClassifications[classification] {
+				continue
+			}
+			field.DataCategory = classification
+		}
+		personalData = append(personalData, field)
+		listed[field.Field] = true
+	}
+
+	// Add the remaining personal fields in a stable order
+	for _, field := range sortedFields(classifications) {
+		if listed[field] || !personalClassifications[classifications[field]] {
+			continue
+		}
+		personalData = append(personalData, PersonalDataField{
+			Field:        field,
+			DataCategory: classifications[field],
+		})
+	}
+	data.PersonalData = personalData
+
+	if classification, ok := im.config.DataClassification[integrationName]; ok {
+		data.Classification = classification
+		return
+	}
+	for field := range pinned {
+		level, ok := classificationLevels[classifications[field]]
+		if ok && level > classificationLevels[data.Classification] {
+			data.Classification = classifications[field]
+		}
+	}
+}
+
+// pinnedClassification returns the most restrictive classification pinned in
+// config for records of integration, considering the pins of fields or, when
+// fields is empty, of every field. It returns "" when nothing is pinned.
+func (im *IntegrationManager) pinnedClassification(integration string, fields []string) string {
+	classification, pinned := im.config.DataClassification[integration]
+	if pinned {
+		return classification
+	}
+
+	selected := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		selected[field] = true
+	}
+
+	prefix := FieldClassificationKey(integration, "")
+	for key, fieldClassification := range im.config.DataClassification {
+		field := strings.TrimPrefix(key, prefix)
+		if field == key || (len(fields) > 0 && !selected[field]) {
+			continue
+		}
+		if classification == "" || classificationLevels[fieldClassification] > classificationLevels[classification] {
+			classification = fieldClassification
+		}
+	}
+	return classification
+}
+
+// sortedFields returns the fields of classifications in sorted order
+func sortedFields(classifications map[string]string) []string {
+	fields := make([]string, 0, len(classifications))
+	for field := range classifications {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
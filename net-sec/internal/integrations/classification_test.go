@@ -0,0 +1,130 @@
+package integrations
+
+import (
+	"context"
+	"testing"
+)
+
+// stubMinimizer classifies fields from a fixed table and pseudonymizes by
+// replacing values
+type stubMinimizer struct {
+	classifications map[string]string
+}
+
+func (s stubMinimizer) MinimizeData(data map[string]interface{}, purpose string) map[string]interface{} {
+	return data
+}
+
+func (s stubMinimizer) PseudonymizeFields(data map[string]interface{}, fields []string) error {
+	for _, field := range fields {
+		data[field] = "pseudo:" + field
+	}
+	return nil
+}
+
+func (s stubMinimizer) ClassifyData(data map[string]interface{}) map[string]string {
+	classifications := make(map[string]string)
+	for field := range data {
+		if classification, ok := s.classifications[field]; ok {
+			classifications[field] = classification
+		}
+	}
+	return classifications
+}
+
+func retrieveClassified(t *testing.T, overrides map[string]string, auditLog AuditLogger) *IntegrationData {
+	t.Helper()
+
+	crm := &mockIntegration{name: "crm", retrieved: &IntegrationData{
+		ID:             "contact-1",
+		Classification: "internal",
+		Content:        map[string]interface{}{"email": "alice@example.com", "notes": "VIP", "plan": "pro"},
+		PersonalData:   []PersonalDataField{{Field: "email", DataCategory: "personal"}},
+	}}
+
+	config := &IntegrationConfig{DataMinimization: true, PseudonymizeData: true, DataClassification: overrides}
+	minimizer := stubMinimizer{classifications: map[string]string{"email": "personal", "notes": "internal", "plan": "internal"}}
+	manager := NewIntegrationManager(config, auditLog, minimizer)
+	if err := manager.RegisterIntegration(crm); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", &DataQuery{
+		Type:          "contact",
+		Fields:        []string{"email", "notes", "plan"},
+		LegalBasis:    "contract",
+		Justification: "support",
+	}, "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	return records[0]
+}
+
+// personalCategories maps each personal data field to its category
+func personalCategories(data *IntegrationData) map[string]string {
+	categories := make(map[string]string)
+	for _, field := range data.PersonalData {
+		categories[field.Field] = field.DataCategory
+	}
+	return categories
+}
+
+func TestConfiguredFieldClassificationOverridesClassifier(t *testing.T) {
+	auditLog := &recordingAuditLog{}
+	data := retrieveClassified(t, map[string]string{FieldClassificationKey("crm", "notes"): "restricted"}, auditLog)
+
+	categories := personalCategories(data)
+	if len(categories) != 2 || categories["email"] != "personal" || categories["notes"] != "restricted" {
+		t.Errorf("expected email personal and notes pinned restricted, got %v", categories)
+	}
+
+	// The pinned field is protected and raises the record's classification
+	if data.Content["notes"] != "pseudo:notes" || data.Content["plan"] != "pro" {
+		t.Errorf("expected only personal fields pseudonymized, got %v", data.Content)
+	}
+	if data.Classification != "restricted" {
+		t.Errorf("expected record classification restricted, got %s", data.Classification)
+	}
+
+	if len(auditLog.accesses) != 1 || auditLog.accesses[0].DataCategory != "restricted" || len(auditLog.accesses[0].FieldsAccessed) != 2 {
+		t.Errorf("expected a restricted personal data access event, got %+v", auditLog.accesses)
+	}
+}
+
+func TestConfiguredClassificationPrecedence(t *testing.T) {
+	data := retrieveClassified(t, map[string]string{
+		"crm":                                  "confidential",
+		FieldClassificationKey("crm", "email"): "internal",
+		FieldClassificationKey("crm", "notes"): "restricted",
+	}, nil)
+
+	// An explicit record classification wins over pinned fields
+	if data.Classification != "confidential" {
+		t.Errorf("expected record classification confidential, got %s", data.Classification)
+	}
+
+	// Config can also declassify a field the integration flagged itself
+	categories := personalCategories(data)
+	if len(categories) != 1 || categories["notes"] != "restricted" {
+		t.Errorf("expected only notes to be personal data, got %v", categories)
+	}
+	if data.Content["email"] != "alice@example.com" {
+		t.Errorf("declassified email should not be pseudonymized, got %v", data.Content["email"])
+	}
+}
+
+func TestClassifierOutputWithoutOverrides(t *testing.T) {
+	data := retrieveClassified(t, nil, nil)
+
+	categories := personalCategories(data)
+	if len(categories) != 1 || categories["email"] != "personal" {
+		t.Errorf("expected classifier output to apply, got %v", categories)
+	}
+	if data.Classification != "internal" {
+		t.Errorf("expected default classification internal, got %s", data.Classification)
+	}
+}
@@ -0,0 +1,81 @@
+package integrations
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegisteredIntegrationsUseManagerClient(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tlsConfig := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+	manager := NewIntegrationManager(&IntegrationConfig{
+		TLSConfig:           tlsConfig,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     time.Minute,
+	}, nil, nil)
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+
+	// The test server's certificate is only trusted through the manager's
+	// TLS config
+	if err := jira.UpdateData(context.Background(), "jira_1", map[string]interface{}{}); err == nil {
+		t.Fatal("expected the default client to reject the test certificate")
+	}
+
+	for _, integration := range []Integration{jira, notion} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if jira.httpClient != manager.HTTPClient() || notion.httpClient != manager.HTTPClient() {
+		t.Fatal("expected registered integrations to use the manager's client")
+	}
+
+	if err := jira.UpdateData(context.Background(), "jira_1", map[string]interface{}{}); err != nil {
+		t.Errorf("jira: %v", err)
+	}
+	if err := notion.UpdateData(context.Background(), "notion_1", map[string]interface{}{}); err != nil {
+		t.Errorf("notion: %v", err)
+	}
+
+	transport := manager.HTTPClient().Transport.(*http.Transport)
+	if transport.TLSClientConfig != tlsConfig {
+		t.Error("expected the transport to use the configured TLS config")
+	}
+	if transport.MaxIdleConnsPerHost != 4 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("unexpected pooling settings: %d idle per host, %s idle timeout",
+			transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+}
+
+func TestManagerClientDefaults(t *testing.T) {
+	client := NewIntegrationManager(&IntegrationConfig{}, nil, nil).HTTPClient()
+	transport := client.Transport.(*http.Transport)
+
+	if client.Timeout != DefaultRequestTimeout {
+		t.Errorf("expected timeout %s, got %s", DefaultRequestTimeout, client.Timeout)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("unexpected default pooling settings: %d idle per host, %s idle timeout",
+			transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if transport.DisableKeepAlives {
+		t.Error("expected keep-alives to be enabled")
+	}
+}
@@ -0,0 +1,120 @@
+package integrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// consentLegalBasis is the prefix of the legal basis for processing on the
+// data subject's consent
+const consentLegalBasis = "Article 6(1)(a)"
+
+// ErrConsentRequired is returned when data retrieved on the legal basis of
+// consent holds personal data of a subject without active consent for the
+// purpose
+var ErrConsentRequired = errors.New("active consent required for processing")
+
+// ConsentChecker reports whether a data subject has active, non-withdrawn
+// consent to processing for a purpose
+type ConsentChecker interface {
+	HasActiveConsent(subjectID, purpose string) bool
+}
+
+// SubjectAttributor is implemented by integrations that attribute the
+// personal data they retrieve to data subjects through
+// PersonalDataField.DataSubjectID. Consent is checked per subject, so only
+// these can serve retrievals on the legal basis of consent.
+type SubjectAttributor interface {
+	AttributesDataSubjects() bool
+}
+
+// SetConsentChecker makes retrievals on the legal basis of consent verify
+// the consent of each data subject in the retrieved data
+func (im *IntegrationManager) SetConsentChecker(checker ConsentChecker) {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.consent = checker
+}
+
+// requiresConsent reports whether processing on legalBasis relies on the
+// data subject's consent
+func requiresConsent(legalBasis string) bool {
+	return strings.HasPrefix(strings.TrimSpace(legalBasis), consentLegalBasis)
+}
+
+// checkConsentQuery rejects a query relying on consent up front when it names
+// no purpose, or when consent is checked and integration cannot attribute
+// personal data to data subjects, as every such retrieval would be refused
+func (im *IntegrationManager) checkConsentQuery(integration Integration, query *DataQuery) error {
+	if !requiresConsent(query.LegalBasis) {
+		return nil
+	}
+	if query.Purpose == "" {
+		return fmt.Errorf("processing purpose required for data retrieval on consent")
+	}
+
+	im.mutex.RLock()
+	checker := im.consent
+	im.mutex.RUnlock()
+
+	if attributor, ok := integration.(SubjectAttributor); checker != nil && (!ok || !attributor.AttributesDataSubjects()) {
+		return fmt.Errorf("%s does not attribute personal data to data subjects, so their consent cannot be verified: %w",
+			integration.Name(), ErrConsentRequired)
+	}
+	return nil
+}
+
+// checkConsent returns ErrConsentRequired when query relies on consent and a
+// subject of the personal data in data has no active consent for the
+// query.Purpose. Personal data of an unknown subject cannot be checked and
+// is refused too. Each refusal is logged as a failed personal data access.
+func (im *IntegrationManager) checkConsent(integrationName string, query *DataQuery, data *IntegrationData, userID string) error {
+	im.mutex.RLock()
+	checker := im.consent
+	im.mutex.RUnlock()
+
+	if checker == nil || !requiresConsent(query.LegalBasis) || len(data.PersonalData) == 0 {
+		return nil
+	}
+
+	fields := make(map[string][]string)
+	subjects := make([]string, 0)
+	for _, field := range data.PersonalData {
+		if _, seen := fields[field.DataSubjectID]; !seen {
+			subjects = append(subjects, field.DataSubjectID)
+		}
+		fields[field.DataSubjectID] = append(fields[field.DataSubjectID], field.Field)
+	}
+
+	denied := make([]string, 0)
+	for _, subjectID := range subjects {
+		if subjectID != "" && checker.HasActiveConsent(subjectID, query.Purpose) {
+			continue
+		}
+		denied = append(denied, subjectID)
+
+		if im.auditLog != nil {
+			im.auditLog.LogPersonalDataAccess(PersonalDataAccessEvent{
+				ID:             generateEventID(),
+				Timestamp:      time.Now(),
+				Integration:    integrationName,
+				UserID:         userID,
+				DataSubjectID:  subjectID,
+				DataCategory:   data.Classification,
+				AccessType:     "read",
+				LegalBasis:     query.LegalBasis,
+				Justification:  query.Justification,
+				FieldsAccessed: fields[subjectID],
+				Success:        false,
+				Metadata:       map[string]interface{}{"reason": "consent_missing", "purpose": query.Purpose},
+			})
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("purpose %q, %d data subjects: %w", query.Purpose, len(denied), ErrConsentRequired)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// consentTable grants consent to the purposes listed per subject
+type consentTable map[string][]string
+
+func (c consentTable) HasActiveConsent(subjectID, purpose string) bool {
+	for _, granted := range c[subjectID] {
+		if granted == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+func newConsentManager(t *testing.T, checker ConsentChecker) (*IntegrationManager, *recordingAuditLog) {
+	t.Helper()
+
+	crm := &mockIntegration{name: "crm", retrieved: &IntegrationData{
+		ID:      "contact-1",
+		Content: map[string]interface{}{"email": "alice@example.com", "plan": "pro"},
+		PersonalData: []PersonalDataField{
+			{Field: "email", DataCategory: "personal", DataSubjectID: "subject-1"},
+		},
+	}}
+
+	auditLog := &recordingAuditLog{}
+	manager := NewIntegrationManager(&IntegrationConfig{}, auditLog, nil)
+	if err := manager.RegisterIntegration(crm); err != nil {
+		t.Fatal(err)
+	}
+	if checker != nil {
+		manager.SetConsentChecker(checker)
+	}
+	return manager, auditLog
+}
+
+func TestRetrieveDataRequiresConsent(t *testing.T) {
+	manager, auditLog := newConsentManager(t, consentTable{"subject-1": {"analytics"}})
+
+	query := &DataQuery{LegalBasis: "Article 6(1)(a) - Consent", Justification: "spring campaign", Purpose: "marketing"}
+	records, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1")
+	if !errors.Is(err, ErrConsentRequired) {
+		t.Fatalf("expected ErrConsentRequired, got %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("expected no data, got %d records", len(records))
+	}
+
+	if len(auditLog.accesses) != 1 {
+		t.Fatalf("expected 1 personal data access event, got %d", len(auditLog.accesses))
+	}
+	access := auditLog.accesses[0]
+	if access.Success || access.DataSubjectID != "subject-1" || len(access.FieldsAccessed) != 1 || access.FieldsAccessed[0] != "email" {
+		t.Errorf("unexpected access event %+v", access)
+	}
+	if event := lastEvent(t, auditLog, "retrieve"); event.Success {
+		t.Errorf("expected the retrieval to be logged as failed")
+	}
+}
+
+func TestRetrieveDataWithConsent(t *testing.T) {
+	manager, auditLog := newConsentManager(t, consentTable{"subject-1": {"marketing"}})
+
+	query := &DataQuery{LegalBasis: "Article 6(1)(a) - Consent", Justification: "spring campaign", Purpose: "marketing"}
+	records, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1")
+	if err != nil {
+		t.Fatalf("RetrieveDataWithCompliance: %v", err)
+	}
+	if len(records) != 1 || records[0].Content["email"] != "alice@example.com" {
+		t.Errorf("unexpected records %v", records)
+	}
+	if len(auditLog.accesses) != 1 || !auditLog.accesses[0].Success {
+		t.Errorf("expected a successful access event, got %+v", auditLog.accesses)
+	}
+}
+
+func TestRetrieveDataRefusesUnknownSubjectsOnConsent(t *testing.T) {
+	manager, _ := newConsentManager(t, consentTable{})
+	crm := manager.integrations["crm"].(*mockIntegration)
+	crm.retrieved.PersonalData[0].DataSubjectID = ""
+
+	query := &DataQuery{LegalBasis: "Article 6(1)(a) - Consent", Justification: "spring campaign", Purpose: "marketing"}
+	if _, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1"); !errors.Is(err, ErrConsentRequired) {
+		t.Fatalf("expected ErrConsentRequired, got %v", err)
+	}
+}
+
+func TestRetrieveDataSkipsConsentForOtherLegalBases(t *testing.T) {
+	manager, _ := newConsentManager(t, consentTable{})
+
+	query := &DataQuery{LegalBasis: "Article 6(1)(b) - Contract", Justification: "support"}
+	if _, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1"); err != nil {
+		t.Fatalf("expected no consent check, got %v", err)
+	}
+}
+
+func TestRetrieveDataMatchesConsentOnPurpose(t *testing.T) {
+	manager, _ := newConsentManager(t, consentTable{"subject-1": {"marketing"}})
+
+	// The justification alone does not name the consented purpose
+	query := &DataQuery{LegalBasis: "Article 6(1)(a) - Consent", Justification: "marketing"}
+	if _, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1"); err == nil || err.Error() != "processing purpose required for data retrieval on consent" {
+		t.Fatalf("expected a missing purpose error, got %v", err)
+	}
+
+	query.Purpose = "analytics"
+	if _, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1"); !errors.Is(err, ErrConsentRequired) {
+		t.Fatalf("expected ErrConsentRequired for another purpose, got %v", err)
+	}
+}
+
+func TestRetrieveDataRejectsConsentFromUnattributedIntegrations(t *testing.T) {
+	manager, auditLog := newConsentManager(t, consentTable{"subject-1": {"marketing"}})
+	crm := manager.integrations["crm"].(*mockIntegration)
+	crm.unattributed = true
+
+	query := &DataQuery{LegalBasis: "Article 6(1)(a) - Consent", Justification: "spring campaign", Purpose: "marketing"}
+	_, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1")
+	if !errors.Is(err, ErrConsentRequired) || !strings.Contains(err.Error(), "crm does not attribute personal data to data subjects") {
+		t.Fatalf("expected the query to be rejected up front, got %v", err)
+	}
+	if len(auditLog.accesses) != 0 {
+		t.Errorf("expected no personal data to be accessed, got %+v", auditLog.accesses)
+	}
+
+	// Without consent checking the integration can still be used
+	unchecked, _ := newConsentManager(t, nil)
+	unchecked.integrations["crm"].(*mockIntegration).unattributed = true
+	if _, err := unchecked.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1"); err != nil {
+		t.Fatalf("expected no consent check, got %v", err)
+	}
+}
@@ -0,0 +1,731 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ===== GOOGLE DRIVE INTEGRATION =====
+
+const (
+	driveScope           = "https://www.googleapis.com/auth/drive"
+	driveDefaultTokenURI = "https://oauth2.googleapis.com/token"
+	driveJWTGrantType    = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// driveTokenLifetime is the lifetime requested for access tokens; Google
+	// caps it at an hour
+	driveTokenLifetime = time.Hour
+	// driveTokenLeeway renews access tokens this long before they expire
+	driveTokenLeeway = time.Minute
+	// driveDefaultFields is the field mask used when a query names no
+	// fields: file metadata without owners or sharing
+	driveDefaultFields = "id,name,mimeType,createdTime,modifiedTime"
+	// driveFileMimeType is the type IntegrationData is uploaded as
+	driveFileMimeType = "application/json"
+)
+
+// driveFieldPattern matches a field of a Drive files field mask, optionally
+// with a sub-selection such as owners(emailAddress)
+var driveFieldPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(\([A-Za-z0-9,/()]*\))?$`)
+
+var (
+	// ErrInvalidServiceAccountKey is returned when the Drive service account
+	// key is missing or cannot be parsed
+	ErrInvalidServiceAccountKey = errors.New("invalid Google service account key")
+	// ErrInvalidDriveField is returned when a Drive query names a field that
+	// is not a valid field mask entry
+	ErrInvalidDriveField = errors.New("invalid Google Drive field")
+)
+
+// driveServiceAccount is the part of a Google service account key the JWT
+// bearer flow needs
+type driveServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewDriveIntegration creates a Google Drive integration that authenticates
+// as the service account in serviceAccountKey, a JSON key file
+func NewDriveIntegration(serviceAccountKey []byte) *DriveIntegration {
+	return &DriveIntegration{
+		serviceAccountKey: serviceAccountKey,
+		baseURL:           "https://www.googleapis.com/drive/v3",
+		uploadURL:         "https://www.googleapis.com/upload/drive/v3",
+		httpClient:        &http.Client{Timeout: DefaultRequestTimeout},
+		metrics:           &IntegrationMetrics{},
+		rateLimiter:       NewRateLimiter(100, 10), // 10 requests per second, burst of 100
+	}
+}
+
+func (d *DriveIntegration) Name() string {
+	return "google_drive"
+}
+
+// AttributesDataSubjects reports that the email addresses of a file's users
+// are attributed to them as data subjects
+func (d *DriveIntegration) AttributesDataSubjects() bool {
+	return true
+}
+
+// SetHTTPClient replaces the integration's default HTTP client
+func (d *DriveIntegration) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.httpClient = client
+}
+
+// SetFieldMapping renames the file fields Drive returns, keyed by field
+// name, to canonical field names
+func (d *DriveIntegration) SetFieldMapping(mapping map[string]string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.fieldMapping = copyFieldMapping(mapping)
+}
+
+// SetRateLimit replaces the integration's default rate limit
+func (d *DriveIntegration) SetRateLimit(limit RateLimit) error {
+	return d.rateLimiter.SetLimit(limit)
+}
+
+func (d *DriveIntegration) Authenticate(credentials map[string]string) error {
+	key, ok := credentials["service_account_key"]
+	if !ok {
+		return fmt.Errorf("service_account_key required for Google Drive authentication")
+	}
+
+	d.mutex.Lock()
+	d.serviceAccountKey = []byte(key)
+	d.accessToken = ""
+	d.tokenExpiry = time.Time{}
+	d.mutex.Unlock()
+
+	return d.ValidateConnection()
+}
+
+// SendData uploads data.Content as a JSON file named after
+// data.Content["title"], in the folder data.Metadata["folder_id"] when set.
+// The data's type, classification, legal basis and purpose are recorded as
+// app properties of the file.
+func (d *DriveIntegration) SendData(ctx context.Context, data *IntegrationData) error {
+	if !d.rateLimiter.Allow() {
+		return ErrRateLimited
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	reqBody, contentType, err := d.convertToDriveUpload(data)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/files?uploadType=multipart&fields=id", d.uploadURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if err := d.setDriveHeaders(ctx, req); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		d.updateDriveMetrics(false, time.Since(start), len(reqBody), 0)
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	d.updateDriveMetrics(success, time.Since(start), len(reqBody), resp.ContentLength)
+
+	if !success {
+		return newAPIError(d.Name(), resp)
+	}
+
+	return nil
+}
+
+// RetrieveData returns the first file matching query. Only the fields in
+// query.Fields are requested from Drive, as a field mask; owners and
+// sharing permissions in the result are reported as personal data.
+func (d *DriveIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
+	if !d.rateLimiter.Allow() {
+		return nil, ErrRateLimited
+	}
+
+	page, _, err := d.retrieveFilePage(ctx, query, "", pageSize(query, 0))
+	if err != nil {
+		return nil, err
+	}
+	if len(page) == 0 {
+		return d.convertFromDriveFormat(map[string]interface{}{}, query), nil
+	}
+	return page[0], nil
+}
+
+// RetrieveDataPaged returns the files matching query, following Drive's
+// page tokens until query.Limit files have been retrieved, or every file when
+// Limit is 0. Each request waits for the rate limiter; when the context is
+// cancelled no records are returned and the error is ctx.Err().
+func (d *DriveIntegration) RetrieveDataPaged(ctx context.Context, query *DataQuery) ([]*IntegrationData, error) {
+	records := make([]*IntegrationData, 0)
+
+	for pageToken := ""; ; {
+		if err := d.rateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		page, next, err := d.retrieveFilePage(ctx, query, pageToken, pageSize(query, len(records)))
+		if err != nil {
+			return nil, err
+		}
+
+		var done bool
+		records, done = limitRecords(query, append(records, page...))
+		if done || next == "" {
+			return records, nil
+		}
+		pageToken = next
+	}
+}
+
+// retrieveFilePage fetches a page of up to size files starting at
+// pageToken, returning them with the token of the next page, or "" when
+// there are no more files
+func (d *DriveIntegration) retrieveFilePage(ctx context.Context, query *DataQuery, pageToken string, size int) ([]*IntegrationData, string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	start := time.Now()
+
+	endpoint, err := d.filesEndpoint(query, pageToken, size)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if err := d.setDriveHeaders(ctx, req); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		d.updateDriveMetrics(false, time.Since(start), 0, 0)
+		return nil, "", contextError(ctx, fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		d.updateDriveMetrics(false, time.Since(start), 0, resp.ContentLength)
+		return nil, "", newAPIError(d.Name(), resp)
+	}
+
+	var listResp struct {
+		Files         []map[string]interface{} `json:"files"`
+		NextPageToken string                   `json:"nextPageToken"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		d.updateDriveMetrics(false, time.Since(start), 0, resp.ContentLength)
+		return nil, "", contextError(ctx, fmt.Errorf("failed to decode response: %w", err))
+	}
+
+	d.updateDriveMetrics(true, time.Since(start), 0, resp.ContentLength)
+
+	page := make([]*IntegrationData, 0, len(listResp.Files))
+	for _, file := range listResp.Files {
+		page = append(page, d.convertFromDriveFormat(file, query))
+	}
+
+	return page, listResp.NextPageToken, nil
+}
+
+// UpdateData patches the metadata of file id, such as its name or
+// description
+func (d *DriveIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	if !d.rateLimiter.Allow() {
+		return ErrRateLimited
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	start := time.Now()
+
+	reqBody, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/files/%s?fields=id", d.baseURL, url.PathEscape(strings.TrimPrefix(id, "drive_")))
+	req, err := http.NewRequestWithContext(ctx, "PATCH", endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if err := d.setDriveHeaders(ctx, req); err != nil {
+		return err
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		d.updateDriveMetrics(false, time.Since(start), len(reqBody), 0)
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	d.updateDriveMetrics(success, time.Since(start), len(reqBody), resp.ContentLength)
+
+	if !success {
+		return newAPIError(d.Name(), resp)
+	}
+
+	return nil
+}
+
+// FindSubjectData is not supported: files are not linked to a data
+// subject ID, so their records must be located manually
+func (d *DriveIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error) {
+	return nil, fmt.Errorf("drive: finding data subject records: %w", ErrUnsupportedOperation)
+}
+
+// EraseSubjectData is not supported: files are not linked to a data
+// subject ID, so their records must be located manually
+func (d *DriveIntegration) EraseSubjectData(ctx context.Context, subjectID string) (int, error) {
+	return 0, fmt.Errorf("drive: erasing data subject records: %w", ErrUnsupportedOperation)
+}
+
+func (d *DriveIntegration) ValidateConnection() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	endpoint := fmt.Sprintf("%s/about?fields=user(emailAddress)", d.baseURL)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := d.setDriveHeaders(context.Background(), req); err != nil {
+		return err
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("authentication failed: %w", newAPIError(d.Name(), resp))
+	}
+
+	return nil
+}
+
+func (d *DriveIntegration) GetMetrics() *IntegrationMetrics {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	metricsCopy := *d.metrics
+	return &metricsCopy
+}
+
+// setDriveHeaders authorizes req with an access token for the service
+// account; the caller holds mutex
+func (d *DriveIntegration) setDriveHeaders(ctx context.Context, req *http.Request) error {
+	token, err := d.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return nil
+}
+
+// token returns a cached access token, exchanging a signed JWT for a new one
+// when it is missing or about to expire; the caller holds mutex
+func (d *DriveIntegration) token(ctx context.Context) (string, error) {
+	if d.accessToken != "" && time.Now().Add(driveTokenLeeway).Before(d.tokenExpiry) {
+		return d.accessToken, nil
+	}
+
+	account, key, err := parseServiceAccountKey(d.serviceAccountKey)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	assertion, err := signDriveJWT(account, key, now)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{"grant_type": {driveJWTGrantType}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, "POST", account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", contextError(ctx, fmt.Errorf("token request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("token request failed: %w", newAPIError(d.Name(), resp))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access token")
+	}
+
+	d.accessToken = tokenResp.AccessToken
+	d.tokenExpiry = now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	return d.accessToken, nil
+}
+
+// parseServiceAccountKey returns the account and RSA private key in a
+// service account JSON key
+func parseServiceAccountKey(keyJSON []byte) (*driveServiceAccount, *rsa.PrivateKey, error) {
+	if len(keyJSON) == 0 {
+		return nil, nil, fmt.Errorf("%w: no key configured", ErrInvalidServiceAccountKey)
+	}
+
+	var account driveServiceAccount
+	if err := json.Unmarshal(keyJSON, &account); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidServiceAccountKey, err)
+	}
+	if account.ClientEmail == "" {
+		return nil, nil, fmt.Errorf("%w: client_email missing", ErrInvalidServiceAccountKey)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = driveDefaultTokenURI
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, nil, fmt.Errorf("%w: private_key is not PEM", ErrInvalidServiceAccountKey)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidServiceAccountKey, err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: private_key is not an RSA key", ErrInvalidServiceAccountKey)
+	}
+
+	return &account, key, nil
+}
+
+// signDriveJWT returns the RS256-signed JWT bearer assertion for account
+func signDriveJWT(account *driveServiceAccount, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": account.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": driveScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(driveTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token assertion: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// filesEndpoint returns the URL listing a page of size files for query. The
+// Drive query is
+// built from quoted filter values, and only the queried fields are requested
+// for data minimization; canonical field names are sent as Drive fields.
+func (d *DriveIntegration) filesEndpoint(query *DataQuery, pageToken string, size int) (string, error) {
+	fields := driveDefaultFields
+	if len(query.Fields) > 0 {
+		names := sourceFields(query.Fields, d.fieldMapping)
+		for _, name := range names {
+			if !driveFieldPattern.MatchString(name) {
+				return "", fmt.Errorf("%w: %q", ErrInvalidDriveField, name)
+			}
+		}
+		fields = "id," + strings.Join(names, ",")
+	}
+
+	q, err := buildDriveQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"q":        {q},
+		"fields":   {"nextPageToken,files(" + fields + ")"},
+		"pageSize": {fmt.Sprint(size)},
+	}
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
+
+	return fmt.Sprintf("%s/files?%s", d.baseURL, params.Encode()), nil
+}
+
+// buildDriveQuery builds the Drive search query for query's folder_id,
+// name and mime_type filters and date range
+func buildDriveQuery(query *DataQuery) (string, error) {
+	clauses := []string{"trashed = false"}
+
+	for filter, clause := range map[string]string{
+		"folder_id": "%s in parents",
+		"name":      "name contains %s",
+		"mime_type": "mimeType = %s",
+	} {
+		value, ok := query.Filters[filter]
+		if !ok {
+			continue
+		}
+		text, isString := value.(string)
+		if !isString || text == "" {
+			return "", fmt.Errorf("invalid %s filter %q", filter, fmt.Sprint(value))
+		}
+		clauses = append(clauses, fmt.Sprintf(clause, quoteDriveQuery(text)))
+	}
+
+	if query.DateRange != nil {
+		if query.DateRange.End.Before(query.DateRange.Start) {
+			return "", fmt.Errorf("invalid date range: end %s is before start %s",
+				query.DateRange.End.Format(time.RFC3339), query.DateRange.Start.Format(time.RFC3339))
+		}
+		clauses = append(clauses,
+			"createdTime >= "+quoteDriveQuery(query.DateRange.Start.UTC().Format(time.RFC3339)),
+			"createdTime <= "+quoteDriveQuery(query.DateRange.End.UTC().Format(time.RFC3339)))
+	}
+
+	// Map iteration order varies; keep the query stable
+	sort.Strings(clauses[1:])
+	return strings.Join(clauses, " and "), nil
+}
+
+// quoteDriveQuery returns value as a single-quoted Drive query string
+func quoteDriveQuery(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return `'` + value + `'`
+}
+
+// convertToDriveUpload returns the multipart/related body uploading data and
+// its content type
+func (d *DriveIntegration) convertToDriveUpload(data *IntegrationData) ([]byte, string, error) {
+	name := fmt.Sprint(data.Content["title"])
+	if data.Content["title"] == nil {
+		name = data.ID
+	}
+
+	appProperties := map[string]string{}
+	for key, value := range map[string]string{
+		"integration_id":     data.ID,
+		"type":               data.Type,
+		"classification":     data.Classification,
+		"legal_basis":        data.LegalBasis,
+		"processing_purpose": data.ProcessingPurpose,
+	} {
+		if value != "" {
+			appProperties[key] = value
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"name":          name,
+		"mimeType":      driveFileMimeType,
+		"appProperties": appProperties,
+	}
+	if description, ok := data.Content["description"]; ok {
+		metadata["description"] = fmt.Sprint(description)
+	}
+	if folder, ok := data.Metadata["folder_id"].(string); ok && folder != "" {
+		metadata["parents"] = []string{folder}
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	contentJSON, err := json.Marshal(data.Content)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"application/json; charset=UTF-8", metadataJSON},
+		{driveFileMimeType, contentJSON},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to build upload: %w", err)
+		}
+		w.Write(part.data)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	return body.Bytes(), "multipart/related; boundary=" + writer.Boundary(), nil
+}
+
+// convertFromDriveFormat converts a Drive file resource to IntegrationData.
+// Besides personal data fields found by name, the email addresses of the
+// file's owners, sharing user and the users and groups it is shared with are
+// reported, with the address as the data subject.
+func (d *DriveIntegration) convertFromDriveFormat(file map[string]interface{}, query *DataQuery) *IntegrationData {
+	data := &IntegrationData{
+		ID:                fmt.Sprintf("drive_%s", file["id"]),
+		Type:              query.Type,
+		Classification:    "internal",
+		Content:           make(map[string]interface{}),
+		Metadata:          make(map[string]interface{}),
+		PersonalData:      make([]PersonalDataField, 0),
+		LegalBasis:        query.LegalBasis,
+		ProcessingPurpose: query.Justification,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+
+	// Sub-selections such as owners(emailAddress) return the whole field
+	topLevel := *query
+	topLevel.Fields = make([]string, 0, len(query.Fields))
+	for _, field := range query.Fields {
+		if i := strings.Index(field, "("); i >= 0 {
+			field = field[:i]
+		}
+		topLevel.Fields = append(topLevel.Fields, field)
+	}
+	addQueriedFields(data, mapFields(file, d.fieldMapping), &topLevel)
+
+	minimized := len(query.Fields) > 0
+	for _, owner := range driveUsers(file["owners"]) {
+		data.PersonalData = append(data.PersonalData, drivePersonalData("owners.emailAddress", owner, minimized))
+	}
+	for _, sharer := range driveUsers(file["sharingUser"]) {
+		data.PersonalData = append(data.PersonalData, drivePersonalData("sharingUser.emailAddress", sharer, minimized))
+	}
+	for _, permission := range driveUsers(file["permissions"]) {
+		if permission["type"] != "user" && permission["type"] != "group" {
+			continue
+		}
+		data.PersonalData = append(data.PersonalData, drivePersonalData("permissions.emailAddress", permission, minimized))
+	}
+
+	return data
+}
+
+// driveUsers returns the user objects in value, a single Drive user or a
+// list of users or permissions, that have an email address
+func driveUsers(value interface{}) []map[string]interface{} {
+	var candidates []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		candidates = v
+	case map[string]interface{}:
+		candidates = []interface{}{v}
+	}
+
+	users := make([]map[string]interface{}, 0, len(candidates))
+	for _, candidate := range candidates {
+		if user, ok := candidate.(map[string]interface{}); ok {
+			if email, _ := user["emailAddress"].(string); email != "" {
+				users = append(users, user)
+			}
+		}
+	}
+	return users
+}
+
+// drivePersonalData describes the email address of a Drive user
+func drivePersonalData(field string, user map[string]interface{}, minimized bool) PersonalDataField {
+	email, _ := user["emailAddress"].(string)
+	return PersonalDataField{
+		Field:         field,
+		DataCategory:  classifyField(field),
+		IsMinimized:   minimized,
+		DataSubjectID: email,
+	}
+}
+
+func (d *DriveIntegration) updateDriveMetrics(success bool, duration time.Duration, bytesSent int, bytesReceived int64) {
+	d.metrics.TotalRequests++
+	if success {
+		d.metrics.SuccessfulRequests++
+	} else {
+		d.metrics.FailedRequests++
+	}
+
+	if d.metrics.TotalRequests == 1 {
+		d.metrics.AverageResponseTime = duration
+	} else {
+		d.metrics.AverageResponseTime = (d.metrics.AverageResponseTime*time.Duration(d.metrics.TotalRequests-1) + duration) / time.Duration(d.metrics.TotalRequests)
+	}
+
+	d.metrics.LastRequestTime = time.Now()
+	d.metrics.DataSent += int64(bytesSent)
+	d.metrics.DataReceived += bytesReceived
+}
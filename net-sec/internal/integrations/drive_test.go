@@ -0,0 +1,215 @@
+package integrations
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newDriveTestServer serves a token endpoint that checks the JWT assertion
+// against key, and hands other requests to api after checking the token
+func newDriveTestServer(t *testing.T, key *rsa.PrivateKey, api http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var tokens atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if r.Form.Get("grant_type") != driveJWTGrantType || len(parts) != 3 {
+			http.Error(w, "bad grant", http.StatusBadRequest)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if !strings.Contains(string(claims), `"iss":"sync@project.iam.gserviceaccount.com"`) {
+			http.Error(w, "bad issuer", http.StatusUnauthorized)
+			return
+		}
+		tokens.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "drive-token", "expires_in": 3600})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer drive-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		api(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &tokens
+}
+
+func newTestDrive(t *testing.T, api http.HandlerFunc) (*DriveIntegration, *atomic.Int32) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, tokens := newDriveTestServer(t, key, api)
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	account, _ := json.Marshal(map[string]string{
+		"client_email":   "sync@project.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      server.URL + "/token",
+	})
+
+	drive := NewDriveIntegration(account)
+	drive.baseURL = server.URL
+	drive.uploadURL = server.URL + "/upload"
+	return drive, tokens
+}
+
+func TestDriveRetrieveDataUsesFieldMask(t *testing.T) {
+	var query, fields string
+	drive, tokens := newTestDrive(t, func(w http.ResponseWriter, r *http.Request) {
+		query, fields = r.URL.Query().Get("q"), r.URL.Query().Get("fields")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"files": []map[string]interface{}{{
+				"id":     "file1",
+				"name":   "Q3 report",
+				"owners": []map[string]interface{}{{"displayName": "Alice", "emailAddress": "alice@example.com"}},
+				"permissions": []map[string]interface{}{
+					{"type": "user", "role": "writer", "emailAddress": "bob@example.com"},
+					{"type": "anyone", "role": "reader"},
+				},
+			}},
+		})
+	})
+
+	data, err := drive.RetrieveData(context.Background(), &DataQuery{
+		Filters: map[string]interface{}{"folder_id": "folder'1"},
+		Fields:  []string{"name", "owners(emailAddress)", "permissions(type,emailAddress)"},
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fields != "nextPageToken,files(id,name,owners(emailAddress),permissions(type,emailAddress))" {
+		t.Errorf("unexpected field mask %q", fields)
+	}
+	if query != `trashed = false and 'folder\'1' in parents` {
+		t.Errorf("unexpected query %q", query)
+	}
+	if data.ID != "drive_file1" || data.Content["name"] != "Q3 report" || data.Content["owners"] == nil {
+		t.Errorf("unexpected data %+v", data)
+	}
+
+	subjects := map[string]string{}
+	for _, field := range data.PersonalData {
+		if field.DataSubjectID != "" {
+			subjects[field.DataSubjectID] = field.Field
+		}
+		if !field.IsMinimized {
+			t.Errorf("expected %s to be marked minimized", field.Field)
+		}
+	}
+	if subjects["alice@example.com"] != "owners.emailAddress" || subjects["bob@example.com"] != "permissions.emailAddress" || len(subjects) != 2 {
+		t.Errorf("unexpected personal data %+v", data.PersonalData)
+	}
+
+	// The access token is reused
+	if _, err := drive.RetrieveData(context.Background(), &DataQuery{Filters: map[string]interface{}{}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := tokens.Load(); n != 1 {
+		t.Errorf("expected one token exchange, got %d", n)
+	}
+	if !strings.HasPrefix(fields, "nextPageToken,files("+driveDefaultFields) {
+		t.Errorf("expected the default field mask, got %q", fields)
+	}
+
+	if _, err := drive.RetrieveData(context.Background(), &DataQuery{Fields: []string{"name),files(*"}}); !errors.Is(err, ErrInvalidDriveField) {
+		t.Errorf("expected ErrInvalidDriveField, got %v", err)
+	}
+}
+
+func TestDriveSendDataUploadsFile(t *testing.T) {
+	var metadata, content map[string]interface{}
+	drive, _ := newTestDrive(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/upload/files" || r.URL.Query().Get("uploadType") != "multipart" {
+			http.NotFound(w, r)
+			return
+		}
+		mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "multipart/related" {
+			http.Error(w, "not multipart", http.StatusBadRequest)
+			return
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for _, target := range []*map[string]interface{}{&metadata, &content} {
+			part, err := reader.NextPart()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body, _ := io.ReadAll(part)
+			json.Unmarshal(body, target)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": "file1"})
+	})
+
+	err := drive.SendData(context.Background(), &IntegrationData{
+		ID:             "incident-7",
+		Type:           "incident",
+		Classification: "confidential",
+		LegalBasis:     "legitimate_interest",
+		Content:        map[string]interface{}{"title": "Incident 7", "severity": "high"},
+		Metadata:       map[string]interface{}{"folder_id": "folder1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties, _ := metadata["appProperties"].(map[string]interface{})
+	if metadata["name"] != "Incident 7" || properties["classification"] != "confidential" || properties["legal_basis"] != "legitimate_interest" {
+		t.Errorf("unexpected metadata %v", metadata)
+	}
+	if parents, _ := metadata["parents"].([]interface{}); len(parents) != 1 || parents[0] != "folder1" {
+		t.Errorf("expected the file in folder1, got %v", metadata["parents"])
+	}
+	if content["severity"] != "high" {
+		t.Errorf("unexpected content %v", content)
+	}
+	if metrics := drive.GetMetrics(); metrics.SuccessfulRequests != 1 || metrics.DataSent == 0 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestDriveAuthenticateRejectsInvalidKey(t *testing.T) {
+	drive := NewDriveIntegration(nil)
+	if err := drive.Authenticate(map[string]string{}); err == nil {
+		t.Error("expected a missing key to be rejected")
+	}
+	if err := drive.Authenticate(map[string]string{"service_account_key": `{"client_email":"a@b","private_key":"none"}`}); !errors.Is(err, ErrInvalidServiceAccountKey) {
+		t.Errorf("expected ErrInvalidServiceAccountKey, got %v", err)
+	}
+
+	var _ Integration = drive
+}
@@ -3,8 +3,11 @@ package integrations
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +21,8 @@ type NotionIntegration struct {
 	metrics     *IntegrationMetrics
 	rateLimiter *RateLimiter
 	mutex       sync.RWMutex
+
+	fieldMapping map[string]string // Source to canonical field names
 }
 
 // JiraIntegration implements GDPR-compliant Jira integration
@@ -29,35 +34,91 @@ type JiraIntegration struct {
 	metrics     *IntegrationMetrics
 	rateLimiter *RateLimiter
 	mutex       sync.RWMutex
+
+	fieldMapping map[string]string // Source to canonical field names
 }
 
 // DriveIntegration implements GDPR-compliant Google Drive integration
 type DriveIntegration struct {
 	serviceAccountKey []byte
 	baseURL           string
+	uploadURL         string
 	httpClient        *http.Client
 	metrics           *IntegrationMetrics
 	rateLimiter       *RateLimiter
 	mutex             sync.RWMutex
+
+	fieldMapping map[string]string // Source to canonical field names
+	accessToken  string
+	tokenExpiry  time.Time
+}
+
+// SlackIntegration implements GDPR-compliant Slack notifications
+type SlackIntegration struct {
+	apiToken    string
+	channel     string
+	webhookURL  string
+	baseURL     string
+	httpClient  *http.Client
+	metrics     *IntegrationMetrics
+	rateLimiter *RateLimiter
+	mutex       sync.RWMutex
 }
 
-// RateLimiter implements token bucket rate limiting
+var (
+	// ErrRateLimited is returned when an integration's rate limiter rejects
+	// a request
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrInvalidRateLimit is returned for a RateLimit that allows no
+	// requests
+	ErrInvalidRateLimit = errors.New("rate limit must allow at least one request per minute")
+)
+
+// RateLimiter implements token bucket rate limiting. Tokens accrue
+// continuously at refillRate per second, so a drained limiter recovers
+// steadily rather than in whole-second steps.
 type RateLimiter struct {
-	tokens     int
+	tokens     float64
 	capacity   int
-	refillRate int
+	refillRate float64 // Tokens per second
 	lastRefill time.Time
+	now        func() time.Time
 	mutex      sync.Mutex
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(capacity, refillRate int) *RateLimiter {
 	return &RateLimiter{
-		tokens:     capacity,
+		tokens:     float64(capacity),
 		capacity:   capacity,
-		refillRate: refillRate,
+		refillRate: float64(refillRate),
 		lastRefill: time.Now(),
+		now:        time.Now,
+	}
+}
+
+// SetLimit changes the limiter to allow limit.RequestsPerMinute requests a
+// minute with bursts of limit.BurstSize, or of a second's worth of requests
+// when BurstSize is 0. Tokens already accrued are kept up to the new
+// capacity.
+func (rl *RateLimiter) SetLimit(limit RateLimit) error {
+	if limit.RequestsPerMinute <= 0 {
+		return ErrInvalidRateLimit
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.refill()
+	rl.refillRate = float64(limit.RequestsPerMinute) / 60
+	rl.capacity = limit.BurstSize
+	if rl.capacity <= 0 {
+		rl.capacity = (limit.RequestsPerMinute + 59) / 60
+	}
+	if rl.tokens > float64(rl.capacity) {
+		rl.tokens = float64(rl.capacity)
 	}
+	return nil
 }
 
 // Allow checks if a request is allowed within rate limits
@@ -65,21 +126,10 @@ func (rl *RateLimiter) Allow() bool {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
-	now := time.Now()
-	elapsed := now.Sub(rl.lastRefill)
-
-	// Refill tokens based on elapsed time
-	tokensToAdd := int(elapsed.Seconds()) * rl.refillRate
-	if tokensToAdd > 0 {
-		rl.tokens += tokensToAdd
-		if rl.tokens > rl.capacity {
-			rl.tokens = rl.capacity
-		}
-		rl.lastRefill = now
-	}
+	rl.refill()
 
 	// Check if we have tokens available
-	if rl.tokens > 0 {
+	if rl.tokens >= 1 {
 		rl.tokens--
 		return true
 	}
@@ -87,14 +137,84 @@ func (rl *RateLimiter) Allow() bool {
 	return false
 }
 
+// Wait blocks until a request is allowed, returning ctx.Err() if ctx is
+// cancelled first
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delay, allowed := rl.reserve()
+		if allowed {
+			return nil
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available, and otherwise returns how long
+// until one accrues
+func (rl *RateLimiter) reserve() (time.Duration, bool) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.refill()
+	if rl.tokens >= 1 {
+		rl.tokens--
+		return 0, true
+	}
+
+	// A limiter that never refills is polled in case its limit is raised
+	if rl.refillRate <= 0 {
+		return time.Second, false
+	}
+	return time.Duration((1 - rl.tokens) / rl.refillRate * float64(time.Second)), false
+}
+
+// Tokens returns the number of requests currently allowed
+func (rl *RateLimiter) Tokens() int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.refill()
+	return int(rl.tokens)
+}
+
+// refill adds the tokens accrued since the last refill, keeping fractions of
+// a token; the caller holds mutex
+func (rl *RateLimiter) refill() {
+	now := rl.now()
+	elapsed := now.Sub(rl.lastRefill)
+	if elapsed <= 0 {
+		return
+	}
+
+	rl.tokens += elapsed.Seconds() * rl.refillRate
+	if rl.tokens > float64(rl.capacity) {
+		rl.tokens = float64(rl.capacity)
+	}
+	rl.lastRefill = now
+}
+
 // ===== NOTION INTEGRATION =====
 
+// notionIDPattern matches Notion object IDs, which are UUIDs written with or
+// without dashes
+var notionIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
+// ErrInvalidDatabaseID is returned when a Notion query has a missing or
+// malformed database ID
+var ErrInvalidDatabaseID = errors.New("invalid Notion database ID")
+
 // NewNotionIntegration creates a new Notion integration
 func NewNotionIntegration(apiToken string) *NotionIntegration {
 	return &NotionIntegration{
 		apiToken:    apiToken,
 		baseURL:     "https://api.notion.com/v1",
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  &http.Client{Timeout: DefaultRequestTimeout},
 		metrics:     &IntegrationMetrics{},
 		rateLimiter: NewRateLimiter(100, 3), // 3 requests per second, burst of 100
 	}
@@ -104,6 +224,30 @@ func (n *NotionIntegration) Name() string {
 	return "notion"
 }
 
+// SetHTTPClient replaces the integration's default HTTP client
+func (n *NotionIntegration) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.httpClient = client
+}
+
+// SetFieldMapping renames the page properties Notion returns, keyed by
+// property name, to canonical field names
+func (n *NotionIntegration) SetFieldMapping(mapping map[string]string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.fieldMapping = copyFieldMapping(mapping)
+}
+
+// SetRateLimit replaces the integration's default rate limit
+func (n *NotionIntegration) SetRateLimit(limit RateLimit) error {
+	return n.rateLimiter.SetLimit(limit)
+}
+
 func (n *NotionIntegration) Authenticate(credentials map[string]string) error {
 	if token, ok := credentials["api_token"]; ok {
 		n.apiToken = token
@@ -114,12 +258,16 @@ func (n *NotionIntegration) Authenticate(credentials map[string]string) error {
 
 func (n *NotionIntegration) SendData(ctx context.Context, data *IntegrationData) error {
 	if !n.rateLimiter.Allow() {
-		return fmt.Errorf("rate limit exceeded")
+		return ErrRateLimited
 	}
 
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	start := time.Now()
 
 	// Convert IntegrationData to Notion format
@@ -151,15 +299,38 @@ func (n *NotionIntegration) SendData(ctx context.Context, data *IntegrationData)
 	n.updateMetrics(success, time.Since(start), len(reqBody), resp.ContentLength)
 
 	if !success {
-		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return newAPIError(n.Name(), resp)
 	}
 
 	return nil
 }
 
+// RetrieveData returns the first page of the database in
+// query.Filters["database_id"]. Use RetrieveDataPaged for every matching
+// page.
 func (n *NotionIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
+	endpoint, err := n.databaseQueryEndpoint(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.rateLimiter.Allow() {
+		return nil, ErrRateLimited
+	}
+
+	page, _, err := n.retrieveDatabasePage(ctx, endpoint, "", pageSize(query, 0), query)
+	if err != nil {
+		return nil, err
+	}
+	if len(page) == 0 {
+		return n.convertFromNotionFormat(map[string]interface{}{}, query), nil
+	}
+	return page[0], nil
+}
+
+func (n *NotionIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
 	if !n.rateLimiter.Allow() {
-		return nil, fmt.Errorf("rate limit exceeded")
+		return ErrRateLimited
 	}
 
 	n.mutex.Lock()
@@ -167,61 +338,48 @@ func (n *NotionIntegration) RetrieveData(ctx context.Context, query *DataQuery)
 
 	start := time.Now()
 
-	// Build query URL with data minimization
-	endpoint := fmt.Sprintf("%s/databases/%s/query", n.baseURL, query.Filters["database_id"])
-
-	queryBody := map[string]interface{}{
-		"page_size": query.Limit,
-	}
-
-	// Apply field filtering for data minimization
-	if len(query.Fields) > 0 {
-		queryBody["filter"] = map[string]interface{}{
-			"property": "Name", // This would be more sophisticated in practice
-			"title": map[string]interface{}{
-				"is_not_empty": true,
-			},
-		}
-	}
-
-	reqBody, err := json.Marshal(queryBody)
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"properties": n.convertToNotionProperties(changes),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(string(reqBody)))
+	endpoint := fmt.Sprintf("%s/pages/%s", n.baseURL, url.PathEscape(strings.TrimPrefix(id, "notion_")))
+	req, err := http.NewRequestWithContext(ctx, "PATCH", endpoint, strings.NewReader(string(reqBody)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	n.setNotionHeaders(req)
 
-	// Execute request
 	resp, err := n.httpClient.Do(req)
 	if err != nil {
 		n.updateMetrics(false, time.Since(start), len(reqBody), 0)
-		return nil, fmt.Errorf("request failed: %w", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	n.updateMetrics(success, time.Since(start), len(reqBody), resp.ContentLength)
+
 	if !success {
-		n.updateMetrics(false, time.Since(start), len(reqBody), resp.ContentLength)
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return newAPIError(n.Name(), resp)
 	}
 
-	// Parse response
-	var notionResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&notionResp); err != nil {
-		n.updateMetrics(false, time.Since(start), len(reqBody), resp.ContentLength)
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return nil
+}
 
-	n.updateMetrics(true, time.Since(start), len(reqBody), resp.ContentLength)
+// FindSubjectData is not supported: pages are not linked to a data
+// subject ID, so their records must be located manually
+func (n *NotionIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error) {
+	return nil, fmt.Errorf("notion: finding data subject records: %w", ErrUnsupportedOperation)
+}
 
-	// Convert to IntegrationData format
-	data := n.convertFromNotionFormat(notionResp, query)
-	return data, nil
+// EraseSubjectData is not supported: pages are not linked to a data
+// subject ID, so their records must be located manually
+func (n *NotionIntegration) EraseSubjectData(ctx context.Context, subjectID string) (int, error) {
+	return 0, fmt.Errorf("notion: erasing data subject records: %w", ErrUnsupportedOperation)
 }
 
 func (n *NotionIntegration) ValidateConnection() error {
@@ -240,7 +398,7 @@ func (n *NotionIntegration) ValidateConnection() error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("authentication failed with status %d", resp.StatusCode)
+		return fmt.Errorf("authentication failed: %w", newAPIError(n.Name(), resp))
 	}
 
 	return nil
@@ -255,6 +413,22 @@ func (n *NotionIntegration) GetMetrics() *IntegrationMetrics {
 	return &metricsCopy
 }
 
+// databaseQueryEndpoint returns the query URL for the database in
+// query.Filters["database_id"], which must be a Notion UUID with or without
+// dashes
+func (n *NotionIntegration) databaseQueryEndpoint(query *DataQuery) (string, error) {
+	value, ok := query.Filters["database_id"]
+	if !ok {
+		return "", fmt.Errorf("%w: database_id filter required", ErrInvalidDatabaseID)
+	}
+	databaseID, isString := value.(string)
+	if !isString || !notionIDPattern.MatchString(databaseID) {
+		return "", fmt.Errorf("%w: %q is not a UUID", ErrInvalidDatabaseID, fmt.Sprint(value))
+	}
+
+	return fmt.Sprintf("%s/databases/%s/query", n.baseURL, url.PathEscape(databaseID)), nil
+}
+
 func (n *NotionIntegration) setNotionHeaders(req *http.Request) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", n.apiToken))
 	req.Header.Set("Content-Type", "application/json")
@@ -287,11 +461,29 @@ func (n *NotionIntegration) convertToNotionFormat(data *IntegrationData) map[str
 	}
 }
 
-func (n *NotionIntegration) convertFromNotionFormat(notionData map[string]interface{}, query *DataQuery) *IntegrationData {
-	// Convert Notion response to IntegrationData format
-	// This is simplified - real implementation would be more sophisticated
+// convertToNotionProperties converts changed fields to Notion rich text
+// page properties
+func (n *NotionIntegration) convertToNotionProperties(changes map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{}, len(changes))
+	for field, value := range changes {
+		properties[field] = map[string]interface{}{
+			"rich_text": []map[string]interface{}{
+				{
+					"text": map[string]interface{}{
+						"content": fmt.Sprint(value),
+					},
+				},
+			},
+		}
+	}
+	return properties
+}
+
+// convertFromNotionFormat converts a page of a Notion database query to
+// IntegrationData
+func (n *NotionIntegration) convertFromNotionFormat(page map[string]interface{}, query *DataQuery) *IntegrationData {
 	data := &IntegrationData{
-		ID:                fmt.Sprintf("notion_%s", notionData["id"]),
+		ID:                fmt.Sprintf("notion_%s", page["id"]),
 		Type:              query.Type,
 		Classification:    "internal", // Would be determined based on content
 		Content:           make(map[string]interface{}),
@@ -304,26 +496,8 @@ func (n *NotionIntegration) convertFromNotionFormat(notionData map[string]interf
 	}
 
 	// Extract content with data minimization
-	if results, ok := notionData["results"].([]interface{}); ok && len(results) > 0 {
-		if page, ok := results[0].(map[string]interface{}); ok {
-			if props, ok := page["properties"].(map[string]interface{}); ok {
-				for key, value := range props {
-					// Only include requested fields for data minimization
-					if len(query.Fields) == 0 || contains(query.Fields, key) {
-						data.Content[key] = value
-
-						// Identify potential personal data fields
-						if isPersonalDataField(key) {
-							data.PersonalData = append(data.PersonalData, PersonalDataField{
-								Field:        key,
-								DataCategory: classifyField(key),
-								IsMinimized:  len(query.Fields) > 0,
-							})
-						}
-					}
-				}
-			}
-		}
+	if props, ok := page["properties"].(map[string]interface{}); ok {
+		addQueriedFields(data, mapFields(props, n.fieldMapping), query)
 	}
 
 	return data
@@ -351,12 +525,23 @@ func (n *NotionIntegration) updateMetrics(success bool, duration time.Duration,
 
 // ===== JIRA INTEGRATION =====
 
+// jqlDateFormat is the date format used in JQL date comparisons
+const jqlDateFormat = "2006-01-02"
+
+// jiraProjectKeyPattern matches Jira project keys: an uppercase letter
+// followed by uppercase letters, digits or underscores
+var jiraProjectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,254}$`)
+
+// ErrInvalidProjectKey is returned when a Jira project key filter is not a
+// valid project key
+var ErrInvalidProjectKey = errors.New("invalid Jira project key")
+
 func NewJiraIntegration(username, apiToken, baseURL string) *JiraIntegration {
 	return &JiraIntegration{
 		username:    username,
 		apiToken:    apiToken,
 		baseURL:     baseURL,
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  &http.Client{Timeout: DefaultRequestTimeout},
 		metrics:     &IntegrationMetrics{},
 		rateLimiter: NewRateLimiter(100, 5), // 5 requests per second
 	}
@@ -366,6 +551,30 @@ func (j *JiraIntegration) Name() string {
 	return "jira"
 }
 
+// SetHTTPClient replaces the integration's default HTTP client
+func (j *JiraIntegration) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.httpClient = client
+}
+
+// SetFieldMapping renames the issue fields Jira returns, keyed by field ID,
+// to canonical field names
+func (j *JiraIntegration) SetFieldMapping(mapping map[string]string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.fieldMapping = copyFieldMapping(mapping)
+}
+
+// SetRateLimit replaces the integration's default rate limit
+func (j *JiraIntegration) SetRateLimit(limit RateLimit) error {
+	return j.rateLimiter.SetLimit(limit)
+}
+
 func (j *JiraIntegration) Authenticate(credentials map[string]string) error {
 	username, hasUser := credentials["username"]
 	token, hasToken := credentials["api_token"]
@@ -384,12 +593,16 @@ func (j *JiraIntegration) Authenticate(credentials map[string]string) error {
 
 func (j *JiraIntegration) SendData(ctx context.Context, data *IntegrationData) error {
 	if !j.rateLimiter.Allow() {
-		return fmt.Errorf("rate limit exceeded")
+		return ErrRateLimited
 	}
 
 	j.mutex.Lock()
 	defer j.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	start := time.Now()
 
 	// Convert to Jira issue format
@@ -419,15 +632,32 @@ func (j *JiraIntegration) SendData(ctx context.Context, data *IntegrationData) e
 	j.updateJiraMetrics(success, time.Since(start), len(reqBody), resp.ContentLength)
 
 	if !success {
-		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return newAPIError(j.Name(), resp)
 	}
 
 	return nil
 }
 
+// RetrieveData returns the first issue matching query, starting at
+// query.Offset. Use RetrieveDataPaged for every matching issue.
 func (j *JiraIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
 	if !j.rateLimiter.Allow() {
-		return nil, fmt.Errorf("rate limit exceeded")
+		return nil, ErrRateLimited
+	}
+
+	page, _, err := j.retrieveIssuePage(ctx, query, query.Offset, pageSize(query, 0))
+	if err != nil {
+		return nil, err
+	}
+	if len(page) == 0 {
+		return j.convertFromJiraFormat(map[string]interface{}{}, query), nil
+	}
+	return page[0], nil
+}
+
+func (j *JiraIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	if !j.rateLimiter.Allow() {
+		return ErrRateLimited
 	}
 
 	j.mutex.Lock()
@@ -435,46 +665,46 @@ func (j *JiraIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*
 
 	start := time.Now()
 
-	// Build JQL query with data minimization
-	jqlQuery := j.buildJQLQuery(query)
-	endpoint := fmt.Sprintf("%s/rest/api/3/search?jql=%s&maxResults=%d&startAt=%d",
-		j.baseURL, jqlQuery, query.Limit, query.Offset)
-
-	// Add field restrictions for data minimization
-	if len(query.Fields) > 0 {
-		endpoint += "&fields=" + strings.Join(query.Fields, ",")
+	reqBody, err := json.Marshal(map[string]interface{}{"fields": changes})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	endpoint := fmt.Sprintf("%s/rest/api/3/issue/%s", j.baseURL, strings.TrimPrefix(id, "jira_"))
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, strings.NewReader(string(reqBody)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	j.setJiraHeaders(req)
 
 	resp, err := j.httpClient.Do(req)
 	if err != nil {
-		j.updateJiraMetrics(false, time.Since(start), 0, 0)
-		return nil, fmt.Errorf("request failed: %w", err)
+		j.updateJiraMetrics(false, time.Since(start), len(reqBody), 0)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	j.updateJiraMetrics(success, time.Since(start), len(reqBody), resp.ContentLength)
+
 	if !success {
-		j.updateJiraMetrics(false, time.Since(start), 0, resp.ContentLength)
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		return newAPIError(j.Name(), resp)
 	}
 
-	var jiraResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&jiraResp); err != nil {
-		j.updateJiraMetrics(false, time.Since(start), 0, resp.ContentLength)
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return nil
+}
 
-	j.updateJiraMetrics(true, time.Since(start), 0, resp.ContentLength)
+// FindSubjectData is not supported: issues are not linked to a data
+// subject ID, so their records must be located manually
+func (j *JiraIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error) {
+	return nil, fmt.Errorf("jira: finding data subject records: %w", ErrUnsupportedOperation)
+}
 
-	data := j.convertFromJiraFormat(jiraResp, query)
-	return data, nil
+// EraseSubjectData is not supported: issues are not linked to a data
+// subject ID, so their records must be located manually
+func (j *JiraIntegration) EraseSubjectData(ctx context.Context, subjectID string) (int, error) {
+	return 0, fmt.Errorf("jira: erasing data subject records: %w", ErrUnsupportedOperation)
 }
 
 func (j *JiraIntegration) ValidateConnection() error {
@@ -493,7 +723,7 @@ func (j *JiraIntegration) ValidateConnection() error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("authentication failed with status %d", resp.StatusCode)
+		return fmt.Errorf("authentication failed: %w", newAPIError(j.Name(), resp))
 	}
 
 	return nil
@@ -527,9 +757,11 @@ func (j *JiraIntegration) convertToJiraFormat(data *IntegrationData) map[string]
 	}
 }
 
-func (j *JiraIntegration) convertFromJiraFormat(jiraData map[string]interface{}, query *DataQuery) *IntegrationData {
+// convertFromJiraFormat converts an issue from a Jira search to
+// IntegrationData
+func (j *JiraIntegration) convertFromJiraFormat(issue map[string]interface{}, query *DataQuery) *IntegrationData {
 	data := &IntegrationData{
-		ID:                fmt.Sprintf("jira_%s", jiraData["id"]),
+		ID:                fmt.Sprintf("jira_%s", issue["id"]),
 		Type:              query.Type,
 		Classification:    "internal",
 		Content:           make(map[string]interface{}),
@@ -541,43 +773,64 @@ func (j *JiraIntegration) convertFromJiraFormat(jiraData map[string]interface{},
 		UpdatedAt:         time.Now(),
 	}
 
-	if issues, ok := jiraData["issues"].([]interface{}); ok && len(issues) > 0 {
-		if issue, ok := issues[0].(map[string]interface{}); ok {
-			if fields, ok := issue["fields"].(map[string]interface{}); ok {
-				for key, value := range fields {
-					if len(query.Fields) == 0 || contains(query.Fields, key) {
-						data.Content[key] = value
-
-						if isPersonalDataField(key) {
-							data.PersonalData = append(data.PersonalData, PersonalDataField{
-								Field:        key,
-								DataCategory: classifyField(key),
-								IsMinimized:  len(query.Fields) > 0,
-							})
-						}
-					}
-				}
-			}
-		}
+	if fields, ok := issue["fields"].(map[string]interface{}); ok {
+		addQueriedFields(data, mapFields(fields, j.fieldMapping), query)
 	}
 
 	return data
 }
 
-func (j *JiraIntegration) buildJQLQuery(query *DataQuery) string {
+// searchEndpoint returns the issue search URL for query, restricted to the
+// queried fields for data minimization. Canonical field names are sent as
+// their Jira field IDs.
+func (j *JiraIntegration) searchEndpoint(query *DataQuery, startAt, maxResults int) (string, error) {
+	jql, err := j.buildJQLQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s/rest/api/3/search?jql=%s&maxResults=%d&startAt=%d",
+		j.baseURL, url.QueryEscape(jql), maxResults, startAt)
+
+	if len(query.Fields) > 0 {
+		endpoint += "&fields=" + url.QueryEscape(strings.Join(sourceFields(query.Fields, j.fieldMapping), ","))
+	}
+
+	return endpoint, nil
+}
+
+// buildJQLQuery builds the JQL for query. The project key is validated
+// against Jira's key format and every value is quoted, so filters cannot
+// widen the query.
+func (j *JiraIntegration) buildJQLQuery(query *DataQuery) (string, error) {
 	jql := "project is not EMPTY"
 
-	if projectKey, ok := query.Filters["project_key"]; ok {
-		jql = fmt.Sprintf("project = %s", projectKey)
+	if value, ok := query.Filters["project_key"]; ok {
+		projectKey, isString := value.(string)
+		if !isString || !jiraProjectKeyPattern.MatchString(projectKey) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidProjectKey, fmt.Sprint(value))
+		}
+		jql = "project = " + quoteJQL(projectKey)
 	}
 
 	if query.DateRange != nil {
-		jql += fmt.Sprintf(" AND created >= '%s' AND created <= '%s'",
-			query.DateRange.Start.Format("2006-01-02"),
-			query.DateRange.End.Format("2006-01-02"))
+		if query.DateRange.End.Before(query.DateRange.Start) {
+			return "", fmt.Errorf("invalid date range: end %s is before start %s",
+				query.DateRange.End.Format(jqlDateFormat), query.DateRange.Start.Format(jqlDateFormat))
+		}
+		jql += fmt.Sprintf(" AND created >= %s AND created <= %s",
+			quoteJQL(query.DateRange.Start.UTC().Format(jqlDateFormat)),
+			quoteJQL(query.DateRange.End.UTC().Format(jqlDateFormat)))
 	}
 
-	return jql
+	return jql, nil
+}
+
+// quoteJQL returns value as a double-quoted JQL string literal
+func quoteJQL(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
 }
 
 func (j *JiraIntegration) updateJiraMetrics(success bool, duration time.Duration, bytesSent int, bytesReceived int64) {
@@ -601,6 +854,26 @@ func (j *JiraIntegration) updateJiraMetrics(success bool, duration time.Duration
 
 // Helper functions for data classification and field detection
 
+// addQueriedFields copies the requested fields into data, flagging those
+// that hold personal data
+func addQueriedFields(data *IntegrationData, fields map[string]interface{}, query *DataQuery) {
+	for key, value := range fields {
+		// Only include requested fields for data minimization
+		if len(query.Fields) == 0 || contains(query.Fields, key) {
+			data.Content[key] = value
+
+			// Identify potential personal data fields
+			if isPersonalDataField(key) {
+				data.PersonalData = append(data.PersonalData, PersonalDataField{
+					Field:        key,
+					DataCategory: classifyField(key),
+					IsMinimized:  len(query.Fields) > 0,
+				})
+			}
+		}
+	}
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -0,0 +1,84 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBuildJQLQuery(t *testing.T) {
+	jira := NewJiraIntegration("user", "token", "https://example.atlassian.net")
+
+	jql, err := jira.buildJQLQuery(&DataQuery{Filters: map[string]interface{}{"project_key": "OPS_2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jql != `project = "OPS_2"` {
+		t.Errorf("unexpected JQL %s", jql)
+	}
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	jql, err = jira.buildJQLQuery(&DataQuery{
+		Filters:   map[string]interface{}{},
+		DateRange: &DateRange{Start: start, End: start.AddDate(0, 1, 0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `project is not EMPTY AND created >= "2024-03-01" AND created <= "2024-04-01"`; jql != want {
+		t.Errorf("expected %s, got %s", want, jql)
+	}
+
+	if _, err := jira.buildJQLQuery(&DataQuery{DateRange: &DateRange{Start: start, End: start.Add(-time.Hour)}}); err == nil {
+		t.Error("expected a reversed date range to be rejected")
+	}
+}
+
+func TestBuildJQLQueryRejectsMaliciousProjectKeys(t *testing.T) {
+	jira := NewJiraIntegration("user", "token", "https://example.atlassian.net")
+
+	for _, key := range []interface{}{
+		"X OR project is not EMPTY",
+		`OPS" OR project is not EMPTY OR project = "X`,
+		"OPS ORDER BY created",
+		"ops",
+		"O",
+		"",
+		42,
+	} {
+		jql, err := jira.buildJQLQuery(&DataQuery{Filters: map[string]interface{}{"project_key": key}})
+		if !errors.Is(err, ErrInvalidProjectKey) {
+			t.Errorf("%v: expected ErrInvalidProjectKey, got JQL %q and error %v", key, jql, err)
+		}
+	}
+}
+
+func TestQuoteJQL(t *testing.T) {
+	if quoted := quoteJQL(`a"b\c`); quoted != `"a\"b\\c"` {
+		t.Errorf("unexpected quoting %s", quoted)
+	}
+}
+
+func TestRetrieveDataRejectsMaliciousProjectKey(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	query := &DataQuery{Limit: 10, Filters: map[string]interface{}{"project_key": "X OR project is not EMPTY"}}
+	if _, err := jira.RetrieveData(context.Background(), query); !errors.Is(err, ErrInvalidProjectKey) {
+		t.Errorf("RetrieveData: expected ErrInvalidProjectKey, got %v", err)
+	}
+	if _, err := jira.RetrieveDataPaged(context.Background(), query); !errors.Is(err, ErrInvalidProjectKey) {
+		t.Errorf("RetrieveDataPaged: expected ErrInvalidProjectKey, got %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
@@ -3,8 +3,10 @@ package integrations
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 )
@@ -16,22 +18,39 @@ type IntegrationManager struct {
 	httpClient    *http.Client
 	auditLog      AuditLogger
 	dataMinimizer DataMinimizer
+	consent       ConsentChecker // Optional; guards retrievals relying on consent
 	mutex         sync.RWMutex
+
+	metricsHistory *metricsHistory
+	sleep          func(ctx context.Context, d time.Duration) error // Waits between retries
 }
 
 // IntegrationConfig contains configuration for external integrations
 type IntegrationConfig struct {
 	RequestTimeout     time.Duration        `json:"request_timeout"`
-	RetryAttempts      int                  `json:"retry_attempts"`
-	RetryBackoff       time.Duration        `json:"retry_backoff"`
+	RetryAttempts      int                  `json:"retry_attempts"` // Retries of transient send and retrieve failures
+	RetryBackoff       time.Duration        `json:"retry_backoff"`  // First retry delay, doubled per retry; 0 uses DefaultRetryBackoff
 	DataMinimization   bool                 `json:"data_minimization"`
 	PseudonymizeData   bool                 `json:"pseudonymize_data"`
 	AuditAllRequests   bool                 `json:"audit_all_requests"`
 	TLSConfig          *tls.Config          `json:"-"`
-	RateLimits         map[string]RateLimit `json:"rate_limits"`
-	DataClassification map[string]string    `json:"data_classification"`
+	RateLimits         map[string]RateLimit `json:"rate_limits"`          // Keyed by integration; absent integrations keep their defaults
+	DataClassification map[string]string    `json:"data_classification"`  // Pinned classifications, keyed by integration or FieldClassificationKey
+	MetricsHistorySize int                  `json:"metrics_history_size"` // Metrics snapshots kept; 0 uses DefaultMetricsHistorySize
+
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host"` // 0 uses DefaultMaxIdleConnsPerHost
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout"`       // 0 uses DefaultIdleConnTimeout
+
+	FieldMappings map[string]map[string]string `json:"field_mappings"` // Source to canonical field names, keyed by integration
 }
 
+// Defaults for the HTTP client shared by registered integrations
+const (
+	DefaultRequestTimeout      = 30 * time.Second
+	DefaultMaxIdleConnsPerHost = 16
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
 // RateLimit defines rate limiting for each integration
 type RateLimit struct {
 	RequestsPerMinute int           `json:"requests_per_minute"`
@@ -45,10 +64,47 @@ type Integration interface {
 	Authenticate(credentials map[string]string) error
 	SendData(ctx context.Context, data *IntegrationData) error
 	RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error)
+	UpdateData(ctx context.Context, id string, changes map[string]interface{}) error
 	ValidateConnection() error
 	GetMetrics() *IntegrationMetrics
 }
 
+// HTTPClientSetter is implemented by integrations that can use the
+// manager's shared HTTP client instead of their own
+type HTTPClientSetter interface {
+	SetHTTPClient(client *http.Client)
+}
+
+// FieldMappingSetter is implemented by integrations that can rename the
+// fields returned by their service to canonical names
+type FieldMappingSetter interface {
+	SetFieldMapping(mapping map[string]string)
+}
+
+// RateLimitSetter is implemented by integrations whose rate limit can be
+// configured
+type RateLimitSetter interface {
+	SetRateLimit(limit RateLimit) error
+}
+
+// SubjectFinder is implemented by integrations that can look up a data
+// subject's records in the external service
+type SubjectFinder interface {
+	FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error)
+}
+
+// PagedRetriever is implemented by integrations that can page through every
+// record matching a query
+type PagedRetriever interface {
+	RetrieveDataPaged(ctx context.Context, query *DataQuery) ([]*IntegrationData, error)
+}
+
+// SubjectEraser is implemented by integrations that can delete a data
+// subject's records from the external service
+type SubjectEraser interface {
+	EraseSubjectData(ctx context.Context, subjectID string) (int, error)
+}
+
 // IntegrationData represents data being sent/received from external services
 type IntegrationData struct {
 	ID                string                 `json:"id"`
@@ -71,6 +127,7 @@ type PersonalDataField struct {
 	OriginalValue      string `json:"original_value,omitempty"`
 	PseudonymizedValue string `json:"pseudonymized_value,omitempty"`
 	IsMinimized        bool   `json:"is_minimized"`
+	DataSubjectID      string `json:"data_subject_id,omitempty"` // Whose personal data this is, when known
 }
 
 // DataQuery represents a query for external data
@@ -83,6 +140,7 @@ type DataQuery struct {
 	DateRange     *DateRange             `json:"date_range,omitempty"`
 	LegalBasis    string                 `json:"legal_basis"`
 	Justification string                 `json:"justification"`
+	Purpose       string                 `json:"purpose,omitempty"` // Processing purpose consent is checked for; required on consent
 }
 
 // DateRange represents a date range for queries
@@ -124,7 +182,7 @@ type IntegrationAuditEvent struct {
 	ID           string                 `json:"id"`
 	Timestamp    time.Time              `json:"timestamp"`
 	Integration  string                 `json:"integration"`
-	Operation    string                 `json:"operation"` // "send", "retrieve", "authenticate"
+	Operation    string                 `json:"operation"` // "send", "retrieve", "update", "erase", "authenticate"
 	UserID       string                 `json:"user_id,omitempty"`
 	Success      bool                   `json:"success"`
 	Error        string                 `json:"error,omitempty"`
@@ -170,20 +228,45 @@ type PersonalDataAccessEvent struct {
 
 // NewIntegrationManager creates a new integration manager
 func NewIntegrationManager(config *IntegrationConfig, auditLog AuditLogger, dataMinimizer DataMinimizer) *IntegrationManager {
-	httpClient := &http.Client{
-		Timeout: config.RequestTimeout,
-		Transport: &http.Transport{
-			TLSClientConfig: config.TLSConfig,
-		},
-	}
-
 	return &IntegrationManager{
 		integrations:  make(map[string]Integration),
 		config:        config,
-		httpClient:    httpClient,
+		httpClient:    newHTTPClient(config),
 		auditLog:      auditLog,
 		dataMinimizer: dataMinimizer,
+
+		metricsHistory: newMetricsHistory(config.MetricsHistorySize),
+		sleep:          sleepContext,
+	}
+}
+
+// newHTTPClient returns a pooling, keep-alive HTTP client using the TLS
+// settings from config
+func newHTTPClient(config *IntegrationConfig) *http.Client {
+	timeout := config.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	maxIdlePerHost := config.MaxIdleConnsPerHost
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = DefaultMaxIdleConnsPerHost
 	}
+	idleTimeout := config.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleConnTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLSConfig
+	transport.MaxIdleConnsPerHost = maxIdlePerHost
+	transport.IdleConnTimeout = idleTimeout
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// HTTPClient returns the HTTP client shared with registered integrations
+func (im *IntegrationManager) HTTPClient() *http.Client {
+	return im.httpClient
 }
 
 // RegisterIntegration registers a new external integration
@@ -196,8 +279,26 @@ func (im *IntegrationManager) RegisterIntegration(integration Integration) error
 		return fmt.Errorf("integration %s already registered", name)
 	}
 
+	if limit, ok := im.config.RateLimits[name]; ok {
+		if setter, ok := integration.(RateLimitSetter); ok {
+			if err := setter.SetRateLimit(limit); err != nil {
+				return fmt.Errorf("integration %s: %w", name, err)
+			}
+		}
+	}
+
 	im.integrations[name] = integration
 
+	// Share the manager's pooled client and TLS settings
+	if setter, ok := integration.(HTTPClientSetter); ok {
+		setter.SetHTTPClient(im.httpClient)
+	}
+	if mapping, ok := im.config.FieldMappings[name]; ok {
+		if setter, ok := integration.(FieldMappingSetter); ok {
+			setter.SetFieldMapping(mapping)
+		}
+	}
+
 	// Log registration
 	if im.auditLog != nil {
 		event := IntegrationAuditEvent{
@@ -213,6 +314,32 @@ func (im *IntegrationManager) RegisterIntegration(integration Integration) error
 	return nil
 }
 
+// SetRateLimit changes the rate limit of a registered integration at
+// runtime, and for the integration if it is registered again
+func (im *IntegrationManager) SetRateLimit(integrationName string, limit RateLimit) error {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+
+	integration, exists := im.integrations[integrationName]
+	if !exists {
+		return fmt.Errorf("integration %s not found", integrationName)
+	}
+
+	setter, ok := integration.(RateLimitSetter)
+	if !ok {
+		return fmt.Errorf("integration %s does not support rate limits", integrationName)
+	}
+	if err := setter.SetRateLimit(limit); err != nil {
+		return fmt.Errorf("integration %s: %w", integrationName, err)
+	}
+
+	if im.config.RateLimits == nil {
+		im.config.RateLimits = make(map[string]RateLimit)
+	}
+	im.config.RateLimits[integrationName] = limit
+	return nil
+}
+
 // SendDataWithCompliance sends data to external system with GDPR compliance
 func (im *IntegrationManager) SendDataWithCompliance(ctx context.Context, integrationName string, data *IntegrationData, userID string) error {
 	im.mutex.RLock()
@@ -242,8 +369,15 @@ func (im *IntegrationManager) SendDataWithCompliance(ctx context.Context, integr
 		}
 	}
 
-	// Send data
-	err := integration.SendData(ctx, data)
+	return im.sendData(ctx, integrationName, integration, data, userID)
+}
+
+// sendData sends data to integration as it is, retrying transient failures,
+// and logs the operation
+func (im *IntegrationManager) sendData(ctx context.Context, integrationName string, integration Integration, data *IntegrationData, userID string) error {
+	attempts, err := im.withRetry(ctx, func() error {
+		return integration.SendData(ctx, data)
+	})
 
 	// Log the operation
 	if im.auditLog != nil {
@@ -258,6 +392,7 @@ func (im *IntegrationManager) SendDataWithCompliance(ctx context.Context, integr
 			RecordsCount: 1,
 			LegalBasis:   data.LegalBasis,
 			Purpose:      data.ProcessingPurpose,
+			Metadata:     map[string]interface{}{"attempts": attempts},
 		}
 
 		if err != nil {
@@ -291,8 +426,11 @@ func (im *IntegrationManager) SendDataWithCompliance(ctx context.Context, integr
 	return err
 }
 
-// RetrieveDataWithCompliance retrieves data from external system with GDPR compliance
-func (im *IntegrationManager) RetrieveDataWithCompliance(ctx context.Context, integrationName string, query *DataQuery, userID string) (*IntegrationData, error) {
+// RetrieveDataWithCompliance retrieves data from external system with GDPR
+// compliance. Integrations that implement PagedRetriever are paged through
+// until query.Limit records have been retrieved, or every matching record
+// when Limit is 0; others return a single record.
+func (im *IntegrationManager) RetrieveDataWithCompliance(ctx context.Context, integrationName string, query *DataQuery, userID string) ([]*IntegrationData, error) {
 	im.mutex.RLock()
 	integration, exists := im.integrations[integrationName]
 	im.mutex.RUnlock()
@@ -310,31 +448,48 @@ func (im *IntegrationManager) RetrieveDataWithCompliance(ctx context.Context, in
 		return nil, fmt.Errorf("business justification required for data retrieval")
 	}
 
+	if err := im.checkConsentQuery(integration, query); err != nil {
+		return nil, err
+	}
+
 	// Apply field limitation for data minimization
 	if im.config.DataMinimization && len(query.Fields) == 0 {
 		return nil, fmt.Errorf("specific fields must be requested for data minimization compliance")
 	}
 
-	// Retrieve data
-	data, err := integration.RetrieveData(ctx, query)
+	// Retrieve data, retrying transient failures
+	var records []*IntegrationData
+	attempts, err := im.withRetry(ctx, func() error {
+		var err error
+		records, err = retrieveRecords(ctx, integration, query)
+		return err
+	})
 
-	// Apply post-retrieval processing if successful
-	if err == nil && data != nil && im.dataMinimizer != nil {
-		// Classify data
-		if im.config.DataMinimization {
-			classifications := im.dataMinimizer.ClassifyData(data.Content)
-			for field, classification := range classifications {
-				if classification == "personal" || classification == "sensitive" {
-					data.PersonalData = append(data.PersonalData, PersonalDataField{
-						Field:        field,
-						DataCategory: classification,
-					})
-				}
+	// Classify data, honouring classifications pinned in config
+	if err == nil {
+		for _, data := range records {
+			im.classifyData(integrationName, data)
+		}
+	}
+
+	// Withhold personal data processed on consent the subjects have not given
+	if err == nil {
+		for _, data := range records {
+			if consentErr := im.checkConsent(integrationName, query, data, userID); consentErr != nil {
+				records, err = nil, consentErr
+				break
 			}
 		}
+	}
 
+	// Apply post-retrieval processing if successful
+	if err == nil && im.dataMinimizer != nil && im.config.PseudonymizeData {
 		// Apply pseudonymization to personal data fields
-		if im.config.PseudonymizeData && len(data.PersonalData) > 0 {
+		for _, data := range records {
+			if len(data.PersonalData) == 0 {
+				continue
+			}
+
 			personalFields := make([]string, 0)
 			for _, field := range data.PersonalData {
 				personalFields = append(personalFields, field.Field)
@@ -349,27 +504,31 @@ func (im *IntegrationManager) RetrieveDataWithCompliance(ctx context.Context, in
 	// Log the operation
 	if im.auditLog != nil {
 		event := IntegrationAuditEvent{
-			ID:          generateEventID(),
-			Timestamp:   time.Now(),
-			Integration: integrationName,
-			Operation:   "retrieve",
-			UserID:      userID,
-			Success:     err == nil,
-			DataType:    query.Type,
-			LegalBasis:  query.LegalBasis,
-			Purpose:     query.Justification,
+			ID:           generateEventID(),
+			Timestamp:    time.Now(),
+			Integration:  integrationName,
+			Operation:    "retrieve",
+			UserID:       userID,
+			Success:      err == nil,
+			DataType:     query.Type,
+			LegalBasis:   query.LegalBasis,
+			Purpose:      query.Justification,
+			RecordsCount: len(records),
+			Metadata:     map[string]interface{}{"attempts": attempts},
 		}
 
 		if err != nil {
 			event.Error = err.Error()
-		} else if data != nil {
-			event.RecordsCount = 1
 		}
 
 		im.auditLog.LogIntegrationEvent(event)
 
 		// Log personal data access if successful
-		if err == nil && data != nil && len(data.PersonalData) > 0 {
+		for _, data := range records {
+			if len(data.PersonalData) == 0 {
+				continue
+			}
+
 			pdEvent := PersonalDataAccessEvent{
 				ID:            generateEventID(),
 				Timestamp:     time.Now(),
@@ -390,7 +549,242 @@ func (im *IntegrationManager) RetrieveDataWithCompliance(ctx context.Context, in
 		}
 	}
 
-	return data, err
+	return records, err
+}
+
+// retrieveRecords returns the records matching query, paging through them
+// when integration is a PagedRetriever
+func retrieveRecords(ctx context.Context, integration Integration, query *DataQuery) ([]*IntegrationData, error) {
+	if paged, ok := integration.(PagedRetriever); ok {
+		return paged.RetrieveDataPaged(ctx, query)
+	}
+
+	data, err := integration.RetrieveData(ctx, query)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return []*IntegrationData{data}, nil
+}
+
+// IntegrationNames returns the names of the registered integrations in
+// sorted order
+func (im *IntegrationManager) IntegrationNames() []string {
+	im.mutex.RLock()
+	defer im.mutex.RUnlock()
+
+	names := make([]string, 0, len(im.integrations))
+	for name := range im.integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FindDataSubject looks up a data subject's records in every registered
+// integration that supports it, for a request under legalBasis. Records are
+// keyed by integration name; integrations that fail are returned in errs,
+// wrapping ErrUnsupportedOperation when they cannot look up subjects.
+func (im *IntegrationManager) FindDataSubject(ctx context.Context, subjectID, userID, legalBasis string) (map[string][]*IntegrationData, map[string]error) {
+	records := make(map[string][]*IntegrationData)
+	errs := make(map[string]error)
+
+	for _, name := range im.IntegrationNames() {
+		im.mutex.RLock()
+		integration := im.integrations[name]
+		im.mutex.RUnlock()
+
+		finder, ok := integration.(SubjectFinder)
+		if !ok {
+			errs[name] = fmt.Errorf("integration %s: finding data subject records: %w", name, ErrUnsupportedOperation)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			errs[name] = err
+			continue
+		}
+
+		data, err := finder.FindSubjectData(ctx, subjectID)
+		if err != nil {
+			errs[name] = err
+		} else {
+			records[name] = data
+		}
+
+		if im.auditLog != nil {
+			im.auditLog.LogPersonalDataAccess(PersonalDataAccessEvent{
+				ID:            generateEventID(),
+				Timestamp:     time.Now(),
+				Integration:   name,
+				UserID:        userID,
+				DataSubjectID: subjectID,
+				AccessType:    "read",
+				LegalBasis:    legalBasis,
+				Justification: "data_subject_request",
+				Success:       err == nil,
+				Metadata: map[string]interface{}{
+					"records_found": len(data),
+				},
+			})
+		}
+	}
+
+	return records, errs
+}
+
+// RectifyDataSubject corrects a data subject's records in every registered
+// integration under GDPR Article 16. Each record found for the subject is
+// updated with the changes to the fields it holds. The returned map has an
+// entry per integration, nil when it was rectified and wrapping
+// ErrUnsupportedOperation when its records cannot be located; the error is
+// only set for invalid requests.
+func (im *IntegrationManager) RectifyDataSubject(ctx context.Context, subjectID string, changes map[string]interface{}, userID, legalBasis string) (map[string]error, error) {
+	if subjectID == "" {
+		return nil, fmt.Errorf("data subject ID required for rectification")
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes given for rectification")
+	}
+	if legalBasis == "" {
+		return nil, fmt.Errorf("legal basis required for rectification")
+	}
+
+	found, results := im.FindDataSubject(ctx, subjectID, userID, legalBasis)
+
+	for name, records := range found {
+		im.mutex.RLock()
+		integration := im.integrations[name]
+		im.mutex.RUnlock()
+
+		var errs []error
+		updated := 0
+		fields := make(map[string]bool)
+		for _, record := range records {
+			if err := ctx.Err(); err != nil {
+				errs = append(errs, err)
+				break
+			}
+
+			update := make(map[string]interface{})
+			for field, value := range changes {
+				if _, ok := record.Content[field]; ok {
+					update[field] = value
+				}
+			}
+			if len(update) == 0 {
+				continue
+			}
+
+			if err := integration.UpdateData(ctx, record.ID, update); err != nil {
+				errs = append(errs, fmt.Errorf("failed to update %s: %w", record.ID, err))
+				continue
+			}
+			updated++
+			for field := range update {
+				fields[field] = true
+			}
+		}
+
+		err := errors.Join(errs...)
+		results[name] = err
+
+		// Log the operation
+		if im.auditLog != nil {
+			event := IntegrationAuditEvent{
+				ID:           generateEventID(),
+				Timestamp:    time.Now(),
+				Integration:  name,
+				Operation:    "update",
+				UserID:       userID,
+				Success:      err == nil,
+				RecordsCount: updated,
+				LegalBasis:   legalBasis,
+				Purpose:      "right_to_rectification",
+			}
+
+			if err != nil {
+				event.Error = err.Error()
+			}
+
+			im.auditLog.LogIntegrationEvent(event)
+
+			pdEvent := PersonalDataAccessEvent{
+				ID:             generateEventID(),
+				Timestamp:      time.Now(),
+				Integration:    name,
+				UserID:         userID,
+				DataSubjectID:  subjectID,
+				AccessType:     "write",
+				LegalBasis:     legalBasis,
+				Justification:  "right_to_rectification",
+				FieldsAccessed: make([]string, 0, len(fields)),
+				Success:        err == nil,
+			}
+
+			for field := range fields {
+				pdEvent.FieldsAccessed = append(pdEvent.FieldsAccessed, field)
+			}
+			sort.Strings(pdEvent.FieldsAccessed)
+
+			im.auditLog.LogPersonalDataAccess(pdEvent)
+		}
+	}
+
+	return results, nil
+}
+
+// EraseSubjectData deletes a data subject's records from an external system
+// under GDPR Article 17, returning the number of records deleted
+func (im *IntegrationManager) EraseSubjectData(ctx context.Context, integrationName, subjectID, userID string) (int, error) {
+	im.mutex.RLock()
+	integration, exists := im.integrations[integrationName]
+	im.mutex.RUnlock()
+
+	if !exists {
+		return 0, fmt.Errorf("integration %s not found", integrationName)
+	}
+
+	var deleted int
+	var err error
+	if eraser, ok := integration.(SubjectEraser); ok {
+		deleted, err = eraser.EraseSubjectData(ctx, subjectID)
+	} else {
+		err = fmt.Errorf("integration %s: erasing data subject records: %w", integrationName, ErrUnsupportedOperation)
+	}
+
+	// Log the operation
+	if im.auditLog != nil {
+		event := IntegrationAuditEvent{
+			ID:           generateEventID(),
+			Timestamp:    time.Now(),
+			Integration:  integrationName,
+			Operation:    "erase",
+			UserID:       userID,
+			Success:      err == nil,
+			RecordsCount: deleted,
+			LegalBasis:   "Article 17",
+			Purpose:      "right_to_erasure",
+		}
+
+		if err != nil {
+			event.Error = err.Error()
+		}
+
+		im.auditLog.LogIntegrationEvent(event)
+
+		im.auditLog.LogPersonalDataAccess(PersonalDataAccessEvent{
+			ID:            generateEventID(),
+			Timestamp:     time.Now(),
+			Integration:   integrationName,
+			UserID:        userID,
+			DataSubjectID: subjectID,
+			AccessType:    "delete",
+			LegalBasis:    "Article 17",
+			Justification: "right_to_erasure",
+			Success:       err == nil,
+		})
+	}
+
+	return deleted, err
 }
 
 // ValidateCompliance validates that an integration meets GDPR compliance requirements
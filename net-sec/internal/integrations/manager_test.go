@@ -0,0 +1,261 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// update is a single UpdateData call
+type update struct {
+	id      string
+	changes map[string]interface{}
+}
+
+// mockIntegration holds records per data subject and records updates
+type mockIntegration struct {
+	name      string
+	subjects  map[string][]*IntegrationData
+	updateErr error
+	metrics   IntegrationMetrics
+	retrieved *IntegrationData
+
+	// unattributed makes the integration report that it does not attribute
+	// personal data to data subjects
+	unattributed bool
+
+	mu      sync.Mutex
+	updates []update
+}
+
+func (m *mockIntegration) Name() string                                     { return m.name }
+func (m *mockIntegration) Authenticate(credentials map[string]string) error { return nil }
+func (m *mockIntegration) SendData(ctx context.Context, data *IntegrationData) error {
+	return nil
+}
+func (m *mockIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
+	return m.retrieved, nil
+}
+func (m *mockIntegration) ValidateConnection() error { return nil }
+
+func (m *mockIntegration) GetMetrics() *IntegrationMetrics {
+	metrics := m.metrics
+	return &metrics
+}
+
+func (m *mockIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.updates = append(m.updates, update{id: id, changes: changes})
+	return m.updateErr
+}
+
+func (m *mockIntegration) AttributesDataSubjects() bool { return !m.unattributed }
+
+func (m *mockIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error) {
+	return m.subjects[subjectID], nil
+}
+
+// recordingAuditLog collects integration audit events
+type recordingAuditLog struct {
+	mu        sync.Mutex
+	events    []IntegrationAuditEvent
+	accesses  []PersonalDataAccessEvent
+	transfers []DataTransferEvent
+}
+
+func (r *recordingAuditLog) LogIntegrationEvent(event IntegrationAuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingAuditLog) LogDataTransfer(event DataTransferEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transfers = append(r.transfers, event)
+}
+
+func (r *recordingAuditLog) LogPersonalDataAccess(event PersonalDataAccessEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.accesses = append(r.accesses, event)
+}
+
+func TestRectifyDataSubject(t *testing.T) {
+	crm := &mockIntegration{name: "crm", subjects: map[string][]*IntegrationData{
+		"subject_1": {
+			{ID: "contact-1", Content: map[string]interface{}{"email": "old@example.com", "name": "Alice"}},
+			{ID: "contact-2", Content: map[string]interface{}{"plan": "pro"}},
+		},
+	}}
+	helpdesk := &mockIntegration{name: "helpdesk", subjects: map[string][]*IntegrationData{
+		"subject_1": {
+			{ID: "ticket-7", Content: map[string]interface{}{"email": "old@example.com", "subject": "Login"}},
+		},
+		"subject_2": {
+			{ID: "ticket-8", Content: map[string]interface{}{"email": "other@example.com"}},
+		},
+	}}
+
+	auditLog := &recordingAuditLog{}
+	manager := NewIntegrationManager(&IntegrationConfig{}, auditLog, nil)
+	for _, integration := range []Integration{crm, helpdesk} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := map[string]interface{}{"email": "alice@example.com", "name": "Alice Smith"}
+	results, err := manager.RectifyDataSubject(context.Background(), "subject_1", changes, "dpo", "Article 16")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results["crm"] != nil || results["helpdesk"] != nil {
+		t.Fatalf("expected both integrations rectified, got %v", results)
+	}
+
+	// Only records holding a changed field are updated, with just those fields
+	wantCRM := []update{{id: "contact-1", changes: map[string]interface{}{"email": "alice@example.com", "name": "Alice Smith"}}}
+	if !reflect.DeepEqual(crm.updates, wantCRM) {
+		t.Errorf("crm: expected updates %v, got %v", wantCRM, crm.updates)
+	}
+	wantHelpdesk := []update{{id: "ticket-7", changes: map[string]interface{}{"email": "alice@example.com"}}}
+	if !reflect.DeepEqual(helpdesk.updates, wantHelpdesk) {
+		t.Errorf("helpdesk: expected updates %v, got %v", wantHelpdesk, helpdesk.updates)
+	}
+
+	writes := make(map[string]PersonalDataAccessEvent)
+	for _, access := range auditLog.accesses {
+		if access.AccessType == "write" {
+			writes[access.Integration] = access
+		}
+	}
+	if len(writes) != 2 {
+		t.Fatalf("expected a write event per integration, got %v", auditLog.accesses)
+	}
+	if fields := strings.Join(writes["crm"].FieldsAccessed, ","); fields != "email,name" {
+		t.Errorf("crm write event has fields %s", fields)
+	}
+	if write := writes["helpdesk"]; write.DataSubjectID != "subject_1" || write.LegalBasis != "Article 16" || !write.Success {
+		t.Errorf("unexpected helpdesk write event: %+v", write)
+	}
+}
+
+func TestRectifyDataSubjectReportsFailures(t *testing.T) {
+	failing := &mockIntegration{
+		name:      "crm",
+		subjects:  map[string][]*IntegrationData{"subject_1": {{ID: "contact-1", Content: map[string]interface{}{"email": "old"}}}},
+		updateErr: fmt.Errorf("service unavailable"),
+	}
+
+	manager := NewIntegrationManager(&IntegrationConfig{}, nil, nil)
+	connectors := []Integration{
+		NewNotionIntegration("token"),
+		NewJiraIntegration("user", "token", "https://example.atlassian.net"),
+		NewDriveIntegration(nil),
+		NewSlackWebhookIntegration("https://hooks.slack.com/services/T/B/X"),
+	}
+	for _, integration := range append([]Integration{failing}, connectors...) {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := manager.RectifyDataSubject(context.Background(), "subject_1", map[string]interface{}{"email": "new"}, "dpo", "Article 16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results["crm"] == nil || !strings.Contains(results["crm"].Error(), "contact-1") {
+		t.Errorf("expected crm update failure, got %v", results["crm"])
+	}
+	for _, connector := range connectors {
+		name := connector.Name()
+		if !errors.Is(results[name], ErrUnsupportedOperation) {
+			t.Errorf("%s: expected an unsupported rectification, got %v", name, results[name])
+		}
+		if _, err := manager.EraseSubjectData(context.Background(), name, "subject_1", "dpo"); !errors.Is(err, ErrUnsupportedOperation) {
+			t.Errorf("%s: expected an unsupported erasure, got %v", name, err)
+		}
+	}
+
+	if _, err := manager.RectifyDataSubject(context.Background(), "subject_1", map[string]interface{}{"email": "new"}, "dpo", ""); err == nil {
+		t.Error("expected rectification without legal basis to be rejected")
+	}
+}
+
+func TestUpdateDataRequests(t *testing.T) {
+	var method, path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid request body %q: %v", data, err)
+		}
+	}))
+	defer server.Close()
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	if err := jira.UpdateData(context.Background(), "jira_10042", map[string]interface{}{"summary": "Fixed"}); err != nil {
+		t.Fatal(err)
+	}
+	if method != "PUT" || path != "/rest/api/3/issue/10042" {
+		t.Errorf("jira: unexpected request %s %s", method, path)
+	}
+	if !reflect.DeepEqual(body, map[string]interface{}{"fields": map[string]interface{}{"summary": "Fixed"}}) {
+		t.Errorf("jira: unexpected body %v", body)
+	}
+
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+	if err := notion.UpdateData(context.Background(), "notion_abc", map[string]interface{}{"Email": "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if method != "PATCH" || path != "/pages/abc" {
+		t.Errorf("notion: unexpected request %s %s", method, path)
+	}
+	want := map[string]interface{}{"properties": map[string]interface{}{
+		"Email": map[string]interface{}{"rich_text": []interface{}{
+			map[string]interface{}{"text": map[string]interface{}{"content": "a@example.com"}},
+		}},
+	}}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("notion: unexpected body %v", body)
+	}
+}
+
+func TestRectifyDataSubjectStopsWhenCancelled(t *testing.T) {
+	crm := &mockIntegration{name: "crm", subjects: map[string][]*IntegrationData{
+		"subject_1": {{ID: "contact-1", Content: map[string]interface{}{"email": "old"}}},
+	}}
+	manager := NewIntegrationManager(&IntegrationConfig{}, nil, nil)
+	if err := manager.RegisterIntegration(crm); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := manager.RectifyDataSubject(ctx, "subject_1", map[string]interface{}{"email": "new"}, "dpo", "Article 16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results["crm"], context.Canceled) {
+		t.Errorf("expected context.Canceled for crm, got %v", results["crm"])
+	}
+	if len(crm.updates) != 0 {
+		t.Errorf("expected no updates after cancellation, got %v", crm.updates)
+	}
+}
@@ -0,0 +1,61 @@
+package integrations
+
+import "sort"
+
+// mapFields returns fields with their keys renamed by mapping, from source
+// to canonical names. Unmapped fields pass through; a mapped field replaces
+// an unmapped one already using its canonical name.
+func mapFields(fields map[string]interface{}, mapping map[string]string) map[string]interface{} {
+	if len(mapping) == 0 {
+		return fields
+	}
+
+	mapped := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if _, renamed := mapping[key]; !renamed {
+			mapped[key] = value
+		}
+	}
+	for key, value := range fields {
+		if canonical, renamed := mapping[key]; renamed {
+			mapped[canonical] = value
+		}
+	}
+	return mapped
+}
+
+// sourceFields returns the source names of canonical field names, for
+// services that are asked for specific fields
+func sourceFields(fields []string, mapping map[string]string) []string {
+	if len(mapping) == 0 {
+		return fields
+	}
+
+	sources := make(map[string][]string, len(mapping))
+	for source, canonical := range mapping {
+		sources[canonical] = append(sources[canonical], source)
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if mapped, ok := sources[field]; ok {
+			sort.Strings(mapped)
+			names = append(names, mapped...)
+			continue
+		}
+		names = append(names, field)
+	}
+	return names
+}
+
+func copyFieldMapping(mapping map[string]string) map[string]string {
+	if mapping == nil {
+		return nil
+	}
+
+	mappingCopy := make(map[string]string, len(mapping))
+	for source, canonical := range mapping {
+		mappingCopy[source] = canonical
+	}
+	return mappingCopy
+}
@@ -0,0 +1,100 @@
+package integrations
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func personalFields(data *IntegrationData) map[string]bool {
+	fields := make(map[string]bool)
+	for _, field := range data.PersonalData {
+		fields[field.Field] = true
+	}
+	return fields
+}
+
+func TestNotionFieldMapping(t *testing.T) {
+	notion := NewNotionIntegration("token")
+	notion.SetFieldMapping(map[string]string{"Name": "title", "Contact": "email"})
+
+	response := map[string]interface{}{
+		"id": "page-1",
+		"properties": map[string]interface{}{
+			"Name":    "Quarterly review",
+			"Contact": "alice@example.com",
+			"Status":  "Done",
+		},
+	}
+
+	data := notion.convertFromNotionFormat(response, &DataQuery{})
+	want := map[string]interface{}{"title": "Quarterly review", "email": "alice@example.com", "Status": "Done"}
+	if !reflect.DeepEqual(data.Content, want) {
+		t.Errorf("expected canonical content %v, got %v", want, data.Content)
+	}
+	if personal := personalFields(data); len(personal) != 1 || !personal["email"] {
+		t.Errorf("expected the canonical email field to be detected as personal data, got %+v", data.PersonalData)
+	}
+
+	// Queried fields use canonical names
+	data = notion.convertFromNotionFormat(response, &DataQuery{Fields: []string{"title"}})
+	if len(data.Content) != 1 || data.Content["title"] != "Quarterly review" {
+		t.Errorf("expected only the title, got %v", data.Content)
+	}
+}
+
+func TestJiraFieldMapping(t *testing.T) {
+	jira := NewJiraIntegration("user", "token", "https://jira.example.com")
+	jira.SetFieldMapping(map[string]string{"summary": "title", "customfield_10042": "reporter_email"})
+
+	data := jira.convertFromJiraFormat(map[string]interface{}{
+		"id": "10001",
+		"fields": map[string]interface{}{
+			"summary":           "Login fails",
+			"customfield_10042": "bob@example.com",
+			"priority":          "High",
+		},
+	}, &DataQuery{})
+
+	want := map[string]interface{}{"title": "Login fails", "reporter_email": "bob@example.com", "priority": "High"}
+	if !reflect.DeepEqual(data.Content, want) {
+		t.Errorf("expected canonical content %v, got %v", want, data.Content)
+	}
+	if personal := personalFields(data); len(personal) != 1 || !personal["reporter_email"] {
+		t.Errorf("expected reporter_email to be detected as personal data, got %+v", data.PersonalData)
+	}
+
+	// Jira is asked for the source field IDs
+	endpoint, err := jira.searchEndpoint(&DataQuery{Fields: []string{"title", "reporter_email", "priority"}}, 0, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := parsed.Query().Get("fields"); fields != "summary,customfield_10042,priority" {
+		t.Errorf("expected source field IDs, got %q", fields)
+	}
+}
+
+func TestRegisterIntegrationAppliesFieldMapping(t *testing.T) {
+	manager := NewIntegrationManager(&IntegrationConfig{
+		FieldMappings: map[string]map[string]string{"notion": {"Name": "title"}},
+	}, nil, nil)
+
+	notion := NewNotionIntegration("token")
+	jira := NewJiraIntegration("user", "token", "https://jira.example.com")
+	for _, integration := range []Integration{notion, jira} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !reflect.DeepEqual(notion.fieldMapping, map[string]string{"Name": "title"}) {
+		t.Errorf("expected the Notion mapping to be applied, got %v", notion.fieldMapping)
+	}
+	if jira.fieldMapping != nil {
+		t.Errorf("expected Jira to keep its source names, got %v", jira.fieldMapping)
+	}
+}
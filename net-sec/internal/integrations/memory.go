@@ -0,0 +1,348 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRecordNotFound is returned when an integration holds no record with
+// the requested ID
+var ErrRecordNotFound = errors.New("record not found")
+
+// MemoryIntegration is an in-process Integration backed by a map, for tests
+// and local development. Records are stored as sent, so the effect of the
+// manager's minimization and pseudonymization can be inspected with Record.
+type MemoryIntegration struct {
+	name    string
+	records map[string]*IntegrationData
+	nextID  int
+	metrics *IntegrationMetrics
+	mutex   sync.RWMutex
+}
+
+// NewMemoryIntegration creates an empty in-memory integration registered
+// under name, or "memory" when name is empty
+func NewMemoryIntegration(name string) *MemoryIntegration {
+	if name == "" {
+		name = "memory"
+	}
+
+	return &MemoryIntegration{
+		name:    name,
+		records: make(map[string]*IntegrationData),
+		metrics: &IntegrationMetrics{},
+	}
+}
+
+func (m *MemoryIntegration) Name() string {
+	return m.name
+}
+
+// AttributesDataSubjects reports that retrieved personal data keeps the
+// data subjects of the stored records
+func (m *MemoryIntegration) AttributesDataSubjects() bool {
+	return true
+}
+
+// Authenticate accepts any credentials
+func (m *MemoryIntegration) Authenticate(credentials map[string]string) error {
+	return nil
+}
+
+// SendData stores a copy of data, assigning an ID when it has none
+func (m *MemoryIntegration) SendData(ctx context.Context, data *IntegrationData) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	record := copyIntegrationData(data)
+	if record.ID == "" {
+		m.nextID++
+		record.ID = fmt.Sprintf("%s_%d", m.name, m.nextID)
+		data.ID = record.ID
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	record.UpdatedAt = time.Now()
+
+	m.records[record.ID] = record
+	m.updateMetrics(true)
+	m.metrics.PersonalDataFields += int64(len(record.PersonalData))
+	return nil
+}
+
+// RetrieveData returns the first record, by ID, matching query
+func (m *MemoryIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	matches := m.match(query)
+	if len(matches) == 0 {
+		m.updateMetrics(false)
+		return nil, fmt.Errorf("%w: no record matches query", ErrRecordNotFound)
+	}
+
+	m.updateMetrics(true)
+	return project(matches[0], query), nil
+}
+
+// RetrieveDataPaged returns the records matching query from query.Offset,
+// ordered by ID, up to query.Limit records when it is set
+func (m *MemoryIntegration) RetrieveDataPaged(ctx context.Context, query *DataQuery) ([]*IntegrationData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	matches := m.match(query)
+	records := make([]*IntegrationData, 0, len(matches))
+	for _, record := range matches {
+		records = append(records, project(record, query))
+	}
+
+	records, _ = limitRecords(query, records)
+	m.updateMetrics(true)
+	return records, nil
+}
+
+// UpdateData merges changes into the content of the record with id
+func (m *MemoryIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		m.updateMetrics(false)
+		return fmt.Errorf("%w: %s", ErrRecordNotFound, id)
+	}
+
+	for field, value := range changes {
+		record.Content[field] = value
+	}
+	record.UpdatedAt = time.Now()
+	m.updateMetrics(true)
+	return nil
+}
+
+// DeleteData removes the record with id
+func (m *MemoryIntegration) DeleteData(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.records[id]; !ok {
+		m.updateMetrics(false)
+		return fmt.Errorf("%w: %s", ErrRecordNotFound, id)
+	}
+
+	delete(m.records, id)
+	m.updateMetrics(true)
+	return nil
+}
+
+// FindSubjectData returns copies of the records holding personal data of
+// subjectID
+func (m *MemoryIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	records := make([]*IntegrationData, 0)
+	for _, id := range m.subjectRecordIDs(subjectID) {
+		records = append(records, copyIntegrationData(m.records[id]))
+	}
+
+	m.updateMetrics(true)
+	return records, nil
+}
+
+// EraseSubjectData deletes the records holding personal data of subjectID
+func (m *MemoryIntegration) EraseSubjectData(ctx context.Context, subjectID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ids := m.subjectRecordIDs(subjectID)
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+
+	m.updateMetrics(true)
+	return len(ids), nil
+}
+
+func (m *MemoryIntegration) ValidateConnection() error {
+	return nil
+}
+
+func (m *MemoryIntegration) GetMetrics() *IntegrationMetrics {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	metricsCopy := *m.metrics
+	return &metricsCopy
+}
+
+// Record returns a copy of the stored record with id
+func (m *MemoryIntegration) Record(id string) (*IntegrationData, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		return nil, false
+	}
+	return copyIntegrationData(record), true
+}
+
+// Len returns the number of stored records
+func (m *MemoryIntegration) Len() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return len(m.records)
+}
+
+// match returns the records matching query's type and filters from
+// query.Offset, ordered by ID. Filters match content or metadata values.
+func (m *MemoryIntegration) match(query *DataQuery) []*IntegrationData {
+	matches := make([]*IntegrationData, 0)
+	for _, id := range m.sortedIDs() {
+		record := m.records[id]
+		if query.Type != "" && record.Type != query.Type {
+			continue
+		}
+		if query.DateRange != nil && (record.CreatedAt.Before(query.DateRange.Start) || record.CreatedAt.After(query.DateRange.End)) {
+			continue
+		}
+		if matchesFilters(record, query.Filters) {
+			matches = append(matches, record)
+		}
+	}
+
+	if query.Offset >= len(matches) {
+		return nil
+	}
+	return matches[query.Offset:]
+}
+
+// subjectRecordIDs returns the IDs of the records with personal data of
+// subjectID, ordered by ID
+func (m *MemoryIntegration) subjectRecordIDs(subjectID string) []string {
+	ids := make([]string, 0)
+	for _, id := range m.sortedIDs() {
+		for _, field := range m.records[id].PersonalData {
+			if field.DataSubjectID == subjectID {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func (m *MemoryIntegration) sortedIDs() []string {
+	ids := make([]string, 0, len(m.records))
+	for id := range m.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (m *MemoryIntegration) updateMetrics(success bool) {
+	m.metrics.TotalRequests++
+	if success {
+		m.metrics.SuccessfulRequests++
+	} else {
+		m.metrics.FailedRequests++
+	}
+	m.metrics.LastRequestTime = time.Now()
+}
+
+// matchesFilters reports whether every filter equals the record's content
+// or metadata value of the same name
+func matchesFilters(record *IntegrationData, filters map[string]interface{}) bool {
+	for key, want := range filters {
+		value, ok := record.Content[key]
+		if !ok {
+			value, ok = record.Metadata[key]
+		}
+		if !ok || fmt.Sprint(value) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// project returns a copy of record holding only the queried fields, with
+// personal data fields classified
+func project(record *IntegrationData, query *DataQuery) *IntegrationData {
+	data := copyIntegrationData(record)
+	data.Content = make(map[string]interface{})
+	data.PersonalData = make([]PersonalDataField, 0)
+	if query.LegalBasis != "" {
+		data.LegalBasis = query.LegalBasis
+	}
+	if query.Justification != "" {
+		data.ProcessingPurpose = query.Justification
+	}
+
+	addQueriedFields(data, record.Content, query)
+	for i, field := range data.PersonalData {
+		for _, stored := range record.PersonalData {
+			if stored.Field == field.Field {
+				data.PersonalData[i].DataSubjectID = stored.DataSubjectID
+			}
+		}
+	}
+	sort.Slice(data.PersonalData, func(i, j int) bool {
+		return data.PersonalData[i].Field < data.PersonalData[j].Field
+	})
+	return data
+}
+
+// copyIntegrationData returns a copy of data with its own content, metadata
+// and personal data slices
+func copyIntegrationData(data *IntegrationData) *IntegrationData {
+	dataCopy := *data
+
+	dataCopy.Content = make(map[string]interface{}, len(data.Content))
+	for key, value := range data.Content {
+		dataCopy.Content[key] = value
+	}
+	dataCopy.Metadata = make(map[string]interface{}, len(data.Metadata))
+	for key, value := range data.Metadata {
+		dataCopy.Metadata[key] = value
+	}
+	dataCopy.PersonalData = append([]PersonalDataField(nil), data.PersonalData...)
+
+	return &dataCopy
+}
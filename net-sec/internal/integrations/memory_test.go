@@ -0,0 +1,175 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// purposeMinimizer keeps only the fields allowed for a purpose and
+// pseudonymizes by replacing values
+type purposeMinimizer struct {
+	stubMinimizer
+	allowed map[string][]string
+}
+
+func (p purposeMinimizer) MinimizeData(data map[string]interface{}, purpose string) map[string]interface{} {
+	minimized := make(map[string]interface{})
+	for _, field := range p.allowed[purpose] {
+		if value, ok := data[field]; ok {
+			minimized[field] = value
+		}
+	}
+	return minimized
+}
+
+func TestSendDataWithComplianceTransformsStoredContent(t *testing.T) {
+	memory := NewMemoryIntegration("crm")
+	minimizer := purposeMinimizer{allowed: map[string][]string{"support": {"email", "name", "plan"}}}
+	manager := NewIntegrationManager(&IntegrationConfig{DataMinimization: true, PseudonymizeData: true}, nil, minimizer)
+	if err := manager.RegisterIntegration(memory); err != nil {
+		t.Fatal(err)
+	}
+
+	data := &IntegrationData{
+		Type: "contact",
+		Content: map[string]interface{}{
+			"email":    "alice@example.com",
+			"name":     "Alice",
+			"plan":     "pro",
+			"birthday": "1990-01-01",
+		},
+		PersonalData: []PersonalDataField{
+			{Field: "email", DataCategory: "sensitive", DataSubjectID: "subject_1"},
+			{Field: "name", DataCategory: "personal", DataSubjectID: "subject_1"},
+		},
+		LegalBasis:        "Article 6(1)(b)",
+		ProcessingPurpose: "support",
+	}
+	if err := manager.SendDataWithCompliance(context.Background(), "crm", data, "agent"); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, ok := memory.Record(data.ID)
+	if !ok {
+		t.Fatalf("expected record %q to be stored", data.ID)
+	}
+	want := map[string]interface{}{"email": "pseudo:email", "name": "pseudo:name", "plan": "pro"}
+	if !reflect.DeepEqual(stored.Content, want) {
+		t.Errorf("expected stored content %v, got %v", want, stored.Content)
+	}
+
+	// Stored records are copies
+	data.Content["plan"] = "free"
+	if stored, _ := memory.Record(data.ID); stored.Content["plan"] != "pro" {
+		t.Error("stored record changed with the sent data")
+	}
+}
+
+func TestMemoryIntegrationOperations(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryIntegration("")
+	if memory.Name() != "memory" {
+		t.Errorf("unexpected default name %s", memory.Name())
+	}
+
+	for _, data := range []*IntegrationData{
+		{ID: "a", Type: "contact", Content: map[string]interface{}{"email": "a@example.com", "plan": "pro"},
+			PersonalData: []PersonalDataField{{Field: "email", DataSubjectID: "subject_1"}}},
+		{ID: "b", Type: "contact", Content: map[string]interface{}{"email": "b@example.com", "plan": "free"},
+			PersonalData: []PersonalDataField{{Field: "email", DataSubjectID: "subject_2"}}},
+		{ID: "c", Type: "ticket", Content: map[string]interface{}{"subject": "Login", "plan": "pro"},
+			PersonalData: []PersonalDataField{{Field: "subject", DataSubjectID: "subject_1"}}},
+	} {
+		if err := memory.SendData(ctx, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Retrieval filters, projects and classifies
+	data, err := memory.RetrieveData(ctx, &DataQuery{Type: "contact", Filters: map[string]interface{}{"plan": "free"}, Fields: []string{"email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.ID != "b" || !reflect.DeepEqual(data.Content, map[string]interface{}{"email": "b@example.com"}) {
+		t.Errorf("unexpected record %s %v", data.ID, data.Content)
+	}
+	if len(data.PersonalData) != 1 || data.PersonalData[0].DataCategory != "sensitive" || !data.PersonalData[0].IsMinimized {
+		t.Errorf("unexpected personal data %+v", data.PersonalData)
+	}
+	if _, err := memory.RetrieveData(ctx, &DataQuery{Type: "invoice"}); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	records, err := memory.RetrieveDataPaged(ctx, &DataQuery{Filters: map[string]interface{}{"plan": "pro"}, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != "c" {
+		t.Errorf("expected only record c after the offset, got %v", records)
+	}
+
+	if err := memory.UpdateData(ctx, "a", map[string]interface{}{"plan": "team"}); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := memory.Record("a"); stored.Content["plan"] != "team" {
+		t.Errorf("update not applied: %v", stored.Content)
+	}
+	if err := memory.UpdateData(ctx, "missing", nil); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	found, err := memory.FindSubjectData(ctx, "subject_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].ID != "a" || found[1].ID != "c" {
+		t.Errorf("unexpected subject records %v", found)
+	}
+
+	if err := memory.DeleteData(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := memory.DeleteData(ctx, "b"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	erased, err := memory.EraseSubjectData(ctx, "subject_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erased != 2 || memory.Len() != 0 {
+		t.Errorf("expected 2 records erased and none left, got %d erased and %d left", erased, memory.Len())
+	}
+
+	if metrics := memory.GetMetrics(); metrics.FailedRequests != 3 || metrics.TotalRequests != 12 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestRetrieveDataWithCompliancePseudonymizesMemoryRecords(t *testing.T) {
+	memory := NewMemoryIntegration("crm")
+	manager := NewIntegrationManager(&IntegrationConfig{DataMinimization: true, PseudonymizeData: true}, nil, stubMinimizer{})
+	if err := manager.RegisterIntegration(memory); err != nil {
+		t.Fatal(err)
+	}
+	if err := memory.SendData(context.Background(), &IntegrationData{ID: "a", Content: map[string]interface{}{"email": "a@example.com", "plan": "pro"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	query := &DataQuery{Fields: []string{"email", "plan"}, LegalBasis: "Article 6(1)(f)", Justification: "support"}
+	records, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	if data := records[0]; data.Content["email"] != "pseudo:email" || data.Content["plan"] != "pro" {
+		t.Errorf("unexpected retrieved content %v", data.Content)
+	}
+	if stored, _ := memory.Record("a"); stored.Content["email"] != "a@example.com" {
+		t.Errorf("retrieval modified the stored record: %v", stored.Content)
+	}
+}
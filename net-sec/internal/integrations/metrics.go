@@ -0,0 +1,161 @@
+package integrations
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsHistorySize is the number of metrics snapshots kept when
+// IntegrationConfig.MetricsHistorySize is unset
+const DefaultMetricsHistorySize = 288
+
+// MetricsSnapshot is a point-in-time view of the metrics across all
+// integrations
+type MetricsSnapshot struct {
+	Timestamp    time.Time          `json:"timestamp"`
+	Total        IntegrationMetrics `json:"total"`
+	SuccessRates map[string]float64 `json:"success_rates"`
+}
+
+// metricsHistory is a bounded ring buffer of metrics snapshots
+type metricsHistory struct {
+	snapshots []MetricsSnapshot
+	next      int
+	full      bool
+	mutex     sync.Mutex
+}
+
+func newMetricsHistory(size int) *metricsHistory {
+	if size <= 0 {
+		size = DefaultMetricsHistorySize
+	}
+	return &metricsHistory{snapshots: make([]MetricsSnapshot, size)}
+}
+
+// add stores a snapshot, overwriting the oldest once the buffer is full
+func (h *metricsHistory) add(snapshot MetricsSnapshot) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.snapshots[h.next] = snapshot
+	h.next = (h.next + 1) % len(h.snapshots)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the stored snapshots, oldest first
+func (h *metricsHistory) list() []MetricsSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.full {
+		return append([]MetricsSnapshot(nil), h.snapshots[:h.next]...)
+	}
+	return append(append([]MetricsSnapshot(nil), h.snapshots[h.next:]...), h.snapshots[:h.next]...)
+}
+
+// AggregateMetrics returns the metrics of all integrations combined.
+// Counters are summed, AverageResponseTime is weighted by request count and
+// LastRequestTime is the most recent request across integrations.
+func (im *IntegrationManager) AggregateMetrics() *IntegrationMetrics {
+	im.mutex.RLock()
+	defer im.mutex.RUnlock()
+
+	return im.aggregateMetrics()
+}
+
+// SuccessRates returns the fraction of successful requests per integration.
+// Integrations without requests have a rate of 0.
+func (im *IntegrationManager) SuccessRates() map[string]float64 {
+	im.mutex.RLock()
+	defer im.mutex.RUnlock()
+
+	return im.successRates()
+}
+
+// RecordMetricsSnapshot adds a snapshot of the current metrics to the
+// history
+func (im *IntegrationManager) RecordMetricsSnapshot() MetricsSnapshot {
+	im.mutex.RLock()
+	snapshot := MetricsSnapshot{
+		Timestamp:    time.Now(),
+		Total:        *im.aggregateMetrics(),
+		SuccessRates: im.successRates(),
+	}
+	im.mutex.RUnlock()
+
+	im.metricsHistory.add(snapshot)
+	return snapshot
+}
+
+// MetricsHistory returns the recorded metrics snapshots, oldest first
+func (im *IntegrationManager) MetricsHistory() []MetricsSnapshot {
+	return im.metricsHistory.list()
+}
+
+// StartMetricsSnapshots records a metrics snapshot every interval until ctx
+// is cancelled
+func (im *IntegrationManager) StartMetricsSnapshots(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				im.RecordMetricsSnapshot()
+			}
+		}
+	}()
+}
+
+// aggregateMetrics sums the integrations' metrics; the caller holds mutex
+func (im *IntegrationManager) aggregateMetrics() *IntegrationMetrics {
+	total := &IntegrationMetrics{}
+	var weightedResponseTime time.Duration
+
+	for _, integration := range im.integrations {
+		metrics := integration.GetMetrics()
+		if metrics == nil {
+			continue
+		}
+
+		total.TotalRequests += metrics.TotalRequests
+		total.SuccessfulRequests += metrics.SuccessfulRequests
+		total.FailedRequests += metrics.FailedRequests
+		total.DataSent += metrics.DataSent
+		total.DataReceived += metrics.DataReceived
+		total.PersonalDataFields += metrics.PersonalDataFields
+		total.PseudonymizedFields += metrics.PseudonymizedFields
+		total.MinimizedFields += metrics.MinimizedFields
+		weightedResponseTime += metrics.AverageResponseTime * time.Duration(metrics.TotalRequests)
+
+		if metrics.LastRequestTime.After(total.LastRequestTime) {
+			total.LastRequestTime = metrics.LastRequestTime
+		}
+	}
+
+	if total.TotalRequests > 0 {
+		total.AverageResponseTime = weightedResponseTime / time.Duration(total.TotalRequests)
+	}
+	return total
+}
+
+// successRates computes per-integration success rates; the caller holds
+// mutex
+func (im *IntegrationManager) successRates() map[string]float64 {
+	rates := make(map[string]float64, len(im.integrations))
+	for name, integration := range im.integrations {
+		metrics := integration.GetMetrics()
+		if metrics == nil || metrics.TotalRequests == 0 {
+			rates[name] = 0
+			continue
+		}
+		rates[name] = float64(metrics.SuccessfulRequests) / float64(metrics.TotalRequests)
+	}
+	return rates
+}
@@ -0,0 +1,114 @@
+package integrations
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAggregateMetrics(t *testing.T) {
+	last := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	crm := &mockIntegration{name: "crm", metrics: IntegrationMetrics{
+		TotalRequests:       10,
+		SuccessfulRequests:  9,
+		FailedRequests:      1,
+		AverageResponseTime: 100 * time.Millisecond,
+		LastRequestTime:     last.Add(-time.Hour),
+		DataSent:            1000,
+		DataReceived:        2000,
+		PersonalDataFields:  4,
+	}}
+	helpdesk := &mockIntegration{name: "helpdesk", metrics: IntegrationMetrics{
+		TotalRequests:       30,
+		SuccessfulRequests:  15,
+		FailedRequests:      15,
+		AverageResponseTime: 300 * time.Millisecond,
+		LastRequestTime:     last,
+		DataSent:            500,
+		PseudonymizedFields: 6,
+		MinimizedFields:     2,
+	}}
+	idle := &mockIntegration{name: "idle"}
+
+	manager := NewIntegrationManager(&IntegrationConfig{}, nil, nil)
+	for _, integration := range []Integration{crm, helpdesk, idle} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	total := manager.AggregateMetrics()
+	want := IntegrationMetrics{
+		TotalRequests:       40,
+		SuccessfulRequests:  24,
+		FailedRequests:      16,
+		AverageResponseTime: 250 * time.Millisecond,
+		LastRequestTime:     last,
+		DataSent:            1500,
+		DataReceived:        2000,
+		PersonalDataFields:  4,
+		PseudonymizedFields: 6,
+		MinimizedFields:     2,
+	}
+	if *total != want {
+		t.Errorf("expected aggregate %+v, got %+v", want, *total)
+	}
+
+	rates := manager.SuccessRates()
+	if len(rates) != 3 || rates["crm"] != 0.9 || rates["helpdesk"] != 0.5 || rates["idle"] != 0 {
+		t.Errorf("unexpected success rates %v", rates)
+	}
+}
+
+func TestMetricsHistoryIsBounded(t *testing.T) {
+	crm := &mockIntegration{name: "crm"}
+	manager := NewIntegrationManager(&IntegrationConfig{MetricsHistorySize: 3}, nil, nil)
+	if err := manager.RegisterIntegration(crm); err != nil {
+		t.Fatal(err)
+	}
+
+	if history := manager.MetricsHistory(); len(history) != 0 {
+		t.Fatalf("expected empty history, got %v", history)
+	}
+
+	for i := 1; i <= 5; i++ {
+		crm.metrics.TotalRequests = int64(i)
+		manager.RecordMetricsSnapshot()
+	}
+
+	history := manager.MetricsHistory()
+	if len(history) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(history))
+	}
+	for i, snapshot := range history {
+		if want := int64(i + 3); snapshot.Total.TotalRequests != want {
+			t.Errorf("snapshot %d: expected %d requests, got %d", i, want, snapshot.Total.TotalRequests)
+		}
+	}
+}
+
+func TestAggregateMetricsConcurrentWithRegistration(t *testing.T) {
+	manager := NewIntegrationManager(&IntegrationConfig{}, nil, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			manager.RegisterIntegration(&mockIntegration{
+				name:    fmt.Sprintf("integration-%d", i),
+				metrics: IntegrationMetrics{TotalRequests: 1, SuccessfulRequests: 1},
+			})
+		}(i)
+		go func() {
+			defer wg.Done()
+			manager.RecordMetricsSnapshot()
+		}()
+	}
+	wg.Wait()
+
+	if total := manager.AggregateMetrics(); total.TotalRequests != 10 || total.SuccessfulRequests != 10 {
+		t.Errorf("unexpected aggregate %+v", total)
+	}
+}
@@ -0,0 +1,55 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNotionRetrieveDataValidatesDatabaseID(t *testing.T) {
+	var requests atomic.Int32
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		path = r.URL.Path
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+	}))
+	defer server.Close()
+
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+
+	for name, filters := range map[string]map[string]interface{}{
+		"missing":   {},
+		"empty":     {"database_id": ""},
+		"traversal": {"database_id": "../users/me"},
+		"query":     {"database_id": "1a2b3c4d5e6f4a8b9c0d1e2f3a4b5c6d?filter=all"},
+		"short":     {"database_id": "1a2b3c4d"},
+		"not hex":   {"database_id": "zzzzzzzz-5e6f-4a8b-9c0d-1e2f3a4b5c6d"},
+		"not text":  {"database_id": 12345},
+	} {
+		query := &DataQuery{Filters: filters}
+		if _, err := notion.RetrieveData(context.Background(), query); !errors.Is(err, ErrInvalidDatabaseID) {
+			t.Errorf("%s: RetrieveData: expected ErrInvalidDatabaseID, got %v", name, err)
+		}
+		if _, err := notion.RetrieveDataPaged(context.Background(), query); !errors.Is(err, ErrInvalidDatabaseID) {
+			t.Errorf("%s: RetrieveDataPaged: expected ErrInvalidDatabaseID, got %v", name, err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("expected invalid database IDs to issue no requests, got %d", n)
+	}
+
+	for _, id := range []string{"1a2b3c4d-5e6f-4a8b-9c0d-1e2f3a4b5c6d", "1A2B3C4D5E6F4A8B9C0D1E2F3A4B5C6D"} {
+		if _, err := notion.RetrieveData(context.Background(), &DataQuery{Filters: map[string]interface{}{"database_id": id}}); err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if want := "/databases/" + id + "/query"; path != want {
+			t.Errorf("expected request to %s, got %s", want, path)
+		}
+	}
+}
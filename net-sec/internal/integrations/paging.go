@@ -0,0 +1,222 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPageSize is the number of records requested per page when a
+	// paged query has no limit
+	defaultPageSize = 50
+	// maxPageSize is the most records Notion and Jira return per page
+	maxPageSize = 100
+)
+
+// pageSize returns the number of records to request in the next page of
+// query once fetched records have been retrieved: the rest of query.Limit,
+// up to maxPageSize
+func pageSize(query *DataQuery, fetched int) int {
+	if query.Limit <= 0 {
+		return defaultPageSize
+	}
+	if remaining := query.Limit - fetched; remaining < maxPageSize {
+		return remaining
+	}
+	return maxPageSize
+}
+
+// limitRecords truncates records to query.Limit, reporting whether the limit
+// has been reached
+func limitRecords(query *DataQuery, records []*IntegrationData) ([]*IntegrationData, bool) {
+	if query.Limit <= 0 || len(records) < query.Limit {
+		return records, false
+	}
+	return records[:query.Limit], true
+}
+
+// contextError returns ctx.Err() when a request failed because ctx was
+// cancelled, and err otherwise
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// RetrieveDataPaged returns the issues matching query, starting at
+// query.Offset and following startAt until query.Limit issues have been
+// retrieved, or every issue when Limit is 0. Each page waits for the rate
+// limiter; when the context is cancelled no records are returned and the
+// error is ctx.Err().
+func (j *JiraIntegration) RetrieveDataPaged(ctx context.Context, query *DataQuery) ([]*IntegrationData, error) {
+	records := make([]*IntegrationData, 0)
+
+	for startAt := query.Offset; ; {
+		if err := j.rateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		page, total, err := j.retrieveIssuePage(ctx, query, startAt, pageSize(query, len(records)))
+		if err != nil {
+			return nil, err
+		}
+
+		var done bool
+		records, done = limitRecords(query, append(records, page...))
+		startAt += len(page)
+		if done || len(page) == 0 || startAt >= total {
+			return records, nil
+		}
+	}
+}
+
+// retrieveIssuePage fetches a page of up to size issues, returning them with
+// the total number of matching issues
+func (j *JiraIntegration) retrieveIssuePage(ctx context.Context, query *DataQuery, startAt, size int) ([]*IntegrationData, int, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	start := time.Now()
+
+	endpoint, err := j.searchEndpoint(query, startAt, size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	j.setJiraHeaders(req)
+
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		j.updateJiraMetrics(false, time.Since(start), 0, 0)
+		return nil, 0, contextError(ctx, fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		j.updateJiraMetrics(false, time.Since(start), 0, resp.ContentLength)
+		return nil, 0, newAPIError(j.Name(), resp)
+	}
+
+	var searchResp struct {
+		Issues []map[string]interface{} `json:"issues"`
+		Total  int                      `json:"total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		j.updateJiraMetrics(false, time.Since(start), 0, resp.ContentLength)
+		return nil, 0, contextError(ctx, fmt.Errorf("failed to decode response: %w", err))
+	}
+
+	j.updateJiraMetrics(true, time.Since(start), 0, resp.ContentLength)
+
+	page := make([]*IntegrationData, 0, len(searchResp.Issues))
+	for _, issue := range searchResp.Issues {
+		page = append(page, j.convertFromJiraFormat(issue, query))
+	}
+
+	return page, searchResp.Total, nil
+}
+
+// RetrieveDataPaged returns the pages of the database in
+// query.Filters["database_id"], following Notion's cursors until query.Limit
+// pages have been retrieved, or every page when Limit is 0. Each request
+// waits for the rate limiter; when the context is cancelled no records are
+// returned and the error is ctx.Err().
+func (n *NotionIntegration) RetrieveDataPaged(ctx context.Context, query *DataQuery) ([]*IntegrationData, error) {
+	endpoint, err := n.databaseQueryEndpoint(query)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*IntegrationData, 0)
+
+	for cursor := ""; ; {
+		if err := n.rateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		page, next, err := n.retrieveDatabasePage(ctx, endpoint, cursor, pageSize(query, len(records)), query)
+		if err != nil {
+			return nil, err
+		}
+
+		var done bool
+		records, done = limitRecords(query, append(records, page...))
+		if done || next == "" {
+			return records, nil
+		}
+		cursor = next
+	}
+}
+
+// retrieveDatabasePage fetches a page of up to size database results from
+// endpoint starting at cursor, returning them with the cursor of the next
+// page, or "" when there are no more results
+func (n *NotionIntegration) retrieveDatabasePage(ctx context.Context, endpoint, cursor string, size int, query *DataQuery) ([]*IntegrationData, string, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	start := time.Now()
+
+	queryBody := map[string]interface{}{
+		"page_size": size,
+	}
+	if cursor != "" {
+		queryBody["start_cursor"] = cursor
+	}
+
+	reqBody, err := json.Marshal(queryBody)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(string(reqBody)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	n.setNotionHeaders(req)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		n.updateMetrics(false, time.Since(start), len(reqBody), 0)
+		return nil, "", contextError(ctx, fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		n.updateMetrics(false, time.Since(start), len(reqBody), resp.ContentLength)
+		return nil, "", newAPIError(n.Name(), resp)
+	}
+
+	var queryResp struct {
+		Results    []map[string]interface{} `json:"results"`
+		HasMore    bool                     `json:"has_more"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&queryResp); err != nil {
+		n.updateMetrics(false, time.Since(start), len(reqBody), resp.ContentLength)
+		return nil, "", contextError(ctx, fmt.Errorf("failed to decode response: %w", err))
+	}
+
+	n.updateMetrics(true, time.Since(start), len(reqBody), resp.ContentLength)
+
+	page := make([]*IntegrationData, 0, len(queryResp.Results))
+	for _, result := range queryResp.Results {
+		page = append(page, n.convertFromNotionFormat(result, query))
+	}
+
+	if !queryResp.HasMore {
+		return page, "", nil
+	}
+	return page, queryResp.NextCursor, nil
+}
@@ -0,0 +1,264 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJiraRetrieveDataPaged(t *testing.T) {
+	var maxResults []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+		maxResults = append(maxResults, size)
+
+		// Jira may return fewer issues than asked for
+		issues := make([]map[string]interface{}, 0)
+		for i := startAt; i < startAt+size && i < startAt+2 && i < 5; i++ {
+			issues = append(issues, map[string]interface{}{
+				"id":     fmt.Sprint(10000 + i),
+				"fields": map[string]interface{}{"summary": fmt.Sprintf("issue %d", i)},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"issues": issues, "total": 5})
+	}))
+	defer server.Close()
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	records, err := jira.RetrieveDataPaged(context.Background(), &DataQuery{Filters: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("expected 5 records, got %d", len(records))
+	}
+	for i, record := range records {
+		if want := fmt.Sprintf("jira_%d", 10000+i); record.ID != want || record.Content["summary"] != fmt.Sprintf("issue %d", i) {
+			t.Errorf("record %d: unexpected %s %v", i, record.ID, record.Content)
+		}
+	}
+
+	maxResults = nil
+	records, err = jira.RetrieveDataPaged(context.Background(), &DataQuery{Limit: 3, Filters: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected the limit of 3 records, got %d", len(records))
+	}
+	if fmt.Sprint(maxResults) != "[3 1]" {
+		t.Errorf("expected pages to ask for the rest of the limit, got maxResults %v", maxResults)
+	}
+}
+
+func TestJiraRetrieveDataPagedStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		if requests.Add(1) == 2 {
+			cancel()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issues": []map[string]interface{}{{"id": fmt.Sprint(startAt), "fields": map[string]interface{}{}}},
+			"total":  100,
+		})
+	}))
+	defer server.Close()
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	records, err := jira.RetrieveDataPaged(ctx, &DataQuery{Filters: map[string]interface{}{}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if records != nil {
+		t.Errorf("expected no partial results, got %d records", len(records))
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected pagination to stop after 2 requests, got %d", n)
+	}
+}
+
+func TestNotionRetrieveDataPagedStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	var cursors []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		cursors = append(cursors, body["start_cursor"])
+
+		n := requests.Add(1)
+		if n == 3 {
+			cancel()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results":     []map[string]interface{}{{"id": fmt.Sprint(n), "properties": map[string]interface{}{}}},
+			"has_more":    true,
+			"next_cursor": fmt.Sprintf("cursor-%d", n),
+		})
+	}))
+	defer server.Close()
+
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+	records, err := notion.RetrieveDataPaged(ctx, &DataQuery{Filters: map[string]interface{}{"database_id": "1a2b3c4d-5e6f-4a8b-9c0d-1e2f3a4b5c6d"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if records != nil {
+		t.Errorf("expected no partial results, got %d records", len(records))
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected pagination to stop after 3 requests, got %d", n)
+	}
+	if fmt.Sprint(cursors) != "[<nil> cursor-1 cursor-2]" {
+		t.Errorf("unexpected cursors %v", cursors)
+	}
+}
+
+func TestSendDataRejectsCancelledContext(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+	for _, integration := range []Integration{jira, notion} {
+		err := integration.SendData(ctx, &IntegrationData{Content: map[string]interface{}{}, Metadata: map[string]interface{}{}})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", integration.Name(), err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
+
+func TestNotionRetrieveDataPagedStopsAtLimit(t *testing.T) {
+	var pageSizes []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		pageSizes = append(pageSizes, body["page_size"])
+
+		results := make([]map[string]interface{}, 0)
+		for i := 0; i < 2; i++ {
+			results = append(results, map[string]interface{}{"id": fmt.Sprint(len(pageSizes), i), "properties": map[string]interface{}{}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results":     results,
+			"has_more":    true,
+			"next_cursor": fmt.Sprintf("cursor-%d", len(pageSizes)),
+		})
+	}))
+	defer server.Close()
+
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+	records, err := notion.RetrieveDataPaged(context.Background(), &DataQuery{
+		Limit:   5,
+		Filters: map[string]interface{}{"database_id": "1a2b3c4d-5e6f-4a8b-9c0d-1e2f3a4b5c6d"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("expected the limit of 5 records, got %d", len(records))
+	}
+	if fmt.Sprint(pageSizes) != "[5 3 1]" {
+		t.Errorf("expected pages to ask for the rest of the limit, got page sizes %v", pageSizes)
+	}
+}
+
+func TestRetrieveDataWithCompliancePagesThroughRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		issues := make([]map[string]interface{}, 0)
+		for i := startAt; i < startAt+2 && i < 5; i++ {
+			issues = append(issues, map[string]interface{}{
+				"id":     fmt.Sprint(10000 + i),
+				"fields": map[string]interface{}{"summary": fmt.Sprintf("issue %d", i)},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"issues": issues, "total": 5})
+	}))
+	defer server.Close()
+
+	auditLog := &recordingAuditLog{}
+	manager := NewIntegrationManager(&IntegrationConfig{}, auditLog, nil)
+	if err := manager.RegisterIntegration(NewJiraIntegration("user", "token", server.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	query := &DataQuery{Limit: 4, Filters: map[string]interface{}{}, LegalBasis: "contract", Justification: "support"}
+	records, err := manager.RetrieveDataWithCompliance(context.Background(), "jira", query, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected the limit of 4 records, got %d", len(records))
+	}
+	for i, record := range records {
+		if want := fmt.Sprintf("jira_%d", 10000+i); record.ID != want {
+			t.Errorf("record %d: expected %s, got %s", i, want, record.ID)
+		}
+	}
+	if event := lastEvent(t, auditLog, "retrieve"); event.RecordsCount != 4 {
+		t.Errorf("expected 4 records logged, got %d", event.RecordsCount)
+	}
+}
+
+func TestRetrieveDataPagedWaitsForRateLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issues": []map[string]interface{}{{"id": fmt.Sprint(startAt), "fields": map[string]interface{}{}}},
+			"total":  3,
+		})
+	}))
+	defer server.Close()
+
+	jira := NewJiraIntegration("user", "token", server.URL)
+	jira.rateLimiter = NewRateLimiter(1, 50)
+
+	// A drained limiter delays later pages instead of failing the retrieval
+	records, err := jira.RetrieveDataPaged(context.Background(), &DataQuery{Filters: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("RetrieveDataPaged: %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("expected 3 records, got %d", len(records))
+	}
+}
+
+func TestRateLimiterWaitStopsWhenCancelled(t *testing.T) {
+	limiter := NewRateLimiter(1, 0)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
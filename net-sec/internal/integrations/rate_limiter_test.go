@@ -0,0 +1,135 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefillsSteadily(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(100, 3)
+	limiter.now = func() time.Time { return now }
+	limiter.lastRefill = now
+
+	for i := 0; i < 100; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("request %d: expected the burst to be allowed", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Fatal("expected a drained limiter to reject requests")
+	}
+
+	// Sub-second calls accumulate credit; 334ms is just over a token at 3/s
+	for i := 0; i < 3; i++ {
+		now = now.Add(334 * time.Millisecond)
+		if got := limiter.Tokens(); got != i+1 {
+			t.Errorf("after %d thirds of a second: expected %d tokens, got %d", i+1, i+1, got)
+		}
+	}
+
+	for second := 0; second < 5; second++ {
+		allowed := 0
+		for limiter.Allow() {
+			allowed++
+		}
+		if second > 0 && allowed != 3 {
+			t.Errorf("second %d: expected 3 requests, got %d", second, allowed)
+		}
+		now = now.Add(time.Second)
+	}
+
+	now = now.Add(time.Hour)
+	if got := limiter.Tokens(); got != 100 {
+		t.Errorf("expected the limiter to refill to capacity, got %d", got)
+	}
+}
+
+func TestRegisterIntegrationAppliesConfiguredRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	manager := NewIntegrationManager(&IntegrationConfig{
+		RateLimits: map[string]RateLimit{"notion": {RequestsPerMinute: 60}},
+	}, nil, nil)
+	notion := NewNotionIntegration("token")
+	notion.baseURL = server.URL
+	if err := manager.RegisterIntegration(notion); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notion.rateLimiter.now = func() time.Time { return now }
+	notion.rateLimiter.lastRefill = now
+
+	// 60 a minute allows one call a second; the default would allow 100 at once
+	data := &IntegrationData{Content: map[string]interface{}{}}
+	for second := 0; second < 10; second++ {
+		for i := 0; i < 5; i++ {
+			notion.SendData(context.Background(), data)
+		}
+		now = now.Add(time.Second)
+	}
+	if n := requests.Load(); n != 10 {
+		t.Errorf("expected 10 requests in 10 seconds, got %d", n)
+	}
+}
+
+func TestSetRateLimitThrottlesAtRuntime(t *testing.T) {
+	manager := NewIntegrationManager(&IntegrationConfig{}, nil, nil)
+	jira := NewJiraIntegration("user", "token", "https://jira.example.com")
+	if err := manager.RegisterIntegration(jira); err != nil {
+		t.Fatal(err)
+	}
+	if got := jira.rateLimiter.Tokens(); got != 100 {
+		t.Fatalf("expected the default burst of 100, got %d", got)
+	}
+
+	if err := manager.SetRateLimit("jira", RateLimit{RequestsPerMinute: 30, BurstSize: 2}); err != nil {
+		t.Fatalf("SetRateLimit: %v", err)
+	}
+	if got := jira.rateLimiter.Tokens(); got != 2 {
+		t.Errorf("expected the burst to shrink to 2, got %d", got)
+	}
+	if limit := manager.config.RateLimits["jira"]; limit.RequestsPerMinute != 30 {
+		t.Errorf("expected the limit to be recorded in the config, got %+v", limit)
+	}
+
+	if err := manager.SetRateLimit("jira", RateLimit{}); !errors.Is(err, ErrInvalidRateLimit) {
+		t.Errorf("expected ErrInvalidRateLimit, got %v", err)
+	}
+	if err := manager.SetRateLimit("memory", RateLimit{RequestsPerMinute: 60}); err == nil {
+		t.Error("expected an error for an unknown integration")
+	}
+}
+
+func TestRateLimiterSetLimitAllowsFractionalRates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(100, 3)
+	limiter.now = func() time.Time { return now }
+	limiter.lastRefill = now
+
+	if err := limiter.SetLimit(RateLimit{RequestsPerMinute: 30}); err != nil {
+		t.Fatal(err)
+	}
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("expected a burst of one request")
+	}
+
+	now = now.Add(time.Second)
+	if limiter.Allow() {
+		t.Error("expected 30 a minute to allow no request after one second")
+	}
+	now = now.Add(time.Second)
+	if !limiter.Allow() {
+		t.Error("expected 30 a minute to allow a request after two seconds")
+	}
+}
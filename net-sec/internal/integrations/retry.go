@@ -0,0 +1,99 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Retry backoff bounds for IntegrationConfig.RetryBackoff
+const (
+	DefaultRetryBackoff = 500 * time.Millisecond
+	MaxRetryBackoff     = 30 * time.Second
+)
+
+// TransientError is implemented by errors that say whether retrying the
+// failed request may succeed
+type TransientError interface {
+	Transient() bool
+}
+
+// isTransient reports whether a request that failed with err is worth
+// retrying: rate limit rejections, transient errors, timeouts and broken
+// connections. TLS and authentication failures are not.
+func isTransient(err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+
+	var transient TransientError
+	if errors.As(err, &transient) {
+		return transient.Transient()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry calls op until it succeeds, fails with an error that is not
+// transient, or has been retried RetryAttempts times, and returns the number
+// of attempts made. Retries wait an exponentially growing, jittered
+// backoff, or longer when an APIError asks for it with Retry-After; if ctx
+// is cancelled while waiting, ctx.Err() is returned.
+func (im *IntegrationManager) withRetry(ctx context.Context, op func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt > im.config.RetryAttempts || !isTransient(err) {
+			return attempt, err
+		}
+
+		delay := im.retryDelay(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+
+		if err := im.sleep(ctx, delay); err != nil {
+			return attempt, err
+		}
+	}
+}
+
+// retryDelay returns the wait before retry number attempt: RetryBackoff
+// doubled for each earlier retry, capped at MaxRetryBackoff, with the upper
+// half randomized so clients that failed together do not retry together
+func (im *IntegrationManager) retryDelay(attempt int) time.Duration {
+	delay := im.config.RetryBackoff
+	if delay <= 0 {
+		delay = DefaultRetryBackoff
+	}
+	for i := 1; i < attempt && delay < MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxRetryBackoff {
+		delay = MaxRetryBackoff
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleepContext waits for d, returning ctx.Err() early if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,166 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// flakyIntegration fails its first sends and retrievals with the errors in
+// failures
+type flakyIntegration struct {
+	mockIntegration
+	failures []error
+	calls    int
+}
+
+func (f *flakyIntegration) next() error {
+	f.calls++
+	if f.calls <= len(f.failures) {
+		return f.failures[f.calls-1]
+	}
+	return nil
+}
+
+func (f *flakyIntegration) SendData(ctx context.Context, data *IntegrationData) error {
+	return f.next()
+}
+
+func (f *flakyIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
+	if err := f.next(); err != nil {
+		return nil, err
+	}
+	return f.retrieved, nil
+}
+
+// newRetryManager returns a manager for integration that records its retry
+// delays instead of sleeping
+func newRetryManager(t *testing.T, integration Integration, attempts int, auditLog AuditLogger) (*IntegrationManager, *[]time.Duration) {
+	t.Helper()
+
+	manager := NewIntegrationManager(&IntegrationConfig{RetryAttempts: attempts, RetryBackoff: 100 * time.Millisecond}, auditLog, nil)
+	if err := manager.RegisterIntegration(integration); err != nil {
+		t.Fatal(err)
+	}
+
+	delays := make([]time.Duration, 0)
+	manager.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return manager, &delays
+}
+
+// lastEvent returns the last event auditLog recorded for operation
+func lastEvent(t *testing.T, auditLog *recordingAuditLog, operation string) IntegrationAuditEvent {
+	t.Helper()
+
+	for i := len(auditLog.events) - 1; i >= 0; i-- {
+		if auditLog.events[i].Operation == operation {
+			return auditLog.events[i]
+		}
+	}
+	t.Fatalf("no %s event logged", operation)
+	return IntegrationAuditEvent{}
+}
+
+func TestSendDataRetriesTransientErrors(t *testing.T) {
+	timeout := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	crm := &flakyIntegration{
+		mockIntegration: mockIntegration{name: "crm"},
+		failures:        []error{ErrRateLimited, timeout},
+	}
+	auditLog := &recordingAuditLog{}
+	manager, delays := newRetryManager(t, crm, 3, auditLog)
+
+	data := &IntegrationData{Type: "contact", Content: map[string]interface{}{}}
+	if err := manager.SendDataWithCompliance(context.Background(), "crm", data, "user1"); err != nil {
+		t.Fatalf("SendDataWithCompliance: %v", err)
+	}
+
+	if crm.calls != 3 {
+		t.Errorf("SendData called %d times, want 3", crm.calls)
+	}
+	if len(*delays) != 2 {
+		t.Fatalf("slept %d times, want 2", len(*delays))
+	}
+	first, second := (*delays)[0], (*delays)[1]
+	if first < 50*time.Millisecond || first > 100*time.Millisecond {
+		t.Errorf("first delay = %v, want 50ms-100ms", first)
+	}
+	if second < 100*time.Millisecond || second > 200*time.Millisecond {
+		t.Errorf("second delay = %v, want 100ms-200ms", second)
+	}
+
+	if got := lastEvent(t, auditLog, "send").Metadata["attempts"]; got != 3 {
+		t.Errorf("attempts = %v, want 3", got)
+	}
+}
+
+func TestSendDataDoesNotRetryPermanentErrors(t *testing.T) {
+	permanent := errors.New("API request failed with status 400")
+	crm := &flakyIntegration{
+		mockIntegration: mockIntegration{name: "crm"},
+		failures:        []error{permanent},
+	}
+	manager, delays := newRetryManager(t, crm, 3, nil)
+
+	data := &IntegrationData{Type: "contact", Content: map[string]interface{}{}}
+	if err := manager.SendDataWithCompliance(context.Background(), "crm", data, "user1"); !errors.Is(err, permanent) {
+		t.Fatalf("SendDataWithCompliance error = %v, want %v", err, permanent)
+	}
+	if crm.calls != 1 || len(*delays) != 0 {
+		t.Errorf("calls = %d, delays = %d; want 1 call and no retries", crm.calls, len(*delays))
+	}
+}
+
+func TestRetrieveDataStopsRetryingAfterAttempts(t *testing.T) {
+	crm := &flakyIntegration{
+		mockIntegration: mockIntegration{name: "crm"},
+		failures:        []error{ErrRateLimited, ErrRateLimited, ErrRateLimited},
+	}
+	auditLog := &recordingAuditLog{}
+	manager, _ := newRetryManager(t, crm, 2, auditLog)
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	if _, err := manager.RetrieveDataWithCompliance(context.Background(), "crm", query, "user1"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("RetrieveDataWithCompliance error = %v, want %v", err, ErrRateLimited)
+	}
+	if crm.calls != 3 {
+		t.Errorf("RetrieveData called %d times, want 3", crm.calls)
+	}
+	if got := lastEvent(t, auditLog, "retrieve").Metadata["attempts"]; got != 3 {
+		t.Errorf("attempts = %v, want 3", got)
+	}
+}
+
+func TestRetrieveDataStopsRetryingWhenCancelled(t *testing.T) {
+	crm := &flakyIntegration{
+		mockIntegration: mockIntegration{name: "crm"},
+		failures:        []error{ErrRateLimited, ErrRateLimited},
+	}
+	manager, _ := newRetryManager(t, crm, 5, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	if _, err := manager.RetrieveDataWithCompliance(ctx, "crm", query, "user1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RetrieveDataWithCompliance error = %v, want %v", err, context.Canceled)
+	}
+	if crm.calls != 1 {
+		t.Errorf("RetrieveData called %d times, want 1", crm.calls)
+	}
+}
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	manager := NewIntegrationManager(&IntegrationConfig{RetryBackoff: time.Second}, nil, nil)
+
+	for i := 0; i < 20; i++ {
+		if delay := manager.retryDelay(10); delay < MaxRetryBackoff/2 || delay > MaxRetryBackoff {
+			t.Fatalf("retryDelay(10) = %v, want %v-%v", delay, MaxRetryBackoff/2, MaxRetryBackoff)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ===== SLACK INTEGRATION =====
+
+const (
+	// slackRedacted replaces personal data in messages to non-public
+	// channels
+	slackRedacted = "[redacted]"
+	// slackMaxSectionFields is the number of fields Block Kit allows in a
+	// section
+	slackMaxSectionFields = 10
+	// slackMaxFieldText and slackMaxHeaderText are the Block Kit limits on
+	// section field and header text
+	slackMaxFieldText  = 2000
+	slackMaxHeaderText = 150
+)
+
+var (
+	// ErrUnsupportedOperation is returned by integrations for operations
+	// the external service cannot perform
+	ErrUnsupportedOperation = errors.New("operation not supported by integration")
+	// ErrSlackNoDestination is returned when a Slack integration has
+	// neither a webhook URL nor a token and channel to post to
+	ErrSlackNoDestination = errors.New("Slack integration needs a webhook URL, or an API token and channel")
+)
+
+// slackEscaper escapes the characters Slack treats as markup, so content
+// cannot mention @channel or inject links
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackError is returned when the Slack Web API answers a call with ok set
+// to false
+type SlackError struct {
+	Method string
+	Code   string // Slack's error code, such as invalid_auth
+}
+
+func (e *SlackError) Error() string {
+	return fmt.Sprintf("slack %s failed: %s", e.Method, e.Code)
+}
+
+// NewSlackIntegration creates a Slack integration that posts to channel
+// with chat.postMessage as the bot or user owning apiToken
+func NewSlackIntegration(apiToken, channel string) *SlackIntegration {
+	return &SlackIntegration{
+		apiToken:    apiToken,
+		channel:     channel,
+		baseURL:     "https://slack.com/api",
+		httpClient:  &http.Client{Timeout: DefaultRequestTimeout},
+		metrics:     &IntegrationMetrics{},
+		rateLimiter: NewRateLimiter(10, 1), // 1 message per second, burst of 10
+	}
+}
+
+// NewSlackWebhookIntegration creates a Slack integration that posts to an
+// incoming webhook
+func NewSlackWebhookIntegration(webhookURL string) *SlackIntegration {
+	s := NewSlackIntegration("", "")
+	s.webhookURL = webhookURL
+	return s
+}
+
+func (s *SlackIntegration) Name() string {
+	return "slack"
+}
+
+// SetHTTPClient replaces the integration's default HTTP client
+func (s *SlackIntegration) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.httpClient = client
+}
+
+// SetRateLimit replaces the integration's default rate limit
+func (s *SlackIntegration) SetRateLimit(limit RateLimit) error {
+	return s.rateLimiter.SetLimit(limit)
+}
+
+// Authenticate sets the API token, and the channel when given, and checks
+// the token with auth.test
+func (s *SlackIntegration) Authenticate(credentials map[string]string) error {
+	token, ok := credentials["api_token"]
+	if !ok {
+		return fmt.Errorf("api_token required for Slack authentication")
+	}
+
+	s.mutex.Lock()
+	s.apiToken = token
+	if channel, ok := credentials["channel"]; ok {
+		s.channel = channel
+	}
+	s.mutex.Unlock()
+
+	return s.ValidateConnection()
+}
+
+// SendData posts data.Content as a Block Kit message, to the webhook when
+// one is configured and otherwise to data.Metadata["channel"] or the
+// integration's channel. Fields listed in data.PersonalData are redacted
+// unless the data is classified public.
+func (s *SlackIntegration) SendData(ctx context.Context, data *IntegrationData) error {
+	if !s.rateLimiter.Allow() {
+		return ErrRateLimited
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	message := convertToSlackMessage(data)
+	if s.webhookURL != "" {
+		return s.post(ctx, s.webhookURL, message, nil)
+	}
+
+	channel := s.channel
+	if override, ok := data.Metadata["channel"].(string); ok && override != "" {
+		channel = override
+	}
+	if s.apiToken == "" || channel == "" {
+		return ErrSlackNoDestination
+	}
+
+	message["channel"] = channel
+	return s.call(ctx, "chat.postMessage", message)
+}
+
+// RetrieveData is not supported: messages are write-only notifications
+func (s *SlackIntegration) RetrieveData(ctx context.Context, query *DataQuery) (*IntegrationData, error) {
+	return nil, fmt.Errorf("slack: retrieving data: %w", ErrUnsupportedOperation)
+}
+
+// UpdateData is not supported: messages are write-only notifications
+func (s *SlackIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	return fmt.Errorf("slack: updating data: %w", ErrUnsupportedOperation)
+}
+
+// FindSubjectData is not supported: messages are write-only
+// notifications
+func (s *SlackIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*IntegrationData, error) {
+	return nil, fmt.Errorf("slack: finding data subject records: %w", ErrUnsupportedOperation)
+}
+
+// EraseSubjectData is not supported: messages are write-only
+// notifications
+func (s *SlackIntegration) EraseSubjectData(ctx context.Context, subjectID string) (int, error) {
+	return 0, fmt.Errorf("slack: erasing data subject records: %w", ErrUnsupportedOperation)
+}
+
+// ValidateConnection checks the API token with auth.test. An integration
+// with only a webhook is not checked, as a webhook cannot be called
+// without posting a message.
+func (s *SlackIntegration) ValidateConnection() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.apiToken == "" {
+		if s.webhookURL != "" {
+			return nil
+		}
+		return ErrSlackNoDestination
+	}
+
+	if err := s.call(context.Background(), "auth.test", map[string]interface{}{}); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	return nil
+}
+
+func (s *SlackIntegration) GetMetrics() *IntegrationMetrics {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	metricsCopy := *s.metrics
+	return &metricsCopy
+}
+
+// call invokes a Slack Web API method, returning a SlackError when Slack
+// rejects it; the caller holds mutex
+func (s *SlackIntegration) call(ctx context.Context, method string, payload map[string]interface{}) error {
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := s.post(ctx, fmt.Sprintf("%s/%s", s.baseURL, method), payload, func(resp *http.Response) error {
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if !result.OK {
+		return &SlackError{Method: method, Code: result.Error}
+	}
+	return nil
+}
+
+// post sends payload as JSON to endpoint, passing successful responses to
+// decode when it is set; the caller holds mutex
+func (s *SlackIntegration) post(ctx context.Context, endpoint string, payload map[string]interface{}, decode func(*http.Response) error) error {
+	start := time.Now()
+
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.apiToken != "" && endpoint != s.webhookURL {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiToken))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.updateSlackMetrics(false, time.Since(start), len(reqBody), 0)
+		return contextError(ctx, fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		s.updateSlackMetrics(false, time.Since(start), len(reqBody), resp.ContentLength)
+		return newAPIError(s.Name(), resp)
+	}
+
+	if decode != nil {
+		if err := decode(resp); err != nil {
+			s.updateSlackMetrics(false, time.Since(start), len(reqBody), resp.ContentLength)
+			return contextError(ctx, err)
+		}
+	}
+
+	s.updateSlackMetrics(true, time.Since(start), len(reqBody), resp.ContentLength)
+	return nil
+}
+
+// convertToSlackMessage formats data as a message payload: a header with
+// the title, sections listing the other content fields, and a context line
+// with the type and classification
+func convertToSlackMessage(data *IntegrationData) map[string]interface{} {
+	content := redactSlackContent(data)
+
+	title := data.Type + " record"
+	if value, ok := content["title"]; ok {
+		title = slackText(value)
+	}
+	title = truncateSlackText(title, slackMaxHeaderText)
+
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": title},
+		},
+	}
+
+	fields := make([]string, 0, len(content))
+	for field := range content {
+		if field != "title" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	for len(fields) > 0 {
+		n := len(fields)
+		if n > slackMaxSectionFields {
+			n = slackMaxSectionFields
+		}
+
+		sectionFields := make([]interface{}, 0, n)
+		for _, field := range fields[:n] {
+			text := fmt.Sprintf("*%s*\n%s", slackEscaper.Replace(field), slackEscaper.Replace(slackText(content[field])))
+			sectionFields = append(sectionFields, map[string]interface{}{
+				"type": "mrkdwn",
+				"text": truncateSlackText(text, slackMaxFieldText),
+			})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": sectionFields})
+		fields = fields[n:]
+	}
+
+	contextElements := make([]interface{}, 0, 2)
+	if data.Type != "" {
+		contextElements = append(contextElements, map[string]interface{}{"type": "mrkdwn", "text": "Type: " + slackEscaper.Replace(data.Type)})
+	}
+	if data.Classification != "" {
+		contextElements = append(contextElements, map[string]interface{}{"type": "mrkdwn", "text": "Classification: " + slackEscaper.Replace(data.Classification)})
+	}
+	if len(contextElements) > 0 {
+		blocks = append(blocks, map[string]interface{}{"type": "context", "elements": contextElements})
+	}
+
+	return map[string]interface{}{
+		"text":   slackEscaper.Replace(title), // Notification fallback, parsed as mrkdwn
+		"blocks": blocks,
+	}
+}
+
+// redactSlackContent returns a copy of data.Content with the fields listed
+// in data.PersonalData redacted, unless data is classified public
+func redactSlackContent(data *IntegrationData) map[string]interface{} {
+	content := make(map[string]interface{}, len(data.Content))
+	for field, value := range data.Content {
+		content[field] = value
+	}
+
+	if data.Classification == "public" {
+		return content
+	}
+	for _, personal := range data.PersonalData {
+		if _, ok := content[personal.Field]; ok {
+			content[personal.Field] = slackRedacted
+		}
+	}
+	return content
+}
+
+// slackText renders a content value as message text
+func slackText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// truncateSlackText shortens text to at most limit characters
+func truncateSlackText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// updateSlackMetrics records the outcome of a request; the caller holds
+// mutex
+func (s *SlackIntegration) updateSlackMetrics(success bool, duration time.Duration, bytesSent int, bytesReceived int64) {
+	s.metrics.TotalRequests++
+	if success {
+		s.metrics.SuccessfulRequests++
+	} else {
+		s.metrics.FailedRequests++
+	}
+
+	if s.metrics.TotalRequests == 1 {
+		s.metrics.AverageResponseTime = duration
+	} else {
+		s.metrics.AverageResponseTime = (s.metrics.AverageResponseTime*time.Duration(s.metrics.TotalRequests-1) + duration) / time.Duration(s.metrics.TotalRequests)
+	}
+
+	s.metrics.LastRequestTime = time.Now()
+	s.metrics.DataSent += int64(bytesSent)
+	s.metrics.DataReceived += bytesReceived
+}
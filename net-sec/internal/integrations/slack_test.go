@@ -0,0 +1,175 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// slackServer records the requests to a fake Slack API and answers method
+// calls with the responses in results, keyed by path
+func slackServer(t *testing.T, results map[string]map[string]interface{}) (*httptest.Server, *[]*http.Request, *[]map[string]interface{}) {
+	t.Helper()
+
+	requests := make([]*http.Request, 0)
+	payloads := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		requests = append(requests, r)
+		payloads = append(payloads, payload)
+
+		if result, ok := results[r.URL.Path]; ok {
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &payloads
+}
+
+func TestSlackSendDataPostsBlockKitMessage(t *testing.T) {
+	server, requests, payloads := slackServer(t, map[string]map[string]interface{}{
+		"/chat.postMessage": {"ok": true, "ts": "1700000000.000100"},
+	})
+	slack := NewSlackIntegration("xoxb-token", "#incidents")
+	slack.baseURL = server.URL
+
+	data := &IntegrationData{
+		Type:           "incident",
+		Classification: "internal",
+		Content: map[string]interface{}{
+			"title":    "Database <!channel> outage",
+			"severity": "high",
+			"reporter": "alice@example.com",
+			"hosts":    []string{"db1", "db2"},
+		},
+		PersonalData: []PersonalDataField{{Field: "reporter", DataCategory: "personal"}},
+	}
+	if err := slack.SendData(context.Background(), data); err != nil {
+		t.Fatalf("SendData: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(*requests))
+	}
+	if auth := (*requests)[0].Header.Get("Authorization"); auth != "Bearer xoxb-token" {
+		t.Errorf("unexpected Authorization header %q", auth)
+	}
+
+	payload := (*payloads)[0]
+	if payload["channel"] != "#incidents" || payload["text"] != "Database &lt;!channel&gt; outage" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+
+	encoded, _ := json.Marshal(payload["blocks"])
+	blocks := string(encoded)
+	if strings.Contains(blocks, "alice@example.com") {
+		t.Errorf("expected the reporter to be redacted, got %s", blocks)
+	}
+	for _, want := range []string{`"type":"header"`, `*reporter*\n[redacted]`, `*severity*\nhigh`, `*hosts*\n[\"db1\",\"db2\"]`, "Classification: internal"} {
+		if !strings.Contains(blocks, want) {
+			t.Errorf("expected blocks to contain %s, got %s", want, blocks)
+		}
+	}
+
+	metrics := slack.GetMetrics()
+	if metrics.TotalRequests != 1 || metrics.SuccessfulRequests != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestSlackSendDataKeepsPublicPersonalData(t *testing.T) {
+	server, _, payloads := slackServer(t, nil)
+	slack := NewSlackWebhookIntegration(server.URL + "/hook")
+
+	data := &IntegrationData{
+		Type:           "incident",
+		Classification: "public",
+		Content:        map[string]interface{}{"title": "Status page update", "contact": "support@example.com"},
+		PersonalData:   []PersonalDataField{{Field: "contact", DataCategory: "personal"}},
+	}
+	if err := slack.SendData(context.Background(), data); err != nil {
+		t.Fatalf("SendData: %v", err)
+	}
+
+	encoded, _ := json.Marshal((*payloads)[0]["blocks"])
+	if !strings.Contains(string(encoded), "support@example.com") {
+		t.Errorf("expected public data to be posted unredacted, got %s", encoded)
+	}
+	if _, ok := (*payloads)[0]["channel"]; ok {
+		t.Errorf("expected webhook payloads to have no channel")
+	}
+}
+
+func TestSlackSendDataSplitsSections(t *testing.T) {
+	content := map[string]interface{}{}
+	for _, field := range strings.Split("a b c d e f g h i j k l", " ") {
+		content[field] = field
+	}
+
+	message := convertToSlackMessage(&IntegrationData{Type: "incident", Content: content})
+	blocks := message["blocks"].([]interface{})
+	if len(blocks) != 4 {
+		t.Fatalf("expected header, two sections and context, got %d blocks", len(blocks))
+	}
+	if n := len(blocks[1].(map[string]interface{})["fields"].([]interface{})); n != slackMaxSectionFields {
+		t.Errorf("expected %d fields in the first section, got %d", slackMaxSectionFields, n)
+	}
+	if message["text"] != "incident record" {
+		t.Errorf("unexpected fallback text %v", message["text"])
+	}
+}
+
+func TestSlackSendDataHonorsRateLimiter(t *testing.T) {
+	server, requests, _ := slackServer(t, nil)
+	slack := NewSlackWebhookIntegration(server.URL)
+	slack.rateLimiter = NewRateLimiter(1, 0)
+
+	data := &IntegrationData{Type: "incident", Content: map[string]interface{}{"title": "outage"}}
+	if err := slack.SendData(context.Background(), data); err != nil {
+		t.Fatalf("SendData: %v", err)
+	}
+	if err := slack.SendData(context.Background(), data); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(*requests))
+	}
+}
+
+func TestSlackAuthenticate(t *testing.T) {
+	server, requests, _ := slackServer(t, map[string]map[string]interface{}{
+		"/auth.test": {"ok": false, "error": "invalid_auth"},
+	})
+	slack := NewSlackIntegration("", "")
+	slack.baseURL = server.URL
+
+	err := slack.Authenticate(map[string]string{"api_token": "xoxb-bad", "channel": "#incidents"})
+	var slackErr *SlackError
+	if !errors.As(err, &slackErr) || slackErr.Code != "invalid_auth" {
+		t.Fatalf("expected invalid_auth, got %v", err)
+	}
+	if len(*requests) != 1 || (*requests)[0].URL.Path != "/auth.test" {
+		t.Errorf("expected a call to auth.test, got %v", *requests)
+	}
+
+	if err := slack.Authenticate(map[string]string{}); err == nil {
+		t.Error("expected an error without api_token")
+	}
+}
+
+func TestSlackRetrieveDataIsUnsupported(t *testing.T) {
+	slack := NewSlackIntegration("xoxb-token", "#incidents")
+
+	if _, err := slack.RetrieveData(context.Background(), &DataQuery{}); !errors.Is(err, ErrUnsupportedOperation) {
+		t.Errorf("expected ErrUnsupportedOperation, got %v", err)
+	}
+}
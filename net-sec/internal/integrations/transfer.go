@@ -0,0 +1,153 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrRestrictedTransferNeedsLegalBasis is returned when a transfer of
+	// data classified restricted has no legal basis
+	ErrRestrictedTransferNeedsLegalBasis = errors.New("transfer of restricted data requires a legal basis")
+	// ErrTransferNeedsPseudonymization is returned when a transfer to a less
+	// restrictive destination has personal data but no data minimizer to
+	// pseudonymize it with
+	ErrTransferNeedsPseudonymization = errors.New("transfer to a less restrictive destination requires pseudonymization")
+)
+
+// TransferData retrieves the records query selects from sourceName with
+// compliance, minimizes and pseudonymizes them for destName, and sends them
+// there. When destName's classification is pinned below the data's, the data
+// is minimized for query.Justification and its personal data is
+// pseudonymized even if the config does not ask for it. Transfers of
+// restricted data without a legal basis are rejected before any request is
+// made. The returned DataTransferEvent, which is also logged, describes the
+// transfer whether or not it succeeded.
+func (im *IntegrationManager) TransferData(ctx context.Context, sourceName, destName string, query *DataQuery, userID string) (*DataTransferEvent, error) {
+	event := &DataTransferEvent{
+		ID:                     generateEventID(),
+		Timestamp:              time.Now(),
+		SourceIntegration:      sourceName,
+		DestinationIntegration: destName,
+		DataType:               query.Type,
+		LegalBasis:             query.LegalBasis,
+		Purpose:                query.Justification,
+	}
+
+	err := im.transferData(ctx, sourceName, destName, query, userID, event)
+	event.Success = err == nil
+	event.Error = getErrorString(err)
+
+	if im.auditLog != nil {
+		im.auditLog.LogDataTransfer(*event)
+	}
+	return event, err
+}
+
+// transferData carries out TransferData, filling in the counts of event
+func (im *IntegrationManager) transferData(ctx context.Context, sourceName, destName string, query *DataQuery, userID string, event *DataTransferEvent) error {
+	im.mutex.RLock()
+	_, sourceExists := im.integrations[sourceName]
+	dest, destExists := im.integrations[destName]
+	im.mutex.RUnlock()
+
+	if !sourceExists {
+		return fmt.Errorf("integration %s not found", sourceName)
+	}
+	if !destExists {
+		return fmt.Errorf("integration %s not found", destName)
+	}
+
+	if query.LegalBasis == "" && (im.pinnedClassification(sourceName, query.Fields) == "restricted" || im.pinnedClassification(destName, nil) == "restricted") {
+		return ErrRestrictedTransferNeedsLegalBasis
+	}
+
+	records, err := im.RetrieveDataWithCompliance(ctx, sourceName, query, userID)
+	if err != nil {
+		return fmt.Errorf("retrieving from %s: %w", sourceName, err)
+	}
+
+	// Prepare every record before sending any, so a record that cannot be
+	// transferred does not leave a partial transfer behind
+	prepared := make([]*IntegrationData, 0, len(records))
+	for _, data := range records {
+		if data.Classification == "restricted" && query.LegalBasis == "" {
+			return ErrRestrictedTransferNeedsLegalBasis
+		}
+
+		record := copyIntegrationData(data)
+		if record.LegalBasis == "" {
+			record.LegalBasis = query.LegalBasis
+		}
+		if record.ProcessingPurpose == "" {
+			record.ProcessingPurpose = query.Justification
+		}
+
+		if err := im.prepareTransfer(destName, record, event); err != nil {
+			return err
+		}
+		prepared = append(prepared, record)
+	}
+
+	for _, record := range prepared {
+		if err := im.sendData(ctx, destName, dest, record, userID); err != nil {
+			return fmt.Errorf("sending to %s: %w", destName, err)
+		}
+		event.RecordsTransferred++
+	}
+	return nil
+}
+
+// prepareTransfer minimizes and pseudonymizes record, as retrieved with
+// compliance, for destName and adds the fields affected to the counts of
+// event
+func (im *IntegrationManager) prepareTransfer(destName string, record *IntegrationData, event *DataTransferEvent) error {
+	downgrade := false
+	if classification := im.pinnedClassification(destName, nil); classification != "" {
+		downgrade = classificationLevels[classification] < classificationLevels[record.Classification]
+	}
+
+	if im.dataMinimizer != nil && (im.config.DataMinimization || downgrade) {
+		minimized := im.dataMinimizer.MinimizeData(record.Content, record.ProcessingPurpose)
+		for field := range record.Content {
+			if _, kept := minimized[field]; !kept {
+				event.MinimizedFields++
+			}
+		}
+		record.Content = minimized
+
+		personalData := make([]PersonalDataField, 0, len(record.PersonalData))
+		for _, field := range record.PersonalData {
+			if _, kept := record.Content[field.Field]; kept {
+				personalData = append(personalData, field)
+			}
+		}
+		record.PersonalData = personalData
+	}
+	event.PersonalDataFields += len(record.PersonalData)
+
+	// Retrieval with compliance already pseudonymized personal data if the
+	// config asks for it
+	if im.config.PseudonymizeData && im.dataMinimizer != nil {
+		event.PseudonymizedFields += len(record.PersonalData)
+		return nil
+	}
+	if !downgrade || len(record.PersonalData) == 0 {
+		return nil
+	}
+	if im.dataMinimizer == nil {
+		return fmt.Errorf("%s is classified below %s data: %w", destName, record.Classification, ErrTransferNeedsPseudonymization)
+	}
+
+	fields := make([]string, 0, len(record.PersonalData))
+	for _, field := range record.PersonalData {
+		fields = append(fields, field.Field)
+	}
+	if err := im.dataMinimizer.PseudonymizeFields(record.Content, fields); err != nil {
+		return fmt.Errorf("pseudonymization failed: %w", err)
+	}
+	event.PseudonymizedFields += len(fields)
+	return nil
+}
@@ -0,0 +1,224 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// droppingMinimizer drops the fields in drop and pseudonymizes like
+// stubMinimizer
+type droppingMinimizer struct {
+	stubMinimizer
+	drop map[string]bool
+}
+
+func (d droppingMinimizer) MinimizeData(data map[string]interface{}, purpose string) map[string]interface{} {
+	minimized := make(map[string]interface{}, len(data))
+	for field, value := range data {
+		if !d.drop[field] {
+			minimized[field] = value
+		}
+	}
+	return minimized
+}
+
+// newTransferManager returns a manager with a CRM source holding one contact
+// and an empty helpdesk destination
+func newTransferManager(t *testing.T, config *IntegrationConfig, minimizer DataMinimizer) (*IntegrationManager, *MemoryIntegration, *recordingAuditLog) {
+	t.Helper()
+
+	crm := &flakyIntegration{mockIntegration: mockIntegration{name: "crm", retrieved: &IntegrationData{
+		ID:             "contact-1",
+		Type:           "contact",
+		Classification: "confidential",
+		Content:        map[string]interface{}{"email": "alice@example.com", "phone": "555-0100", "notes": "VIP", "plan": "pro"},
+		PersonalData: []PersonalDataField{
+			{Field: "email", DataCategory: "personal"},
+			{Field: "phone", DataCategory: "personal"},
+		},
+	}}}
+	helpdesk := NewMemoryIntegration("helpdesk")
+
+	auditLog := &recordingAuditLog{}
+	manager := NewIntegrationManager(config, auditLog, minimizer)
+	for _, integration := range []Integration{crm, helpdesk} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return manager, helpdesk, auditLog
+}
+
+func TestTransferDataPseudonymizesForLessRestrictiveDestination(t *testing.T) {
+	config := &IntegrationConfig{DataClassification: map[string]string{"helpdesk": "internal"}}
+	minimizer := droppingMinimizer{drop: map[string]bool{"phone": true, "notes": true}}
+	manager, helpdesk, auditLog := newTransferManager(t, config, minimizer)
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	event, err := manager.TransferData(context.Background(), "crm", "helpdesk", query, "user1")
+	if err != nil {
+		t.Fatalf("TransferData: %v", err)
+	}
+
+	if helpdesk.Len() != 1 {
+		t.Fatalf("expected 1 record in the destination, got %d", helpdesk.Len())
+	}
+	record, _ := helpdesk.Record("contact-1")
+	if record.Content["email"] != "pseudo:email" || record.Content["plan"] != "pro" {
+		t.Errorf("unexpected transferred content %v", record.Content)
+	}
+	if _, ok := record.Content["phone"]; ok {
+		t.Errorf("expected phone to be minimized away, got %v", record.Content)
+	}
+	if record.LegalBasis != "contract" || record.ProcessingPurpose != "support" {
+		t.Errorf("expected the query's legal basis and purpose, got %q and %q", record.LegalBasis, record.ProcessingPurpose)
+	}
+
+	want := DataTransferEvent{
+		SourceIntegration:      "crm",
+		DestinationIntegration: "helpdesk",
+		DataType:               "contact",
+		RecordsTransferred:     1,
+		PersonalDataFields:     1,
+		PseudonymizedFields:    1,
+		MinimizedFields:        2,
+		LegalBasis:             "contract",
+		Purpose:                "support",
+		Success:                true,
+	}
+	got := *event
+	got.ID, got.Timestamp = "", want.Timestamp
+	if got != want {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+	if len(auditLog.transfers) != 1 || auditLog.transfers[0] != *event {
+		t.Errorf("expected the event to be logged, got %+v", auditLog.transfers)
+	}
+}
+
+func TestTransferDataKeepsDataForEquallyRestrictiveDestination(t *testing.T) {
+	config := &IntegrationConfig{DataClassification: map[string]string{"helpdesk": "confidential"}}
+	manager, helpdesk, _ := newTransferManager(t, config, droppingMinimizer{})
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	event, err := manager.TransferData(context.Background(), "crm", "helpdesk", query, "user1")
+	if err != nil {
+		t.Fatalf("TransferData: %v", err)
+	}
+
+	record, _ := helpdesk.Record("contact-1")
+	if record.Content["email"] != "alice@example.com" {
+		t.Errorf("expected the email to be transferred as is, got %v", record.Content["email"])
+	}
+	if event.PersonalDataFields != 2 || event.PseudonymizedFields != 0 || event.MinimizedFields != 0 {
+		t.Errorf("unexpected counts %+v", event)
+	}
+}
+
+func TestTransferDataRejectsRestrictedDataWithoutLegalBasis(t *testing.T) {
+	for name, classification := range map[string]map[string]string{
+		"source":      {"crm": "restricted"},
+		"field":       {FieldClassificationKey("crm", "notes"): "restricted"},
+		"destination": {"helpdesk": "restricted"},
+	} {
+		manager, helpdesk, auditLog := newTransferManager(t, &IntegrationConfig{DataClassification: classification}, nil)
+
+		query := &DataQuery{Type: "contact", Justification: "support"}
+		event, err := manager.TransferData(context.Background(), "crm", "helpdesk", query, "user1")
+		if !errors.Is(err, ErrRestrictedTransferNeedsLegalBasis) {
+			t.Fatalf("%s: expected ErrRestrictedTransferNeedsLegalBasis, got %v", name, err)
+		}
+
+		crm := manager.integrations["crm"].(*flakyIntegration)
+		if crm.calls != 0 || helpdesk.Len() != 0 {
+			t.Errorf("%s: expected no requests, got %d retrievals and %d records", name, crm.calls, helpdesk.Len())
+		}
+		if event.Success || event.Error == "" || len(auditLog.transfers) != 1 {
+			t.Errorf("%s: expected a failed transfer to be logged, got %+v", name, event)
+		}
+	}
+}
+
+func TestPinnedClassification(t *testing.T) {
+	manager := NewIntegrationManager(&IntegrationConfig{DataClassification: map[string]string{
+		FieldClassificationKey("crm", "notes"): "restricted",
+		FieldClassificationKey("crm", "email"): "confidential",
+		"helpdesk":                             "internal",
+	}}, nil, nil)
+
+	for _, tc := range []struct {
+		integration string
+		fields      []string
+		want        string
+	}{
+		{"crm", nil, "restricted"},
+		{"crm", []string{"email", "plan"}, "confidential"},
+		{"crm", []string{"plan"}, ""},
+		{"helpdesk", []string{"notes"}, "internal"},
+		{"archive", nil, ""},
+	} {
+		if got := manager.pinnedClassification(tc.integration, tc.fields); got != tc.want {
+			t.Errorf("pinnedClassification(%s, %v) = %q, want %q", tc.integration, tc.fields, got, tc.want)
+		}
+	}
+}
+
+func TestTransferDataRequiresPseudonymizationForDowngrade(t *testing.T) {
+	config := &IntegrationConfig{DataClassification: map[string]string{"helpdesk": "public"}}
+	manager, helpdesk, _ := newTransferManager(t, config, nil)
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	if _, err := manager.TransferData(context.Background(), "crm", "helpdesk", query, "user1"); !errors.Is(err, ErrTransferNeedsPseudonymization) {
+		t.Fatalf("expected ErrTransferNeedsPseudonymization, got %v", err)
+	}
+	if helpdesk.Len() != 0 {
+		t.Errorf("expected nothing to be sent, got %d records", helpdesk.Len())
+	}
+}
+
+func TestTransferDataUnknownDestination(t *testing.T) {
+	manager, _, _ := newTransferManager(t, &IntegrationConfig{}, nil)
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	if _, err := manager.TransferData(context.Background(), "crm", "archive", query, "user1"); err == nil {
+		t.Fatal("expected an error for an unknown destination")
+	}
+	if crm := manager.integrations["crm"].(*flakyIntegration); crm.calls != 0 {
+		t.Errorf("expected no retrieval, got %d", crm.calls)
+	}
+}
+
+func TestTransferDataSendsEveryRecord(t *testing.T) {
+	crm := NewMemoryIntegration("crm")
+	helpdesk := NewMemoryIntegration("helpdesk")
+	auditLog := &recordingAuditLog{}
+	manager := NewIntegrationManager(&IntegrationConfig{}, auditLog, nil)
+	for _, integration := range []Integration{crm, helpdesk} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := crm.SendData(context.Background(), &IntegrationData{
+			ID:           id,
+			Type:         "contact",
+			Content:      map[string]interface{}{"email": id + "@example.com", "plan": "pro"},
+			PersonalData: []PersonalDataField{{Field: "email", DataCategory: "personal"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := &DataQuery{Type: "contact", LegalBasis: "contract", Justification: "support"}
+	event, err := manager.TransferData(context.Background(), "crm", "helpdesk", query, "user1")
+	if err != nil {
+		t.Fatalf("TransferData: %v", err)
+	}
+	if helpdesk.Len() != 3 {
+		t.Fatalf("expected 3 records in the destination, got %d", helpdesk.Len())
+	}
+	if event.RecordsTransferred != 3 || event.PersonalDataFields != 3 {
+		t.Errorf("unexpected counts %+v", event)
+	}
+}
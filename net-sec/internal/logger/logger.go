@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 )
 
 // LogLevel represents the logging level
@@ -39,19 +40,14 @@ type Logger struct {
 	level  LogLevel
 	format string
 	output io.Writer
+	mu     sync.Mutex
 }
 
 var globalLogger *Logger
 
 // Init initializes the global logger
 func Init(level, format string) {
-	logLevel := parseLevel(level)
-
-	logger := &Logger{
-		level:  logLevel,
-		format: format,
-		output: os.Stderr,
-	}
+	logger := New(level, format, os.Stderr)
 
 	globalLogger = logger
 
@@ -60,6 +56,16 @@ func Init(level, format string) {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 }
 
+// New creates a logger writing entries at or above level to output in the
+// given format ("json" or "text")
+func New(level, format string, output io.Writer) *Logger {
+	return &Logger{
+		level:  parseLevel(level),
+		format: format,
+		output: output,
+	}
+}
+
 // parseLevel converts string to LogLevel
 func parseLevel(level string) LogLevel {
 	switch strings.ToLower(level) {
@@ -88,7 +94,9 @@ func Get() *Logger {
 // SetOutput sets the logger output
 func SetOutput(output io.Writer) {
 	if globalLogger != nil {
+		globalLogger.mu.Lock()
 		globalLogger.output = output
+		globalLogger.mu.Unlock()
 		log.SetOutput(output)
 	}
 }
@@ -127,11 +135,5 @@ func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
 		msg = fmt.Sprintf(msg, args...)
 	}
 
-	if l.format == "json" {
-		// JSON format logging (simplified)
-		fmt.Fprintf(l.output, `{"level":"%s","message":"%s"}`+"\n", level.String(), msg)
-	} else {
-		// Text format logging
-		fmt.Fprintf(l.output, "[%s] %s\n", level.String(), msg)
-	}
+	l.write(level, msg, nil)
 }
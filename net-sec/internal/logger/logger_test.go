@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// decodeEntries parses each line of buf as a JSON log entry
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not valid JSON: %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestStructuredJSONOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log := New("debug", "json", &buf).Component("rbac").With(Fields{"session_id": "sess_1"})
+
+	log.Warn(`Access "denied"`, Fields{
+		"event_type": "access_denied",
+		"user_id":    "user_1",
+		"error":      errors.New("insufficient permissions"),
+	})
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	expected := map[string]interface{}{
+		"level":      "WARN",
+		"message":    `Access "denied"`,
+		"component":  "rbac",
+		"event_type": "access_denied",
+		"user_id":    "user_1",
+		"session_id": "sess_1",
+		"error":      "insufficient permissions",
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("%s: got %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("expected a time field")
+	}
+}
+
+func TestStructuredLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	log := New("warn", "json", &buf).Component("retention")
+
+	log.Debug("debug", nil)
+	log.Info("info", nil)
+	log.Warn("warn", nil)
+	log.Error("error", nil)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries at warn level, got %d: %v", len(entries), entries)
+	}
+	if entries[0]["level"] != "WARN" || entries[1]["level"] != "ERROR" {
+		t.Errorf("unexpected levels: %v", entries)
+	}
+}
+
+func TestStructuredTextOutput(t *testing.T) {
+	var buf bytes.Buffer
+	New("info", "text", &buf).Component("monitor").Info("Monitor started", Fields{"event_id": "evt_1"})
+
+	expected := "[INFO] Monitor started component=monitor event_id=evt_1\n"
+	if buf.String() != expected {
+		t.Errorf("got %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestPlainMessagesAreValidJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := New("info", "json", &buf)
+	l.log(LevelInfo, "quoted %q", "value")
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 || entries[0]["message"] != `quoted "value"` {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields are key/value pairs attached to a structured log entry
+type Fields map[string]interface{}
+
+// StructuredLogger writes leveled log entries with structured fields
+type StructuredLogger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+
+	// With returns a logger that adds fields to every entry
+	With(fields Fields) StructuredLogger
+}
+
+// Component returns a structured logger tagging entries with the component
+// name, backed by the global logger
+func Component(name string) StructuredLogger {
+	return Get().Component(name)
+}
+
+// Component returns a structured logger tagging entries with the component
+// name
+func (l *Logger) Component(name string) StructuredLogger {
+	return &fieldLogger{base: l, fields: Fields{"component": name}}
+}
+
+// fieldLogger is a StructuredLogger with a set of fields added to each entry
+type fieldLogger struct {
+	base   *Logger
+	fields Fields
+}
+
+func (f *fieldLogger) Debug(msg string, fields Fields) { f.log(LevelDebug, msg, fields) }
+func (f *fieldLogger) Info(msg string, fields Fields)  { f.log(LevelInfo, msg, fields) }
+func (f *fieldLogger) Warn(msg string, fields Fields)  { f.log(LevelWarn, msg, fields) }
+func (f *fieldLogger) Error(msg string, fields Fields) { f.log(LevelError, msg, fields) }
+
+// With returns a logger with fields merged into the existing ones
+func (f *fieldLogger) With(fields Fields) StructuredLogger {
+	return &fieldLogger{base: f.base, fields: merge(f.fields, fields)}
+}
+
+func (f *fieldLogger) log(level LogLevel, msg string, fields Fields) {
+	if f.base.level > level {
+		return
+	}
+	f.base.write(level, msg, merge(f.fields, fields))
+}
+
+// merge returns a new map with the fields of b overriding those of a
+func merge(a, b Fields) Fields {
+	merged := make(Fields, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+	for key, value := range b {
+		merged[key] = value
+	}
+	return merged
+}
+
+// write formats a log entry and writes it to the output
+func (l *Logger) write(level LogLevel, msg string, fields Fields) {
+	var line string
+
+	if l.format == "json" {
+		entry := make(map[string]interface{}, len(fields)+3)
+		for key, value := range fields {
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			entry[key] = value
+		}
+		entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+		entry["level"] = level.String()
+		entry["message"] = msg
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{
+				"level":   level.String(),
+				"message": msg,
+				"error":   fmt.Sprintf("failed to encode log fields: %v", err),
+			})
+		}
+		line = string(data)
+	} else {
+		line = fmt.Sprintf("[%s] %s", level.String(), msg)
+		if len(fields) > 0 {
+			line += " " + formatFields(fields)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintln(l.output, line)
+}
+
+// formatFields renders fields as sorted key=value pairs
+func formatFields(fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, fields[key]))
+	}
+	return strings.Join(pairs, " ")
+}
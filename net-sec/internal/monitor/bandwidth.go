@@ -0,0 +1,230 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// Bandwidth test defaults
+const (
+	defaultUploadSize  = 1 << 20  // 1 MiB
+	defaultMaxDownload = 25 << 20 // 25 MiB
+	defaultPingSamples = 3
+	bandwidthTimeout   = 30 * time.Second
+)
+
+// BandwidthOptions configures a BandwidthTester
+type BandwidthOptions struct {
+	Client      *http.Client  // Defaults to http.DefaultClient
+	DownloadURL string        // Downloaded to measure download speed; skipped when empty
+	UploadURL   string        // Receives a POST of UploadSize bytes; skipped when empty
+	PingURL     string        // Sampled with HEAD requests; defaults to DownloadURL
+	UploadSize  int64         // Defaults to 1 MiB
+	MaxDownload int64         // Caps the bytes read from DownloadURL; defaults to 25 MiB
+	PingSamples int           // Defaults to 3
+	Timeout     time.Duration // Bounds the whole measurement; defaults to 30s
+}
+
+// BandwidthMeasurer measures the throughput and round-trip time of the link
+type BandwidthMeasurer interface {
+	Measure(ctx context.Context) (BandwidthInfo, error)
+}
+
+// BandwidthTester measures download and upload throughput and round-trip
+// time against configurable endpoints
+type BandwidthTester struct {
+	opts BandwidthOptions
+	now  func() time.Time
+}
+
+// NewBandwidthTester creates a tester, filling unset options with defaults
+func NewBandwidthTester(opts BandwidthOptions) *BandwidthTester {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.PingURL == "" {
+		opts.PingURL = opts.DownloadURL
+	}
+	if opts.UploadSize <= 0 {
+		opts.UploadSize = defaultUploadSize
+	}
+	if opts.MaxDownload <= 0 {
+		opts.MaxDownload = defaultMaxDownload
+	}
+	if opts.PingSamples <= 0 {
+		opts.PingSamples = defaultPingSamples
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = bandwidthTimeout
+	}
+
+	return &BandwidthTester{opts: opts, now: time.Now}
+}
+
+// Measure runs the ping, download and upload tests in turn. Speeds are in
+// megabits per second and ping in milliseconds; skipped tests report 0.
+func (t *BandwidthTester) Measure(ctx context.Context) (BandwidthInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.opts.Timeout)
+	defer cancel()
+
+	var info BandwidthInfo
+
+	if t.opts.PingURL != "" {
+		ping, err := t.ping(ctx)
+		if err != nil {
+			return info, fmt.Errorf("ping failed: %w", err)
+		}
+		info.Ping = ping
+	}
+
+	if t.opts.DownloadURL != "" {
+		download, err := t.download(ctx)
+		if err != nil {
+			return info, fmt.Errorf("download test failed: %w", err)
+		}
+		info.Download = download
+	}
+
+	if t.opts.UploadURL != "" {
+		upload, err := t.upload(ctx)
+		if err != nil {
+			return info, fmt.Errorf("upload test failed: %w", err)
+		}
+		info.Upload = upload
+	}
+
+	return info, nil
+}
+
+// ping returns the average HEAD round trip to PingURL in milliseconds
+func (t *BandwidthTester) ping(ctx context.Context) (float64, error) {
+	var total time.Duration
+	for i := 0; i < t.opts.PingSamples; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, t.opts.PingURL, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		start := t.now()
+		resp, err := t.opts.Client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		total += t.now().Sub(start)
+	}
+
+	return float64(total) / float64(t.opts.PingSamples) / float64(time.Millisecond), nil
+}
+
+// download returns the throughput reading DownloadURL's body
+func (t *BandwidthTester) download(ctx context.Context) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.opts.DownloadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := t.now()
+	resp, err := t.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, t.opts.MaxDownload))
+	if err != nil {
+		return 0, err
+	}
+	return megabitsPerSecond(n, t.now().Sub(start)), nil
+}
+
+// upload returns the throughput posting UploadSize bytes to UploadURL
+func (t *BandwidthTester) upload(ctx context.Context) (float64, error) {
+	body := bytes.NewReader(make([]byte, t.opts.UploadSize))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.opts.UploadURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	start := t.now()
+	resp, err := t.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	elapsed := t.now().Sub(start)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return megabitsPerSecond(t.opts.UploadSize, elapsed), nil
+}
+
+// megabitsPerSecond converts n bytes transferred in elapsed to Mbps
+func megabitsPerSecond(n int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / 1e6 / elapsed.Seconds()
+}
+
+// newConfiguredBandwidthTester builds the bandwidth tester from config, or
+// returns nil when bandwidth measurement is not enabled
+func newConfiguredBandwidthTester(config *MonitorConfig) BandwidthMeasurer {
+	if config.BandwidthCheckInterval <= 0 || (config.BandwidthDownloadURL == "" && config.BandwidthUploadURL == "") {
+		return nil
+	}
+
+	return NewBandwidthTester(BandwidthOptions{
+		Client:      &http.Client{},
+		DownloadURL: config.BandwidthDownloadURL,
+		UploadURL:   config.BandwidthUploadURL,
+	})
+}
+
+// bandwidthLoop measures the link every BandwidthCheckInterval until ctx is
+// cancelled. A measurement takes up to its timeout, so it runs apart from
+// monitorLoop rather than holding up the other checks.
+func (m *Monitor) bandwidthLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.config.BandwidthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.checkBandwidth(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkBandwidth measures the link and records the result on the primary
+// interface
+func (m *Monitor) checkBandwidth(ctx context.Context) {
+	info, err := m.bandwidth.Measure(ctx)
+	if err != nil {
+		m.logger.Warn("Bandwidth measurement failed", logger.Fields{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	m.mu.Lock()
+	m.status.NetworkStatus.PrimaryInterface.Bandwidth = info
+	m.status.NetworkStatus.PrimaryInterface.LastTested = time.Now()
+	m.status.Timestamp = time.Now()
+	m.mu.Unlock()
+}
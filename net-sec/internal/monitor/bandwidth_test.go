@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// steppingClock returns a clock advancing by step on every call
+func steppingClock(step time.Duration) func() time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+// bandwidthServer serves a download of size bytes and accepts uploads,
+// recording the bytes received
+func bandwidthServer(size int, uploaded *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead:
+		case r.Method == http.MethodGet && r.URL.Path == "/download":
+			w.Write(bytes.Repeat([]byte{'x'}, size))
+		case r.Method == http.MethodPost && r.URL.Path == "/upload":
+			n, _ := io.Copy(io.Discard, r.Body)
+			uploaded.Store(n)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestBandwidthTesterMeasure(t *testing.T) {
+	var uploaded atomic.Int64
+	server := bandwidthServer(1250000, &uploaded)
+	defer server.Close()
+
+	tester := NewBandwidthTester(BandwidthOptions{
+		DownloadURL: server.URL + "/download",
+		UploadURL:   server.URL + "/upload",
+		UploadSize:  625000,
+	})
+	// Every transfer appears to take 100ms
+	tester.now = steppingClock(100 * time.Millisecond)
+
+	info, err := tester.Measure(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1.25 MB in 100ms is 100 Mbps; 625 kB is 50 Mbps
+	if info.Download < 99 || info.Download > 101 {
+		t.Errorf("expected about 100 Mbps download, got %.2f", info.Download)
+	}
+	if info.Upload < 49 || info.Upload > 51 {
+		t.Errorf("expected about 50 Mbps upload, got %.2f", info.Upload)
+	}
+	if info.Ping < 99 || info.Ping > 101 {
+		t.Errorf("expected about 100ms ping, got %.2f", info.Ping)
+	}
+	if n := uploaded.Load(); n != 625000 {
+		t.Errorf("expected 625000 bytes uploaded, got %d", n)
+	}
+}
+
+func TestBandwidthTesterCapsDownload(t *testing.T) {
+	var uploaded atomic.Int64
+	server := bandwidthServer(1<<20, &uploaded)
+	defer server.Close()
+
+	tester := NewBandwidthTester(BandwidthOptions{DownloadURL: server.URL + "/download", MaxDownload: 125000})
+	tester.now = steppingClock(time.Second)
+
+	info, err := tester.Measure(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Download != 1 {
+		t.Errorf("expected 125000 bytes in 1s to be 1 Mbps, got %.2f", info.Download)
+	}
+	if info.Upload != 0 {
+		t.Errorf("expected the upload test to be skipped, got %.2f", info.Upload)
+	}
+}
+
+func TestBandwidthTesterReportsFailures(t *testing.T) {
+	var uploaded atomic.Int64
+	server := bandwidthServer(0, &uploaded)
+	defer server.Close()
+
+	tester := NewBandwidthTester(BandwidthOptions{DownloadURL: server.URL + "/missing"})
+	if _, err := tester.Measure(context.Background()); err == nil {
+		t.Error("expected an error for a missing download")
+	}
+}
+
+func TestBandwidthChecksAreOptIn(t *testing.T) {
+	for _, config := range []*MonitorConfig{
+		{BandwidthDownloadURL: "http://example.com/download"},
+		{BandwidthCheckInterval: time.Minute},
+	} {
+		if newConfiguredBandwidthTester(config) != nil {
+			t.Errorf("expected no bandwidth tester for %+v", config)
+		}
+	}
+	if newConfiguredBandwidthTester(&MonitorConfig{BandwidthCheckInterval: time.Minute, BandwidthDownloadURL: "http://example.com/download"}) == nil {
+		t.Error("expected a bandwidth tester when enabled")
+	}
+}
+
+func TestMonitorRecordsBandwidth(t *testing.T) {
+	var uploaded atomic.Int64
+	server := bandwidthServer(1250000, &uploaded)
+	defer server.Close()
+
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	err := m.Initialize(&MonitorConfig{
+		NetworkCheckInterval:   time.Hour,
+		VPNCheckInterval:       time.Hour,
+		DNSCheckInterval:       time.Hour,
+		CaptiveCheckInterval:   time.Hour,
+		BandwidthCheckInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tester := NewBandwidthTester(BandwidthOptions{DownloadURL: server.URL + "/download"})
+	tester.now = steppingClock(100 * time.Millisecond)
+	m.SetBandwidthTester(tester)
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown(context.Background())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.GetStatus().NetworkStatus.PrimaryInterface.Bandwidth.Download == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("bandwidth was not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if bandwidth := m.GetStatus().NetworkStatus.PrimaryInterface.Bandwidth; bandwidth.Download < 99 || bandwidth.Download > 101 {
+		t.Errorf("expected about 100 Mbps download, got %.2f", bandwidth.Download)
+	}
+}
+
+// blockingMeasurer is a BandwidthMeasurer that hangs until cancelled
+type blockingMeasurer struct {
+	started chan struct{}
+	once    sync.Once
+}
+
+func (b *blockingMeasurer) Measure(ctx context.Context) (BandwidthInfo, error) {
+	b.once.Do(func() { close(b.started) })
+	<-ctx.Done()
+	return BandwidthInfo{}, ctx.Err()
+}
+
+// countingTransportProbe counts the DNS checks that probe it
+type countingTransportProbe struct {
+	probes atomic.Int64
+}
+
+func (p *countingTransportProbe) ProbeTransport(ctx context.Context, servers []string) string {
+	p.probes.Add(1)
+	return TransportPlaintext
+}
+
+func TestBandwidthMeasurementDoesNotBlockChecks(t *testing.T) {
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	err := m.Initialize(&MonitorConfig{
+		NetworkCheckInterval:   time.Hour,
+		VPNCheckInterval:       time.Hour,
+		DNSCheckInterval:       5 * time.Millisecond,
+		CaptiveCheckInterval:   time.Hour,
+		BandwidthCheckInterval: time.Millisecond,
+		DNSServers:             []string{"10.0.0.53"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	measurer := &blockingMeasurer{started: make(chan struct{})}
+	m.SetBandwidthTester(measurer)
+	probe := &countingTransportProbe{}
+	m.SetDNSTransportProbe(probe)
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown(context.Background())
+
+	<-measurer.started
+	before := probe.probes.Load()
+	deadline := time.Now().Add(5 * time.Second)
+	for probe.probes.Load() < before+3 {
+		if time.Now().After(deadline) {
+			t.Fatal("DNS checks stalled behind the bandwidth measurement")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
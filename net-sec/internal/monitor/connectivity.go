@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Connectivity components reported in ConnectivityResult.ErrorDetails
+const (
+	ComponentDNS   = "dns"
+	ComponentHTTP  = "http"
+	ComponentHTTPS = "https"
+)
+
+// ConnectivityOptions configures a ConnectivityTester
+type ConnectivityOptions struct {
+	Resolver       Resolver      // Defaults to net.DefaultResolver
+	Client         *http.Client  // Defaults to http.DefaultClient
+	DNSHost        string        // Defaults to the host of HTTPSURL, then HTTPURL
+	HTTPURL        string        // Plain HTTP URL; skipped when empty
+	HTTPSURL       string        // HTTPS URL; skipped when empty
+	ExpectedStatus int           // Status returned by the URLs when online; defaults to 204
+	Timeout        time.Duration // Bounds each attempt; defaults to probeTimeout
+	Retries        int           // Extra attempts for a failing sub-test
+	RetryDelay     time.Duration // Pause between attempts
+}
+
+// ConnectivityTester tests DNS resolution, HTTP and HTTPS connectivity
+// separately so failures can be attributed to a single component
+type ConnectivityTester struct {
+	opts ConnectivityOptions
+}
+
+// NewConnectivityTester creates a tester, filling unset options with defaults
+func NewConnectivityTester(opts ConnectivityOptions) *ConnectivityTester {
+	if opts.Resolver == nil {
+		opts.Resolver = net.DefaultResolver
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.ExpectedStatus == 0 {
+		opts.ExpectedStatus = http.StatusNoContent
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = probeTimeout
+	}
+	if opts.DNSHost == "" {
+		for _, rawURL := range []string{opts.HTTPSURL, opts.HTTPURL} {
+			if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+				opts.DNSHost = u.Hostname()
+				break
+			}
+		}
+	}
+
+	return &ConnectivityTester{opts: opts}
+}
+
+// Test runs the DNS, HTTP and HTTPS sub-tests in turn. Each failed
+// component adds an entry prefixed with its name to ErrorDetails. Internet
+// access is reported when either HTTP or HTTPS answered as expected.
+func (t *ConnectivityTester) Test(ctx context.Context) ConnectivityResult {
+	start := time.Now()
+	result := ConnectivityResult{TestTimestamp: start}
+
+	if t.opts.DNSHost != "" {
+		err := t.attempt(ctx, func(ctx context.Context) error {
+			_, err := t.opts.Resolver.LookupHost(ctx, t.opts.DNSHost)
+			return err
+		})
+		result.DNSResolution = err == nil
+		result.ErrorDetails = appendFailure(result.ErrorDetails, ComponentDNS, err)
+	}
+
+	if t.opts.HTTPURL != "" {
+		err := t.attempt(ctx, t.probe(t.opts.HTTPURL))
+		result.HTTPConnectivity = err == nil
+		result.ErrorDetails = appendFailure(result.ErrorDetails, ComponentHTTP, err)
+	}
+
+	if t.opts.HTTPSURL != "" {
+		err := t.attempt(ctx, t.probe(t.opts.HTTPSURL))
+		result.HTTPSConnectivity = err == nil
+		result.ErrorDetails = appendFailure(result.ErrorDetails, ComponentHTTPS, err)
+	}
+
+	result.InternetAccess = result.HTTPConnectivity || result.HTTPSConnectivity
+	result.TestDuration = time.Since(start)
+	return result
+}
+
+// FailedComponents returns the components that failed in result, in test
+// order
+func (t *ConnectivityTester) FailedComponents(result ConnectivityResult) []string {
+	var failed []string
+	if t.opts.DNSHost != "" && !result.DNSResolution {
+		failed = append(failed, ComponentDNS)
+	}
+	if t.opts.HTTPURL != "" && !result.HTTPConnectivity {
+		failed = append(failed, ComponentHTTP)
+	}
+	if t.opts.HTTPSURL != "" && !result.HTTPSConnectivity {
+		failed = append(failed, ComponentHTTPS)
+	}
+	return failed
+}
+
+// probe returns a sub-test requesting rawURL and expecting ExpectedStatus
+func (t *ConnectivityTester) probe(rawURL string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		result := ProbeHTTP(ctx, t.opts.Client, rawURL, t.opts.ExpectedStatus)
+		if !result.InternetAccess {
+			return fmt.Errorf("%s", strings.Join(result.ErrorDetails, "; "))
+		}
+		return nil
+	}
+}
+
+// attempt runs test with a per-attempt timeout, retrying failures until
+// Retries is exhausted or ctx is done
+func (t *ConnectivityTester) attempt(ctx context.Context, test func(context.Context) error) error {
+	var err error
+	for i := 0; i <= t.opts.Retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(t.opts.RetryDelay):
+			}
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		testCtx, cancel := context.WithTimeout(ctx, t.opts.Timeout)
+		err = test(testCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// appendFailure records err against component when it is non-nil
+func appendFailure(details []string, component string, err error) []string {
+	if err == nil {
+		return details
+	}
+	return append(details, fmt.Sprintf("%s: %v", component, err))
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubResolver answers every lookup with err, or a fixed address
+type stubResolver struct {
+	err error
+}
+
+func (r stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []string{"192.0.2.1"}, nil
+}
+
+// newConnectivityServers starts an HTTP and an HTTPS server answering with
+// the given status codes
+func newConnectivityServers(t *testing.T, httpStatus, httpsStatus int) (*httptest.Server, *httptest.Server) {
+	t.Helper()
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(httpStatus)
+	}))
+	t.Cleanup(plain.Close)
+
+	tls := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(httpsStatus)
+	}))
+	t.Cleanup(tls.Close)
+
+	return plain, tls
+}
+
+func newTestTester(resolver Resolver, plain, tls *httptest.Server) *ConnectivityTester {
+	return NewConnectivityTester(ConnectivityOptions{
+		Resolver: resolver,
+		Client:   tls.Client(),
+		DNSHost:  "connectivity.example.com",
+		HTTPURL:  plain.URL + "/generate_204",
+		HTTPSURL: tls.URL + "/generate_204",
+		Timeout:  time.Second,
+	})
+}
+
+func TestConnectivityAllPass(t *testing.T) {
+	plain, tls := newConnectivityServers(t, http.StatusNoContent, http.StatusNoContent)
+	tester := newTestTester(stubResolver{}, plain, tls)
+
+	result := tester.Test(context.Background())
+	if !result.InternetAccess || !result.DNSResolution || !result.HTTPConnectivity || !result.HTTPSConnectivity {
+		t.Errorf("expected every component to pass: %+v", result)
+	}
+	if len(result.ErrorDetails) != 0 || len(tester.FailedComponents(result)) != 0 {
+		t.Errorf("unexpected failures: %v", result.ErrorDetails)
+	}
+	if result.TestTimestamp.IsZero() || result.TestDuration <= 0 {
+		t.Errorf("expected timing to be recorded: %+v", result)
+	}
+}
+
+func TestConnectivityDNSOnlyFailure(t *testing.T) {
+	plain, tls := newConnectivityServers(t, http.StatusNoContent, http.StatusNoContent)
+	tester := newTestTester(stubResolver{err: errors.New("no such host")}, plain, tls)
+
+	result := tester.Test(context.Background())
+	if result.DNSResolution || !result.HTTPConnectivity || !result.HTTPSConnectivity || !result.InternetAccess {
+		t.Errorf("expected only DNS to fail: %+v", result)
+	}
+	if len(result.ErrorDetails) != 1 || result.ErrorDetails[0] != "dns: no such host" {
+		t.Errorf("unexpected error details: %v", result.ErrorDetails)
+	}
+	if failed := strings.Join(tester.FailedComponents(result), ","); failed != ComponentDNS {
+		t.Errorf("expected dns to be the failed component, got %s", failed)
+	}
+}
+
+func TestConnectivityHTTPSOnlyFailure(t *testing.T) {
+	plain, tls := newConnectivityServers(t, http.StatusNoContent, http.StatusInternalServerError)
+	tester := newTestTester(stubResolver{}, plain, tls)
+
+	result := tester.Test(context.Background())
+	if !result.DNSResolution || !result.HTTPConnectivity || result.HTTPSConnectivity || !result.InternetAccess {
+		t.Errorf("expected only HTTPS to fail: %+v", result)
+	}
+	if len(result.ErrorDetails) != 1 || !strings.HasPrefix(result.ErrorDetails[0], "https: ") ||
+		!strings.Contains(result.ErrorDetails[0], "got 500") {
+		t.Errorf("unexpected error details: %v", result.ErrorDetails)
+	}
+	if failed := strings.Join(tester.FailedComponents(result), ","); failed != ComponentHTTPS {
+		t.Errorf("expected https to be the failed component, got %s", failed)
+	}
+}
+
+func TestConnectivityRetriesAndCancellation(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt only
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tester := NewConnectivityTester(ConnectivityOptions{
+		Resolver: stubResolver{},
+		HTTPURL:  server.URL,
+		Retries:  2,
+	})
+	if result := tester.Test(context.Background()); !result.HTTPConnectivity || len(result.ErrorDetails) != 0 {
+		t.Errorf("expected a retry to succeed: %+v", result)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := tester.Test(ctx)
+	if result.InternetAccess || len(result.ErrorDetails) != 2 {
+		t.Errorf("expected a cancelled test to fail every component: %+v", result)
+	}
+}
+
+func TestCheckNetworkStatusReportsFailedComponent(t *testing.T) {
+	plain, tls := newConnectivityServers(t, http.StatusNoContent, http.StatusNoContent)
+
+	m := NewMonitor()
+	if err := m.Initialize(&MonitorConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetConnectivityTester(newTestTester(stubResolver{err: errors.New("no such host")}, plain, tls))
+
+	m.checkNetworkStatus(context.Background())
+
+	status := m.GetStatus().NetworkStatus
+	if status.Status != StatusWarning || status.ConnectivityTest.DNSResolution {
+		t.Errorf("expected a DNS warning, got %+v", status)
+	}
+
+	select {
+	case event := <-m.GetEventStream():
+		if event.Message != "DNS resolution failed" || event.Severity != StatusWarning || event.Details["error"] != "no such host" {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	default:
+		t.Fatal("expected a DNS failure event")
+	}
+
+	// A persisting failure is not reported again
+	m.checkNetworkStatus(context.Background())
+	select {
+	case event := <-m.GetEventStream():
+		t.Errorf("unexpected repeated event: %+v", event)
+	default:
+	}
+}
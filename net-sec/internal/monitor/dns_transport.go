@@ -0,0 +1,236 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNS transports reported in DNSStatus.EncryptedTransport
+const (
+	TransportDoH       = "doh"
+	TransportDoT       = "dot"
+	TransportPlaintext = "plaintext"
+)
+
+// dohContentType is the media type of RFC 8484 DNS-over-HTTPS messages
+const dohContentType = "application/dns-message"
+
+// DNSTransportProbe reports the most secure transport answered by any of
+// the given resolvers: TransportDoH, TransportDoT or TransportPlaintext
+type DNSTransportProbe interface {
+	ProbeTransport(ctx context.Context, servers []string) string
+}
+
+// DNSTransportProber probes resolvers for DNS-over-HTTPS at
+// https://<server>/dns-query and DNS-over-TLS on port 853. Servers given as
+// https:// URLs are only probed for DoH at that URL.
+type DNSTransportProber struct {
+	Client    *http.Client // Used for DoH; defaults to http.DefaultClient
+	TLSConfig *tls.Config  // Used for DoT; ServerName defaults to the server
+	QueryName string       // Resolved by each probe; defaults to "example.com."
+}
+
+// NewDNSTransportProber creates a prober using client for DoH requests
+func NewDNSTransportProber(client *http.Client) *DNSTransportProber {
+	return &DNSTransportProber{Client: client}
+}
+
+// ProbeTransport implements DNSTransportProbe, preferring DoH over DoT
+func (p *DNSTransportProber) ProbeTransport(ctx context.Context, servers []string) string {
+	query, err := p.query()
+	if err != nil {
+		return TransportPlaintext
+	}
+
+	for _, server := range servers {
+		if p.probeDoH(ctx, dohURL(server), query) == nil {
+			return TransportDoH
+		}
+	}
+	for _, server := range servers {
+		if strings.HasPrefix(server, "https://") {
+			continue
+		}
+		if p.probeDoT(ctx, server, query) == nil {
+			return TransportDoT
+		}
+	}
+
+	return TransportPlaintext
+}
+
+// query returns a packed A query for QueryName
+func (p *DNSTransportProber) query() ([]byte, error) {
+	name := p.QueryName
+	if name == "" {
+		name = "example.com."
+	}
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	return msg.Pack()
+}
+
+// probeDoH sends query to url as an RFC 8484 GET request
+func (p *DNSTransportProber) probeDoH(ctx context.Context, url string, query []byte) error {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		url+"?dns="+base64.RawURLEncoding.EncodeToString(query), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", dohContentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DoH request returned status %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, dohContentType) {
+		return fmt.Errorf("DoH response has content type %q", contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return err
+	}
+	return checkDNSResponse(body)
+}
+
+// probeDoT sends query to server on the DNS-over-TLS port
+func (p *DNSTransportProber) probeDoT(ctx context.Context, server string, query []byte) error {
+	// Plain DNS addresses are probed on the DoT port
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host = strings.Trim(server, "[]")
+	}
+	if err != nil || port == "53" {
+		port = "853"
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if p.TLSConfig != nil {
+		config = p.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// DNS over TCP messages are prefixed with their length
+	framed := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(framed, uint16(len(query)))
+	copy(framed[2:], query)
+	if _, err := conn.Write(framed); err != nil {
+		return err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return err
+	}
+	return checkDNSResponse(response)
+}
+
+// checkDNSResponse verifies that msg is a DNS response
+func checkDNSResponse(msg []byte) error {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(msg)
+	if err != nil {
+		return fmt.Errorf("invalid DNS response: %w", err)
+	}
+	if !header.Response {
+		return fmt.Errorf("DNS message is not a response")
+	}
+	return nil
+}
+
+// dohURL returns the DoH endpoint of server
+func dohURL(server string) string {
+	if strings.HasPrefix(server, "https://") {
+		return server
+	}
+	if host, port, err := net.SplitHostPort(server); err == nil && port == "53" {
+		server = host
+	}
+	if strings.Contains(server, ":") && !strings.HasPrefix(server, "[") {
+		server = "[" + server + "]"
+	}
+	return "https://" + server + "/dns-query"
+}
+
+// probeDNSTransport returns the encrypted transport offered by the
+// configured DNS servers, or "" when there are none to probe
+func (m *Monitor) probeDNSTransport() string {
+	if m.dnsTransport == nil || len(m.config.DNSServers) == 0 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	return m.dnsTransport.ProbeTransport(ctx, m.config.DNSServers)
+}
+
+// reportPlaintextDNS sends a warning that the resolvers only answer over
+// plaintext DNS while the VPN is connected
+func (m *Monitor) reportPlaintextDNS() {
+	m.sendEvent(&MonitorEvent{
+		Type:      EventSecurityThreat,
+		Timestamp: time.Now(),
+		Severity:  StatusWarning,
+		Component: "dns",
+		Message:   "Only plaintext DNS is available while the VPN is connected",
+		Details: map[string]interface{}{
+			"dns_servers":         append([]string(nil), m.config.DNSServers...),
+			"encrypted_transport": TransportPlaintext,
+		},
+		Source: "dns_transport_probe",
+		Tags:   []string{"dns", "encryption"},
+	})
+}
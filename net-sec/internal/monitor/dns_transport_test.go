@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsAnswer returns a packed response to query
+func dnsAnswer(t *testing.T, query []byte) []byte {
+	t.Helper()
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		t.Errorf("invalid DNS query: %v", err)
+		return nil
+	}
+	msg.Header.Response = true
+	response, err := msg.Pack()
+	if err != nil {
+		t.Error(err)
+	}
+	return response
+}
+
+func TestDNSTransportProberDetectsDoH(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		if err != nil || r.Header.Get("Accept") != dohContentType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", dohContentType)
+		w.Write(dnsAnswer(t, query))
+	}))
+	defer server.Close()
+
+	prober := NewDNSTransportProber(server.Client())
+	if transport := prober.ProbeTransport(context.Background(), []string{server.URL + "/dns-query"}); transport != TransportDoH {
+		t.Errorf("expected %s, got %s", TransportDoH, transport)
+	}
+}
+
+func TestDNSTransportProberDetectsDoT(t *testing.T) {
+	httpServer := httptest.NewUnstartedServer(nil)
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", httpServer.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		response := dnsAnswer(t, query)
+		binary.BigEndian.PutUint16(length[:], uint16(len(response)))
+		conn.Write(append(length[:], response...))
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(httpServer.Certificate())
+	prober := &DNSTransportProber{
+		// Nothing serves DoH on this address
+		Client:    &http.Client{Timeout: time.Second},
+		TLSConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if transport := prober.ProbeTransport(ctx, []string{listener.Addr().String()}); transport != TransportDoT {
+		t.Errorf("expected %s, got %s", TransportDoT, transport)
+	}
+}
+
+func TestDNSTransportProberReportsPlaintext(t *testing.T) {
+	// A resolver that accepts connections but speaks neither DoH nor DoT
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	prober := NewDNSTransportProber(&http.Client{Timeout: time.Second})
+	if transport := prober.ProbeTransport(context.Background(), []string{listener.Addr().String()}); transport != TransportPlaintext {
+		t.Errorf("expected %s, got %s", TransportPlaintext, transport)
+	}
+}
+
+// fixedTransportProbe reports a fixed transport
+type fixedTransportProbe string
+
+func (p fixedTransportProbe) ProbeTransport(ctx context.Context, servers []string) string {
+	return string(p)
+}
+
+func TestCheckDNSStatusWarnsOnPlaintextDNSOverVPN(t *testing.T) {
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	if err := m.Initialize(&MonitorConfig{DNSServers: []string{"10.0.0.53"}}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetDNSTransportProbe(fixedTransportProbe(TransportPlaintext))
+
+	// No warning while the VPN is down
+	m.checkDNSStatus()
+	if status := m.GetStatus().DNSStatus; status.EncryptedTransport != TransportPlaintext || status.Status != StatusOK {
+		t.Errorf("unexpected DNS status %+v", status)
+	}
+	if len(m.eventStream) != 0 {
+		t.Fatalf("expected no events without a VPN, got %d", len(m.eventStream))
+	}
+
+	m.mu.Lock()
+	m.status.VPNStatus.Connected = true
+	m.mu.Unlock()
+
+	// The warning is raised once while the condition lasts
+	m.checkDNSStatus()
+	m.checkDNSStatus()
+	if len(m.eventStream) != 1 {
+		t.Fatalf("expected a single warning, got %d events", len(m.eventStream))
+	}
+	event := <-m.eventStream
+	if event.Type != EventSecurityThreat || event.Severity != StatusWarning || event.Component != "dns" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if status := m.GetStatus().DNSStatus; status.Status != StatusWarning {
+		t.Errorf("expected DNS status warning, got %s", status.Status)
+	}
+
+	// Encrypted DNS clears the condition
+	m.SetDNSTransportProbe(fixedTransportProbe(TransportDoH))
+	m.checkDNSStatus()
+	if status := m.GetStatus().DNSStatus; status.EncryptedTransport != TransportDoH || status.Status != StatusOK {
+		t.Errorf("unexpected DNS status %+v", status)
+	}
+	if len(m.eventStream) != 0 {
+		t.Errorf("expected no events with DoH, got %d", len(m.eventStream))
+	}
+}
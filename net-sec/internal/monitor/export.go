@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// WriteStatusJSON writes status as indented JSON, for one-shot exports to
+// external dashboards
+func WriteStatusJSON(w io.Writer, status SystemStatus) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(status)
+}
+
+// RunChecks runs every subsystem check once and updates the overall status.
+// It lets a monitor that was initialized but not started report a status.
+func (m *Monitor) RunChecks(ctx context.Context) {
+	m.checkNetworkStatus(ctx)
+	m.checkVPNStatus()
+	m.checkDNSStatus()
+	m.checkCaptivePortalStatus()
+	m.updateOverallStatus()
+}
+
+// StatusHandler serves the current SystemStatus as JSON
+func (m *Monitor) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := WriteStatusJSON(w, m.GetStatus()); err != nil {
+			m.logger.Warn("Failed to write status", logger.Fields{
+				"error": err.Error(),
+			})
+		}
+	})
+}
+
+// EventsHandler streams MonitorEvents as Server-Sent Events. Each event is
+// sent with its type as the SSE event name and its JSON encoding as data.
+// The stream ends when the client disconnects or the monitor shuts down.
+func (m *Monitor) EventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		events, unsubscribe := m.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := writeSSE(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+}
+
+// writeSSE writes event as a single Server-Sent Event
+func writeSSE(w io.Writer, event *MonitorEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type.String(), data)
+	return err
+}
@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+func TestWriteStatusJSONIncludesSubsystems(t *testing.T) {
+	status := SystemStatus{
+		OverallStatus: StatusError,
+		NetworkStatus: NetworkStatus{Status: StatusOK, ActiveInterface: "wlan0"},
+		VPNStatus:     VPNStatus{Status: StatusError},
+		DNSStatus:     DNSStatus{Status: StatusWarning, EncryptedTransport: TransportPlaintext},
+		CaptiveStatus: CaptivePortalStatus{Status: StatusOK},
+		ActiveAlerts:  []Alert{{ID: "alert_1", Severity: StatusError, Title: "VPN down"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteStatusJSON(&buf, status); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"overall_status", "network_status", "vpn_status", "dns_status", "captive_status", "active_alerts"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("expected %s in the snapshot", key)
+		}
+	}
+
+	var decoded SystemStatus
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.OverallStatus != StatusError || decoded.VPNStatus.Status != StatusError ||
+		decoded.DNSStatus.Status != StatusWarning || decoded.DNSStatus.EncryptedTransport != TransportPlaintext ||
+		decoded.NetworkStatus.ActiveInterface != "wlan0" || len(decoded.ActiveAlerts) != 1 {
+		t.Errorf("snapshot did not round-trip: %+v", decoded)
+	}
+}
+
+// readSSE returns the next event name and data from an SSE stream
+func readSSE(t *testing.T, reader *bufio.Reader) (string, string, error) {
+	t.Helper()
+
+	var name, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data, nil
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventsHandlerStreamsEvents(t *testing.T) {
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	if err := m.Initialize(&MonitorConfig{
+		NetworkCheckInterval: time.Hour,
+		VPNCheckInterval:     time.Hour,
+		DNSCheckInterval:     time.Hour,
+		CaptiveCheckInterval: time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(m.EventsHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", contentType)
+	}
+
+	// The subscription exists once the headers arrive
+	m.AddAlert(Alert{Type: AlertVPNDown, Severity: StatusError, Title: "VPN tunnel down"})
+
+	done := make(chan struct{})
+	var names []string
+	var alert MonitorEvent
+	var streamErr error
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(resp.Body)
+		for {
+			name, data, err := readSSE(t, reader)
+			if err != nil {
+				streamErr = err
+				return
+			}
+			names = append(names, name)
+			if name == EventAlert.String() {
+				if err := json.Unmarshal([]byte(data), &alert); err != nil {
+					streamErr = err
+					return
+				}
+			}
+		}
+	}()
+
+	// Wait for the alert to be handled before shutting down
+	deadline := time.Now().Add(time.Second)
+	for len(m.GetStatus().RecentEvents) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to close on shutdown")
+	}
+
+	if streamErr != io.EOF {
+		t.Errorf("expected the stream to end cleanly, got %v", streamErr)
+	}
+	if alert.Message != "VPN tunnel down" || alert.Severity != StatusError {
+		t.Errorf("expected the alert to be delivered, got %+v (events %v)", alert, names)
+	}
+	if len(names) == 0 || names[len(names)-1] != EventSystemShutdown.String() {
+		t.Errorf("expected the shutdown event last, got %v", names)
+	}
+}
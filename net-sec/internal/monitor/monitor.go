@@ -1,9 +1,15 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
 )
 
 // Monitor provides real-time monitoring of network security status
@@ -11,9 +17,21 @@ type Monitor struct {
 	config      *MonitorConfig
 	status      *SystemStatus
 	eventStream chan *MonitorEvent
-	stopChan    chan bool
+	cancel      context.CancelFunc
+	loops       sync.WaitGroup
 	mu          sync.RWMutex
 	running     bool
+	logger      logger.StructuredLogger
+
+	connectivity         *ConnectivityTester
+	connectivityFailures []string
+
+	dnsTransport       DNSTransportProbe
+	plaintextDNSWarned bool
+
+	bandwidth BandwidthMeasurer
+
+	subscribers subscribers
 }
 
 // MonitorConfig contains monitoring configuration options
@@ -29,6 +47,16 @@ type MonitorConfig struct {
 	EnableDashboard      bool
 	DashboardPort        int
 	MetricsRetention     time.Duration
+	ConnectivityURL      string   // Probed by network checks when set
+	HTTPSConnectivityURL string   // Probed over TLS by network checks when set
+	ExpectedStatus       int      // Status returned by ConnectivityURL when online
+	ConnectivityRetries  int      // Extra attempts for each failing connectivity sub-test
+	DNSTestDomains       []string // Resolved by DNS checks when set
+	DNSServers           []string // Probed for DoH and DoT support by DNS checks when set
+
+	BandwidthCheckInterval time.Duration // Bandwidth is only measured when set
+	BandwidthDownloadURL   string        // Downloaded by bandwidth checks
+	BandwidthUploadURL     string        // Receives uploads from bandwidth checks
 }
 
 // SystemStatus represents the current system status
@@ -140,12 +168,13 @@ type IPLeakTestResult struct {
 
 // DNSStatus contains DNS configuration and status
 type DNSStatus struct {
-	ConfiguredServers []string                 `json:"configured_servers"`
-	ActiveServers     []string                 `json:"active_servers"`
-	ResolutionTest    DNSTestResult            `json:"resolution_test"`
-	LeakTest          DNSLeakTestResult        `json:"leak_test"`
-	Status            StatusLevel              `json:"status"`
-	ResponseTimes     map[string]time.Duration `json:"response_times"`
+	ConfiguredServers  []string                 `json:"configured_servers"`
+	ActiveServers      []string                 `json:"active_servers"`
+	ResolutionTest     DNSTestResult            `json:"resolution_test"`
+	LeakTest           DNSLeakTestResult        `json:"leak_test"`
+	Status             StatusLevel              `json:"status"`
+	ResponseTimes      map[string]time.Duration `json:"response_times"`
+	EncryptedTransport string                   `json:"encrypted_transport,omitempty"` // TransportDoH, TransportDoT or TransportPlaintext; empty when no servers are probed
 }
 
 // DNSTestResult contains DNS resolution test results
@@ -236,6 +265,7 @@ const (
 	EventPerformanceIssue
 	EventConfigurationChange
 	EventAlert
+	EventStatusChange
 )
 
 // String returns the string representation of the event type
@@ -261,6 +291,8 @@ func (e EventType) String() string {
 		return "CONFIGURATION_CHANGE"
 	case EventAlert:
 		return "ALERT"
+	case EventStatusChange:
+		return "STATUS_CHANGE"
 	default:
 		return "UNKNOWN"
 	}
@@ -323,10 +355,35 @@ func NewMonitor() *Monitor {
 	return &Monitor{
 		status:      &SystemStatus{},
 		eventStream: make(chan *MonitorEvent, 1000),
-		stopChan:    make(chan bool, 1),
+		logger:      logger.Component("monitor"),
 	}
 }
 
+// SetLogger sets the logger used for monitoring events. It must be called
+// before Start.
+func (m *Monitor) SetLogger(l logger.StructuredLogger) {
+	m.logger = l
+}
+
+// SetConnectivityTester replaces the tester used by network checks. It must
+// be called after Initialize and before Start.
+func (m *Monitor) SetConnectivityTester(tester *ConnectivityTester) {
+	m.connectivity = tester
+}
+
+// SetBandwidthTester replaces the tester used by bandwidth checks, which run
+// every BandwidthCheckInterval. It must be called after Initialize and
+// before Start.
+func (m *Monitor) SetBandwidthTester(tester BandwidthMeasurer) {
+	m.bandwidth = tester
+}
+
+// SetDNSTransportProbe replaces the probe used by DNS checks to detect
+// encrypted DNS support. It must be called after Initialize and before Start.
+func (m *Monitor) SetDNSTransportProbe(probe DNSTransportProbe) {
+	m.dnsTransport = probe
+}
+
 // Initialize sets up the monitor with the given configuration
 func (m *Monitor) Initialize(config *MonitorConfig) error {
 	m.mu.Lock()
@@ -336,10 +393,47 @@ func (m *Monitor) Initialize(config *MonitorConfig) error {
 	m.status.Timestamp = time.Now()
 	m.status.ActiveAlerts = make([]Alert, 0)
 	m.status.RecentEvents = make([]MonitorEvent, 0)
+	m.connectivity = newConfiguredConnectivityTester(config)
+	m.bandwidth = newConfiguredBandwidthTester(config)
+	if len(config.DNSServers) > 0 {
+		m.status.DNSStatus.ConfiguredServers = append([]string(nil), config.DNSServers...)
+		m.dnsTransport = NewDNSTransportProber(&http.Client{Timeout: probeTimeout})
+	}
 
 	return nil
 }
 
+// newConfiguredConnectivityTester builds the network check tester from
+// config, or returns nil when no connectivity URL is configured
+func newConfiguredConnectivityTester(config *MonitorConfig) *ConnectivityTester {
+	opts := ConnectivityOptions{
+		Client:         &http.Client{},
+		ExpectedStatus: config.ExpectedStatus,
+		Timeout:        probeTimeout,
+		Retries:        config.ConnectivityRetries,
+		RetryDelay:     time.Second,
+	}
+
+	for _, rawURL := range []string{config.ConnectivityURL, config.HTTPSConnectivityURL} {
+		switch {
+		case rawURL == "":
+		case strings.HasPrefix(rawURL, "https://"):
+			if opts.HTTPSURL == "" {
+				opts.HTTPSURL = rawURL
+			}
+		default:
+			if opts.HTTPURL == "" {
+				opts.HTTPURL = rawURL
+			}
+		}
+	}
+	if opts.HTTPURL == "" && opts.HTTPSURL == "" {
+		return nil
+	}
+
+	return NewConnectivityTester(opts)
+}
+
 // Start begins monitoring operations
 func (m *Monitor) Start() error {
 	m.mu.Lock()
@@ -347,12 +441,31 @@ func (m *Monitor) Start() error {
 		m.mu.Unlock()
 		return fmt.Errorf("monitor is already running")
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
 	m.running = true
 	m.mu.Unlock()
 
 	// Start monitoring goroutines
-	go m.monitorLoop()
-	go m.eventProcessor()
+	m.loops.Add(2)
+	go func() {
+		defer m.loops.Done()
+		m.monitorLoop(ctx)
+	}()
+	go func() {
+		defer m.loops.Done()
+		m.eventProcessor(ctx)
+	}()
+
+	// Bandwidth tests saturate the link, so they run on their own, slower
+	// cadence and only when enabled
+	if m.bandwidth != nil && m.config.BandwidthCheckInterval > 0 {
+		m.loops.Add(1)
+		go func() {
+			defer m.loops.Done()
+			m.bandwidthLoop(ctx)
+		}()
+	}
 
 	// Send startup event
 	m.sendEvent(&MonitorEvent{
@@ -367,7 +480,7 @@ func (m *Monitor) Start() error {
 	return nil
 }
 
-// Stop stops monitoring operations
+// Stop stops monitoring operations without waiting for them to finish
 func (m *Monitor) Stop() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -376,7 +489,38 @@ func (m *Monitor) Stop() error {
 		return fmt.Errorf("monitor is not running")
 	}
 
-	// Send shutdown event
+	m.stopLocked()
+	return nil
+}
+
+// Shutdown stops monitoring operations and waits for the monitoring
+// goroutines to exit or ctx to be done, then closes every subscription.
+// Calling it on a monitor that is not running only closes subscriptions.
+func (m *Monitor) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running {
+		m.stopLocked()
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.loops.Wait()
+		close(done)
+	}()
+	defer m.subscribers.close()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("monitor shutdown: %w", ctx.Err())
+	}
+}
+
+// stopLocked sends the shutdown event and cancels the monitoring
+// goroutines; the caller holds mu
+func (m *Monitor) stopLocked() {
 	m.sendEvent(&MonitorEvent{
 		Type:      EventSystemShutdown,
 		Timestamp: time.Now(),
@@ -386,17 +530,16 @@ func (m *Monitor) Stop() error {
 		Source:    "system",
 	})
 
-	m.stopChan <- true
+	m.cancel()
 	m.running = false
-
-	return nil
 }
 
-// GetStatus returns the current system status
+// GetStatus returns a snapshot of the current system status. The snapshot
+// is a deep copy and is not affected by later monitor updates.
 func (m *Monitor) GetStatus() SystemStatus {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return *m.status
+	return m.status.clone()
 }
 
 // GetEventStream returns the event stream channel
@@ -429,8 +572,8 @@ func (m *Monitor) AddAlert(alert Alert) {
 	})
 }
 
-// monitorLoop runs the main monitoring loop
-func (m *Monitor) monitorLoop() {
+// monitorLoop runs the main monitoring loop until ctx is cancelled
+func (m *Monitor) monitorLoop(ctx context.Context) {
 	networkTicker := time.NewTicker(m.config.NetworkCheckInterval)
 	vpnTicker := time.NewTicker(m.config.VPNCheckInterval)
 	dnsTicker := time.NewTicker(m.config.DNSCheckInterval)
@@ -444,35 +587,57 @@ func (m *Monitor) monitorLoop() {
 	for {
 		select {
 		case <-networkTicker.C:
-			m.checkNetworkStatus()
+			m.checkNetworkStatus(ctx)
 		case <-vpnTicker.C:
 			m.checkVPNStatus()
 		case <-dnsTicker.C:
 			m.checkDNSStatus()
 		case <-captiveTicker.C:
 			m.checkCaptivePortalStatus()
-		case <-m.stopChan:
+		case <-ctx.Done():
 			return
 		}
+
+		m.updateOverallStatus()
 	}
 }
 
-// eventProcessor processes and handles monitoring events
-func (m *Monitor) eventProcessor() {
-	for event := range m.eventStream {
-		// Process event (logging, alerting, etc.)
-		m.processEvent(event)
-
-		// Add to recent events (keep only last N events)
-		m.mu.Lock()
-		m.status.RecentEvents = append(m.status.RecentEvents, *event)
-		if len(m.status.RecentEvents) > 100 { // Keep last 100 events
-			m.status.RecentEvents = m.status.RecentEvents[1:]
+// eventProcessor processes and handles monitoring events until ctx is
+// cancelled, then handles the events already queued
+func (m *Monitor) eventProcessor(ctx context.Context) {
+	for {
+		select {
+		case event := <-m.eventStream:
+			m.handleEvent(event)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-m.eventStream:
+					m.handleEvent(event)
+				default:
+					return
+				}
+			}
 		}
-		m.mu.Unlock()
 	}
 }
 
+// handleEvent processes an event and adds it to the recent events
+func (m *Monitor) handleEvent(event *MonitorEvent) {
+	// Process event (logging, alerting, etc.)
+	m.processEvent(event)
+
+	// Add to recent events (keep only last N events)
+	m.mu.Lock()
+	m.status.RecentEvents = append(m.status.RecentEvents, *event)
+	if len(m.status.RecentEvents) > 100 { // Keep last 100 events
+		m.status.RecentEvents = m.status.RecentEvents[1:]
+	}
+	m.mu.Unlock()
+
+	m.subscribers.publish(event)
+}
+
 // sendEvent sends an event to the event stream
 func (m *Monitor) sendEvent(event *MonitorEvent) {
 	if event.ID == "" {
@@ -483,17 +648,97 @@ func (m *Monitor) sendEvent(event *MonitorEvent) {
 	case m.eventStream <- event:
 	default:
 		// Event stream full, drop event
+		m.logger.Warn("Event stream full, dropping event", logger.Fields{
+			"event_id":   event.ID,
+			"event_type": event.Type.String(),
+		})
 	}
 }
 
+// probeTimeout bounds each connectivity and DNS probe
+const probeTimeout = 5 * time.Second
+
 // Check functions (simplified implementations)
-func (m *Monitor) checkNetworkStatus() {
-	// Implementation would check actual network interfaces and connectivity
-	// For now, update timestamp
+func (m *Monitor) checkNetworkStatus(ctx context.Context) {
+	status := StatusOK
+	var connectivity ConnectivityResult
+	var failed []string
+
+	if m.connectivity != nil {
+		connectivity = m.connectivity.Test(ctx)
+		failed = m.connectivity.FailedComponents(connectivity)
+
+		switch {
+		case !connectivity.InternetAccess:
+			status = StatusError
+		case len(failed) > 0:
+			status = StatusWarning
+		}
+	}
+
 	m.mu.Lock()
-	m.status.NetworkStatus.Status = StatusOK
+	m.status.NetworkStatus.Status = status
+	m.status.NetworkStatus.ConnectivityTest = connectivity
 	m.status.Timestamp = time.Now()
+	previous := m.connectivityFailures
+	m.connectivityFailures = failed
 	m.mu.Unlock()
+
+	m.reportConnectivityChanges(previous, connectivity, failed)
+}
+
+// reportConnectivityChanges sends an event for each component that started
+// failing since the previous check, and one when all components recover
+func (m *Monitor) reportConnectivityChanges(previous []string, result ConnectivityResult, failed []string) {
+	wasFailing := make(map[string]bool, len(previous))
+	for _, component := range previous {
+		wasFailing[component] = true
+	}
+
+	for _, component := range failed {
+		if wasFailing[component] {
+			continue
+		}
+
+		severity := StatusError
+		message := strings.ToUpper(component) + " connectivity failed"
+		if component == ComponentDNS {
+			message = "DNS resolution failed"
+			if result.InternetAccess {
+				// The connectivity URLs still answer, so only name resolution is affected
+				severity = StatusWarning
+			}
+		}
+
+		details := map[string]interface{}{"connectivity_component": component}
+		for _, detail := range result.ErrorDetails {
+			if strings.HasPrefix(detail, component+": ") {
+				details["error"] = strings.TrimPrefix(detail, component+": ")
+			}
+		}
+
+		m.sendEvent(&MonitorEvent{
+			Type:      EventNetworkChange,
+			Timestamp: time.Now(),
+			Severity:  severity,
+			Component: "network",
+			Message:   message,
+			Details:   details,
+			Source:    "connectivity_test",
+			Tags:      []string{component},
+		})
+	}
+
+	if len(previous) > 0 && len(failed) == 0 {
+		m.sendEvent(&MonitorEvent{
+			Type:      EventNetworkChange,
+			Timestamp: time.Now(),
+			Severity:  StatusOK,
+			Component: "network",
+			Message:   "Connectivity restored",
+			Source:    "connectivity_test",
+		})
+	}
 }
 
 func (m *Monitor) checkVPNStatus() {
@@ -505,11 +750,37 @@ func (m *Monitor) checkVPNStatus() {
 }
 
 func (m *Monitor) checkDNSStatus() {
-	// Implementation would perform DNS resolution tests
+	status := StatusOK
+	var resolution DNSTestResult
+
+	if len(m.config.DNSTestDomains) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		resolution = ProbeDNS(ctx, net.DefaultResolver, m.config.DNSTestDomains)
+		cancel()
+
+		if !resolution.Successful {
+			status = StatusError
+		}
+	}
+
+	transport := m.probeDNSTransport()
+
 	m.mu.Lock()
-	m.status.DNSStatus.Status = StatusOK
+	plaintextOverVPN := transport == TransportPlaintext && m.status.VPNStatus.Connected
+	if plaintextOverVPN && status == StatusOK {
+		status = StatusWarning
+	}
+	m.status.DNSStatus.Status = status
+	m.status.DNSStatus.ResolutionTest = resolution
+	m.status.DNSStatus.EncryptedTransport = transport
 	m.status.Timestamp = time.Now()
+	warn := plaintextOverVPN && !m.plaintextDNSWarned
+	m.plaintextDNSWarned = plaintextOverVPN
 	m.mu.Unlock()
+
+	if warn {
+		m.reportPlaintextDNS()
+	}
 }
 
 func (m *Monitor) checkCaptivePortalStatus() {
@@ -523,8 +794,24 @@ func (m *Monitor) checkCaptivePortalStatus() {
 
 // processEvent processes a monitoring event
 func (m *Monitor) processEvent(event *MonitorEvent) {
-	// Log event, send notifications, update metrics, etc.
-	// This would contain actual event processing logic
+	fields := logger.Fields{}
+	for key, value := range event.Details {
+		fields[key] = value
+	}
+	fields["component"] = event.Component
+	fields["event_id"] = event.ID
+	fields["event_type"] = event.Type.String()
+	fields["severity"] = event.Severity.String()
+	fields["source"] = event.Source
+
+	switch event.Severity {
+	case StatusOK:
+		m.logger.Info(event.Message, fields)
+	case StatusWarning:
+		m.logger.Warn(event.Message, fields)
+	default:
+		m.logger.Error(event.Message, fields)
+	}
 }
 
 // Helper functions
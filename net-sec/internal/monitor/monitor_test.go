@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+	"go.uber.org/goleak"
+)
+
+func TestShutdownStopsMonitorGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	err := m.Initialize(&MonitorConfig{
+		NetworkCheckInterval: time.Millisecond,
+		VPNCheckInterval:     time.Millisecond,
+		DNSCheckInterval:     time.Millisecond,
+		CaptiveCheckInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queued events, including the shutdown event, were handled
+	events := m.GetStatus().RecentEvents
+	if len(events) == 0 || events[len(events)-1].Type != EventSystemShutdown {
+		t.Errorf("expected the shutdown event to be recorded last, got %+v", events)
+	}
+
+	// Further calls are no-ops
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("second shutdown failed: %v", err)
+	}
+	if err := m.Stop(); err == nil {
+		t.Error("expected Stop on a shut down monitor to fail")
+	}
+}
+
+func TestGetStatusReturnsIsolatedSnapshots(t *testing.T) {
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	err := m.Initialize(&MonitorConfig{
+		NetworkCheckInterval: time.Hour,
+		VPNCheckInterval:     time.Hour,
+		DNSCheckInterval:     time.Hour,
+		CaptiveCheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			m.AddAlert(Alert{Title: "alert", Metadata: map[string]interface{}{"n": i}, Actions: []string{"notify"}})
+		}
+	}()
+
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+
+		status := m.GetStatus()
+		alerts, events := len(status.ActiveAlerts), len(status.RecentEvents)
+
+		// Writing to the snapshot must not touch the live status
+		for i := range status.ActiveAlerts {
+			status.ActiveAlerts[i].Metadata["n"] = -1
+			status.ActiveAlerts[i].Actions[0] = "tampered"
+		}
+		for i := range status.RecentEvents {
+			status.RecentEvents[i].Message = "tampered"
+			if status.RecentEvents[i].Details != nil {
+				status.RecentEvents[i].Details["alert_id"] = "tampered"
+			}
+		}
+		status.ActiveAlerts = append(status.ActiveAlerts, Alert{Title: "injected"})
+
+		if len(status.ActiveAlerts) != alerts+1 || len(status.RecentEvents) != events {
+			t.Fatal("snapshot changed while it was being read")
+		}
+	}
+
+	status := m.GetStatus()
+	if len(status.ActiveAlerts) != 200 {
+		t.Fatalf("expected 200 alerts, got %d", len(status.ActiveAlerts))
+	}
+	for i, alert := range status.ActiveAlerts {
+		if alert.Title != "alert" || alert.Metadata["n"] != i || alert.Actions[0] != "notify" {
+			t.Fatalf("live alert %d was modified through a snapshot: %+v", i, alert)
+		}
+	}
+	for _, event := range status.RecentEvents {
+		if event.Message == "tampered" || event.Details["alert_id"] == "tampered" {
+			t.Fatalf("live event was modified through a snapshot: %+v", event)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package monitor
+
+import "time"
+
+// aggregateStatus returns the most severe of the subsystem statuses and
+// the unresolved alerts
+func (s *SystemStatus) aggregateStatus() StatusLevel {
+	overall := StatusOK
+	for _, level := range []StatusLevel{
+		s.NetworkStatus.Status,
+		s.VPNStatus.Status,
+		s.DNSStatus.Status,
+		s.CaptiveStatus.Status,
+	} {
+		if level > overall {
+			overall = level
+		}
+	}
+
+	for _, alert := range s.ActiveAlerts {
+		if !alert.Resolved && alert.Severity > overall {
+			overall = alert.Severity
+		}
+	}
+	return overall
+}
+
+// updateOverallStatus recomputes OverallStatus after a check and sends an
+// EventStatusChange event when the level changes
+func (m *Monitor) updateOverallStatus() {
+	m.mu.Lock()
+	previous := m.status.OverallStatus
+	overall := m.status.aggregateStatus()
+	m.status.OverallStatus = overall
+	m.mu.Unlock()
+
+	if overall == previous {
+		return
+	}
+
+	m.sendEvent(&MonitorEvent{
+		Type:      EventStatusChange,
+		Timestamp: time.Now(),
+		Severity:  overall,
+		Component: "monitor",
+		Message:   "Overall status changed from " + previous.String() + " to " + overall.String(),
+		Details: map[string]interface{}{
+			"previous_status": previous.String(),
+			"overall_status":  overall.String(),
+		},
+		Source: "status_aggregation",
+	})
+}
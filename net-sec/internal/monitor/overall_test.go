@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+func TestOverallStatusReflectsMostSevereSubsystem(t *testing.T) {
+	m := NewMonitor()
+	m.SetLogger(logger.New("error", "text", io.Discard).Component("monitor"))
+	if err := m.Initialize(&MonitorConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name   string
+		update func(s *SystemStatus)
+		want   StatusLevel
+		change bool
+	}{
+		{"all ok", func(s *SystemStatus) {}, StatusOK, false},
+		{"dns warning", func(s *SystemStatus) { s.DNSStatus.Status = StatusWarning }, StatusWarning, true},
+		{"vpn error", func(s *SystemStatus) { s.VPNStatus.Status = StatusError }, StatusError, true},
+		{"captive warning under vpn error", func(s *SystemStatus) { s.CaptiveStatus.Status = StatusWarning }, StatusError, false},
+		{"network critical", func(s *SystemStatus) { s.NetworkStatus.Status = StatusCritical }, StatusCritical, true},
+		{"recovered", func(s *SystemStatus) {
+			s.NetworkStatus.Status, s.VPNStatus.Status = StatusOK, StatusOK
+			s.DNSStatus.Status, s.CaptiveStatus.Status = StatusOK, StatusOK
+		}, StatusOK, true},
+		{"unresolved alert", func(s *SystemStatus) {
+			s.ActiveAlerts = append(s.ActiveAlerts, Alert{Severity: StatusError})
+		}, StatusError, true},
+		{"resolved alert", func(s *SystemStatus) { s.ActiveAlerts[0].Resolved = true }, StatusOK, true},
+	}
+
+	for _, step := range steps {
+		m.mu.Lock()
+		step.update(m.status)
+		previous := m.status.OverallStatus
+		m.mu.Unlock()
+
+		m.updateOverallStatus()
+
+		if got := m.GetStatus().OverallStatus; got != step.want {
+			t.Errorf("%s: expected overall %s, got %s", step.name, step.want, got)
+		}
+		if !step.change {
+			if len(m.eventStream) != 0 {
+				t.Errorf("%s: expected no transition event, got %d", step.name, len(m.eventStream))
+			}
+			continue
+		}
+
+		if len(m.eventStream) != 1 {
+			t.Fatalf("%s: expected one transition event, got %d", step.name, len(m.eventStream))
+		}
+		event := <-m.eventStream
+		if event.Type != EventStatusChange || event.Severity != step.want ||
+			event.Details["previous_status"] != previous.String() || event.Details["overall_status"] != step.want.String() {
+			t.Errorf("%s: unexpected transition event %+v", step.name, event)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Resolver resolves a hostname to its IP addresses
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// ServerResolver returns a resolver that sends its queries directly to the
+// DNS server at addr, bypassing the system resolver configuration
+func ServerResolver(addr string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// ProbeDNS resolves each domain with resolver and reports which failed
+func ProbeDNS(ctx context.Context, resolver Resolver, domains []string) DNSTestResult {
+	result := DNSTestResult{
+		TestedDomains: domains,
+		TestTimestamp: time.Now(),
+	}
+
+	var total time.Duration
+	for _, domain := range domains {
+		start := time.Now()
+		_, err := resolver.LookupHost(ctx, domain)
+		total += time.Since(start)
+
+		if err != nil {
+			result.FailedDomains = append(result.FailedDomains, domain)
+		}
+	}
+
+	if len(domains) > 0 {
+		result.AverageTime = total / time.Duration(len(domains))
+	}
+	result.Successful = len(domains) > 0 && len(result.FailedDomains) == 0
+
+	return result
+}
+
+// ProbeHTTP requests url and reports whether it answered with expectedStatus
+func ProbeHTTP(ctx context.Context, client *http.Client, url string, expectedStatus int) (result ConnectivityResult) {
+	start := time.Now()
+	result.TestTimestamp = start
+	defer func() { result.TestDuration = time.Since(start) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.ErrorDetails = append(result.ErrorDetails, err.Error())
+		return result
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.ErrorDetails = append(result.ErrorDetails, err.Error())
+		return result
+	}
+	resp.Body.Close()
+
+	// The request reached the server, so its name resolved
+	result.DNSResolution = true
+	if req.URL.Scheme == "https" {
+		result.HTTPSConnectivity = true
+	} else {
+		result.HTTPConnectivity = true
+	}
+
+	if resp.StatusCode != expectedStatus {
+		result.ErrorDetails = append(result.ErrorDetails,
+			fmt.Sprintf("expected status %d, got %d", expectedStatus, resp.StatusCode))
+		return result
+	}
+
+	result.InternetAccess = true
+	return result
+}
@@ -0,0 +1,105 @@
+package monitor
+
+import "time"
+
+// clone returns a deep copy of the status, so callers outside the monitor
+// never share slices or maps with the live status. Detail and metadata maps
+// are copied one level deep; their values are treated as immutable once an
+// event or alert is created.
+func (s *SystemStatus) clone() SystemStatus {
+	c := *s
+	c.NetworkStatus = s.NetworkStatus.clone()
+	c.VPNStatus.IPLeakTest.DetectedIPs = cloneStrings(s.VPNStatus.IPLeakTest.DetectedIPs)
+	c.VPNStatus.IPLeakTest.TestSources = cloneStrings(s.VPNStatus.IPLeakTest.TestSources)
+	c.DNSStatus = s.DNSStatus.clone()
+	c.CaptiveStatus.PortalInfo = s.CaptiveStatus.PortalInfo.clone()
+	if s.SystemMetrics.LoadAverage != nil {
+		c.SystemMetrics.LoadAverage = append([]float64(nil), s.SystemMetrics.LoadAverage...)
+	}
+
+	if s.ActiveAlerts != nil {
+		c.ActiveAlerts = make([]Alert, len(s.ActiveAlerts))
+		for i, alert := range s.ActiveAlerts {
+			c.ActiveAlerts[i] = alert.clone()
+		}
+	}
+	if s.RecentEvents != nil {
+		c.RecentEvents = make([]MonitorEvent, len(s.RecentEvents))
+		for i, event := range s.RecentEvents {
+			c.RecentEvents[i] = event.clone()
+		}
+	}
+	return c
+}
+
+func (n NetworkStatus) clone() NetworkStatus {
+	n.PrimaryInterface.DNS = cloneStrings(n.PrimaryInterface.DNS)
+	n.BackupInterface.DNS = cloneStrings(n.BackupInterface.DNS)
+	n.ConnectivityTest.ErrorDetails = cloneStrings(n.ConnectivityTest.ErrorDetails)
+	return n
+}
+
+func (d DNSStatus) clone() DNSStatus {
+	d.ConfiguredServers = cloneStrings(d.ConfiguredServers)
+	d.ActiveServers = cloneStrings(d.ActiveServers)
+	d.ResolutionTest.TestedDomains = cloneStrings(d.ResolutionTest.TestedDomains)
+	d.ResolutionTest.FailedDomains = cloneStrings(d.ResolutionTest.FailedDomains)
+	d.LeakTest.DetectedServers = cloneStrings(d.LeakTest.DetectedServers)
+	d.LeakTest.ExpectedServers = cloneStrings(d.LeakTest.ExpectedServers)
+	if d.ResponseTimes != nil {
+		times := make(map[string]time.Duration, len(d.ResponseTimes))
+		for server, rtt := range d.ResponseTimes {
+			times[server] = rtt
+		}
+		d.ResponseTimes = times
+	}
+	return d
+}
+
+func (p *PortalInfo) clone() *PortalInfo {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	if p.Headers != nil {
+		c.Headers = make(map[string]string, len(p.Headers))
+		for k, v := range p.Headers {
+			c.Headers[k] = v
+		}
+	}
+	return &c
+}
+
+func (a Alert) clone() Alert {
+	a.Actions = cloneStrings(a.Actions)
+	a.Metadata = cloneDetails(a.Metadata)
+	if a.ResolvedAt != nil {
+		resolvedAt := *a.ResolvedAt
+		a.ResolvedAt = &resolvedAt
+	}
+	return a
+}
+
+func (e MonitorEvent) clone() MonitorEvent {
+	e.Details = cloneDetails(e.Details)
+	e.Tags = cloneStrings(e.Tags)
+	return e
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
+func cloneDetails(details map[string]interface{}) map[string]interface{} {
+	if details == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(details))
+	for k, v := range details {
+		c[k] = v
+	}
+	return c
+}
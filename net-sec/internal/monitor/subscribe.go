@@ -0,0 +1,75 @@
+package monitor
+
+import "sync"
+
+// subscriberBufferSize is the number of events buffered per subscriber;
+// events for a subscriber whose buffer is full are dropped
+const subscriberBufferSize = 64
+
+// subscribers fans handled events out to subscription channels
+type subscribers struct {
+	mu     sync.Mutex
+	chans  map[int]chan *MonitorEvent
+	nextID int
+	closed bool
+}
+
+// Subscribe returns a channel receiving every event handled by the monitor
+// from now on, and a function ending the subscription. The channel is
+// closed when the subscription ends or the monitor shuts down.
+func (m *Monitor) Subscribe() (<-chan *MonitorEvent, func()) {
+	s := &m.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan *MonitorEvent, subscriberBufferSize)
+	if s.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if s.chans == nil {
+		s.chans = make(map[int]chan *MonitorEvent)
+	}
+
+	id := s.nextID
+	s.nextID++
+	s.chans[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if _, ok := s.chans[id]; ok {
+				delete(s.chans, id)
+				close(ch)
+			}
+		})
+	}
+}
+
+// publish sends event to every subscriber without blocking
+func (s *subscribers) publish(event *MonitorEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ch := range s.chans {
+		eventCopy := *event
+		select {
+		case ch <- &eventCopy:
+		default:
+		}
+	}
+}
+
+// close ends every subscription; later subscriptions are closed at once
+func (s *subscribers) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, ch := range s.chans {
+		delete(s.chans, id)
+		close(ch)
+	}
+	s.closed = true
+}
@@ -0,0 +1,197 @@
+package multipath
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Interface types recognised by DetectInterfaces
+const (
+	TypeEthernet = "ethernet"
+	TypeWiFi     = "wifi"
+	TypeLTE      = "lte"
+)
+
+// typePreference ranks interface types for primary selection: wired links
+// first, metered cellular links last
+var typePreference = []string{TypeEthernet, TypeWiFi, TypeLTE}
+
+// NetInterface describes a network interface as reported by the OS
+type NetInterface struct {
+	Name    string
+	Type    string // TypeEthernet, TypeWiFi, TypeLTE or empty when unknown
+	Up      bool   // administratively up
+	Running bool   // link has carrier
+}
+
+// InterfaceSource lists the network interfaces of the host
+type InterfaceSource interface {
+	Interfaces() ([]NetInterface, error)
+}
+
+// DetectInterfaces picks a primary and backup interface from the host's
+// interfaces. Interfaces that are down or of unknown type (loopback, VPN
+// tunnels, bridges) are ignored. backup is empty when only one usable
+// interface exists.
+func DetectInterfaces() (primary, backup string, err error) {
+	return DetectInterfacesFrom(OSInterfaceSource{})
+}
+
+// DetectInterfacesFrom picks a primary and backup interface from source.
+// Interfaces with carrier beat those without, then ethernet beats wifi beats
+// lte; the backup is the best remaining interface, preferring one of a
+// different type than the primary.
+func DetectInterfacesFrom(source InterfaceSource) (primary, backup string, err error) {
+	interfaces, err := source.Interfaces()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var candidates []NetInterface
+	for _, iface := range interfaces {
+		if iface.Up && typeRank(iface.Type) >= 0 {
+			candidates = append(candidates, iface)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", "", fmt.Errorf("no usable network interface found")
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Running != b.Running {
+			return a.Running
+		}
+		if ra, rb := typeRank(a.Type), typeRank(b.Type); ra != rb {
+			return ra < rb
+		}
+		return a.Name < b.Name
+	})
+
+	first := candidates[0]
+	for _, iface := range candidates[1:] {
+		if iface.Type != first.Type {
+			return first.Name, iface.Name, nil
+		}
+	}
+	if len(candidates) > 1 {
+		return first.Name, candidates[1].Name, nil
+	}
+	return first.Name, "", nil
+}
+
+func typeRank(interfaceType string) int {
+	for i, t := range typePreference {
+		if t == interfaceType {
+			return i
+		}
+	}
+	return -1
+}
+
+// OSInterfaceSource lists interfaces with net.Interfaces. On Linux the type
+// comes from /sys/class/net; elsewhere it is inferred from the interface
+// name.
+type OSInterfaceSource struct {
+	// SysfsRoot overrides /sys/class/net
+	SysfsRoot string
+}
+
+// Interfaces returns the host's interfaces with their type and link state
+func (s OSInterfaceSource) Interfaces() ([]NetInterface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	root := s.SysfsRoot
+	if root == "" {
+		root = "/sys/class/net"
+	}
+	_, statErr := os.Stat(root)
+	useSysfs := statErr == nil
+
+	result := make([]NetInterface, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		info := NetInterface{
+			Name:    iface.Name,
+			Up:      iface.Flags&net.FlagUp != 0,
+			Running: iface.Flags&net.FlagRunning != 0,
+		}
+		if useSysfs {
+			info.Type = classifySysfs(filepath.Join(root, iface.Name))
+		} else {
+			info.Type = classifyName(iface.Name)
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// ARP hardware types from include/uapi/linux/if_arp.h
+const (
+	arphrdEther = 1
+	arphrdPPP   = 512
+	arphrdRawIP = 519
+)
+
+// classifySysfs derives an interface type from its /sys/class/net entry.
+// Virtual interfaces have no device link and are left unclassified.
+func classifySysfs(dir string) string {
+	if exists(filepath.Join(dir, "wireless")) || exists(filepath.Join(dir, "phy80211")) {
+		return TypeWiFi
+	}
+
+	uevent, _ := os.ReadFile(filepath.Join(dir, "device", "uevent"))
+	if strings.Contains(string(uevent), "DEVTYPE=wwan") {
+		return TypeLTE
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "type"))
+	if err != nil {
+		return ""
+	}
+	hwType, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return ""
+	}
+
+	switch hwType {
+	case arphrdPPP, arphrdRawIP:
+		return TypeLTE
+	case arphrdEther:
+		if exists(filepath.Join(dir, "device")) {
+			return TypeEthernet
+		}
+	}
+	return ""
+}
+
+// classifyName infers an interface type from common naming schemes
+func classifyName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(lower, "wl"), strings.HasPrefix(lower, "wi-fi"), strings.HasPrefix(lower, "wifi"):
+		return TypeWiFi
+	case strings.HasPrefix(lower, "ppp"), strings.HasPrefix(lower, "wwan"), strings.HasPrefix(lower, "wwp"),
+		strings.HasPrefix(lower, "rmnet"), strings.HasPrefix(lower, "pdp_ip"):
+		return TypeLTE
+	case strings.HasPrefix(lower, "eth"), strings.HasPrefix(lower, "en"):
+		return TypeEthernet
+	}
+	return ""
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,127 @@
+package multipath
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// staticSource returns a fixed set of interfaces
+type staticSource struct {
+	interfaces []NetInterface
+	err        error
+}
+
+func (s staticSource) Interfaces() ([]NetInterface, error) { return s.interfaces, s.err }
+
+func TestDetectInterfacesFrom(t *testing.T) {
+	tests := []struct {
+		name            string
+		interfaces      []NetInterface
+		primary, backup string
+	}{
+		{
+			name: "ethernet preferred over wifi and lte",
+			interfaces: []NetInterface{
+				{Name: "wwan0", Type: TypeLTE, Up: true, Running: true},
+				{Name: "wlan0", Type: TypeWiFi, Up: true, Running: true},
+				{Name: "eth0", Type: TypeEthernet, Up: true, Running: true},
+			},
+			primary: "eth0", backup: "wlan0",
+		},
+		{
+			name: "link state beats type",
+			interfaces: []NetInterface{
+				{Name: "eth0", Type: TypeEthernet, Up: true},
+				{Name: "wlan0", Type: TypeWiFi, Up: true, Running: true},
+			},
+			primary: "wlan0", backup: "eth0",
+		},
+		{
+			name: "backup prefers a different type",
+			interfaces: []NetInterface{
+				{Name: "eth1", Type: TypeEthernet, Up: true, Running: true},
+				{Name: "eth0", Type: TypeEthernet, Up: true, Running: true},
+				{Name: "wwan0", Type: TypeLTE, Up: true, Running: true},
+			},
+			primary: "eth0", backup: "wwan0",
+		},
+		{
+			name: "same type backup when nothing else is usable",
+			interfaces: []NetInterface{
+				{Name: "eth1", Type: TypeEthernet, Up: true, Running: true},
+				{Name: "eth0", Type: TypeEthernet, Up: true, Running: true},
+				{Name: "wlan0", Type: TypeWiFi},
+			},
+			primary: "eth0", backup: "eth1",
+		},
+		{
+			name: "down and unknown interfaces are ignored",
+			interfaces: []NetInterface{
+				{Name: "docker0", Up: true, Running: true},
+				{Name: "eth0", Type: TypeEthernet},
+				{Name: "wlan0", Type: TypeWiFi, Up: true, Running: true},
+			},
+			primary: "wlan0", backup: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, backup, err := DetectInterfacesFrom(staticSource{interfaces: tt.interfaces})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if primary != tt.primary || backup != tt.backup {
+				t.Errorf("expected %q/%q, got %q/%q", tt.primary, tt.backup, primary, backup)
+			}
+		})
+	}
+}
+
+func TestDetectInterfacesFromErrors(t *testing.T) {
+	if _, _, err := DetectInterfacesFrom(staticSource{err: fmt.Errorf("permission denied")}); err == nil {
+		t.Error("expected the source error to be returned")
+	}
+	if _, _, err := DetectInterfacesFrom(staticSource{interfaces: []NetInterface{{Name: "tun0", Up: true}}}); err == nil {
+		t.Error("expected an error without usable interfaces")
+	}
+}
+
+func TestClassifySysfs(t *testing.T) {
+	root := t.TempDir()
+	entry := func(name, hwType string, files ...string) string {
+		dir := filepath.Join(root, name)
+		for _, file := range append(files, "type") {
+			path := filepath.Join(dir, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			content := ""
+			switch file {
+			case "type":
+				content = hwType + "\n"
+			case "device/uevent":
+				content = "DEVTYPE=wwan\n"
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	for dir, want := range map[string]string{
+		entry("enp3s0", "1", "device/vendor"): TypeEthernet,
+		entry("wlp2s0", "1", "wireless/x"):    TypeWiFi,
+		entry("wwan0", "1", "device/uevent"):  TypeLTE,
+		entry("ppp0", "512"):                  TypeLTE,
+		entry("docker0", "1"):                 "",
+		entry("tun0", "65534"):                "",
+	} {
+		if got := classifySysfs(dir); got != want {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(dir), want, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package multipath
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/firewall"
+)
+
+// KillSwitchRules returns the firewall rules confining outbound traffic to
+// the active interface on platform, so nothing leaks through the other
+// interface during a transition. Windows rules are built from the
+// interface's current addresses.
+func (m *Manager) KillSwitchRules(platform string) (*firewall.RuleSet, error) {
+	m.mu.RLock()
+	enabled := m.options != nil && m.options.EnableKillSwitch
+	active := m.status.ActiveInterface
+	m.mu.RUnlock()
+
+	if !enabled {
+		return nil, fmt.Errorf("kill switch is not enabled")
+	}
+	if active == "" {
+		return nil, fmt.Errorf("no active interface")
+	}
+
+	builder, err := firewall.NewRuleBuilder(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	killSwitch := &firewall.KillSwitch{Interface: active}
+	if platform == firewall.PlatformWindows {
+		if killSwitch.LocalAddresses, err = m.interfaceAddrs(active); err != nil {
+			return nil, fmt.Errorf("failed to read addresses of %s: %w", active, err)
+		}
+	}
+
+	return builder.Build(firewall.Spec{Name: "multipath", KillSwitch: killSwitch})
+}
+
+// interfaceAddresses returns the IP addresses assigned to an interface
+func interfaceAddresses(name string) ([]string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			addresses = append(addresses, ipNet.IP.String())
+		}
+	}
+	return addresses, nil
+}
+
+// applyKillSwitch replaces the applied kill-switch rules with rules
+// confining traffic to the active interface. It does nothing unless the
+// kill switch is enabled and the manager is running, so a failover racing
+// Stop cannot leave rules behind. Rules that were only partly applied are
+// kept so removeKillSwitch still cleans them up.
+func (m *Manager) applyKillSwitch() error {
+	m.killSwitchMu.Lock()
+	defer m.killSwitchMu.Unlock()
+
+	m.mu.RLock()
+	enabled := m.options != nil && m.options.EnableKillSwitch && m.running
+	m.mu.RUnlock()
+	if !enabled {
+		return nil
+	}
+
+	if err := m.removeKillSwitchLocked(); err != nil {
+		return err
+	}
+
+	set, err := m.KillSwitchRules(runtime.GOOS)
+	if err != nil {
+		return err
+	}
+
+	m.killSwitch = set
+	for _, command := range set.Apply {
+		if err := m.runCommand(command); err != nil {
+			return fmt.Errorf("failed to apply kill switch: %w", err)
+		}
+	}
+
+	m.sendKillSwitchEvent(EventKillSwitchActivated, "Outbound traffic confined to "+m.GetStatus().ActiveInterface)
+	return nil
+}
+
+// removeKillSwitch removes the applied kill-switch rules, if any
+func (m *Manager) removeKillSwitch() error {
+	m.killSwitchMu.Lock()
+	defer m.killSwitchMu.Unlock()
+	return m.removeKillSwitchLocked()
+}
+
+// removeKillSwitchLocked runs every remove command of the applied rules,
+// even after one fails, so as much as possible is restored. Callers must
+// hold m.killSwitchMu.
+func (m *Manager) removeKillSwitchLocked() error {
+	if m.killSwitch == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, command := range m.killSwitch.Remove {
+		if err := m.runCommand(command); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.killSwitch = nil
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to remove kill switch: %w", err)
+	}
+
+	m.sendKillSwitchEvent(EventKillSwitchDeactivated, "Kill-switch rules removed")
+	return nil
+}
+
+// sendKillSwitchEvent reports a kill-switch change on the status channel
+func (m *Manager) sendKillSwitchEvent(eventType EventType, reason string) {
+	select {
+	case m.eventChan <- &StatusEvent{Type: eventType, Timestamp: time.Now(), Reason: reason}:
+	default:
+	}
+}
+
+// runShellCommand runs a firewall command through the platform shell
+func runShellCommand(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", command, err, output)
+	}
+	return nil
+}
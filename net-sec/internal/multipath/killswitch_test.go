@@ -0,0 +1,100 @@
+package multipath
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/firewall"
+)
+
+func TestKillSwitchRulesFollowActiveInterface(t *testing.T) {
+	m := newTestManager(t, &Options{EnableKillSwitch: true})
+	m.interfaceAddrs = func(name string) ([]string, error) {
+		return map[string][]string{"wlan0": {"192.168.1.20"}, "eth0": {"10.0.0.5"}}[name], nil
+	}
+
+	set, err := m.KillSwitchRules(firewall.PlatformLinux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Apply) != 2 || !strings.Contains(set.Apply[0], "! -o wlan0") {
+		t.Errorf("expected traffic confined to wlan0, got %q", set.Apply)
+	}
+
+	m.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed")
+
+	set, err = m.KillSwitchRules(firewall.PlatformDarwin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(set.Apply[0], "'pass out quick on eth0 all'") {
+		t.Errorf("expected pf to pass only eth0 after failover, got %q", set.Apply)
+	}
+
+	set, err = m.KillSwitchRules(firewall.PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(set.Apply, "\n"), "localip=10.0.0.5") {
+		t.Errorf("expected netsh to allow eth0's address, got %q", set.Apply)
+	}
+}
+
+func TestKillSwitchRulesRequireKillSwitch(t *testing.T) {
+	m := newTestManager(t, &Options{})
+	if _, err := m.KillSwitchRules(firewall.PlatformLinux); err == nil {
+		t.Error("expected an error when the kill switch is disabled")
+	}
+}
+
+func TestKillSwitchAppliedOnFailoverAndRemovedOnStop(t *testing.T) {
+	m := newTestManager(t, &Options{EnableKillSwitch: true, CheckInterval: time.Hour})
+	m.interfaceAddrs = func(name string) ([]string, error) {
+		return map[string][]string{"wlan0": {"192.168.1.20"}, "eth0": {"10.0.0.5"}}[name], nil
+	}
+	var commands []string
+	m.runCommand = func(command string) error {
+		commands = append(commands, command)
+		return nil
+	}
+
+	// Nothing is applied before the manager runs
+	m.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed")
+	if len(commands) != 0 {
+		t.Fatalf("expected no commands while stopped, got %q", commands)
+	}
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m.performFailover(EventRecovery, "eth0", "wlan0", "Primary interface recovered")
+	toPrimary, err := m.KillSwitchRules(runtime.GOOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(commands, toPrimary.Apply) {
+		t.Fatalf("expected the wlan0 rules to be applied, got %q", commands)
+	}
+
+	// A later failover removes the previous rules before applying new ones
+	commands = nil
+	m.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed")
+	toBackup, err := m.KillSwitchRules(runtime.GOOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append([]string(nil), toPrimary.Remove...), toBackup.Apply...); !reflect.DeepEqual(commands, want) {
+		t.Errorf("expected the wlan0 rules to be replaced, got %q", commands)
+	}
+
+	commands = nil
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(commands, toBackup.Remove) {
+		t.Errorf("expected Stop to remove the eth0 rules, got %q", commands)
+	}
+}
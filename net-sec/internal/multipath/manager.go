@@ -1,20 +1,37 @@
 package multipath
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 	"time"
+
+	"github.com/stealthguard/net-sec/internal/firewall"
 )
 
+// defaultHistorySize is the number of failover events kept in memory when
+// Options.HistorySize is not set
+const defaultHistorySize = 100
+
 // Manager handles multipath networking operations
 type Manager struct {
 	options   *Options
 	status    *Status
 	eventChan chan *StatusEvent
 	stopChan  chan bool
+	history   []StatusEvent
 	mu        sync.RWMutex
 	running   bool
+
+	interfaceAddrs func(name string) ([]string, error) // Addresses for Windows kill-switch rules
+	runCommand     func(command string) error          // Runs kill-switch firewall commands
+	killSwitch     *firewall.RuleSet                   // Applied kill-switch rules; guarded by killSwitchMu
+	killSwitchMu   sync.Mutex                          // Serializes applying and removing the kill switch
 }
 
 // Options contains multipath configuration options
@@ -29,6 +46,8 @@ type Options struct {
 	EnableKillSwitch  bool
 	DNSServers        []string
 	RoutingTable      string
+	HistorySize       int
+	HistoryFile       string
 }
 
 // Status represents the current multipath status
@@ -114,6 +133,8 @@ func NewManager() *Manager {
 			Primary: InterfaceStatus{Status: "unknown"},
 			Backup:  InterfaceStatus{Status: "unknown"},
 		},
+		interfaceAddrs: interfaceAddresses,
+		runCommand:     runShellCommand,
 	}
 }
 
@@ -151,6 +172,13 @@ func (m *Manager) Initialize(opts *Options) error {
 	// Set initial active interface
 	m.status.ActiveInterface = opts.PrimaryInterface
 
+	// Restore failover history persisted by previous runs
+	if opts.HistoryFile != "" {
+		if err := m.loadHistory(opts.HistoryFile); err != nil {
+			return fmt.Errorf("failed to load failover history: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -170,19 +198,19 @@ func (m *Manager) Start() error {
 	return nil
 }
 
-// Stop stops multipath monitoring
+// Stop stops multipath monitoring and removes the kill switch
 func (m *Manager) Stop() error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if !m.running {
+		m.mu.Unlock()
 		return fmt.Errorf("multipath manager is not running")
 	}
 
 	m.stopChan <- true
 	m.running = false
+	m.mu.Unlock()
 
-	return nil
+	return m.removeKillSwitch()
 }
 
 // StartDaemon starts the multipath manager as a background daemon
@@ -199,6 +227,21 @@ func (m *Manager) GetStatus() Status {
 	return *m.status
 }
 
+// GetHistory returns the recorded failover and recovery events that occurred
+// at or after since, oldest first
+func (m *Manager) GetHistory(since time.Time) []StatusEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := make([]StatusEvent, 0, len(m.history))
+	for _, event := range m.history {
+		if !event.Timestamp.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
 // GetStatusChannel returns the event channel for monitoring status changes
 func (m *Manager) GetStatusChannel() chan *StatusEvent {
 	return m.eventChan
@@ -331,7 +374,7 @@ func (m *Manager) evaluateFailover(primaryHealthy, backupHealthy bool, primaryFa
 		primaryFailCount >= m.options.FailoverThreshold &&
 		backupHealthy {
 
-		m.performFailover(m.options.PrimaryInterface, m.options.BackupInterface, "Primary interface failed")
+		m.performFailover(EventFailover, m.options.PrimaryInterface, m.options.BackupInterface, "Primary interface failed")
 	}
 
 	// Recovery from backup to primary
@@ -339,27 +382,45 @@ func (m *Manager) evaluateFailover(primaryHealthy, backupHealthy bool, primaryFa
 		backupSuccessCount >= m.options.RecoveryThreshold &&
 		primaryHealthy {
 
-		m.performFailover(m.options.BackupInterface, m.options.PrimaryInterface, "Primary interface recovered")
+		m.performFailover(EventRecovery, m.options.BackupInterface, m.options.PrimaryInterface, "Primary interface recovered")
 	}
 }
 
 // performFailover executes a failover between interfaces
-func (m *Manager) performFailover(from, to, reason string) {
+func (m *Manager) performFailover(eventType EventType, from, to, reason string) {
+	now := time.Now()
+	event := &StatusEvent{
+		Type:          eventType,
+		Timestamp:     now,
+		FromInterface: from,
+		ToInterface:   to,
+		Reason:        reason,
+	}
+
 	m.mu.Lock()
 	m.status.ActiveInterface = to
 	m.status.FailoverCount++
-	m.status.LastFailover = time.Now()
+	m.status.LastFailover = now
+	m.appendHistory(*event)
+	historyFile := m.options.HistoryFile
 	m.mu.Unlock()
 
-	// Send event
-	event := &StatusEvent{
-		Type:          EventFailover,
-		Timestamp:     time.Now(),
-		FromInterface: from,
-		ToInterface:   to,
-		Reason:        reason,
+	// Persist event so the failover record survives restarts
+	if historyFile != "" {
+		if err := appendHistoryFile(historyFile, event); err != nil {
+			errorEvent := &StatusEvent{
+				Type:      eventType,
+				Timestamp: time.Now(),
+				Reason:    fmt.Sprintf("Failed to persist failover history: %v", err),
+			}
+			select {
+			case m.eventChan <- errorEvent:
+			default:
+			}
+		}
 	}
 
+	// Send event
 	select {
 	case m.eventChan <- event:
 	default:
@@ -379,6 +440,19 @@ func (m *Manager) performFailover(from, to, reason string) {
 		default:
 		}
 	}
+
+	// Confine traffic to the new interface
+	if err := m.applyKillSwitch(); err != nil {
+		errorEvent := &StatusEvent{
+			Type:      EventKillSwitchActivated,
+			Timestamp: time.Now(),
+			Reason:    fmt.Sprintf("Failed to apply kill switch: %v", err),
+		}
+		select {
+		case m.eventChan <- errorEvent:
+		default:
+		}
+	}
 }
 
 // updateRouting updates system routing to use the specified interface
@@ -394,3 +468,88 @@ func (m *Manager) updateRouting(activeInterface string) error {
 
 	return nil
 }
+
+// appendHistory adds an event to the bounded in-memory history.
+// Callers must hold m.mu.
+func (m *Manager) appendHistory(event StatusEvent) {
+	limit := defaultHistorySize
+	if m.options != nil && m.options.HistorySize > 0 {
+		limit = m.options.HistorySize
+	}
+
+	m.history = append(m.history, event)
+	if len(m.history) > limit {
+		m.history = append([]StatusEvent(nil), m.history[len(m.history)-limit:]...)
+	}
+}
+
+// loadHistory restores failover history and counters from an append-only
+// history file. A missing file is not an error. A torn last entry, left by a
+// crash mid-write, is truncated away so later entries append cleanly; an
+// invalid entry anywhere else is an error. Callers must hold m.mu.
+func (m *Manager) loadHistory(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var good int64 // Offset just past the last valid entry
+	var torn error
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		if entry := bytes.TrimSpace(line); len(entry) > 0 {
+			if torn != nil {
+				return fmt.Errorf("invalid history entry: %w", torn)
+			}
+
+			var event StatusEvent
+			if err := json.Unmarshal(entry, &event); err != nil {
+				torn = err
+			} else {
+				m.appendHistory(event)
+				m.status.FailoverCount++
+				if event.Timestamp.After(m.status.LastFailover) {
+					m.status.LastFailover = event.Timestamp
+				}
+			}
+		}
+		if torn == nil {
+			good += int64(len(line))
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if torn != nil {
+		return file.Truncate(good)
+	}
+	return nil
+}
+
+// appendHistoryFile appends a single event as a JSON line to the history file
+func appendHistoryFile(path string, event *StatusEvent) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
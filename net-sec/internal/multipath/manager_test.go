@@ -0,0 +1,135 @@
+package multipath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, opts *Options) *Manager {
+	t.Helper()
+
+	if opts.PrimaryInterface == "" {
+		opts.PrimaryInterface = "wlan0"
+	}
+	if opts.BackupInterface == "" {
+		opts.BackupInterface = "eth0"
+	}
+
+	m := NewManager()
+	if err := m.Initialize(opts); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return m
+}
+
+func TestFailoverHistoryRecordsEventsInOrder(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "failover.jsonl")
+	m := newTestManager(t, &Options{HistoryFile: historyFile})
+
+	start := time.Now()
+	m.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed")
+	m.performFailover(EventRecovery, "eth0", "wlan0", "Primary interface recovered")
+	m.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed again")
+
+	history := m.GetHistory(start)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 history events, got %d", len(history))
+	}
+
+	expected := []struct {
+		eventType EventType
+		reason    string
+	}{
+		{EventFailover, "Primary interface failed"},
+		{EventRecovery, "Primary interface recovered"},
+		{EventFailover, "Primary interface failed again"},
+	}
+	for i, want := range expected {
+		if history[i].Type != want.eventType || history[i].Reason != want.reason {
+			t.Errorf("event %d: got %s %q, want %s %q",
+				i, history[i].Type, history[i].Reason, want.eventType, want.reason)
+		}
+		if i > 0 && history[i].Timestamp.Before(history[i-1].Timestamp) {
+			t.Errorf("event %d is out of chronological order", i)
+		}
+	}
+
+	if got := m.GetHistory(time.Now().Add(time.Hour)); len(got) != 0 {
+		t.Errorf("expected no events after a future timestamp, got %d", len(got))
+	}
+
+	// A restarted manager restores history and counters from the file
+	restarted := newTestManager(t, &Options{HistoryFile: historyFile})
+	status := restarted.GetStatus()
+	if status.FailoverCount != 3 {
+		t.Errorf("expected restored failover count 3, got %d", status.FailoverCount)
+	}
+	if !status.LastFailover.Equal(history[2].Timestamp) {
+		t.Errorf("expected restored last failover %v, got %v", history[2].Timestamp, status.LastFailover)
+	}
+	if got := restarted.GetHistory(time.Time{}); len(got) != 3 || got[1].Reason != "Primary interface recovered" {
+		t.Errorf("restored history does not match persisted events: %+v", got)
+	}
+}
+
+func TestFailoverHistoryTruncatesTornLastEntry(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "failover.jsonl")
+	m := newTestManager(t, &Options{HistoryFile: historyFile})
+	m.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed")
+	m.performFailover(EventRecovery, "eth0", "wlan0", "Primary interface recovered")
+
+	// Simulate a crash part way through appending a third entry
+	file, err := os.OpenFile(historyFile, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"type":"failover","reas`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	restarted := newTestManager(t, &Options{HistoryFile: historyFile})
+	if got := restarted.GetHistory(time.Time{}); len(got) != 2 {
+		t.Fatalf("expected the 2 complete events to be restored, got %d", len(got))
+	}
+
+	// Entries appended after the truncation are read back intact
+	restarted.performFailover(EventFailover, "wlan0", "eth0", "Primary interface failed again")
+	again := newTestManager(t, &Options{HistoryFile: historyFile})
+	if got := again.GetHistory(time.Time{}); len(got) != 3 || got[2].Reason != "Primary interface failed again" {
+		t.Errorf("expected 3 events after the torn entry was truncated, got %+v", got)
+	}
+}
+
+func TestFailoverHistoryRejectsCorruptEntry(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "failover.jsonl")
+	if err := os.WriteFile(historyFile, []byte("not json\n{\"type\":\"failover\"}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager()
+	if err := m.Initialize(&Options{PrimaryInterface: "wlan0", BackupInterface: "eth0", HistoryFile: historyFile}); err == nil {
+		t.Error("expected a corrupt entry before valid ones to fail initialization")
+	}
+}
+
+func TestFailoverHistoryIsBounded(t *testing.T) {
+	m := newTestManager(t, &Options{HistorySize: 2})
+
+	m.performFailover(EventFailover, "wlan0", "eth0", "first")
+	m.performFailover(EventRecovery, "eth0", "wlan0", "second")
+	m.performFailover(EventFailover, "wlan0", "eth0", "third")
+
+	history := m.GetHistory(time.Time{})
+	if len(history) != 2 {
+		t.Fatalf("expected history bounded to 2 events, got %d", len(history))
+	}
+	if history[0].Reason != "second" || history[1].Reason != "third" {
+		t.Errorf("expected the two most recent events, got %q and %q", history[0].Reason, history[1].Reason)
+	}
+	if count := m.GetStatus().FailoverCount; count != 3 {
+		t.Errorf("expected failover count 3, got %d", count)
+	}
+}
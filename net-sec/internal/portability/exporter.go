@@ -0,0 +1,371 @@
+package portability
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/rbac"
+)
+
+// ExportFormat identifies the layout of portability archives
+const ExportFormat = "net-sec/portability/v1"
+
+// Files in a portability archive
+const (
+	ManifestFile = "manifest.json"
+	DataFile     = "data.json"
+	CSVFile      = "data.csv"
+)
+
+// Depseudonymizer reverses the pseudonymization of a personal data value
+type Depseudonymizer interface {
+	Depseudonymize(value, dataCategory string) (string, error)
+}
+
+// PortabilityOptions configures a PortabilityExporter
+type PortabilityOptions struct {
+	OutputDir  string // Directory archives are written to
+	IncludeCSV bool   // Add a flattened CSV copy of the data
+}
+
+// PortabilityExporter exports a data subject's records from the registered
+// integrations in a machine-readable archive (GDPR Article 20)
+type PortabilityExporter struct {
+	integrations    *integrations.IntegrationManager
+	access          *rbac.AccessController
+	depseudonymizer Depseudonymizer
+	options         PortabilityOptions
+	now             func() time.Time
+	logger          logger.StructuredLogger
+}
+
+// Manifest describes the contents of a portability archive
+type Manifest struct {
+	Format      string           `json:"format"`
+	SubjectID   string           `json:"subject_id"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Files       []string         `json:"files"`
+	Sources     []ManifestSource `json:"sources"`
+}
+
+// ManifestSource describes the data exported from a single integration
+type ManifestSource struct {
+	Integration         string   `json:"integration"`
+	Records             int      `json:"records"`
+	Fields              []string `json:"fields"`
+	PseudonymizedFields []string `json:"pseudonymized_fields,omitempty"` // Left pseudonymized without consent
+	ExcludedFields      int      `json:"excluded_fields,omitempty"`      // Third-party personal data left out
+	Error               string   `json:"error,omitempty"`
+}
+
+// SubjectExport is the data file of a portability archive
+type SubjectExport struct {
+	SubjectID   string         `json:"subject_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Records     []ExportRecord `json:"records"`
+}
+
+// ExportRecord is a single exported record
+type ExportRecord struct {
+	Source    string                 `json:"source"`
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// NewPortabilityExporter creates an exporter over the manager's
+// integrations. Pseudonymized values are only reversed when depseudonymizer
+// is set and the subject has active consent for the field's data category
+// in access; either may be nil.
+func NewPortabilityExporter(manager *integrations.IntegrationManager, access *rbac.AccessController, depseudonymizer Depseudonymizer, options PortabilityOptions) *PortabilityExporter {
+	return &PortabilityExporter{
+		integrations:    manager,
+		access:          access,
+		depseudonymizer: depseudonymizer,
+		options:         options,
+		now:             time.Now,
+		logger:          logger.Component("portability"),
+	}
+}
+
+// SetLogger sets the logger used for exports
+func (pe *PortabilityExporter) SetLogger(l logger.StructuredLogger) {
+	pe.logger = l
+}
+
+// Export writes an archive of everything the integrations hold about
+// subjectID and returns its path. Integrations that fail are listed in the
+// manifest with their error rather than failing the export.
+func (pe *PortabilityExporter) Export(ctx context.Context, subjectID, userID string) (string, error) {
+	if subjectID == "" {
+		return "", fmt.Errorf("data subject ID required for export")
+	}
+
+	generatedAt := pe.now().UTC()
+	found, errs := pe.integrations.FindDataSubject(ctx, subjectID, userID, "Article 20")
+	consented := pe.consentedCategories(subjectID, generatedAt)
+
+	names := make([]string, 0, len(found)+len(errs))
+	for name := range found {
+		names = append(names, name)
+	}
+	for name := range errs {
+		if _, ok := found[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	export := SubjectExport{SubjectID: subjectID, GeneratedAt: generatedAt, Records: make([]ExportRecord, 0)}
+	manifest := Manifest{
+		Format:      ExportFormat,
+		SubjectID:   subjectID,
+		GeneratedAt: generatedAt,
+		Files:       []string{DataFile},
+		Sources:     make([]ManifestSource, 0, len(names)),
+	}
+	if pe.options.IncludeCSV {
+		manifest.Files = append(manifest.Files, CSVFile)
+	}
+
+	for _, name := range names {
+		source := ManifestSource{Integration: name, Fields: make([]string, 0)}
+		if err, failed := errs[name]; failed {
+			source.Error = err.Error()
+			manifest.Sources = append(manifest.Sources, source)
+			continue
+		}
+
+		fields := make(map[string]bool)
+		pseudonymized := make(map[string]bool)
+		for _, data := range found[name] {
+			record, excluded := pe.exportRecord(name, subjectID, data, consented, pseudonymized)
+			source.ExcludedFields += excluded
+			for field := range record.Data {
+				fields[field] = true
+			}
+			export.Records = append(export.Records, record)
+		}
+
+		source.Records = len(found[name])
+		source.Fields = sortedKeys(fields)
+		if len(pseudonymized) > 0 {
+			source.PseudonymizedFields = sortedKeys(pseudonymized)
+		}
+		manifest.Sources = append(manifest.Sources, source)
+	}
+
+	path, err := pe.writeArchive(subjectID, generatedAt, &manifest, &export)
+	if err != nil {
+		return "", err
+	}
+
+	pe.logger.Info("Portability export written", logger.Fields{
+		"event_type": "portability_export",
+		"subject_id": subjectID,
+		"user_id":    userID,
+		"records":    len(export.Records),
+		"sources":    len(manifest.Sources),
+		"path":       path,
+	})
+
+	return path, nil
+}
+
+// consentedCategories returns the data categories the subject has active
+// consent for
+func (pe *PortabilityExporter) consentedCategories(subjectID string, now time.Time) map[string]bool {
+	consented := make(map[string]bool)
+	if pe.access == nil {
+		return consented
+	}
+
+	for _, consent := range pe.access.ConsentRecords(subjectID) {
+		if consent.IsActive(now) {
+			consented[consent.DataCategory] = true
+		}
+	}
+	return consented
+}
+
+// exportRecord converts an integration record for export, leaving out other
+// subjects' personal data and reversing pseudonymization where consented.
+// Fields left pseudonymized are added to pseudonymized. It returns the
+// record and the number of fields excluded.
+func (pe *PortabilityExporter) exportRecord(source, subjectID string, data *integrations.IntegrationData, consented, pseudonymized map[string]bool) (ExportRecord, int) {
+	record := ExportRecord{
+		Source:    source,
+		ID:        data.ID,
+		Type:      data.Type,
+		CreatedAt: data.CreatedAt,
+		UpdatedAt: data.UpdatedAt,
+		Data:      make(map[string]interface{}, len(data.Content)),
+	}
+	for key, value := range data.Content {
+		record.Data[key] = value
+	}
+
+	excluded := 0
+	for _, field := range data.PersonalData {
+		if field.DataSubjectID != "" && field.DataSubjectID != subjectID {
+			delete(record.Data, field.Field)
+			excluded++
+			continue
+		}
+		if field.PseudonymizedValue == "" {
+			continue
+		}
+
+		record.Data[field.Field] = field.PseudonymizedValue
+		if !consented[field.DataCategory] || pe.depseudonymizer == nil {
+			pseudonymized[field.Field] = true
+			continue
+		}
+
+		value, err := pe.depseudonymizer.Depseudonymize(field.PseudonymizedValue, field.DataCategory)
+		if err != nil {
+			pe.logger.Warn("Failed to de-pseudonymize field for export", logger.Fields{
+				"event_type": "portability_depseudonymize_failed",
+				"subject_id": subjectID,
+				"source":     source,
+				"field":      field.Field,
+				"error":      err,
+			})
+			pseudonymized[field.Field] = true
+			continue
+		}
+		record.Data[field.Field] = value
+	}
+
+	return record, excluded
+}
+
+// writeArchive writes the archive atomically to the output directory
+func (pe *PortabilityExporter) writeArchive(subjectID string, generatedAt time.Time, manifest *Manifest, export *SubjectExport) (string, error) {
+	if err := os.MkdirAll(pe.options.OutputDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	name := fmt.Sprintf("portability-%s-%s.zip", safeName(subjectID), generatedAt.Format("20060102T150405Z"))
+	path := filepath.Join(pe.options.OutputDir, name)
+
+	tmp, err := os.CreateTemp(pe.options.OutputDir, "."+name+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	archive := zip.NewWriter(tmp)
+	err = writeJSON(archive, ManifestFile, manifest)
+	if err == nil {
+		err = writeJSON(archive, DataFile, export)
+	}
+	if err == nil && pe.options.IncludeCSV {
+		err = writeCSV(archive, export)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	return path, nil
+}
+
+// writeJSON adds v to the archive as an indented JSON file
+func writeJSON(archive *zip.Writer, name string, v interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeCSV adds the export to the archive with one row per record field
+func writeCSV(archive *zip.Writer, export *SubjectExport) error {
+	w, err := archive.Create(CSVFile)
+	if err != nil {
+		return err
+	}
+	return encodeCSV(w, export)
+}
+
+// encodeCSV writes the export as source,record_id,type,field,value rows.
+// Values that are not strings are written as JSON.
+func encodeCSV(w io.Writer, export *SubjectExport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"source", "record_id", "type", "field", "value"}); err != nil {
+		return err
+	}
+
+	for _, record := range export.Records {
+		fields := make([]string, 0, len(record.Data))
+		for field := range record.Data {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			value, ok := record.Data[field].(string)
+			if !ok {
+				encoded, err := json.Marshal(record.Data[field])
+				if err != nil {
+					return fmt.Errorf("failed to encode %s.%s: %w", record.ID, field, err)
+				}
+				value = string(encoded)
+			}
+
+			if err := writer.Write([]string{record.Source, record.ID, record.Type, field, value}); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// safeName replaces characters that are unsafe in file names
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,248 @@
+package portability
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/rbac"
+)
+
+// mockIntegration returns fixed records for each data subject
+type mockIntegration struct {
+	name     string
+	subjects map[string][]*integrations.IntegrationData
+}
+
+func (m *mockIntegration) Name() string                                     { return m.name }
+func (m *mockIntegration) Authenticate(credentials map[string]string) error { return nil }
+func (m *mockIntegration) SendData(ctx context.Context, data *integrations.IntegrationData) error {
+	return nil
+}
+func (m *mockIntegration) RetrieveData(ctx context.Context, query *integrations.DataQuery) (*integrations.IntegrationData, error) {
+	return nil, nil
+}
+func (m *mockIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	return nil
+}
+func (m *mockIntegration) ValidateConnection() error                    { return nil }
+func (m *mockIntegration) GetMetrics() *integrations.IntegrationMetrics { return nil }
+
+func (m *mockIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*integrations.IntegrationData, error) {
+	return m.subjects[subjectID], nil
+}
+
+// reverseDepseudonymizer treats pseudonyms as "pseudo:" plus the reversed value
+type reverseDepseudonymizer struct{}
+
+func (reverseDepseudonymizer) Depseudonymize(value, dataCategory string) (string, error) {
+	runes := []rune(strings.TrimPrefix(value, "pseudo:"))
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes), nil
+}
+
+func newTestExporter(t *testing.T, includeCSV bool) *PortabilityExporter {
+	t.Helper()
+
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	crm := &mockIntegration{name: "crm", subjects: map[string][]*integrations.IntegrationData{
+		"subject_1": {{
+			ID:      "contact-1",
+			Type:    "contact",
+			Content: map[string]interface{}{"name": "pseudo:ecilA", "plan": "pro", "seats": 3},
+			PersonalData: []integrations.PersonalDataField{
+				{Field: "name", DataCategory: "personal", PseudonymizedValue: "pseudo:ecilA"},
+			},
+			CreatedAt: created,
+			UpdatedAt: created,
+		}},
+	}}
+	helpdesk := &mockIntegration{name: "helpdesk", subjects: map[string][]*integrations.IntegrationData{
+		"subject_1": {{
+			ID:   "ticket-7",
+			Type: "ticket",
+			Content: map[string]interface{}{
+				"subject":   "Login issue",
+				"diagnosis": "pseudo:enilesab",
+				"assignee":  "bob@example.com",
+			},
+			PersonalData: []integrations.PersonalDataField{
+				{Field: "diagnosis", DataCategory: "sensitive", PseudonymizedValue: "pseudo:enilesab"},
+				{Field: "assignee", DataCategory: "personal", DataSubjectID: "subject_2"},
+			},
+			CreatedAt: created,
+			UpdatedAt: created,
+		}},
+	}}
+
+	manager := integrations.NewIntegrationManager(&integrations.IntegrationConfig{}, nil, nil)
+	for _, integration := range []integrations.Integration{crm, helpdesk} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Consent covers personal data only, so sensitive data stays pseudonymized
+	access := rbac.NewAccessController(&rbac.RBACConfig{SessionTimeout: time.Hour}, nil)
+	access.SetLogger(logger.New("error", "text", io.Discard).Component("rbac"))
+	err := access.AddUser(&rbac.User{
+		ID:            "user_1",
+		DataSubjectID: "subject_1",
+		ConsentRecords: []rbac.ConsentRecord{
+			{ID: "c1", DataCategory: "personal", ConsentGiven: true},
+			{ID: "c2", DataCategory: "sensitive", ConsentGiven: true, WithdrawnAt: &created},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := NewPortabilityExporter(manager, access, reverseDepseudonymizer{}, PortabilityOptions{
+		OutputDir:  t.TempDir(),
+		IncludeCSV: includeCSV,
+	})
+	exporter.SetLogger(logger.New("error", "text", io.Discard).Component("portability"))
+	exporter.now = func() time.Time { return time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC) }
+	return exporter
+}
+
+// readArchive returns the contents of each file in a zip archive
+func readArchive(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = data
+	}
+	return files
+}
+
+func TestExportFromTwoIntegrations(t *testing.T) {
+	exporter := newTestExporter(t, false)
+
+	path, err := exporter.Export(context.Background(), "subject_1", "dpo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "portability-subject_1-20240601T093000Z.zip") {
+		t.Errorf("unexpected archive path %s", path)
+	}
+
+	files := readArchive(t, path)
+	if len(files) != 2 || files[ManifestFile] == nil || files[DataFile] == nil {
+		t.Fatalf("expected manifest and data files, got %d files", len(files))
+	}
+
+	var manifest Manifest
+	decoder := json.NewDecoder(bytes.NewReader(files[ManifestFile]))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		t.Fatalf("manifest does not match schema: %v", err)
+	}
+
+	if manifest.Format != ExportFormat || manifest.SubjectID != "subject_1" {
+		t.Errorf("unexpected manifest header: %+v", manifest)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0] != DataFile {
+		t.Errorf("unexpected manifest files: %v", manifest.Files)
+	}
+	if len(manifest.Sources) != 2 {
+		t.Fatalf("expected two sources, got %+v", manifest.Sources)
+	}
+
+	crm, helpdesk := manifest.Sources[0], manifest.Sources[1]
+	if crm.Integration != "crm" || crm.Records != 1 || strings.Join(crm.Fields, ",") != "name,plan,seats" {
+		t.Errorf("unexpected crm source: %+v", crm)
+	}
+	if len(crm.PseudonymizedFields) != 0 {
+		t.Errorf("consented crm fields should be de-pseudonymized: %v", crm.PseudonymizedFields)
+	}
+	if helpdesk.Integration != "helpdesk" || strings.Join(helpdesk.Fields, ",") != "diagnosis,subject" {
+		t.Errorf("unexpected helpdesk source: %+v", helpdesk)
+	}
+	if helpdesk.ExcludedFields != 1 || strings.Join(helpdesk.PseudonymizedFields, ",") != "diagnosis" {
+		t.Errorf("expected third-party assignee excluded and diagnosis pseudonymized: %+v", helpdesk)
+	}
+
+	var export SubjectExport
+	decoder = json.NewDecoder(bytes.NewReader(files[DataFile]))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&export); err != nil {
+		t.Fatalf("data file does not match schema: %v", err)
+	}
+
+	if export.SubjectID != "subject_1" || len(export.Records) != 2 {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+	for _, record := range export.Records {
+		if record.Source == "" || record.ID == "" || record.Type == "" || record.CreatedAt.IsZero() {
+			t.Errorf("record is missing required fields: %+v", record)
+		}
+	}
+	if name := export.Records[0].Data["name"]; name != "Alice" {
+		t.Errorf("expected de-pseudonymized name, got %v", name)
+	}
+	if diagnosis := export.Records[1].Data["diagnosis"]; diagnosis != "pseudo:enilesab" {
+		t.Errorf("expected pseudonymized diagnosis without consent, got %v", diagnosis)
+	}
+	if _, ok := export.Records[1].Data["assignee"]; ok {
+		t.Error("third-party personal data was exported")
+	}
+}
+
+func TestExportIncludesCSV(t *testing.T) {
+	exporter := newTestExporter(t, true)
+
+	path, err := exporter.Export(context.Background(), "subject_1", "dpo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readArchive(t, path)
+	rows, err := csv.NewReader(bytes.NewReader(files[CSVFile])).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+
+	want := [][]string{
+		{"source", "record_id", "type", "field", "value"},
+		{"crm", "contact-1", "contact", "name", "Alice"},
+		{"crm", "contact-1", "contact", "plan", "pro"},
+		{"crm", "contact-1", "contact", "seats", "3"},
+		{"helpdesk", "ticket-7", "ticket", "diagnosis", "pseudo:enilesab"},
+		{"helpdesk", "ticket-7", "ticket", "subject", "Login issue"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %v", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d: expected %v, got %v", i, want[i], rows[i])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package privacy
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// BatchItem is one value to pseudonymize with PseudonymizeBatch
+type BatchItem struct {
+	Data       string
+	DataType   string
+	Purpose    string
+	LegalBasis string
+}
+
+// PseudonymizeBatch pseudonymizes items in parallel on BatchWorkers workers.
+// Results and errors are indexed like items; a failed item leaves a nil
+// result and does not stop the others. One aggregated audit event is logged
+// for the batch, plus an event per failed item. It is safe to call while
+// keys rotate; each item uses the key active when it is processed.
+func (pe *PseudonymizationEngine) PseudonymizeBatch(items []BatchItem) ([]*PseudonymizedData, []error) {
+	results := make([]*PseudonymizedData, len(items))
+	errs := make([]error, len(items))
+	events := make([]PseudonymizationEvent, len(items))
+
+	workers := pe.runBatch(len(items), func(i int) {
+		item := items[i]
+		results[i], events[i], errs[i] = pe.pseudonymize(item.Data, item.DataType, item.Purpose, item.LegalBasis)
+		pe.recordOperation(events[i])
+	})
+
+	// The summary carries the items' purpose when they share one
+	var purpose string
+	for i, item := range items {
+		if i == 0 {
+			purpose = item.Purpose
+		} else if item.Purpose != purpose {
+			purpose = ""
+			break
+		}
+	}
+	pe.logBatch("pseudonymize_batch", purpose, events, errs, workers)
+	return results, errs
+}
+
+// DePseudonymizeBatch reverses pseudonyms in parallel like
+// PseudonymizeBatch, under a single purpose and legal basis
+func (pe *PseudonymizationEngine) DePseudonymizeBatch(items []*PseudonymizedData, purpose, legalBasis string) ([]string, []error) {
+	results := make([]string, len(items))
+	errs := make([]error, len(items))
+	events := make([]PseudonymizationEvent, len(items))
+
+	workers := pe.runBatch(len(items), func(i int) {
+		if items[i] == nil {
+			errs[i] = fmt.Errorf("item %d has no pseudonymized data", i)
+			events[i] = PseudonymizationEvent{
+				ID:           generateID(),
+				Timestamp:    time.Now(),
+				Operation:    "de-pseudonymize",
+				Purpose:      purpose,
+				LegalBasis:   legalBasis,
+				ErrorMessage: errs[i].Error(),
+			}
+			pe.recordOperation(events[i])
+			return
+		}
+		results[i], events[i], errs[i] = pe.dePseudonymize(items[i], purpose, legalBasis)
+		pe.recordOperation(events[i])
+	})
+
+	pe.logBatch("de-pseudonymize_batch", purpose, events, errs, workers)
+	return results, errs
+}
+
+// runBatch calls process for every index below n on a bounded worker pool
+// and returns the number of workers used
+func (pe *PseudonymizationEngine) runBatch(n int, process func(i int)) int {
+	workers := pe.config.BatchWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				process(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return workers
+}
+
+// logBatch logs the events of failed items and an event summarizing the
+// batch
+func (pe *PseudonymizationEngine) logBatch(operation, purpose string, events []PseudonymizationEvent, errs []error, workers int) {
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			pe.logPseudonymization(events[i])
+		}
+	}
+
+	summary := PseudonymizationEvent{
+		ID:        generateID(),
+		Timestamp: time.Now(),
+		DataType:  "batch",
+		Operation: operation,
+		Algorithm: pe.config.Algorithm,
+		Purpose:   purpose,
+		Success:   failed == 0,
+		Metadata: map[string]interface{}{
+			"total":     len(errs),
+			"succeeded": len(errs) - failed,
+			"failed":    failed,
+			"workers":   workers,
+		},
+	}
+	pe.logPseudonymization(summary)
+}
@@ -0,0 +1,122 @@
+package privacy
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPseudonymizeBatch(t *testing.T) {
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = FormatPreservingEncryption
+	config.BatchWorkers = 3
+	auditLog := &recordingAuditLog{}
+	engine, err := NewPseudonymizationEngine(config, auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []BatchItem
+	for i := 0; i < 20; i++ {
+		items = append(items, BatchItem{Data: fmt.Sprintf("user%d@example.com", i), DataType: "email", Purpose: "migration", LegalBasis: "contract"})
+	}
+	items[7].Data = "not-an-email"
+
+	results, errs := engine.PseudonymizeBatch(items)
+	if len(results) != len(items) || len(errs) != len(items) {
+		t.Fatalf("expected a result and error per item, got %d and %d", len(results), len(errs))
+	}
+	for i := range items {
+		if i == 7 {
+			if errs[i] == nil || results[i] != nil {
+				t.Errorf("expected item 7 to fail, got %+v, %v", results[i], errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil {
+			t.Fatalf("item %d: %v", i, errs[i])
+		}
+		original, err := engine.DePseudonymize(results[i], "migration", "contract")
+		if err != nil || original != items[i].Data {
+			t.Errorf("item %d: expected %q, got %q, %v", i, items[i].Data, original, err)
+		}
+	}
+
+	// One failure event and the summary precede the 19 de-pseudonymizations
+	events := auditLog.events[:2]
+	if events[0].Success || events[0].Operation != "pseudonymize" {
+		t.Errorf("expected the failed item's event first, got %+v", events[0])
+	}
+	summary := events[1]
+	if summary.Operation != "pseudonymize_batch" || summary.Success || summary.Purpose != "migration" ||
+		summary.Metadata["total"] != 20 || summary.Metadata["succeeded"] != 19 || summary.Metadata["failed"] != 1 || summary.Metadata["workers"] != 3 {
+		t.Errorf("unexpected batch summary %+v", summary)
+	}
+}
+
+func TestDePseudonymizeBatch(t *testing.T) {
+	auditLog := &recordingAuditLog{}
+	engine, err := NewPseudonymizationEngine(nil, auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []BatchItem{
+		{Data: "alice", DataType: "name", Purpose: "support"},
+		{Data: "bob", DataType: "name", Purpose: "billing"},
+	}
+	pseudonyms, errs := engine.PseudonymizeBatch(items)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if summary := auditLog.events[0]; summary.Purpose != "" || !summary.Success {
+		t.Errorf("expected a successful summary without a shared purpose, got %+v", summary)
+	}
+
+	originals, errs := engine.DePseudonymizeBatch(append(pseudonyms, nil), "support", "contract")
+	if originals[0] != "alice" || originals[1] != "bob" || errs[0] != nil || errs[1] != nil {
+		t.Errorf("unexpected results %q, %v", originals, errs)
+	}
+	if errs[2] == nil {
+		t.Error("expected a nil item to fail")
+	}
+	if len(auditLog.events) != 3 || auditLog.events[2].Operation != "de-pseudonymize_batch" || auditLog.events[2].Metadata["failed"] != 1 {
+		t.Errorf("unexpected audit events %+v", auditLog.events)
+	}
+}
+
+func TestPseudonymizeBatchDuringRotation(t *testing.T) {
+	engine, err := NewPseudonymizationEngine(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]BatchItem, 200)
+	for i := range items {
+		items[i] = BatchItem{Data: fmt.Sprintf("value-%d", i), DataType: "name", Purpose: "migration"}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if err := engine.ForceRotateKeys(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	results, errs := engine.PseudonymizeBatch(items)
+	wg.Wait()
+
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatalf("item %d: %v", i, errs[i])
+		}
+		if original, err := engine.DePseudonymize(result, "migration", "contract"); err != nil || original != items[i].Data {
+			t.Errorf("item %d: expected %q, got %q, %v", i, items[i].Data, original, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package privacy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Deterministic encryption derives the GCM nonce from the plaintext with a
+// keyed HMAC (a synthetic IV, as in SIV modes) instead of drawing it at
+// random. Equal plaintexts under the same key therefore produce equal
+// pseudonyms, which lets analytics join datasets on the pseudonym without
+// de-pseudonymizing them.
+//
+// The trade-off is that pseudonyms reveal which records share a value, so
+// frequency analysis of low-cardinality fields (country, gender, status)
+// can recover plaintexts. Only use AES256Deterministic for high-entropy
+// identifiers that must be joinable, and prefer AES256Encryption otherwise.
+
+// deterministicNonceLabel separates the nonce key from the encryption key
+const deterministicNonceLabel = "net-sec/pseudonymization/deterministic-nonce"
+
+// deterministicPseudonymization encrypts data with a nonce derived from the
+// data and key
+func (pe *PseudonymizationEngine) deterministicPseudonymization(data string, key *CryptoKey) (string, string, error) {
+	gcm, err := newDeterministicGCM(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce := deterministicNonce(key, []byte(data), gcm.NonceSize())
+	encrypted := gcm.Seal(nonce, nonce, []byte(data), nil)
+	encoded := base64.URLEncoding.EncodeToString(encrypted)
+
+	// Create hash for lookup without decryption
+	hasher := sha256.New()
+	hasher.Write([]byte(data))
+	hasher.Write(key.Salt)
+	hashValue := hex.EncodeToString(hasher.Sum(nil))
+
+	return encoded, hashValue, nil
+}
+
+// deterministicDePseudonymization reverses deterministic encryption and
+// checks that the nonce matches the recovered data
+func (pe *PseudonymizationEngine) deterministicDePseudonymization(encryptedData string, key *CryptoKey) (string, error) {
+	encrypted, err := base64.URLEncoding.DecodeString(encryptedData)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted data: %w", err)
+	}
+
+	gcm, err := newDeterministicGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(encrypted) < nonceSize {
+		return "", fmt.Errorf("encrypted data too short")
+	}
+
+	nonce, ciphertext := encrypted[:nonceSize], encrypted[nonceSize:]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	if !hmac.Equal(nonce, deterministicNonce(key, plaintext, nonceSize)) {
+		return "", fmt.Errorf("synthetic nonce does not match decrypted data")
+	}
+
+	return string(plaintext), nil
+}
+
+// newDeterministicGCM creates the AES-GCM cipher for key
+func newDeterministicGCM(key *CryptoKey) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// deterministicNonce derives a size-byte nonce from data with an HMAC keyed
+// by a sub-key of key, so the nonce reveals nothing about the encryption key
+func deterministicNonce(key *CryptoKey, data []byte, size int) []byte {
+	subKey := hmac.New(sha256.New, key.Key)
+	subKey.Write([]byte(deterministicNonceLabel))
+
+	mac := hmac.New(sha256.New, subKey.Sum(nil))
+	mac.Write(data)
+	return mac.Sum(nil)[:size]
+}
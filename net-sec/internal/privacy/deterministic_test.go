@@ -0,0 +1,116 @@
+package privacy
+
+import (
+	"testing"
+)
+
+func newDeterministicEngine(t *testing.T) *PseudonymizationEngine {
+	t.Helper()
+
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = AES256Deterministic
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestDeterministicPseudonymsAreEqualForEqualInput(t *testing.T) {
+	engine := newDeterministicEngine(t)
+
+	first, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := engine.Pseudonymize("bob@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.PseudonymizedValue != second.PseudonymizedValue {
+		t.Errorf("expected equal pseudonyms, got %s and %s", first.PseudonymizedValue, second.PseudonymizedValue)
+	}
+	if first.PseudonymizedValue == other.PseudonymizedValue {
+		t.Error("different inputs produced the same pseudonym")
+	}
+	if first.Algorithm != AES256Deterministic {
+		t.Errorf("unexpected algorithm %v", first.Algorithm)
+	}
+}
+
+func TestDeterministicPseudonymsAreReversible(t *testing.T) {
+	engine := newDeterministicEngine(t)
+
+	for _, value := range []string{"alice@example.com", "", "ünïcødé 🔑"} {
+		pseudo, err := engine.Pseudonymize(value, "identifier", "analytics", "legitimate_interest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		original, err := engine.DePseudonymize(pseudo, "analytics", "legitimate_interest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if original != value {
+			t.Errorf("expected %q, got %q", value, original)
+		}
+	}
+}
+
+func TestDeterministicPseudonymsDependOnKey(t *testing.T) {
+	engine := newDeterministicEngine(t)
+
+	before, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before.KeyVersion == after.KeyVersion {
+		t.Fatalf("expected a new key version after rotation, got %d", after.KeyVersion)
+	}
+	if before.PseudonymizedValue == after.PseudonymizedValue {
+		t.Error("different keys produced the same pseudonym")
+	}
+
+	// Pseudonyms under the old key remain reversible
+	original, err := engine.DePseudonymize(before, "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original != "alice@example.com" {
+		t.Errorf("unexpected original value %q", original)
+	}
+}
+
+func TestDeterministicDePseudonymizeRejectsTampering(t *testing.T) {
+	engine := newDeterministicEngine(t)
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := *pseudo
+	value := []byte(tampered.PseudonymizedValue)
+	if value[0] == 'A' {
+		value[0] = 'B'
+	} else {
+		value[0] = 'A'
+	}
+	tampered.PseudonymizedValue = string(value)
+
+	if _, err := engine.DePseudonymize(&tampered, "analytics", "legitimate_interest"); err == nil {
+		t.Error("expected tampered pseudonym to be rejected")
+	}
+}
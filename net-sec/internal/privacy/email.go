@@ -0,0 +1,113 @@
+package privacy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// EmailDomainMode selects how format-preserving email pseudonymization
+// treats the domain
+type EmailDomainMode int
+
+const (
+	// EmailDomainExample hashes the domain under the reserved .example TLD
+	EmailDomainExample EmailDomainMode = iota
+	// EmailDomainPreserveTLD hashes the domain labels but keeps the original
+	// TLD, for systems that validate TLDs
+	EmailDomainPreserveTLD
+	// EmailDomainFixed maps every address to FormatPreservationRule's
+	// PseudonymDomain
+	EmailDomainFixed
+	// EmailDomainUnchanged keeps the domain and encrypts the local part
+	// with FF1, preserving its length; the address can be de-pseudonymized
+	EmailDomainUnchanged
+)
+
+// Label lengths, in hex characters, for EmailDomainPreserveTLD and
+// EmailDomainFixed. The local label alone identifies the address, so 64
+// bits keep collisions unlikely below billions of addresses.
+const (
+	emailLocalLabelLen  = 16
+	emailDomainLabelLen = 12
+)
+
+// pseudonymizeEmailWithDomain pseudonymizes an address per rule.EmailDomain.
+// The local label is derived from the whole address so equal local parts on
+// different domains do not share a pseudonym.
+func (pe *PseudonymizationEngine) pseudonymizeEmailWithDomain(local, domain string, rule *FormatPreservationRule, key *CryptoKey) (string, string, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if local == "" || domain == "" {
+		return "", "", fmt.Errorf("invalid email format")
+	}
+	email := local + "@" + domain
+
+	var pseudoDomain string
+	switch rule.EmailDomain {
+	case EmailDomainPreserveTLD:
+		dot := strings.LastIndex(domain, ".")
+		if dot <= 0 || !validDomainLabel(domain[dot+1:]) {
+			return "", "", fmt.Errorf("email domain %q has no valid TLD", domain)
+		}
+		pseudoDomain = saltedHex("domain", domain[:dot], key, emailDomainLabelLen) + domain[dot:]
+	case EmailDomainFixed:
+		if rule.PseudonymDomain == "" {
+			return "", "", fmt.Errorf("email pseudonym domain is not configured")
+		}
+		pseudoDomain = rule.PseudonymDomain
+	default:
+		return "", "", fmt.Errorf("unsupported email domain mode: %v", rule.EmailDomain)
+	}
+
+	pseudoEmail := saltedHex("local", email, key, emailLocalLabelLen) + "@" + pseudoDomain
+
+	// Create hash for lookup
+	hasher := sha256.New()
+	hasher.Write([]byte(email))
+	hasher.Write(key.Salt)
+	hashValue := hex.EncodeToString(hasher.Sum(nil))
+
+	return pseudoEmail, hashValue, nil
+}
+
+// cryptEmailLocal encrypts or decrypts the local part of email with FF1,
+// keeping the domain
+func cryptEmailLocal(email string, rule *FormatPreservationRule, key *CryptoKey, encrypt bool) (string, error) {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid email format")
+	}
+
+	alphabet := rule.AllowedChars
+	if alphabet == "" {
+		alphabet = DefaultEmailAlphabet
+	}
+	local, err := fpeCryptString(key, alphabet, rule.DataType, email[:at], encrypt)
+	if err != nil {
+		return "", err
+	}
+	return local + email[at:], nil
+}
+
+// saltedHex returns the first n hex characters of the salted hash of value,
+// domain-separated by label
+func saltedHex(label, value string, key *CryptoKey, n int) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(label + ":" + value))
+	hasher.Write(key.Salt)
+	return hex.EncodeToString(hasher.Sum(nil))[:n]
+}
+
+// validDomainLabel reports whether label is a syntactically valid DNS label
+func validDomainLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
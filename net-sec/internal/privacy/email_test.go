@@ -0,0 +1,102 @@
+package privacy
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func newEmailEngine(t *testing.T, mode EmailDomainMode, pseudonymDomain string) *PseudonymizationEngine {
+	t.Helper()
+
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = FormatPreservingEncryption
+	config.PreservationRules = []FormatPreservationRule{
+		{DataType: "email", PreserveFormat: true, EmailDomain: mode, PseudonymDomain: pseudonymDomain},
+	}
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func pseudonymizeEmail(t *testing.T, engine *PseudonymizationEngine, email string) string {
+	t.Helper()
+
+	pseudo, err := engine.Pseudonymize(email, "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatalf("%s: %v", email, err)
+	}
+	if _, err := mail.ParseAddress(pseudo.PseudonymizedValue); err != nil {
+		t.Errorf("%s: pseudonym %q is not a valid address: %v", email, pseudo.PseudonymizedValue, err)
+	}
+	return pseudo.PseudonymizedValue
+}
+
+func TestEmailPseudonymPreservesTLD(t *testing.T) {
+	engine := newEmailEngine(t, EmailDomainPreserveTLD, "")
+
+	for email, tld := range map[string]string{
+		"alice@example.com":      ".com",
+		"bob@mail.example.co.uk": ".uk",
+		"carol@Example.DE":       ".de",
+	} {
+		pseudo := pseudonymizeEmail(t, engine, email)
+		if !strings.HasSuffix(pseudo, tld) {
+			t.Errorf("%s: expected TLD %s, got %s", email, tld, pseudo)
+		}
+		if strings.Contains(pseudo, "example") || strings.Contains(pseudo, strings.Split(email, "@")[0]) {
+			t.Errorf("%s: pseudonym %s leaks the original labels", email, pseudo)
+		}
+	}
+
+	if pseudonymizeEmail(t, engine, "alice@example.com") != pseudonymizeEmail(t, engine, "alice@EXAMPLE.com") {
+		t.Error("domains should be case-insensitive")
+	}
+	if _, err := engine.Pseudonymize("alice@localhost", "email", "analytics", "legitimate_interest"); err == nil {
+		t.Error("expected a domain without TLD to be rejected")
+	}
+}
+
+func TestEmailPseudonymIsDeterministic(t *testing.T) {
+	for _, mode := range []EmailDomainMode{EmailDomainExample, EmailDomainPreserveTLD, EmailDomainFixed} {
+		engine := newEmailEngine(t, mode, "pseudo.example.net")
+
+		first := pseudonymizeEmail(t, engine, "alice@example.com")
+		if second := pseudonymizeEmail(t, engine, "alice@example.com"); first != second {
+			t.Errorf("mode %v: expected the same pseudonym, got %s and %s", mode, first, second)
+		}
+		if other := pseudonymizeEmail(t, engine, "alice@example.org"); first == other {
+			t.Errorf("mode %v: different addresses share pseudonym %s", mode, first)
+		}
+	}
+}
+
+func TestEmailPseudonymFixedDomain(t *testing.T) {
+	engine := newEmailEngine(t, EmailDomainFixed, "pseudo.example.net")
+
+	seen := make(map[string]string)
+	for i := 0; i < 5000; i++ {
+		email := fmt.Sprintf("user%d@domain%d.com", i, i%7)
+		pseudo := pseudonymizeEmail(t, engine, email)
+		if !strings.HasSuffix(pseudo, "@pseudo.example.net") {
+			t.Fatalf("%s: expected the pseudonym domain, got %s", email, pseudo)
+		}
+		if previous, ok := seen[pseudo]; ok {
+			t.Fatalf("%s and %s collide on %s", previous, email, pseudo)
+		}
+		seen[pseudo] = email
+	}
+
+	// Equal local parts on different domains get different pseudonyms
+	if pseudonymizeEmail(t, engine, "alice@a.com") == pseudonymizeEmail(t, engine, "alice@b.com") {
+		t.Error("local parts are linkable across domains")
+	}
+
+	unset := newEmailEngine(t, EmailDomainFixed, "")
+	if _, err := unset.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest"); err == nil {
+		t.Error("expected an error without a pseudonym domain")
+	}
+}
@@ -0,0 +1,285 @@
+package privacy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/netip"
+)
+
+// Format-preserving encryption uses FF1 from NIST SP 800-38G: a Feistel
+// network over numeral strings whose round function is AES CBC-MAC. A value
+// is mapped to numerals through its rule's alphabet, encrypted, and mapped
+// back, so ciphertext has the same length and character set as the input
+// and decrypts with the same key. The tweak is the data type, so the same
+// value in two fields encrypts differently.
+
+const (
+	ff1Rounds   = 10
+	ff1MaxRadix = 1 << 16
+	// ff1MinDomain is the smallest radix^length FF1 accepts, the 10^6
+	// SP 800-38G Rev. 1 requires. Smaller domains can be recovered by
+	// enumeration, so short values such as three-character email local
+	// parts are rejected rather than weakly encrypted.
+	ff1MinDomain = 1_000_000
+
+	// fpeKeyLabel separates the FF1 key from the encryption key
+	fpeKeyLabel = "net-sec/pseudonymization/ff1"
+
+	// DefaultEmailAlphabet is the alphabet email local parts are encrypted
+	// over when a rule has no AllowedChars. Other characters, such as dots
+	// and plus signs, are kept in place.
+	DefaultEmailAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// ff1 encrypts numeral strings in a fixed radix
+type ff1 struct {
+	block cipher.Block
+	radix int
+}
+
+// newFF1 creates an FF1 cipher for radix with an AES key
+func newFF1(key []byte, radix int) (*ff1, error) {
+	if radix < 2 || radix > ff1MaxRadix {
+		return nil, fmt.Errorf("FF1 radix %d is out of range", radix)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &ff1{block: block, radix: radix}, nil
+}
+
+// encrypt returns the FF1 encryption of the numerals x under tweak
+func (f *ff1) encrypt(tweak []byte, x []int) ([]int, error) {
+	return f.crypt(tweak, x, true)
+}
+
+// decrypt returns the FF1 decryption of the numerals x under tweak
+func (f *ff1) decrypt(tweak []byte, x []int) ([]int, error) {
+	return f.crypt(tweak, x, false)
+}
+
+// crypt runs the FF1 Feistel rounds, forwards to encrypt and backwards to
+// decrypt
+func (f *ff1) crypt(tweak []byte, x []int, encrypt bool) ([]int, error) {
+	n := len(x)
+	radix := big.NewInt(int64(f.radix))
+	if n < 2 || new(big.Int).Exp(radix, big.NewInt(int64(n)), nil).Cmp(big.NewInt(ff1MinDomain)) < 0 {
+		return nil, fmt.Errorf("%d characters are too few for format-preserving encryption", n)
+	}
+	for _, numeral := range x {
+		if numeral < 0 || numeral >= f.radix {
+			return nil, fmt.Errorf("numeral %d is out of range for radix %d", numeral, f.radix)
+		}
+	}
+
+	u, v := n/2, n-n/2
+	a := append([]int(nil), x[:u]...)
+	b := append([]int(nil), x[u:]...)
+
+	radixU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	radixV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+	byteLen := (new(big.Int).Sub(radixV, big.NewInt(1)).BitLen() + 7) / 8
+	d := 4*((byteLen+3)/4) + 4
+
+	p := make([]byte, aes.BlockSize)
+	p[0], p[1], p[2] = 1, 2, 1
+	p[3], p[4], p[5] = byte(f.radix>>16), byte(f.radix>>8), byte(f.radix)
+	p[6] = ff1Rounds
+	p[7] = byte(u)
+	binary.BigEndian.PutUint32(p[8:12], uint32(n))
+	binary.BigEndian.PutUint32(p[12:16], uint32(len(tweak)))
+
+	pad := ((-(len(tweak) + byteLen + 1))%aes.BlockSize + aes.BlockSize) % aes.BlockSize
+	q := make([]byte, len(tweak)+pad+1+byteLen)
+	copy(q, tweak)
+
+	for round := 0; round < ff1Rounds; round++ {
+		i := round
+		half := b
+		if !encrypt {
+			i = ff1Rounds - 1 - round
+			half = a
+		}
+
+		q[len(tweak)+pad] = byte(i)
+		f.num(half).FillBytes(q[len(q)-byteLen:])
+		y := f.roundValue(p, q, d)
+
+		m, modulus := u, radixU
+		if i%2 == 1 {
+			m, modulus = v, radixV
+		}
+
+		c := new(big.Int)
+		if encrypt {
+			c.Add(f.num(a), y).Mod(c, modulus)
+			a, b = b, f.str(c, m)
+		} else {
+			c.Sub(f.num(b), y).Mod(c, modulus)
+			a, b = f.str(c, m), a
+		}
+	}
+
+	return append(a, b...), nil
+}
+
+// roundValue returns the first d bytes of the FF1 round function output
+// for p || q as an integer
+func (f *ff1) roundValue(p, q []byte, d int) *big.Int {
+	r := make([]byte, aes.BlockSize)
+	for _, data := range [][]byte{p, q} {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			for j := range r {
+				r[j] ^= data[i+j]
+			}
+			f.block.Encrypt(r, r)
+		}
+	}
+
+	s := append([]byte(nil), r...)
+	for j := 1; len(s) < d; j++ {
+		block := append([]byte(nil), r...)
+		var counter [aes.BlockSize]byte
+		binary.BigEndian.PutUint64(counter[8:], uint64(j))
+		for k := range block {
+			block[k] ^= counter[k]
+		}
+		f.block.Encrypt(block, block)
+		s = append(s, block...)
+	}
+
+	return new(big.Int).SetBytes(s[:d])
+}
+
+// num returns the value of the numerals x, most significant first
+func (f *ff1) num(x []int) *big.Int {
+	radix := big.NewInt(int64(f.radix))
+	value := new(big.Int)
+	for _, numeral := range x {
+		value.Mul(value, radix).Add(value, big.NewInt(int64(numeral)))
+	}
+	return value
+}
+
+// str returns value as m numerals, most significant first
+func (f *ff1) str(value *big.Int, m int) []int {
+	radix := big.NewInt(int64(f.radix))
+	value = new(big.Int).Set(value)
+	digit := new(big.Int)
+
+	x := make([]int, m)
+	for i := m - 1; i >= 0; i-- {
+		value.DivMod(value, radix, digit)
+		x[i] = int(digit.Int64())
+	}
+	return x
+}
+
+// fpeKey derives the FF1 key from key, so FF1 and AES-GCM never share key
+// material
+func fpeKey(key *CryptoKey) []byte {
+	mac := hmac.New(sha256.New, key.Key)
+	mac.Write([]byte(fpeKeyLabel))
+	return mac.Sum(nil)
+}
+
+// fpeCryptString encrypts or decrypts the characters of value found in
+// alphabet; other characters are kept in place
+func fpeCryptString(key *CryptoKey, alphabet, tweak, value string, encrypt bool) (string, error) {
+	chars := []rune(alphabet)
+	index := make(map[rune]int, len(chars))
+	for i, char := range chars {
+		if _, ok := index[char]; ok {
+			return "", fmt.Errorf("format-preserving alphabet repeats %q", char)
+		}
+		index[char] = i
+	}
+
+	f, err := newFF1(fpeKey(key), len(chars))
+	if err != nil {
+		return "", err
+	}
+
+	runes := []rune(value)
+	var positions, numerals []int
+	for i, r := range runes {
+		if numeral, ok := index[r]; ok {
+			positions = append(positions, i)
+			numerals = append(numerals, numeral)
+		}
+	}
+
+	if encrypt {
+		numerals, err = f.encrypt([]byte(tweak), numerals)
+	} else {
+		numerals, err = f.decrypt([]byte(tweak), numerals)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for i, position := range positions {
+		runes[position] = chars[numerals[i]]
+	}
+	return string(runes), nil
+}
+
+// fpeCryptIP encrypts or decrypts an IP address octet-wise, so IPv4 maps to
+// IPv4 and IPv6 to IPv6
+func fpeCryptIP(key *CryptoKey, tweak, ip string, encrypt bool) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	f, err := newFF1(fpeKey(key), 256)
+	if err != nil {
+		return "", err
+	}
+
+	octets := addr.AsSlice()
+	numerals := make([]int, len(octets))
+	for i, octet := range octets {
+		numerals[i] = int(octet)
+	}
+
+	if encrypt {
+		numerals, err = f.encrypt([]byte(tweak), numerals)
+	} else {
+		numerals, err = f.decrypt([]byte(tweak), numerals)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for i, numeral := range numerals {
+		octets[i] = byte(numeral)
+	}
+	result, _ := netip.AddrFromSlice(octets)
+	return result.String(), nil
+}
+
+// formatPreservingString encrypts data over rule.AllowedChars
+func (pe *PseudonymizationEngine) formatPreservingString(data, dataType string, rule *FormatPreservationRule, key *CryptoKey) (string, string, error) {
+	pseudo, err := fpeCryptString(key, rule.AllowedChars, dataType, data, true)
+	if err != nil {
+		return "", "", err
+	}
+	return pseudo, fpeHash(data, key), nil
+}
+
+// fpeHash returns the lookup hash of data
+func fpeHash(data string, key *CryptoKey) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(data))
+	hasher.Write(key.Salt)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
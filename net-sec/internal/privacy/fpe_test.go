@@ -0,0 +1,165 @@
+package privacy
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFF1Vectors(t *testing.T) {
+	// NIST SP 800-38G FF1 samples 1 to 3
+	const alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+	tests := []struct {
+		radix      int
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{10, "", "0123456789", "2433477484"},
+		{10, "39383736353433323130", "0123456789", "6124200773"},
+		{36, "3737373770717273373737", "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+	}
+
+	key, _ := hex.DecodeString("2B7E151628AED2A6ABF7158809CF4F3C")
+	for _, tt := range tests {
+		f, err := newFF1(key, tt.radix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tweak, _ := hex.DecodeString(tt.tweak)
+
+		numerals := make([]int, len(tt.plaintext))
+		for i, char := range tt.plaintext {
+			numerals[i] = strings.IndexRune(alphabet, char)
+		}
+
+		encrypted, err := f.encrypt(tweak, numerals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got strings.Builder
+		for _, numeral := range encrypted {
+			got.WriteByte(alphabet[numeral])
+		}
+		if got.String() != tt.ciphertext {
+			t.Errorf("radix %d: expected %s, got %s", tt.radix, tt.ciphertext, got.String())
+		}
+
+		decrypted, err := f.decrypt(tweak, encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range numerals {
+			if decrypted[i] != numerals[i] {
+				t.Fatalf("radix %d: decryption did not restore the plaintext", tt.radix)
+			}
+		}
+	}
+}
+
+func newFPEEngine(t *testing.T, rules ...FormatPreservationRule) *PseudonymizationEngine {
+	t.Helper()
+
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = FormatPreservingEncryption
+	config.PreservationRules = append(config.PreservationRules, rules...)
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// roundTrip pseudonymizes data and checks that it de-pseudonymizes back
+func roundTrip(t *testing.T, engine *PseudonymizationEngine, data, dataType string) string {
+	t.Helper()
+
+	pseudo, err := engine.Pseudonymize(data, dataType, "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	if pseudo.PseudonymizedValue == data {
+		t.Errorf("%s: pseudonym equals the data", data)
+	}
+
+	original, err := engine.DePseudonymize(pseudo, "analytics", "legitimate_interest")
+	if err != nil || original != data {
+		t.Errorf("%s: expected the original value, got %q, %v", data, original, err)
+	}
+	return pseudo.PseudonymizedValue
+}
+
+func TestFormatPreservingPhoneNumbers(t *testing.T) {
+	digits := "0123456789"
+	engine := newFPEEngine(t,
+		FormatPreservationRule{DataType: "phone", PreserveFormat: true, AllowedChars: digits},
+		FormatPreservationRule{DataType: "emergency_phone", PreserveFormat: true, AllowedChars: digits},
+	)
+
+	pseudo := roundTrip(t, engine, "5551234567", "phone")
+	if !regexp.MustCompile(`^[0-9]{10}$`).MatchString(pseudo) {
+		t.Errorf("expected a 10-digit number, got %s", pseudo)
+	}
+
+	// Characters outside the alphabet keep their place
+	if pseudo := roundTrip(t, engine, "555-123-4567", "phone"); !regexp.MustCompile(`^[0-9]{3}-[0-9]{3}-[0-9]{4}$`).MatchString(pseudo) {
+		t.Errorf("expected the separators to be kept, got %s", pseudo)
+	}
+
+	if other := roundTrip(t, engine, "5551234567", "emergency_phone"); other == pseudo {
+		t.Error("expected the data type to tweak the ciphertext")
+	}
+}
+
+func TestFormatPreservingEmailKeepsDomain(t *testing.T) {
+	engine := newFPEEngine(t)
+
+	for _, email := range []string{"alice@example.com", "bob.smith+news@Mail.Example.org"} {
+		pseudo := roundTrip(t, engine, email, "email")
+
+		at := strings.LastIndex(email, "@")
+		if !strings.HasSuffix(pseudo, email[at:]) {
+			t.Errorf("%s: expected the domain to be kept, got %s", email, pseudo)
+		}
+		if len([]rune(pseudo)) != len([]rune(email)) || strings.HasPrefix(pseudo, email[:at]) {
+			t.Errorf("%s: expected an encrypted local part of the same length, got %s", email, pseudo)
+		}
+	}
+
+	// 62^3 is below the FF1 minimum domain of 10^6
+	for _, email := range []string{"a@example.com", "abc@example.com"} {
+		if _, err := engine.Pseudonymize(email, "email", "analytics", "legitimate_interest"); err == nil {
+			t.Errorf("%s: expected a local part too short for FF1 to be rejected", email)
+		}
+	}
+}
+
+func TestFormatPreservingEmailWithRewrittenDomainIsOneWay(t *testing.T) {
+	engine := newEmailEngine(t, EmailDomainFixed, "pseudo.example.net")
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.DePseudonymize(pseudo, "analytics", "legitimate_interest"); err == nil {
+		t.Error("expected a rewritten domain not to be reversible")
+	}
+}
+
+func TestFormatPreservingIPAddresses(t *testing.T) {
+	engine := newFPEEngine(t)
+
+	for _, ip := range []string{"192.0.2.10", "2001:db8::1"} {
+		pseudo := roundTrip(t, engine, ip, "ip_address")
+
+		addr, err := netip.ParseAddr(pseudo)
+		if err != nil {
+			t.Fatalf("%s: pseudonym %q is not an address", ip, pseudo)
+		}
+		if addr.Is4() != netip.MustParseAddr(ip).Is4() {
+			t.Errorf("%s: expected the address family to be kept, got %s", ip, pseudo)
+		}
+	}
+}
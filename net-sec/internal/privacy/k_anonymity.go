@@ -0,0 +1,315 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// K-anonymization works on a batch of records rather than one value: the
+// quasi-identifiers of every record are generalized until each combination
+// of them is shared by at least k records. Generalization is full-domain, so
+// all values of a field are raised to the same level; the field with the
+// most distinct values is raised first. Records left in classes smaller than
+// k are dropped from the output.
+
+// ErrKAnonymizationNeedsRecords is returned when Pseudonymize is asked to
+// k-anonymize a single value
+var ErrKAnonymizationNeedsRecords = errors.New("k-anonymization applies to record batches; use KAnonymizer")
+
+// suppressedValue replaces a quasi-identifier generalized to its top level
+const suppressedValue = "*"
+
+// Generalizer maps the values of a quasi-identifier to coarser ones. Level 0
+// is the value itself; level Levels() must map every value to the same one.
+type Generalizer interface {
+	Levels() int
+	Generalize(value string, level int) (string, error)
+}
+
+// NumericRangeGeneralizer generalizes integers, such as ages, to ranges of
+// increasing width: with Widths 5 and 10, 34 becomes "30-34", then "30-39",
+// then "*"
+type NumericRangeGeneralizer struct {
+	Widths []int
+}
+
+// Levels returns the number of range widths plus full suppression
+func (g NumericRangeGeneralizer) Levels() int {
+	return len(g.Widths) + 1
+}
+
+// Generalize returns the range of width Widths[level-1] holding value
+func (g NumericRangeGeneralizer) Generalize(value string, level int) (string, error) {
+	if level >= g.Levels() {
+		return suppressedValue, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("%q is not an integer", value)
+	}
+	if level == 0 {
+		return strconv.Itoa(n), nil
+	}
+
+	width := g.Widths[level-1]
+	if width <= 0 {
+		return "", fmt.Errorf("range width %d must be positive", width)
+	}
+	low := n - ((n%width)+width)%width
+	return fmt.Sprintf("%d-%d", low, low+width-1), nil
+}
+
+// TruncationGeneralizer generalizes hierarchical codes, such as zip codes,
+// by masking one more trailing character per level: "02139" becomes
+// "0213*", then "021**", up to Characters masked, then "*"
+type TruncationGeneralizer struct {
+	Characters int
+}
+
+// Levels returns the number of maskable characters plus full suppression
+func (g TruncationGeneralizer) Levels() int {
+	return g.Characters + 1
+}
+
+// Generalize masks the last level characters of value
+func (g TruncationGeneralizer) Generalize(value string, level int) (string, error) {
+	if level >= g.Levels() {
+		return suppressedValue, nil
+	}
+
+	runes := []rune(value)
+	if level > len(runes) {
+		level = len(runes)
+	}
+	return string(runes[:len(runes)-level]) + strings.Repeat("*", level), nil
+}
+
+// suppressionGeneralizer keeps a value or suppresses it; it is used for
+// quasi-identifiers without a configured generalizer
+type suppressionGeneralizer struct{}
+
+func (suppressionGeneralizer) Levels() int { return 1 }
+
+func (suppressionGeneralizer) Generalize(value string, level int) (string, error) {
+	if level >= 1 {
+		return suppressedValue, nil
+	}
+	return value, nil
+}
+
+// KAnonymizerConfig contains configuration for k-anonymization
+type KAnonymizerConfig struct {
+	// Generalizers maps quasi-identifier fields to their generalization;
+	// other quasi-identifiers are either kept or suppressed
+	Generalizers map[string]Generalizer
+	// MaxSuppressionRate is the share of records that may be dropped
+	// instead of generalizing every record further
+	MaxSuppressionRate float64
+}
+
+// DefaultKAnonymizerConfig returns a configuration that suppresses up to 5%
+// of records before generalizing further
+func DefaultKAnonymizerConfig() *KAnonymizerConfig {
+	return &KAnonymizerConfig{
+		Generalizers:       map[string]Generalizer{},
+		MaxSuppressionRate: 0.05,
+	}
+}
+
+// KAnonymityReport describes the outcome of a k-anonymization
+type KAnonymityReport struct {
+	K                  int            `json:"k"`
+	AchievedK          int            `json:"achieved_k"` // smallest equivalence class in the output; 0 if every record was suppressed
+	Records            int            `json:"records"`
+	SuppressedRecords  int            `json:"suppressed_records"`
+	SuppressionRate    float64        `json:"suppression_rate"`
+	EquivalenceClasses int            `json:"equivalence_classes"`
+	Levels             map[string]int `json:"generalization_levels"`
+}
+
+// KAnonymizer generalizes and suppresses records to k-anonymity
+type KAnonymizer struct {
+	config *KAnonymizerConfig
+}
+
+// NewKAnonymizer creates a k-anonymizer
+func NewKAnonymizer(config *KAnonymizerConfig) *KAnonymizer {
+	if config == nil {
+		config = DefaultKAnonymizerConfig()
+	}
+	return &KAnonymizer{config: config}
+}
+
+// SetGeneralizer sets the generalization of a quasi-identifier field
+func (ka *KAnonymizer) SetGeneralizer(field string, generalizer Generalizer) {
+	if ka.config.Generalizers == nil {
+		ka.config.Generalizers = make(map[string]Generalizer)
+	}
+	ka.config.Generalizers[field] = generalizer
+}
+
+// Anonymize returns records with every combination of quasiIdentifiers
+// shared by at least k records. Fields other than the quasi-identifiers are
+// kept; records that cannot reach k are left out.
+func (ka *KAnonymizer) Anonymize(records []map[string]string, quasiIdentifiers []string, k int) ([]map[string]string, error) {
+	anonymized, _, err := ka.AnonymizeWithReport(records, quasiIdentifiers, k)
+	return anonymized, err
+}
+
+// AnonymizeWithReport is Anonymize that also reports the achieved k,
+// suppression rate and generalization levels
+func (ka *KAnonymizer) AnonymizeWithReport(records []map[string]string, quasiIdentifiers []string, k int) ([]map[string]string, *KAnonymityReport, error) {
+	if k < 1 {
+		return nil, nil, fmt.Errorf("k must be at least 1, got %d", k)
+	}
+	if len(quasiIdentifiers) == 0 {
+		return nil, nil, fmt.Errorf("no quasi-identifiers given")
+	}
+	for i, record := range records {
+		for _, field := range quasiIdentifiers {
+			if _, ok := record[field]; !ok {
+				return nil, nil, fmt.Errorf("record %d lacks quasi-identifier %q", i, field)
+			}
+		}
+	}
+
+	levels := make(map[string]int, len(quasiIdentifiers))
+	columns := make(map[string][]string, len(quasiIdentifiers))
+	for _, field := range quasiIdentifiers {
+		column, err := ka.generalizeColumn(records, field, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		columns[field] = column
+	}
+
+	maxSuppressed := int(math.Floor(ka.config.MaxSuppressionRate * float64(len(records))))
+	var classes map[string][]int
+	for {
+		classes = equivalenceClasses(columns, quasiIdentifiers, len(records))
+		if smallClassRecords(classes, k) <= maxSuppressed {
+			break
+		}
+
+		field := ka.nextField(columns, levels, quasiIdentifiers)
+		if field == "" {
+			break
+		}
+		levels[field]++
+		column, err := ka.generalizeColumn(records, field, levels[field])
+		if err != nil {
+			return nil, nil, err
+		}
+		columns[field] = column
+	}
+
+	kept := make([]bool, len(records))
+	report := &KAnonymityReport{K: k, Records: len(records), Levels: levels}
+	for _, members := range classes {
+		if len(members) < k {
+			report.SuppressedRecords += len(members)
+			continue
+		}
+		report.EquivalenceClasses++
+		if report.AchievedK == 0 || len(members) < report.AchievedK {
+			report.AchievedK = len(members)
+		}
+		for _, i := range members {
+			kept[i] = true
+		}
+	}
+	if len(records) > 0 {
+		report.SuppressionRate = float64(report.SuppressedRecords) / float64(len(records))
+	}
+
+	anonymized := make([]map[string]string, 0, len(records)-report.SuppressedRecords)
+	for i, record := range records {
+		if !kept[i] {
+			continue
+		}
+		out := make(map[string]string, len(record))
+		for field, value := range record {
+			out[field] = value
+		}
+		for _, field := range quasiIdentifiers {
+			out[field] = columns[field][i]
+		}
+		anonymized = append(anonymized, out)
+	}
+
+	return anonymized, report, nil
+}
+
+// generalizer returns the generalization of field
+func (ka *KAnonymizer) generalizer(field string) Generalizer {
+	if generalizer, ok := ka.config.Generalizers[field]; ok {
+		return generalizer
+	}
+	return suppressionGeneralizer{}
+}
+
+// generalizeColumn returns the values of field in records at level
+func (ka *KAnonymizer) generalizeColumn(records []map[string]string, field string, level int) ([]string, error) {
+	generalizer := ka.generalizer(field)
+	column := make([]string, len(records))
+	for i, record := range records {
+		value, err := generalizer.Generalize(record[field], level)
+		if err != nil {
+			return nil, fmt.Errorf("quasi-identifier %q of record %d: %w", field, i, err)
+		}
+		column[i] = value
+	}
+	return column, nil
+}
+
+// nextField returns the quasi-identifier to generalize next: the one with
+// the most distinct values that can still be generalized, or "" if none can
+func (ka *KAnonymizer) nextField(columns map[string][]string, levels map[string]int, quasiIdentifiers []string) string {
+	next, mostDistinct := "", 0
+	for _, field := range quasiIdentifiers {
+		if levels[field] >= ka.generalizer(field).Levels() {
+			continue
+		}
+		distinct := make(map[string]struct{})
+		for _, value := range columns[field] {
+			distinct[value] = struct{}{}
+		}
+		if len(distinct) > mostDistinct {
+			next, mostDistinct = field, len(distinct)
+		}
+	}
+	return next
+}
+
+// equivalenceClasses groups record indexes by their quasi-identifier values
+func equivalenceClasses(columns map[string][]string, quasiIdentifiers []string, n int) map[string][]int {
+	fields := append([]string(nil), quasiIdentifiers...)
+	sort.Strings(fields)
+
+	classes := make(map[string][]int)
+	values := make([]string, len(fields))
+	for i := 0; i < n; i++ {
+		for j, field := range fields {
+			values[j] = strconv.Quote(columns[field][i])
+		}
+		key := strings.Join(values, ",")
+		classes[key] = append(classes[key], i)
+	}
+	return classes
+}
+
+// smallClassRecords returns the number of records in classes smaller than k
+func smallClassRecords(classes map[string][]int, k int) int {
+	count := 0
+	for _, members := range classes {
+		if len(members) < k {
+			count += len(members)
+		}
+	}
+	return count
+}
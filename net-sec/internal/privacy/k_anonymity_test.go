@@ -0,0 +1,122 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestKAnonymizer() *KAnonymizer {
+	anonymizer := NewKAnonymizer(&KAnonymizerConfig{MaxSuppressionRate: 0.1})
+	anonymizer.SetGeneralizer("age", NumericRangeGeneralizer{Widths: []int{5, 10, 20}})
+	anonymizer.SetGeneralizer("zip", TruncationGeneralizer{Characters: 5})
+	return anonymizer
+}
+
+func TestKAnonymizerReachesK(t *testing.T) {
+	var records []map[string]string
+	for i := 0; i < 30; i++ {
+		records = append(records, map[string]string{
+			"age":       fmt.Sprint(20 + i),
+			"zip":       fmt.Sprintf("021%02d", i%7),
+			"diagnosis": fmt.Sprintf("d%d", i),
+		})
+	}
+
+	anonymized, report, err := newTestKAnonymizer().AnonymizeWithReport(records, []string{"age", "zip"}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	classes := make(map[string]int)
+	for _, record := range anonymized {
+		classes[record["age"]+"|"+record["zip"]]++
+		if !strings.HasPrefix(record["diagnosis"], "d") {
+			t.Errorf("expected non-identifying fields to be kept, got %v", record)
+		}
+	}
+	for class, size := range classes {
+		if size < 3 {
+			t.Errorf("class %s has %d records", class, size)
+		}
+	}
+
+	if report.AchievedK < 3 || report.EquivalenceClasses != len(classes) || report.Records != 30 ||
+		report.SuppressedRecords != 30-len(anonymized) || report.SuppressionRate > 0.1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if records[0]["age"] != "20" {
+		t.Error("expected the input records to be left unchanged")
+	}
+}
+
+func TestKAnonymizerGeneralizations(t *testing.T) {
+	ages := NumericRangeGeneralizer{Widths: []int{5, 10}}
+	for level, expected := range []string{"34", "30-34", "30-39", "*"} {
+		if got, err := ages.Generalize("34", level); err != nil || got != expected {
+			t.Errorf("age level %d: expected %s, got %s, %v", level, expected, got, err)
+		}
+	}
+	if _, err := ages.Generalize("thirty", 1); err == nil {
+		t.Error("expected a non-numeric age to be rejected")
+	}
+
+	zips := TruncationGeneralizer{Characters: 3}
+	for level, expected := range []string{"02139", "0213*", "021**", "02***", "*"} {
+		if got, err := zips.Generalize("02139", level); err != nil || got != expected {
+			t.Errorf("zip level %d: expected %s, got %s, %v", level, expected, got, err)
+		}
+	}
+}
+
+func TestKAnonymizerSuppressesOutliers(t *testing.T) {
+	records := []map[string]string{
+		{"age": "31", "zip": "02139"},
+		{"age": "32", "zip": "02138"},
+		{"age": "33", "zip": "02139"},
+		{"age": "34", "zip": "02134"},
+		{"age": "35", "zip": "02139"},
+		{"age": "36", "zip": "02131"},
+		{"age": "37", "zip": "02139"},
+		{"age": "38", "zip": "02132"},
+		{"age": "39", "zip": "02139"},
+		{"age": "30", "zip": "02135"},
+		{"age": "97", "zip": "94110"},
+	}
+
+	anonymized, report, err := newTestKAnonymizer().AnonymizeWithReport(records, []string{"age", "zip"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anonymized) != 10 || report.SuppressedRecords != 1 || report.AchievedK != 5 {
+		t.Fatalf("expected the outlier alone to be suppressed, got %v, %+v", anonymized, report)
+	}
+	for _, record := range anonymized {
+		if record["age"] == "97" || record["zip"] == "94110" {
+			t.Errorf("outlier leaked in %v", record)
+		}
+	}
+
+	// Too few records for k are all suppressed
+	anonymized, report, err = newTestKAnonymizer().AnonymizeWithReport(records[:3], []string{"age", "zip"}, 5)
+	if err != nil || len(anonymized) != 0 || report.AchievedK != 0 || report.SuppressionRate != 1 {
+		t.Errorf("expected every record to be suppressed, got %v, %+v, %v", anonymized, report, err)
+	}
+}
+
+func TestKAnonymizationNeedsRecords(t *testing.T) {
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = KAnonymization
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Pseudonymize("34", "age", "research", "consent"); !errors.Is(err, ErrKAnonymizationNeedsRecords) {
+		t.Errorf("expected ErrKAnonymizationNeedsRecords, got %v", err)
+	}
+
+	if _, err := newTestKAnonymizer().Anonymize([]map[string]string{{"age": "34"}}, []string{"age", "zip"}, 2); err == nil {
+		t.Error("expected a record without a quasi-identifier to be rejected")
+	}
+}
@@ -0,0 +1,186 @@
+package privacy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Environment variables NewPKCS11KeyBackendFromEnv reads
+const (
+	PKCS11LibraryEnv = "NETSEC_PKCS11_LIBRARY"
+	PKCS11SlotEnv    = "NETSEC_PKCS11_SLOT"
+	PKCS11PINEnv     = "NETSEC_PKCS11_PIN"
+)
+
+var (
+	// ErrHSMUnavailable is returned when a hardware security module is
+	// requested from a build without PKCS#11 support
+	ErrHSMUnavailable = errors.New("hardware security module support is not built in; rebuild with -tags pkcs11")
+	// ErrHSMNotConfigured is returned when a hardware security module is
+	// requested without a PKCS#11 library or slot
+	ErrHSMNotConfigured = errors.New("no PKCS#11 library or slot configured")
+	// ErrHSMUnsupportedAlgorithm is returned for algorithms that derive
+	// sub-keys from raw key material, which a hardware security module
+	// does not release
+	ErrHSMUnsupportedAlgorithm = errors.New("algorithm needs raw key material, which a hardware security module does not release")
+)
+
+// KeyBackend generates keys and encrypts with them using AES-256-GCM. The
+// software backend keeps raw key bytes in CryptoKey.Key; a hardware backend
+// keeps only a reference to a key object that never leaves the token.
+type KeyBackend interface {
+	// GenerateKey creates a size-byte key and returns its CryptoKey.Key
+	GenerateKey(size int) ([]byte, error)
+	// DestroyKey deletes the key behind key.Key and wipes it
+	DestroyKey(key *CryptoKey) error
+	// Seal encrypts plaintext, returning the nonce followed by the
+	// ciphertext
+	Seal(key *CryptoKey, plaintext []byte) ([]byte, error)
+	// Open decrypts the output of Seal
+	Open(key *CryptoKey, sealed []byte) ([]byte, error)
+}
+
+// PKCS11Config identifies the token a PKCS#11 key backend uses
+type PKCS11Config struct {
+	Library string // Path of the PKCS#11 module
+	Slot    uint
+	PIN     string // User PIN
+}
+
+// PKCS11ConfigFromEnv reads a PKCS#11 configuration from PKCS11LibraryEnv,
+// PKCS11SlotEnv and PKCS11PINEnv
+func PKCS11ConfigFromEnv() (*PKCS11Config, error) {
+	library, slot := os.Getenv(PKCS11LibraryEnv), os.Getenv(PKCS11SlotEnv)
+	if library == "" || slot == "" {
+		return nil, fmt.Errorf("%s and %s must be set: %w", PKCS11LibraryEnv, PKCS11SlotEnv, ErrHSMNotConfigured)
+	}
+
+	id, err := strconv.ParseUint(slot, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", PKCS11SlotEnv, slot, err)
+	}
+	return &PKCS11Config{Library: library, Slot: uint(id), PIN: os.Getenv(PKCS11PINEnv)}, nil
+}
+
+// NewPKCS11KeyBackendFromEnv opens the PKCS#11 token configured in the
+// environment
+func NewPKCS11KeyBackendFromEnv() (KeyBackend, error) {
+	if !pkcs11Supported {
+		return nil, ErrHSMUnavailable
+	}
+
+	config, err := PKCS11ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewPKCS11KeyBackend(config)
+}
+
+// newKeyBackend returns the backend config asks for: its Backend, a
+// PKCS#11 token from the environment for HardwareSecurityModule, or software
+// keys
+func newKeyBackend(config *KeyManagerConfig) (KeyBackend, error) {
+	switch {
+	case config.Backend != nil:
+		return config.Backend, nil
+	case config.HardwareSecurityModule:
+		return NewPKCS11KeyBackendFromEnv()
+	default:
+		return softwareKeyBackend{}, nil
+	}
+}
+
+// holdsKeyMaterial reports whether backend keeps raw key bytes in
+// CryptoKey.Key
+func holdsKeyMaterial(backend KeyBackend) bool {
+	_, software := backend.(softwareKeyBackend)
+	return software
+}
+
+// softwareKeyBackend keeps keys in process memory
+type softwareKeyBackend struct{}
+
+func (softwareKeyBackend) GenerateKey(size int) ([]byte, error) {
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key bytes: %w", err)
+	}
+	return key, nil
+}
+
+func (softwareKeyBackend) DestroyKey(key *CryptoKey) error {
+	wipeKey(key)
+	return nil
+}
+
+func (softwareKeyBackend) Seal(key *CryptoKey, plaintext []byte) ([]byte, error) {
+	gcm, err := newKeyGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (softwareKeyBackend) Open(key *CryptoKey, sealed []byte) ([]byte, error) {
+	gcm, err := newKeyGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("encrypted data too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// newKeyGCM returns an AES-GCM cipher keyed with key.Key
+func newKeyGCM(key *CryptoKey) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// checkKeyBackend rejects algorithms that need raw key material when config
+// keeps keys on a hardware security module
+func checkKeyBackend(config *PseudonymizationConfig) error {
+	hardware := config.HardwareSecurityModule
+	if config.KeyBackend != nil {
+		hardware = !holdsKeyMaterial(config.KeyBackend)
+	}
+	if !hardware {
+		return nil
+	}
+
+	switch {
+	case config.Algorithm == FormatPreservingEncryption, config.Algorithm == AES256Deterministic:
+		return fmt.Errorf("algorithm %v: %w", config.Algorithm, ErrHSMUnsupportedAlgorithm)
+	case config.Algorithm == ReversibleTokenization && config.DeterministicTokens:
+		return fmt.Errorf("deterministic tokens: %w", ErrHSMUnsupportedAlgorithm)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !pkcs11
+
+package privacy
+
+// pkcs11Supported reports whether this build links the PKCS#11 backend
+const pkcs11Supported = false
+
+// NewPKCS11KeyBackend fails with ErrHSMUnavailable; build with -tags pkcs11
+// for hardware security module support
+func NewPKCS11KeyBackend(config *PKCS11Config) (KeyBackend, error) {
+	return nil, ErrHSMUnavailable
+}
@@ -0,0 +1,239 @@
+//go:build pkcs11
+
+package privacy
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+// pkcs11Supported reports whether this build links the PKCS#11 backend
+const pkcs11Supported = true
+
+const (
+	pkcs11KeyIDLen = 16
+	pkcs11KeyLabel = "net-sec-pseudonymization"
+	gcmNonceSize   = 12
+	gcmTagBits     = 128
+)
+
+// PKCS11KeyBackend keeps keys as non-extractable AES objects on a PKCS#11
+// token. CryptoKey.Key holds the object's CKA_ID, which stays valid across
+// sessions, rather than key bytes.
+type PKCS11KeyBackend struct {
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	mutex   sync.Mutex // A PKCS#11 session runs one operation at a time
+	handles map[string]pkcs11.ObjectHandle
+}
+
+// NewPKCS11KeyBackend loads the PKCS#11 module in config and logs in to its
+// slot
+func NewPKCS11KeyBackend(config *PKCS11Config) (KeyBackend, error) {
+	if config == nil || config.Library == "" {
+		return nil, ErrHSMNotConfigured
+	}
+
+	ctx := pkcs11.New(config.Library)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 library %s", config.Library)
+	}
+	if err := ctx.Initialize(); err != nil && !isPKCS11Error(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 library: %w", err)
+	}
+
+	session, err := ctx.OpenSession(config.Slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to open session on slot %d: %w", config.Slot, err)
+	}
+	if err := ctx.Login(session, pkcs11.CKU_USER, config.PIN); err != nil && !isPKCS11Error(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		ctx.CloseSession(session)
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to log in to slot %d: %w", config.Slot, err)
+	}
+
+	return &PKCS11KeyBackend{
+		ctx:     ctx,
+		session: session,
+		handles: make(map[string]pkcs11.ObjectHandle),
+	}, nil
+}
+
+// Close logs out and unloads the PKCS#11 module
+func (b *PKCS11KeyBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.ctx.Logout(b.session)
+	err := b.ctx.CloseSession(b.session)
+	b.ctx.Finalize()
+	b.ctx.Destroy()
+	return err
+}
+
+// GenerateKey creates a persistent, sensitive and non-extractable AES key
+// on the token and returns its CKA_ID
+func (b *PKCS11KeyBackend) GenerateKey(size int) ([]byte, error) {
+	id := make([]byte, pkcs11KeyIDLen)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	handle, err := b.ctx.GenerateKey(b.session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, size),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, pkcs11KeyLabel),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key on token: %w", err)
+	}
+
+	b.handles[string(id)] = handle
+	return id, nil
+}
+
+// DestroyKey deletes the key object from the token; keys already gone are
+// not an error
+func (b *PKCS11KeyBackend) DestroyKey(key *CryptoKey) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	handle, found, err := b.handle(key)
+	if err != nil {
+		return err
+	}
+	if found {
+		if err := b.ctx.DestroyObject(b.session, handle); err != nil {
+			return fmt.Errorf("failed to destroy key %d on token: %w", key.ID, err)
+		}
+	}
+
+	delete(b.handles, string(key.Key))
+	wipeKey(key)
+	return nil
+}
+
+// Seal encrypts plaintext with CKM_AES_GCM on the token
+func (b *PKCS11KeyBackend) Seal(key *CryptoKey, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	handle, err := b.requireHandle(key)
+	if err != nil {
+		return nil, err
+	}
+
+	params := pkcs11.NewGCMParams(nonce, nil, gcmTagBits)
+	defer params.Free()
+	if err := b.ctx.EncryptInit(b.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, handle); err != nil {
+		return nil, fmt.Errorf("failed to start encryption: %w", err)
+	}
+	ciphertext, err := b.ctx.Encrypt(b.session, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	// Some tokens replace the nonce with their own
+	if iv := params.IV(); len(iv) == gcmNonceSize {
+		nonce = iv
+	}
+	return append(nonce, ciphertext...), nil
+}
+
+// Open decrypts the output of Seal with CKM_AES_GCM on the token
+func (b *PKCS11KeyBackend) Open(key *CryptoKey, sealed []byte) ([]byte, error) {
+	if len(sealed) < gcmNonceSize {
+		return nil, fmt.Errorf("encrypted data too short")
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	handle, err := b.requireHandle(key)
+	if err != nil {
+		return nil, err
+	}
+
+	params := pkcs11.NewGCMParams(sealed[:gcmNonceSize], nil, gcmTagBits)
+	defer params.Free()
+	if err := b.ctx.DecryptInit(b.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, handle); err != nil {
+		return nil, fmt.Errorf("failed to start decryption: %w", err)
+	}
+	plaintext, err := b.ctx.Decrypt(b.session, sealed[gcmNonceSize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// requireHandle returns the object handle of key, failing if the token has
+// no such key; the caller holds mutex
+func (b *PKCS11KeyBackend) requireHandle(key *CryptoKey) (pkcs11.ObjectHandle, error) {
+	handle, found, err := b.handle(key)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("key %d not found on token", key.ID)
+	}
+	return handle, nil
+}
+
+// handle looks up the object handle of key by its CKA_ID; the caller holds
+// mutex
+func (b *PKCS11KeyBackend) handle(key *CryptoKey) (pkcs11.ObjectHandle, bool, error) {
+	if handle, ok := b.handles[string(key.Key)]; ok {
+		return handle, true, nil
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, key.Key),
+	}
+	if err := b.ctx.FindObjectsInit(b.session, template); err != nil {
+		return 0, false, fmt.Errorf("failed to search token: %w", err)
+	}
+	handles, _, err := b.ctx.FindObjects(b.session, 1)
+	b.ctx.FindObjectsFinal(b.session)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to search token: %w", err)
+	}
+	if len(handles) == 0 {
+		return 0, false, nil
+	}
+
+	b.handles[string(key.Key)] = handles[0]
+	return handles[0], true, nil
+}
+
+// isPKCS11Error reports whether err is the PKCS#11 return value code
+func isPKCS11Error(err error, code uint) bool {
+	var pkcs11Err pkcs11.Error
+	return errors.As(err, &pkcs11Err) && uint(pkcs11Err) == code
+}
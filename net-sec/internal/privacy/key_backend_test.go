@@ -0,0 +1,148 @@
+package privacy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHSM keeps software keys behind opaque references, like a token
+type fakeHSM struct {
+	mutex     sync.Mutex
+	keys      map[string]*CryptoKey
+	destroyed int
+}
+
+func newFakeHSM() *fakeHSM {
+	return &fakeHSM{keys: make(map[string]*CryptoKey)}
+}
+
+func (h *fakeHSM) GenerateKey(size int) ([]byte, error) {
+	material, err := softwareKeyBackend{}.GenerateKey(size)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ref := fmt.Sprintf("handle-%d", len(h.keys)+h.destroyed+1)
+	h.keys[ref] = &CryptoKey{Key: material}
+	return []byte(ref), nil
+}
+
+func (h *fakeHSM) DestroyKey(key *CryptoKey) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.keys[string(key.Key)]; ok {
+		delete(h.keys, string(key.Key))
+		h.destroyed++
+	}
+	wipeKey(key)
+	return nil
+}
+
+func (h *fakeHSM) inner(key *CryptoKey) (*CryptoKey, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	inner, ok := h.keys[string(key.Key)]
+	if !ok {
+		return nil, fmt.Errorf("key %d not found on token", key.ID)
+	}
+	return inner, nil
+}
+
+func (h *fakeHSM) Seal(key *CryptoKey, plaintext []byte) ([]byte, error) {
+	inner, err := h.inner(key)
+	if err != nil {
+		return nil, err
+	}
+	return softwareKeyBackend{}.Seal(inner, plaintext)
+}
+
+func (h *fakeHSM) Open(key *CryptoKey, sealed []byte) ([]byte, error) {
+	inner, err := h.inner(key)
+	if err != nil {
+		return nil, err
+	}
+	return softwareKeyBackend{}.Open(inner, sealed)
+}
+
+func TestHardwareBackendKeepsOnlyReferences(t *testing.T) {
+	hsm := newFakeHSM()
+	config := DefaultPseudonymizationConfig()
+	config.KeyBackend = hsm
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := engine.keyManager.GetActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(key.Key, []byte("handle-")) {
+		t.Errorf("expected the key to hold a reference, got %x", key.Key)
+	}
+
+	if err := engine.ForceRotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if original, err := engine.DePseudonymize(pseudo, "support", "contract"); err != nil || original != "alice@example.com" {
+		t.Errorf("expected the archived token key to decrypt, got %q, %v", original, err)
+	}
+
+	// Revoking the archived key destroys its token object
+	now := time.Now().Add(8 * 365 * 24 * time.Hour)
+	engine.keyManager.now = func() time.Time { return now }
+	if err := engine.keyManager.PruneArchivedKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if hsm.destroyed != 1 || len(hsm.keys) != 1 {
+		t.Errorf("expected one destroyed and one remaining key, got %d and %d", hsm.destroyed, len(hsm.keys))
+	}
+	if _, err := engine.DePseudonymize(pseudo, "support", "contract"); err == nil {
+		t.Error("expected the revoked key not to decrypt")
+	}
+}
+
+func TestHardwareBackendRejectsRawKeyAlgorithms(t *testing.T) {
+	for _, algorithm := range []PseudoAlgorithm{FormatPreservingEncryption, AES256Deterministic} {
+		config := DefaultPseudonymizationConfig()
+		config.Algorithm = algorithm
+		config.KeyBackend = newFakeHSM()
+		if _, err := NewPseudonymizationEngine(config, nil); !errors.Is(err, ErrHSMUnsupportedAlgorithm) {
+			t.Errorf("algorithm %v: expected ErrHSMUnsupportedAlgorithm, got %v", algorithm, err)
+		}
+	}
+
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = ReversibleTokenization
+	config.KeyBackend = newFakeHSM()
+	if _, err := NewPseudonymizationEngine(config, nil); err != nil {
+		t.Errorf("expected random tokens to work with a token, got %v", err)
+	}
+}
+
+func TestHardwareSecurityModuleNeedsConfiguration(t *testing.T) {
+	t.Setenv(PKCS11LibraryEnv, "")
+	t.Setenv(PKCS11SlotEnv, "")
+
+	_, err := NewKeyManager(&KeyManagerConfig{KeySize: 32, HardwareSecurityModule: true})
+	if !errors.Is(err, ErrHSMUnavailable) && !errors.Is(err, ErrHSMNotConfigured) {
+		t.Errorf("expected a missing HSM to be reported, got %v", err)
+	}
+
+	t.Setenv(PKCS11LibraryEnv, "/usr/lib/softhsm/libsofthsm2.so")
+	t.Setenv(PKCS11SlotEnv, "first")
+	if _, err := PKCS11ConfigFromEnv(); err == nil || !strings.Contains(err.Error(), PKCS11SlotEnv) {
+		t.Errorf("expected an invalid slot to be rejected, got %v", err)
+	}
+}
@@ -2,26 +2,66 @@ package privacy
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"time"
 )
 
-// NewKeyManager creates a new key manager instance
+// Key rotation types recorded in KeyRotationEvent.RotationType
+const (
+	RotationScheduled = "scheduled"
+	RotationManual    = "manual"
+	RotationForced    = "forced"
+	// RotationRetention marks revocations by PruneArchivedKeys outside a
+	// rotation
+	RotationRetention = "retention"
+)
+
+var (
+	// ErrRotationTooSoon is returned when an unforced rotation is requested
+	// within MinRotationInterval of the previous one
+	ErrRotationTooSoon = errors.New("key rotation requested too soon after the previous rotation")
+	// ErrKeyRevoked is returned by GetKey for keys whose archive retention
+	// has ended
+	ErrKeyRevoked = errors.New("key has been revoked")
+)
+
+// NewKeyManager creates a new key manager instance with an initial active
+// key. Keys live in config.Backend; with HardwareSecurityModule and no
+// Backend, they are generated on the PKCS#11 token configured in the
+// environment.
 func NewKeyManager(config *KeyManagerConfig) (*KeyManager, error) {
+	backend, err := newKeyBackend(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key backend: %w", err)
+	}
+
 	km := &KeyManager{
 		activeKeys:   make(map[int]*CryptoKey),
 		archivedKeys: make(map[int]*CryptoKey),
+		revokedKeys:  make(map[int]*CryptoKey),
 		config:       config,
-		currentKeyID: 1,
+		backend:      backend,
+		now:          time.Now,
+	}
+
+	if config.Store != nil {
+		if err := km.restoreKeys(); err != nil {
+			return nil, fmt.Errorf("failed to restore keys: %w", err)
+		}
 	}
 
 	// Generate initial key
-	if err := km.generateInitialKey(); err != nil {
-		return nil, fmt.Errorf("failed to generate initial key: %w", err)
+	if _, restored := km.activeKeys[km.currentKeyID]; !restored {
+		if err := km.generateInitialKey(); err != nil {
+			return nil, fmt.Errorf("failed to generate initial key: %w", err)
+		}
 	}
 
 	// Start key rotation scheduler
-	go km.keyRotationScheduler()
+	if config.RotationInterval > 0 {
+		go km.keyRotationScheduler()
+	}
 
 	return km, nil
 }
@@ -31,85 +71,217 @@ func (km *KeyManager) GetActiveKey() (*CryptoKey, error) {
 	km.mutex.RLock()
 	defer km.mutex.RUnlock()
 
-	for _, key := range km.activeKeys {
-		if key.Status == KeyActive {
-			return key, nil
-		}
+	if key, exists := km.activeKeys[km.currentKeyID]; exists && key.Status == KeyActive {
+		return key, nil
 	}
 
 	return nil, fmt.Errorf("no active key found")
 }
 
-// GetKey retrieves a key by ID (active or archived)
+// GetKey retrieves a key by ID (active or archived). Archived keys not in
+// memory are loaded from the key store. Revoked keys are returned, with their
+// key material wiped, together with ErrKeyRevoked.
 func (km *KeyManager) GetKey(keyID int) (*CryptoKey, error) {
 	km.mutex.RLock()
-	defer km.mutex.RUnlock()
+	key := km.cachedKey(keyID)
+	revoked := km.revokedKeys[keyID]
+	km.mutex.RUnlock()
+
+	if key != nil {
+		return key, nil
+	}
+	if revoked != nil {
+		return revoked, fmt.Errorf("key with ID %d: %w", keyID, ErrKeyRevoked)
+	}
+	if km.config.Store == nil {
+		return nil, fmt.Errorf("key with ID %d not found", keyID)
+	}
+	return km.loadArchivedKey(keyID)
+}
 
+// cachedKey returns the in-memory key with ID keyID, or nil; the caller
+// holds mutex
+func (km *KeyManager) cachedKey(keyID int) *CryptoKey {
 	// Check active keys first
 	if key, exists := km.activeKeys[keyID]; exists {
-		return key, nil
+		return key
 	}
 
 	// Check archived keys
 	if key, exists := km.archivedKeys[keyID]; exists {
-		return key, nil
+		return key
 	}
 
-	return nil, fmt.Errorf("key with ID %d not found", keyID)
+	return nil
 }
 
-// RotateKeys performs key rotation
+// RotateKeys performs a manual key rotation
 func (km *KeyManager) RotateKeys() error {
+	return km.Rotate(RotationManual)
+}
+
+// Rotate archives the active key and replaces it with a new one: the old
+// key moves from KeyActive through KeyRotating to KeyArchived, and each
+// transition is audited before a summary event for the rotation. Archived
+// keys stay available to GetKey until ArchiveRetention has passed. Unless
+// rotationType is RotationForced, rotations within MinRotationInterval of
+// the previous one fail with ErrRotationTooSoon. The whole sequence runs
+// under the write lock, so concurrent rotations cannot skip or reuse a key
+// ID.
+func (km *KeyManager) Rotate(rotationType string) error {
 	km.mutex.Lock()
 	defer km.mutex.Unlock()
 
 	event := KeyRotationEvent{
 		ID:           generateID(),
-		Timestamp:    time.Now(),
-		RotationType: "manual",
-	}
-
-	// Get current active key
-	var oldKey *CryptoKey
-	for _, key := range km.activeKeys {
-		if key.Status == KeyActive {
-			oldKey = key
-			break
-		}
+		Timestamp:    km.now(),
+		RotationType: rotationType,
 	}
 
+	oldKey := km.activeKeys[km.currentKeyID]
 	if oldKey != nil {
 		event.OldKeyID = oldKey.ID
 	}
 
+	if rotationType != RotationForced && km.config.MinRotationInterval > 0 && !km.lastRotation.IsZero() &&
+		event.Timestamp.Sub(km.lastRotation) < km.config.MinRotationInterval {
+		event.Success = false
+		event.ErrorMessage = ErrRotationTooSoon.Error()
+		km.logRotation(event)
+		return fmt.Errorf("last rotation at %s: %w", km.lastRotation.Format(time.RFC3339), ErrRotationTooSoon)
+	}
+
 	// Generate new key
 	newKey, err := km.generateKey()
 	if err != nil {
 		event.Success = false
 		event.ErrorMessage = err.Error()
-		if km.auditLog != nil {
-			km.auditLog.LogKeyRotation(event)
-		}
+		km.logRotation(event)
 		return fmt.Errorf("failed to generate new key: %w", err)
 	}
 
 	event.NewKeyID = newKey.ID
 
-	// Mark old key as rotating, new key as active
 	if oldKey != nil {
-		oldKey.Status = KeyRotating
-		// Archive old key after grace period
-		go km.archiveKeyAfterGracePeriod(oldKey, 24*time.Hour)
+		km.transition(oldKey, KeyRotating, rotationType)
+	}
+
+	if err := km.persistRotation(oldKey, newKey, event.Timestamp); err != nil {
+		km.backend.DestroyKey(newKey)
+		if oldKey != nil {
+			km.transition(oldKey, KeyActive, rotationType)
+		}
+		event.Success = false
+		event.ErrorMessage = err.Error()
+		km.logRotation(event)
+		return fmt.Errorf("failed to persist rotated keys: %w", err)
+	}
+
+	// Archive the old key; it is still needed to de-pseudonymize its data
+	if oldKey != nil {
+		delete(km.activeKeys, oldKey.ID)
+		oldKey.ArchivedAt = event.Timestamp
+		km.transition(oldKey, KeyArchived, rotationType)
+		km.archivedKeys[oldKey.ID] = oldKey
 	}
 
 	km.activeKeys[newKey.ID] = newKey
+	km.currentKeyID = newKey.ID
+	km.lastRotation = event.Timestamp
+	km.logTransition(newKey.ID, "new", newKey.Status, rotationType)
+
 	event.Success = true
+	if err := km.pruneArchivedKeys(rotationType); err != nil {
+		// The rotation itself succeeded; record why pruning did not
+		event.ErrorMessage = err.Error()
+	}
+	km.logRotation(event)
 
+	return nil
+}
+
+// transition moves key to status and audits the change; the caller holds
+// mutex
+func (km *KeyManager) transition(key *CryptoKey, status KeyStatus, rotationType string) {
+	from := key.Status.String()
+	key.Status = status
+	km.logTransition(key.ID, from, status, rotationType)
+}
+
+// logTransition audits a key moving from one status to another
+func (km *KeyManager) logTransition(keyID int, from string, to KeyStatus, rotationType string) {
+	km.logRotation(KeyRotationEvent{
+		ID:           generateID(),
+		Timestamp:    km.now(),
+		KeyID:        keyID,
+		Transition:   from + "->" + to.String(),
+		RotationType: rotationType,
+		Success:      true,
+	})
+}
+
+// logRotation sends event to the audit logger, if any
+func (km *KeyManager) logRotation(event KeyRotationEvent) {
 	if km.auditLog != nil {
 		km.auditLog.LogKeyRotation(event)
 	}
+}
 
-	return nil
+// PruneArchivedKeys wipes and revokes archived keys older than
+// ArchiveRetention. Their key store entries keep only the metadata.
+func (km *KeyManager) PruneArchivedKeys() error {
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	return km.pruneArchivedKeys(RotationRetention)
+}
+
+// pruneArchivedKeys revokes expired archived keys, recording rotationType
+// in their transition events; the caller holds mutex
+func (km *KeyManager) pruneArchivedKeys(rotationType string) error {
+	if km.config.ArchiveRetention <= 0 {
+		return nil
+	}
+
+	for id, key := range km.archivedKeys {
+		if !km.retentionEnded(key) {
+			continue
+		}
+
+		// Securely destroy key material
+		if err := km.backend.DestroyKey(key); err != nil {
+			return err
+		}
+		km.transition(key, KeyRevoked, rotationType)
+		delete(km.archivedKeys, id)
+		km.revokedKeys[id] = key
+	}
+
+	if km.config.Store == nil {
+		return nil
+	}
+	keys, err := km.config.Store.LoadKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load stored keys: %w", err)
+	}
+	defer wipeKeys(keys)
+	return km.pruneStoredKeys(keys)
+}
+
+// retentionEnded reports whether key was archived more than
+// ArchiveRetention ago
+func (km *KeyManager) retentionEnded(key *CryptoKey) bool {
+	if km.config.ArchiveRetention <= 0 || key.Status != KeyArchived || key.ArchivedAt.IsZero() {
+		return false
+	}
+	return key.ArchivedAt.Before(km.now().Add(-km.config.ArchiveRetention))
+}
+
+// wipeKey zeroes key's key material
+func wipeKey(key *CryptoKey) {
+	for i := range key.Key {
+		key.Key[i] = 0
+	}
 }
 
 // generateInitialKey generates the first key for the system
@@ -119,30 +291,38 @@ func (km *KeyManager) generateInitialKey() error {
 		return err
 	}
 
+	if km.config.Store != nil {
+		if err := km.config.Store.SaveKey(key); err != nil {
+			return fmt.Errorf("failed to persist key: %w", err)
+		}
+	}
+
 	km.activeKeys[key.ID] = key
+	km.currentKeyID = key.ID
 	return nil
 }
 
-// generateKey creates a new cryptographic key
+// generateKey creates a new cryptographic key with the next ID; the caller
+// updates currentKeyID once the key is in place
 func (km *KeyManager) generateKey() (*CryptoKey, error) {
-	keyBytes := make([]byte, km.config.KeySize)
-	if _, err := rand.Read(keyBytes); err != nil {
-		return nil, fmt.Errorf("failed to generate key bytes: %w", err)
-	}
-
 	salt := make([]byte, 16) // 128-bit salt
 	if _, err := rand.Read(salt); err != nil {
 		return nil, fmt.Errorf("failed to generate salt: %w", err)
 	}
 
-	km.currentKeyID++
+	keyBytes, err := km.backend.GenerateKey(km.config.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := km.now()
 	key := &CryptoKey{
-		ID:        km.currentKeyID,
+		ID:        km.currentKeyID + 1,
 		Key:       keyBytes,
 		Salt:      salt,
 		Algorithm: "AES-256-GCM",
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(km.config.RotationInterval),
+		CreatedAt: now,
+		ExpiresAt: now.Add(km.config.RotationInterval),
 		Status:    KeyActive,
 		Purpose:   "pseudonymization",
 	}
@@ -156,43 +336,27 @@ func (km *KeyManager) keyRotationScheduler() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		if err := km.RotateKeys(); err != nil {
+		if err := km.Rotate(RotationScheduled); err != nil {
 			// Log error but continue - manual intervention may be needed
 			fmt.Printf("Scheduled key rotation failed: %v\n", err)
 		}
 	}
 }
 
-// archiveKeyAfterGracePeriod archives a key after a grace period
-func (km *KeyManager) archiveKeyAfterGracePeriod(key *CryptoKey, gracePeriod time.Duration) {
-	time.Sleep(gracePeriod)
-
-	km.mutex.Lock()
-	defer km.mutex.Unlock()
-
-	// Move key from active to archived
-	delete(km.activeKeys, key.ID)
-	key.Status = KeyArchived
-	km.archivedKeys[key.ID] = key
-
-	// Schedule deletion after archive retention period
-	go km.deleteKeyAfterRetention(key, km.config.ArchiveRetention)
-}
-
-// deleteKeyAfterRetention securely deletes a key after retention period
-func (km *KeyManager) deleteKeyAfterRetention(key *CryptoKey, retentionPeriod time.Duration) {
-	time.Sleep(retentionPeriod)
-
-	km.mutex.Lock()
-	defer km.mutex.Unlock()
-
-	// Securely wipe key material
-	for i := range key.Key {
-		key.Key[i] = 0
+// String returns the lowercase name of the status
+func (s KeyStatus) String() string {
+	switch s {
+	case KeyActive:
+		return "active"
+	case KeyRotating:
+		return "rotating"
+	case KeyArchived:
+		return "archived"
+	case KeyRevoked:
+		return "revoked"
+	default:
+		return fmt.Sprintf("status(%d)", int(s))
 	}
-
-	key.Status = KeyRevoked
-	delete(km.archivedKeys, key.ID)
 }
 
 // GetKeyMetrics returns metrics about key management
@@ -203,8 +367,9 @@ func (km *KeyManager) GetKeyMetrics() *KeyMetrics {
 	return &KeyMetrics{
 		ActiveKeys:   len(km.activeKeys),
 		ArchivedKeys: len(km.archivedKeys),
+		RevokedKeys:  len(km.revokedKeys),
 		TotalKeys:    km.currentKeyID,
-		LastRotation: time.Now(), // This would track actual last rotation
+		LastRotation: km.lastRotation,
 	}
 }
 
@@ -212,6 +377,7 @@ func (km *KeyManager) GetKeyMetrics() *KeyMetrics {
 type KeyMetrics struct {
 	ActiveKeys   int       `json:"active_keys"`
 	ArchivedKeys int       `json:"archived_keys"`
+	RevokedKeys  int       `json:"revoked_keys"`
 	TotalKeys    int       `json:"total_keys"`
 	LastRotation time.Time `json:"last_rotation"`
 }
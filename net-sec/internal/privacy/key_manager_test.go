@@ -0,0 +1,271 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestKeyManager(t *testing.T) *KeyManager {
+	t.Helper()
+
+	km, err := NewKeyManager(&KeyManagerConfig{KeySize: 32, ArchiveRetention: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+func TestNewKeyManagerHasActiveKey(t *testing.T) {
+	km := newTestKeyManager(t)
+
+	key, err := km.GetActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != 1 || key.Status != KeyActive || len(key.Key) != 32 || len(key.Salt) != 16 {
+		t.Errorf("unexpected initial key %+v", key)
+	}
+	if got, err := km.GetKey(1); err != nil || got != key {
+		t.Errorf("expected GetKey to return the initial key, got %+v, %v", got, err)
+	}
+}
+
+func TestRotateKeysArchivesPreviousKey(t *testing.T) {
+	km := newTestKeyManager(t)
+	auditLog := &recordingAuditLog{}
+	km.auditLog = auditLog
+
+	for want := 2; want <= 3; want++ {
+		if err := km.RotateKeys(); err != nil {
+			t.Fatal(err)
+		}
+		active, err := km.GetActiveKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active.ID != want {
+			t.Errorf("expected active key %d, got %d", want, active.ID)
+		}
+	}
+
+	for _, id := range []int{1, 2} {
+		key, err := km.GetKey(id)
+		if err != nil {
+			t.Fatalf("archived key %d not found: %v", id, err)
+		}
+		if key.Status != KeyArchived || key.ArchivedAt.IsZero() {
+			t.Errorf("key %d should be archived: %+v", id, key)
+		}
+	}
+	if metrics := km.GetKeyMetrics(); metrics.ActiveKeys != 1 || metrics.ArchivedKeys != 2 || metrics.TotalKeys != 3 {
+		t.Errorf("unexpected key metrics %+v", metrics)
+	}
+
+	summaries := auditLog.rotationSummaries()
+	if len(summaries) != 2 {
+		t.Fatalf("expected a rotation event per rotation, got %+v", summaries)
+	}
+	if last := summaries[1]; last.OldKeyID != 2 || last.NewKeyID != 3 || !last.Success {
+		t.Errorf("unexpected rotation event %+v", last)
+	}
+}
+
+func TestRotateKeysPrunesExpiredArchives(t *testing.T) {
+	km := newTestKeyManager(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	km.now = func() time.Time { return now }
+
+	if err := km.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	first, err := km.GetKey(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Still within retention
+	now = now.Add(29 * 24 * time.Hour)
+	if err := km.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := km.GetKey(1); err != nil {
+		t.Fatalf("key 1 pruned before its retention ended: %v", err)
+	}
+
+	now = now.Add(2 * 24 * time.Hour)
+	if err := km.PruneArchivedKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := km.GetKey(1); err == nil {
+		t.Error("expected key 1 to be pruned after its retention")
+	}
+	if first.Status != KeyRevoked || first.Key[0] != 0 || first.Key[31] != 0 {
+		t.Errorf("pruned key was not wiped: %+v", first)
+	}
+	if _, err := km.GetKey(2); err != nil {
+		t.Errorf("key 2 pruned before its retention ended: %v", err)
+	}
+}
+
+func TestDePseudonymizeWithArchivedKey(t *testing.T) {
+	auditLog := &recordingAuditLog{}
+	engine, err := NewPseudonymizationEngine(nil, auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := engine.DePseudonymize(pseudo, "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original != "alice@example.com" {
+		t.Errorf("expected original value, got %q", original)
+	}
+
+	next, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.KeyVersion != pseudo.KeyVersion+1 {
+		t.Errorf("expected key version %d after rotation, got %d", pseudo.KeyVersion+1, next.KeyVersion)
+	}
+	if summaries := auditLog.rotationSummaries(); len(summaries) != 1 {
+		t.Errorf("expected the engine's audit logger to record the rotation, got %+v", summaries)
+	}
+}
+
+func TestConcurrentRotationsCreateOneKey(t *testing.T) {
+	km, err := NewKeyManager(&KeyManagerConfig{KeySize: 32, MinRotationInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog := &recordingAuditLog{}
+	km.auditLog = auditLog
+
+	const callers = 20
+	errs := make(chan error, callers)
+	var start sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < callers; i++ {
+		rotationType := RotationManual
+		if i%2 == 0 {
+			rotationType = RotationScheduled
+		}
+		go func() {
+			start.Wait()
+			errs <- km.Rotate(rotationType)
+		}()
+	}
+	start.Done()
+
+	succeeded := 0
+	for i := 0; i < callers; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrRotationTooSoon):
+			t.Errorf("unexpected rotation error: %v", err)
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("expected exactly one rotation to succeed, got %d", succeeded)
+	}
+	if active, err := km.GetActiveKey(); err != nil || active.ID != 2 {
+		t.Errorf("expected key version 2 to be active, got %+v, %v", active, err)
+	}
+	if summaries := auditLog.rotationSummaries(); len(summaries) != callers {
+		t.Errorf("expected an event per rotation attempt, got %d", len(summaries))
+	}
+}
+
+func TestRotationFloor(t *testing.T) {
+	km, err := NewKeyManager(&KeyManagerConfig{KeySize: 32, MinRotationInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	km.now = func() time.Time { return now }
+	auditLog := &recordingAuditLog{}
+	km.auditLog = auditLog
+
+	if err := km.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(30 * time.Minute)
+	if err := km.Rotate(RotationScheduled); !errors.Is(err, ErrRotationTooSoon) {
+		t.Fatalf("expected the floor to reject the rotation, got %v", err)
+	}
+	if err := km.Rotate(RotationForced); err != nil {
+		t.Fatalf("forced rotation failed: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if err := km.Rotate(RotationScheduled); err != nil {
+		t.Fatalf("rotation after the floor failed: %v", err)
+	}
+
+	var types []string
+	for _, event := range auditLog.rotationSummaries() {
+		types = append(types, fmt.Sprintf("%s:%t", event.RotationType, event.Success))
+	}
+	if got := strings.Join(types, ","); got != "manual:true,scheduled:false,forced:true,scheduled:true" {
+		t.Errorf("unexpected rotation events %s", got)
+	}
+	if metrics := km.GetKeyMetrics(); !metrics.LastRotation.Equal(now) || metrics.TotalKeys != 4 {
+		t.Errorf("unexpected key metrics %+v", metrics)
+	}
+}
+
+func TestRotationAuditsKeyTransitions(t *testing.T) {
+	km := newTestKeyManager(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	km.now = func() time.Time { return now }
+	auditLog := &recordingAuditLog{}
+	km.auditLog = auditLog
+
+	if err := km.Rotate(RotationScheduled); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(31 * 24 * time.Hour)
+	if err := km.PruneArchivedKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	var transitions []string
+	for _, event := range auditLog.rotations {
+		if event.Transition != "" {
+			transitions = append(transitions, fmt.Sprintf("%d:%s:%s", event.KeyID, event.Transition, event.RotationType))
+		}
+	}
+	want := "1:active->rotating:scheduled,1:rotating->archived:scheduled,2:new->active:scheduled,1:archived->revoked:retention"
+	if got := strings.Join(transitions, ","); got != want {
+		t.Errorf("unexpected transitions %s", got)
+	}
+
+	revoked, err := km.GetKey(1)
+	if !errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("expected ErrKeyRevoked, got %v", err)
+	}
+	if revoked == nil || revoked.Status != KeyRevoked {
+		t.Errorf("expected the revoked key's metadata, got %+v", revoked)
+	}
+	if _, err := km.GetKey(7); err == nil || errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("expected an unknown key to fail differently, got %v", err)
+	}
+	if metrics := km.GetKeyMetrics(); metrics.RevokedKeys != 1 || metrics.ArchivedKeys != 0 {
+		t.Errorf("unexpected key metrics %+v", metrics)
+	}
+}
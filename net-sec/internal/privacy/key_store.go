@@ -0,0 +1,440 @@
+package privacy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyStorePassphraseEnv holds the passphrase NewFileKeyStoreFromEnv derives
+// the master key from
+const KeyStorePassphraseEnv = "NETSEC_KEYSTORE_PASSPHRASE"
+
+// Master key derivation parameters. The KDF and salt are recorded in the
+// store, so a store keeps opening after the configured KDF changes.
+const (
+	masterKeyLen     = 32
+	masterSaltLen    = 16
+	pbkdf2Iterations = 600000
+	scryptN          = 32768
+	argon2idTime     = 3
+	argon2idMemory   = 64 * 1024 // KiB
+	argon2idThreads  = 4
+)
+
+const (
+	keyStoreMetaFile = "keystore.json"
+	keyFilePrefix    = "key-"
+	keyFileSuffix    = ".json"
+	// masterCheckLabel is sealed under the master key to detect a wrong
+	// passphrase when the store is opened
+	masterCheckLabel = "net-sec/pseudonymization/key-store"
+)
+
+// ErrWrongPassphrase is returned when a key store is opened with a
+// passphrase other than the one it was created with
+var ErrWrongPassphrase = errors.New("key store passphrase is incorrect")
+
+// KeyStore persists KeyManager keys so pseudonyms stay reversible across
+// restarts
+type KeyStore interface {
+	SaveKey(key *CryptoKey) error
+	LoadKeys() ([]*CryptoKey, error)
+	DeleteKey(id int) error
+}
+
+// FileKeyStore keeps each key in its own file under a directory, with the
+// key material wrapped by a master key derived from a passphrase
+type FileKeyStore struct {
+	dir    string
+	master cipher.AEAD
+}
+
+// keyStoreMeta is the store's keystore.json
+type keyStoreMeta struct {
+	KDF   KeyDerivationFunc `json:"kdf"`
+	Salt  []byte            `json:"salt"`
+	Check []byte            `json:"check"`
+}
+
+// storedKey is a key file; WrappedKey is Key sealed under the master key
+// with the key ID as additional data
+type storedKey struct {
+	ID         int       `json:"id"`
+	WrappedKey []byte    `json:"wrapped_key"`
+	Salt       []byte    `json:"salt"`
+	Algorithm  string    `json:"algorithm"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Status     KeyStatus `json:"status"`
+	Purpose    string    `json:"purpose"`
+	ArchivedAt time.Time `json:"archived_at,omitempty"`
+}
+
+// NewFileKeyStoreFromEnv opens the store in dir with the passphrase in
+// KeyStorePassphraseEnv
+func NewFileKeyStoreFromEnv(dir string, kdf KeyDerivationFunc) (*FileKeyStore, error) {
+	passphrase := os.Getenv(KeyStorePassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("%s is not set", KeyStorePassphraseEnv)
+	}
+	return NewFileKeyStore(dir, []byte(passphrase), kdf)
+}
+
+// NewFileKeyStore opens the store in dir, creating it with kdf if it does
+// not exist yet
+func NewFileKeyStore(dir string, passphrase []byte, kdf KeyDerivationFunc) (*FileKeyStore, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("key store passphrase is empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key store: %w", err)
+	}
+
+	metaPath := filepath.Join(dir, keyStoreMetaFile)
+	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return createFileKeyStore(dir, passphrase, kdf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key store: %w", err)
+	}
+
+	var meta keyStoreMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse key store: %w", err)
+	}
+	master, err := newMasterAEAD(passphrase, meta.KDF, meta.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := openWrapped(master, meta.Check, []byte(masterCheckLabel)); err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return &FileKeyStore{dir: dir, master: master}, nil
+}
+
+// createFileKeyStore initializes a new store in dir
+func createFileKeyStore(dir string, passphrase []byte, kdf KeyDerivationFunc) (*FileKeyStore, error) {
+	salt := make([]byte, masterSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	master, err := newMasterAEAD(passphrase, kdf, salt)
+	if err != nil {
+		return nil, err
+	}
+	check, err := sealWrapped(master, nil, []byte(masterCheckLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	store := &FileKeyStore{dir: dir, master: master}
+	if err := store.writeJSON(keyStoreMetaFile, keyStoreMeta{KDF: kdf, Salt: salt, Check: check}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// SaveKey writes key, replacing any earlier version of it
+func (s *FileKeyStore) SaveKey(key *CryptoKey) error {
+	wrapped, err := sealWrapped(s.master, key.Key, keyAdditionalData(key.ID))
+	if err != nil {
+		return err
+	}
+
+	return s.writeJSON(keyFileName(key.ID), storedKey{
+		ID:         key.ID,
+		WrappedKey: wrapped,
+		Salt:       key.Salt,
+		Algorithm:  key.Algorithm,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		Status:     key.Status,
+		Purpose:    key.Purpose,
+		ArchivedAt: key.ArchivedAt,
+	})
+}
+
+// LoadKeys returns every stored key, unwrapped
+func (s *FileKeyStore) LoadKeys() ([]*CryptoKey, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key store: %w", err)
+	}
+
+	var keys []*CryptoKey
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, keyFilePrefix) || !strings.HasSuffix(name, keyFileSuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		var stored storedKey
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		keyBytes, err := openWrapped(s.master, stored.WrappedKey, keyAdditionalData(stored.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap %s: %w", name, err)
+		}
+
+		keys = append(keys, &CryptoKey{
+			ID:         stored.ID,
+			Key:        keyBytes,
+			Salt:       stored.Salt,
+			Algorithm:  stored.Algorithm,
+			CreatedAt:  stored.CreatedAt,
+			ExpiresAt:  stored.ExpiresAt,
+			Status:     stored.Status,
+			Purpose:    stored.Purpose,
+			ArchivedAt: stored.ArchivedAt,
+		})
+	}
+	return keys, nil
+}
+
+// DeleteKey removes the key with the given ID
+func (s *FileKeyStore) DeleteKey(id int) error {
+	if err := os.Remove(filepath.Join(s.dir, keyFileName(id))); err != nil {
+		return fmt.Errorf("failed to delete key %d: %w", id, err)
+	}
+	return nil
+}
+
+// writeJSON writes v to name in the store through a temporary file, so a
+// crash never leaves a partial key file
+func (s *FileKeyStore) writeJSON(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func keyFileName(id int) string {
+	return keyFilePrefix + strconv.Itoa(id) + keyFileSuffix
+}
+
+func keyAdditionalData(id int) []byte {
+	return []byte("key:" + strconv.Itoa(id))
+}
+
+// newMasterAEAD derives the master key from passphrase with kdf
+func newMasterAEAD(passphrase []byte, kdf KeyDerivationFunc, salt []byte) (cipher.AEAD, error) {
+	var master []byte
+	switch kdf {
+	case PBKDF2_SHA256:
+		master = pbkdf2.Key(passphrase, salt, pbkdf2Iterations, masterKeyLen, sha256.New)
+	case Scrypt:
+		var err error
+		if master, err = scrypt.Key(passphrase, salt, scryptN, 8, 1, masterKeyLen); err != nil {
+			return nil, fmt.Errorf("scrypt failed: %w", err)
+		}
+	case Argon2id:
+		master = argon2.IDKey(passphrase, salt, argon2idTime, argon2idMemory, argon2idThreads, masterKeyLen)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function")
+	}
+
+	block, err := aes.NewCipher(master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// sealWrapped encrypts plaintext under master, prefixed with the nonce
+func sealWrapped(master cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, master.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return master.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openWrapped reverses sealWrapped
+func openWrapped(master cipher.AEAD, wrapped, additionalData []byte) ([]byte, error) {
+	if len(wrapped) < master.NonceSize() {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	nonce, ciphertext := wrapped[:master.NonceSize()], wrapped[master.NonceSize():]
+	return master.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// restoreKeys loads the current key from the store and makes the next
+// generated key follow the highest stored ID. Archived keys stay in the
+// store until GetKey needs them; revoked keys are kept in memory without
+// their key material, so GetKey keeps reporting them as revoked.
+func (km *KeyManager) restoreKeys() error {
+	keys, err := km.config.Store.LoadKeys()
+	if err != nil {
+		return err
+	}
+
+	var current *CryptoKey
+	for _, key := range keys {
+		if key.ID > km.currentKeyID {
+			km.currentKeyID = key.ID
+		}
+		if key.Status == KeyActive && (current == nil || key.ID > current.ID) {
+			current = key
+		}
+	}
+
+	for _, key := range keys {
+		if key == current || (key.Status != KeyActive && key.Status != KeyRotating) {
+			continue
+		}
+		// A rotation that failed halfway left an older key active
+		key.Status = KeyArchived
+		key.ArchivedAt = km.now()
+		if err := km.config.Store.SaveKey(key); err != nil {
+			return err
+		}
+	}
+
+	if current != nil && current.ID == km.currentKeyID {
+		km.activeKeys[current.ID] = current
+		if current.ID > 1 {
+			km.lastRotation = current.CreatedAt
+		}
+	}
+
+	err = km.pruneStoredKeys(keys)
+	for _, key := range keys {
+		if key != km.activeKeys[key.ID] {
+			wipeKey(key)
+		}
+		if key.Status == KeyRevoked {
+			km.revokedKeys[key.ID] = key
+		}
+	}
+	return err
+}
+
+// loadArchivedKey loads an archived key from the store into memory
+func (km *KeyManager) loadArchivedKey(keyID int) (*CryptoKey, error) {
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	if key := km.cachedKey(keyID); key != nil {
+		return key, nil
+	}
+
+	keys, err := km.config.Store.LoadKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key %d: %w", keyID, err)
+	}
+
+	var found *CryptoKey
+	for _, key := range keys {
+		if key.ID == keyID && key.Status == KeyArchived && !km.retentionEnded(key) {
+			found = key
+			continue
+		}
+		wipeKey(key)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("key with ID %d not found", keyID)
+	}
+
+	km.archivedKeys[keyID] = found
+	return found, nil
+}
+
+// persistRotation stores oldKey as archived at archivedAt and newKey as
+// active. On failure the store is left with oldKey active.
+func (km *KeyManager) persistRotation(oldKey, newKey *CryptoKey, archivedAt time.Time) error {
+	store := km.config.Store
+	if store == nil {
+		return nil
+	}
+
+	if oldKey != nil {
+		archived := *oldKey
+		archived.Status = KeyArchived
+		archived.ArchivedAt = archivedAt
+		if err := store.SaveKey(&archived); err != nil {
+			return err
+		}
+	}
+
+	if err := store.SaveKey(newKey); err != nil {
+		if oldKey != nil {
+			restored := *oldKey
+			restored.Status = KeyActive
+			store.SaveKey(&restored)
+		}
+		return err
+	}
+	return nil
+}
+
+// pruneStoredKeys destroys the key material of stored keys whose archive
+// retention has ended or that were revoked, and stores them as revoked
+// tombstones holding only their metadata
+func (km *KeyManager) pruneStoredKeys(keys []*CryptoKey) error {
+	for _, key := range keys {
+		tombstone := key.Status == KeyRevoked && len(key.Key) == 0
+		if tombstone || (key.Status != KeyRevoked && !km.retentionEnded(key)) {
+			continue
+		}
+		if err := km.backend.DestroyKey(key); err != nil {
+			return err
+		}
+		key.Key = nil
+		key.Status = KeyRevoked
+		if err := km.config.Store.SaveKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wipeKeys zeroes the key material of keys
+func wipeKeys(keys []*CryptoKey) {
+	for _, key := range keys {
+		wipeKey(key)
+	}
+}
@@ -0,0 +1,193 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestKeyStore(t *testing.T, dir string) *FileKeyStore {
+	t.Helper()
+
+	store, err := NewFileKeyStore(dir, []byte("correct horse battery staple"), Scrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func newStoredEngine(t *testing.T, store KeyStore) *PseudonymizationEngine {
+	t.Helper()
+
+	config := DefaultPseudonymizationConfig()
+	config.KeyStore = store
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestKeysSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	engine := newStoredEngine(t, newTestKeyStore(t, dir))
+
+	before, err := engine.Pseudonymize("alice@example.com", "name", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := engine.Pseudonymize("bob@example.com", "name", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new engine over the same store resumes with the current key and
+	// loads the archived one on demand
+	restarted := newStoredEngine(t, newTestKeyStore(t, dir))
+	active, err := restarted.keyManager.GetActiveKey()
+	if err != nil || active.ID != after.KeyVersion {
+		t.Fatalf("expected key %d to be active after the restart, got %+v, %v", after.KeyVersion, active, err)
+	}
+	if metrics := restarted.keyManager.GetKeyMetrics(); metrics.ArchivedKeys != 0 {
+		t.Errorf("expected archived keys to stay in the store until needed, got %d in memory", metrics.ArchivedKeys)
+	}
+
+	for pseudo, want := range map[*PseudonymizedData]string{before: "alice@example.com", after: "bob@example.com"} {
+		original, err := restarted.DePseudonymize(pseudo, "support", "contract")
+		if err != nil || original != want {
+			t.Errorf("key %d: expected %q, got %q, %v", pseudo.KeyVersion, want, original, err)
+		}
+	}
+
+	if err := restarted.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := restarted.keyManager.GetActiveKey(); active.ID != after.KeyVersion+1 {
+		t.Errorf("expected rotation to continue from the stored IDs, got key %d", active.ID)
+	}
+}
+
+func TestKeyStoreNeverWritesKeyMaterial(t *testing.T) {
+	dir := t.TempDir()
+	km, err := NewKeyManager(&KeyManagerConfig{KeySize: 32, Store: newTestKeyStore(t, dir)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := km.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	var secrets [][]byte
+	for _, id := range []int{1, 2} {
+		key, err := km.GetKey(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secrets = append(secrets, key.Key, []byte(base64.StdEncoding.EncodeToString(key.Key)))
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range secrets {
+			if bytes.Contains(data, secret) {
+				t.Errorf("%s contains key material", file.Name())
+			}
+		}
+	}
+}
+
+func TestKeyStoreRejectsWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	newTestKeyStore(t, dir)
+
+	if _, err := NewFileKeyStore(dir, []byte("wrong"), Scrypt); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestKeyStoreFromEnv(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv(KeyStorePassphraseEnv, "")
+	if _, err := NewFileKeyStoreFromEnv(dir, Argon2id); err == nil {
+		t.Error("expected an unset passphrase to be rejected")
+	}
+
+	t.Setenv(KeyStorePassphraseEnv, "correct horse battery staple")
+	if _, err := NewFileKeyStoreFromEnv(dir, Argon2id); err != nil {
+		t.Fatal(err)
+	}
+	// The KDF recorded at creation is used when reopening
+	if _, err := NewFileKeyStoreFromEnv(dir, PBKDF2_SHA256); err != nil {
+		t.Errorf("expected the store to reopen with its recorded KDF, got %v", err)
+	}
+}
+
+func TestArchiveRetentionRevokesStoredKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestKeyStore(t, dir)
+	config := &KeyManagerConfig{KeySize: 32, ArchiveRetention: 30 * 24 * time.Hour, Store: store}
+	km, err := NewKeyManager(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	km.now = func() time.Time { return now }
+
+	if err := km.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, keyFileName(1))
+	if _, err := os.Stat(keyFile); err != nil {
+		t.Fatalf("expected the archived key to be stored: %v", err)
+	}
+
+	now = now.Add(31 * 24 * time.Hour)
+	if err := km.PruneArchivedKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := km.GetKey(1); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("expected the expired key to be revoked, got %v", err)
+	}
+	if _, err := km.GetKey(2); err != nil {
+		t.Errorf("expected the active key to remain: %v", err)
+	}
+
+	// Only a tombstone without key material is kept
+	keys, err := store.LoadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if key.ID == 1 && (key.Status != KeyRevoked || len(key.Key) != 0) {
+			t.Errorf("expected a revoked tombstone for key 1, got status %v with %d key bytes", key.Status, len(key.Key))
+		}
+	}
+
+	// The revocation is still reported after a restart
+	restarted, err := NewKeyManager(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := restarted.GetKey(1)
+	if !errors.Is(err, ErrKeyRevoked) || len(key.Key) != 0 {
+		t.Errorf("expected ErrKeyRevoked after a restart, got %v", err)
+	}
+	if metrics := restarted.GetKeyMetrics(); metrics.RevokedKeys != 1 {
+		t.Errorf("expected one revoked key, got %d", metrics.RevokedKeys)
+	}
+}
@@ -0,0 +1,87 @@
+package privacy
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetricsCountConcurrentOperations(t *testing.T) {
+	engine, err := NewPseudonymizationEngine(nil, &recordingAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				pseudo, err := engine.Pseudonymize(fmt.Sprintf("user%d-%d@example.com", i, j), "email", "analytics", "consent")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := engine.DePseudonymize(pseudo, "analytics", "consent"); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, err := engine.DePseudonymize(&PseudonymizedData{Algorithm: SHA256Hash}, "analytics", "consent"); err == nil {
+		t.Fatal("expected hashed pseudonyms not to be reversible")
+	}
+
+	metrics, err := engine.GetMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.TotalPseudonymizations != 40 || metrics.TotalDePseudonymizations != 40 || metrics.FailedOperations != 1 {
+		t.Errorf("unexpected operation counts %+v", metrics)
+	}
+	if metrics.AlgorithmDistribution[AES256Encryption] != 40 || len(metrics.AlgorithmDistribution) != 1 {
+		t.Errorf("unexpected algorithm distribution %v", metrics.AlgorithmDistribution)
+	}
+	if metrics.AuditedOperations != 81 || metrics.UnauditedOperations != 0 {
+		t.Errorf("expected every operation to be audited, got %+v", metrics)
+	}
+	if !metrics.KeyRotationCurrent || metrics.ActiveKeys != 1 || metrics.ComplianceScore != 100 {
+		t.Errorf("expected a full compliance score, got %+v", metrics)
+	}
+}
+
+func TestComplianceScore(t *testing.T) {
+	// Without an audit logger, only key rotation counts
+	engine, err := NewPseudonymizationEngine(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "consent"); err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := engine.GetMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.UnauditedOperations != 1 || metrics.ComplianceScore != 30 {
+		t.Errorf("expected an unaudited score of 30, got %+v", metrics)
+	}
+
+	// An audited engine whose active key has outlived the rotation interval
+	engine, err = NewPseudonymizationEngine(nil, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.keyManager.now = func() time.Time { return time.Now().Add(engine.config.KeyRotationInterval + time.Hour) }
+	metrics, err = engine.GetMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.KeyRotationCurrent || metrics.ComplianceScore != 70 {
+		t.Errorf("expected an overdue rotation to score 70, got %+v", metrics)
+	}
+}
@@ -0,0 +1,82 @@
+package privacy
+
+import "fmt"
+
+// PseudonymizationPreview describes how Pseudonymize would treat a value
+type PseudonymizationPreview struct {
+	DataType   string                  `json:"data_type"`
+	Purpose    string                  `json:"purpose"`
+	Algorithm  PseudoAlgorithm         `json:"algorithm"` // algorithm actually applied to this data type
+	Reversible bool                    `json:"reversible"`
+	Rule       *FormatPreservationRule `json:"rule,omitempty"`
+	KeyVersion int                     `json:"key_version"`
+	Sample     string                  `json:"sample"` // output for data under the current key
+}
+
+// Preview reports the algorithm, reversibility and format preservation rule
+// Pseudonymize would apply to data, with a sample of its output. Nothing is
+// persisted, audited or changed in the key manager.
+func (pe *PseudonymizationEngine) Preview(data, dataType, purpose string) (*PseudonymizationPreview, error) {
+	activeKey, err := pe.keyManager.GetActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active key: %w", err)
+	}
+
+	engine := pe
+	if pe.config.Algorithm == ReversibleTokenization {
+		// Issue the sample token into a scratch vault
+		scratch := *pe
+		scratch.vault = NewMemoryTokenVault()
+		engine = &scratch
+	}
+
+	sample, _, err := engine.applyAlgorithm(data, dataType, activeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, rule := pe.effectiveAlgorithm(dataType)
+	return &PseudonymizationPreview{
+		DataType:   dataType,
+		Purpose:    purpose,
+		Algorithm:  algorithm,
+		Reversible: reversible(algorithm, rule),
+		Rule:       rule,
+		KeyVersion: activeKey.ID,
+		Sample:     sample,
+	}, nil
+}
+
+// effectiveAlgorithm returns the algorithm applied to dataType and the
+// format preservation rule used, if any. Format-preserving encryption falls
+// back to AES256Encryption for data types it has no rule or format for;
+// data types other than email and IP addresses need AllowedChars.
+func (pe *PseudonymizationEngine) effectiveAlgorithm(dataType string) (PseudoAlgorithm, *FormatPreservationRule) {
+	if pe.config.Algorithm != FormatPreservingEncryption {
+		return pe.config.Algorithm, nil
+	}
+
+	rule := pe.preservationRule(dataType)
+	if rule == nil {
+		return AES256Encryption, nil
+	}
+	switch {
+	case dataType == "email", dataType == "ip_address", rule.AllowedChars != "":
+		return FormatPreservingEncryption, rule
+	default:
+		return AES256Encryption, nil
+	}
+}
+
+// reversible reports whether pseudonyms made with algorithm and rule can be
+// de-pseudonymized
+func reversible(algorithm PseudoAlgorithm, rule *FormatPreservationRule) bool {
+	switch algorithm {
+	case AES256Encryption, AES256Deterministic, ReversibleTokenization:
+		return true
+	case FormatPreservingEncryption:
+		return rule.DataType != "email" || rule.EmailDomain == EmailDomainUnchanged
+	default:
+		return false
+	}
+}
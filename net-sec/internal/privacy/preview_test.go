@@ -0,0 +1,128 @@
+package privacy
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingAuditLog collects pseudonymization and key rotation events
+type recordingAuditLog struct {
+	mu        sync.Mutex
+	events    []PseudonymizationEvent
+	rotations []KeyRotationEvent
+}
+
+func (r *recordingAuditLog) LogPseudonymization(event PseudonymizationEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingAuditLog) LogKeyRotation(event KeyRotationEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotations = append(r.rotations, event)
+	return nil
+}
+
+func (r *recordingAuditLog) LogDataAccess(event DataAccessEvent) error { return nil }
+
+// rotationSummaries returns the per-rotation events, without key status
+// transitions
+func (r *recordingAuditLog) rotationSummaries() []KeyRotationEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var summaries []KeyRotationEvent
+	for _, event := range r.rotations {
+		if event.Transition == "" {
+			summaries = append(summaries, event)
+		}
+	}
+	return summaries
+}
+
+func TestPreviewSelectsAlgorithmPerDataType(t *testing.T) {
+	tests := []struct {
+		algorithm  PseudoAlgorithm
+		dataType   string
+		data       string
+		want       PseudoAlgorithm
+		reversible bool
+		rule       bool
+	}{
+		{FormatPreservingEncryption, "email", "alice@example.com", FormatPreservingEncryption, true, true},
+		{FormatPreservingEncryption, "ip_address", "192.0.2.10", FormatPreservingEncryption, true, true},
+		{FormatPreservingEncryption, "name", "Alice", AES256Encryption, true, false},
+		{AES256Encryption, "email", "alice@example.com", AES256Encryption, true, false},
+		{AES256Deterministic, "email", "alice@example.com", AES256Deterministic, true, false},
+		{SHA256Hash, "email", "alice@example.com", SHA256Hash, false, false},
+		{ReversibleTokenization, "email", "alice@example.com", ReversibleTokenization, true, false},
+	}
+
+	for _, tt := range tests {
+		config := DefaultPseudonymizationConfig()
+		config.Algorithm = tt.algorithm
+		config.IterationCount = 1
+		auditLog := &recordingAuditLog{}
+		engine, err := NewPseudonymizationEngine(config, auditLog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := NewMemoryPseudonymStore()
+		engine.SetStore(store)
+
+		preview, err := engine.Preview(tt.data, tt.dataType, "analytics")
+		if err != nil {
+			t.Fatalf("%v/%s: %v", tt.algorithm, tt.dataType, err)
+		}
+
+		if preview.Algorithm != tt.want || preview.Reversible != tt.reversible || (preview.Rule != nil) != tt.rule {
+			t.Errorf("%v/%s: unexpected preview %+v", tt.algorithm, tt.dataType, preview)
+		}
+		if preview.Rule != nil && preview.Rule.DataType != tt.dataType {
+			t.Errorf("%v/%s: wrong rule %+v", tt.algorithm, tt.dataType, preview.Rule)
+		}
+		if preview.Sample == "" || strings.Contains(preview.Sample, tt.data) {
+			t.Errorf("%v/%s: sample %q does not mask the input", tt.algorithm, tt.dataType, preview.Sample)
+		}
+		if preview.DataType != tt.dataType || preview.Purpose != "analytics" || preview.KeyVersion == 0 {
+			t.Errorf("%v/%s: preview metadata missing: %+v", tt.algorithm, tt.dataType, preview)
+		}
+
+		if len(auditLog.events) != 0 || len(auditLog.rotations) != 0 {
+			t.Errorf("%v/%s: preview emitted audit events %+v", tt.algorithm, tt.dataType, auditLog.events)
+		}
+		if records, _ := store.ListByDataType(tt.dataType); len(records) != 0 {
+			t.Errorf("%v/%s: preview persisted %d pseudonyms", tt.algorithm, tt.dataType, len(records))
+		}
+	}
+}
+
+func TestPreviewMatchesPseudonymize(t *testing.T) {
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = FormatPreservingEncryption
+	auditLog := &recordingAuditLog{}
+	engine, err := NewPseudonymizationEngine(config, auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := engine.Preview("alice@example.com", "email", "analytics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "analytics", "legitimate_interest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if preview.Sample != pseudo.PseudonymizedValue || preview.KeyVersion != pseudo.KeyVersion {
+		t.Errorf("preview %+v does not match pseudonym %+v", preview, pseudo)
+	}
+	if len(auditLog.events) != 1 || auditLog.events[0].Operation != "pseudonymize" {
+		t.Errorf("expected only the real run to be audited, got %+v", auditLog.events)
+	}
+}
@@ -0,0 +1,102 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrPseudonymNotFound is returned when a store holds no record with the
+// requested ID
+var ErrPseudonymNotFound = errors.New("pseudonym not found")
+
+// PseudonymStore persists pseudonymized records so they can be
+// de-pseudonymized later by ID
+type PseudonymStore interface {
+	Save(data *PseudonymizedData) error
+	Get(id string) (*PseudonymizedData, error)
+	ListByDataType(dataType string) ([]*PseudonymizedData, error)
+	Delete(id string) error
+}
+
+// MemoryPseudonymStore keeps pseudonymized records in memory
+type MemoryPseudonymStore struct {
+	records map[string]*PseudonymizedData
+	mutex   sync.RWMutex
+}
+
+// NewMemoryPseudonymStore creates an empty in-memory store
+func NewMemoryPseudonymStore() *MemoryPseudonymStore {
+	return &MemoryPseudonymStore{records: make(map[string]*PseudonymizedData)}
+}
+
+// Save stores a copy of data, replacing any record with the same ID
+func (s *MemoryPseudonymStore) Save(data *PseudonymizedData) error {
+	if data == nil || data.ID == "" {
+		return fmt.Errorf("pseudonymized data requires an ID")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[data.ID] = copyPseudonymizedData(data)
+	return nil
+}
+
+// Get returns the record with the given ID
+func (s *MemoryPseudonymStore) Get(id string) (*PseudonymizedData, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, ok := s.records[id]
+	if !ok {
+		return nil, fmt.Errorf("pseudonym %s: %w", id, ErrPseudonymNotFound)
+	}
+	return copyPseudonymizedData(data), nil
+}
+
+// ListByDataType returns the records of a data type, oldest first
+func (s *MemoryPseudonymStore) ListByDataType(dataType string) ([]*PseudonymizedData, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var records []*PseudonymizedData
+	for _, data := range s.records {
+		if data.DataType == dataType {
+			records = append(records, copyPseudonymizedData(data))
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+// Delete removes the record with the given ID
+func (s *MemoryPseudonymStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.records[id]; !ok {
+		return fmt.Errorf("pseudonym %s: %w", id, ErrPseudonymNotFound)
+	}
+	delete(s.records, id)
+	return nil
+}
+
+// copyPseudonymizedData returns a copy of data that shares no metadata map
+func copyPseudonymizedData(data *PseudonymizedData) *PseudonymizedData {
+	copied := *data
+	if data.Metadata != nil {
+		copied.Metadata = make(map[string]interface{}, len(data.Metadata))
+		for key, value := range data.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	return &copied
+}
@@ -0,0 +1,157 @@
+package privacy
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const pseudonymSchema = `
+CREATE TABLE IF NOT EXISTS pseudonyms (
+	id                  TEXT PRIMARY KEY,
+	pseudonymized_value TEXT NOT NULL,
+	algorithm           INTEGER NOT NULL,
+	key_version         INTEGER NOT NULL,
+	created_at          TEXT NOT NULL,
+	data_type           TEXT NOT NULL,
+	purpose             TEXT NOT NULL,
+	metadata            TEXT NOT NULL,
+	hash_value          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pseudonyms_data_type ON pseudonyms (data_type, created_at);`
+
+// pseudonymTimeFormat is fixed width so created_at sorts chronologically
+const pseudonymTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+const pseudonymColumns = `id, pseudonymized_value, algorithm, key_version, created_at, data_type, purpose, metadata, hash_value`
+
+// SQLitePseudonymStore persists pseudonymized records in a SQLite database
+type SQLitePseudonymStore struct {
+	db *sql.DB
+}
+
+// NewSQLitePseudonymStore opens the SQLite database at path, creating the
+// schema if needed
+func NewSQLitePseudonymStore(path string) (*SQLitePseudonymStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pseudonym store: %w", err)
+	}
+	// SQLite allows a single writer; serialise access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(pseudonymSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create pseudonym schema: %w", err)
+	}
+	return &SQLitePseudonymStore{db: db}, nil
+}
+
+// Save stores data, replacing any record with the same ID
+func (s *SQLitePseudonymStore) Save(data *PseudonymizedData) error {
+	if data == nil || data.ID == "" {
+		return fmt.Errorf("pseudonymized data requires an ID")
+	}
+
+	metadata, err := json.Marshal(data.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for pseudonym %s: %w", data.ID, err)
+	}
+
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO pseudonyms (`+pseudonymColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		data.ID, data.PseudonymizedValue, int(data.Algorithm), data.KeyVersion,
+		data.CreatedAt.UTC().Format(pseudonymTimeFormat), data.DataType, data.Purpose, string(metadata), data.HashValue)
+	if err != nil {
+		return fmt.Errorf("failed to save pseudonym %s: %w", data.ID, err)
+	}
+	return nil
+}
+
+// Get returns the record with the given ID
+func (s *SQLitePseudonymStore) Get(id string) (*PseudonymizedData, error) {
+	row := s.db.QueryRow(`SELECT `+pseudonymColumns+` FROM pseudonyms WHERE id = ?`, id)
+
+	data, err := scanPseudonym(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("pseudonym %s: %w", id, ErrPseudonymNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pseudonym %s: %w", id, err)
+	}
+	return data, nil
+}
+
+// ListByDataType returns the records of a data type, oldest first
+func (s *SQLitePseudonymStore) ListByDataType(dataType string) ([]*PseudonymizedData, error) {
+	rows, err := s.db.Query(`SELECT `+pseudonymColumns+` FROM pseudonyms WHERE data_type = ? ORDER BY created_at, id`, dataType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s pseudonyms: %w", dataType, err)
+	}
+	defer rows.Close()
+
+	var records []*PseudonymizedData
+	for rows.Next() {
+		data, err := scanPseudonym(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s pseudonym: %w", dataType, err)
+		}
+		records = append(records, data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list %s pseudonyms: %w", dataType, err)
+	}
+	return records, nil
+}
+
+// Delete removes the record with the given ID
+func (s *SQLitePseudonymStore) Delete(id string) error {
+	result, err := s.db.Exec(`DELETE FROM pseudonyms WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete pseudonym %s: %w", id, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete pseudonym %s: %w", id, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("pseudonym %s: %w", id, ErrPseudonymNotFound)
+	}
+	return nil
+}
+
+// Close closes the underlying database
+func (s *SQLitePseudonymStore) Close() error {
+	return s.db.Close()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPseudonym decodes a row selected with pseudonymColumns
+func scanPseudonym(row rowScanner) (*PseudonymizedData, error) {
+	var data PseudonymizedData
+	var algorithm int
+	var createdAt, metadata string
+
+	err := row.Scan(&data.ID, &data.PseudonymizedValue, &algorithm, &data.KeyVersion,
+		&createdAt, &data.DataType, &data.Purpose, &metadata, &data.HashValue)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Algorithm = PseudoAlgorithm(algorithm)
+	if data.CreatedAt, err = time.Parse(pseudonymTimeFormat, createdAt); err != nil {
+		return nil, fmt.Errorf("invalid created_at %q: %w", createdAt, err)
+	}
+	if err := json.Unmarshal([]byte(metadata), &data.Metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return &data, nil
+}
@@ -0,0 +1,113 @@
+package privacy
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nopAuditLog discards pseudonymization audit events
+type nopAuditLog struct{}
+
+func (nopAuditLog) LogPseudonymization(event PseudonymizationEvent) error { return nil }
+func (nopAuditLog) LogKeyRotation(event KeyRotationEvent) error           { return nil }
+func (nopAuditLog) LogDataAccess(event DataAccessEvent) error             { return nil }
+
+func testPseudonymStore(t *testing.T, store PseudonymStore) {
+	t.Helper()
+
+	created := time.Date(2024, 4, 1, 8, 0, 0, 500, time.UTC)
+	records := []*PseudonymizedData{
+		{ID: "p2", PseudonymizedValue: "b", Algorithm: AES256Encryption, KeyVersion: 2, CreatedAt: created.Add(time.Minute), DataType: "email", Purpose: "support", Metadata: map[string]interface{}{"legal_basis": "contract"}, HashValue: "h2"},
+		{ID: "p1", PseudonymizedValue: "a", Algorithm: SHA256Hash, KeyVersion: 1, CreatedAt: created, DataType: "email", Purpose: "support", Metadata: map[string]interface{}{}, HashValue: "h1"},
+		{ID: "p3", PseudonymizedValue: "c", Algorithm: ReversibleTokenization, KeyVersion: 1, CreatedAt: created, DataType: "ip_address", Purpose: "security", HashValue: "h3"},
+	}
+	for _, record := range records {
+		if err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.Get("p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PseudonymizedValue != "b" || got.Algorithm != AES256Encryption || got.KeyVersion != 2 ||
+		!got.CreatedAt.Equal(records[0].CreatedAt) || got.Metadata["legal_basis"] != "contract" || got.HashValue != "h2" {
+		t.Errorf("unexpected record: %+v", got)
+	}
+
+	emails, err := store.ListByDataType("email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 2 || emails[0].ID != "p1" || emails[1].ID != "p2" {
+		t.Errorf("expected email records oldest first, got %+v", emails)
+	}
+
+	if err := store.Delete("p2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("p2"); !errors.Is(err, ErrPseudonymNotFound) {
+		t.Errorf("expected deleted record to be missing, got %v", err)
+	}
+	if err := store.Delete("p2"); !errors.Is(err, ErrPseudonymNotFound) {
+		t.Errorf("expected deleting a missing record to fail, got %v", err)
+	}
+}
+
+func TestMemoryPseudonymStore(t *testing.T) {
+	testPseudonymStore(t, NewMemoryPseudonymStore())
+}
+
+func TestSQLitePseudonymStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pseudonyms.db")
+	store, err := NewSQLitePseudonymStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPseudonymStore(t, store)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records survive reopening the database
+	store, err = NewSQLitePseudonymStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.Get("p1"); err != nil {
+		t.Errorf("expected p1 to persist: %v", err)
+	}
+}
+
+func TestPseudonymizePersistsToStore(t *testing.T) {
+	engine, err := NewPseudonymizationEngine(nil, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryPseudonymStore()
+	engine.SetStore(store)
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(pseudo.ID); err != nil {
+		t.Fatalf("expected pseudonym to be persisted: %v", err)
+	}
+
+	original, err := engine.DePseudonymizeByID(pseudo.ID, "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original != "alice@example.com" {
+		t.Errorf("expected original value, got %q", original)
+	}
+
+	if _, err := engine.DePseudonymizeByID("missing", "support", "contract"); !errors.Is(err, ErrPseudonymNotFound) {
+		t.Errorf("expected unknown ID to fail, got %v", err)
+	}
+}
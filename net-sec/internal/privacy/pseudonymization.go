@@ -1,15 +1,12 @@
 package privacy
-package privacy
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
-	"io"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,17 +20,26 @@ type PseudonymizationEngine struct {
 	config     *PseudonymizationConfig
 	keyManager *KeyManager
 	auditLog   AuditLogger
+	store      PseudonymStore
+	vault      TokenVault
+	metrics    *engineMetrics
 }
 
 // PseudonymizationConfig contains configuration for the pseudonymization engine
 type PseudonymizationConfig struct {
-	Algorithm           PseudoAlgorithm
-	KeyRotationInterval time.Duration
-	SaltLength          int
-	IterationCount      int
-	KeyDerivationFunc   KeyDerivationFunc
-	PreservationRules   []FormatPreservationRule
-	AuditEnabled        bool
+	Algorithm              PseudoAlgorithm
+	KeyRotationInterval    time.Duration
+	MinKeyRotationInterval time.Duration // Floor between unforced key rotations
+	SaltLength             int
+	IterationCount         int
+	KeyDerivationFunc      KeyDerivationFunc
+	PreservationRules      []FormatPreservationRule
+	AuditEnabled           bool
+	KeyStore               KeyStore   // Persists pseudonymization keys; nil keeps them in memory only
+	BatchWorkers           int        // Workers per batch call; defaults to runtime.NumCPU()
+	DeterministicTokens    bool       // ReversibleTokenization reuses a token for equal data under the same key
+	HardwareSecurityModule bool       // Keep keys on the PKCS#11 token configured in the environment
+	KeyBackend             KeyBackend // Generates and uses keys; overrides HardwareSecurityModule
 }
 
 // PseudoAlgorithm defines the pseudonymization algorithm
@@ -45,6 +51,9 @@ const (
 	FormatPreservingEncryption
 	ReversibleTokenization
 	KAnonymization
+	// AES256Deterministic maps equal plaintext and key to equal ciphertext,
+	// trading semantic security for equality joins; see deterministic.go
+	AES256Deterministic
 )
 
 // KeyDerivationFunc defines key derivation methods
@@ -63,6 +72,8 @@ type FormatPreservationRule struct {
 	PreserveLength  bool
 	AllowedChars    string
 	MaskingPattern  string
+	EmailDomain     EmailDomainMode // How email domains are pseudonymized
+	PseudonymDomain string          // Domain used with EmailDomainFixed
 }
 
 // PseudonymizedData represents pseudonymized data with metadata
@@ -82,10 +93,14 @@ type PseudonymizedData struct {
 type KeyManager struct {
 	activeKeys   map[int]*CryptoKey
 	archivedKeys map[int]*CryptoKey
+	revokedKeys  map[int]*CryptoKey // Wiped keys whose archive retention ended
 	config       *KeyManagerConfig
+	backend      KeyBackend
 	currentKeyID int
 	mutex        sync.RWMutex
 	auditLog     AuditLogger
+	now          func() time.Time
+	lastRotation time.Time
 }
 
 // CryptoKey represents a cryptographic key with metadata
@@ -98,6 +113,7 @@ type CryptoKey struct {
 	ExpiresAt   time.Time `json:"expires_at"`
 	Status      KeyStatus `json:"status"`
 	Purpose     string    `json:"purpose"`
+	ArchivedAt  time.Time `json:"archived_at,omitempty"`
 }
 
 // KeyStatus defines the lifecycle status of a key
@@ -114,9 +130,12 @@ const (
 type KeyManagerConfig struct {
 	KeySize             int
 	RotationInterval    time.Duration
+	MinRotationInterval time.Duration // Unforced rotations within this of the last are rejected
 	ArchiveRetention    time.Duration
 	BackupEncryption    bool
-	HardwareSecurityModule bool
+	HardwareSecurityModule bool // Keep keys on a PKCS#11 token; see key_backend.go
+	Store               KeyStore // Persists keys across restarts; nil keeps them in memory only
+	Backend             KeyBackend // Generates and uses keys; nil uses software keys, or PKCS#11 with HardwareSecurityModule
 }
 
 // AuditLogger interface for compliance logging
@@ -126,6 +145,19 @@ type AuditLogger interface {
 	LogDataAccess(event DataAccessEvent) error
 }
 
+// NullAuditLogger discards all audit events. The engine uses it when
+// constructed without an audit logger.
+type NullAuditLogger struct{}
+
+// LogPseudonymization discards the event
+func (NullAuditLogger) LogPseudonymization(event PseudonymizationEvent) error { return nil }
+
+// LogKeyRotation discards the event
+func (NullAuditLogger) LogKeyRotation(event KeyRotationEvent) error { return nil }
+
+// LogDataAccess discards the event
+func (NullAuditLogger) LogDataAccess(event DataAccessEvent) error { return nil }
+
 // PseudonymizationEvent represents an audit event for pseudonymization
 type PseudonymizationEvent struct {
 	ID            string                 `json:"id"`
@@ -147,7 +179,9 @@ type KeyRotationEvent struct {
 	Timestamp    time.Time `json:"timestamp"`
 	OldKeyID     int       `json:"old_key_id"`
 	NewKeyID     int       `json:"new_key_id"`
-	RotationType string    `json:"rotation_type"` // scheduled, emergency, manual
+	KeyID        int       `json:"key_id,omitempty"`     // Key whose status changed
+	Transition   string    `json:"transition,omitempty"` // e.g. "active->rotating"; empty for the rotation summary
+	RotationType string    `json:"rotation_type"`        // scheduled, manual, forced, retention
 	Success      bool      `json:"success"`
 	ErrorMessage string    `json:"error_message,omitempty"`
 }
@@ -173,20 +207,33 @@ func NewPseudonymizationEngine(config *PseudonymizationConfig, auditLog AuditLog
 	if config == nil {
 		config = DefaultPseudonymizationConfig()
 	}
+	if auditLog == nil {
+		auditLog = NullAuditLogger{}
+	}
+	if err := checkKeyBackend(config); err != nil {
+		return nil, err
+	}
 
 	keyManager, err := NewKeyManager(&KeyManagerConfig{
-		KeySize:          32, // 256-bit keys
-		RotationInterval: config.KeyRotationInterval,
-		ArchiveRetention: 7 * 365 * 24 * time.Hour, // 7 years for compliance
+		KeySize:             32, // 256-bit keys
+		RotationInterval:    config.KeyRotationInterval,
+		MinRotationInterval: config.MinKeyRotationInterval,
+		ArchiveRetention:    7 * 365 * 24 * time.Hour, // 7 years for compliance
+		HardwareSecurityModule: config.HardwareSecurityModule,
+		Store:               config.KeyStore,
+		Backend:             config.KeyBackend,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create key manager: %w", err)
 	}
+	keyManager.auditLog = auditLog
 
 	return &PseudonymizationEngine{
 		config:     config,
 		keyManager: keyManager,
 		auditLog:   auditLog,
+		vault:      NewMemoryTokenVault(),
+		metrics:    newEngineMetrics(),
 	}, nil
 }
 
@@ -202,8 +249,10 @@ func DefaultPseudonymizationConfig() *PseudonymizationConfig {
 			{
 				DataType:       "email",
 				PreserveFormat: true,
-				PreserveLength: false,
+				PreserveLength: true,
+				AllowedChars:   DefaultEmailAlphabet,
 				MaskingPattern: "****@****.***",
+				EmailDomain:    EmailDomainUnchanged,
 			},
 			{
 				DataType:       "ip_address",
@@ -218,6 +267,15 @@ func DefaultPseudonymizationConfig() *PseudonymizationConfig {
 
 // Pseudonymize converts personal data to pseudonymized form
 func (pe *PseudonymizationEngine) Pseudonymize(data string, dataType, purpose, legalBasis string) (*PseudonymizedData, error) {
+	result, event, err := pe.pseudonymize(data, dataType, purpose, legalBasis)
+	pe.recordOperation(event)
+	pe.logPseudonymization(event)
+	return result, err
+}
+
+// pseudonymize pseudonymizes data and returns the audit event for the
+// caller to log
+func (pe *PseudonymizationEngine) pseudonymize(data string, dataType, purpose, legalBasis string) (*PseudonymizedData, PseudonymizationEvent, error) {
 	event := PseudonymizationEvent{
 		ID:         generateID(),
 		Timestamp:  time.Now(),
@@ -233,31 +291,14 @@ func (pe *PseudonymizationEngine) Pseudonymize(data string, dataType, purpose, l
 	if err != nil {
 		event.Success = false
 		event.ErrorMessage = err.Error()
-		pe.auditLog.LogPseudonymization(event)
-		return nil, fmt.Errorf("failed to get active key: %w", err)
-	}
-
-	var pseudonymizedValue string
-	var hashValue string
-
-	switch pe.config.Algorithm {
-	case SHA256Hash:
-		pseudonymizedValue, hashValue, err = pe.hashPseudonymization(data, activeKey)
-	case AES256Encryption:
-		pseudonymizedValue, hashValue, err = pe.encryptionPseudonymization(data, activeKey)
-	case FormatPreservingEncryption:
-		pseudonymizedValue, hashValue, err = pe.formatPreservingPseudonymization(data, dataType, activeKey)
-	case ReversibleTokenization:
-		pseudonymizedValue, hashValue, err = pe.tokenizationPseudonymization(data, activeKey)
-	default:
-		err = fmt.Errorf("unsupported algorithm: %v", pe.config.Algorithm)
+		return nil, event, fmt.Errorf("failed to get active key: %w", err)
 	}
 
+	pseudonymizedValue, hashValue, err := pe.applyAlgorithm(data, dataType, activeKey)
 	if err != nil {
 		event.Success = false
 		event.ErrorMessage = err.Error()
-		pe.auditLog.LogPseudonymization(event)
-		return nil, err
+		return nil, event, err
 	}
 
 	result := &PseudonymizedData{
@@ -275,21 +316,63 @@ func (pe *PseudonymizationEngine) Pseudonymize(data string, dataType, purpose, l
 		},
 	}
 
-	event.Success = true
 	event.Metadata = map[string]interface{}{
 		"pseudonym_id": result.ID,
 		"key_version":  activeKey.ID,
 	}
 
-	if pe.config.AuditEnabled {
-		pe.auditLog.LogPseudonymization(event)
+	if pe.store != nil {
+		if err := pe.store.Save(result); err != nil {
+			event.Success = false
+			event.ErrorMessage = err.Error()
+			return nil, event, fmt.Errorf("failed to persist pseudonym: %w", err)
+		}
 	}
 
-	return result, nil
+	event.Success = true
+	return result, event, nil
+}
+
+// logPseudonymization records event when auditing is enabled
+func (pe *PseudonymizationEngine) logPseudonymization(event PseudonymizationEvent) {
+	if !pe.config.AuditEnabled || pe.auditLog == nil {
+		return
+	}
+	pe.auditLog.LogPseudonymization(event)
+}
+
+// applyAlgorithm pseudonymizes data with the configured algorithm and
+// returns the pseudonym and its lookup hash
+func (pe *PseudonymizationEngine) applyAlgorithm(data, dataType string, key *CryptoKey) (string, string, error) {
+	switch pe.config.Algorithm {
+	case SHA256Hash:
+		return pe.hashPseudonymization(data, key)
+	case AES256Encryption:
+		return pe.encryptionPseudonymization(data, key)
+	case FormatPreservingEncryption:
+		return pe.formatPreservingPseudonymization(data, dataType, key)
+	case ReversibleTokenization:
+		return pe.tokenizationPseudonymization(data, key)
+	case AES256Deterministic:
+		return pe.deterministicPseudonymization(data, key)
+	case KAnonymization:
+		return "", "", ErrKAnonymizationNeedsRecords
+	default:
+		return "", "", fmt.Errorf("unsupported algorithm: %v", pe.config.Algorithm)
+	}
 }
 
 // DePseudonymize converts pseudonymized data back to original form
 func (pe *PseudonymizationEngine) DePseudonymize(pseudoData *PseudonymizedData, purpose, legalBasis string) (string, error) {
+	originalData, event, err := pe.dePseudonymize(pseudoData, purpose, legalBasis)
+	pe.recordOperation(event)
+	pe.logPseudonymization(event)
+	return originalData, err
+}
+
+// dePseudonymize reverses pseudoData and returns the audit event for the
+// caller to log
+func (pe *PseudonymizationEngine) dePseudonymize(pseudoData *PseudonymizedData, purpose, legalBasis string) (string, PseudonymizationEvent, error) {
 	event := PseudonymizationEvent{
 		ID:         generateID(),
 		Timestamp:  time.Now(),
@@ -309,8 +392,7 @@ func (pe *PseudonymizationEngine) DePseudonymize(pseudoData *PseudonymizedData,
 	if err != nil {
 		event.Success = false
 		event.ErrorMessage = err.Error()
-		pe.auditLog.LogPseudonymization(event)
-		return "", fmt.Errorf("failed to get key version %d: %w", pseudoData.KeyVersion, err)
+		return "", event, fmt.Errorf("failed to get key version %d: %w", pseudoData.KeyVersion, err)
 	}
 
 	var originalData string
@@ -319,14 +401,15 @@ func (pe *PseudonymizationEngine) DePseudonymize(pseudoData *PseudonymizedData,
 	case SHA256Hash:
 		event.Success = false
 		event.ErrorMessage = "SHA256 hash is not reversible"
-		pe.auditLog.LogPseudonymization(event)
-		return "", fmt.Errorf("SHA256 hash pseudonymization is not reversible")
+		return "", event, fmt.Errorf("SHA256 hash pseudonymization is not reversible")
 	case AES256Encryption:
 		originalData, err = pe.decryptionDePseudonymization(pseudoData.PseudonymizedValue, key)
 	case FormatPreservingEncryption:
 		originalData, err = pe.formatPreservingDePseudonymization(pseudoData.PseudonymizedValue, pseudoData.DataType, key)
 	case ReversibleTokenization:
 		originalData, err = pe.tokenizationDePseudonymization(pseudoData.PseudonymizedValue, key)
+	case AES256Deterministic:
+		originalData, err = pe.deterministicDePseudonymization(pseudoData.PseudonymizedValue, key)
 	default:
 		err = fmt.Errorf("unsupported algorithm: %v", pseudoData.Algorithm)
 	}
@@ -334,16 +417,36 @@ func (pe *PseudonymizationEngine) DePseudonymize(pseudoData *PseudonymizedData,
 	if err != nil {
 		event.Success = false
 		event.ErrorMessage = err.Error()
-		pe.auditLog.LogPseudonymization(event)
-		return "", err
+		return "", event, err
 	}
 
 	event.Success = true
-	if pe.config.AuditEnabled {
-		pe.auditLog.LogPseudonymization(event)
+	return originalData, event, nil
+}
+
+// DePseudonymizeByID loads a persisted pseudonym from the store and converts
+// it back to original form
+func (pe *PseudonymizationEngine) DePseudonymizeByID(id, purpose, legalBasis string) (string, error) {
+	if pe.store == nil {
+		return "", fmt.Errorf("no pseudonym store configured")
 	}
 
-	return originalData, nil
+	pseudoData, err := pe.store.Get(id)
+	if err != nil {
+		return "", err
+	}
+	return pe.DePseudonymize(pseudoData, purpose, legalBasis)
+}
+
+// SetStore sets the store pseudonymized records are persisted to; nil
+// disables persistence
+func (pe *PseudonymizationEngine) SetStore(store PseudonymStore) {
+	pe.store = store
+}
+
+// Store returns the configured pseudonym store, or nil
+func (pe *PseudonymizationEngine) Store() PseudonymStore {
+	return pe.store
 }
 
 // hashPseudonymization performs irreversible hash-based pseudonymization
@@ -370,22 +473,10 @@ func (pe *PseudonymizationEngine) hashPseudonymization(data string, key *CryptoK
 
 // encryptionPseudonymization performs reversible encryption-based pseudonymization
 func (pe *PseudonymizationEngine) encryptionPseudonymization(data string, key *CryptoKey) (string, string, error) {
-	block, err := aes.NewCipher(key.Key)
+	encrypted, err := pe.keyManager.backend.Seal(key, []byte(data))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create cipher: %w", err)
+		return "", "", err
 	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create GCM: %w", err)
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
-	}
-
-	encrypted := gcm.Seal(nonce, nonce, []byte(data), nil)
 	encoded := base64.URLEncoding.EncodeToString(encrypted)
 	
 	// Create hash for lookup without decryption
@@ -404,66 +495,62 @@ func (pe *PseudonymizationEngine) decryptionDePseudonymization(encryptedData str
 		return "", fmt.Errorf("failed to decode encrypted data: %w", err)
 	}
 
-	block, err := aes.NewCipher(key.Key)
+	decrypted, err := pe.keyManager.backend.Open(key, encrypted)
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(encrypted) < nonceSize {
-		return "", fmt.Errorf("encrypted data too short")
-	}
-
-	nonce := encrypted[:nonceSize]
-	ciphertext := encrypted[nonceSize:]
-
-	decrypted, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to decrypt: %w", err)
+		return "", err
 	}
 
 	return string(decrypted), nil
 }
 
-// formatPreservingPseudonymization preserves format of original data
+// formatPreservingPseudonymization encrypts data with FF1 so the pseudonym
+// keeps its format. Data types without a rule, or with neither a built-in
+// format nor AllowedChars, fall back to AES256Encryption.
 func (pe *PseudonymizationEngine) formatPreservingPseudonymization(data, dataType string, key *CryptoKey) (string, string, error) {
-	// Find applicable preservation rule
-	var rule *FormatPreservationRule
-	for _, r := range pe.config.PreservationRules {
-		if r.DataType == dataType {
-			rule = &r
-			break
-		}
-	}
-
-	if rule == nil {
-		// Fall back to regular encryption if no rule found
+	algorithm, rule := pe.effectiveAlgorithm(dataType)
+	if algorithm != FormatPreservingEncryption {
 		return pe.encryptionPseudonymization(data, key)
 	}
 
-	// Apply format-preserving logic based on data type
 	switch dataType {
 	case "email":
-		return pe.formatPreservingEmail(data, key)
+		return pe.formatPreservingEmail(data, rule, key)
 	case "ip_address":
 		return pe.formatPreservingIPAddress(data, key)
 	default:
-		return pe.encryptionPseudonymization(data, key)
+		return pe.formatPreservingString(data, dataType, rule, key)
 	}
 }
 
+// preservationRule returns the format preservation rule for dataType, or nil
+func (pe *PseudonymizationEngine) preservationRule(dataType string) *FormatPreservationRule {
+	for i := range pe.config.PreservationRules {
+		if pe.config.PreservationRules[i].DataType == dataType {
+			rule := pe.config.PreservationRules[i]
+			return &rule
+		}
+	}
+	return nil
+}
+
 // formatPreservingEmail preserves email format while pseudonymizing
-func (pe *PseudonymizationEngine) formatPreservingEmail(email string, key *CryptoKey) (string, string, error) {
+func (pe *PseudonymizationEngine) formatPreservingEmail(email string, rule *FormatPreservationRule, key *CryptoKey) (string, string, error) {
 	parts := strings.Split(email, "@")
 	if len(parts) != 2 {
 		return "", "", fmt.Errorf("invalid email format")
 	}
 
+	if rule.EmailDomain == EmailDomainUnchanged {
+		pseudoEmail, err := cryptEmailLocal(email, rule, key, true)
+		if err != nil {
+			return "", "", err
+		}
+		return pseudoEmail, fpeHash(email, key), nil
+	}
+	if rule.EmailDomain != EmailDomainExample {
+		return pe.pseudonymizeEmailWithDomain(parts[0], parts[1], rule, key)
+	}
+
 	// Hash the local part
 	localHash := sha256.Sum256(append([]byte(parts[0]), key.Salt...))
 	localPseudo := hex.EncodeToString(localHash[:])[:8] // Take first 8 chars
@@ -483,56 +570,35 @@ func (pe *PseudonymizationEngine) formatPreservingEmail(email string, key *Crypt
 	return pseudoEmail, hashValue, nil
 }
 
-// formatPreservingIPAddress preserves IP address format
+// formatPreservingIPAddress encrypts an IP address into another address of
+// the same family
 func (pe *PseudonymizationEngine) formatPreservingIPAddress(ip string, key *CryptoKey) (string, string, error) {
-	// Hash the IP and convert to new IP format
-	hasher := sha256.New()
-	hasher.Write([]byte(ip))
-	hasher.Write(key.Salt)
-	hash := hasher.Sum(nil)
-
-	// Convert hash bytes to IP octets (keeping private range 10.x.x.x)
-	pseudoIP := fmt.Sprintf("10.%d.%d.%d", hash[0], hash[1], hash[2])
-
-	hashValue := hex.EncodeToString(hash)
-
-	return pseudoIP, hashValue, nil
+	pseudoIP, err := fpeCryptIP(key, "ip_address", ip, true)
+	if err != nil {
+		return "", "", err
+	}
+	return pseudoIP, fpeHash(ip, key), nil
 }
 
-// formatPreservingDePseudonymization reverses format-preserving pseudonymization
+// formatPreservingDePseudonymization reverses format-preserving
+// pseudonymization. Emails whose domain was rewritten cannot be reversed.
 func (pe *PseudonymizationEngine) formatPreservingDePseudonymization(pseudoData, dataType string, key *CryptoKey) (string, error) {
-	// Format-preserving pseudonymization is typically one-way for privacy
-	// This would require a lookup table for reversible operations
-	return "", fmt.Errorf("format-preserving de-pseudonymization requires lookup table (not implemented for privacy)")
-}
-
-// tokenizationPseudonymization performs tokenization-based pseudonymization
-func (pe *PseudonymizationEngine) tokenizationPseudonymization(data string, key *CryptoKey) (string, string, error) {
-	// Generate a random token
-	token := make([]byte, 16)
-	if _, err := rand.Read(token); err != nil {
-		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	algorithm, rule := pe.effectiveAlgorithm(dataType)
+	if algorithm != FormatPreservingEncryption {
+		return pe.decryptionDePseudonymization(pseudoData, key)
 	}
 
-	tokenStr := base64.URLEncoding.EncodeToString(token)
-
-	// Create hash for lookup
-	hasher := sha256.New()
-	hasher.Write([]byte(data))
-	hasher.Write(key.Salt)
-	hashValue := hex.EncodeToString(hasher.Sum(nil))
-
-	// Note: In practice, this would store the mapping in a secure token vault
-	// For this implementation, we're showing the concept
-
-	return tokenStr, hashValue, nil
-}
-
-// tokenizationDePseudonymization reverses tokenization
-func (pe *PseudonymizationEngine) tokenizationDePseudonymization(token string, key *CryptoKey) (string, error) {
-	// This would lookup the token in a secure vault
-	// For this implementation, return error as vault is not implemented
-	return "", fmt.Errorf("tokenization de-pseudonymization requires secure token vault (not implemented)")
+	switch dataType {
+	case "email":
+		if rule.EmailDomain != EmailDomainUnchanged {
+			return "", fmt.Errorf("email pseudonyms with a rewritten domain are not reversible")
+		}
+		return cryptEmailLocal(pseudoData, rule, key, false)
+	case "ip_address":
+		return fpeCryptIP(key, dataType, pseudoData, false)
+	default:
+		return fpeCryptString(key, rule.AllowedChars, dataType, pseudoData, false)
+	}
 }
 
 // RotateKeys performs key rotation
@@ -540,25 +606,38 @@ func (pe *PseudonymizationEngine) RotateKeys() error {
 	return pe.keyManager.RotateKeys()
 }
 
-// GetMetrics returns pseudonymization metrics for compliance monitoring
-func (pe *PseudonymizationEngine) GetMetrics() (*PseudonymizationMetrics, error) {
-	// Implementation would collect actual metrics
-	return &PseudonymizationMetrics{
-		TotalPseudonymizations:   0,
-		ActiveKeys:              len(pe.keyManager.activeKeys),
-		LastKeyRotation:         time.Now().Add(-time.Hour),
-		AlgorithmDistribution:   map[PseudoAlgorithm]int{},
-		ComplianceScore:         95.5,
-	}, nil
+// ForceRotateKeys rotates keys regardless of MinKeyRotationInterval, e.g.
+// after a suspected key compromise
+func (pe *PseudonymizationEngine) ForceRotateKeys() error {
+	return pe.keyManager.Rotate(RotationForced)
+}
+
+// Config returns a copy of the engine configuration
+func (pe *PseudonymizationEngine) Config() PseudonymizationConfig {
+	config := *pe.config
+	config.PreservationRules = append([]FormatPreservationRule(nil), pe.config.PreservationRules...)
+	return config
 }
 
-// PseudonymizationMetrics contains metrics for monitoring
-type PseudonymizationMetrics struct {
-	TotalPseudonymizations int                         `json:"total_pseudonymizations"`
-	ActiveKeys            int                         `json:"active_keys"`
-	LastKeyRotation       time.Time                   `json:"last_key_rotation"`
-	AlgorithmDistribution map[PseudoAlgorithm]int     `json:"algorithm_distribution"`
-	ComplianceScore       float64                     `json:"compliance_score"`
+// RedactedKeys returns the metadata of the active and archived keys, sorted
+// by ID, with key material and salts removed
+func (pe *PseudonymizationEngine) RedactedKeys() []CryptoKey {
+	km := pe.keyManager
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	keys := make([]CryptoKey, 0, len(km.activeKeys)+len(km.archivedKeys))
+	for _, store := range []map[int]*CryptoKey{km.activeKeys, km.archivedKeys} {
+		for _, key := range store {
+			redacted := *key
+			redacted.Key = nil
+			redacted.Salt = nil
+			keys = append(keys, redacted)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
 }
 
 // generateID generates a unique ID for audit trails
@@ -0,0 +1,63 @@
+package privacy
+
+import "testing"
+
+func TestEngineWithoutAuditLogger(t *testing.T) {
+	for _, algorithm := range []PseudoAlgorithm{AES256Encryption, AES256Deterministic, FormatPreservingEncryption, SHA256Hash} {
+		config := DefaultPseudonymizationConfig()
+		config.Algorithm = algorithm
+		config.IterationCount = 1
+		engine, err := NewPseudonymizationEngine(config, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+		if err != nil {
+			t.Fatalf("%v: %v", algorithm, err)
+		}
+		if pseudo.PseudonymizedValue == "" || pseudo.PseudonymizedValue == "alice@example.com" {
+			t.Errorf("%v: unexpected pseudonym %q", algorithm, pseudo.PseudonymizedValue)
+		}
+
+		// Failures are reported without an audit logger too
+		original, err := engine.DePseudonymize(pseudo, "support", "contract")
+		switch algorithm {
+		case AES256Encryption, AES256Deterministic, FormatPreservingEncryption:
+			if err != nil || original != "alice@example.com" {
+				t.Errorf("%v: expected the original value, got %q, %v", algorithm, original, err)
+			}
+		default:
+			if err == nil {
+				t.Errorf("%v: expected de-pseudonymization to fail", algorithm)
+			}
+		}
+
+		if _, err := engine.DePseudonymize(&PseudonymizedData{KeyVersion: 999}, "support", "contract"); err == nil {
+			t.Errorf("%v: expected an unknown key version to fail", algorithm)
+		}
+	}
+}
+
+func TestAuditDisabledSkipsAuditLogger(t *testing.T) {
+	config := DefaultPseudonymizationConfig()
+	config.AuditEnabled = false
+	auditLog := &recordingAuditLog{}
+	engine, err := NewPseudonymizationEngine(config, auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.DePseudonymize(pseudo, "support", "contract"); err != nil {
+		t.Fatal(err)
+	}
+	engine.DePseudonymize(&PseudonymizedData{KeyVersion: 999}, "support", "contract")
+
+	if len(auditLog.events) != 0 {
+		t.Errorf("expected no audit events with auditing disabled, got %+v", auditLog.events)
+	}
+}
@@ -0,0 +1,259 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Token vault entries hold the plaintext encrypted under the key that was
+// active when the token was issued, prefixed with that key's ID. The vault
+// itself never sees plaintext, and tokens issued before a key rotation keep
+// resolving through the archived key.
+
+var (
+	// ErrTokenNotFound is returned when a vault holds no entry for a token
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrTokenCollision is returned when a token is already mapped to
+	// different data
+	ErrTokenCollision = errors.New("token already maps to different data")
+)
+
+// deterministicTokenLabel separates the token key from the encryption key
+const deterministicTokenLabel = "net-sec/pseudonymization/deterministic-token"
+
+// TokenVault maps tokens issued by ReversibleTokenization to the encrypted
+// data they stand for
+type TokenVault interface {
+	// Store maps token to ciphertext; hash is the lookup hash of the data
+	Store(hash, token, ciphertext string) error
+	// Lookup returns the ciphertext token maps to
+	Lookup(token string) (string, error)
+}
+
+// tokenEntry is a vault entry
+type tokenEntry struct {
+	Hash       string `json:"hash"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// putTokenEntry adds an entry to entries. Storing a token again with the
+// same hash replaces its ciphertext, so deterministic tokens can be
+// re-issued.
+func putTokenEntry(entries map[string]tokenEntry, hash, token, ciphertext string) error {
+	if token == "" {
+		return fmt.Errorf("token vault entries require a token")
+	}
+	if existing, ok := entries[token]; ok && existing.Hash != hash {
+		return fmt.Errorf("token %s: %w", token, ErrTokenCollision)
+	}
+	entries[token] = tokenEntry{Hash: hash, Ciphertext: ciphertext}
+	return nil
+}
+
+// MemoryTokenVault keeps token mappings in memory
+type MemoryTokenVault struct {
+	entries map[string]tokenEntry
+	mutex   sync.RWMutex
+}
+
+// NewMemoryTokenVault creates an empty in-memory vault
+func NewMemoryTokenVault() *MemoryTokenVault {
+	return &MemoryTokenVault{entries: make(map[string]tokenEntry)}
+}
+
+// Store maps token to ciphertext
+func (v *MemoryTokenVault) Store(hash, token, ciphertext string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return putTokenEntry(v.entries, hash, token, ciphertext)
+}
+
+// Lookup returns the ciphertext token maps to
+func (v *MemoryTokenVault) Lookup(token string) (string, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	entry, ok := v.entries[token]
+	if !ok {
+		return "", fmt.Errorf("token %s: %w", token, ErrTokenNotFound)
+	}
+	return entry.Ciphertext, nil
+}
+
+// FileTokenVault keeps token mappings in a JSON file readable only by its
+// owner. The file is rewritten atomically on every Store.
+type FileTokenVault struct {
+	path    string
+	entries map[string]tokenEntry
+	mutex   sync.RWMutex
+}
+
+// NewFileTokenVault opens the vault at path, creating it on first Store
+func NewFileTokenVault(path string) (*FileTokenVault, error) {
+	v := &FileTokenVault{path: path, entries: make(map[string]tokenEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token vault: %w", err)
+	}
+	if err := json.Unmarshal(data, &v.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse token vault: %w", err)
+	}
+	return v, nil
+}
+
+// Store maps token to ciphertext and persists the vault
+func (v *FileTokenVault) Store(hash, token, ciphertext string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	previous, existed := v.entries[token]
+	if err := putTokenEntry(v.entries, hash, token, ciphertext); err != nil {
+		return err
+	}
+	if err := v.save(); err != nil {
+		if existed {
+			v.entries[token] = previous
+		} else {
+			delete(v.entries, token)
+		}
+		return err
+	}
+	return nil
+}
+
+// Lookup returns the ciphertext token maps to
+func (v *FileTokenVault) Lookup(token string) (string, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	entry, ok := v.entries[token]
+	if !ok {
+		return "", fmt.Errorf("token %s: %w", token, ErrTokenNotFound)
+	}
+	return entry.Ciphertext, nil
+}
+
+// save writes the entries to a temporary file and renames it over the
+// vault; the caller holds mutex
+func (v *FileTokenVault) save() error {
+	data, err := json.Marshal(v.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode token vault: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), "."+filepath.Base(v.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write token vault: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write token vault: %w", err)
+	}
+
+	// CreateTemp already restricts the file to its owner
+	if err := os.Rename(tmp.Name(), v.path); err != nil {
+		return fmt.Errorf("failed to write token vault: %w", err)
+	}
+	return nil
+}
+
+// SetTokenVault sets the vault ReversibleTokenization stores tokens in
+func (pe *PseudonymizationEngine) SetTokenVault(vault TokenVault) {
+	pe.vault = vault
+}
+
+// tokenizationPseudonymization replaces data with a token and stores the
+// data, encrypted under key, in the token vault
+func (pe *PseudonymizationEngine) tokenizationPseudonymization(data string, key *CryptoKey) (string, string, error) {
+	if pe.vault == nil {
+		return "", "", fmt.Errorf("no token vault configured")
+	}
+
+	encrypted, hashValue, err := pe.encryptionPseudonymization(data, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	var token string
+	if pe.config.DeterministicTokens {
+		token = deterministicToken(key, []byte(data))
+	} else if token, err = generateToken(); err != nil {
+		return "", "", err
+	}
+
+	ciphertext := strconv.Itoa(key.ID) + ":" + encrypted
+	if err := pe.vault.Store(hashValue, token, ciphertext); err != nil {
+		return "", "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, hashValue, nil
+}
+
+// tokenizationDePseudonymization resolves token through the vault and
+// decrypts the data with the key recorded in its entry
+func (pe *PseudonymizationEngine) tokenizationDePseudonymization(token string, key *CryptoKey) (string, error) {
+	if pe.vault == nil {
+		return "", fmt.Errorf("no token vault configured")
+	}
+
+	ciphertext, err := pe.vault.Lookup(token)
+	if err != nil {
+		return "", err
+	}
+
+	version, encrypted, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed token vault entry")
+	}
+	keyID, err := strconv.Atoi(version)
+	if err != nil {
+		return "", fmt.Errorf("malformed token vault entry: %w", err)
+	}
+
+	if keyID != key.ID {
+		if key, err = pe.keyManager.GetKey(keyID); err != nil {
+			return "", fmt.Errorf("failed to get key version %d: %w", keyID, err)
+		}
+	}
+	return pe.decryptionDePseudonymization(encrypted, key)
+}
+
+// generateToken returns a random 128-bit token
+func generateToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(token), nil
+}
+
+// deterministicToken derives a 128-bit token from data with an HMAC keyed
+// by a sub-key of key, so equal data under the same key shares a token
+func deterministicToken(key *CryptoKey, data []byte) string {
+	subKey := hmac.New(sha256.New, key.Key)
+	subKey.Write([]byte(deterministicTokenLabel))
+
+	mac := hmac.New(sha256.New, subKey.Sum(nil))
+	mac.Write(data)
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,150 @@
+package privacy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTokenizationEngine(t *testing.T, deterministic bool) *PseudonymizationEngine {
+	t.Helper()
+
+	config := DefaultPseudonymizationConfig()
+	config.Algorithm = ReversibleTokenization
+	config.DeterministicTokens = deterministic
+	engine, err := NewPseudonymizationEngine(config, nopAuditLog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestTokenizationIsReversible(t *testing.T) {
+	engine := newTokenizationEngine(t, false)
+
+	first, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.PseudonymizedValue == second.PseudonymizedValue {
+		t.Error("expected random tokens for repeated data")
+	}
+	if strings.Contains(first.PseudonymizedValue, "alice") {
+		t.Errorf("token %q leaks the data", first.PseudonymizedValue)
+	}
+
+	for _, pseudo := range []*PseudonymizedData{first, second} {
+		original, err := engine.DePseudonymize(pseudo, "support", "contract")
+		if err != nil || original != "alice@example.com" {
+			t.Errorf("expected the original value, got %q, %v", original, err)
+		}
+	}
+
+	if _, err := engine.DePseudonymize(&PseudonymizedData{Algorithm: ReversibleTokenization, KeyVersion: 1, PseudonymizedValue: "unknown"}, "support", "contract"); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestDeterministicTokensReuseToken(t *testing.T) {
+	engine := newTokenizationEngine(t, true)
+
+	first, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := engine.Pseudonymize("bob@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.PseudonymizedValue != second.PseudonymizedValue {
+		t.Errorf("expected equal data to share a token, got %q and %q", first.PseudonymizedValue, second.PseudonymizedValue)
+	}
+	if first.PseudonymizedValue == other.PseudonymizedValue {
+		t.Error("expected different data to get different tokens")
+	}
+	if original, err := engine.DePseudonymize(second, "support", "contract"); err != nil || original != "alice@example.com" {
+		t.Errorf("expected the original value, got %q, %v", original, err)
+	}
+}
+
+func TestTokensResolveAfterKeyRotation(t *testing.T) {
+	engine := newTokenizationEngine(t, false)
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.ForceRotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := engine.DePseudonymize(pseudo, "support", "contract")
+	if err != nil || original != "alice@example.com" {
+		t.Errorf("expected the token to resolve through the archived key, got %q, %v", original, err)
+	}
+}
+
+func TestTokenVaultRejectsCollisions(t *testing.T) {
+	vault := NewMemoryTokenVault()
+	if err := vault.Store("hash-a", "token", "1:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Store("hash-a", "token", "1:b"); err != nil {
+		t.Errorf("expected the same data to re-issue its token, got %v", err)
+	}
+	if err := vault.Store("hash-b", "token", "1:c"); !errors.Is(err, ErrTokenCollision) {
+		t.Errorf("expected ErrTokenCollision, got %v", err)
+	}
+	if ciphertext, err := vault.Lookup("token"); err != nil || ciphertext != "1:b" {
+		t.Errorf("expected the re-issued entry, got %q, %v", ciphertext, err)
+	}
+}
+
+func TestFileTokenVaultPersistsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	engine := newTokenizationEngine(t, false)
+	vault, err := NewFileTokenVault(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.SetTokenVault(vault)
+
+	pseudo, err := engine.Pseudonymize("alice@example.com", "email", "support", "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "alice") {
+		t.Error("expected the vault file to hold no plaintext")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected an owner-only vault file, got %v, %v", info.Mode(), err)
+	}
+
+	reopened, err := NewFileTokenVault(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.SetTokenVault(reopened)
+
+	original, err := engine.DePseudonymize(pseudo, "support", "contract")
+	if err != nil || original != "alice@example.com" {
+		t.Errorf("expected the reopened vault to resolve the token, got %q, %v", original, err)
+	}
+}
@@ -1,31 +1,47 @@
 package rbac
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
 )
 
 // AccessController manages role-based access control with GDPR compliance
 type AccessController struct {
-	roles       map[string]*Role
-	users       map[string]*User
-	permissions map[string]*Permission
-	sessions    map[string]*Session
-	auditLog    AuditLogger
-	mutex       sync.RWMutex
-	config      *RBACConfig
+	roles        map[string]*Role
+	users        map[string]*User
+	permissions  map[string]*Permission
+	sessions     map[string]*Session
+	auditLog     AuditLogger
+	mutex        sync.RWMutex
+	config       *RBACConfig
+	logger       logger.StructuredLogger
+	cancel       context.CancelFunc
+	cleanupDone  chan struct{}
+	now          func() time.Time
+	usage        map[string]*permissionUsage // Access checks granted, by permission ID
+	roleRequests map[string]*RoleRequest     // Requests for roles requiring approval, by request ID
+	storeErr     error                       // Why the store failed to load; saving is refused until Reload succeeds
 }
 
 // RBACConfig contains RBAC configuration settings
 type RBACConfig struct {
-	SessionTimeout        time.Duration `json:"session_timeout"`
-	MaxFailedAttempts     int           `json:"max_failed_attempts"`
-	LockoutDuration       time.Duration `json:"lockout_duration"`
-	RequireMFA            bool          `json:"require_mfa"`
-	AuditAllAccess        bool          `json:"audit_all_access"`
-	PrivilegeEscalation   bool          `json:"privilege_escalation_detection"`
-	DataClassificationReq bool          `json:"data_classification_required"`
+	SessionTimeout        time.Duration      `json:"session_timeout"`
+	IdleTimeout           time.Duration      `json:"idle_timeout"` // Expires sessions without activity; 0 disables
+	MaxFailedAttempts     int                `json:"max_failed_attempts"`
+	LockoutDuration       time.Duration      `json:"lockout_duration"`
+	RequireMFA            bool               `json:"require_mfa"`
+	AuditAllAccess        bool               `json:"audit_all_access"`
+	PrivilegeEscalation   bool               `json:"privilege_escalation_detection"`
+	DataClassificationReq bool               `json:"data_classification_required"`
+	PasswordMinLength     int                `json:"password_min_length"`          // 0 uses DefaultPasswordMinLength
+	PasswordMinClasses    int                `json:"password_min_classes"`         // 0 uses DefaultPasswordMinClasses
+	ConsentController     *ConsentController `json:"consent_controller,omitempty"` // Named on consent receipts
+	ResourceSeparator     string             `json:"resource_separator,omitempty"` // "" uses DefaultResourceSeparator
+	Store                 Store              `json:"-"`                            // Persists users, roles and permissions; nil keeps them in memory
 }
 
 // User represents a system user with GDPR data subject rights
@@ -33,6 +49,7 @@ type User struct {
 	ID                string                 `json:"id"`
 	Username          string                 `json:"username"`
 	Email             string                 `json:"email"`
+	PasswordHash      string                 `json:"-"` // Argon2id hash set by SetPassword
 	Roles             []string               `json:"roles"`
 	IsActive          bool                   `json:"is_active"`
 	IsLocked          bool                   `json:"is_locked"`
@@ -40,10 +57,14 @@ type User struct {
 	LastFailedAttempt *time.Time             `json:"last_failed_attempt"`
 	LastLogin         *time.Time             `json:"last_login"`
 	MFAEnabled        bool                   `json:"mfa_enabled"`
+	MFASecret         string                 `json:"-"`                       // Base32 TOTP secret set by EnrollMFA
+	MFALastStep       int64                  `json:"mfa_last_step,omitempty"` // Last TOTP time step accepted, so a code cannot be replayed
+	MFAFailedAttempts int                    `json:"mfa_failed_attempts"`
 	CreatedAt         time.Time              `json:"created_at"`
 	UpdatedAt         time.Time              `json:"updated_at"`
 	DataSubjectID     string                 `json:"data_subject_id,omitempty"` // GDPR data subject reference
 	ConsentRecords    []ConsentRecord        `json:"consent_records,omitempty"`
+	RoleExpiry        map[string]time.Time   `json:"role_expiry,omitempty"` // Expiry of temporary roles, by role ID
 	Metadata          map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -139,6 +160,7 @@ type AccessAuditEvent struct {
 	Success       bool                   `json:"success"`
 	DenialReason  string                 `json:"denial_reason,omitempty"`
 	DataCategory  string                 `json:"data_category,omitempty"`
+	DataSubjectID string                 `json:"data_subject_id,omitempty"` // Whose personal data was accessed, from the "data_subject" context key
 	LegalBasis    string                 `json:"legal_basis,omitempty"`
 	Justification string                 `json:"justification,omitempty"`
 	RiskLevel     string                 `json:"risk_level"` // "low", "medium", "high", "critical"
@@ -181,7 +203,7 @@ type SessionAuditEvent struct {
 	Timestamp time.Time              `json:"timestamp"`
 	SessionID string                 `json:"session_id"`
 	UserID    string                 `json:"user_id"`
-	EventType string                 `json:"event_type"` // "created", "expired", "terminated", "extended"
+	EventType string                 `json:"event_type"` // "created", "expired", "terminated", "extended", "mfa_verified"
 	IPAddress string                 `json:"ip_address"`
 	UserAgent string                 `json:"user_agent,omitempty"`
 	Reason    string                 `json:"reason,omitempty"`
@@ -191,25 +213,54 @@ type SessionAuditEvent struct {
 
 // NewAccessController creates a new RBAC access controller
 func NewAccessController(config *RBACConfig, auditLog AuditLogger) *AccessController {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	ac := &AccessController{
-		roles:       make(map[string]*Role),
-		users:       make(map[string]*User),
-		permissions: make(map[string]*Permission),
-		sessions:    make(map[string]*Session),
-		auditLog:    auditLog,
-		config:      config,
+		roles:        make(map[string]*Role),
+		users:        make(map[string]*User),
+		permissions:  make(map[string]*Permission),
+		sessions:     make(map[string]*Session),
+		auditLog:     auditLog,
+		config:       config,
+		logger:       logger.Component("rbac"),
+		cancel:       cancel,
+		cleanupDone:  make(chan struct{}),
+		now:          time.Now,
+		usage:        make(map[string]*permissionUsage),
+		roleRequests: make(map[string]*RoleRequest),
 	}
 
-	// Initialize default permissions and roles
+	// Load the stored users, roles and permissions, then seed the built-ins
+	// the store lacks
+	if config.Store != nil {
+		if err := ac.load(); err != nil {
+			ac.storeErr = err
+			ac.logger.Error("Failed to load RBAC store", logger.Fields{
+				"event_type": "rbac_store_load_failed",
+				"error":      err.Error(),
+			})
+		}
+	}
 	ac.initializeDefaults()
+	if ac.storeErr == nil {
+		ac.saveOrLog()
+	}
 
 	// Start session cleanup goroutine
-	go ac.sessionCleanup()
+	go ac.sessionCleanup(ctx)
 
 	return ac
 }
 
-// initializeDefaults sets up default GDPR-compliant permissions and roles
+// SetLogger sets the logger used for access control events
+func (ac *AccessController) SetLogger(l logger.StructuredLogger) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	ac.logger = l
+}
+
+// initializeDefaults sets up default GDPR-compliant permissions and roles,
+// leaving any already loaded from the store as they are
 func (ac *AccessController) initializeDefaults() {
 	// Default permissions for GDPR operations
 	defaultPermissions := []*Permission{
@@ -279,9 +330,23 @@ func (ac *AccessController) initializeDefaults() {
 			RequiresJustification: true,
 			IsHighRisk:            true,
 		},
+		{
+			ID:                    "approve_role_requests",
+			Name:                  "Approve Role Requests",
+			Description:           "Approve or deny requests for roles that require approval",
+			Resource:              "role_requests",
+			Action:                "approve",
+			DataClassification:    "internal",
+			GDPRImplications:      []string{"Article 5(1)(f)", "Article 32"},
+			RequiresJustification: false,
+			IsHighRisk:            false,
+		},
 	}
 
 	for _, perm := range defaultPermissions {
+		if _, exists := ac.permissions[perm.ID]; exists {
+			continue
+		}
 		perm.CreatedAt = time.Now()
 		ac.permissions[perm.ID] = perm
 	}
@@ -292,12 +357,12 @@ func (ac *AccessController) initializeDefaults() {
 			ID:                 "data_protection_officer",
 			Name:               "Data Protection Officer",
 			Description:        "GDPR DPO with full compliance oversight",
-			Permissions:        []string{"personal_data_read", "personal_data_write", "personal_data_delete", "data_export", "audit_log_read", "pseudonymization_manage"},
+			Permissions:        []string{"personal_data_read", "personal_data_write", "personal_data_delete", "data_export", "audit_log_read", "pseudonymization_manage", "approve_role_requests"},
 			DataCategories:     []string{"personal", "sensitive", "transaction", "log"},
 			ProcessingPurposes: []string{"compliance", "audit", "legal_obligation"},
 			LegalBases:         []string{"Article 6(1)(c)", "Article 6(1)(f)"},
 			IsBuiltIn:          true,
-			RequiresApproval:   false,
+			RequiresApproval:   true, // Its holders approve role requests, so it cannot be self-assigned
 			MaxSessionDuration: 8 * time.Hour,
 		},
 		{
@@ -339,41 +404,147 @@ func (ac *AccessController) initializeDefaults() {
 	}
 
 	for _, role := range defaultRoles {
+		if _, exists := ac.roles[role.ID]; exists {
+			continue
+		}
 		role.CreatedAt = time.Now()
 		role.UpdatedAt = time.Now()
 		ac.roles[role.ID] = role
 	}
 }
 
-// sessionCleanup removes expired sessions
-func (ac *AccessController) sessionCleanup() {
+// Shutdown stops the session cleanup goroutine and waits for it to exit or
+// ctx to be done. Further calls are no-ops.
+func (ac *AccessController) Shutdown(ctx context.Context) error {
+	ac.cancel()
+
+	select {
+	case <-ac.cleanupDone:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("access controller shutdown: %w", ctx.Err())
+	}
+}
+
+// sessionCleanup removes expired sessions and temporary roles until ctx is
+// cancelled
+func (ac *AccessController) sessionCleanup(ctx context.Context) {
+	defer close(ac.cleanupDone)
+
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		ac.mutex.Lock()
-		now := time.Now()
-
-		for id, session := range ac.sessions {
-			if now.After(session.ExpiresAt) {
-				delete(ac.sessions, id)
-
-				// Log session expiration
-				if ac.auditLog != nil {
-					event := SessionAuditEvent{
-						ID:        generateAuditID(),
-						Timestamp: now,
-						SessionID: id,
-						UserID:    session.UserID,
-						EventType: "expired",
-						Duration:  now.Sub(session.CreatedAt),
-						Reason:    "session_timeout",
-					}
-					ac.auditLog.LogSessionEvent(event)
-				}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ac.removeExpiredSessions()
+			ac.removeExpiredRoles()
+		}
+	}
+}
+
+// removeExpiredSessions deletes and audits the sessions past their absolute
+// expiry or idle for longer than IdleTimeout
+func (ac *AccessController) removeExpiredSessions() {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	now := ac.now()
+
+	for id, session := range ac.sessions {
+		if reason := ac.sessionExpiryReason(session, now); reason != "" {
+			ac.expireSession(id, session, now, reason)
+		}
+	}
+}
+
+// sessionExpiryReason returns "session_timeout" when session is past its
+// absolute expiry, "idle_timeout" when it has been idle for longer than
+// IdleTimeout, and "" while it is still valid
+func (ac *AccessController) sessionExpiryReason(session *Session, now time.Time) string {
+	if now.After(session.ExpiresAt) {
+		return "session_timeout"
+	}
+	if ac.config.IdleTimeout > 0 && now.Sub(session.LastActivity) > ac.config.IdleTimeout {
+		return "idle_timeout"
+	}
+	return ""
+}
+
+// expireSession deletes session and audits its expiry for reason. The caller
+// must hold the write lock.
+func (ac *AccessController) expireSession(id string, session *Session, now time.Time, reason string) {
+	delete(ac.sessions, id)
+
+	ac.logger.Info("Session expired", logger.Fields{
+		"event_type": "session_expired",
+		"session_id": id,
+		"user_id":    session.UserID,
+		"reason":     reason,
+	})
+
+	// Log session expiration
+	if ac.auditLog != nil {
+		event := SessionAuditEvent{
+			ID:        generateAuditID(),
+			Timestamp: now,
+			SessionID: id,
+			UserID:    session.UserID,
+			EventType: "expired",
+			Duration:  now.Sub(session.CreatedAt),
+			Reason:    reason,
+		}
+		ac.auditLog.LogSessionEvent(event)
+	}
+}
+
+// removeExpiredRoles revokes temporary roles past their expiry and audits
+// each removal
+func (ac *AccessController) removeExpiredRoles() {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	now := ac.now()
+	expired := false
+	for _, user := range ac.users {
+		for roleID, expiresAt := range user.RoleExpiry {
+			if now.Before(expiresAt) {
+				continue
+			}
+			expired = true
+			if err := ac.revokeRole(user.ID, roleID); err != nil {
+				delete(user.RoleExpiry, roleID)
+			}
+
+			ac.logger.Info("Temporary role expired", logger.Fields{
+				"event_type": "temporary_role_expired",
+				"user_id":    user.ID,
+				"role_id":    roleID,
+				"expired_at": expiresAt,
+			})
+
+			if ac.auditLog != nil {
+				ac.auditLog.LogPermissionCheck(PermissionAuditEvent{
+					ID:         generateAuditID(),
+					Timestamp:  now,
+					UserID:     user.ID,
+					Permission: "role:" + roleID,
+					Resource:   "role_assignment",
+					Granted:    false,
+					Reason:     "temporary_role_expired",
+					Metadata: map[string]interface{}{
+						"role_id":    roleID,
+						"expired_at": expiresAt,
+					},
+				})
 			}
 		}
-		ac.mutex.Unlock()
+	}
+
+	if expired {
+		ac.saveOrLog()
 	}
 }
 
@@ -0,0 +1,70 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+	"go.uber.org/goleak"
+)
+
+func TestAccessDecisionsAreLogged(t *testing.T) {
+	var buf bytes.Buffer
+	ac := NewAccessController(&RBACConfig{SessionTimeout: time.Hour}, nil)
+	ac.SetLogger(logger.New("info", "json", &buf).Component("rbac"))
+
+	if err := ac.AddUser(&User{ID: "user_1"}); err != nil {
+		t.Fatal(err)
+	}
+	session, err := ac.CreateSession("user_1", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ac.CheckAccess(session.ID, "audit_logs", "read", map[string]interface{}{}) {
+		t.Fatal("user without roles should be denied")
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected session and denial entries, got %v", entries)
+	}
+	if entries[0]["event_type"] != "session_created" || entries[0]["session_id"] != session.ID {
+		t.Errorf("unexpected session entry: %v", entries[0])
+	}
+
+	denied := entries[1]
+	if denied["event_type"] != "access_denied" || denied["level"] != "WARN" || denied["component"] != "rbac" {
+		t.Errorf("unexpected denial entry: %v", denied)
+	}
+	if denied["user_id"] != "user_1" || denied["session_id"] != session.ID || denied["reason"] != "insufficient_permissions" {
+		t.Errorf("denial entry is missing IDs: %v", denied)
+	}
+}
+
+func TestShutdownStopsSessionCleanup(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	ac := NewAccessController(&RBACConfig{SessionTimeout: time.Hour}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := ac.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Further calls are no-ops
+	if err := ac.Shutdown(ctx); err != nil {
+		t.Fatalf("second shutdown failed: %v", err)
+	}
+}
@@ -2,13 +2,18 @@ package rbac
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
 )
 
 // CheckAccess verifies if a user has permission to perform an action
 func (ac *AccessController) CheckAccess(sessionID, resource, action string, context map[string]interface{}) bool {
-	ac.mutex.RLock()
-	defer ac.mutex.RUnlock()
+	// The write lock is held because the session's activity is updated
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
 
 	session, exists := ac.sessions[sessionID]
 	if !exists {
@@ -17,9 +22,15 @@ func (ac *AccessController) CheckAccess(sessionID, resource, action string, cont
 	}
 
 	// Check session validity
-	if time.Now().After(session.ExpiresAt) {
+	now := ac.now()
+	switch ac.sessionExpiryReason(session, now) {
+	case "session_timeout":
 		ac.logAccessDenied(session.UserID, resource, action, "session_expired", context)
 		return false
+	case "idle_timeout":
+		ac.expireSession(sessionID, session, now, "idle_timeout")
+		ac.logAccessDenied(session.UserID, resource, action, "idle_timeout", context)
+		return false
 	}
 
 	user, exists := ac.users[session.UserID]
@@ -28,54 +39,30 @@ func (ac *AccessController) CheckAccess(sessionID, resource, action string, cont
 		return false
 	}
 
-	// Get user's effective permissions
-	permissions := ac.getUserPermissions(user)
-
-	// Check specific permission
-	permitted := false
-	var permissionUsed *Permission
+	// Check specific permission, using only the roles whose IP and time
+	// restrictions the session meets
+	permissionUsed, restriction := ac.matchPermission(ac.resolveRoles(user, session, now), resource, action)
+	permitted := permissionUsed != nil
 
-	for _, perm := range permissions {
-		if ac.permissionMatches(perm, resource, action) {
-			permitted = true
-			permissionUsed = perm
-			break
-		}
+	if !permitted && restriction != "" {
+		ac.logAccessDenied(session.UserID, resource, action, restriction, context)
+		return false
 	}
 
 	// Enhanced checks for high-risk operations
-	if permitted && permissionUsed != nil && permissionUsed.IsHighRisk {
-		// Check MFA requirement
-		if ac.config.RequireMFA && !session.MFAVerified {
-			ac.logAccessDenied(session.UserID, resource, action, "mfa_required", context)
+	if permitted {
+		if reason := ac.highRiskDenial(permissionUsed, session, context); reason != "" {
+			ac.logAccessDenied(session.UserID, resource, action, reason, context)
 			return false
 		}
-
-		// Check if justification is required and provided
-		if permissionUsed.RequiresJustification {
-			if justification, ok := context["justification"]; !ok || justification == "" {
-				ac.logAccessDenied(session.UserID, resource, action, "justification_required", context)
-				return false
-			}
-		}
-
-		// Check data classification compatibility
-		if ac.config.DataClassificationReq {
-			if dataClass, ok := context["data_classification"]; ok {
-				if !ac.isDataClassificationCompatible(permissionUsed.DataClassification, dataClass.(string)) {
-					ac.logAccessDenied(session.UserID, resource, action, "data_classification_mismatch", context)
-					return false
-				}
-			}
-		}
 	}
 
 	// Update session activity
-	session.LastActivity = time.Now()
+	session.LastActivity = now
 	if session.AccessedResources == nil {
 		session.AccessedResources = make(map[string]time.Time)
 	}
-	session.AccessedResources[resource] = time.Now()
+	session.AccessedResources[resource] = now
 
 	// Log access attempt
 	riskLevel := "low"
@@ -84,20 +71,22 @@ func (ac *AccessController) CheckAccess(sessionID, resource, action string, cont
 	}
 
 	event := AccessAuditEvent{
-		ID:        generateAuditID(),
-		Timestamp: time.Now(),
-		UserID:    session.UserID,
-		SessionID: sessionID,
-		Resource:  resource,
-		Action:    action,
-		IPAddress: session.IPAddress,
-		UserAgent: session.UserAgent,
-		Success:   permitted,
-		RiskLevel: riskLevel,
-		Metadata:  context,
+		ID:            generateAuditID(),
+		Timestamp:     time.Now(),
+		UserID:        session.UserID,
+		SessionID:     sessionID,
+		Resource:      resource,
+		Action:        action,
+		DataSubjectID: dataSubject(context),
+		IPAddress:     session.IPAddress,
+		UserAgent:     session.UserAgent,
+		Success:       permitted,
+		RiskLevel:     riskLevel,
+		Metadata:      context,
 	}
 
 	if permitted {
+		ac.recordPermissionUse(permissionUsed.ID, now)
 		event.LegalBasis = ac.getLegalBasisForAccess(user, permissionUsed)
 		if dataCategory, ok := context["data_category"]; ok {
 			event.DataCategory = dataCategory.(string)
@@ -106,6 +95,23 @@ func (ac *AccessController) CheckAccess(sessionID, resource, action string, cont
 		event.DenialReason = "insufficient_permissions"
 	}
 
+	fields := logger.Fields{
+		"audit_id":   event.ID,
+		"user_id":    session.UserID,
+		"session_id": sessionID,
+		"resource":   resource,
+		"action":     action,
+		"risk_level": riskLevel,
+	}
+	if permitted {
+		fields["event_type"] = "access_granted"
+		ac.logger.Debug("Access granted", fields)
+	} else {
+		fields["event_type"] = "access_denied"
+		fields["reason"] = event.DenialReason
+		ac.logger.Warn("Access denied", fields)
+	}
+
 	if ac.auditLog != nil {
 		ac.auditLog.LogAccessAttempt(event)
 	}
@@ -118,6 +124,11 @@ func (ac *AccessController) CreateSession(userID, ipAddress, userAgent string) (
 	ac.mutex.Lock()
 	defer ac.mutex.Unlock()
 
+	return ac.createSession(userID, ipAddress, userAgent)
+}
+
+// createSession creates a session for a user; the caller holds mutex
+func (ac *AccessController) createSession(userID, ipAddress, userAgent string) (*Session, error) {
 	user, exists := ac.users[userID]
 	if !exists {
 		return nil, fmt.Errorf("user not found")
@@ -127,19 +138,12 @@ func (ac *AccessController) CreateSession(userID, ipAddress, userAgent string) (
 		return nil, fmt.Errorf("user account is inactive")
 	}
 
-	if user.IsLocked {
-		// Check if lockout period has expired
-		if user.LastFailedAttempt != nil && time.Since(*user.LastFailedAttempt) < ac.config.LockoutDuration {
-			return nil, fmt.Errorf("user account is locked")
-		} else {
-			// Unlock user
-			user.IsLocked = false
-			user.FailedAttempts = 0
-		}
+	now := ac.now()
+	if err := ac.checkLockout(user, now); err != nil {
+		return nil, err
 	}
 
 	sessionID := generateSessionID()
-	now := time.Now()
 	expiresAt := now.Add(ac.config.SessionTimeout)
 
 	session := &Session{
@@ -157,6 +161,13 @@ func (ac *AccessController) CreateSession(userID, ipAddress, userAgent string) (
 
 	ac.sessions[sessionID] = session
 
+	ac.logger.Info("Session created", logger.Fields{
+		"event_type": "session_created",
+		"session_id": sessionID,
+		"user_id":    userID,
+		"ip_address": ipAddress,
+	})
+
 	// Update user login time
 	user.LastLogin = &now
 	user.UpdatedAt = now
@@ -181,6 +192,22 @@ func (ac *AccessController) CreateSession(userID, ipAddress, userAgent string) (
 	return session, nil
 }
 
+// checkLockout refuses a locked user until LockoutDuration has passed since
+// the last failed attempt, and then unlocks them; the caller holds mutex
+func (ac *AccessController) checkLockout(user *User, now time.Time) error {
+	if !user.IsLocked {
+		return nil
+	}
+	if user.LastFailedAttempt != nil && now.Sub(*user.LastFailedAttempt) < ac.config.LockoutDuration {
+		return fmt.Errorf("user account is locked")
+	}
+
+	user.IsLocked = false
+	user.FailedAttempts = 0
+	user.MFAFailedAttempts = 0
+	return nil
+}
+
 // ElevatePrivileges temporarily grants additional privileges to a session
 func (ac *AccessController) ElevatePrivileges(sessionID string, privileges []string, duration time.Duration, justification, approvedBy string) error {
 	ac.mutex.Lock()
@@ -207,6 +234,15 @@ func (ac *AccessController) ElevatePrivileges(sessionID string, privileges []str
 	if escalationDetected && ac.config.PrivilegeEscalation {
 		riskScore := ac.calculateEscalationRisk(session, privileges)
 
+		ac.logger.Warn("Privilege escalation detected", logger.Fields{
+			"event_type":  "privilege_escalation",
+			"session_id":  sessionID,
+			"user_id":     session.UserID,
+			"privileges":  privileges,
+			"risk_score":  riskScore,
+			"approved_by": approvedBy,
+		})
+
 		event := PrivilegeEscalationEvent{
 			ID:              generateAuditID(),
 			Timestamp:       time.Now(),
@@ -240,11 +276,12 @@ func (ac *AccessController) ElevatePrivileges(sessionID string, privileges []str
 	return nil
 }
 
-// getUserPermissions retrieves all permissions for a user based on their roles
+// getUserPermissions retrieves all permissions for a user based on their
+// active roles
 func (ac *AccessController) getUserPermissions(user *User) []*Permission {
 	var permissions []*Permission
 
-	for _, roleID := range user.Roles {
+	for _, roleID := range ac.activeRoles(user) {
 		if role, exists := ac.roles[roleID]; exists {
 			for _, permID := range role.Permissions {
 				if perm, exists := ac.permissions[permID]; exists {
@@ -257,13 +294,144 @@ func (ac *AccessController) getUserPermissions(user *User) []*Permission {
 	return permissions
 }
 
+// resolvedRole is one of a user's active roles resolved for access checks
+type resolvedRole struct {
+	permissions []*Permission
+	restriction string // Why the session cannot use the role now, "" when it can
+}
+
+// resolveRoles resolves the permissions and restrictions of the user's
+// active roles for session at now, in role order
+func (ac *AccessController) resolveRoles(user *User, session *Session, now time.Time) []resolvedRole {
+	roleIDs := ac.activeRoles(user)
+	resolved := make([]resolvedRole, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, exists := ac.roles[roleID]
+		if !exists {
+			continue
+		}
+
+		permissions := make([]*Permission, 0, len(role.Permissions))
+		for _, permID := range role.Permissions {
+			if perm, exists := ac.permissions[permID]; exists {
+				permissions = append(permissions, perm)
+			}
+		}
+		resolved = append(resolved, resolvedRole{
+			permissions: permissions,
+			restriction: ac.roleRestriction(role, session, now),
+		})
+	}
+	return resolved
+}
+
+// matchPermission returns the first permission matching resource and action
+// granted by a role the session may use. When none is, the restriction of
+// the first role that would have granted one is returned instead.
+func (ac *AccessController) matchPermission(roles []resolvedRole, resource, action string) (*Permission, string) {
+	restriction := ""
+	for _, role := range roles {
+		for _, perm := range role.permissions {
+			if !ac.permissionMatches(perm, resource, action) {
+				continue
+			}
+			if role.restriction == "" {
+				return perm, ""
+			}
+			if restriction == "" {
+				restriction = role.restriction
+			}
+			break
+		}
+	}
+	return nil, restriction
+}
+
+// highRiskDenial returns why a high-risk permission cannot be used by
+// session in context: "mfa_required", "justification_required" or
+// "data_classification_mismatch". It returns "" when it can be, and for
+// permissions that are not high-risk.
+func (ac *AccessController) highRiskDenial(perm *Permission, session *Session, context map[string]interface{}) string {
+	if !perm.IsHighRisk {
+		return ""
+	}
+
+	// Check MFA requirement
+	if ac.config.RequireMFA && !session.MFAVerified {
+		return "mfa_required"
+	}
+
+	// Check if justification is required and provided
+	if perm.RequiresJustification {
+		if justification, ok := context["justification"]; !ok || justification == "" {
+			return "justification_required"
+		}
+	}
+
+	// Check data classification compatibility
+	if ac.config.DataClassificationReq {
+		if dataClass, ok := context["data_classification"]; ok {
+			if !ac.isDataClassificationCompatible(perm.DataClassification, dataClass.(string)) {
+				return "data_classification_mismatch"
+			}
+		}
+	}
+	return ""
+}
+
+// activeRoles returns the user's roles, leaving out expired temporary roles
+// not yet removed by the sweep
+func (ac *AccessController) activeRoles(user *User) []string {
+	if len(user.RoleExpiry) == 0 {
+		return user.Roles
+	}
+
+	now := ac.now()
+	roles := make([]string, 0, len(user.Roles))
+	for _, roleID := range user.Roles {
+		if expiresAt, ok := user.RoleExpiry[roleID]; ok && !now.Before(expiresAt) {
+			continue
+		}
+		roles = append(roles, roleID)
+	}
+	return roles
+}
+
+// DefaultResourceSeparator separates the segments of hierarchical resource
+// names, such as personal_data/customer/123, when
+// RBACConfig.ResourceSeparator is unset
+const DefaultResourceSeparator = "/"
+
 // permissionMatches checks if a permission matches the requested resource/action
 func (ac *AccessController) permissionMatches(permission *Permission, resource, action string) bool {
-	resourceMatch := permission.Resource == resource || permission.Resource == "*"
+	separator := ac.config.ResourceSeparator
+	if separator == "" {
+		separator = DefaultResourceSeparator
+	}
+
+	resourceMatch := resourceMatches(permission.Resource, resource, separator)
 	actionMatch := permission.Action == action || permission.Action == "*"
 	return resourceMatch && actionMatch
 }
 
+// resourceMatches reports whether resource is matched by pattern: "*"
+// matches every resource, a pattern ending in separator followed by "*"
+// matches every resource below that prefix at any depth, and any other
+// pattern only matches itself. The prefix must end at a separator, so
+// personal_data/* matches personal_data/customer/123 but neither
+// personal_data itself nor personal_data_logs.
+func resourceMatches(pattern, resource, separator string) bool {
+	if pattern == "*" || pattern == resource {
+		return true
+	}
+
+	if !strings.HasSuffix(pattern, separator+"*") {
+		return false
+	}
+	prefix := strings.TrimSuffix(pattern, "*")
+	return len(resource) > len(prefix) && strings.HasPrefix(resource, prefix)
+}
+
 // isDataClassificationCompatible checks if the permission allows access to the data classification
 func (ac *AccessController) isDataClassificationCompatible(permissionClass, dataClass string) bool {
 	classificationLevels := map[string]int{
@@ -286,7 +454,7 @@ func (ac *AccessController) isDataClassificationCompatible(permissionClass, data
 // getLegalBasisForAccess determines the GDPR legal basis for the access
 func (ac *AccessController) getLegalBasisForAccess(user *User, permission *Permission) string {
 	// Find the most appropriate legal basis from user's roles
-	for _, roleID := range user.Roles {
+	for _, roleID := range ac.activeRoles(user) {
 		if role, exists := ac.roles[roleID]; exists {
 			// Check if the role has permissions that match this permission
 			for _, permID := range role.Permissions {
@@ -363,17 +531,26 @@ func (ac *AccessController) getPermissionNames(permissions []*Permission) []stri
 
 // logAccessDenied logs a denied access attempt
 func (ac *AccessController) logAccessDenied(userID, resource, action, reason string, context map[string]interface{}) {
+	ac.logger.Warn("Access denied", logger.Fields{
+		"event_type": "access_denied",
+		"user_id":    userID,
+		"resource":   resource,
+		"action":     action,
+		"reason":     reason,
+	})
+
 	if ac.auditLog != nil {
 		event := AccessAuditEvent{
-			ID:           generateAuditID(),
-			Timestamp:    time.Now(),
-			UserID:       userID,
-			Resource:     resource,
-			Action:       action,
-			Success:      false,
-			DenialReason: reason,
-			RiskLevel:    "medium",
-			Metadata:     context,
+			ID:            generateAuditID(),
+			Timestamp:     time.Now(),
+			UserID:        userID,
+			Resource:      resource,
+			Action:        action,
+			DataSubjectID: dataSubject(context),
+			Success:       false,
+			DenialReason:  reason,
+			RiskLevel:     "medium",
+			Metadata:      context,
 		}
 
 		if ipAddr, ok := context["ip_address"]; ok {
@@ -384,12 +561,21 @@ func (ac *AccessController) logAccessDenied(userID, resource, action, reason str
 	}
 }
 
+// dataSubject returns the data subject an access concerns, from the
+// "data_subject" key of the access context
+func dataSubject(context map[string]interface{}) string {
+	subjectID, _ := context["data_subject"].(string)
+	return subjectID
+}
+
 // generateSessionID generates a unique session ID
 func generateSessionID() string {
 	return fmt.Sprintf("sess_%d", time.Now().UnixNano())
 }
 
-// AddUser adds a new user to the system
+// AddUser adds a new user to the system. Roles set on the user are granted
+// as provisioned, without the approval workflow, which is how the first
+// data protection officer is set up.
 func (ac *AccessController) AddUser(user *User) error {
 	ac.mutex.Lock()
 	defer ac.mutex.Unlock()
@@ -404,8 +590,80 @@ func (ac *AccessController) AddUser(user *User) error {
 	user.IsLocked = false
 	user.FailedAttempts = 0
 
-	ac.users[user.ID] = user
-	return nil
+	return ac.commit(func() error {
+		ac.users[user.ID] = user
+		return nil
+	})
+}
+
+// AddRole adds a custom role granting existing permissions
+func (ac *AccessController) AddRole(role *Role) error {
+	if role.ID == "" {
+		return fmt.Errorf("role ID required")
+	}
+
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	if _, exists := ac.roles[role.ID]; exists {
+		return fmt.Errorf("role already exists")
+	}
+	for _, permID := range role.Permissions {
+		if _, exists := ac.permissions[permID]; !exists {
+			return fmt.Errorf("permission %s not found", permID)
+		}
+	}
+
+	role.IsBuiltIn = false
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = time.Now()
+
+	return ac.commit(func() error {
+		ac.roles[role.ID] = role
+		return nil
+	})
+}
+
+// ConsentRecords returns the consent records of the users linked to a data
+// subject, ordered by user ID
+func (ac *AccessController) ConsentRecords(subjectID string) []ConsentRecord {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	userIDs := make([]string, 0)
+	for id, user := range ac.users {
+		if user.DataSubjectID == subjectID {
+			userIDs = append(userIDs, id)
+		}
+	}
+	sort.Strings(userIDs)
+
+	records := make([]ConsentRecord, 0)
+	for _, id := range userIDs {
+		records = append(records, ac.users[id].ConsentRecords...)
+	}
+	return records
+}
+
+// IsActive reports whether consent was given and has neither been withdrawn
+// nor expired at t
+func (c ConsentRecord) IsActive(t time.Time) bool {
+	if !c.ConsentGiven || c.WithdrawnAt != nil {
+		return false
+	}
+	return c.ExpiresAt == nil || t.Before(*c.ExpiresAt)
+}
+
+// HasActiveConsent reports whether a data subject has active consent to
+// processing for purpose
+func (ac *AccessController) HasActiveConsent(subjectID, purpose string) bool {
+	now := ac.now()
+	for _, consent := range ac.ConsentRecords(subjectID) {
+		if consent.ProcessingPurpose == purpose && consent.IsActive(now) {
+			return true
+		}
+	}
+	return false
 }
 
 // AssignRole assigns a role to a user
@@ -413,6 +671,104 @@ func (ac *AccessController) AssignRole(userID, roleID string) error {
 	ac.mutex.Lock()
 	defer ac.mutex.Unlock()
 
+	return ac.commit(func() error {
+		return ac.assignRole(userID, roleID)
+	})
+}
+
+// AssignRoleToUsers assigns a role to each user independently and returns
+// the outcome per user, nil on success. A failure for one user, including a
+// role that requires approval, does not stop the others.
+func (ac *AccessController) AssignRoleToUsers(userIDs []string, roleID string) map[string]error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	return ac.commitEach(userIDs, func(userID string) error {
+		return ac.assignRole(userID, roleID)
+	})
+}
+
+// AssignTemporaryRole assigns a role to a user until the given time, after
+// which it no longer grants access and is removed by the cleanup sweep.
+// Assigning a temporary role the user already holds temporarily moves its
+// expiry.
+func (ac *AccessController) AssignTemporaryRole(userID, roleID string, until time.Time) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	if !until.After(ac.now()) {
+		return fmt.Errorf("temporary role expiry must be in the future")
+	}
+
+	return ac.commit(func() error {
+		user, exists := ac.users[userID]
+		if !exists {
+			return fmt.Errorf("user not found")
+		}
+
+		if _, temporary := user.RoleExpiry[roleID]; temporary {
+			user.RoleExpiry[roleID] = until
+			user.UpdatedAt = time.Now()
+			return nil
+		}
+
+		if err := ac.assignRole(userID, roleID); err != nil {
+			return err
+		}
+
+		if user.RoleExpiry == nil {
+			user.RoleExpiry = make(map[string]time.Time)
+		}
+		user.RoleExpiry[roleID] = until
+		return nil
+	})
+}
+
+// RevokeRole removes a role from a user
+func (ac *AccessController) RevokeRole(userID, roleID string) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	return ac.commit(func() error {
+		return ac.revokeRole(userID, roleID)
+	})
+}
+
+// RevokeRoleFromUsers removes a role from each user independently and
+// returns the outcome per user, nil on success
+func (ac *AccessController) RevokeRoleFromUsers(userIDs []string, roleID string) map[string]error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	return ac.commitEach(userIDs, func(userID string) error {
+		return ac.revokeRole(userID, roleID)
+	})
+}
+
+// commitEach applies change to each user independently in a single commit
+// and returns the outcome per user, nil on success. A failed save undoes
+// every change and is reported as the result of each user that changed; the
+// caller holds mutex.
+func (ac *AccessController) commitEach(userIDs []string, change func(userID string) error) map[string]error {
+	results := make(map[string]error, len(userIDs))
+	err := ac.commit(func() error {
+		for _, userID := range userIDs {
+			results[userID] = change(userID)
+		}
+		return nil
+	})
+	if err != nil {
+		for userID, result := range results {
+			if result == nil {
+				results[userID] = err
+			}
+		}
+	}
+	return results
+}
+
+// assignRole assigns a role to a user; the caller holds mutex
+func (ac *AccessController) assignRole(userID, roleID string) error {
 	user, exists := ac.users[userID]
 	if !exists {
 		return fmt.Errorf("user not found")
@@ -423,17 +779,18 @@ func (ac *AccessController) AssignRole(userID, roleID string) error {
 		return fmt.Errorf("role not found")
 	}
 
-	// Check if role requires approval
+	// Roles requiring approval are assigned through RequestRole
 	if role.RequiresApproval {
-		// In a real system, this would trigger an approval workflow
-		return fmt.Errorf("role assignment requires approval")
+		return fmt.Errorf("role assignment requires approval; use RequestRole")
 	}
 
-	// Check if user already has the role
-	for _, existingRole := range user.Roles {
-		if existingRole == roleID {
-			return fmt.Errorf("user already has this role")
-		}
+	return addRole(user, roleID)
+}
+
+// addRole appends roleID to the user's roles unless they already hold it
+func addRole(user *User, roleID string) error {
+	if hasRole(user, roleID) {
+		return fmt.Errorf("user already has this role")
 	}
 
 	user.Roles = append(user.Roles, roleID)
@@ -442,11 +799,18 @@ func (ac *AccessController) AssignRole(userID, roleID string) error {
 	return nil
 }
 
-// RevokeRole removes a role from a user
-func (ac *AccessController) RevokeRole(userID, roleID string) error {
-	ac.mutex.Lock()
-	defer ac.mutex.Unlock()
+// hasRole reports whether the user holds roleID
+func hasRole(user *User, roleID string) bool {
+	for _, existingRole := range user.Roles {
+		if existingRole == roleID {
+			return true
+		}
+	}
+	return false
+}
 
+// revokeRole removes a role from a user; the caller holds mutex
+func (ac *AccessController) revokeRole(userID, roleID string) error {
 	user, exists := ac.users[userID]
 	if !exists {
 		return fmt.Errorf("user not found")
@@ -455,6 +819,7 @@ func (ac *AccessController) RevokeRole(userID, roleID string) error {
 	for i, role := range user.Roles {
 		if role == roleID {
 			user.Roles = append(user.Roles[:i], user.Roles[i+1:]...)
+			delete(user.RoleExpiry, roleID)
 			user.UpdatedAt = time.Now()
 			return nil
 		}
@@ -486,9 +851,9 @@ func (ac *AccessController) GetRBACMetrics() *RBACMetrics {
 		}
 	}
 
-	now := time.Now()
+	now := ac.now()
 	for _, session := range ac.sessions {
-		if now.Before(session.ExpiresAt) {
+		if ac.sessionExpiryReason(session, now) == "" {
 			metrics.ActiveSessions++
 		}
 	}
@@ -505,3 +870,31 @@ type RBACMetrics struct {
 	TotalPermissions int `json:"total_permissions"`
 	ActiveSessions   int `json:"active_sessions"`
 }
+
+// Roles returns copies of the defined roles, sorted by ID
+func (ac *AccessController) Roles() []Role {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	roles := make([]Role, 0, len(ac.roles))
+	for _, role := range ac.roles {
+		roles = append(roles, *role)
+	}
+
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+	return roles
+}
+
+// Permissions returns copies of the defined permissions, sorted by ID
+func (ac *AccessController) Permissions() []Permission {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	permissions := make([]Permission, 0, len(ac.permissions))
+	for _, permission := range ac.permissions {
+		permissions = append(permissions, *permission)
+	}
+
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].ID < permissions[j].ID })
+	return permissions
+}
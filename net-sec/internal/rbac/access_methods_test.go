@@ -0,0 +1,362 @@
+package rbac
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+func newTestController(t *testing.T, userIDs ...string) *AccessController {
+	t.Helper()
+
+	ac := NewAccessController(&RBACConfig{SessionTimeout: time.Hour}, nil)
+	ac.SetLogger(logger.New("error", "text", io.Discard).Component("rbac"))
+	for _, id := range userIDs {
+		if err := ac.AddUser(&User{ID: id, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ac
+}
+
+// provisionRole gives a user a role directly, as provisioning the user with
+// the role through AddUser would, bypassing the approval workflow
+func provisionRole(t *testing.T, ac *AccessController, userID, roleID string) {
+	t.Helper()
+
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	if err := addRole(ac.users[userID], roleID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectResults checks a per-user result map: an empty want means success,
+// otherwise the error must contain want
+func expectResults(t *testing.T, results map[string]error, want map[string]string) {
+	t.Helper()
+
+	if len(results) != len(want) {
+		t.Errorf("expected results for %d users, got %v", len(want), results)
+	}
+	for userID, message := range want {
+		err, ok := results[userID]
+		switch {
+		case !ok:
+			t.Errorf("%s: missing result", userID)
+		case message == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", userID, err)
+		case message != "" && (err == nil || !strings.Contains(err.Error(), message)):
+			t.Errorf("%s: expected error containing %q, got %v", userID, message, err)
+		}
+	}
+}
+
+func TestAssignRoleToUsers(t *testing.T) {
+	ac := newTestController(t, "alice", "bob", "carol")
+	if err := ac.AssignRole("carol", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+
+	results := ac.AssignRoleToUsers([]string{"alice", "bob", "mallory", "carol"}, "auditor")
+	expectResults(t, results, map[string]string{
+		"alice":   "",
+		"bob":     "",
+		"mallory": "user not found",
+		"carol":   "already has this role",
+	})
+	for _, id := range []string{"alice", "bob"} {
+		if roles := ac.users[id].Roles; len(roles) != 1 || roles[0] != "auditor" {
+			t.Errorf("%s: expected the auditor role, got %v", id, roles)
+		}
+	}
+
+	// An approval-gated role is refused per user without aborting the batch
+	results = ac.AssignRoleToUsers([]string{"alice", "mallory"}, "data_processor")
+	expectResults(t, results, map[string]string{
+		"alice":   "requires approval",
+		"mallory": "user not found",
+	})
+	if roles := ac.users["alice"].Roles; len(roles) != 1 {
+		t.Errorf("approval-gated role was assigned: %v", roles)
+	}
+}
+
+func TestRevokeRoleFromUsers(t *testing.T) {
+	ac := newTestController(t, "alice", "bob", "carol")
+	ac.AssignRoleToUsers([]string{"alice", "bob"}, "auditor")
+
+	results := ac.RevokeRoleFromUsers([]string{"alice", "bob", "carol", "mallory"}, "auditor")
+	expectResults(t, results, map[string]string{
+		"alice":   "",
+		"bob":     "",
+		"carol":   "does not have this role",
+		"mallory": "user not found",
+	})
+	for _, id := range []string{"alice", "bob"} {
+		if roles := ac.users[id].Roles; len(roles) != 0 {
+			t.Errorf("%s: expected no roles, got %v", id, roles)
+		}
+	}
+}
+
+// recordingAuditLog collects permission and session audit events
+type recordingAuditLog struct {
+	permissions []PermissionAuditEvent
+	sessions    []SessionAuditEvent
+}
+
+func (r *recordingAuditLog) LogAccessAttempt(event AccessAuditEvent) {}
+func (r *recordingAuditLog) LogPermissionCheck(event PermissionAuditEvent) {
+	r.permissions = append(r.permissions, event)
+}
+func (r *recordingAuditLog) LogPrivilegeEscalation(event PrivilegeEscalationEvent) {}
+func (r *recordingAuditLog) LogSessionEvent(event SessionAuditEvent) {
+	r.sessions = append(r.sessions, event)
+}
+
+func TestTemporaryRoleExpires(t *testing.T) {
+	ac := newTestController(t, "contractor")
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	provisionRole(t, ac, "contractor", "data_protection_officer")
+	if err := ac.AssignTemporaryRole("contractor", "auditor", now.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	session, err := ac.CreateSession("contractor", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DPO role is removed so only the temporary role grants audit log access
+	if err := ac.RevokeRole("contractor", "data_protection_officer"); err != nil {
+		t.Fatal(err)
+	}
+	if !ac.CheckAccess(session.ID, "audit_logs", "read", map[string]interface{}{}) {
+		t.Fatal("expected the temporary role to grant access")
+	}
+
+	// Past the expiry the role stops granting access before the sweep runs
+	now = now.Add(45 * time.Minute)
+	if ac.CheckAccess(session.ID, "audit_logs", "read", map[string]interface{}{}) {
+		t.Error("expected an expired temporary role to be ignored")
+	}
+
+	ac.removeExpiredRoles()
+	user := ac.users["contractor"]
+	if len(user.Roles) != 0 || len(user.RoleExpiry) != 0 {
+		t.Errorf("expected the temporary role to be removed, got roles %v expiry %v", user.Roles, user.RoleExpiry)
+	}
+	if len(auditLog.permissions) != 1 || auditLog.permissions[0].Reason != "temporary_role_expired" ||
+		auditLog.permissions[0].Metadata["role_id"] != "auditor" {
+		t.Errorf("expected an expiry audit event, got %+v", auditLog.permissions)
+	}
+	if ac.CheckAccess(session.ID, "audit_logs", "read", map[string]interface{}{}) {
+		t.Error("expected access to be denied after the sweep")
+	}
+}
+
+func TestTemporaryRoleLeavesPermanentRoles(t *testing.T) {
+	ac := newTestController(t, "alice")
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	if err := ac.AddRole(&Role{ID: "reviewer", Name: "Reviewer", Permissions: []string{"audit_log_read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.AssignRole("alice", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.AssignTemporaryRole("alice", "data_protection_officer", now.Add(time.Minute)); err == nil || !strings.Contains(err.Error(), "requires approval") {
+		t.Errorf("expected a role requiring approval to be refused, got %v", err)
+	}
+	if err := ac.AssignTemporaryRole("alice", "reviewer", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.AssignTemporaryRole("alice", "auditor", now.Add(time.Minute)); err == nil {
+		t.Error("expected a permanent role not to be made temporary")
+	}
+	if err := ac.AssignTemporaryRole("alice", "reviewer", now.Add(-time.Minute)); err == nil {
+		t.Error("expected an expiry in the past to be rejected")
+	}
+
+	now = now.Add(time.Hour)
+	ac.removeExpiredRoles()
+	if roles := ac.users["alice"].Roles; len(roles) != 1 || roles[0] != "auditor" {
+		t.Errorf("expected only the permanent role to remain, got %v", roles)
+	}
+}
+
+// expiredSessions returns the reasons of the recorded session expiries
+func (r *recordingAuditLog) expiredSessions() []string {
+	reasons := make([]string, 0)
+	for _, event := range r.sessions {
+		if event.EventType == "expired" {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+	return reasons
+}
+
+func TestIdleSessionExpiresOnAccess(t *testing.T) {
+	ac := newTestController(t, "alice")
+	ac.config.IdleTimeout = 10 * time.Minute
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	if err := ac.AssignRole("alice", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+	session, err := ac.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Activity within the idle window keeps the session alive
+	for i := 0; i < 3; i++ {
+		now = now.Add(8 * time.Minute)
+		if !ac.CheckAccess(session.ID, "audit_logs", "read", map[string]interface{}{}) {
+			t.Fatalf("expected access after %d minutes of activity", 8*(i+1))
+		}
+	}
+
+	now = now.Add(11 * time.Minute)
+	if ac.CheckAccess(session.ID, "audit_logs", "read", map[string]interface{}{}) {
+		t.Error("expected an idle session to be denied")
+	}
+	if _, ok := ac.sessions[session.ID]; ok {
+		t.Error("expected the idle session to be invalidated")
+	}
+	if reasons := auditLog.expiredSessions(); len(reasons) != 1 || reasons[0] != "idle_timeout" {
+		t.Errorf("expected one idle_timeout expiry, got %v", reasons)
+	}
+}
+
+func TestCleanupExpiresIdleSessions(t *testing.T) {
+	ac := newTestController(t, "alice", "bob", "carol")
+	ac.config.IdleTimeout = 10 * time.Minute
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	sessions := make(map[string]*Session)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		if err := ac.AssignRole(id, "auditor"); err != nil {
+			t.Fatal(err)
+		}
+		session, err := ac.CreateSession(id, "192.0.2.10", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[id] = session
+	}
+
+	// Only alice stays active; bob is idle and carol outlives her absolute
+	// expiry despite recent activity
+	ac.sessions[sessions["carol"].ID].ExpiresAt = now.Add(15 * time.Minute)
+	now = now.Add(9 * time.Minute)
+	for _, id := range []string{"alice", "carol"} {
+		if !ac.CheckAccess(sessions[id].ID, "audit_logs", "read", map[string]interface{}{}) {
+			t.Fatalf("%s: expected access", id)
+		}
+	}
+	now = now.Add(7 * time.Minute)
+
+	if metrics := ac.GetRBACMetrics(); metrics.ActiveSessions != 1 {
+		t.Errorf("expected 1 active session, got %d", metrics.ActiveSessions)
+	}
+
+	ac.removeExpiredSessions()
+	if _, ok := ac.sessions[sessions["alice"].ID]; !ok {
+		t.Error("expected the active session to remain")
+	}
+	for _, id := range []string{"bob", "carol"} {
+		if _, ok := ac.sessions[sessions[id].ID]; ok {
+			t.Errorf("%s: expected the session to be removed", id)
+		}
+	}
+
+	reasons := make(map[string]string)
+	for _, event := range auditLog.sessions {
+		if event.EventType == "expired" {
+			reasons[event.UserID] = event.Reason
+		}
+	}
+	if len(reasons) != 2 || reasons["bob"] != "idle_timeout" || reasons["carol"] != "session_timeout" {
+		t.Errorf("expected bob idle and carol timed out, got %v", reasons)
+	}
+}
+
+func TestHasActiveConsent(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	withdrawn := now.Add(-time.Hour)
+	expired := now.Add(-time.Minute)
+
+	ac := newTestController(t)
+	ac.now = func() time.Time { return now }
+	if err := ac.AddUser(&User{ID: "alice", DataSubjectID: "subject-1", ConsentRecords: []ConsentRecord{
+		{ID: "c1", ProcessingPurpose: "marketing", ConsentGiven: true},
+		{ID: "c2", ProcessingPurpose: "analytics", ConsentGiven: true, WithdrawnAt: &withdrawn},
+		{ID: "c3", ProcessingPurpose: "research", ConsentGiven: true, ExpiresAt: &expired},
+		{ID: "c4", ProcessingPurpose: "support", ConsentGiven: false},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for purpose, want := range map[string]bool{"marketing": true, "analytics": false, "research": false, "support": false, "billing": false} {
+		if got := ac.HasActiveConsent("subject-1", purpose); got != want {
+			t.Errorf("%s: HasActiveConsent = %v, want %v", purpose, got, want)
+		}
+	}
+	if ac.HasActiveConsent("subject-2", "marketing") {
+		t.Error("expected no consent for an unknown subject")
+	}
+}
+
+func TestPermissionMatchesResourceWildcards(t *testing.T) {
+	ac := newTestController(t)
+
+	for _, tc := range []struct {
+		name      string
+		separator string
+		pattern   string
+		resource  string
+		want      bool
+	}{
+		{"exact", "", "personal_data", "personal_data", true},
+		{"exact mismatch", "", "personal_data", "personal_data_logs", false},
+		{"match all", "", "*", "personal_data/customer/123", true},
+		{"prefix child", "", "personal_data/customer/*", "personal_data/customer/123", true},
+		{"prefix descendant", "", "personal_data/*", "personal_data/customer/123", true},
+		{"prefix sibling name", "", "personal_data/*", "personal_data_logs", false},
+		{"prefix sibling path", "", "personal_data/*", "personal_data_logs/customer", false},
+		{"prefix parent", "", "personal_data/*", "personal_data", false},
+		{"prefix empty child", "", "personal_data/*", "personal_data/", false},
+		{"prefix other branch", "", "personal_data/customer/*", "personal_data/employee/7", false},
+		{"literal star segment", "", "personal_data*", "personal_data_logs", false},
+		{"custom separator", ":", "personal_data:customer:*", "personal_data:customer:123", true},
+		{"custom separator ignores slash", ":", "personal_data/*", "personal_data/customer", false},
+	} {
+		ac.config.ResourceSeparator = tc.separator
+
+		perm := &Permission{Resource: tc.pattern, Action: "read"}
+		if got := ac.permissionMatches(perm, tc.resource, "read"); got != tc.want {
+			t.Errorf("%s: %q matching %q = %v, want %v", tc.name, tc.pattern, tc.resource, got, tc.want)
+		}
+		if ac.permissionMatches(perm, tc.resource, "write") {
+			t.Errorf("%s: expected the action to still be checked", tc.name)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package rbac
+
+// AccessCheck is one resource and action to evaluate with CheckAccessBatch
+type AccessCheck struct {
+	Key      string                 // Identifies the check in the results; "" uses Resource:Action
+	Resource string                 // What resource is accessed
+	Action   string                 // What action is performed
+	Context  map[string]interface{} // As passed to CheckAccess
+}
+
+// key returns the check's key in the CheckAccessBatch results
+func (c AccessCheck) key() string {
+	if c.Key != "" {
+		return c.Key
+	}
+	return c.Resource + ":" + c.Action
+}
+
+// CheckAccessBatch evaluates several checks for a session at once, such as
+// the actions a menu offers, and returns whether each is permitted, by key.
+// The user's permissions are resolved once under a single read lock, and
+// each check is gated like CheckAccess, including MFA, justification and
+// role restrictions. Only denials are logged. Unlike CheckAccess, the
+// session's activity and permission usage are not updated, as nothing has
+// been accessed yet.
+func (ac *AccessController) CheckAccessBatch(sessionID string, checks []AccessCheck) map[string]bool {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	results := make(map[string]bool, len(checks))
+	for _, check := range checks {
+		results[check.key()] = false
+	}
+	if len(checks) == 0 {
+		return results
+	}
+
+	// A session or user that cannot be used denies every check; the denial
+	// is logged once for the batch
+	batchContext := map[string]interface{}{"checks": len(checks)}
+
+	session, exists := ac.sessions[sessionID]
+	if !exists {
+		ac.logAccessDenied("", "*", "*", "invalid_session", batchContext)
+		return results
+	}
+
+	now := ac.now()
+	switch ac.sessionExpiryReason(session, now) {
+	case "session_timeout":
+		ac.logAccessDenied(session.UserID, "*", "*", "session_expired", batchContext)
+		return results
+	case "idle_timeout":
+		// The read lock cannot expire the session; the cleanup sweep will
+		ac.logAccessDenied(session.UserID, "*", "*", "idle_timeout", batchContext)
+		return results
+	}
+
+	user, exists := ac.users[session.UserID]
+	if !exists || !user.IsActive || user.IsLocked {
+		ac.logAccessDenied(session.UserID, "*", "*", "user_inactive_or_locked", batchContext)
+		return results
+	}
+
+	roles := ac.resolveRoles(user, session, now)
+	for _, check := range checks {
+		perm, reason := ac.matchPermission(roles, check.Resource, check.Action)
+		if perm != nil {
+			reason = ac.highRiskDenial(perm, session, check.Context)
+		} else if reason == "" {
+			reason = "insufficient_permissions"
+		}
+
+		if reason != "" {
+			ac.logAccessDenied(session.UserID, check.Resource, check.Action, reason, check.Context)
+			continue
+		}
+		results[check.key()] = true
+	}
+	return results
+}
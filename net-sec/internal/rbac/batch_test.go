@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckAccessBatch(t *testing.T) {
+	ac := newTestController(t, "dpo")
+	ac.config.RequireMFA = true
+	auditLog := &recordingAccessLog{}
+	ac.auditLog = auditLog
+
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	provisionRole(t, ac, "dpo", "data_protection_officer")
+	session, err := ac.CreateSession("dpo", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	justified := map[string]interface{}{"justification": "subject access request"}
+	checks := []AccessCheck{
+		{Resource: "audit_logs", Action: "read"},
+		{Key: "export", Resource: "data_export", Action: "execute", Context: justified},
+		{Resource: "personal_data", Action: "read", Context: justified},
+		{Resource: "network", Action: "configure"},
+	}
+
+	now = now.Add(time.Minute)
+	results := ac.CheckAccessBatch(session.ID, checks)
+	want := map[string]bool{"audit_logs:read": true, "export": false, "personal_data:read": false, "network:configure": false}
+	if len(results) != len(want) {
+		t.Fatalf("unexpected results %v", results)
+	}
+	for key, permitted := range want {
+		if results[key] != permitted {
+			t.Errorf("%s: got %v, want %v", key, results[key], permitted)
+		}
+	}
+
+	reasons := map[string]string{}
+	for _, event := range auditLog.attempts {
+		if event.Success {
+			t.Errorf("expected only denials to be logged, got %+v", event)
+		}
+		reasons[event.Resource] = event.DenialReason
+	}
+	if len(reasons) != 3 || reasons["data_export"] != "mfa_required" || reasons["network"] != "insufficient_permissions" {
+		t.Errorf("unexpected denials %v", reasons)
+	}
+	if !ac.sessions[session.ID].LastActivity.Equal(session.CreatedAt) {
+		t.Error("expected the session activity not to change")
+	}
+
+	// The batch agrees with individual checks once MFA is verified
+	ac.sessions[session.ID].MFAVerified = true
+	results = ac.CheckAccessBatch(session.ID, checks)
+	for _, check := range checks {
+		if single := ac.CheckAccess(session.ID, check.Resource, check.Action, check.Context); results[check.key()] != single {
+			t.Errorf("%s: batch %v, CheckAccess %v", check.key(), results[check.key()], single)
+		}
+	}
+	if !results["export"] || !results["personal_data:read"] {
+		t.Errorf("expected high-risk checks to pass after MFA, got %v", results)
+	}
+}
+
+func TestCheckAccessBatchInvalidSession(t *testing.T) {
+	ac := newTestController(t)
+	auditLog := &recordingAccessLog{}
+	ac.auditLog = auditLog
+
+	results := ac.CheckAccessBatch("sess_missing", []AccessCheck{
+		{Resource: "audit_logs", Action: "read"},
+		{Resource: "personal_data", Action: "read"},
+	})
+	if len(results) != 2 || results["audit_logs:read"] || results["personal_data:read"] {
+		t.Errorf("expected every check denied, got %v", results)
+	}
+	if len(auditLog.attempts) != 1 || auditLog.attempts[0].DenialReason != "invalid_session" {
+		t.Errorf("expected a single invalid_session denial, got %+v", auditLog.attempts)
+	}
+}
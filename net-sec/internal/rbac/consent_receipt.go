@@ -0,0 +1,131 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// ConsentReceiptVersion identifies the receipt format
+const ConsentReceiptVersion = "ISO/IEC 29184:2020"
+
+// ConsentController identifies the PII controller named on consent receipts
+type ConsentController struct {
+	Name             string `json:"name"`
+	Contact          string `json:"contact,omitempty"`
+	Email            string `json:"email,omitempty"`
+	Address          string `json:"address,omitempty"`
+	PrivacyPolicyURL string `json:"privacy_policy_url,omitempty"`
+	WithdrawalURL    string `json:"withdrawal_url,omitempty"` // Where data subjects withdraw consent
+}
+
+// ConsentReceipt is the record of a consent handed to the data subject, in
+// the style of an ISO/IEC 29184 consent receipt
+type ConsentReceipt struct {
+	Version          string            `json:"version"`
+	ReceiptID        string            `json:"receipt_id"`
+	ConsentID        string            `json:"consent_id"`
+	IssuedAt         time.Time         `json:"issued_at"`
+	Subject          string            `json:"subject"` // Data subject ID, or the user ID when the user has none
+	Controller       ConsentController `json:"controller"`
+	Purposes         []ConsentPurpose  `json:"purposes"`
+	LegalBasis       string            `json:"legal_basis"`
+	ConsentTimestamp time.Time         `json:"consent_timestamp"`
+	CollectionMethod string            `json:"collection_method"`
+	ExpiresAt        *time.Time        `json:"expires_at,omitempty"`
+	WithdrawnAt      *time.Time        `json:"withdrawn_at,omitempty"`
+	WithdrawalMethod string            `json:"withdrawal_method"` // How the subject can withdraw consent
+}
+
+// ConsentPurpose is a processing purpose covered by a consent receipt
+type ConsentPurpose struct {
+	Purpose      string `json:"purpose"`
+	DataCategory string `json:"data_category"`
+}
+
+// GenerateConsentReceipt builds the receipt for a consent given by a user,
+// naming the controller from RBACConfig.ConsentController
+func (ac *AccessController) GenerateConsentReceipt(userID, consentID string) (*ConsentReceipt, error) {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	controller := ac.config.ConsentController
+	if controller == nil || controller.Name == "" {
+		return nil, fmt.Errorf("consent controller not configured")
+	}
+	if controller.WithdrawalURL == "" && controller.Email == "" && controller.Contact == "" {
+		return nil, fmt.Errorf("consent controller has no withdrawal contact")
+	}
+
+	user, exists := ac.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	var consent *ConsentRecord
+	for i := range user.ConsentRecords {
+		if user.ConsentRecords[i].ID == consentID {
+			consent = &user.ConsentRecords[i]
+			break
+		}
+	}
+	if consent == nil {
+		return nil, fmt.Errorf("consent %s not found", consentID)
+	}
+	if !consent.ConsentGiven {
+		return nil, fmt.Errorf("consent %s was not given", consentID)
+	}
+
+	subject := user.DataSubjectID
+	if subject == "" {
+		subject = user.ID
+	}
+
+	receipt := &ConsentReceipt{
+		Version:   ConsentReceiptVersion,
+		ReceiptID: fmt.Sprintf("receipt_%d", ac.now().UnixNano()),
+		ConsentID: consent.ID,
+		IssuedAt:  ac.now(),
+		Subject:   subject,
+		Purposes: []ConsentPurpose{{
+			Purpose:      consent.ProcessingPurpose,
+			DataCategory: consent.DataCategory,
+		}},
+		LegalBasis:       consent.LegalBasis,
+		ConsentTimestamp: consent.ConsentDate,
+		CollectionMethod: consent.ConsentMethod,
+		ExpiresAt:        consent.ExpiresAt,
+		WithdrawnAt:      consent.WithdrawnAt,
+		Controller:       *controller,
+	}
+	receipt.WithdrawalMethod = withdrawalMethod(controller, receipt.ReceiptID)
+
+	ac.logger.Info("Consent receipt generated", logger.Fields{
+		"event_type": "consent_receipt_generated",
+		"user_id":    userID,
+		"consent_id": consentID,
+		"receipt_id": receipt.ReceiptID,
+	})
+
+	return receipt, nil
+}
+
+// JSON returns the receipt encoded for handing to the data subject
+func (r *ConsentReceipt) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// withdrawalMethod describes how consent can be withdrawn from controller,
+// preferring its withdrawal URL
+func withdrawalMethod(controller *ConsentController, receiptID string) string {
+	switch {
+	case controller.WithdrawalURL != "":
+		return fmt.Sprintf("Withdraw consent at any time at %s, quoting receipt %s", controller.WithdrawalURL, receiptID)
+	case controller.Email != "":
+		return fmt.Sprintf("Withdraw consent at any time by emailing %s, quoting receipt %s", controller.Email, receiptID)
+	default:
+		return fmt.Sprintf("Withdraw consent at any time by contacting %s, quoting receipt %s", controller.Contact, receiptID)
+	}
+}
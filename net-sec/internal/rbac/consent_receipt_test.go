@@ -0,0 +1,104 @@
+package rbac
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateConsentReceipt(t *testing.T) {
+	ac := newTestController(t)
+	ac.config.ConsentController = &ConsentController{
+		Name:             "StealthGuard Ltd",
+		Email:            "privacy@example.com",
+		PrivacyPolicyURL: "https://example.com/privacy",
+		WithdrawalURL:    "https://example.com/consent",
+	}
+	issuedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ac.now = func() time.Time { return issuedAt }
+
+	consentDate := issuedAt.Add(-24 * time.Hour)
+	if err := ac.AddUser(&User{
+		ID:            "alice",
+		IsActive:      true,
+		DataSubjectID: "subject-1",
+		ConsentRecords: []ConsentRecord{
+			{ID: "marketing", DataCategory: "contact", ProcessingPurpose: "newsletter", LegalBasis: "consent", ConsentGiven: false},
+			{ID: "analytics", DataCategory: "usage", ProcessingPurpose: "product_analytics", LegalBasis: "consent",
+				ConsentGiven: true, ConsentDate: consentDate, ConsentMethod: "opt-in"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := ac.GenerateConsentReceipt("alice", "analytics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := receipt.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"version", "receipt_id", "consent_id", "issued_at", "subject", "controller",
+		"purposes", "legal_basis", "consent_timestamp", "collection_method", "withdrawal_method"} {
+		if value, ok := fields[field]; !ok || value == "" || value == nil {
+			t.Errorf("expected %s to be populated, got %v", field, value)
+		}
+	}
+
+	if receipt.Subject != "subject-1" || receipt.ConsentID != "analytics" || receipt.LegalBasis != "consent" ||
+		receipt.CollectionMethod != "opt-in" || !receipt.ConsentTimestamp.Equal(consentDate) || !receipt.IssuedAt.Equal(issuedAt) {
+		t.Errorf("receipt does not match the stored consent: %+v", receipt)
+	}
+	if len(receipt.Purposes) != 1 || receipt.Purposes[0] != (ConsentPurpose{Purpose: "product_analytics", DataCategory: "usage"}) {
+		t.Errorf("unexpected purposes %+v", receipt.Purposes)
+	}
+	if receipt.Controller.Name != "StealthGuard Ltd" {
+		t.Errorf("expected the configured controller, got %+v", receipt.Controller)
+	}
+	if !strings.Contains(receipt.WithdrawalMethod, "https://example.com/consent") ||
+		!strings.Contains(receipt.WithdrawalMethod, receipt.ReceiptID) {
+		t.Errorf("expected withdrawal instructions naming the URL and receipt, got %q", receipt.WithdrawalMethod)
+	}
+
+	if _, err := ac.GenerateConsentReceipt("alice", "marketing"); err == nil {
+		t.Error("expected no receipt for consent that was not given")
+	}
+	if _, err := ac.GenerateConsentReceipt("alice", "missing"); err == nil {
+		t.Error("expected an error for an unknown consent")
+	}
+	if _, err := ac.GenerateConsentReceipt("bob", "analytics"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+func TestConsentReceiptRequiresWithdrawalContact(t *testing.T) {
+	ac := newTestController(t)
+	if err := ac.AddUser(&User{ID: "alice", ConsentRecords: []ConsentRecord{{ID: "c1", ConsentGiven: true}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ac.GenerateConsentReceipt("alice", "c1"); err == nil {
+		t.Error("expected an error without a configured controller")
+	}
+
+	ac.config.ConsentController = &ConsentController{Name: "StealthGuard Ltd"}
+	if _, err := ac.GenerateConsentReceipt("alice", "c1"); err == nil {
+		t.Error("expected an error without a withdrawal contact")
+	}
+
+	ac.config.ConsentController.Email = "privacy@example.com"
+	receipt, err := ac.GenerateConsentReceipt("alice", "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Subject != "alice" || !strings.Contains(receipt.WithdrawalMethod, "privacy@example.com") {
+		t.Errorf("expected the user ID as subject and email withdrawal, got %+v", receipt)
+	}
+}
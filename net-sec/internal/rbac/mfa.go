@@ -0,0 +1,245 @@
+package rbac
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// TOTP parameters (RFC 6238): HMAC-SHA1, 6 digits and 30 second steps, the
+// defaults authenticator apps assume
+const (
+	totpDigits     = 6
+	totpStep       = 30 * time.Second
+	totpSkew       = 1  // Steps accepted either side of the current one for clock drift
+	totpSecretSize = 20 // Bytes, the HMAC-SHA1 output size recommended by RFC 4226
+)
+
+// DefaultMaxMFAFailures is the number of failed MFA verifications that
+// locks an account when RBACConfig.MaxFailedAttempts is unset
+const DefaultMaxMFAFailures = 5
+
+// totpEncoding is the unpadded base32 used by otpauth:// provisioning URIs
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollMFA generates a TOTP secret for a user and enables MFA. The base32
+// secret is returned for provisioning an authenticator app; enrolling again
+// replaces it.
+func (ac *AccessController) EnrollMFA(userID string) (string, error) {
+	key := make([]byte, totpSecretSize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate MFA secret: %w", err)
+	}
+	secret := totpEncoding.EncodeToString(key)
+
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	err := ac.commit(func() error {
+		user, exists := ac.users[userID]
+		if !exists {
+			return fmt.Errorf("user not found")
+		}
+
+		user.MFASecret = secret
+		user.MFAEnabled = true
+		user.MFALastStep = 0
+		user.MFAFailedAttempts = 0
+		user.UpdatedAt = ac.now()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ac.logger.Info("MFA enrolled", logger.Fields{
+		"event_type": "mfa_enrolled",
+		"user_id":    userID,
+	})
+	return secret, nil
+}
+
+// VerifyMFA checks a TOTP code from the session user's authenticator and
+// marks the session MFA verified, which CheckAccess requires for high-risk
+// permissions when RequireMFA is set. Codes from the current time step and
+// one step either side are accepted, and a step cannot be used twice, so a
+// code observed in transit cannot be replayed. Failures are counted per
+// user; reaching MaxFailedAttempts (DefaultMaxMFAFailures when unset)
+// terminates the session and locks the account for LockoutDuration.
+func (ac *AccessController) VerifyMFA(sessionID, code string) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	session, exists := ac.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+
+	now := ac.now()
+	if reason := ac.sessionExpiryReason(session, now); reason != "" {
+		ac.expireSession(sessionID, session, now, reason)
+		return fmt.Errorf("session expired")
+	}
+
+	user, exists := ac.users[session.UserID]
+	if !exists || !user.IsActive {
+		return fmt.Errorf("user inactive or locked")
+	}
+	if err := ac.checkLockout(user, now); err != nil {
+		return err
+	}
+	if !user.MFAEnabled || user.MFASecret == "" {
+		return fmt.Errorf("MFA not enrolled for user %s", user.ID)
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(user.MFASecret))
+	if err != nil {
+		return fmt.Errorf("invalid MFA secret: %w", err)
+	}
+
+	step, ok := matchTOTP(key, code, now)
+	switch {
+	case !ok:
+		ac.recordFailedMFA(session, user, "invalid_code", now)
+		return fmt.Errorf("invalid MFA code")
+	case step <= user.MFALastStep:
+		ac.recordFailedMFA(session, user, "code_reused", now)
+		return fmt.Errorf("MFA code already used")
+	}
+
+	// Persist the accepted step before trusting the session, so the code
+	// cannot be replayed after a restart
+	err = ac.commit(func() error {
+		user := ac.users[session.UserID]
+		user.MFALastStep = step
+		user.MFAFailedAttempts = 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	session.MFAVerified = true
+	session.LastActivity = now
+	ac.logMFAEvent(session, "mfa_verified", "", now)
+	return nil
+}
+
+// recordFailedMFA counts a failed MFA verification for user. Once the limit
+// is reached the session is terminated and the account locked, so codes
+// cannot be guessed by retrying; the caller holds mutex.
+func (ac *AccessController) recordFailedMFA(session *Session, user *User, reason string, now time.Time) {
+	user.MFAFailedAttempts++
+	ac.logMFAEvent(session, "mfa_failed", reason, now)
+
+	maxFailures := ac.config.MaxFailedAttempts
+	if maxFailures <= 0 {
+		maxFailures = DefaultMaxMFAFailures
+	}
+	if user.MFAFailedAttempts < maxFailures {
+		ac.saveOrLog()
+		return
+	}
+
+	user.IsLocked = true
+	user.LastFailedAttempt = &now
+	ac.expireSession(session.ID, session, now, "mfa_failed_attempts")
+	ac.saveOrLog()
+
+	ac.logger.Warn("Account locked after repeated MFA failures", logger.Fields{
+		"event_type":          "account_locked",
+		"user_id":             user.ID,
+		"ip_address":          session.IPAddress,
+		"mfa_failed_attempts": user.MFAFailedAttempts,
+	})
+
+	if ac.auditLog != nil {
+		ac.auditLog.LogSessionEvent(SessionAuditEvent{
+			ID:        generateAuditID(),
+			Timestamp: now,
+			SessionID: session.ID,
+			UserID:    user.ID,
+			EventType: "account_locked",
+			IPAddress: session.IPAddress,
+			UserAgent: session.UserAgent,
+			Reason:    "max_mfa_failures",
+			Duration:  ac.config.LockoutDuration,
+			Metadata: map[string]interface{}{
+				"mfa_failed_attempts": user.MFAFailedAttempts,
+				"breach_suspected":    true,
+			},
+		})
+	}
+}
+
+// logMFAEvent logs and audits the outcome of an MFA verification; the caller
+// holds mutex
+func (ac *AccessController) logMFAEvent(session *Session, eventType, reason string, now time.Time) {
+	fields := logger.Fields{
+		"event_type": eventType,
+		"session_id": session.ID,
+		"user_id":    session.UserID,
+	}
+	if reason != "" {
+		fields["reason"] = reason
+		ac.logger.Warn("MFA verification failed", fields)
+	} else {
+		ac.logger.Info("MFA verified", fields)
+	}
+
+	if ac.auditLog != nil {
+		ac.auditLog.LogSessionEvent(SessionAuditEvent{
+			ID:        generateAuditID(),
+			Timestamp: now,
+			SessionID: session.ID,
+			UserID:    session.UserID,
+			EventType: eventType,
+			IPAddress: session.IPAddress,
+			UserAgent: session.UserAgent,
+			Reason:    reason,
+		})
+	}
+}
+
+// matchTOTP returns the time step within totpSkew of now whose code matches
+// code, comparing in constant time
+func matchTOTP(key []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpStep/time.Second)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the code for a time step with the dynamic truncation of
+// RFC 4226
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus)
+}
@@ -0,0 +1,230 @@
+package rbac
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Key is the SHA-1 key of the RFC 6238 test vectors
+var rfc6238Key = []byte("12345678901234567890")
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	// RFC 6238 lists 8 digit codes; these are their last 6 digits
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1234567890:  "005924",
+		20000000000: "353130",
+	} {
+		if got := totpCode(rfc6238Key, unix/30); got != want {
+			t.Errorf("totpCode at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+// newMFAController returns a controller requiring MFA with a DPO enrolled
+// with the RFC 6238 key, at a fixed time of 1111111109
+func newMFAController(t *testing.T) (*AccessController, *recordingAuditLog) {
+	t.Helper()
+
+	ac := newTestController(t, "dpo")
+	ac.config.RequireMFA = true
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+
+	now := time.Unix(1111111109, 0)
+	ac.now = func() time.Time { return now }
+
+	provisionRole(t, ac, "dpo", "data_protection_officer")
+	ac.users["dpo"].MFAEnabled = true
+	ac.users["dpo"].MFASecret = totpEncoding.EncodeToString(rfc6238Key)
+	return ac, auditLog
+}
+
+func TestVerifyMFAUnlocksHighRiskPermissions(t *testing.T) {
+	ac, auditLog := newMFAController(t)
+
+	session, err := ac.CreateSession("dpo", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := map[string]interface{}{"justification": "subject access request"}
+	if ac.CheckAccess(session.ID, "personal_data", "read", context) {
+		t.Fatal("expected high-risk access to need MFA")
+	}
+	if !ac.CheckAccess(session.ID, "audit_logs", "read", nil) {
+		t.Error("expected low-risk access without MFA")
+	}
+
+	if err := ac.VerifyMFA(session.ID, "000000"); err == nil {
+		t.Fatal("expected a wrong code to be rejected")
+	}
+	if err := ac.VerifyMFA(session.ID, "081804"); err != nil {
+		t.Fatalf("VerifyMFA: %v", err)
+	}
+	if !ac.CheckAccess(session.ID, "personal_data", "read", context) {
+		t.Error("expected high-risk access after MFA")
+	}
+
+	var eventTypes []string
+	for _, event := range auditLog.sessions {
+		if strings.HasPrefix(event.EventType, "mfa_") {
+			if event.SessionID != session.ID || event.UserID != "dpo" {
+				t.Errorf("unexpected event %+v", event)
+			}
+			eventTypes = append(eventTypes, event.EventType+":"+event.Reason)
+		}
+	}
+	if got := strings.Join(eventTypes, ","); got != "mfa_failed:invalid_code,mfa_verified:" {
+		t.Errorf("MFA events = %s", got)
+	}
+}
+
+func TestVerifyMFARejectsReplayedCode(t *testing.T) {
+	ac, _ := newMFAController(t)
+
+	first, err := ac.CreateSession("dpo", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ac.CreateSession("dpo", "198.51.100.7", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ac.VerifyMFA(first.ID, "081804"); err != nil {
+		t.Fatalf("VerifyMFA: %v", err)
+	}
+	if err := ac.VerifyMFA(second.ID, "081804"); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Fatalf("expected the replayed code to be rejected, got %v", err)
+	}
+	if ac.sessions[second.ID].MFAVerified {
+		t.Error("expected the second session to stay unverified")
+	}
+
+	// The previous step's code is within the skew but not newer than the
+	// step already used
+	previous := totpCode(rfc6238Key, 1111111109/30-1)
+	if err := ac.VerifyMFA(second.ID, previous); err == nil {
+		t.Error("expected an older step's code to be rejected")
+	}
+
+	next := totpCode(rfc6238Key, 1111111109/30+1)
+	if err := ac.VerifyMFA(second.ID, next); err != nil {
+		t.Errorf("expected the next step's code to be accepted: %v", err)
+	}
+}
+
+func TestEnrollMFA(t *testing.T) {
+	ac := newTestController(t, "alice")
+
+	secret, err := ac.EnrollMFA("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != totpSecretSize {
+		t.Fatalf("expected a %d byte base32 secret, got %q (%v)", totpSecretSize, secret, err)
+	}
+	if user := ac.users["alice"]; !user.MFAEnabled || user.MFASecret != secret {
+		t.Errorf("expected MFA enabled with the secret, got %+v", user)
+	}
+
+	session, err := ac.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.VerifyMFA(session.ID, totpCode(key, time.Now().Unix()/30)); err != nil {
+		t.Errorf("VerifyMFA: %v", err)
+	}
+
+	other := newTestController(t, "bob")
+	session, err = other.CreateSession("bob", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.VerifyMFA(session.ID, "123456"); err == nil || !strings.Contains(err.Error(), "not enrolled") {
+		t.Errorf("expected an error for a user without MFA, got %v", err)
+	}
+}
+
+func TestVerifyMFALocksAfterRepeatedFailures(t *testing.T) {
+	ac, auditLog := newMFAController(t)
+	ac.config.MaxFailedAttempts = 3
+	ac.config.LockoutDuration = time.Hour
+
+	session, err := ac.CreateSession("dpo", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := ac.VerifyMFA(session.ID, "000000"); err == nil {
+			t.Fatal("expected a wrong code to be rejected")
+		}
+	}
+
+	if _, exists := ac.sessions[session.ID]; exists {
+		t.Error("expected the session to be terminated")
+	}
+	if !ac.users["dpo"].IsLocked {
+		t.Error("expected the account to be locked")
+	}
+	if err := ac.VerifyMFA(session.ID, "081804"); err == nil {
+		t.Error("expected the correct code to be refused after the lockout")
+	}
+
+	locked := false
+	for _, event := range auditLog.sessions {
+		if event.EventType == "account_locked" && event.Reason == "max_mfa_failures" {
+			locked = true
+		}
+	}
+	if !locked {
+		t.Error("expected the lockout to be audited")
+	}
+}
+
+func TestMFAReplayStateSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	now := time.Unix(1111111109, 0)
+
+	newController := func() *AccessController {
+		ac := newStoreController(t, path)
+		ac.now = func() time.Time { return now }
+		return ac
+	}
+
+	ac := newController()
+	if err := ac.AddUser(&User{ID: "alice", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	ac.mutex.Lock()
+	ac.users["alice"].MFAEnabled = true
+	ac.users["alice"].MFASecret = totpEncoding.EncodeToString(rfc6238Key)
+	ac.mutex.Unlock()
+
+	session, err := ac.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.VerifyMFA(session.ID, "081804"); err != nil {
+		t.Fatalf("VerifyMFA: %v", err)
+	}
+	if err := ac.VerifyMFA(session.ID, "000000"); err == nil {
+		t.Fatal("expected a wrong code to be rejected")
+	}
+
+	restarted := newController()
+	if got := restarted.users["alice"].MFAFailedAttempts; got != 1 {
+		t.Errorf("expected 1 failed attempt after restart, got %d", got)
+	}
+	session, err = restarted.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.VerifyMFA(session.ID, "081804"); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("expected the code to stay used after restart, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package rbac
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+	"golang.org/x/crypto/argon2"
+)
+
+// Password policy defaults used when the RBACConfig fields are unset
+const (
+	DefaultPasswordMinLength  = 12
+	DefaultPasswordMinClasses = 3
+)
+
+// Argon2id parameters for new password hashes, following RFC 9106's
+// recommendation for memory-constrained environments. Verification reads
+// the parameters from the stored hash, so they can be raised later.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// Bounds on the Argon2id parameters accepted from a stored hash. A hash with
+// zero threads would panic, and huge memory or time costs would let a single
+// login attempt exhaust the host, so anything outside them is rejected.
+const (
+	argon2MaxTime    = 16
+	argon2MaxMemory  = 256 * 1024 // KiB
+	argon2MaxThreads = 16
+	argon2MinKeyLen  = 16
+	argon2MaxKeyLen  = 64
+)
+
+// SetPassword checks plaintext against the password policy and stores its
+// Argon2id hash. The plaintext itself is never stored.
+func (ac *AccessController) SetPassword(userID, plaintext string) error {
+	if err := ac.checkPasswordPolicy(plaintext); err != nil {
+		return err
+	}
+
+	// Hash before taking the lock; Argon2id is deliberately slow
+	hash, err := hashPassword(plaintext)
+	if err != nil {
+		return err
+	}
+
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	return ac.commit(func() error {
+		user, exists := ac.users[userID]
+		if !exists {
+			return fmt.Errorf("user not found")
+		}
+
+		user.PasswordHash = hash
+		user.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// Authenticate verifies a user's password and creates a session. Failed
+// attempts are counted, and the account is locked for LockoutDuration once
+// MaxFailedAttempts is reached. Unknown and locked accounts get the same
+// error as a wrong password after the same Argon2id work, so neither can be
+// told apart from outside, and every failure is audited.
+func (ac *AccessController) Authenticate(userID, password, ipAddress, userAgent string) (*Session, error) {
+	ac.mutex.RLock()
+	hash := ""
+	if user, exists := ac.users[userID]; exists {
+		hash = user.PasswordHash
+	}
+	ac.mutex.RUnlock()
+
+	// Verify outside the lock; Argon2id is deliberately slow. Accounts
+	// without a password are checked against a dummy hash to take as long.
+	var verifyErr error
+	if hash != "" {
+		verifyErr = verifyPassword(hash, password)
+	} else {
+		dummy, _ := dummyPasswordHash()
+		verifyPassword(dummy, password)
+		verifyErr = fmt.Errorf("no password set")
+	}
+
+	// The lockout check and the failure count happen under one lock, so
+	// concurrent attempts cannot slip past MaxFailedAttempts
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	now := ac.now()
+	user, exists := ac.users[userID]
+	if !exists {
+		ac.auditFailedAuthentication(userID, ipAddress, userAgent, "unknown_user", now, nil)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	if err := ac.checkLockout(user, now); err != nil {
+		ac.auditFailedAuthentication(userID, ipAddress, userAgent, "account_locked", now, nil)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	if verifyErr != nil || user.PasswordHash != hash {
+		ac.recordFailedAuthentication(user, ipAddress, userAgent)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	session, err := ac.createSession(userID, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	user.FailedAttempts = 0
+	return session, nil
+}
+
+// dummyPasswordHash is the hash of a random password that no one knows,
+// verified against for accounts without a password
+var dummyPasswordHash = sync.OnceValues(func() (string, error) {
+	password := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(password); err != nil {
+		return "", err
+	}
+	return hashPassword(string(password))
+})
+
+// recordFailedAuthentication counts a failed password check and locks the
+// user once MaxFailedAttempts is reached; the caller holds mutex
+func (ac *AccessController) recordFailedAuthentication(user *User, ipAddress, userAgent string) {
+	now := ac.now()
+	user.FailedAttempts++
+	user.LastFailedAttempt = &now
+	locking := !user.IsLocked && ac.config.MaxFailedAttempts > 0 && user.FailedAttempts >= ac.config.MaxFailedAttempts
+	if locking {
+		user.IsLocked = true
+	}
+
+	ac.auditFailedAuthentication(user.ID, ipAddress, userAgent, "invalid_credentials", now, map[string]interface{}{
+		"failed_attempts": user.FailedAttempts,
+		"locked":          user.IsLocked,
+	})
+	if !locking {
+		return
+	}
+	ac.saveOrLog()
+
+	// Reaching the limit suggests credential guessing, so the lockout is
+	// audited as a suspected breach for incident response to pick up
+	lockedUntil := now.Add(ac.config.LockoutDuration)
+	ac.logger.Warn("Account locked after repeated authentication failures", logger.Fields{
+		"event_type":      "account_locked",
+		"user_id":         user.ID,
+		"ip_address":      ipAddress,
+		"failed_attempts": user.FailedAttempts,
+		"locked_until":    lockedUntil,
+	})
+
+	if ac.auditLog != nil {
+		ac.auditLog.LogSessionEvent(SessionAuditEvent{
+			ID:        generateAuditID(),
+			Timestamp: now,
+			UserID:    user.ID,
+			EventType: "account_locked",
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    "max_failed_attempts",
+			Duration:  ac.config.LockoutDuration,
+			Metadata: map[string]interface{}{
+				"failed_attempts":  user.FailedAttempts,
+				"locked_until":     lockedUntil,
+				"breach_suspected": true,
+			},
+		})
+	}
+}
+
+// auditFailedAuthentication logs and audits a refused authentication for
+// reason; the caller holds mutex
+func (ac *AccessController) auditFailedAuthentication(userID, ipAddress, userAgent, reason string, now time.Time, metadata map[string]interface{}) {
+	fields := logger.Fields{
+		"event_type": "authentication_failed",
+		"user_id":    userID,
+		"ip_address": ipAddress,
+		"reason":     reason,
+	}
+	for key, value := range metadata {
+		fields[key] = value
+	}
+	ac.logger.Warn("Authentication failed", fields)
+
+	if ac.auditLog != nil {
+		ac.auditLog.LogSessionEvent(SessionAuditEvent{
+			ID:        generateAuditID(),
+			Timestamp: now,
+			UserID:    userID,
+			EventType: "authentication_failed",
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    reason,
+			Metadata:  metadata,
+		})
+	}
+}
+
+// checkPasswordPolicy enforces the configured minimum length and number of
+// character classes (lower case, upper case, digits, other)
+func (ac *AccessController) checkPasswordPolicy(password string) error {
+	minLength := ac.config.PasswordMinLength
+	if minLength <= 0 {
+		minLength = DefaultPasswordMinLength
+	}
+	minClasses := ac.config.PasswordMinClasses
+	if minClasses <= 0 {
+		minClasses = DefaultPasswordMinClasses
+	}
+
+	if length := len([]rune(password)); length < minLength {
+		return fmt.Errorf("password must be at least %d characters, got %d", minLength, length)
+	}
+
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+	if classes < minClasses {
+		return fmt.Errorf("password must mix at least %d of lower case, upper case, digits and symbols", minClasses)
+	}
+	return nil
+}
+
+// hashPassword returns the Argon2id hash of password with a random salt in
+// PHC string format
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassword checks password against a hash from hashPassword in
+// constant time
+func verifyPassword(hash, password string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return fmt.Errorf("no password set or unsupported hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	if iterations < 1 || iterations > argon2MaxTime ||
+		threads < 1 || threads > argon2MaxThreads ||
+		memory < 8*uint32(threads) || memory > argon2MaxMemory {
+		return fmt.Errorf("argon2 parameters %q out of bounds", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return fmt.Errorf("invalid salt: %w", err)
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return fmt.Errorf("invalid hash: %w", err)
+	}
+	if len(expected) < argon2MinKeyLen || len(expected) > argon2MaxKeyLen {
+		return fmt.Errorf("invalid hash length %d", len(expected))
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	if subtle.ConstantTimeCompare(actual, expected) != 1 {
+		return fmt.Errorf("password mismatch")
+	}
+	return nil
+}
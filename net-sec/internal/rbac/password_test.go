@@ -0,0 +1,240 @@
+package rbac
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetPasswordHashesWithSalt(t *testing.T) {
+	ac := newTestController(t, "alice", "bob")
+	const password = "Correct-Horse-42"
+
+	for _, id := range []string{"alice", "bob"} {
+		if err := ac.SetPassword(id, password); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alice, bob := ac.users["alice"].PasswordHash, ac.users["bob"].PasswordHash
+	if !strings.HasPrefix(alice, "$argon2id$v=19$") {
+		t.Errorf("unexpected hash format %q", alice)
+	}
+	if alice == bob {
+		t.Error("the same password should hash differently for each user")
+	}
+	if strings.Contains(alice, password) {
+		t.Error("hash contains the plaintext")
+	}
+
+	payload, err := json.Marshal(ac.users["alice"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(payload), password) || strings.Contains(string(payload), alice) {
+		t.Errorf("serialized user exposes credentials: %s", payload)
+	}
+
+	if err := ac.SetPassword("mallory", password); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+func TestVerifyPasswordRejectsOutOfBoundsParameters(t *testing.T) {
+	const password = "Correct-Horse-42"
+	hash, err := hashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyPassword(hash, password); err != nil {
+		t.Fatalf("expected the hash to verify, got %v", err)
+	}
+
+	params := "m=65536,t=3,p=4"
+	for _, bad := range []string{"m=65536,t=3,p=0", "m=65536,t=0,p=4", "m=65536,t=100000,p=4",
+		"m=4194304,t=3,p=4", "m=16,t=3,p=4", "m=65536,t=3,p=255"} {
+		// Out of bounds parameters must be rejected before any hashing,
+		// so these return at once instead of panicking or exhausting memory
+		if err := verifyPassword(strings.Replace(hash, params, bad, 1), password); err == nil || !strings.Contains(err.Error(), "out of bounds") {
+			t.Errorf("%s: expected an out of bounds error, got %v", bad, err)
+		}
+	}
+
+	parts := strings.Split(hash, "$")
+	parts[5] = parts[5][:8]
+	if err := verifyPassword(strings.Join(parts, "$"), password); err == nil {
+		t.Error("expected a truncated hash to be rejected")
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	ac := newTestController(t, "alice")
+
+	for password, want := range map[string]string{
+		"Short-1":          "at least 12 characters",
+		"alllowercaseonly": "at least 3 of",
+		"lowercase1234567": "at least 3 of",
+		"Lowercase1234567": "",
+		"lower-case-12345": "",
+	} {
+		err := ac.SetPassword("alice", password)
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", password, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("%q: expected error containing %q, got %v", password, want, err)
+		}
+	}
+
+	ac.config.PasswordMinLength = 6
+	ac.config.PasswordMinClasses = 1
+	if err := ac.SetPassword("alice", "simple"); err != nil {
+		t.Errorf("configured policy not applied: %v", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ac := newTestController(t, "alice")
+	ac.config.MaxFailedAttempts = 3
+	ac.config.LockoutDuration = time.Hour
+	if err := ac.SetPassword("alice", "Correct-Horse-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ac.Authenticate("alice", "Correct-Horse-42", "10.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.UserID != "alice" {
+		t.Errorf("unexpected session %+v", session)
+	}
+
+	if _, err := ac.Authenticate("alice", "correct-horse-42", "10.0.0.1", "test"); err == nil {
+		t.Error("expected a wrong password to be rejected")
+	}
+	if attempts := ac.users["alice"].FailedAttempts; attempts != 1 {
+		t.Errorf("expected 1 failed attempt, got %d", attempts)
+	}
+
+	// A successful login resets the counter
+	if _, err := ac.Authenticate("alice", "Correct-Horse-42", "10.0.0.1", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts := ac.users["alice"].FailedAttempts; attempts != 0 {
+		t.Errorf("expected failed attempts to reset, got %d", attempts)
+	}
+
+	for i := 0; i < 3; i++ {
+		ac.Authenticate("alice", "wrong", "10.0.0.1", "test")
+	}
+	if !ac.users["alice"].IsLocked {
+		t.Fatal("expected the user to be locked after repeated failures")
+	}
+	if _, err := ac.Authenticate("alice", "Correct-Horse-42", "10.0.0.1", "test"); err == nil || err.Error() != "invalid credentials" {
+		t.Errorf("expected a locked user to get the generic error, got %v", err)
+	}
+
+	if _, err := ac.Authenticate("mallory", "Correct-Horse-42", "10.0.0.1", "test"); err == nil || err.Error() != "invalid credentials" {
+		t.Errorf("expected unknown users to get the generic error, got %v", err)
+	}
+}
+
+func TestAuthenticateWithoutPassword(t *testing.T) {
+	ac := newTestController(t, "alice")
+	if _, err := ac.Authenticate("alice", "", "10.0.0.1", "test"); err == nil {
+		t.Error("expected a user without a password to be refused")
+	}
+}
+
+func TestAuthenticateLockoutIsAuditedAndExpires(t *testing.T) {
+	ac := newTestController(t, "alice")
+	ac.config.MaxFailedAttempts = 5
+	ac.config.LockoutDuration = 30 * time.Minute
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+	if err := ac.SetPassword("alice", "Correct-Horse-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	for i := 0; i < 6; i++ {
+		if _, err := ac.Authenticate("alice", "wrong", "192.0.2.200", "test"); err == nil {
+			t.Fatalf("attempt %d: expected a wrong password to be rejected", i+1)
+		}
+	}
+	if user := ac.users["alice"]; !user.IsLocked || user.FailedAttempts != 5 {
+		t.Fatalf("expected the user locked after 5 failures, got %d failures and locked %v", user.FailedAttempts, user.IsLocked)
+	}
+
+	var reasons []string
+	var locked []SessionAuditEvent
+	for _, event := range auditLog.sessions {
+		switch event.EventType {
+		case "authentication_failed":
+			reasons = append(reasons, event.Reason)
+		case "account_locked":
+			locked = append(locked, event)
+		}
+	}
+	if len(reasons) != 6 || reasons[4] != "invalid_credentials" || reasons[5] != "account_locked" {
+		t.Errorf("unexpected failure reasons %v", reasons)
+	}
+	if len(locked) != 1 || locked[0].UserID != "alice" || locked[0].IPAddress != "192.0.2.200" || locked[0].Metadata["breach_suspected"] != true {
+		t.Errorf("expected one breach-worthy lockout event, got %+v", locked)
+	}
+
+	if _, err := ac.Authenticate("alice", "Correct-Horse-42", "192.0.2.200", "test"); err == nil {
+		t.Error("expected a locked user to be refused")
+	}
+	if _, err := ac.CreateSession("alice", "192.0.2.200", "test"); err == nil {
+		t.Error("expected CreateSession to refuse a locked user")
+	}
+
+	now = now.Add(31 * time.Minute)
+	if _, err := ac.Authenticate("alice", "Correct-Horse-42", "192.0.2.200", "test"); err != nil {
+		t.Fatalf("expected the lockout to expire: %v", err)
+	}
+	if user := ac.users["alice"]; user.IsLocked || user.FailedAttempts != 0 {
+		t.Errorf("expected the user unlocked, got %d failures and locked %v", user.FailedAttempts, user.IsLocked)
+	}
+}
+
+func TestAuthenticateCountsConcurrentFailuresUpToTheLimit(t *testing.T) {
+	ac := newTestController(t, "alice")
+	ac.config.MaxFailedAttempts = 2
+	ac.config.LockoutDuration = time.Hour
+	if err := ac.SetPassword("alice", "Correct-Horse-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ac.Authenticate("alice", "wrong", "192.0.2.200", "test")
+		}()
+	}
+	wg.Wait()
+
+	if user := ac.users["alice"]; !user.IsLocked || user.FailedAttempts != 2 {
+		t.Errorf("expected the user locked after 2 counted failures, got %d failures and locked %v", user.FailedAttempts, user.IsLocked)
+	}
+}
+
+func TestAuthenticateAuditsUnknownUsers(t *testing.T) {
+	ac := newTestController(t)
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+
+	if _, err := ac.Authenticate("nonexistent-user", "guess", "192.0.2.200", "test"); err == nil {
+		t.Fatal("expected an unknown user to be refused")
+	}
+	if len(auditLog.sessions) != 1 || auditLog.sessions[0].Reason != "unknown_user" || auditLog.sessions[0].UserID != "nonexistent-user" {
+		t.Errorf("expected the attempt to be audited, got %+v", auditLog.sessions)
+	}
+}
@@ -0,0 +1,118 @@
+package rbac
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// exceptionDateLayout is the format of TimeRestrictions.Exceptions
+const exceptionDateLayout = "2006-01-02"
+
+// roleRestriction returns "ip_not_allowed" when the session's IP address is
+// outside role's AllowedIPRanges, "outside_allowed_hours" when now is outside
+// its TimeRestrictions, and "" when the role may be used. Empty restrictions
+// always allow; malformed ones deny.
+func (ac *AccessController) roleRestriction(role *Role, session *Session, now time.Time) string {
+	if len(role.AllowedIPRanges) > 0 && !ipAllowed(role.AllowedIPRanges, session.IPAddress) {
+		return "ip_not_allowed"
+	}
+
+	if restrictions := role.TimeRestrictions; restrictions != nil {
+		allowed, err := withinAllowedTime(restrictions, now)
+		if err != nil {
+			ac.logger.Error("Invalid role time restrictions", logger.Fields{
+				"event_type": "invalid_time_restrictions",
+				"role_id":    role.ID,
+				"timezone":   restrictions.Timezone,
+				"error":      err.Error(),
+			})
+		}
+		if !allowed {
+			return "outside_allowed_hours"
+		}
+	}
+	return ""
+}
+
+// ipAllowed reports whether address, optionally with a port, is within one
+// of ranges. Ranges are CIDR blocks or single addresses; entries that parse
+// as neither match nothing.
+func ipAllowed(ranges []string, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return false
+		}
+		if ip = net.ParseIP(host); ip == nil {
+			return false
+		}
+	}
+
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if _, network, err := net.ParseCIDR(r); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(r); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinAllowedTime reports whether now, in the restrictions' timezone
+// (UTC when unset), falls in AllowedHours and AllowedDays or on one of the
+// Exceptions dates. An empty AllowedHours or AllowedDays allows any hour or
+// day. The error is set, and access denied, when the timezone is unknown.
+func withinAllowedTime(restrictions *TimeRestrictions, now time.Time) (bool, error) {
+	location := time.UTC
+	if restrictions.Timezone != "" {
+		loaded, err := time.LoadLocation(restrictions.Timezone)
+		if err != nil {
+			return false, err
+		}
+		location = loaded
+	}
+	local := now.In(location)
+
+	date := local.Format(exceptionDateLayout)
+	for _, exception := range restrictions.Exceptions {
+		if strings.TrimSpace(exception) == date {
+			return true, nil
+		}
+	}
+
+	if len(restrictions.AllowedDays) > 0 && !dayAllowed(restrictions.AllowedDays, local.Weekday()) {
+		return false, nil
+	}
+
+	if len(restrictions.AllowedHours) > 0 {
+		for _, hour := range restrictions.AllowedHours {
+			if hour == local.Hour() {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// dayAllowed reports whether day is in days, given as full or three letter
+// English names in any case
+func dayAllowed(days []string, day time.Weekday) bool {
+	name := strings.ToLower(day.String())
+	for _, allowed := range days {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == name || (len(allowed) == 3 && strings.HasPrefix(name, allowed)) {
+			return true
+		}
+	}
+	return false
+}
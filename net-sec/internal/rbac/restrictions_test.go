@@ -0,0 +1,153 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIPAllowed(t *testing.T) {
+	ranges := []string{"10.0.0.0/8", " 192.0.2.10 ", "2001:db8::/32", "not-a-range"}
+
+	for address, want := range map[string]bool{
+		"10.1.2.3":         true,
+		"10.1.2.3:52100":   true,
+		"192.0.2.10":       true,
+		"192.0.2.11":       false,
+		"2001:db8::1":      true,
+		"[2001:db8::1]:80": true,
+		"198.51.100.7":     false,
+		"":                 false,
+		"garbage":          false,
+	} {
+		if got := ipAllowed(ranges, address); got != want {
+			t.Errorf("ipAllowed(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestWithinAllowedTime(t *testing.T) {
+	businessHours := &TimeRestrictions{
+		AllowedHours: []int{9, 10, 11, 12, 13, 14, 15, 16},
+		AllowedDays:  []string{"Monday", "Tuesday", "wed", "THU", "Friday"},
+		Timezone:     "Europe/Helsinki",
+		Exceptions:   []string{"2024-03-02"},
+	}
+
+	for _, tc := range []struct {
+		name         string
+		restrictions *TimeRestrictions
+		now          time.Time
+		want         bool
+	}{
+		// 07:30 UTC is 09:30 in Helsinki (UTC+2 in winter)
+		{"in hours locally", businessHours, time.Date(2024, 2, 27, 7, 30, 0, 0, time.UTC), true},
+		// 15:30 UTC is 17:30 in Helsinki
+		{"after hours locally", businessHours, time.Date(2024, 2, 27, 15, 30, 0, 0, time.UTC), false},
+		// 22:30 UTC on Friday is 00:30 on Saturday in Helsinki
+		{"weekend locally", businessHours, time.Date(2024, 3, 8, 22, 30, 0, 0, time.UTC), false},
+		{"exception date", businessHours, time.Date(2024, 3, 2, 20, 0, 0, 0, time.UTC), true},
+		{"empty", &TimeRestrictions{}, time.Date(2024, 3, 3, 3, 0, 0, 0, time.UTC), true},
+		{"days only", &TimeRestrictions{AllowedDays: []string{"sunday"}}, time.Date(2024, 3, 3, 3, 0, 0, 0, time.UTC), true},
+		{"UTC by default", &TimeRestrictions{AllowedHours: []int{3}}, time.Date(2024, 3, 3, 3, 0, 0, 0, time.FixedZone("EST", -5*3600)), false},
+	} {
+		got, err := withinAllowedTime(tc.restrictions, tc.now)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: withinAllowedTime = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if allowed, err := withinAllowedTime(&TimeRestrictions{Timezone: "Mars/Olympus_Mons"}, time.Now()); err == nil || allowed {
+		t.Errorf("expected an unknown timezone to deny with an error, got %v, %v", allowed, err)
+	}
+}
+
+func TestCheckAccessEnforcesRoleRestrictions(t *testing.T) {
+	ac := newTestController(t, "auditor")
+	auditLog := &recordingAccessLog{}
+	ac.auditLog = auditLog
+
+	now := time.Date(2024, 2, 27, 10, 30, 0, 0, time.UTC) // A Tuesday
+	ac.now = func() time.Time { return now }
+
+	ac.roles["auditor"].AllowedIPRanges = []string{"10.0.0.0/8"}
+	ac.roles["auditor"].TimeRestrictions = &TimeRestrictions{AllowedHours: []int{9, 10}, Timezone: "UTC"}
+	if err := ac.AssignRole("auditor", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+
+	office, err := ac.CreateSession("auditor", "10.20.30.40", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := ac.CreateSession("auditor", "203.0.113.9", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ac.CheckAccess(office.ID, "audit_logs", "read", nil) {
+		t.Error("expected access from an allowed range in allowed hours")
+	}
+	if ac.CheckAccess(remote.ID, "audit_logs", "read", nil) {
+		t.Error("expected access from outside the allowed ranges to be denied")
+	}
+
+	now = now.Add(40 * time.Minute)
+	if ac.CheckAccess(office.ID, "audit_logs", "read", nil) {
+		t.Error("expected access outside allowed hours to be denied")
+	}
+
+	var reasons []string
+	for _, event := range auditLog.attempts {
+		if !event.Success {
+			reasons = append(reasons, event.DenialReason)
+		}
+	}
+	if len(reasons) != 2 || reasons[0] != "ip_not_allowed" || reasons[1] != "outside_allowed_hours" {
+		t.Errorf("unexpected denial reasons %v", reasons)
+	}
+
+	// An unrestricted role granting the same permission still applies
+	provisionRole(t, ac, "auditor", "data_protection_officer")
+	if !ac.CheckAccess(remote.ID, "audit_logs", "read", nil) {
+		t.Error("expected an unrestricted role to grant access")
+	}
+}
+
+// recordingAccessLog collects access audit events
+type recordingAccessLog struct {
+	recordingAuditLog
+	attempts []AccessAuditEvent
+}
+
+func (r *recordingAccessLog) LogAccessAttempt(event AccessAuditEvent) {
+	r.attempts = append(r.attempts, event)
+}
+
+func TestCheckAccessRecordsDataSubject(t *testing.T) {
+	ac := newTestController(t, "auditor")
+	auditLog := &recordingAccessLog{}
+	ac.auditLog = auditLog
+	if err := ac.AssignRole("auditor", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+	session, err := ac.CreateSession("auditor", "10.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access := map[string]interface{}{"data_subject": "subject_1"}
+	ac.CheckAccess(session.ID, "audit_logs", "read", access)
+	ac.CheckAccess("missing", "audit_logs", "read", access)
+	ac.CheckAccess(session.ID, "audit_logs", "read", nil)
+
+	var subjects []string
+	for _, event := range auditLog.attempts {
+		subjects = append(subjects, event.DataSubjectID)
+	}
+	if len(subjects) != 3 || subjects[0] != "subject_1" || subjects[1] != "subject_1" || subjects[2] != "" {
+		t.Errorf("expected granted and denied accesses to record the data subject, got %q", subjects)
+	}
+}
@@ -0,0 +1,241 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// Role request statuses
+const (
+	RoleRequestPending  = "pending"
+	RoleRequestApproved = "approved"
+	RoleRequestDenied   = "denied"
+)
+
+// RoleRequest asks for a role that requires approval to be assigned to a
+// user
+type RoleRequest struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	RoleID        string     `json:"role_id"`
+	Justification string     `json:"justification"`
+	Status        string     `json:"status"` // "pending", "approved", "denied"
+	RequestedAt   time.Time  `json:"requested_at"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+	DecidedBy     string     `json:"decided_by,omitempty"`
+	DenialReason  string     `json:"denial_reason,omitempty"`
+}
+
+// RequestRole queues a request to assign a role that requires approval.
+// The role is assigned once a user holding the approve_role_requests
+// permission approves the request with ApproveRoleRequest.
+func (ac *AccessController) RequestRole(userID, roleID, justification string) (*RoleRequest, error) {
+	if justification == "" {
+		return nil, fmt.Errorf("role requests require a justification")
+	}
+
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	user, exists := ac.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	role, exists := ac.roles[roleID]
+	if !exists {
+		return nil, fmt.Errorf("role not found")
+	}
+	if !role.RequiresApproval {
+		return nil, fmt.Errorf("role %s does not require approval; use AssignRole", roleID)
+	}
+	if hasRole(user, roleID) {
+		return nil, fmt.Errorf("user already has this role")
+	}
+
+	for _, request := range ac.roleRequests {
+		if request.Status == RoleRequestPending && request.UserID == userID && request.RoleID == roleID {
+			return nil, fmt.Errorf("a request for this role is already pending: %s", request.ID)
+		}
+	}
+
+	now := ac.now()
+	request := &RoleRequest{
+		ID:            fmt.Sprintf("rolereq_%d_%d", now.UnixNano(), len(ac.roleRequests)),
+		UserID:        userID,
+		RoleID:        roleID,
+		Justification: justification,
+		Status:        RoleRequestPending,
+		RequestedAt:   now,
+	}
+	ac.roleRequests[request.ID] = request
+
+	ac.logRoleRequest(request, user, now)
+
+	requestCopy := *request
+	return &requestCopy, nil
+}
+
+// ApproveRoleRequest assigns the requested role. The approver must hold the
+// approve_role_requests permission and cannot approve their own request.
+func (ac *AccessController) ApproveRoleRequest(requestID, approverID string) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	request, _, err := ac.decidableRoleRequest(requestID, approverID)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := ac.roles[request.RoleID]; !exists {
+		return fmt.Errorf("role not found")
+	}
+	err = ac.commit(func() error {
+		return addRole(ac.users[request.UserID], request.RoleID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The request is only decided once the role assignment is saved
+	now := ac.now()
+	request.Status = RoleRequestApproved
+	request.DecidedAt = &now
+	request.DecidedBy = approverID
+
+	ac.logRoleRequest(request, ac.users[request.UserID], now)
+	return nil
+}
+
+// DenyRoleRequest rejects a pending role request for reason. The approver
+// must hold the approve_role_requests permission.
+func (ac *AccessController) DenyRoleRequest(requestID, approverID, reason string) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	request, user, err := ac.decidableRoleRequest(requestID, approverID)
+	if err != nil {
+		return err
+	}
+
+	now := ac.now()
+	request.Status = RoleRequestDenied
+	request.DecidedAt = &now
+	request.DecidedBy = approverID
+	request.DenialReason = reason
+
+	ac.logRoleRequest(request, user, now)
+	return nil
+}
+
+// PendingRoleRequests returns copies of the role requests awaiting a
+// decision, oldest first
+func (ac *AccessController) PendingRoleRequests() []RoleRequest {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	pending := make([]RoleRequest, 0)
+	for _, request := range ac.roleRequests {
+		if request.Status == RoleRequestPending {
+			pending = append(pending, *request)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].RequestedAt.Equal(pending[j].RequestedAt) {
+			return pending[i].RequestedAt.Before(pending[j].RequestedAt)
+		}
+		return pending[i].ID < pending[j].ID
+	})
+	return pending
+}
+
+// decidableRoleRequest returns a pending request and its user after checking
+// that approverID may decide it; the caller holds mutex
+func (ac *AccessController) decidableRoleRequest(requestID, approverID string) (*RoleRequest, *User, error) {
+	request, exists := ac.roleRequests[requestID]
+	if !exists {
+		return nil, nil, fmt.Errorf("role request not found")
+	}
+	if request.Status != RoleRequestPending {
+		return nil, nil, fmt.Errorf("role request already %s", request.Status)
+	}
+
+	approver, exists := ac.users[approverID]
+	if !exists || !approver.IsActive || approver.IsLocked {
+		return nil, nil, fmt.Errorf("approver inactive or locked")
+	}
+	if approverID == request.UserID {
+		return nil, nil, fmt.Errorf("users cannot decide their own role requests")
+	}
+
+	authorized := false
+	for _, perm := range ac.getUserPermissions(approver) {
+		if ac.permissionMatches(perm, "role_requests", "approve") {
+			authorized = true
+			break
+		}
+	}
+	if !authorized {
+		ac.logAccessDenied(approverID, "role_requests", "approve", "insufficient_permissions", map[string]interface{}{
+			"request_id": requestID,
+		})
+		return nil, nil, fmt.Errorf("approver lacks the approve_role_requests permission")
+	}
+
+	user, exists := ac.users[request.UserID]
+	if !exists {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+	return request, user, nil
+}
+
+// logRoleRequest logs and audits a role request reaching its current
+// status; the caller holds mutex
+func (ac *AccessController) logRoleRequest(request *RoleRequest, user *User, now time.Time) {
+	ac.logger.Info("Role request "+request.Status, logger.Fields{
+		"event_type": "role_request_" + request.Status,
+		"request_id": request.ID,
+		"user_id":    request.UserID,
+		"role_id":    request.RoleID,
+		"decided_by": request.DecidedBy,
+	})
+
+	if ac.auditLog != nil {
+		metadata := map[string]interface{}{
+			"request_id": request.ID,
+			"status":     request.Status,
+		}
+		if request.DenialReason != "" {
+			metadata["denial_reason"] = request.DenialReason
+		}
+
+		// The roles held apart from the requested one, which an approval
+		// has already added
+		fromRoles := make([]string, 0, len(user.Roles))
+		for _, roleID := range user.Roles {
+			if roleID != request.RoleID {
+				fromRoles = append(fromRoles, roleID)
+			}
+		}
+
+		event := PrivilegeEscalationEvent{
+			ID:              generateAuditID(),
+			Timestamp:       now,
+			UserID:          request.UserID,
+			FromPrivileges:  fromRoles,
+			ToPrivileges:    []string{request.RoleID},
+			Justification:   request.Justification,
+			Success:         request.Status == RoleRequestApproved,
+			DetectionMethod: "manual",
+			Metadata:        metadata,
+		}
+		if request.Status == RoleRequestApproved {
+			event.ApprovedBy = request.DecidedBy
+		}
+		ac.auditLog.LogPrivilegeEscalation(event)
+	}
+}
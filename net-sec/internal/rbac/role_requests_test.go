@@ -0,0 +1,147 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+)
+
+// recordingEscalationLog collects privilege escalation audit events
+type recordingEscalationLog struct {
+	recordingAuditLog
+	escalations []PrivilegeEscalationEvent
+}
+
+func (r *recordingEscalationLog) LogPrivilegeEscalation(event PrivilegeEscalationEvent) {
+	r.escalations = append(r.escalations, event)
+}
+
+func TestRoleRequestApproval(t *testing.T) {
+	ac := newTestController(t, "analyst", "dpo")
+	auditLog := &recordingEscalationLog{}
+	ac.auditLog = auditLog
+	provisionRole(t, ac, "dpo", "data_protection_officer")
+
+	if err := ac.AssignRole("analyst", "data_processor"); err == nil || !strings.Contains(err.Error(), "requires approval") {
+		t.Fatalf("expected AssignRole to require approval, got %v", err)
+	}
+
+	request, err := ac.RequestRole("analyst", "data_processor", "customer support rota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Status != RoleRequestPending {
+		t.Errorf("expected a pending request, got %+v", request)
+	}
+	if _, err := ac.RequestRole("analyst", "data_processor", "again"); err == nil {
+		t.Error("expected a duplicate pending request to be rejected")
+	}
+	if pending := ac.PendingRoleRequests(); len(pending) != 1 || pending[0].ID != request.ID {
+		t.Fatalf("unexpected pending requests %+v", pending)
+	}
+
+	if err := ac.ApproveRoleRequest(request.ID, "analyst"); err == nil {
+		t.Error("expected a self-approval to be rejected")
+	}
+	if err := ac.ApproveRoleRequest(request.ID, "dpo"); err != nil {
+		t.Fatalf("ApproveRoleRequest: %v", err)
+	}
+	if !hasRole(ac.users["analyst"], "data_processor") {
+		t.Error("expected the role to be assigned")
+	}
+	if pending := ac.PendingRoleRequests(); len(pending) != 0 {
+		t.Errorf("expected no pending requests, got %+v", pending)
+	}
+	if err := ac.DenyRoleRequest(request.ID, "dpo", "too late"); err == nil || !strings.Contains(err.Error(), "already approved") {
+		t.Errorf("expected a decided request to be final, got %v", err)
+	}
+
+	if len(auditLog.escalations) != 2 {
+		t.Fatalf("expected requested and approved events, got %+v", auditLog.escalations)
+	}
+	approved := auditLog.escalations[1]
+	if approved.Metadata["status"] != RoleRequestApproved || approved.ApprovedBy != "dpo" || !approved.Success || len(approved.FromPrivileges) != 0 {
+		t.Errorf("unexpected approval event %+v", approved)
+	}
+}
+
+func TestDenyRoleRequest(t *testing.T) {
+	ac := newTestController(t, "analyst", "auditor", "dpo")
+	auditLog := &recordingEscalationLog{}
+	ac.auditLog = auditLog
+	if err := ac.AssignRole("auditor", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+	provisionRole(t, ac, "dpo", "data_protection_officer")
+
+	request, err := ac.RequestRole("analyst", "data_subject_coordinator", "erasure backlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ac.DenyRoleRequest(request.ID, "auditor", "no"); err == nil || !strings.Contains(err.Error(), "approve_role_requests") {
+		t.Errorf("expected an approver without the permission to be rejected, got %v", err)
+	}
+	if err := ac.DenyRoleRequest(request.ID, "dpo", "not needed for the role"); err != nil {
+		t.Fatal(err)
+	}
+	if hasRole(ac.users["analyst"], "data_subject_coordinator") {
+		t.Error("expected a denied role not to be assigned")
+	}
+
+	denied := auditLog.escalations[len(auditLog.escalations)-1]
+	if denied.Success || denied.Metadata["status"] != RoleRequestDenied || denied.Metadata["denial_reason"] != "not needed for the role" {
+		t.Errorf("unexpected denial event %+v", denied)
+	}
+
+	// A denied request does not block a new one
+	if _, err := ac.RequestRole("analyst", "data_subject_coordinator", "erasure backlog grew"); err != nil {
+		t.Errorf("expected a new request after a denial: %v", err)
+	}
+}
+
+func TestRequestRoleValidation(t *testing.T) {
+	ac := newTestController(t, "analyst")
+
+	for name, tc := range map[string]struct{ userID, roleID, justification string }{
+		"no justification":   {"analyst", "data_processor", ""},
+		"unknown user":       {"mallory", "data_processor", "x"},
+		"unknown role":       {"analyst", "superuser", "x"},
+		"no approval needed": {"analyst", "auditor", "x"},
+	} {
+		if _, err := ac.RequestRole(tc.userID, tc.roleID, tc.justification); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDPORoleRequiresApproval(t *testing.T) {
+	ac := newTestController(t, "alice", "dpo")
+	provisionRole(t, ac, "dpo", "data_protection_officer")
+
+	if err := ac.AssignRole("alice", "data_protection_officer"); err == nil || !strings.Contains(err.Error(), "requires approval") {
+		t.Fatalf("expected the DPO role to require approval, got %v", err)
+	}
+
+	request, err := ac.RequestRole("alice", "data_protection_officer", "deputy DPO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.ApproveRoleRequest(request.ID, "alice"); err == nil {
+		t.Error("expected a self-approval to be rejected")
+	}
+	if err := ac.ApproveRoleRequest(request.ID, "dpo"); err != nil {
+		t.Fatalf("ApproveRoleRequest: %v", err)
+	}
+
+	// Holding approve_role_requests does not allow approving your own request
+	own, err := ac.RequestRole("dpo", "data_processor", "ad hoc processing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.ApproveRoleRequest(own.ID, "dpo"); err == nil || !strings.Contains(err.Error(), "own role requests") {
+		t.Errorf("expected a DPO's own request to need another approver, got %v", err)
+	}
+	if err := ac.ApproveRoleRequest(own.ID, "alice"); err != nil {
+		t.Errorf("expected another DPO to approve: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// RevokeSession terminates a session immediately, such as one suspected to
+// be compromised. Later access checks on it fail with invalid_session.
+func (ac *AccessController) RevokeSession(sessionID string) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	session, exists := ac.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+
+	ac.terminateSession(sessionID, session, ac.now(), "revoked")
+	return nil
+}
+
+// RevokeAllUserSessions terminates every session of a user and returns how
+// many were terminated
+func (ac *AccessController) RevokeAllUserSessions(userID string) (int, error) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	if _, exists := ac.users[userID]; !exists {
+		return 0, fmt.Errorf("user not found")
+	}
+
+	now := ac.now()
+	revoked := 0
+	for id, session := range ac.sessions {
+		if session.UserID == userID {
+			ac.terminateSession(id, session, now, "all_user_sessions_revoked")
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// ListSessions returns copies of a user's sessions, or of every session when
+// userID is empty, oldest first. Session metadata is left out of the copies.
+func (ac *AccessController) ListSessions(userID string) []*Session {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	sessions := make([]*Session, 0)
+	for _, session := range ac.sessions {
+		if userID != "" && session.UserID != userID {
+			continue
+		}
+
+		sessionCopy := *session
+		sessionCopy.Metadata = nil
+		sessionCopy.ElevatedPrivileges = append([]string(nil), session.ElevatedPrivileges...)
+		if session.ElevatedExpiresAt != nil {
+			expiresAt := *session.ElevatedExpiresAt
+			sessionCopy.ElevatedExpiresAt = &expiresAt
+		}
+		sessionCopy.AccessedResources = make(map[string]time.Time, len(session.AccessedResources))
+		for resource, accessedAt := range session.AccessedResources {
+			sessionCopy.AccessedResources[resource] = accessedAt
+		}
+		sessions = append(sessions, &sessionCopy)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// terminateSession deletes session and audits its termination for reason;
+// the caller holds mutex
+func (ac *AccessController) terminateSession(id string, session *Session, now time.Time, reason string) {
+	delete(ac.sessions, id)
+
+	ac.logger.Warn("Session terminated", logger.Fields{
+		"event_type": "session_terminated",
+		"session_id": id,
+		"user_id":    session.UserID,
+		"ip_address": session.IPAddress,
+		"reason":     reason,
+	})
+
+	if ac.auditLog != nil {
+		ac.auditLog.LogSessionEvent(SessionAuditEvent{
+			ID:        generateAuditID(),
+			Timestamp: now,
+			SessionID: id,
+			UserID:    session.UserID,
+			EventType: "terminated",
+			IPAddress: session.IPAddress,
+			UserAgent: session.UserAgent,
+			Duration:  now.Sub(session.CreatedAt),
+			Reason:    reason,
+		})
+	}
+}
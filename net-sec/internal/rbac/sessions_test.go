@@ -0,0 +1,105 @@
+package rbac
+
+import "testing"
+
+func TestRevokeSession(t *testing.T) {
+	ac := newTestController(t, "alice")
+	auditLog := &recordingAccessLog{}
+	ac.auditLog = auditLog
+	if err := ac.AssignRole("alice", "auditor"); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ac.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ac.CheckAccess(session.ID, "audit_logs", "read", nil) {
+		t.Fatal("expected access before revocation")
+	}
+
+	if err := ac.RevokeSession(session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ac.CheckAccess(session.ID, "audit_logs", "read", nil) {
+		t.Error("expected access on a revoked session to fail")
+	}
+	if last := auditLog.attempts[len(auditLog.attempts)-1]; last.DenialReason != "invalid_session" {
+		t.Errorf("expected invalid_session, got %q", last.DenialReason)
+	}
+
+	terminated := auditLog.sessions[len(auditLog.sessions)-1]
+	if terminated.EventType != "terminated" || terminated.SessionID != session.ID || terminated.Reason != "revoked" {
+		t.Errorf("unexpected termination event %+v", terminated)
+	}
+
+	if err := ac.RevokeSession(session.ID); err == nil {
+		t.Error("expected revoking twice to fail")
+	}
+}
+
+func TestRevokeAllUserSessions(t *testing.T) {
+	ac := newTestController(t, "alice", "bob")
+	auditLog := &recordingAuditLog{}
+	ac.auditLog = auditLog
+
+	for _, userID := range []string{"alice", "alice", "bob"} {
+		if _, err := ac.CreateSession(userID, "192.0.2.10", "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revoked, err := ac.RevokeAllUserSessions("alice")
+	if err != nil || revoked != 2 {
+		t.Fatalf("expected 2 sessions revoked, got %d (%v)", revoked, err)
+	}
+	if sessions := ac.ListSessions("alice"); len(sessions) != 0 {
+		t.Errorf("expected no sessions left for alice, got %d", len(sessions))
+	}
+	if sessions := ac.ListSessions(""); len(sessions) != 1 || sessions[0].UserID != "bob" {
+		t.Errorf("expected bob's session to remain, got %+v", sessions)
+	}
+
+	terminated := 0
+	for _, event := range auditLog.sessions {
+		if event.EventType == "terminated" && event.UserID == "alice" && event.Reason == "all_user_sessions_revoked" {
+			terminated++
+		}
+	}
+	if terminated != 2 {
+		t.Errorf("expected 2 termination events, got %d", terminated)
+	}
+
+	if _, err := ac.RevokeAllUserSessions("mallory"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+func TestListSessionsReturnsCopies(t *testing.T) {
+	ac := newTestController(t, "alice")
+
+	session, err := ac.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac.sessions[session.ID].Metadata["device_fingerprint"] = "secret"
+	ac.sessions[session.ID].AccessedResources["audit_logs"] = session.CreatedAt
+
+	sessions := ac.ListSessions("alice")
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+	listed := sessions[0]
+	if listed.Metadata != nil {
+		t.Errorf("expected metadata to be left out, got %v", listed.Metadata)
+	}
+	if _, ok := listed.AccessedResources["audit_logs"]; !ok {
+		t.Errorf("expected accessed resources to be listed, got %v", listed.AccessedResources)
+	}
+
+	listed.AccessedResources["personal_data"] = session.CreatedAt
+	listed.IPAddress = "203.0.113.9"
+	if _, ok := ac.sessions[session.ID].AccessedResources["personal_data"]; ok || ac.sessions[session.ID].IPAddress != "192.0.2.10" {
+		t.Error("expected changes to the copy not to reach the session")
+	}
+}
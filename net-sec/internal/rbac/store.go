@@ -0,0 +1,312 @@
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// Store persists the users, roles and permissions of an AccessController
+// across restarts, including each user's MFA replay and failure state.
+// Sessions and role requests are not stored.
+type Store interface {
+	// Load returns the stored state, empty when nothing has been saved yet
+	Load() (*Snapshot, error)
+	// Save replaces the stored state. The snapshot shares maps and slices
+	// with the controller, so it must not be kept after Save returns.
+	Save(snapshot *Snapshot) error
+}
+
+// Snapshot is the state of an AccessController kept by a Store, each list
+// ordered by ID
+type Snapshot struct {
+	Users       []User       `json:"users"`
+	Roles       []Role       `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// FileStore keeps the snapshot in a JSON file. The file holds the password
+// hashes and MFA secrets that User leaves out of its JSON, so it is written
+// readable by its owner only.
+type FileStore struct {
+	path string
+}
+
+// storedSnapshot is the FileStore file
+type storedSnapshot struct {
+	Users       []storedUser `json:"users"`
+	Roles       []Role       `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// storedUser adds the secrets User does not marshal
+type storedUser struct {
+	User
+	PasswordHash string `json:"password_hash,omitempty"`
+	MFASecret    string `json:"mfa_secret,omitempty"`
+}
+
+// NewFileStore returns a store keeping the snapshot in the file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the snapshot, returning an empty one when the file does not
+// exist yet
+func (s *FileStore) Load() (*Snapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC store: %w", err)
+	}
+
+	var stored storedSnapshot
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse RBAC store: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		Users:       make([]User, 0, len(stored.Users)),
+		Roles:       stored.Roles,
+		Permissions: stored.Permissions,
+	}
+	for _, entry := range stored.Users {
+		user := entry.User
+		user.PasswordHash = entry.PasswordHash
+		user.MFASecret = entry.MFASecret
+		snapshot.Users = append(snapshot.Users, user)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to a temporary file and renames it over the
+// store, so a crash never leaves a partial file
+func (s *FileStore) Save(snapshot *Snapshot) error {
+	stored := storedSnapshot{
+		Users:       make([]storedUser, 0, len(snapshot.Users)),
+		Roles:       snapshot.Roles,
+		Permissions: snapshot.Permissions,
+	}
+	for _, user := range snapshot.Users {
+		stored.Users = append(stored.Users, storedUser{
+			User:         user,
+			PasswordHash: user.PasswordHash,
+			MFASecret:    user.MFASecret,
+		})
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode RBAC store: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create RBAC store directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write RBAC store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		// Flush the contents before the rename makes them the store
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write RBAC store: %w", err)
+	}
+
+	// CreateTemp already restricts the file to its owner
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write RBAC store: %w", err)
+	}
+	return nil
+}
+
+// Reload replaces the users, roles and permissions with those in the
+// configured store, seeds any missing built-ins, and allows saving again
+// after a failed load. Sessions are kept.
+func (ac *AccessController) Reload() error {
+	if ac.config.Store == nil {
+		return fmt.Errorf("no RBAC store configured")
+	}
+
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	if err := ac.load(); err != nil {
+		return err
+	}
+	ac.storeErr = nil
+	return ac.commit(func() error {
+		ac.initializeDefaults()
+		return nil
+	})
+}
+
+// load replaces the users, roles and permissions with the stored ones; the
+// caller holds mutex
+func (ac *AccessController) load() error {
+	snapshot, err := ac.config.Store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load RBAC store: %w", err)
+	}
+
+	users := make(map[string]*User, len(snapshot.Users))
+	for i := range snapshot.Users {
+		users[snapshot.Users[i].ID] = &snapshot.Users[i]
+	}
+	roles := make(map[string]*Role, len(snapshot.Roles))
+	for i := range snapshot.Roles {
+		roles[snapshot.Roles[i].ID] = &snapshot.Roles[i]
+	}
+	permissions := make(map[string]*Permission, len(snapshot.Permissions))
+	for i := range snapshot.Permissions {
+		permissions[snapshot.Permissions[i].ID] = &snapshot.Permissions[i]
+	}
+
+	ac.users, ac.roles, ac.permissions = users, roles, permissions
+	return nil
+}
+
+// save writes the users, roles and permissions to the configured store, if
+// any. Saving is refused after a failed load, so a store that could not be
+// read is not overwritten. The caller holds mutex.
+func (ac *AccessController) save() error {
+	if ac.config.Store == nil {
+		return nil
+	}
+	if ac.storeErr != nil {
+		return fmt.Errorf("RBAC store not loaded: %w", ac.storeErr)
+	}
+
+	snapshot := &Snapshot{
+		Users:       make([]User, 0, len(ac.users)),
+		Roles:       make([]Role, 0, len(ac.roles)),
+		Permissions: make([]Permission, 0, len(ac.permissions)),
+	}
+	for _, user := range ac.users {
+		snapshot.Users = append(snapshot.Users, *user)
+	}
+	for _, role := range ac.roles {
+		snapshot.Roles = append(snapshot.Roles, *role)
+	}
+	for _, perm := range ac.permissions {
+		snapshot.Permissions = append(snapshot.Permissions, *perm)
+	}
+	sort.Slice(snapshot.Users, func(i, j int) bool { return snapshot.Users[i].ID < snapshot.Users[j].ID })
+	sort.Slice(snapshot.Roles, func(i, j int) bool { return snapshot.Roles[i].ID < snapshot.Roles[j].ID })
+	sort.Slice(snapshot.Permissions, func(i, j int) bool { return snapshot.Permissions[i].ID < snapshot.Permissions[j].ID })
+
+	if err := ac.config.Store.Save(snapshot); err != nil {
+		return fmt.Errorf("failed to save RBAC store: %w", err)
+	}
+	return nil
+}
+
+// commit applies mutate to a copy of the users, roles and permissions, saves
+// the copy and only then keeps it, so a change that fails, or that cannot be
+// saved, leaves the controller as it was. mutate sees the copy through
+// ac.users, ac.roles and ac.permissions. The caller holds mutex.
+func (ac *AccessController) commit(mutate func() error) error {
+	if ac.config.Store == nil {
+		return mutate()
+	}
+
+	users, roles, permissions := ac.users, ac.roles, ac.permissions
+	ac.users, ac.roles, ac.permissions = cloneUsers(users), cloneRoles(roles), clonePermissions(permissions)
+
+	err := mutate()
+	if err == nil {
+		err = ac.save()
+	}
+	if err != nil {
+		ac.users, ac.roles, ac.permissions = users, roles, permissions
+	}
+	return err
+}
+
+// cloneUsers deep copies users, so changes to the copy leave them unchanged
+func cloneUsers(users map[string]*User) map[string]*User {
+	clones := make(map[string]*User, len(users))
+	for id, user := range users {
+		clone := *user
+		clone.Roles = slices.Clone(user.Roles)
+		clone.ConsentRecords = slices.Clone(user.ConsentRecords)
+		clone.LastFailedAttempt = cloneTime(user.LastFailedAttempt)
+		clone.LastLogin = cloneTime(user.LastLogin)
+		clone.RoleExpiry = maps.Clone(user.RoleExpiry)
+		clone.Metadata = maps.Clone(user.Metadata)
+		clones[id] = &clone
+	}
+	return clones
+}
+
+// cloneRoles deep copies roles
+func cloneRoles(roles map[string]*Role) map[string]*Role {
+	clones := make(map[string]*Role, len(roles))
+	for id, role := range roles {
+		clone := *role
+		clone.Permissions = slices.Clone(role.Permissions)
+		clone.DataCategories = slices.Clone(role.DataCategories)
+		clone.ProcessingPurposes = slices.Clone(role.ProcessingPurposes)
+		clone.LegalBases = slices.Clone(role.LegalBases)
+		clone.AllowedIPRanges = slices.Clone(role.AllowedIPRanges)
+		if role.TimeRestrictions != nil {
+			restrictions := *role.TimeRestrictions
+			clone.TimeRestrictions = &restrictions
+		}
+		clone.Metadata = maps.Clone(role.Metadata)
+		clones[id] = &clone
+	}
+	return clones
+}
+
+// clonePermissions deep copies permissions
+func clonePermissions(permissions map[string]*Permission) map[string]*Permission {
+	clones := make(map[string]*Permission, len(permissions))
+	for id, perm := range permissions {
+		clone := *perm
+		clone.GDPRImplications = slices.Clone(perm.GDPRImplications)
+		clone.Metadata = maps.Clone(perm.Metadata)
+		clones[id] = &clone
+	}
+	return clones
+}
+
+// cloneTime copies the time t points to
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
+
+// saveOrLog saves to the store and logs a failure, for changes with no
+// caller to return the error to; the caller holds mutex
+func (ac *AccessController) saveOrLog() {
+	if err := ac.save(); err != nil {
+		ac.logger.Error("Failed to save RBAC store", logger.Fields{
+			"event_type": "rbac_store_save_failed",
+			"error":      err.Error(),
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package rbac
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// newStoreController returns a controller backed by the file store at path
+func newStoreController(t *testing.T, path string) *AccessController {
+	t.Helper()
+
+	ac := NewAccessController(&RBACConfig{SessionTimeout: time.Hour, Store: NewFileStore(path)}, nil)
+	ac.SetLogger(logger.New("error", "text", io.Discard).Component("rbac"))
+	return ac
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac", "store.json")
+	ac := newStoreController(t, path)
+
+	consentDate := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := ac.AddUser(&User{
+		ID:            "alice",
+		Email:         "alice@example.com",
+		DataSubjectID: "subject-1",
+		ConsentRecords: []ConsentRecord{
+			{ID: "consent-1", ProcessingPurpose: "marketing", ConsentGiven: true, ConsentDate: consentDate, ConsentMethod: "opt-in"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.SetPassword("alice", "Correct-Horse-42"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ac.EnrollMFA("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.AddRole(&Role{ID: "support", Name: "Support", Permissions: []string{"personal_data_read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.AssignRole("alice", "support"); err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(time.Hour).Round(0)
+	if err := ac.AssignTemporaryRole("alice", "auditor", until); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to built-ins survive the reload rather than being reseeded
+	ac.mutex.Lock()
+	ac.roles["auditor"].MaxSessionDuration = time.Hour
+	ac.mutex.Unlock()
+	if err := ac.RevokeRole("alice", "support"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.AssignRole("alice", "support"); err != nil {
+		t.Fatal(err)
+	}
+	secret := ac.users["alice"].MFASecret
+
+	reloaded := newStoreController(t, path)
+	user, exists := reloaded.users["alice"]
+	if !exists {
+		t.Fatal("expected the user to be loaded")
+	}
+	if len(user.Roles) != 2 || user.Roles[0] != "auditor" || user.Roles[1] != "support" || !user.RoleExpiry["auditor"].Equal(until) {
+		t.Errorf("unexpected role assignments %v, expiry %v", user.Roles, user.RoleExpiry)
+	}
+	if len(user.ConsentRecords) != 1 || !user.ConsentRecords[0].ConsentDate.Equal(consentDate) || !user.ConsentRecords[0].IsActive(time.Now()) {
+		t.Errorf("unexpected consent records %+v", user.ConsentRecords)
+	}
+	if !reloaded.HasActiveConsent("subject-1", "marketing") {
+		t.Error("expected consent to be found through the data subject")
+	}
+	if !user.MFAEnabled || user.MFASecret != secret {
+		t.Error("expected the MFA secret to round-trip")
+	}
+	if _, err := reloaded.Authenticate("alice", "Correct-Horse-42", "192.0.2.10", "test"); err != nil {
+		t.Errorf("expected the password hash to round-trip: %v", err)
+	}
+
+	if role, exists := reloaded.roles["support"]; !exists || role.IsBuiltIn || len(role.Permissions) != 1 {
+		t.Errorf("expected the custom role to be loaded, got %+v", role)
+	}
+	if reloaded.roles["auditor"].MaxSessionDuration != time.Hour {
+		t.Error("expected the stored built-in role to be kept")
+	}
+	if len(reloaded.roles) != len(ac.roles) || len(reloaded.permissions) != len(ac.permissions) {
+		t.Errorf("expected %d roles and %d permissions, got %d and %d", len(ac.roles), len(ac.permissions), len(reloaded.roles), len(reloaded.permissions))
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the store file, got %v", entries)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the store readable by its owner only, got %v (%v)", info.Mode(), err)
+	}
+}
+
+func TestFileStoreFailedLoadIsNotOverwritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ac := newStoreController(t, path)
+	if _, exists := ac.roles["auditor"]; !exists {
+		t.Error("expected the built-ins despite the failed load")
+	}
+	if err := ac.AddUser(&User{ID: "alice"}); err == nil {
+		t.Error("expected saving to be refused after a failed load")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("expected the unreadable store to be left alone, got %q", data)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if err := ac.AddUser(&User{ID: "bob"}); err != nil {
+		t.Errorf("expected saving to work after Reload: %v", err)
+	}
+	if _, exists := newStoreController(t, path).users["bob"]; !exists {
+		t.Error("expected the user to be saved")
+	}
+}
+
+// failingStore is an in-memory Store whose saves fail while fail is set
+type failingStore struct {
+	snapshot *Snapshot
+	fail     bool
+}
+
+func (s *failingStore) Load() (*Snapshot, error) { return &Snapshot{}, nil }
+
+func (s *failingStore) Save(snapshot *Snapshot) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	s.snapshot = snapshot
+	return nil
+}
+
+func TestFailedSaveLeavesStateUnchanged(t *testing.T) {
+	store := &failingStore{}
+	ac := NewAccessController(&RBACConfig{SessionTimeout: time.Hour, Store: store}, nil)
+	ac.SetLogger(logger.New("error", "text", io.Discard).Component("rbac"))
+	if err := ac.AddUser(&User{ID: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, roleID := range []string{"analyst", "reviewer"} {
+		if err := ac.AddRole(&Role{ID: roleID, Permissions: []string{"audit_log_read"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ac.AssignRole("alice", roleID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store.fail = true
+	for name, change := range map[string]func() error{
+		"AddUser":     func() error { return ac.AddUser(&User{ID: "bob"}) },
+		"AddRole":     func() error { return ac.AddRole(&Role{ID: "support"}) },
+		"AssignRole":  func() error { return ac.AssignRole("alice", "auditor") },
+		"RevokeRole":  func() error { return ac.RevokeRole("alice", "analyst") },
+		"SetPassword": func() error { return ac.SetPassword("alice", "Correct-Horse-42") },
+		"EnrollMFA":   func() error { _, err := ac.EnrollMFA("alice"); return err },
+		"AssignTemporaryRole": func() error {
+			return ac.AssignTemporaryRole("alice", "auditor", time.Now().Add(time.Hour))
+		},
+		"RevokeRoleFromUsers": func() error { return ac.RevokeRoleFromUsers([]string{"alice"}, "analyst")["alice"] },
+	} {
+		if err := change(); err == nil || !strings.Contains(err.Error(), "disk full") {
+			t.Errorf("%s: expected the save error, got %v", name, err)
+		}
+	}
+
+	alice := ac.users["alice"]
+	if _, exists := ac.users["bob"]; exists {
+		t.Error("expected the unsaved user to be dropped")
+	}
+	if _, exists := ac.roles["support"]; exists {
+		t.Error("expected the unsaved role to be dropped")
+	}
+	if strings.Join(alice.Roles, ",") != "analyst,reviewer" || len(alice.RoleExpiry) != 0 {
+		t.Errorf("expected the saved roles to be kept, got %v, expiry %v", alice.Roles, alice.RoleExpiry)
+	}
+	if alice.PasswordHash != "" || alice.MFAEnabled || alice.MFASecret != "" {
+		t.Error("expected the unsaved credentials to be dropped")
+	}
+	if len(store.snapshot.Users) != 1 || strings.Join(store.snapshot.Users[0].Roles, ",") != "analyst,reviewer" {
+		t.Errorf("expected the last saved snapshot to be intact, got %+v", store.snapshot.Users)
+	}
+}
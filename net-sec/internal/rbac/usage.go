@@ -0,0 +1,68 @@
+package rbac
+
+import "time"
+
+// PermissionUsageStat compares how widely a permission is granted with how
+// often it is exercised
+type PermissionUsageStat struct {
+	PermissionID string     `json:"permission_id"`
+	Grants       int        `json:"grants"`              // Active users holding the permission through their roles
+	Uses         int64      `json:"uses"`                // Access checks the permission granted
+	LastUsed     *time.Time `json:"last_used,omitempty"` // Nil when never used
+}
+
+// permissionUsage counts the access checks a permission granted
+type permissionUsage struct {
+	uses     int64
+	lastUsed time.Time
+}
+
+// recordPermissionUse counts a granted access check; the caller holds the
+// write lock
+func (ac *AccessController) recordPermissionUse(permissionID string, now time.Time) {
+	usage, exists := ac.usage[permissionID]
+	if !exists {
+		usage = &permissionUsage{}
+		ac.usage[permissionID] = usage
+	}
+	usage.uses++
+	usage.lastUsed = now
+}
+
+// GetPermissionUsage returns the usage of every permission, by permission
+// ID. Permissions granted to many users but rarely used point to
+// over-provisioned roles.
+func (ac *AccessController) GetPermissionUsage() map[string]PermissionUsageStat {
+	ac.mutex.RLock()
+	defer ac.mutex.RUnlock()
+
+	stats := make(map[string]PermissionUsageStat, len(ac.permissions))
+	for id := range ac.permissions {
+		stat := PermissionUsageStat{PermissionID: id}
+		if usage, exists := ac.usage[id]; exists {
+			lastUsed := usage.lastUsed
+			stat.Uses = usage.uses
+			stat.LastUsed = &lastUsed
+		}
+		stats[id] = stat
+	}
+
+	for _, user := range ac.users {
+		if !user.IsActive {
+			continue
+		}
+
+		// A permission reached through several roles is one grant
+		granted := make(map[string]bool)
+		for _, perm := range ac.getUserPermissions(user) {
+			granted[perm.ID] = true
+		}
+		for id := range granted {
+			stat := stats[id]
+			stat.Grants++
+			stats[id] = stat
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,78 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPermissionUsageTracksGrantedChecks(t *testing.T) {
+	ac := newTestController(t, "alice", "bob")
+	now := time.Now()
+	ac.now = func() time.Time { return now }
+
+	ac.roles["support"] = &Role{ID: "support", Permissions: []string{"personal_data_read", "personal_data_write"}}
+	for userID, roles := range map[string][]string{"alice": {"support", "auditor"}, "bob": {"auditor"}} {
+		for _, role := range roles {
+			if err := ac.AssignRole(userID, role); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	alice, err := ac.CreateSession("alice", "192.0.2.10", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := ac.CreateSession("bob", "192.0.2.11", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		session, resource, action string
+		context                   map[string]interface{}
+		want                      bool
+	}{
+		{alice.ID, "audit_logs", "read", map[string]interface{}{}, true},
+		{alice.ID, "audit_logs", "read", map[string]interface{}{}, true},
+		{bob.ID, "audit_logs", "read", map[string]interface{}{}, true},
+		{alice.ID, "personal_data", "read", map[string]interface{}{"justification": "support ticket"}, true},
+		// A denied check does not count as a use
+		{alice.ID, "personal_data", "read", map[string]interface{}{}, false},
+		{bob.ID, "personal_data", "read", map[string]interface{}{"justification": "curious"}, false},
+	}
+	for i, check := range checks {
+		now = now.Add(time.Minute)
+		if got := ac.CheckAccess(check.session, check.resource, check.action, check.context); got != check.want {
+			t.Fatalf("check %d: expected %v, got %v", i, check.want, got)
+		}
+	}
+
+	usage := ac.GetPermissionUsage()
+	if len(usage) != len(ac.permissions) {
+		t.Errorf("expected usage for all %d permissions, got %d", len(ac.permissions), len(usage))
+	}
+
+	expect := map[string]struct {
+		grants int
+		uses   int64
+	}{
+		"audit_log_read":          {grants: 2, uses: 3},
+		"personal_data_read":      {grants: 1, uses: 1},
+		"personal_data_write":     {grants: 1, uses: 0},
+		"pseudonymization_manage": {grants: 0, uses: 0},
+	}
+	for id, want := range expect {
+		stat := usage[id]
+		if stat.PermissionID != id || stat.Grants != want.grants || stat.Uses != want.uses {
+			t.Errorf("%s: expected %d grants and %d uses, got %+v", id, want.grants, want.uses, stat)
+		}
+		if (stat.Uses == 0) != (stat.LastUsed == nil) {
+			t.Errorf("%s: last used %v does not match %d uses", id, stat.LastUsed, stat.Uses)
+		}
+	}
+
+	// The fourth check was the last to use personal_data_read
+	if lastUsed := usage["personal_data_read"].LastUsed; lastUsed == nil || !lastUsed.Equal(now.Add(-2*time.Minute)) {
+		t.Errorf("expected personal_data_read last used two checks ago, got %v", lastUsed)
+	}
+}
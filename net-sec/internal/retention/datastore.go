@@ -0,0 +1,185 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// DataRecord identifies a stored record that retention operations act on
+type DataRecord struct {
+	ID           string    `json:"id"`
+	SubjectID    string    `json:"subject_id"`
+	DataCategory string    `json:"data_category"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DataStore is the storage backend holding the records retention policies
+// apply to. Queries use the same keys as PurgeJob.DataQuery, such as
+// "data_category" and "subject_id".
+type DataStore interface {
+	FindRecords(ctx context.Context, query map[string]interface{}) ([]DataRecord, error)
+	DeleteRecords(ctx context.Context, ids []string) (int, error)
+	AnonymizeRecords(ctx context.Context, ids []string) (int, error)
+}
+
+// SetDataStore sets the datastore the scheduler purges records from
+func (rs *RetentionScheduler) SetDataStore(store DataStore) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.dataStore = store
+}
+
+// DataStore returns the scheduler's datastore, or nil if none is set
+func (rs *RetentionScheduler) DataStore() DataStore {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+	return rs.dataStore
+}
+
+// PolicyForCategory returns the retention policy for a data category, or
+// nil if none is defined
+func (rs *RetentionScheduler) PolicyForCategory(category string) *RetentionPolicy {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	for _, policy := range rs.policies {
+		if policy.DataCategory == category {
+			return policy
+		}
+	}
+	return nil
+}
+
+// ActiveLegalHolds returns the active, unexpired legal holds covering the
+// data selected by dataQuery, sorted by ID. A hold covers the query when any
+// record could match both, as decided by queriesOverlap for purges, so a
+// hold with an empty query covers all data.
+func (rs *RetentionScheduler) ActiveLegalHolds(dataQuery map[string]interface{}) []*LegalHold {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	holds := make([]*LegalHold, 0)
+	for _, hold := range rs.legalHolds {
+		if !hold.IsActive || (hold.ExpiresAt != nil && time.Now().After(*hold.ExpiresAt)) {
+			continue
+		}
+
+		if queriesOverlap(hold.DataQuery, dataQuery) {
+			holds = append(holds, hold)
+		}
+	}
+
+	sort.Slice(holds, func(i, j int) bool { return holds[i].ID < holds[j].ID })
+	return holds
+}
+
+// PurgeRecords removes records from the datastore using the purge method of
+// each record's retention policy. Records under an "anonymize" or
+// "pseudonymize" policy are anonymized; all others are deleted.
+func (rs *RetentionScheduler) PurgeRecords(ctx context.Context, records []DataRecord, userID string) (deleted, anonymized int, err error) {
+	store := rs.DataStore()
+	if store == nil {
+		return 0, 0, fmt.Errorf("no datastore configured")
+	}
+
+	var deleteIDs, anonymizeIDs []string
+	for _, record := range records {
+		if rs.PolicyForCategory(record.DataCategory).anonymizes() {
+			anonymizeIDs = append(anonymizeIDs, record.ID)
+		} else {
+			deleteIDs = append(deleteIDs, record.ID)
+		}
+	}
+
+	defer func() {
+		rs.logPurge(userID, len(records), deleted, anonymized, err)
+	}()
+
+	if len(deleteIDs) > 0 {
+		if deleted, err = store.DeleteRecords(ctx, deleteIDs); err != nil {
+			return deleted, 0, fmt.Errorf("failed to delete records: %w", err)
+		}
+	}
+	if len(anonymizeIDs) > 0 {
+		if anonymized, err = store.AnonymizeRecords(ctx, anonymizeIDs); err != nil {
+			return deleted, anonymized, fmt.Errorf("failed to anonymize records: %w", err)
+		}
+	}
+
+	return deleted, anonymized, nil
+}
+
+// anonymizes reports whether records under the policy are anonymized rather
+// than deleted. A nil policy deletes.
+func (p *RetentionPolicy) anonymizes() bool {
+	return p != nil && (p.PurgeMethod == "anonymize" || p.PurgeMethod == "pseudonymize")
+}
+
+// logPurge records the outcome of PurgeRecords
+func (rs *RetentionScheduler) logPurge(userID string, found, deleted, anonymized int, err error) {
+	fields := logger.Fields{
+		"event_type":         "records_purged",
+		"user_id":            userID,
+		"records_found":      found,
+		"records_deleted":    deleted,
+		"records_anonymized": anonymized,
+	}
+	if err != nil {
+		fields["error"] = err
+		rs.logger.Error("Record purge failed", fields)
+	} else {
+		rs.logger.Info("Records purged", fields)
+	}
+
+	event := RetentionAuditEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		EventType: "records_purged",
+		UserID:    userID,
+		Details: map[string]interface{}{
+			"records_found":      found,
+			"records_deleted":    deleted,
+			"records_anonymized": anonymized,
+		},
+		Success: err == nil,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	rs.emit(event)
+}
+
+// Policies returns copies of the retention policies, sorted by ID
+func (rs *RetentionScheduler) Policies() []RetentionPolicy {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	policies := make([]RetentionPolicy, 0, len(rs.policies))
+	for _, policy := range rs.policies {
+		policies = append(policies, *policy)
+	}
+
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	return policies
+}
+
+// ListActiveLegalHolds returns copies of every active, unexpired legal hold,
+// sorted by ID
+func (rs *RetentionScheduler) ListActiveLegalHolds() []LegalHold {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	holds := make([]LegalHold, 0)
+	for _, hold := range rs.legalHolds {
+		if hold.IsActive && (hold.ExpiresAt == nil || time.Now().Before(*hold.ExpiresAt)) {
+			holds = append(holds, *hold)
+		}
+	}
+
+	sort.Slice(holds, func(i, j int) bool { return holds[i].ID < holds[j].ID })
+	return holds
+}
@@ -0,0 +1,69 @@
+package retention
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// eventBufferSize is the number of retention events buffered for Events
+// consumers before new events are dropped
+const eventBufferSize = 256
+
+// eventTap is a bounded, non-blocking feed of retention events
+type eventTap struct {
+	ch      chan RetentionAuditEvent
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+func newEventTap(size int) *eventTap {
+	return &eventTap{ch: make(chan RetentionAuditEvent, size)}
+}
+
+// send queues event, dropping it when the buffer is full or the tap closed
+func (t *eventTap) send(event RetentionAuditEvent) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return
+	}
+	select {
+	case t.ch <- event:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// close closes the channel; later sends are ignored
+func (t *eventTap) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.ch)
+	}
+}
+
+// Events returns a channel carrying the retention audit events as they are
+// emitted. The buffer is bounded: events are dropped and counted by
+// DroppedEvents while it is full. The channel is closed by Shutdown.
+func (rs *RetentionScheduler) Events() <-chan RetentionAuditEvent {
+	return rs.events.ch
+}
+
+// DroppedEvents returns the number of events dropped because the Events
+// buffer was full
+func (rs *RetentionScheduler) DroppedEvents() uint64 {
+	return rs.events.dropped.Load()
+}
+
+// emit sends event to the audit logger and the Events channel
+func (rs *RetentionScheduler) emit(event RetentionAuditEvent) {
+	if rs.auditLog != nil {
+		rs.auditLog.LogRetentionEvent(event)
+	}
+	rs.events.send(event)
+}
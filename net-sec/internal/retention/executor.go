@@ -0,0 +1,56 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+)
+
+// PurgeExecutor carries out purge jobs against the backend holding the data,
+// selecting records with the same queries as PurgeJob.DataQuery. Unlike a
+// DataStore it purges by query, so backends that can delete or anonymize in
+// place never have to list the affected records.
+type PurgeExecutor interface {
+	// Count returns the number of records matching query
+	Count(ctx context.Context, query map[string]interface{}) (int, error)
+	// Purge removes at most limit of the records matching query with the
+	// policy's purge method ("secure_delete", "anonymize" or "pseudonymize")
+	// and returns the number of records purged, including on error. Purged
+	// records must no longer match query, so repeated calls make progress.
+	Purge(ctx context.Context, query map[string]interface{}, method string, limit int) (int, error)
+}
+
+// SetPurgeExecutor sets the executor purge jobs are run with. It takes
+// precedence over the datastore for purge jobs.
+func (rs *RetentionScheduler) SetPurgeExecutor(executor PurgeExecutor) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.executor = executor
+}
+
+// purgeMethod returns the purge method of the policy, or "secure_delete" for
+// a nil policy or one without a method
+func (p *RetentionPolicy) purgeMethod() string {
+	if p == nil || p.PurgeMethod == "" {
+		return "secure_delete"
+	}
+	return p.PurgeMethod
+}
+
+// executorBatches counts the records matching job.DataQuery and returns a
+// purgeBatch purging them through executor with the method of the job's
+// policy
+func (rs *RetentionScheduler) executorBatches(ctx context.Context, executor PurgeExecutor, job *PurgeJob) (int, purgeBatch, error) {
+	found, err := executor.Count(ctx, job.DataQuery)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	rs.mutex.Lock()
+	method := rs.policies[job.PolicyID].purgeMethod()
+	job.Metadata["purge_method"] = method
+	rs.mutex.Unlock()
+
+	return found, func(ctx context.Context, start, end int) (int, error) {
+		return executor.Purge(ctx, job.DataQuery, method, end-start)
+	}, nil
+}
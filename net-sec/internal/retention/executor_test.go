@@ -0,0 +1,222 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// recordingExecutor is a PurgeExecutor over count matching records recording
+// its calls. With purgeErr set every purge fails after purging purged
+// records; afterPurge runs after each successful purge.
+type recordingExecutor struct {
+	mutex      sync.Mutex
+	count      int
+	purged     int
+	purgeErr   error
+	afterPurge func()
+	calls      []string
+	queries    []map[string]interface{}
+}
+
+func (e *recordingExecutor) Count(ctx context.Context, query map[string]interface{}) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.calls = append(e.calls, fmt.Sprintf("count %v", query["data_category"]))
+	e.queries = append(e.queries, query)
+	return e.count - e.purged, nil
+}
+
+func (e *recordingExecutor) Purge(ctx context.Context, query map[string]interface{}, method string, limit int) (int, error) {
+	e.mutex.Lock()
+	e.calls = append(e.calls, fmt.Sprintf("purge %v %s %d", query["data_category"], method, limit))
+	if e.purgeErr != nil {
+		e.mutex.Unlock()
+		return e.purged, e.purgeErr
+	}
+	purged := e.count - e.purged
+	if purged > limit {
+		purged = limit
+	}
+	e.purged += purged
+	afterPurge := e.afterPurge
+	e.mutex.Unlock()
+
+	if afterPurge != nil {
+		afterPurge()
+	}
+	return purged, nil
+}
+
+func (e *recordingExecutor) recorded() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return strings.Join(e.calls, ", ")
+}
+
+// runExecutorJob runs a purge job for the "behavioral" category under an
+// anonymizing policy and returns the finished job
+func runExecutorJob(t *testing.T, rs *RetentionScheduler, dryRun bool) *PurgeJob {
+	t.Helper()
+
+	policy := &RetentionPolicy{ID: "behavioral", DataCategory: "behavioral", PurgeMethod: "anonymize"}
+	if err := rs.AddRetentionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	job, err := rs.SchedulePurgeJob(policy.ID, map[string]interface{}{"data_category": "behavioral"}, time.Now(), dryRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.executePurgeJob(job)
+
+	finished, err := rs.GetPurgeJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return finished
+}
+
+// runningJobID returns the ID of the purge job rs is running
+func runningJobID(rs *RetentionScheduler) string {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+	for id := range rs.jobCancels {
+		return id
+	}
+	return ""
+}
+
+func newExecutorScheduler(t *testing.T, executor PurgeExecutor) *RetentionScheduler {
+	t.Helper()
+
+	rs := NewRetentionScheduler(nil)
+	t.Cleanup(rs.Shutdown)
+	rs.SetLogger(logger.New("error", "text", io.Discard).Component("retention"))
+	if executor != nil {
+		rs.SetPurgeExecutor(executor)
+	}
+	return rs
+}
+
+func TestPurgeExecutorUsesPolicyMethod(t *testing.T) {
+	executor := &recordingExecutor{count: 40}
+	rs := newExecutorScheduler(t, executor)
+	// The executor takes precedence over the datastore
+	rs.SetDataStore(newBatchingStore("behavioral", 5))
+
+	job := runExecutorJob(t, rs, false)
+	if job.Status != "completed" || job.RecordsFound != 40 || job.RecordsPurged != 40 {
+		t.Errorf("expected 40 of 40 records purged, got %+v", job)
+	}
+	if calls := executor.recorded(); calls != "count behavioral, purge behavioral anonymize 40" {
+		t.Errorf("unexpected executor calls %q", calls)
+	}
+}
+
+func TestPurgeExecutorDryRunOnlyCounts(t *testing.T) {
+	executor := &recordingExecutor{count: 12}
+	rs := newExecutorScheduler(t, executor)
+
+	job := runExecutorJob(t, rs, true)
+	if job.Status != "completed" || job.RecordsFound != 12 || job.RecordsPurged != 0 {
+		t.Errorf("expected a completed dry run finding 12 records, got %+v", job)
+	}
+	if job.Metadata["dry_run_result"] != "would purge 12 records" {
+		t.Errorf("unexpected dry run result %v", job.Metadata["dry_run_result"])
+	}
+	if calls := executor.recorded(); calls != "count behavioral" {
+		t.Errorf("expected only a count, got %q", calls)
+	}
+}
+
+func TestPurgeExecutorErrorFailsJob(t *testing.T) {
+	executor := &recordingExecutor{count: 55, purged: 15, purgeErr: fmt.Errorf("connection reset")}
+	rs := newExecutorScheduler(t, executor)
+
+	job := runExecutorJob(t, rs, false)
+	if job.Status != "failed" || job.ErrorMessage != "failed to purge records: connection reset" {
+		t.Errorf("expected the executor error on a failed job, got %q: %q", job.Status, job.ErrorMessage)
+	}
+	if job.RecordsPurged != 15 {
+		t.Errorf("expected the partial purge to be counted, got %d", job.RecordsPurged)
+	}
+}
+
+func TestPurgeExecutorPurgesInBatches(t *testing.T) {
+	executor := &recordingExecutor{count: 25}
+	rs := newExecutorScheduler(t, executor)
+	rs.batchSize = 10
+
+	// Each batch is counted once the executor returns it
+	var progress []int
+	executor.afterPurge = func() {
+		job, err := rs.GetPurgeJob(runningJobID(rs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		progress = append(progress, job.RecordsPurged)
+	}
+
+	job := runExecutorJob(t, rs, false)
+	if job.Status != "completed" || job.RecordsPurged != 25 {
+		t.Errorf("expected 25 records purged, got %+v", job)
+	}
+	want := "count behavioral, purge behavioral anonymize 10, purge behavioral anonymize 10, purge behavioral anonymize 5"
+	if calls := executor.recorded(); calls != want {
+		t.Errorf("unexpected executor calls %q", calls)
+	}
+	if fmt.Sprint(progress) != "[0 10 20]" {
+		t.Errorf("expected progress after each batch, got %v", progress)
+	}
+}
+
+func TestCancelPurgeJobStopsExecutor(t *testing.T) {
+	executor := &recordingExecutor{count: 25}
+	rs := newExecutorScheduler(t, executor)
+	rs.batchSize = 10
+
+	executor.afterPurge = func() {
+		if err := rs.CancelPurgeJob(runningJobID(rs)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	job := runExecutorJob(t, rs, false)
+	if job.Status != "cancelled" || job.RecordsPurged != 10 {
+		t.Errorf("expected a cancelled job with 10 records purged, got %s with %d", job.Status, job.RecordsPurged)
+	}
+	if calls := executor.recorded(); calls != "count behavioral, purge behavioral anonymize 10" {
+		t.Errorf("expected no purge after cancellation, got %q", calls)
+	}
+}
+
+func TestPurgeExecutorNotCalledUnderLegalHold(t *testing.T) {
+	executor := &recordingExecutor{count: 40}
+	rs := newExecutorScheduler(t, executor)
+	if err := rs.CreateLegalHold(&LegalHold{ID: "hold_1", DataQuery: map[string]interface{}{"data_category": "behavioral"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	job := runExecutorJob(t, rs, false)
+	if job.Status != "cancelled" {
+		t.Errorf("expected the job to be cancelled, got %s", job.Status)
+	}
+	if calls := executor.recorded(); calls != "" {
+		t.Errorf("expected no executor calls, got %q", calls)
+	}
+}
+
+func TestPurgeJobFailsWithoutExecutor(t *testing.T) {
+	rs := newExecutorScheduler(t, nil)
+
+	job := runExecutorJob(t, rs, true)
+	if job.Status != "failed" || job.RecordsFound != 0 {
+		t.Errorf("expected the job to fail without an executor, got %+v", job)
+	}
+}
@@ -0,0 +1,126 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultPurgeBatchSize is the number of records purged per datastore or
+// executor call
+const defaultPurgeBatchSize = 500
+
+// GetPurgeJob returns a copy of the purge job with the given ID. While the
+// job is running RecordsPurged reflects the batches completed so far.
+func (rs *RetentionScheduler) GetPurgeJob(jobID string) (*PurgeJob, error) {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	job, exists := rs.jobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("purge job %s not found", jobID)
+	}
+
+	jobCopy := *job
+	jobCopy.Metadata = make(map[string]interface{}, len(job.Metadata))
+	for key, value := range job.Metadata {
+		jobCopy.Metadata[key] = value
+	}
+	return &jobCopy, nil
+}
+
+// CancelPurgeJob cancels a pending purge job, or stops a running one before
+// its next batch. Records purged before cancellation stay counted in
+// RecordsPurged.
+func (rs *RetentionScheduler) CancelPurgeJob(jobID string) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	job, exists := rs.jobs[jobID]
+	if !exists {
+		return fmt.Errorf("purge job %s not found", jobID)
+	}
+
+	switch job.Status {
+	case "pending":
+		job.Status = "cancelled"
+		job.ErrorMessage = "purge cancelled before it started"
+		completedAt := time.Now()
+		job.CompletedAt = &completedAt
+	case "running":
+		rs.jobCancels[jobID]()
+	default:
+		return fmt.Errorf("purge job %s is already %s", jobID, job.Status)
+	}
+	return nil
+}
+
+// purgeBatch purges the found records start to end of a purge job and
+// returns the number purged, including on error
+type purgeBatch func(ctx context.Context, start, end int) (int, error)
+
+// storeBatches finds the records matching job.DataQuery and returns a
+// purgeBatch deleting or anonymizing them in store by ID
+func (rs *RetentionScheduler) storeBatches(ctx context.Context, store DataStore, job *PurgeJob) (int, purgeBatch, error) {
+	records, err := store.FindRecords(ctx, job.DataQuery)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to find records: %w", err)
+	}
+
+	rs.mutex.RLock()
+	policy := rs.policies[job.PolicyID]
+	rs.mutex.RUnlock()
+
+	purge := store.DeleteRecords
+	if policy.anonymizes() {
+		purge = store.AnonymizeRecords
+	}
+
+	return len(records), func(ctx context.Context, start, end int) (int, error) {
+		ids := make([]string, 0, end-start)
+		for _, record := range records[start:end] {
+			ids = append(ids, record.ID)
+		}
+		return purge(ctx, ids)
+	}, nil
+}
+
+// purgeJobRecords records the number of records found on the job and, unless
+// the job is a dry run, purges them in batches, adding each batch to
+// job.RecordsPurged once it returns. It stops before the next batch when ctx
+// is cancelled.
+func (rs *RetentionScheduler) purgeJobRecords(ctx context.Context, job *PurgeJob, found int, purge purgeBatch) error {
+	rs.mutex.Lock()
+	job.RecordsFound = found
+	if job.DryRun {
+		job.Metadata["dry_run_result"] = fmt.Sprintf("would purge %d records", found)
+	}
+	batchSize := rs.batchSize
+	rs.mutex.Unlock()
+
+	if job.DryRun {
+		return nil
+	}
+
+	for start := 0; start < found; start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + batchSize
+		if end > found {
+			end = found
+		}
+
+		// Partial batches still count so the total matches the backend
+		purged, err := purge(ctx, start, end)
+		rs.mutex.Lock()
+		job.RecordsPurged += purged
+		rs.mutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to purge records: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// batchingStore is a DataStore whose deletions block until the test lets
+// each batch proceed
+type batchingStore struct {
+	mutex   sync.Mutex
+	records map[string]DataRecord
+	batches chan []string
+	proceed chan struct{}
+}
+
+func newBatchingStore(category string, count int) *batchingStore {
+	store := &batchingStore{
+		records: make(map[string]DataRecord),
+		batches: make(chan []string),
+		proceed: make(chan struct{}),
+	}
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("record_%02d", i)
+		store.records[id] = DataRecord{ID: id, DataCategory: category}
+	}
+	return store
+}
+
+func (s *batchingStore) FindRecords(ctx context.Context, query map[string]interface{}) ([]DataRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records := make([]DataRecord, 0, len(s.records))
+	for i := 0; i < len(s.records); i++ {
+		records = append(records, s.records[fmt.Sprintf("record_%02d", i)])
+	}
+	return records, nil
+}
+
+func (s *batchingStore) DeleteRecords(ctx context.Context, ids []string) (int, error) {
+	s.batches <- ids
+	select {
+	case <-s.proceed:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, id := range ids {
+		delete(s.records, id)
+	}
+	return len(ids), nil
+}
+
+func (s *batchingStore) AnonymizeRecords(ctx context.Context, ids []string) (int, error) {
+	return 0, fmt.Errorf("unexpected anonymization")
+}
+
+func (s *batchingStore) remaining() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.records)
+}
+
+// startBatchedPurge schedules a purge of store on a quiet scheduler purging
+// ten records per batch and runs it in the background
+func startBatchedPurge(t *testing.T, store *batchingStore) (*RetentionScheduler, *PurgeJob, <-chan struct{}) {
+	t.Helper()
+
+	rs := NewRetentionScheduler(nil)
+	t.Cleanup(rs.Shutdown)
+	rs.SetLogger(logger.New("error", "text", io.Discard).Component("retention"))
+	rs.SetDataStore(store)
+	rs.batchSize = 10
+
+	policy := &RetentionPolicy{ID: "logs", DataCategory: "log", PurgeMethod: "secure_delete"}
+	if err := rs.AddRetentionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	job, err := rs.SchedulePurgeJob(policy.ID, map[string]interface{}{"data_category": "log"}, time.Now(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rs.executePurgeJob(job)
+	}()
+	return rs, job, done
+}
+
+func expectPurged(t *testing.T, rs *RetentionScheduler, jobID, status string, purged int) {
+	t.Helper()
+
+	job, err := rs.GetPurgeJob(jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != status || job.RecordsPurged != purged {
+		t.Errorf("expected %s job with %d records purged, got %s with %d", status, purged, job.Status, job.RecordsPurged)
+	}
+}
+
+func TestPurgeJobReportsProgress(t *testing.T) {
+	store := newBatchingStore("log", 25)
+	rs, job, done := startBatchedPurge(t, store)
+
+	for i, size := range []int{10, 10, 5} {
+		batch := <-store.batches
+		if len(batch) != size {
+			t.Fatalf("batch %d: expected %d records, got %d", i, size, len(batch))
+		}
+		expectPurged(t, rs, job.ID, "running", 10*i)
+		store.proceed <- struct{}{}
+	}
+	<-done
+
+	expectPurged(t, rs, job.ID, "completed", 25)
+	if got, _ := rs.GetPurgeJob(job.ID); got.RecordsFound != 25 || got.CompletedAt == nil {
+		t.Errorf("expected 25 records found and a completion time, got %+v", got)
+	}
+	if store.remaining() != 0 {
+		t.Errorf("expected every record to be deleted, %d remain", store.remaining())
+	}
+}
+
+func TestCancelPurgeJobStopsDeletion(t *testing.T) {
+	store := newBatchingStore("log", 25)
+	rs, job, done := startBatchedPurge(t, store)
+
+	<-store.batches
+	store.proceed <- struct{}{}
+	<-store.batches
+
+	// The in-flight batch is abandoned and no further batches are issued
+	if err := rs.CancelPurgeJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	expectPurged(t, rs, job.ID, "cancelled", 10)
+	if store.remaining() != 15 {
+		t.Errorf("expected 15 records to remain, got %d", store.remaining())
+	}
+	if err := rs.CancelPurgeJob(job.ID); err == nil {
+		t.Error("expected cancelling a finished job to fail")
+	}
+}
+
+func TestCancelPendingPurgeJob(t *testing.T) {
+	rs := NewRetentionScheduler(nil)
+	defer rs.Shutdown()
+	rs.SetLogger(logger.New("error", "text", io.Discard).Component("retention"))
+	rs.SetDataStore(newBatchingStore("log", 5))
+
+	policy := &RetentionPolicy{ID: "logs", DataCategory: "log"}
+	if err := rs.AddRetentionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	job, err := rs.SchedulePurgeJob(policy.ID, map[string]interface{}{"data_category": "log"}, time.Now(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.CancelPurgeJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cancelled job is never run, so the store is not touched
+	rs.executePurgeJob(job)
+	expectPurged(t, rs, job.ID, "cancelled", 0)
+}
@@ -0,0 +1,191 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+// notificationCohort is the width of the cohorts expiry notifications are
+// sent for, so data entering its notification window is announced once a day
+// rather than every hour
+const notificationCohort = 24 * time.Hour
+
+// Notifier delivers notifications about data approaching the end of its
+// retention period, such as to the data protection officer or data subjects
+type Notifier interface {
+	NotifyRetentionExpiry(ctx context.Context, notification RetentionExpiryNotification) error
+}
+
+// RetentionExpiryNotification announces a cohort of records that entered the
+// notification window of their retention policy
+type RetentionExpiryNotification struct {
+	PolicyID           string    `json:"policy_id"`
+	DataCategory       string    `json:"data_category"`
+	RecordCount        int       `json:"record_count"`
+	CreatedAfter       time.Time `json:"created_after"`        // The cohort's records were created at or after this time
+	CreatedBefore      time.Time `json:"created_before"`       // and before this one
+	ProjectedPurgeDate time.Time `json:"projected_purge_date"` // When the newest records in the cohort expire
+}
+
+// WatermarkStore persists, per policy, the creation time up to which expiry
+// notifications have been sent, so a restarted scheduler does not announce
+// the same cohorts again. It is typically kept in the backend holding the
+// rest of the retention state.
+type WatermarkStore interface {
+	LoadWatermarks(ctx context.Context) (map[string]time.Time, error)
+	SaveWatermark(ctx context.Context, policyID string, until time.Time) error
+}
+
+// SetWatermarkStore sets the store notification watermarks are persisted in
+// and restores the watermarks it holds. A restored watermark never moves an
+// in-memory one backwards.
+func (rs *RetentionScheduler) SetWatermarkStore(store WatermarkStore) error {
+	watermarks, err := store.LoadWatermarks(rs.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load notification watermarks: %w", err)
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.watermarks = store
+	for policyID, until := range watermarks {
+		if until.After(rs.notifiedUntil[policyID]) {
+			rs.notifiedUntil[policyID] = until
+		}
+	}
+	return nil
+}
+
+// SetNotifier sets the notifier expiry notifications are sent to. Records
+// are counted through the purge executor, so notifications are only sent
+// when one is also set.
+func (rs *RetentionScheduler) SetNotifier(notifier Notifier) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.notifier = notifier
+}
+
+// dispatchExpiryNotifications notifies about the records of each policy that
+// entered its notification window since the policy was last notified about.
+// The window opens NotificationDays before expiry, as GetNotificationDate
+// gives; on the first run every record not yet expired but inside the window
+// is announced. A cohort whose notification fails is retried on the next run.
+// Watermarks are saved to the watermark store, if one is set, once their
+// cohort is notified.
+func (rs *RetentionScheduler) dispatchExpiryNotifications(now time.Time) {
+	rs.mutex.RLock()
+	executor, notifier, watermarks := rs.executor, rs.notifier, rs.watermarks
+	policies := make([]RetentionPolicy, 0, len(rs.policies))
+	for _, policy := range rs.policies {
+		if policy.NotificationDays > 0 {
+			policies = append(policies, *policy)
+		}
+	}
+	rs.mutex.RUnlock()
+
+	if executor == nil || notifier == nil {
+		return
+	}
+
+	for i := range policies {
+		policy := &policies[i]
+		window := time.Duration(policy.NotificationDays) * 24 * time.Hour
+
+		// Records created before createdBefore are inside the window
+		createdBefore := now.Add(window - policy.RetentionPeriod).Truncate(notificationCohort)
+		createdAfter := now.Add(-policy.RetentionPeriod)
+
+		rs.mutex.RLock()
+		notified := rs.notifiedUntil[policy.ID]
+		rs.mutex.RUnlock()
+		if notified.After(createdAfter) {
+			createdAfter = notified
+		}
+		if !createdAfter.Before(createdBefore) {
+			continue
+		}
+
+		count, err := executor.Count(rs.ctx, map[string]interface{}{
+			"data_category":  policy.DataCategory,
+			"created_after":  createdAfter,
+			"created_before": createdBefore,
+		})
+		if err != nil {
+			rs.logger.Error("Failed to count records for expiry notification", logger.Fields{
+				"event_type": "notification_failed",
+				"policy_id":  policy.ID,
+				"error":      err.Error(),
+			})
+			continue
+		}
+
+		if count > 0 {
+			notification := RetentionExpiryNotification{
+				PolicyID:           policy.ID,
+				DataCategory:       policy.DataCategory,
+				RecordCount:        count,
+				CreatedAfter:       createdAfter,
+				CreatedBefore:      createdBefore,
+				ProjectedPurgeDate: CalculateRetentionDate(createdBefore, policy),
+			}
+			err = notifier.NotifyRetentionExpiry(rs.ctx, notification)
+			rs.logNotification(notification, err)
+			if err != nil {
+				continue
+			}
+		}
+
+		rs.mutex.Lock()
+		rs.notifiedUntil[policy.ID] = createdBefore
+		rs.mutex.Unlock()
+
+		if watermarks != nil {
+			if err := watermarks.SaveWatermark(rs.ctx, policy.ID, createdBefore); err != nil {
+				rs.logger.Error("Failed to save notification watermark", logger.Fields{
+					"event_type": "notification_failed",
+					"policy_id":  policy.ID,
+					"error":      err.Error(),
+				})
+			}
+		}
+	}
+}
+
+// logNotification records the outcome of an expiry notification
+func (rs *RetentionScheduler) logNotification(notification RetentionExpiryNotification, err error) {
+	fields := logger.Fields{
+		"event_type":           "notification_sent",
+		"policy_id":            notification.PolicyID,
+		"data_category":        notification.DataCategory,
+		"record_count":         notification.RecordCount,
+		"projected_purge_date": notification.ProjectedPurgeDate,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		rs.logger.Error("Expiry notification failed", fields)
+	} else {
+		rs.logger.Info("Expiry notification sent", fields)
+	}
+
+	event := RetentionAuditEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		EventType: "notification_sent",
+		PolicyID:  notification.PolicyID,
+		Details: map[string]interface{}{
+			"data_category":        notification.DataCategory,
+			"record_count":         notification.RecordCount,
+			"created_after":        notification.CreatedAfter,
+			"created_before":       notification.CreatedBefore,
+			"projected_purge_date": notification.ProjectedPurgeDate,
+		},
+		Success: err == nil,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	rs.emit(event)
+}
@@ -0,0 +1,154 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// recordingNotifier is a Notifier recording the notifications it is sent
+type recordingNotifier struct {
+	notifications []RetentionExpiryNotification
+	err           error
+}
+
+func (n *recordingNotifier) NotifyRetentionExpiry(ctx context.Context, notification RetentionExpiryNotification) error {
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
+
+func TestDispatchExpiryNotifications(t *testing.T) {
+	executor := &recordingExecutor{count: 8}
+	rs := newExecutorScheduler(t, executor)
+	notifier := &recordingNotifier{}
+	rs.SetNotifier(notifier)
+	events := rs.Events()
+
+	day := 24 * time.Hour
+	policy := &RetentionPolicy{ID: "logs", DataCategory: "log", RetentionPeriod: 30 * day, NotificationDays: 7}
+	if err := rs.AddRetentionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	<-events
+
+	now := time.Date(2024, 5, 10, 9, 30, 0, 0, time.UTC)
+	rs.dispatchExpiryNotifications(now)
+
+	// The first run announces everything inside the window but not expired
+	createdBefore := time.Date(2024, 4, 17, 0, 0, 0, 0, time.UTC)
+	if len(notifier.notifications) != 1 {
+		t.Fatalf("expected one notification, got %+v", notifier.notifications)
+	}
+	want := RetentionExpiryNotification{
+		PolicyID:           "logs",
+		DataCategory:       "log",
+		RecordCount:        8,
+		CreatedAfter:       now.Add(-30 * day),
+		CreatedBefore:      createdBefore,
+		ProjectedPurgeDate: createdBefore.Add(30 * day),
+	}
+	if notifier.notifications[0] != want {
+		t.Errorf("got %+v, want %+v", notifier.notifications[0], want)
+	}
+	if query := executor.queries[0]; query["data_category"] != "log" || query["created_before"] != createdBefore {
+		t.Errorf("unexpected count query %v", query)
+	}
+	event := <-events
+	if event.EventType != "notification_sent" || event.PolicyID != "logs" || !event.Success || event.Details["record_count"] != 8 {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+
+	// Later the same day the cohort has already been announced
+	rs.dispatchExpiryNotifications(now.Add(5 * time.Hour))
+	if len(executor.queries) != 1 || len(notifier.notifications) != 1 {
+		t.Errorf("expected the cohort not to be notified again, got %+v", notifier.notifications)
+	}
+
+	// The next day only the records newly inside the window are announced
+	rs.dispatchExpiryNotifications(now.Add(day))
+	if len(notifier.notifications) != 2 {
+		t.Fatalf("expected a second notification, got %+v", notifier.notifications)
+	}
+	if next := notifier.notifications[1]; !next.CreatedAfter.Equal(createdBefore) || !next.CreatedBefore.Equal(createdBefore.Add(day)) {
+		t.Errorf("expected the next day's cohort, got %+v", next)
+	}
+}
+
+func TestDispatchExpiryNotificationsRetriesFailures(t *testing.T) {
+	executor := &recordingExecutor{count: 3}
+	rs := newExecutorScheduler(t, executor)
+	notifier := &recordingNotifier{err: fmt.Errorf("smtp unavailable")}
+	rs.SetNotifier(notifier)
+
+	policy := &RetentionPolicy{ID: "logs", DataCategory: "log", RetentionPeriod: 30 * 24 * time.Hour, NotificationDays: 7}
+	if err := rs.AddRetentionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 10, 9, 30, 0, 0, time.UTC)
+	rs.dispatchExpiryNotifications(now)
+	notifier.err = nil
+	rs.dispatchExpiryNotifications(now.Add(time.Hour))
+
+	if len(notifier.notifications) != 2 || !notifier.notifications[1].CreatedBefore.Equal(notifier.notifications[0].CreatedBefore) {
+		t.Errorf("expected the failed notification to be resent, got %+v", notifier.notifications)
+	}
+
+	// An empty cohort is not announced
+	executor.count = 0
+	rs.dispatchExpiryNotifications(now.Add(24 * time.Hour))
+	if len(notifier.notifications) != 2 || len(executor.queries) != 3 {
+		t.Errorf("expected an empty cohort to be counted but not notified, got %+v", notifier.notifications)
+	}
+}
+
+// memoryWatermarks is a WatermarkStore keeping watermarks in a map, standing
+// in for persistent storage shared across scheduler restarts
+type memoryWatermarks map[string]time.Time
+
+func (m memoryWatermarks) LoadWatermarks(ctx context.Context) (map[string]time.Time, error) {
+	watermarks := make(map[string]time.Time, len(m))
+	for policyID, until := range m {
+		watermarks[policyID] = until
+	}
+	return watermarks, nil
+}
+
+func (m memoryWatermarks) SaveWatermark(ctx context.Context, policyID string, until time.Time) error {
+	m[policyID] = until
+	return nil
+}
+
+func TestNotificationWatermarkSurvivesRestart(t *testing.T) {
+	store := memoryWatermarks{}
+	policy := RetentionPolicy{ID: "logs", DataCategory: "log", RetentionPeriod: 30 * 24 * time.Hour, NotificationDays: 7}
+	now := time.Date(2024, 5, 10, 9, 30, 0, 0, time.UTC)
+
+	// dispatch runs one notification pass on a freshly started scheduler
+	dispatch := func(at time.Time) *recordingNotifier {
+		rs := newExecutorScheduler(t, &recordingExecutor{count: 5})
+		notifier := &recordingNotifier{}
+		rs.SetNotifier(notifier)
+		if err := rs.SetWatermarkStore(store); err != nil {
+			t.Fatal(err)
+		}
+		p := policy
+		if err := rs.AddRetentionPolicy(&p); err != nil {
+			t.Fatal(err)
+		}
+		rs.dispatchExpiryNotifications(at)
+		return notifier
+	}
+
+	if first := dispatch(now); len(first.notifications) != 1 {
+		t.Fatalf("expected one notification, got %+v", first.notifications)
+	}
+	if until := store["logs"]; !until.Equal(time.Date(2024, 4, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the watermark to be saved, got %v", until)
+	}
+
+	if restarted := dispatch(now.Add(time.Hour)); len(restarted.notifications) != 0 {
+		t.Errorf("expected no repeat notification after a restart, got %+v", restarted.notifications)
+	}
+}
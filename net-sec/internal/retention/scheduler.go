@@ -3,9 +3,11 @@ package retention
 import (
 	"context"
 	"fmt"
-	"log"
+	"reflect"
 	"sync"
 	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
 )
 
 // RetentionScheduler manages automated data retention and purge operations
@@ -17,6 +19,16 @@ type RetentionScheduler struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	auditLog   AuditLogger
+	logger     logger.StructuredLogger
+	dataStore  DataStore
+	executor   PurgeExecutor
+	notifier   Notifier
+	events     *eventTap
+	jobCancels map[string]context.CancelFunc // Cancels running purge jobs
+	batchSize  int                           // Records purged per datastore or executor call
+
+	notifiedUntil map[string]time.Time // Per policy, the creation time expiry notifications have covered
+	watermarks    WatermarkStore       // Persists notifiedUntil across restarts
 }
 
 // RetentionPolicy defines data retention rules per GDPR Article 5(e)
@@ -30,6 +42,7 @@ type RetentionPolicy struct {
 	SubjectRights    []string      `json:"subject_rights"`    // Rights that apply to this data
 	AutomatedPurge   bool          `json:"automated_purge"`   // Enable automatic purging
 	NotificationDays int           `json:"notification_days"` // Days before expiry to notify
+	Version          int           `json:"version"`           // Incremented each time the policy is replaced
 	CreatedAt        time.Time     `json:"created_at"`
 	UpdatedAt        time.Time     `json:"updated_at"`
 }
@@ -97,6 +110,12 @@ func NewRetentionScheduler(auditLog AuditLogger) *RetentionScheduler {
 		ctx:        ctx,
 		cancel:     cancel,
 		auditLog:   auditLog,
+		logger:     logger.Component("retention"),
+		events:     newEventTap(eventBufferSize),
+		jobCancels: make(map[string]context.CancelFunc),
+		batchSize:  defaultPurgeBatchSize,
+
+		notifiedUntil: make(map[string]time.Time),
 	}
 
 	// Start the scheduler
@@ -105,32 +124,51 @@ func NewRetentionScheduler(auditLog AuditLogger) *RetentionScheduler {
 	return rs
 }
 
-// AddRetentionPolicy adds a new retention policy
+// SetLogger sets the logger used for retention operations. It should be
+// called before any policies are added.
+func (rs *RetentionScheduler) SetLogger(l logger.StructuredLogger) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.logger = l
+}
+
+// AddRetentionPolicy adds a new retention policy, replacing any policy with
+// the same ID as its next version
 func (rs *RetentionScheduler) AddRetentionPolicy(policy *RetentionPolicy) error {
 	rs.mutex.Lock()
 	defer rs.mutex.Unlock()
 
-	policy.CreatedAt = time.Now()
+	createdAt, version := time.Now(), 1
+	if previous, exists := rs.policies[policy.ID]; exists {
+		createdAt, version = previous.CreatedAt, previous.Version+1
+	}
+
+	policy.CreatedAt = createdAt
 	policy.UpdatedAt = time.Now()
+	policy.Version = version
 
 	rs.policies[policy.ID] = policy
 
+	rs.logger.Info("Retention policy added", logger.Fields{
+		"event_type":    "policy_created",
+		"policy_id":     policy.ID,
+		"data_category": policy.DataCategory,
+	})
+
 	// Log audit event
-	if rs.auditLog != nil {
-		event := RetentionAuditEvent{
-			ID:        generateEventID(),
-			Timestamp: time.Now(),
-			EventType: "policy_created",
-			PolicyID:  policy.ID,
-			Details: map[string]interface{}{
-				"data_category":    policy.DataCategory,
-				"retention_period": policy.RetentionPeriod.String(),
-				"legal_basis":      policy.LegalBasis,
-			},
-			Success: true,
-		}
-		rs.auditLog.LogRetentionEvent(event)
-	}
+	rs.emit(RetentionAuditEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		EventType: "policy_created",
+		PolicyID:  policy.ID,
+		Details: map[string]interface{}{
+			"data_category":    policy.DataCategory,
+			"retention_period": policy.RetentionPeriod.String(),
+			"legal_basis":      policy.LegalBasis,
+			"version":          policy.Version,
+		},
+		Success: true,
+	})
 
 	return nil
 }
@@ -158,23 +196,28 @@ func (rs *RetentionScheduler) SchedulePurgeJob(policyID string, dataQuery map[st
 
 	rs.jobs[job.ID] = job
 
+	rs.logger.Info("Purge job scheduled", logger.Fields{
+		"event_type":   "job_scheduled",
+		"job_id":       job.ID,
+		"policy_id":    policyID,
+		"scheduled_at": scheduledAt,
+		"dry_run":      dryRun,
+	})
+
 	// Log audit event
-	if rs.auditLog != nil {
-		event := RetentionAuditEvent{
-			ID:        generateEventID(),
-			Timestamp: time.Now(),
-			EventType: "job_scheduled",
-			PolicyID:  policyID,
-			JobID:     job.ID,
-			Details: map[string]interface{}{
-				"scheduled_at":  scheduledAt,
-				"dry_run":       dryRun,
-				"data_category": policy.DataCategory,
-			},
-			Success: true,
-		}
-		rs.auditLog.LogRetentionEvent(event)
-	}
+	rs.emit(RetentionAuditEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		EventType: "job_scheduled",
+		PolicyID:  policyID,
+		JobID:     job.ID,
+		Details: map[string]interface{}{
+			"scheduled_at":  scheduledAt,
+			"dry_run":       dryRun,
+			"data_category": policy.DataCategory,
+		},
+		Success: true,
+	})
 
 	return job, nil
 }
@@ -190,6 +233,12 @@ func (rs *RetentionScheduler) CreateLegalHold(hold *LegalHold) error {
 
 	rs.legalHolds[hold.ID] = hold
 
+	rs.logger.Info("Legal hold created", logger.Fields{
+		"event_type": "legal_hold_created",
+		"hold_id":    hold.ID,
+		"created_by": hold.CreatedBy,
+	})
+
 	// Log audit event
 	if rs.auditLog != nil {
 		rs.auditLog.LogLegalHold(hold, "created")
@@ -210,6 +259,7 @@ func (rs *RetentionScheduler) scheduler() {
 		case <-ticker.C:
 			rs.processScheduledJobs()
 			rs.scheduleAutomaticPurges()
+			rs.dispatchExpiryNotifications(time.Now())
 		}
 	}
 }
@@ -259,12 +309,29 @@ func (rs *RetentionScheduler) scheduleAutomaticPurges() {
 	}
 }
 
-// executePurgeJob executes a single purge job
+// executePurgeJob executes a single purge job once no legal hold conflicts
+// with it. With a purge executor configured the job is run by the executor;
+// otherwise the datastore's matching records are purged in batches, updating
+// RecordsPurged as each batch completes. Without either the job fails.
 func (rs *RetentionScheduler) executePurgeJob(job *PurgeJob) {
 	rs.mutex.Lock()
+	if job.Status != "pending" {
+		rs.mutex.Unlock()
+		return
+	}
 	job.Status = "running"
+	ctx, cancel := context.WithCancel(rs.ctx)
+	rs.jobCancels[job.ID] = cancel
+	store, executor := rs.dataStore, rs.executor
 	rs.mutex.Unlock()
 
+	defer func() {
+		rs.mutex.Lock()
+		delete(rs.jobCancels, job.ID)
+		rs.mutex.Unlock()
+		cancel()
+	}()
+
 	defer func() {
 		if r := recover(); r != nil {
 			rs.mutex.Lock()
@@ -274,6 +341,13 @@ func (rs *RetentionScheduler) executePurgeJob(job *PurgeJob) {
 			job.CompletedAt = &completedAt
 			rs.mutex.Unlock()
 
+			rs.logger.Error("Purge job failed", logger.Fields{
+				"event_type": "purge_failed",
+				"job_id":     job.ID,
+				"policy_id":  job.PolicyID,
+				"error":      job.ErrorMessage,
+			})
+
 			if rs.auditLog != nil {
 				rs.auditLog.LogPurgeJob(job)
 			}
@@ -289,61 +363,83 @@ func (rs *RetentionScheduler) executePurgeJob(job *PurgeJob) {
 		job.CompletedAt = &completedAt
 		rs.mutex.Unlock()
 
+		rs.logger.Warn("Purge job cancelled due to legal hold", logger.Fields{
+			"event_type": "purge_cancelled",
+			"job_id":     job.ID,
+			"policy_id":  job.PolicyID,
+		})
+
 		if rs.auditLog != nil {
 			rs.auditLog.LogPurgeJob(job)
 		}
 		return
 	}
 
-	// Simulate data identification and purging
-	// In a real implementation, this would:
-	// 1. Query the database with job.DataQuery
-	// 2. Apply the purge method from the policy
-	// 3. Update record counts
-	// 4. Handle errors appropriately
+	var (
+		found int
+		purge purgeBatch
+		err   error
+	)
+	switch {
+	case executor != nil:
+		found, purge, err = rs.executorBatches(ctx, executor, job)
+	case store != nil:
+		found, purge, err = rs.storeBatches(ctx, store, job)
+	default:
+		err = fmt.Errorf("no purge executor or datastore configured")
+	}
+	if err == nil {
+		err = rs.purgeJobRecords(ctx, job, found, purge)
+	}
 
 	rs.mutex.Lock()
-	job.RecordsFound = 1250 // Simulated
-
-	if job.DryRun {
-		job.RecordsPurged = 0
-		job.Status = "completed"
-		job.Metadata["dry_run_result"] = "would purge 1250 records"
-	} else {
-		// Simulate actual purging
-		job.RecordsPurged = job.RecordsFound
+	switch {
+	case err == nil:
 		job.Status = "completed"
+	case ctx.Err() != nil:
+		job.Status = "cancelled"
+		job.ErrorMessage = fmt.Sprintf("purge cancelled after %d of %d records", job.RecordsPurged, job.RecordsFound)
+	default:
+		job.Status = "failed"
+		job.ErrorMessage = err.Error()
 	}
-
 	completedAt := time.Now()
 	job.CompletedAt = &completedAt
 	rs.mutex.Unlock()
 
 	// Log completion
+	rs.emit(RetentionAuditEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		EventType: "purge_completed",
+		PolicyID:  job.PolicyID,
+		JobID:     job.ID,
+		Details: map[string]interface{}{
+			"records_found":  job.RecordsFound,
+			"records_purged": job.RecordsPurged,
+			"dry_run":        job.DryRun,
+			"status":         job.Status,
+		},
+		Success: job.Status == "completed",
+		Error:   job.ErrorMessage,
+	})
 	if rs.auditLog != nil {
-		event := RetentionAuditEvent{
-			ID:        generateEventID(),
-			Timestamp: time.Now(),
-			EventType: "purge_completed",
-			PolicyID:  job.PolicyID,
-			JobID:     job.ID,
-			Details: map[string]interface{}{
-				"records_found":  job.RecordsFound,
-				"records_purged": job.RecordsPurged,
-				"dry_run":        job.DryRun,
-			},
-			Success: job.Status == "completed",
-			Error:   job.ErrorMessage,
-		}
-		rs.auditLog.LogRetentionEvent(event)
 		rs.auditLog.LogPurgeJob(job)
 	}
 
-	log.Printf("Purge job %s completed: %d/%d records processed",
-		job.ID, job.RecordsPurged, job.RecordsFound)
+	rs.logger.Info("Purge job completed", logger.Fields{
+		"event_type":     "purge_completed",
+		"job_id":         job.ID,
+		"policy_id":      job.PolicyID,
+		"records_found":  job.RecordsFound,
+		"records_purged": job.RecordsPurged,
+		"dry_run":        job.DryRun,
+		"status":         job.Status,
+	})
 }
 
-// hasLegalHoldConflict checks if any legal holds prevent purging the specified data
+// hasLegalHoldConflict checks if any legal holds prevent purging the specified
+// data, which is when a hold's query overlaps the data selected by dataQuery
 func (rs *RetentionScheduler) hasLegalHoldConflict(dataQuery map[string]interface{}) bool {
 	rs.mutex.RLock()
 	defer rs.mutex.RUnlock()
@@ -358,20 +454,122 @@ func (rs *RetentionScheduler) hasLegalHoldConflict(dataQuery map[string]interfac
 			continue
 		}
 
-		// Simple conflict detection - in practice this would be more sophisticated
-		// checking overlap between dataQuery and hold.DataQuery
-		if dataCategory, exists := dataQuery["data_category"]; exists {
-			if holdCategory, holdExists := hold.DataQuery["data_category"]; holdExists {
-				if dataCategory == holdCategory {
-					return true
-				}
-			}
+		if queriesOverlap(hold.DataQuery, dataQuery) {
+			return true
 		}
 	}
 
 	return false
 }
 
+// queriesOverlap reports whether any record could match both queries. A key
+// missing from a query leaves it unconstrained, so a hold without a key
+// protects every value of it, and a hold with an empty query protects all
+// data. "created_after" and "created_before" bound the creation time,
+// inclusively and exclusively, and overlap when the combined range is not
+// empty; every other key, such as "data_category" or "user_id", overlaps
+// when the values share one. A value may be a list of alternatives, and a
+// value that cannot be compared is treated as overlapping so that data is
+// never purged from under a hold by mistake.
+func queriesOverlap(a, b map[string]interface{}) bool {
+	var after, before time.Time
+	for _, query := range []map[string]interface{}{a, b} {
+		if bound, ok := queryTime(query["created_after"]); ok && bound.After(after) {
+			after = bound
+		}
+		if bound, ok := queryTime(query["created_before"]); ok && (before.IsZero() || bound.Before(before)) {
+			before = bound
+		}
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return false
+	}
+
+	for key, value := range a {
+		if key == "created_after" || key == "created_before" {
+			continue
+		}
+		other, exists := b[key]
+		if exists && !valuesOverlap(value, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesOverlap reports whether two query values, each a single value or a
+// list of alternatives, share a value. Values are compared by their string
+// form, so a user_id of 123 matches "123". Values that are not scalars, such
+// as maps or structs, always overlap.
+func valuesOverlap(a, b interface{}) bool {
+	aValues, ok := queryValues(a)
+	if !ok {
+		return true
+	}
+	bValues, ok := queryValues(b)
+	if !ok {
+		return true
+	}
+
+	values := make(map[string]bool)
+	for _, value := range aValues {
+		values[fmt.Sprint(value)] = true
+	}
+	for _, value := range bValues {
+		if values[fmt.Sprint(value)] {
+			return true
+		}
+	}
+	return false
+}
+
+// queryValues returns the alternatives in a query value, expanding a slice
+// or array of any element type. It reports false when a value is not a
+// scalar that can be compared by its string form.
+func queryValues(value interface{}) ([]interface{}, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}, isScalar(value)
+	}
+
+	values := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i).Interface()
+		if !isScalar(elem) {
+			return nil, false
+		}
+		values = append(values, elem)
+	}
+	return values, true
+}
+
+// isScalar reports whether value is a boolean, number or string
+func isScalar(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// queryTime returns the time in a date range value, a time.Time or an
+// RFC 3339 string
+func queryTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
 // GetRetentionMetrics returns metrics about retention operations
 func (rs *RetentionScheduler) GetRetentionMetrics() *RetentionMetrics {
 	rs.mutex.RLock()
@@ -418,9 +616,11 @@ type RetentionMetrics struct {
 	ActiveHolds    int `json:"active_holds"`
 }
 
-// Shutdown gracefully shuts down the retention scheduler
+// Shutdown gracefully shuts down the retention scheduler and closes the
+// Events channel
 func (rs *RetentionScheduler) Shutdown() {
 	rs.cancel()
+	rs.events.close()
 }
 
 // Helper functions for ID generation
@@ -0,0 +1,149 @@
+package retention
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/logger"
+)
+
+func TestEventsStreamsSchedulerEvents(t *testing.T) {
+	rs := NewRetentionScheduler(nil)
+	rs.SetLogger(logger.New("error", "text", io.Discard).Component("retention"))
+	rs.SetPurgeExecutor(&recordingExecutor{count: 3})
+	events := rs.Events()
+
+	policy := DefaultPolicies()[0]
+	if err := rs.AddRetentionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	job, err := rs.SchedulePurgeJob(policy.ID, map[string]interface{}{"data_category": policy.DataCategory}, time.Now(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.executePurgeJob(job)
+	rs.Shutdown()
+
+	var got []RetentionAuditEvent
+	for event := range events {
+		got = append(got, event)
+	}
+
+	want := []string{"policy_created", "job_scheduled", "purge_completed"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), got)
+	}
+	for i, event := range got {
+		if event.EventType != want[i] {
+			t.Errorf("event %d: expected %s, got %s", i, want[i], event.EventType)
+		}
+	}
+	if got[1].JobID != job.ID || got[2].JobID != job.ID {
+		t.Errorf("job events do not reference job %s: %+v", job.ID, got[1:])
+	}
+	if dryRun, _ := got[2].Details["dry_run"].(bool); !dryRun || !got[2].Success {
+		t.Errorf("expected a successful dry-run completion, got %+v", got[2])
+	}
+	if rs.DroppedEvents() != 0 {
+		t.Errorf("expected no dropped events, got %d", rs.DroppedEvents())
+	}
+
+	// Shutdown is idempotent and later events are discarded
+	rs.Shutdown()
+	rs.emit(RetentionAuditEvent{EventType: "late"})
+}
+
+func TestEventsDropsWhenBufferFull(t *testing.T) {
+	rs := NewRetentionScheduler(nil)
+	defer rs.Shutdown()
+
+	for i := 0; i < eventBufferSize+3; i++ {
+		rs.emit(RetentionAuditEvent{EventType: "policy_created"})
+	}
+	if dropped := rs.DroppedEvents(); dropped != 3 {
+		t.Errorf("expected 3 dropped events, got %d", dropped)
+	}
+	if buffered := len(rs.Events()); buffered != eventBufferSize {
+		t.Errorf("expected a full buffer of %d events, got %d", eventBufferSize, buffered)
+	}
+}
+
+func TestQueriesOverlap(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		hold    map[string]interface{}
+		purge   map[string]interface{}
+		overlap bool
+	}{
+		{"same category", map[string]interface{}{"data_category": "personal"}, map[string]interface{}{"data_category": "personal"}, true},
+		{"different category", map[string]interface{}{"data_category": "personal"}, map[string]interface{}{"data_category": "log"}, false},
+		{"user in purged category", map[string]interface{}{"user_id": "123"}, map[string]interface{}{"data_category": "personal"}, true},
+		{"user of another category", map[string]interface{}{"user_id": "123", "data_category": "log"}, map[string]interface{}{"data_category": "personal"}, false},
+		{"numeric user id", map[string]interface{}{"user_id": 123}, map[string]interface{}{"user_id": "123"}, true},
+		{"category alternatives", map[string]interface{}{"data_category": []string{"log", "personal"}}, map[string]interface{}{"data_category": "personal"}, true},
+		{"numeric user alternatives", map[string]interface{}{"user_id": []int{7, 123}}, map[string]interface{}{"user_id": "123"}, true},
+		{"other numeric users", map[string]interface{}{"user_id": []int{7}}, map[string]interface{}{"user_id": []int64{123}}, false},
+		{"uncomparable value", map[string]interface{}{"user_id": map[string]interface{}{"id": 7}}, map[string]interface{}{"user_id": "123"}, true},
+		{"uncomparable alternative", map[string]interface{}{"user_id": []interface{}{"7", struct{}{}}}, map[string]interface{}{"user_id": "123"}, true},
+		{"empty hold", map[string]interface{}{}, map[string]interface{}{"data_category": "log"}, true},
+		{"hold after cutoff", map[string]interface{}{"created_after": jun}, map[string]interface{}{"created_before": jan}, false},
+		{"hold before cutoff", map[string]interface{}{"created_before": jun}, map[string]interface{}{"created_before": jan}, true},
+		{"hold range spans cutoff", map[string]interface{}{"created_after": "2023-12-01T00:00:00Z", "created_before": jun}, map[string]interface{}{"created_before": jan}, true},
+		{"hold starts at cutoff", map[string]interface{}{"created_after": jan}, map[string]interface{}{"created_before": jan}, false},
+		{"unparseable date", map[string]interface{}{"created_after": "soon"}, map[string]interface{}{"created_before": jan}, true},
+	}
+
+	for _, tt := range tests {
+		if got := queriesOverlap(tt.hold, tt.purge); got != tt.overlap {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.overlap)
+		}
+		if got := queriesOverlap(tt.purge, tt.hold); got != tt.overlap {
+			t.Errorf("%s (reversed): got %v, want %v", tt.name, got, tt.overlap)
+		}
+	}
+}
+
+func TestLegalHoldConflict(t *testing.T) {
+	rs := NewRetentionScheduler(nil)
+	defer rs.Shutdown()
+	rs.SetLogger(logger.New("error", "text", io.Discard).Component("retention"))
+
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	broadPurge := map[string]interface{}{"data_category": "personal", "created_before": cutoff}
+
+	// Holds on other categories or on newer data leave the purge alone
+	for _, hold := range []*LegalHold{
+		{ID: "hold_logs", DataQuery: map[string]interface{}{"data_category": "log", "user_id": "123"}},
+		{ID: "hold_recent", DataQuery: map[string]interface{}{"data_category": "personal", "created_after": cutoff.Add(time.Hour)}},
+	} {
+		if err := rs.CreateLegalHold(hold); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rs.hasLegalHoldConflict(broadPurge) {
+		t.Error("expected disjoint holds not to conflict")
+	}
+
+	// A hold on one user protects their records from a category purge
+	if err := rs.CreateLegalHold(&LegalHold{ID: "hold_user", DataQuery: map[string]interface{}{"user_id": "123"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !rs.hasLegalHoldConflict(broadPurge) {
+		t.Error("expected the narrow hold to conflict with the broad purge")
+	}
+	if rs.hasLegalHoldConflict(map[string]interface{}{"data_category": "personal", "user_id": "456", "created_before": cutoff}) {
+		t.Error("expected a purge of another user not to conflict")
+	}
+
+	// A hold listing users as a []int protects each of them
+	if err := rs.CreateLegalHold(&LegalHold{ID: "hold_int_users", DataQuery: map[string]interface{}{"user_id": []int{456, 789}}}); err != nil {
+		t.Fatal(err)
+	}
+	if !rs.hasLegalHoldConflict(map[string]interface{}{"data_category": "personal", "user_id": "456", "created_before": cutoff}) {
+		t.Error("expected a []int hold to conflict with a purge of a listed user")
+	}
+}
@@ -0,0 +1,362 @@
+package sar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/audit"
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// redactedValue replaces other data subjects' personal data in reports
+const redactedValue = "[REDACTED]"
+
+// Source names used in Report.SourceErrors besides integration names
+const (
+	SourceAudit     = "audit"
+	SourceDataStore = "datastore"
+)
+
+// AuditLogger records processed subject access requests
+type AuditLogger interface {
+	LogSubjectAccessRequest(event audit.SubjectAccessEvent)
+}
+
+// SubjectAccessRequest compiles everything the system holds about a data
+// subject for a right of access request (GDPR Article 15)
+type SubjectAccessRequest struct {
+	access       *rbac.AccessController
+	retention    *retention.RetentionScheduler
+	auditReader  *audit.AuditReader
+	integrations *integrations.IntegrationManager
+	auditLog     AuditLogger
+	now          func() time.Time
+	logger       logger.StructuredLogger
+}
+
+// Report is the outcome of a subject access request
+type Report struct {
+	ID                 string                 `json:"id"`
+	SubjectID          string                 `json:"subject_id"`
+	RequestedBy        string                 `json:"requested_by"`
+	GeneratedAt        time.Time              `json:"generated_at"`
+	ConsentRecords     []rbac.ConsentRecord   `json:"consent_records"`
+	RetentionPolicies  []PolicySummary        `json:"retention_policies"`
+	AuditEvents        []AuditEntry           `json:"audit_events"`
+	IntegrationRecords []IntegrationRecord    `json:"integration_records"`
+	StoredRecords      []retention.DataRecord `json:"stored_records"`
+	SourceErrors       map[string]string      `json:"source_errors,omitempty"` // Sources that could not be searched
+}
+
+// PolicySummary describes a retention policy applying to the subject's data
+type PolicySummary struct {
+	ID              string   `json:"id"`
+	DataCategory    string   `json:"data_category"`
+	RetentionPeriod string   `json:"retention_period"`
+	PurgeMethod     string   `json:"purge_method"`
+	LegalBasis      string   `json:"legal_basis"`
+	SubjectRights   []string `json:"subject_rights"`
+}
+
+// AuditEntry is an audit event referencing the subject. The wrapped event
+// is left out since it may describe other subjects.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Category  string    `json:"category"`
+	Type      string    `json:"type"`
+	UserID    string    `json:"user_id,omitempty"`
+	Success   bool      `json:"success"`
+}
+
+// IntegrationRecord is a record held about the subject by an integration
+type IntegrationRecord struct {
+	Source         string                 `json:"source"`
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type"`
+	Classification string                 `json:"classification,omitempty"`
+	Data           map[string]interface{} `json:"data"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// NewSubjectAccessRequest creates a SAR service over the given sources. Any
+// source may be nil to leave it out of reports.
+func NewSubjectAccessRequest(access *rbac.AccessController, scheduler *retention.RetentionScheduler, reader *audit.AuditReader, manager *integrations.IntegrationManager, auditLog AuditLogger) *SubjectAccessRequest {
+	return &SubjectAccessRequest{
+		access:       access,
+		retention:    scheduler,
+		auditReader:  reader,
+		integrations: manager,
+		auditLog:     auditLog,
+		now:          time.Now,
+		logger:       logger.Component("sar"),
+	}
+}
+
+// SetLogger sets the logger used for subject access requests
+func (s *SubjectAccessRequest) SetLogger(l logger.StructuredLogger) {
+	s.logger = l
+}
+
+// Process compiles the report for subjectID and records that the request
+// was processed. Sources that fail are listed in Report.SourceErrors rather
+// than failing the request.
+func (s *SubjectAccessRequest) Process(ctx context.Context, subjectID, requestedBy string) (*Report, error) {
+	if subjectID == "" {
+		return nil, fmt.Errorf("data subject ID required for access request")
+	}
+
+	report := &Report{
+		ID:                 fmt.Sprintf("sar_%d", time.Now().UnixNano()),
+		SubjectID:          subjectID,
+		RequestedBy:        requestedBy,
+		GeneratedAt:        s.now().UTC(),
+		ConsentRecords:     make([]rbac.ConsentRecord, 0),
+		RetentionPolicies:  make([]PolicySummary, 0),
+		AuditEvents:        make([]AuditEntry, 0),
+		IntegrationRecords: make([]IntegrationRecord, 0),
+		StoredRecords:      make([]retention.DataRecord, 0),
+		SourceErrors:       make(map[string]string),
+	}
+	categories := make(map[string]bool)
+
+	if s.access != nil {
+		report.ConsentRecords = s.access.ConsentRecords(subjectID)
+		for _, consent := range report.ConsentRecords {
+			categories[consent.DataCategory] = true
+		}
+	}
+
+	// Read the audit log before the lookups below add to it
+	if s.auditReader != nil {
+		page, err := s.auditReader.Query(audit.AuditFilter{SubjectID: subjectID})
+		if err != nil {
+			report.SourceErrors[SourceAudit] = err.Error()
+		} else {
+			for _, record := range page.Records {
+				report.AuditEvents = append(report.AuditEvents, AuditEntry{
+					Timestamp: record.Timestamp,
+					Category:  record.Category,
+					Type:      record.Type,
+					UserID:    record.UserID,
+					Success:   record.Success,
+				})
+			}
+		}
+	}
+
+	if s.retention != nil {
+		if store := s.retention.DataStore(); store != nil {
+			records, err := store.FindRecords(ctx, map[string]interface{}{"subject_id": subjectID})
+			if err != nil {
+				report.SourceErrors[SourceDataStore] = err.Error()
+			}
+			for _, record := range records {
+				report.StoredRecords = append(report.StoredRecords, record)
+				categories[record.DataCategory] = true
+			}
+		}
+	}
+
+	if s.integrations != nil {
+		found, errs := s.integrations.FindDataSubject(ctx, subjectID, requestedBy, "Article 15")
+		for name, err := range errs {
+			report.SourceErrors[name] = err.Error()
+		}
+
+		names := make([]string, 0, len(found))
+		for name := range found {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			for _, data := range found[name] {
+				report.IntegrationRecords = append(report.IntegrationRecords, integrationRecord(name, subjectID, data))
+				for _, field := range data.PersonalData {
+					if field.DataSubjectID == "" || field.DataSubjectID == subjectID {
+						categories[field.DataCategory] = true
+					}
+				}
+			}
+		}
+	}
+
+	if s.retention != nil {
+		report.RetentionPolicies = s.policies(categories)
+	}
+
+	s.recordProcessed(report)
+	return report, nil
+}
+
+// policies returns the retention policies for the given data categories
+func (s *SubjectAccessRequest) policies(categories map[string]bool) []PolicySummary {
+	summaries := make([]PolicySummary, 0, len(categories))
+	for category := range categories {
+		policy := s.retention.PolicyForCategory(category)
+		if policy == nil {
+			continue
+		}
+		summaries = append(summaries, PolicySummary{
+			ID:              policy.ID,
+			DataCategory:    policy.DataCategory,
+			RetentionPeriod: formatPeriod(policy.RetentionPeriod),
+			PurgeMethod:     policy.PurgeMethod,
+			LegalBasis:      policy.LegalBasis,
+			SubjectRights:   policy.SubjectRights,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+	return summaries
+}
+
+// recordProcessed logs and audits the processed request
+func (s *SubjectAccessRequest) recordProcessed(report *Report) {
+	s.logger.Info("Subject access request processed", logger.Fields{
+		"event_type":          "subject_access_request",
+		"sar_id":              report.ID,
+		"subject_id":          report.SubjectID,
+		"requested_by":        report.RequestedBy,
+		"integration_records": len(report.IntegrationRecords),
+		"source_errors":       len(report.SourceErrors),
+	})
+
+	if s.auditLog != nil {
+		event := audit.SubjectAccessEvent{
+			ID:                 report.ID,
+			Timestamp:          report.GeneratedAt,
+			SubjectID:          report.SubjectID,
+			RequestedBy:        report.RequestedBy,
+			ConsentRecords:     len(report.ConsentRecords),
+			RetentionPolicies:  len(report.RetentionPolicies),
+			AuditEvents:        len(report.AuditEvents),
+			IntegrationRecords: len(report.IntegrationRecords),
+			StoredRecords:      len(report.StoredRecords),
+		}
+		if len(report.SourceErrors) > 0 {
+			event.SourceErrors = report.SourceErrors
+		}
+		s.auditLog.LogSubjectAccessRequest(event)
+	}
+}
+
+// integrationRecord copies an integration record for the report, redacting
+// personal data belonging to other subjects
+func integrationRecord(source, subjectID string, data *integrations.IntegrationData) IntegrationRecord {
+	record := IntegrationRecord{
+		Source:         source,
+		ID:             data.ID,
+		Type:           data.Type,
+		Classification: data.Classification,
+		Data:           make(map[string]interface{}, len(data.Content)),
+		CreatedAt:      data.CreatedAt,
+	}
+	for key, value := range data.Content {
+		record.Data[key] = value
+	}
+
+	for _, field := range data.PersonalData {
+		if field.DataSubjectID != "" && field.DataSubjectID != subjectID {
+			if _, ok := record.Data[field.Field]; ok {
+				record.Data[field.Field] = redactedValue
+			}
+		}
+	}
+	return record
+}
+
+// JSON returns the report as indented JSON
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Text returns the report in human-readable form
+func (r *Report) Text() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Subject access report for %s\n", r.SubjectID)
+	fmt.Fprintf(&b, "Generated %s by %s (request %s)\n", r.GeneratedAt.Format(time.RFC3339), r.RequestedBy, r.ID)
+
+	fmt.Fprintf(&b, "\nConsent records (%d)\n", len(r.ConsentRecords))
+	for _, consent := range r.ConsentRecords {
+		status := "active"
+		if !consent.IsActive(r.GeneratedAt) {
+			status = "inactive"
+		}
+		fmt.Fprintf(&b, "  - %s for %s: %s, given %s via %s\n", consent.DataCategory, consent.ProcessingPurpose,
+			status, consent.ConsentDate.Format("2006-01-02"), consent.ConsentMethod)
+	}
+
+	fmt.Fprintf(&b, "\nRetention policies (%d)\n", len(r.RetentionPolicies))
+	for _, policy := range r.RetentionPolicies {
+		fmt.Fprintf(&b, "  - %s data is kept %s, then %s (%s)\n", policy.DataCategory, policy.RetentionPeriod,
+			policy.PurgeMethod, policy.LegalBasis)
+	}
+
+	fmt.Fprintf(&b, "\nAudit events (%d)\n", len(r.AuditEvents))
+	for _, event := range r.AuditEvents {
+		outcome := "succeeded"
+		if !event.Success {
+			outcome = "failed"
+		}
+		fmt.Fprintf(&b, "  - %s %s/%s by %s, %s\n", event.Timestamp.Format(time.RFC3339), event.Category,
+			event.Type, valueOr(event.UserID, "system"), outcome)
+	}
+
+	fmt.Fprintf(&b, "\nIntegration records (%d)\n", len(r.IntegrationRecords))
+	for _, record := range r.IntegrationRecords {
+		fmt.Fprintf(&b, "  - %s %s (%s)\n", record.Source, record.ID, record.Type)
+		fields := make([]string, 0, len(record.Data))
+		for field := range record.Data {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fmt.Fprintf(&b, "      %s: %v\n", field, record.Data[field])
+		}
+	}
+
+	fmt.Fprintf(&b, "\nStored records (%d)\n", len(r.StoredRecords))
+	for _, record := range r.StoredRecords {
+		fmt.Fprintf(&b, "  - %s (%s), created %s\n", record.ID, record.DataCategory, record.CreatedAt.Format("2006-01-02"))
+	}
+
+	if len(r.SourceErrors) > 0 {
+		sources := make([]string, 0, len(r.SourceErrors))
+		for source := range r.SourceErrors {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+
+		fmt.Fprintf(&b, "\nSources that could not be searched (%d)\n", len(sources))
+		for _, source := range sources {
+			fmt.Fprintf(&b, "  - %s: %s\n", source, r.SourceErrors[source])
+		}
+	}
+
+	return b.String()
+}
+
+// formatPeriod formats a retention period in days
+func formatPeriod(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
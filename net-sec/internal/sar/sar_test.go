@@ -0,0 +1,202 @@
+package sar
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stealthguard/net-sec/internal/audit"
+	"github.com/stealthguard/net-sec/internal/integrations"
+	"github.com/stealthguard/net-sec/internal/logger"
+	"github.com/stealthguard/net-sec/internal/privacy"
+	"github.com/stealthguard/net-sec/internal/rbac"
+	"github.com/stealthguard/net-sec/internal/retention"
+)
+
+// mockIntegration returns fixed records for each data subject
+type mockIntegration struct {
+	name     string
+	subjects map[string][]*integrations.IntegrationData
+}
+
+func (m *mockIntegration) Name() string                                     { return m.name }
+func (m *mockIntegration) Authenticate(credentials map[string]string) error { return nil }
+func (m *mockIntegration) SendData(ctx context.Context, data *integrations.IntegrationData) error {
+	return nil
+}
+func (m *mockIntegration) RetrieveData(ctx context.Context, query *integrations.DataQuery) (*integrations.IntegrationData, error) {
+	return nil, nil
+}
+func (m *mockIntegration) UpdateData(ctx context.Context, id string, changes map[string]interface{}) error {
+	return nil
+}
+func (m *mockIntegration) ValidateConnection() error                    { return nil }
+func (m *mockIntegration) GetMetrics() *integrations.IntegrationMetrics { return nil }
+
+func (m *mockIntegration) FindSubjectData(ctx context.Context, subjectID string) ([]*integrations.IntegrationData, error) {
+	return m.subjects[subjectID], nil
+}
+
+func TestProcessSubjectAccessRequest(t *testing.T) {
+	discard := logger.New("error", "text", io.Discard)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := audit.NewFileAuditLogger(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// Audit history: three events about the subject, two about someone else
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	events := []privacy.DataAccessEvent{
+		{ID: "e1", Timestamp: at, UserID: "support", DataSubject: "subject_1", Success: true},
+		{ID: "e2", Timestamp: at.Add(time.Hour), UserID: "support", DataSubject: "subject_2", Success: true},
+		{ID: "e3", Timestamp: at.Add(2 * time.Hour), UserID: "analyst", DataSubject: "subject_1", Success: false},
+	}
+	for _, event := range events {
+		if err := sink.LogDataAccess(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a1", Timestamp: at.Add(3 * time.Hour), UserID: "dpo", Resource: "personal_data", DataSubjectID: "subject_1", Success: true})
+	sink.LogAccessAttempt(rbac.AccessAuditEvent{ID: "a2", Timestamp: at.Add(4 * time.Hour), UserID: "dpo", Resource: "personal_data", DataSubjectID: "subject_2", Success: true})
+
+	access := rbac.NewAccessController(&rbac.RBACConfig{SessionTimeout: time.Hour}, nil)
+	access.SetLogger(discard.Component("rbac"))
+	err = access.AddUser(&rbac.User{
+		ID:            "user_1",
+		DataSubjectID: "subject_1",
+		ConsentRecords: []rbac.ConsentRecord{
+			{ID: "c1", DataCategory: "personal", ProcessingPurpose: "support", ConsentGiven: true, ConsentDate: at, ConsentMethod: "explicit"},
+			{ID: "c2", DataCategory: "marketing", ProcessingPurpose: "newsletter", ConsentGiven: true, ConsentDate: at, ConsentMethod: "opt-in", WithdrawnAt: &at},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := access.AddUser(&rbac.User{ID: "user_2", DataSubjectID: "subject_2", ConsentRecords: []rbac.ConsentRecord{{ID: "c3", DataCategory: "log"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := retention.NewRetentionScheduler(nil)
+	defer scheduler.Shutdown()
+	scheduler.SetLogger(discard.Component("retention"))
+	for _, policy := range retention.DefaultPolicies() {
+		if err := scheduler.AddRetentionPolicy(policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := integrations.NewIntegrationManager(&integrations.IntegrationConfig{}, sink, nil)
+	crm := &mockIntegration{name: "crm", subjects: map[string][]*integrations.IntegrationData{
+		"subject_1": {{
+			ID:           "contact-1",
+			Type:         "contact",
+			Content:      map[string]interface{}{"email": "alice@example.com", "plan": "pro"},
+			PersonalData: []integrations.PersonalDataField{{Field: "email", DataCategory: "personal"}},
+			CreatedAt:    at,
+		}},
+	}}
+	helpdesk := &mockIntegration{name: "helpdesk", subjects: map[string][]*integrations.IntegrationData{
+		"subject_1": {{
+			ID:      "ticket-7",
+			Type:    "ticket",
+			Content: map[string]interface{}{"subject": "Refund", "diagnosis": "n/a", "assignee": "bob@example.com"},
+			PersonalData: []integrations.PersonalDataField{
+				{Field: "diagnosis", DataCategory: "sensitive"},
+				{Field: "assignee", DataCategory: "personal", DataSubjectID: "subject_2"},
+			},
+			CreatedAt: at,
+		}},
+	}}
+	for _, integration := range []integrations.Integration{crm, helpdesk} {
+		if err := manager.RegisterIntegration(integration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewSubjectAccessRequest(access, scheduler, audit.NewAuditReader(path), manager, sink)
+	service.SetLogger(discard.Component("sar"))
+
+	report, err := service.Process(context.Background(), "subject_1", "dpo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.SourceErrors) != 0 {
+		t.Errorf("unexpected source errors: %v", report.SourceErrors)
+	}
+	if len(report.ConsentRecords) != 2 || report.ConsentRecords[0].ID != "c1" || report.ConsentRecords[1].ID != "c2" {
+		t.Errorf("expected the subject's two consent records, got %+v", report.ConsentRecords)
+	}
+
+	// Consents and integration data cover personal, marketing and sensitive data
+	var policyIDs []string
+	for _, policy := range report.RetentionPolicies {
+		policyIDs = append(policyIDs, policy.ID)
+	}
+	if got := strings.Join(policyIDs, ","); got != "marketing-data-standard,personal-data-standard,sensitive-data-standard" {
+		t.Errorf("unexpected retention policies %s", got)
+	}
+
+	if len(report.AuditEvents) != 3 {
+		t.Fatalf("expected the subject's three audit events, got %+v", report.AuditEvents)
+	}
+	if report.AuditEvents[0].UserID != "support" || report.AuditEvents[1].UserID != "analyst" || report.AuditEvents[1].Success ||
+		report.AuditEvents[2].Category != audit.CategoryRBAC || report.AuditEvents[2].UserID != "dpo" {
+		t.Errorf("unexpected audit events: %+v", report.AuditEvents)
+	}
+
+	if len(report.IntegrationRecords) != 2 {
+		t.Fatalf("expected a record per integration, got %+v", report.IntegrationRecords)
+	}
+	if crmRecord := report.IntegrationRecords[0]; crmRecord.Source != "crm" || crmRecord.Data["email"] != "alice@example.com" {
+		t.Errorf("unexpected crm record: %+v", crmRecord)
+	}
+	if assignee := report.IntegrationRecords[1].Data["assignee"]; assignee != redactedValue {
+		t.Errorf("other subject's data should be redacted, got %v", assignee)
+	}
+
+	text := report.Text()
+	for _, want := range []string{"Subject access report for subject_1", "Consent records (2)", "personal data is kept 730 days",
+		"privacy/data_access by analyst, failed", "helpdesk ticket-7 (ticket)", "assignee: [REDACTED]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text report is missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "bob@example.com") {
+		t.Error("text report discloses another subject's data")
+	}
+
+	payload, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatalf("invalid JSON payload: %v", err)
+	}
+	if decoded.SubjectID != "subject_1" || len(decoded.IntegrationRecords) != 2 || len(decoded.AuditEvents) != 3 {
+		t.Errorf("JSON payload is incomplete: %s", payload)
+	}
+
+	// The request itself is recorded in the audit log
+	page, err := audit.NewAuditReader(path).Query(audit.AuditFilter{Category: audit.CategorySubjectAccess})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Records[0].SubjectID != "subject_1" || page.Records[0].UserID != "dpo" {
+		t.Fatalf("expected one processed SAR record, got %+v", page.Records)
+	}
+	decodedEvent, err := page.Records[0].DecodeEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sarEvent := decodedEvent.(*audit.SubjectAccessEvent); sarEvent.IntegrationRecords != 2 || sarEvent.AuditEvents != 3 {
+		t.Errorf("unexpected SAR audit event: %+v", sarEvent)
+	}
+}
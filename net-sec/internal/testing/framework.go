@@ -3,8 +3,10 @@ package testing
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -15,6 +17,7 @@ type TestFramework struct {
 	results     *TestResults
 	httpServer  *httptest.Server
 	mockServers map[string]*MockServer
+	suites      []TestSuite
 	mu          sync.RWMutex
 }
 
@@ -26,9 +29,13 @@ type TestConfig struct {
 	DNSTests             bool
 	IntegrationTests     bool
 	LoadTests            bool
-	Timeout              time.Duration
+	Timeout              time.Duration // Timeout for test cases that set none; 0 uses defaultTestTimeout
 	ConcurrentTests      int
 	MockServerPort       int
+	LoadRequestRate      int              // Target requests per second for load tests
+	LoadDuration         time.Duration    // Duration of the load test run
+	LoadRampUp           time.Duration    // Time to ramp up to LoadRequestRate
+	MockServer           MockServerConfig // Fault injection applied to the built-in mock servers
 }
 
 // TestResults contains comprehensive test results
@@ -47,12 +54,13 @@ type TestResults struct {
 
 // TestSuiteResult contains results for a test suite
 type TestSuiteResult struct {
-	Name     string        `json:"name"`
-	Tests    []TestResult  `json:"tests"`
-	Duration time.Duration `json:"duration"`
-	Status   TestStatus    `json:"status"`
-	Setup    *TestResult   `json:"setup,omitempty"`
-	Teardown *TestResult   `json:"teardown,omitempty"`
+	Name     string          `json:"name"`
+	Tests    []TestResult    `json:"tests"`
+	Duration time.Duration   `json:"duration"`
+	Status   TestStatus      `json:"status"`
+	Setup    *TestResult     `json:"setup,omitempty"`
+	Teardown *TestResult     `json:"teardown,omitempty"`
+	LoadTest *LoadTestResult `json:"load_test,omitempty"`
 }
 
 // TestResult contains individual test results
@@ -123,6 +131,7 @@ type MockServer struct {
 	Handlers map[string]http.HandlerFunc
 	Requests []MockRequest
 	Config   MockServerConfig
+	rand     *rand.Rand
 	mu       sync.RWMutex
 }
 
@@ -143,6 +152,10 @@ type MockServerConfig struct {
 	CustomResponses map[string]string `json:"custom_responses"`
 }
 
+// defaultTestTimeout bounds test cases when neither the case nor the config
+// sets a timeout
+const defaultTestTimeout = 30 * time.Second
+
 // NewTestFramework creates a new test framework instance
 func NewTestFramework() *TestFramework {
 	return &TestFramework{
@@ -160,234 +173,110 @@ func (tf *TestFramework) Initialize(config *TestConfig) error {
 	// Initialize results structure
 	tf.results.TestSuites = make([]TestSuiteResult, 0)
 
+	// Register the built-in suites enabled by the configuration
+	tf.suites = nil
+	tf.registerDefaultTests()
+
 	return nil
 }
 
-// RunAllTests executes all configured test suites
-func (tf *TestFramework) RunAllTests(ctx context.Context) (*TestResults, error) {
-	defer tf.finalize()
+// TestSuite is a named, ordered group of test cases
+type TestSuite struct {
+	Name  string
+	Tests []TestCase
+}
 
-	// Run test suites based on configuration
-	if tf.config.CaptivePortalTests {
-		if err := tf.runCaptivePortalTests(ctx); err != nil {
-			return nil, fmt.Errorf("captive portal tests failed: %w", err)
-		}
-	}
+// RegisterTest adds a test case to the named suite, creating the suite if
+// needed. Suites run in the order they were first registered.
+func (tf *TestFramework) RegisterTest(suiteName string, testCase TestCase) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
 
-	if tf.config.NetworkFailoverTests {
-		if err := tf.runNetworkFailoverTests(ctx); err != nil {
-			return nil, fmt.Errorf("network failover tests failed: %w", err)
+	for i := range tf.suites {
+		if tf.suites[i].Name == suiteName {
+			tf.suites[i].Tests = append(tf.suites[i].Tests, testCase)
+			return
 		}
 	}
+	tf.suites = append(tf.suites, TestSuite{Name: suiteName, Tests: []TestCase{testCase}})
+}
 
-	if tf.config.WireGuardTests {
-		if err := tf.runWireGuardTests(ctx); err != nil {
-			return nil, fmt.Errorf("wireguard tests failed: %w", err)
-		}
+// RunAllTests executes all registered test suites. Up to ConcurrentTests
+// suites run in parallel; suite results are reported in registration order.
+func (tf *TestFramework) RunAllTests(ctx context.Context) (*TestResults, error) {
+	defer tf.finalize()
+
+	tf.mu.RLock()
+	suites := append([]TestSuite(nil), tf.suites...)
+	tf.mu.RUnlock()
+
+	workers := tf.config.ConcurrentTests
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(suites) {
+		workers = len(suites)
 	}
 
-	if tf.config.DNSTests {
-		if err := tf.runDNSTests(ctx); err != nil {
-			return nil, fmt.Errorf("dns tests failed: %w", err)
-		}
+	suiteResults := make([]*TestSuiteResult, len(suites))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				suiteResults[i] = tf.runSuite(ctx, suites[i])
+			}
+		}()
 	}
 
-	if tf.config.IntegrationTests {
-		if err := tf.runIntegrationTests(ctx); err != nil {
-			return nil, fmt.Errorf("integration tests failed: %w", err)
+	for i := range suites {
+		if ctx.Err() != nil {
+			break
 		}
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
-	return tf.results, nil
-}
-
-// runCaptivePortalTests executes captive portal detection tests
-func (tf *TestFramework) runCaptivePortalTests(ctx context.Context) error {
-	suite := TestSuiteResult{
-		Name:  "CaptivePortalTests",
-		Tests: make([]TestResult, 0),
+	// Load tests run on their own so other suites don't skew the measurements
+	if tf.config.LoadTests && ctx.Err() == nil {
+		suiteResults = append(suiteResults, tf.runLoadTests(ctx))
 	}
-	suiteStart := time.Now()
-
-	// Test HTTP 204 detection
-	result := tf.runTest(ctx, TestCase{
-		Name:        "HTTP204Detection",
-		Description: "Test HTTP 204 captive portal detection",
-		Timeout:     30 * time.Second,
-		TestFunc:    tf.testHTTP204Detection,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	// Test DNS hijacking detection
-	result = tf.runTest(ctx, TestCase{
-		Name:        "DNSHijackingDetection",
-		Description: "Test DNS hijacking detection",
-		Timeout:     30 * time.Second,
-		TestFunc:    tf.testDNSHijackingDetection,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	// Test captive portal bypass
-	result = tf.runTest(ctx, TestCase{
-		Name:        "CaptivePortalBypass",
-		Description: "Test captive portal bypass mechanisms",
-		Timeout:     60 * time.Second,
-		TestFunc:    tf.testCaptivePortalBypass,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	suite.Duration = time.Since(suiteStart)
-	suite.Status = tf.calculateSuiteStatus(suite.Tests)
 
 	tf.mu.Lock()
-	tf.results.TestSuites = append(tf.results.TestSuites, suite)
-	tf.mu.Unlock()
-
-	return nil
-}
-
-// runNetworkFailoverTests executes network failover tests
-func (tf *TestFramework) runNetworkFailoverTests(ctx context.Context) error {
-	suite := TestSuiteResult{
-		Name:  "NetworkFailoverTests",
-		Tests: make([]TestResult, 0),
+	for _, result := range suiteResults {
+		if result != nil {
+			tf.results.TestSuites = append(tf.results.TestSuites, *result)
+		}
 	}
-	suiteStart := time.Now()
-
-	// Test interface detection
-	result := tf.runTest(ctx, TestCase{
-		Name:        "InterfaceDetection",
-		Description: "Test network interface detection",
-		Timeout:     15 * time.Second,
-		TestFunc:    tf.testInterfaceDetection,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	// Test failover logic
-	result = tf.runTest(ctx, TestCase{
-		Name:        "FailoverLogic",
-		Description: "Test network failover logic",
-		Timeout:     60 * time.Second,
-		TestFunc:    tf.testFailoverLogic,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	// Test recovery logic
-	result = tf.runTest(ctx, TestCase{
-		Name:        "RecoveryLogic",
-		Description: "Test network recovery logic",
-		Timeout:     60 * time.Second,
-		TestFunc:    tf.testRecoveryLogic,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	suite.Duration = time.Since(suiteStart)
-	suite.Status = tf.calculateSuiteStatus(suite.Tests)
-
-	tf.mu.Lock()
-	tf.results.TestSuites = append(tf.results.TestSuites, suite)
 	tf.mu.Unlock()
 
-	return nil
-}
-
-// runWireGuardTests executes WireGuard configuration tests
-func (tf *TestFramework) runWireGuardTests(ctx context.Context) error {
-	suite := TestSuiteResult{
-		Name:  "WireGuardTests",
-		Tests: make([]TestResult, 0),
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("test run cancelled: %w", err)
 	}
-	suiteStart := time.Now()
-
-	// Test key generation
-	result := tf.runTest(ctx, TestCase{
-		Name:        "KeyGeneration",
-		Description: "Test WireGuard key generation",
-		Timeout:     10 * time.Second,
-		TestFunc:    tf.testWireGuardKeyGeneration,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	// Test config generation
-	result = tf.runTest(ctx, TestCase{
-		Name:        "ConfigGeneration",
-		Description: "Test WireGuard config file generation",
-		Timeout:     10 * time.Second,
-		TestFunc:    tf.testWireGuardConfigGeneration,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	suite.Duration = time.Since(suiteStart)
-	suite.Status = tf.calculateSuiteStatus(suite.Tests)
-
-	tf.mu.Lock()
-	tf.results.TestSuites = append(tf.results.TestSuites, suite)
-	tf.mu.Unlock()
 
-	return nil
+	return tf.results, nil
 }
 
-// runDNSTests executes DNS-related tests
-func (tf *TestFramework) runDNSTests(ctx context.Context) error {
-	suite := TestSuiteResult{
-		Name:  "DNSTests",
-		Tests: make([]TestResult, 0),
+// runSuite executes every test case of a suite and returns the suite result
+func (tf *TestFramework) runSuite(ctx context.Context, suite TestSuite) *TestSuiteResult {
+	result := &TestSuiteResult{
+		Name:  suite.Name,
+		Tests: make([]TestResult, 0, len(suite.Tests)),
 	}
 	suiteStart := time.Now()
 
-	// Test DNS resolution
-	result := tf.runTest(ctx, TestCase{
-		Name:        "DNSResolution",
-		Description: "Test DNS resolution functionality",
-		Timeout:     30 * time.Second,
-		TestFunc:    tf.testDNSResolution,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	// Test DNS leak protection
-	result = tf.runTest(ctx, TestCase{
-		Name:        "DNSLeakProtection",
-		Description: "Test DNS leak protection",
-		Timeout:     30 * time.Second,
-		TestFunc:    tf.testDNSLeakProtection,
-	})
-	suite.Tests = append(suite.Tests, result)
-
-	suite.Duration = time.Since(suiteStart)
-	suite.Status = tf.calculateSuiteStatus(suite.Tests)
-
-	tf.mu.Lock()
-	tf.results.TestSuites = append(tf.results.TestSuites, suite)
-	tf.mu.Unlock()
-
-	return nil
-}
-
-// runIntegrationTests executes integration tests
-func (tf *TestFramework) runIntegrationTests(ctx context.Context) error {
-	suite := TestSuiteResult{
-		Name:  "IntegrationTests",
-		Tests: make([]TestResult, 0),
+	for _, testCase := range suite.Tests {
+		result.Tests = append(result.Tests, tf.runTest(ctx, testCase))
 	}
-	suiteStart := time.Now()
-
-	// Test end-to-end workflow
-	result := tf.runTest(ctx, TestCase{
-		Name:        "EndToEndWorkflow",
-		Description: "Test complete end-to-end workflow",
-		Timeout:     300 * time.Second, // 5 minutes
-		TestFunc:    tf.testEndToEndWorkflow,
-	})
-	suite.Tests = append(suite.Tests, result)
 
-	suite.Duration = time.Since(suiteStart)
-	suite.Status = tf.calculateSuiteStatus(suite.Tests)
+	result.Duration = time.Since(suiteStart)
+	result.Status = tf.calculateSuiteStatus(result.Tests)
 
-	tf.mu.Lock()
-	tf.results.TestSuites = append(tf.results.TestSuites, suite)
-	tf.mu.Unlock()
-
-	return nil
+	return result
 }
 
 // TestCase represents an individual test case
@@ -398,148 +287,94 @@ type TestCase struct {
 	TestFunc    func(ctx context.Context) TestResult
 }
 
-// runTest executes an individual test case
-func (tf *TestFramework) runTest(ctx context.Context, testCase TestCase) TestResult {
-	start := time.Now()
-
-	// Create test context with timeout
-	testCtx, cancel := context.WithTimeout(ctx, testCase.Timeout)
-	defer cancel()
-
-	// Execute test
-	result := testCase.TestFunc(testCtx)
-	result.Name = testCase.Name
-	result.Description = testCase.Description
-	result.StartTime = start
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(start)
-
-	// Update counters
-	tf.mu.Lock()
-	tf.results.TotalTests++
-	switch result.Status {
-	case TestStatusPassed:
-		tf.results.PassedTests++
-	case TestStatusFailed:
-		tf.results.FailedTests++
-	case TestStatusSkipped:
-		tf.results.SkippedTests++
-	}
-	tf.mu.Unlock()
-
-	return result
-}
-
-// Test implementation functions (these would contain actual test logic)
-func (tf *TestFramework) testHTTP204Detection(ctx context.Context) TestResult {
-	// Create mock captive portal server
-	mockServer := tf.createCaptivePortalMockServer()
-	defer mockServer.Close()
-
-	// Test HTTP 204 detection logic
-	// This would implement actual testing of the captive portal detector
-
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"HTTP 204 detection working correctly"},
-		Assertions: []Assertion{
-			{
-				Description: "HTTP 204 response detected",
-				Expected:    true,
-				Actual:      true,
-				Passed:      true,
-			},
-		},
-	}
-}
-
-func (tf *TestFramework) testDNSHijackingDetection(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"DNS hijacking detection working correctly"},
-	}
-}
-
-func (tf *TestFramework) testCaptivePortalBypass(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"Captive portal bypass mechanisms working"},
-	}
-}
-
-func (tf *TestFramework) testInterfaceDetection(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"Network interface detection working"},
+// Assert records an assertion comparing expected and actual values. A failed
+// assertion marks the test as failed.
+func (r *TestResult) Assert(description string, expected, actual interface{}) bool {
+	passed := reflect.DeepEqual(expected, actual)
+	assertion := Assertion{
+		Description: description,
+		Expected:    expected,
+		Actual:      actual,
+		Passed:      passed,
 	}
-}
-
-func (tf *TestFramework) testFailoverLogic(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"Network failover logic working"},
+	if !passed {
+		assertion.Message = fmt.Sprintf("expected %v, got %v", expected, actual)
+		r.Status = TestStatusFailed
+		if r.Error == "" {
+			r.Error = fmt.Sprintf("%s: %s", description, assertion.Message)
+		}
 	}
-}
 
-func (tf *TestFramework) testRecoveryLogic(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"Network recovery logic working"},
-	}
+	r.Assertions = append(r.Assertions, assertion)
+	return passed
 }
 
-func (tf *TestFramework) testWireGuardKeyGeneration(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"WireGuard key generation working"},
-	}
+// Fail marks the test as failed with the given error
+func (r *TestResult) Fail(format string, args ...interface{}) {
+	r.Status = TestStatusFailed
+	r.Error = fmt.Sprintf(format, args...)
 }
 
-func (tf *TestFramework) testWireGuardConfigGeneration(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"WireGuard config generation working"},
+// testTimeout returns the timeout of testCase, falling back to the
+// configured timeout and then defaultTestTimeout when unset
+func (tf *TestFramework) testTimeout(testCase TestCase) time.Duration {
+	switch {
+	case testCase.Timeout > 0:
+		return testCase.Timeout
+	case tf.config.Timeout > 0:
+		return tf.config.Timeout
+	default:
+		return defaultTestTimeout
 	}
 }
 
-func (tf *TestFramework) testDNSResolution(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"DNS resolution working"},
-	}
-}
+// runTest executes an individual test case
+func (tf *TestFramework) runTest(ctx context.Context, testCase TestCase) (result TestResult) {
+	start := time.Now()
 
-func (tf *TestFramework) testDNSLeakProtection(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"DNS leak protection working"},
-	}
-}
+	// Create test context with timeout
+	testCtx, cancel := context.WithTimeout(ctx, tf.testTimeout(testCase))
+	defer cancel()
 
-func (tf *TestFramework) testEndToEndWorkflow(ctx context.Context) TestResult {
-	return TestResult{
-		Status: TestStatusPassed,
-		Output: []string{"End-to-end workflow working"},
-	}
-}
+	defer func() {
+		// A panicking test fails instead of aborting the whole run
+		if r := recover(); r != nil {
+			result = TestResult{Status: TestStatusFailed, Error: fmt.Sprintf("test panicked: %v", r)}
+		}
 
-// createCaptivePortalMockServer creates a mock captive portal server
-func (tf *TestFramework) createCaptivePortalMockServer() *httptest.Server {
-	mux := http.NewServeMux()
+		// Tests that only record assertions pass unless one failed
+		if result.Status == "" {
+			result.Status = TestStatusPassed
+			for _, assertion := range result.Assertions {
+				if !assertion.Passed {
+					result.Status = TestStatusFailed
+					break
+				}
+			}
+		}
 
-	// Mock captive portal endpoint
-	mux.HandleFunc("/generate_204", func(w http.ResponseWriter, r *http.Request) {
-		// Simulate captive portal - return redirect instead of 204
-		w.Header().Set("Location", "http://captive.portal/login")
-		w.WriteHeader(http.StatusFound)
-	})
+		result.Name = testCase.Name
+		result.Description = testCase.Description
+		result.StartTime = start
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(start)
 
-	// Mock normal endpoint
-	mux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNoContent) // 204
-	})
+		// Update counters
+		tf.mu.Lock()
+		tf.results.TotalTests++
+		switch result.Status {
+		case TestStatusPassed:
+			tf.results.PassedTests++
+		case TestStatusFailed:
+			tf.results.FailedTests++
+		case TestStatusSkipped:
+			tf.results.SkippedTests++
+		}
+		tf.mu.Unlock()
+	}()
 
-	return httptest.NewServer(mux)
+	// Execute test
+	return testCase.TestFunc(testCtx)
 }
 
 // calculateSuiteStatus calculates the overall status of a test suite
@@ -579,9 +414,16 @@ func (tf *TestFramework) finalize() {
 		tf.results.Summary.SuccessRate = float64(tf.results.PassedTests) / float64(tf.results.TotalTests) * 100
 	}
 
-	// Calculate average test time
+	// Calculate average test time from the tests themselves, since suites
+	// may have run concurrently
 	if tf.results.TotalTests > 0 {
-		tf.results.Summary.AverageTestTime = tf.results.Duration.Seconds() / float64(tf.results.TotalTests)
+		var total time.Duration
+		for _, suite := range tf.results.TestSuites {
+			for _, test := range suite.Tests {
+				total += test.Duration
+			}
+		}
+		tf.results.Summary.AverageTestTime = total.Seconds() / float64(tf.results.TotalTests)
 	}
 
 	// Add recommendations
@@ -0,0 +1,416 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	gotesting "testing"
+	"time"
+)
+
+func TestRunAllTestsRecordsFailedAssertions(t *gotesting.T) {
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	tf.RegisterTest("Custom", TestCase{
+		Name:    "Failing",
+		Timeout: time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			result := TestResult{}
+			result.Assert("one equals two", 1, 2)
+			return result
+		},
+	})
+	tf.RegisterTest("Custom", TestCase{
+		Name:    "Passing",
+		Timeout: time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			result := TestResult{}
+			result.Assert("one equals one", 1, 1)
+			return result
+		},
+	})
+	tf.RegisterTest("Custom", TestCase{
+		Name:    "Panicking",
+		Timeout: time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			panic("boom")
+		},
+	})
+
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+
+	if results.TotalTests != 3 || results.PassedTests != 1 || results.FailedTests != 2 {
+		t.Fatalf("unexpected counters: total=%d passed=%d failed=%d",
+			results.TotalTests, results.PassedTests, results.FailedTests)
+	}
+	if len(results.TestSuites) != 1 || results.TestSuites[0].Status != TestStatusFailed {
+		t.Fatalf("expected a single failed suite, got %+v", results.TestSuites)
+	}
+
+	failing := results.TestSuites[0].Tests[0]
+	if len(failing.Assertions) != 1 || failing.Assertions[0].Passed {
+		t.Fatalf("expected one failed assertion, got %+v", failing.Assertions)
+	}
+	if failing.Error == "" {
+		t.Fatal("expected failed assertion to set the test error")
+	}
+}
+
+func TestCaptivePortalSuitePasses(t *gotesting.T) {
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{CaptivePortalTests: true, WireGuardTests: true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+
+	for _, suite := range results.TestSuites {
+		for _, test := range suite.Tests {
+			if test.Status != TestStatusPassed {
+				t.Errorf("%s/%s: status %s: %s", suite.Name, test.Name, test.Status, test.Error)
+			}
+		}
+	}
+}
+
+func TestRunAllTestsRunsSuitesConcurrently(t *gotesting.T) {
+	const suites = 4
+	const delay = 200 * time.Millisecond
+
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{ConcurrentTests: suites}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	names := []string{"A", "B", "C", "D"}
+	for _, name := range names {
+		tf.RegisterTest(name, TestCase{
+			Name:    "Slow",
+			Timeout: time.Second,
+			TestFunc: func(ctx context.Context) TestResult {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return TestResult{Status: TestStatusFailed, Error: ctx.Err().Error()}
+				}
+				return TestResult{Status: TestStatusPassed}
+			},
+		})
+	}
+
+	start := time.Now()
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed >= suites*delay {
+		t.Fatalf("expected concurrent run faster than %v, took %v", suites*delay, elapsed)
+	}
+	if results.TotalTests != suites || results.PassedTests != suites {
+		t.Fatalf("unexpected counters: total=%d passed=%d", results.TotalTests, results.PassedTests)
+	}
+	for i, suite := range results.TestSuites {
+		if suite.Name != names[i] {
+			t.Fatalf("suite %d: expected %s, got %s", i, names[i], suite.Name)
+		}
+	}
+}
+
+func TestRunAllTestsHonorsTestTimeout(t *gotesting.T) {
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{ConcurrentTests: 2}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	tf.RegisterTest("Timeout", TestCase{
+		Name:    "Hangs",
+		Timeout: 50 * time.Millisecond,
+		TestFunc: func(ctx context.Context) TestResult {
+			<-ctx.Done()
+			return TestResult{Status: TestStatusFailed, Error: ctx.Err().Error()}
+		},
+	})
+
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+	if results.FailedTests != 1 {
+		t.Fatalf("expected timed out test to fail, got %+v", results.TestSuites)
+	}
+
+	cancelled := NewTestFramework()
+	if err := cancelled.Initialize(&TestConfig{CaptivePortalTests: true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cancelled.RunAllTests(ctx); err == nil {
+		t.Fatal("expected cancelled run to return an error")
+	}
+}
+
+func TestCaptivePortalTestsUseTestContext(t *gotesting.T) {
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{CaptivePortalTests: true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// Detection runs under the test case's context, so a cancelled case
+	// fails instead of completing the detection
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, testFunc := range map[string]func(context.Context) TestResult{
+		"HTTP204Detection":    tf.testHTTP204Detection,
+		"CaptivePortalBypass": tf.testCaptivePortalBypass,
+		"EndToEndWorkflow":    tf.testEndToEndWorkflow,
+	} {
+		if result := testFunc(ctx); result.Status != TestStatusFailed || !strings.Contains(result.Error, "context canceled") {
+			t.Errorf("%s: expected a cancelled detection to fail, got %s: %s", name, result.Status, result.Error)
+		}
+	}
+}
+
+func TestRunAllTestsDefaultsTestTimeout(t *gotesting.T) {
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{Timeout: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	var deadlines []time.Duration
+	record := func(ctx context.Context) TestResult {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return TestResult{Status: TestStatusFailed, Error: "no deadline"}
+		}
+		deadlines = append(deadlines, time.Until(deadline))
+		return TestResult{}
+	}
+
+	// A test case without a timeout uses the configured one
+	tf.RegisterTest("Defaults", TestCase{Name: "Configured", TestFunc: record})
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+	if results.PassedTests != 1 || len(deadlines) != 1 || deadlines[0] <= 0 || deadlines[0] > 50*time.Millisecond {
+		t.Fatalf("expected the configured timeout, got %+v with deadlines %v", results.TestSuites, deadlines)
+	}
+
+	// Without a configured timeout the fixed default applies
+	tf = NewTestFramework()
+	if err := tf.Initialize(&TestConfig{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	tf.RegisterTest("Defaults", TestCase{Name: "Fallback", TestFunc: record})
+	if results, err = tf.RunAllTests(context.Background()); err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+	if results.PassedTests != 1 || len(deadlines) != 2 || deadlines[1] <= 50*time.Millisecond || deadlines[1] > defaultTestTimeout {
+		t.Errorf("expected the default timeout, got %+v with deadlines %v", results.TestSuites, deadlines)
+	}
+}
+
+func TestRunAllTestsRunsLoadTests(t *gotesting.T) {
+	tf := NewTestFramework()
+	err := tf.Initialize(&TestConfig{
+		LoadTests:       true,
+		LoadRequestRate: 40,
+		LoadDuration:    500 * time.Millisecond,
+		LoadRampUp:      100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+	if len(results.TestSuites) != 1 || results.TestSuites[0].LoadTest == nil {
+		t.Fatalf("expected a load test suite, got %+v", results.TestSuites)
+	}
+
+	load := results.TestSuites[0].LoadTest
+	if load.Requests < 5 {
+		t.Fatalf("expected requests to be issued, got %d", load.Requests)
+	}
+	if load.P50Latency <= 0 || load.P95Latency < load.P50Latency || load.P99Latency < load.P95Latency {
+		t.Fatalf("unexpected latency percentiles: p50=%v p95=%v p99=%v", load.P50Latency, load.P95Latency, load.P99Latency)
+	}
+	if load.Errors != 0 || results.TestSuites[0].Status != TestStatusPassed {
+		t.Fatalf("expected clean load run, got %d errors, status %s", load.Errors, results.TestSuites[0].Status)
+	}
+}
+
+func TestMockServerRecordsRequestsAndInjectsDelay(t *gotesting.T) {
+	const delay = 100 * time.Millisecond
+
+	mock, err := NewMockServer(MockServerConfig{
+		ResponseDelay:   delay,
+		CustomResponses: map[string]string{"/custom": "custom body"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMockServer failed: %v", err)
+	}
+	defer mock.Close()
+
+	start := time.Now()
+	resp, err := http.Get(mock.URL() + "/custom?x=1")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("expected response delayed by %v, took %v", delay, elapsed)
+	}
+	if string(body) != "custom body" {
+		t.Fatalf("expected custom response, got %q", body)
+	}
+
+	requests := mock.GetRequests()
+	if len(requests) != 1 || requests[0].Method != "GET" || requests[0].URL != "/custom?x=1" {
+		t.Fatalf("unexpected recorded requests: %+v", requests)
+	}
+}
+
+func TestMockServerFailureRate(t *gotesting.T) {
+	const failureRate = 0.3
+	const total = 500
+
+	mock, err := NewMockServer(MockServerConfig{FailureRate: failureRate}, map[string]http.HandlerFunc{
+		"/ok": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMockServer failed: %v", err)
+	}
+	defer mock.Close()
+
+	failures := 0
+	for i := 0; i < total; i++ {
+		resp, err := http.Get(mock.URL() + "/ok")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			failures++
+		}
+	}
+
+	if ratio := float64(failures) / total; math.Abs(ratio-failureRate) > 0.1 {
+		t.Fatalf("expected failure ratio near %.2f, got %.2f", failureRate, ratio)
+	}
+	if len(mock.GetRequests()) != total {
+		t.Fatalf("expected %d recorded requests, got %d", total, len(mock.GetRequests()))
+	}
+}
+
+func runSampleResults(t *gotesting.T) *TestResults {
+	t.Helper()
+
+	tf := NewTestFramework()
+	if err := tf.Initialize(&TestConfig{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	tf.RegisterTest("Sample", TestCase{
+		Name:    "Passing",
+		Timeout: time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			result := TestResult{}
+			result.Assert("true is true", true, true)
+			return result
+		},
+	})
+	tf.RegisterTest("Sample", TestCase{
+		Name:    "Failing",
+		Timeout: time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			result := TestResult{}
+			result.Assert("status", 204, 302)
+			return result
+		},
+	})
+	tf.RegisterTest("Sample", TestCase{
+		Name:    "Skipped",
+		Timeout: time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			return TestResult{Status: TestStatusSkipped, Output: []string{"not supported"}}
+		},
+	})
+
+	results, err := tf.RunAllTests(context.Background())
+	if err != nil {
+		t.Fatalf("RunAllTests failed: %v", err)
+	}
+	return results
+}
+
+func TestWriteResultsJSONRoundTrips(t *gotesting.T) {
+	results := runSampleResults(t)
+
+	var buf bytes.Buffer
+	if err := results.WriteResults(&buf, OutputFormatJSON); err != nil {
+		t.Fatalf("WriteResults failed: %v", err)
+	}
+
+	var decoded TestResults
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to decode JSON results: %v", err)
+	}
+	if decoded.TotalTests != 3 || decoded.FailedTests != 1 || decoded.SkippedTests != 1 {
+		t.Fatalf("unexpected decoded counters: %+v", decoded)
+	}
+	if len(decoded.TestSuites) != 1 || decoded.TestSuites[0].Tests[1].Error != results.TestSuites[0].Tests[1].Error {
+		t.Fatalf("decoded suites do not match: %+v", decoded.TestSuites)
+	}
+}
+
+func TestWriteResultsJUnit(t *gotesting.T) {
+	results := runSampleResults(t)
+
+	var buf bytes.Buffer
+	if err := results.WriteResults(&buf, OutputFormatJUnit); err != nil {
+		t.Fatalf("WriteResults failed: %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse JUnit XML: %v", err)
+	}
+	if report.Tests != 3 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("unexpected JUnit report: %+v", report)
+	}
+
+	cases := report.Suites[0].TestCases
+	if len(cases) != 3 || cases[1].Failure == nil || cases[2].Skipped == nil {
+		t.Fatalf("unexpected JUnit test cases: %+v", cases)
+	}
+	if cases[0].Failure != nil || cases[0].ClassName != "Sample" {
+		t.Fatalf("unexpected passing test case: %+v", cases[0])
+	}
+
+	if err := results.WriteResults(&buf, "yaml"); err == nil {
+		t.Fatal("expected unsupported format error")
+	}
+}
@@ -0,0 +1,204 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLoadRequestRate = 10
+	defaultLoadDuration    = 10 * time.Second
+
+	// maxLoadErrorRate is the error rate above which a load test fails
+	maxLoadErrorRate = 0.05
+)
+
+// LoadTestResult contains the measurements of a load test run
+type LoadTestResult struct {
+	TargetURL  string        `json:"target_url"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	Duration   time.Duration `json:"duration"`
+	Throughput float64       `json:"throughput"` // Completed requests per second
+	ErrorRate  float64       `json:"error_rate"`
+	P50Latency time.Duration `json:"p50_latency"`
+	P95Latency time.Duration `json:"p95_latency"`
+	P99Latency time.Duration `json:"p99_latency"`
+}
+
+// loadTestSettings returns the configured load parameters with defaults applied
+func (tf *TestFramework) loadTestSettings() (rate int, duration, rampUp time.Duration) {
+	rate = tf.config.LoadRequestRate
+	if rate <= 0 {
+		rate = defaultLoadRequestRate
+	}
+	duration = tf.config.LoadDuration
+	if duration <= 0 {
+		duration = defaultLoadDuration
+	}
+	rampUp = tf.config.LoadRampUp
+	if rampUp > duration {
+		rampUp = duration
+	}
+	return rate, duration, rampUp
+}
+
+// runLoadTests drives load against the captive portal mock server and
+// returns the LoadTests suite result
+func (tf *TestFramework) runLoadTests(ctx context.Context) *TestSuiteResult {
+	rate, duration, rampUp := tf.loadTestSettings()
+	var load *LoadTestResult
+
+	suiteStart := time.Now()
+	testResult := tf.runTest(ctx, TestCase{
+		Name:        "CaptivePortalLoad",
+		Description: fmt.Sprintf("Load test captive portal detection endpoint at %d req/s", rate),
+		// Allow in-flight requests to drain after the load window
+		Timeout: duration + 30*time.Second,
+		TestFunc: func(ctx context.Context) TestResult {
+			mockServer, err := tf.createCaptivePortalMockServer()
+			if err != nil {
+				return TestResult{Status: TestStatusFailed, Error: err.Error()}
+			}
+			defer mockServer.Close()
+
+			load = runLoad(ctx, http.DefaultClient, mockServer.URL()+"/success", rate, duration, rampUp)
+
+			result := TestResult{}
+			result.Assert("Requests completed", true, load.Requests > 0)
+			result.Assert("Error rate within threshold", true, load.ErrorRate <= maxLoadErrorRate)
+			result.Output = append(result.Output, fmt.Sprintf("%d requests, %.1f req/s, p50=%v p95=%v p99=%v, error rate %.2f%%",
+				load.Requests, load.Throughput, load.P50Latency, load.P95Latency, load.P99Latency, load.ErrorRate*100))
+			return result
+		},
+	})
+
+	return &TestSuiteResult{
+		Name:     "LoadTests",
+		Tests:    []TestResult{testResult},
+		Duration: time.Since(suiteStart),
+		Status:   tf.calculateSuiteStatus([]TestResult{testResult}),
+		LoadTest: load,
+	}
+}
+
+// runLoad issues GET requests to url at the given rate for duration. The rate
+// increases linearly from zero to rate over rampUp.
+func runLoad(ctx context.Context, client *http.Client, url string, rate int, duration, rampUp time.Duration) *LoadTestResult {
+	result := &LoadTestResult{TargetURL: url}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+	)
+
+	// Poll often enough to keep up with the target rate
+	interval := time.Second / time.Duration(rate)
+	if interval > 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	sent := 0
+	start := time.Now()
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= duration || ctx.Err() != nil {
+			break
+		}
+
+		for due := requestsDue(elapsed, rate, rampUp); sent < due; sent++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				requestStart := time.Now()
+				err := loadRequest(ctx, client, url)
+				latency := time.Since(requestStart)
+
+				mu.Lock()
+				defer mu.Unlock()
+				result.Requests++
+				if err != nil {
+					result.Errors++
+					return
+				}
+				latencies = append(latencies, latency)
+			}()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+		}
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	if result.Requests > 0 {
+		result.ErrorRate = float64(result.Errors) / float64(result.Requests)
+	}
+	if seconds := result.Duration.Seconds(); seconds > 0 {
+		result.Throughput = float64(result.Requests-result.Errors) / seconds
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50Latency = percentile(latencies, 50)
+	result.P95Latency = percentile(latencies, 95)
+	result.P99Latency = percentile(latencies, 99)
+
+	return result
+}
+
+// requestsDue returns how many requests should have been started after
+// elapsed, counting the request sent at the start of the run
+func requestsDue(elapsed time.Duration, rate int, rampUp time.Duration) int {
+	seconds := elapsed.Seconds()
+	if rampUp <= 0 {
+		return int(float64(rate)*seconds) + 1
+	}
+
+	ramp := rampUp.Seconds()
+	if seconds < ramp {
+		return int(float64(rate)*seconds*seconds/(2*ramp)) + 1
+	}
+	return int(float64(rate)*(ramp/2+seconds-ramp)) + 1
+}
+
+// loadRequest performs a single load test request
+func loadRequest(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("server error: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
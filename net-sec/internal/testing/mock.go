@@ -0,0 +1,101 @@
+package testing
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+)
+
+// NewMockServer starts a mock server serving the given handlers by path.
+// Responses are delayed by ResponseDelay, fail with 503 at FailureRate
+// probability, and CustomResponses take precedence over handlers. Every
+// request is recorded.
+func NewMockServer(config MockServerConfig, handlers map[string]http.HandlerFunc) (*MockServer, error) {
+	if handlers == nil {
+		handlers = make(map[string]http.HandlerFunc)
+	}
+
+	mock := &MockServer{
+		Handlers: handlers,
+		Config:   config,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(mock.serveHTTP))
+	if config.Port != 0 {
+		listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(config.Port)))
+		if err != nil {
+			return nil, err
+		}
+		server.Listener.Close()
+		server.Listener = listener
+	}
+	server.Start()
+	mock.Server = server
+
+	return mock, nil
+}
+
+// URL returns the base URL of the mock server
+func (m *MockServer) URL() string {
+	return m.Server.URL
+}
+
+// Close shuts down the mock server
+func (m *MockServer) Close() {
+	m.Server.Close()
+}
+
+// GetRequests returns a copy of the requests received so far
+func (m *MockServer) GetRequests() []MockRequest {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]MockRequest(nil), m.Requests...)
+}
+
+// serveHTTP records the request and applies the configured faults before
+// dispatching it
+func (m *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	m.mu.Lock()
+	m.Requests = append(m.Requests, MockRequest{
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		Headers:   r.Header.Clone(),
+		Body:      string(body),
+		Timestamp: time.Now(),
+	})
+	fail := m.Config.FailureRate > 0 && m.rand.Float64() < m.Config.FailureRate
+	m.mu.Unlock()
+
+	if m.Config.ResponseDelay > 0 {
+		select {
+		case <-time.After(m.Config.ResponseDelay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fail {
+		http.Error(w, "injected failure", http.StatusServiceUnavailable)
+		return
+	}
+
+	if response, ok := m.Config.CustomResponses[r.URL.Path]; ok {
+		w.Write([]byte(response))
+		return
+	}
+
+	if handler, ok := m.Handlers[r.URL.Path]; ok {
+		handler(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
@@ -0,0 +1,142 @@
+package testing
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats supported by WriteResults
+const (
+	OutputFormatJSON  = "json"
+	OutputFormatJUnit = "junit"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is a JUnit XML test suite
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a JUnit XML test case
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure describes a failed JUnit test case
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSkipped marks a skipped JUnit test case
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteResults writes the test results to w in the given format
+func (r *TestResults) WriteResults(w io.Writer, format string) error {
+	switch format {
+	case OutputFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode results: %w", err)
+		}
+		return nil
+
+	case OutputFormatJUnit:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(toJUnit(r)); err != nil {
+			return fmt.Errorf("failed to encode results: %w", err)
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// toJUnit converts test results to a JUnit XML report
+func toJUnit(results *TestResults) junitTestSuites {
+	report := junitTestSuites{
+		Tests:    results.TotalTests,
+		Failures: results.FailedTests,
+		Skipped:  results.SkippedTests,
+		Time:     results.Duration.Seconds(),
+	}
+
+	for _, suite := range results.TestSuites {
+		junitSuite := junitTestSuite{
+			Name:  suite.Name,
+			Tests: len(suite.Tests),
+			Time:  suite.Duration.Seconds(),
+		}
+
+		for _, test := range suite.Tests {
+			if junitSuite.Timestamp == "" && !test.StartTime.IsZero() {
+				junitSuite.Timestamp = test.StartTime.Format("2006-01-02T15:04:05")
+			}
+
+			testCase := junitTestCase{
+				Name:      test.Name,
+				ClassName: suite.Name,
+				Time:      test.Duration.Seconds(),
+				SystemOut: strings.Join(test.Output, "\n"),
+			}
+
+			switch test.Status {
+			case TestStatusFailed:
+				junitSuite.Failures++
+				testCase.Failure = &junitFailure{Message: test.Error, Text: failureDetails(test)}
+			case TestStatusSkipped:
+				junitSuite.Skipped++
+				testCase.Skipped = &junitSkipped{Message: strings.Join(test.Output, "; ")}
+			}
+
+			junitSuite.TestCases = append(junitSuite.TestCases, testCase)
+		}
+
+		report.Suites = append(report.Suites, junitSuite)
+	}
+
+	return report
+}
+
+// failureDetails lists the failed assertions of a test
+func failureDetails(test TestResult) string {
+	var details []string
+	for _, assertion := range test.Assertions {
+		if !assertion.Passed {
+			details = append(details, fmt.Sprintf("%s: %s", assertion.Description, assertion.Message))
+		}
+	}
+	return strings.Join(details, "\n")
+}
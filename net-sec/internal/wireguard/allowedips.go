@@ -0,0 +1,89 @@
+package wireguard
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// defaultRoutes are the full-tunnel AllowedIPs
+var defaultRoutes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/0"),
+	netip.MustParsePrefix("::/0"),
+}
+
+// ComputeAllowedIPs returns the CIDRs covering the default routes minus the
+// excluded ranges, for split tunnels that route everything except some
+// subnets. The result is sorted and has no overlapping entries.
+func ComputeAllowedIPs(excluded []string) ([]string, error) {
+	prefixes := append([]netip.Prefix(nil), defaultRoutes...)
+
+	for _, cidr := range excluded {
+		exclude, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excluded IP range %q (use CIDR notation): %w", cidr, err)
+		}
+		prefixes = subtractPrefix(prefixes, exclude.Masked())
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		if a, b := prefixes[i].Addr(), prefixes[j].Addr(); a != b {
+			return a.Less(b)
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+
+	allowed := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		allowed[i] = prefix.String()
+	}
+	return allowed, nil
+}
+
+// subtractPrefix removes exclude from the disjoint prefixes
+func subtractPrefix(prefixes []netip.Prefix, exclude netip.Prefix) []netip.Prefix {
+	var result []netip.Prefix
+	for _, prefix := range prefixes {
+		switch {
+		case !prefix.Overlaps(exclude):
+			result = append(result, prefix)
+		case prefix.Bits() >= exclude.Bits():
+			// Fully excluded
+		default:
+			result = append(result, splitAround(prefix, exclude)...)
+		}
+	}
+	return result
+}
+
+// splitAround returns the prefixes covering prefix minus the narrower
+// exclude it contains, by halving prefix until exclude is reached and
+// keeping the halves that do not contain it
+func splitAround(prefix, exclude netip.Prefix) []netip.Prefix {
+	var result []netip.Prefix
+	for prefix.Bits() < exclude.Bits() {
+		low := netip.PrefixFrom(prefix.Addr(), prefix.Bits()+1)
+		high := netip.PrefixFrom(setBit(prefix.Addr(), prefix.Bits()), prefix.Bits()+1)
+
+		if low.Contains(exclude.Addr()) {
+			result = append(result, high)
+			prefix = low
+		} else {
+			result = append(result, low)
+			prefix = high
+		}
+	}
+	return result
+}
+
+// setBit returns addr with bit i, counted from the most significant, set
+func setBit(addr netip.Addr, i int) netip.Addr {
+	if addr.Is4() {
+		b := addr.As4()
+		b[i/8] |= 0x80 >> (i % 8)
+		return netip.AddrFrom4(b)
+	}
+	b := addr.As16()
+	b[i/8] |= 0x80 >> (i % 8)
+	return netip.AddrFrom16(b)
+}
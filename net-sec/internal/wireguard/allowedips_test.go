@@ -0,0 +1,137 @@
+package wireguard
+
+import (
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parsePrefixes parses the CIDRs or fails the test
+func parsePrefixes(t *testing.T, cidrs []string) []netip.Prefix {
+	t.Helper()
+
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			t.Fatalf("invalid CIDR %q: %v", cidr, err)
+		}
+		prefixes[i] = prefix
+	}
+	return prefixes
+}
+
+// addressCount returns the number of IPv4 addresses in prefix
+func addressCount(prefix netip.Prefix) uint64 {
+	return 1 << (32 - prefix.Bits())
+}
+
+func TestComputeAllowedIPsExcludesSubnet(t *testing.T) {
+	allowed, err := ComputeAllowedIPs([]string{"192.168.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefixes := parsePrefixes(t, allowed)
+	var total uint64
+	for i, prefix := range prefixes {
+		if prefix.Addr().Is6() {
+			if prefix.String() != "::/0" {
+				t.Errorf("IPv6 routes should be untouched, got %s", prefix)
+			}
+			continue
+		}
+
+		if prefix.Overlaps(netip.MustParsePrefix("192.168.0.0/16")) {
+			t.Errorf("%s overlaps the excluded range", prefix)
+		}
+		for _, other := range prefixes[i+1:] {
+			if prefix.Overlaps(other) {
+				t.Errorf("%s overlaps %s", prefix, other)
+			}
+		}
+		total += addressCount(prefix)
+	}
+
+	// Together the routes cover everything but the excluded /16
+	if want := uint64(1<<32 - 1<<16); total != want {
+		t.Errorf("expected %d IPv4 addresses to be routed, got %d", want, total)
+	}
+	for _, addr := range []string{"0.0.0.0", "8.8.8.8", "192.167.255.255", "192.169.0.0", "255.255.255.255"} {
+		if !containsAddr(prefixes, netip.MustParseAddr(addr)) {
+			t.Errorf("%s is not routed through the tunnel", addr)
+		}
+	}
+	if containsAddr(prefixes, netip.MustParseAddr("192.168.1.10")) {
+		t.Error("excluded address is routed through the tunnel")
+	}
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestComputeAllowedIPs(t *testing.T) {
+	tests := []struct {
+		excluded []string
+		want     []string
+	}{
+		{nil, []string{"0.0.0.0/0", "::/0"}},
+		{[]string{"128.0.0.0/1"}, []string{"0.0.0.0/1", "::/0"}},
+		// Overlapping exclusions and host bits are handled
+		{[]string{"10.0.0.0/8", "10.1.2.3/16"}, nil},
+		{[]string{"0.0.0.0/0", "fd00::/8"}, nil},
+	}
+
+	for _, tt := range tests {
+		allowed, err := ComputeAllowedIPs(tt.excluded)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.excluded, err)
+		}
+		if tt.want != nil && !reflect.DeepEqual(allowed, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.excluded, tt.want, allowed)
+		}
+		for _, prefix := range parsePrefixes(t, allowed) {
+			for _, excluded := range parsePrefixes(t, tt.excluded) {
+				if prefix.Overlaps(excluded) {
+					t.Errorf("%v: %s overlaps %s", tt.excluded, prefix, excluded)
+				}
+			}
+		}
+	}
+
+	if allowed, _ := ComputeAllowedIPs([]string{"0.0.0.0/0", "fd00::/8"}); len(allowed) != 8 || strings.HasPrefix(allowed[0], "0.") {
+		t.Errorf("expected only IPv6 routes around fd00::/8, got %v", allowed)
+	}
+}
+
+func TestGenerateConfigValidatesExcludedIPs(t *testing.T) {
+	dir := t.TempDir()
+	g := &Generator{keysDir: filepath.Join(dir, "keys"), configsDir: filepath.Join(dir, "configs")}
+	opts := &GeneratorOptions{
+		ServerEndpoint: "vpn.example.com:51820",
+		MTU:            1420,
+		GenerateKeys:   true,
+		ExcludedIPs:    []string{"192.168.0.0/16"},
+	}
+
+	config, err := g.GenerateConfig(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := ComputeAllowedIPs(opts.ExcludedIPs); !reflect.DeepEqual(config.Peer.AllowedIPs, want) {
+		t.Errorf("expected AllowedIPs %v, got %v", want, config.Peer.AllowedIPs)
+	}
+
+	opts.ExcludedIPs = []string{"192.168.1.1"}
+	if _, err := g.GenerateConfig(opts); err == nil || !strings.Contains(err.Error(), "CIDR") {
+		t.Errorf("expected a non-CIDR exclusion to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidEndpoint is returned for server endpoints that are not a valid
+// host:port
+var ErrInvalidEndpoint = errors.New("invalid server endpoint")
+
+// endpointResolveTimeout bounds the lookup of an endpoint hostname
+const endpointResolveTimeout = 5 * time.Second
+
+// Resolver resolves a hostname to its IP addresses
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// ParseEndpoint splits a WireGuard endpoint into its host and port. The host
+// is an IPv4 address, a bracketed IPv6 address such as [2001:db8::1]:51820,
+// or a hostname.
+func ParseEndpoint(endpoint string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		var addrErr *net.AddrError
+		switch {
+		case !strings.Contains(endpoint, ":"):
+			return "", 0, fmt.Errorf("%w: %q must include a port (host:port)", ErrInvalidEndpoint, endpoint)
+		case errors.As(err, &addrErr) && addrErr.Err == "too many colons in address":
+			return "", 0, fmt.Errorf("%w: %q: IPv6 addresses must be bracketed, e.g. [2001:db8::1]:51820", ErrInvalidEndpoint, endpoint)
+		default:
+			return "", 0, fmt.Errorf("%w: %q: %v", ErrInvalidEndpoint, endpoint, err)
+		}
+	}
+
+	if host == "" {
+		return "", 0, fmt.Errorf("%w: %q has no host", ErrInvalidEndpoint, endpoint)
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", 0, fmt.Errorf("%w: %q: port must be between 1 and 65535", ErrInvalidEndpoint, endpoint)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() == nil && !strings.HasPrefix(endpoint, "[") {
+			return "", 0, fmt.Errorf("%w: %q: IPv6 addresses must be bracketed, e.g. [2001:db8::1]:51820", ErrInvalidEndpoint, endpoint)
+		}
+		return host, uint16(port), nil
+	}
+	if strings.HasPrefix(endpoint, "[") {
+		return "", 0, fmt.Errorf("%w: %q: %q is not an IPv6 address", ErrInvalidEndpoint, endpoint, host)
+	}
+	if !validHostname(host) {
+		return "", 0, fmt.Errorf("%w: %q: %q is not a valid hostname", ErrInvalidEndpoint, endpoint, host)
+	}
+
+	return host, uint16(port), nil
+}
+
+// validHostname reports whether host is a valid DNS name made of letters,
+// digits and hyphens
+func validHostname(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || len(host) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+
+	// A name made only of digits and dots is a malformed IPv4 address
+	return strings.Trim(host, "0123456789.") != ""
+}
+
+// resolveEndpointHost checks that the endpoint hostname resolves. IP
+// addresses are not looked up.
+func (g *Generator) resolveEndpointHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	resolver := g.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), endpointResolveTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve server endpoint host %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("server endpoint host %s has no addresses", host)
+	}
+	return nil
+}
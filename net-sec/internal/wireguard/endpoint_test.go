@@ -0,0 +1,104 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		host     string
+		port     uint16
+	}{
+		{"203.0.113.7:51820", "203.0.113.7", 51820},
+		{"[2001:db8::1]:51820", "2001:db8::1", 51820},
+		{"[::ffff:203.0.113.7]:443", "::ffff:203.0.113.7", 443},
+		{"vpn.example.com:51820", "vpn.example.com", 51820},
+		{"wg-1.example.com.:1", "wg-1.example.com.", 1},
+	}
+	for _, tt := range tests {
+		host, port, err := ParseEndpoint(tt.endpoint)
+		if err != nil {
+			t.Errorf("%s: %v", tt.endpoint, err)
+			continue
+		}
+		if host != tt.host || port != tt.port {
+			t.Errorf("%s: expected %s %d, got %s %d", tt.endpoint, tt.host, tt.port, host, port)
+		}
+	}
+}
+
+func TestParseEndpointRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		reason   string
+	}{
+		{"vpn.example.com", "must include a port"},
+		{"2001:db8::1:51820", "must be bracketed"},
+		{"[2001:db8::1]", "missing port"},
+		{":51820", "has no host"},
+		{"vpn.example.com:", "port must be between"},
+		{"vpn.example.com:0", "port must be between"},
+		{"vpn.example.com:65536", "port must be between"},
+		{"vpn.example.com:wg", "port must be between"},
+		{"[vpn.example.com]:51820", "not an IPv6 address"},
+		{"vpn_example.com:51820", "not a valid hostname"},
+		{"-vpn.example.com:51820", "not a valid hostname"},
+		{"vpn..example.com:51820", "not a valid hostname"},
+		{"203.0.113.256:51820", "not a valid hostname"},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseEndpoint(tt.endpoint)
+		if !errors.Is(err, ErrInvalidEndpoint) || !strings.Contains(err.Error(), tt.reason) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.endpoint, tt.reason, err)
+		}
+	}
+}
+
+// stubResolver resolves the hosts in its table
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func TestGenerateConfigResolvesEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	g := &Generator{
+		keysDir:    filepath.Join(dir, "keys"),
+		configsDir: filepath.Join(dir, "configs"),
+		resolver:   stubResolver{"vpn.example.com": {"203.0.113.7"}},
+	}
+
+	for _, endpoint := range []string{"vpn.example.com:51820", "[2001:db8::1]:51820", "203.0.113.9:51820", "unknown.example.com:51820"} {
+		opts := &GeneratorOptions{ServerEndpoint: endpoint, MTU: 1420, GenerateKeys: true}
+		if _, err := g.GenerateConfig(opts); err != nil {
+			t.Errorf("%s: expected endpoint to be accepted without resolution, got %v", endpoint, err)
+		}
+	}
+
+	for _, endpoint := range []string{"vpn.example.com:51820", "[2001:db8::1]:51820", "203.0.113.9:51820"} {
+		opts := &GeneratorOptions{ServerEndpoint: endpoint, MTU: 1420, GenerateKeys: true, ResolveEndpoint: true}
+		config, err := g.GenerateConfig(opts)
+		if err != nil {
+			t.Errorf("%s: %v", endpoint, err)
+			continue
+		}
+		if config.Peer.Endpoint != endpoint {
+			t.Errorf("expected endpoint %s, got %s", endpoint, config.Peer.Endpoint)
+		}
+	}
+
+	opts := &GeneratorOptions{ServerEndpoint: "unknown.example.com:51820", MTU: 1420, GenerateKeys: true, ResolveEndpoint: true}
+	if _, err := g.GenerateConfig(opts); err == nil || !strings.Contains(err.Error(), "failed to resolve server endpoint host unknown.example.com") {
+		t.Errorf("expected a resolution error, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/stealthguard/net-sec/internal/firewall"
 	"golang.org/x/crypto/curve25519"
 )
 
@@ -17,6 +18,7 @@ import (
 type Generator struct {
 	keysDir    string
 	configsDir string
+	resolver   Resolver // Resolves endpoint hostnames; defaults to net.DefaultResolver
 }
 
 // GeneratorOptions contains configuration options for generation
@@ -29,6 +31,8 @@ type GeneratorOptions struct {
 	MTU             int
 	Keepalive       int
 	GenerateKeys    bool
+	ExcludedIPs     []string // CIDRs routed outside the tunnel
+	ResolveEndpoint bool     // Check that the endpoint hostname resolves
 }
 
 // Config represents a WireGuard configuration
@@ -106,9 +110,18 @@ func (g *Generator) GenerateConfig(opts *GeneratorOptions) (*Config, error) {
 		return nil, fmt.Errorf("invalid options: %w", err)
 	}
 
+	allowedIPs, err := ComputeAllowedIPs(opts.ExcludedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	killSwitch, err := killSwitchRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kill-switch rules: %w", err)
+	}
+
 	// Generate keys if needed
 	var keyPair *KeyPair
-	var err error
 
 	if opts.GenerateKeys {
 		keyPair, err = g.GenerateKeyPair()
@@ -135,12 +148,12 @@ func (g *Generator) GenerateConfig(opts *GeneratorOptions) (*Config, error) {
 			Address:    opts.ClientIP,
 			DNS:        opts.DNS,
 			MTU:        opts.MTU,
-			PostUp:     g.generatePostUpCommands(opts),
-			PostDown:   g.generatePostDownCommands(opts),
+			PostUp:     killSwitch.Apply,
+			PostDown:   killSwitch.Remove,
 		},
 		Peer: Peer{
 			PublicKey:           serverPublicKey,
-			AllowedIPs:          []string{"0.0.0.0/0", "::/0"},
+			AllowedIPs:          allowedIPs,
 			Endpoint:            opts.ServerEndpoint,
 			PersistentKeepalive: opts.Keepalive,
 		},
@@ -179,8 +192,14 @@ func (g *Generator) validateOptions(opts *GeneratorOptions) error {
 	}
 
 	// Validate endpoint format
-	if !strings.Contains(opts.ServerEndpoint, ":") {
-		return fmt.Errorf("server endpoint must include port (host:port)")
+	host, _, err := ParseEndpoint(opts.ServerEndpoint)
+	if err != nil {
+		return err
+	}
+	if opts.ResolveEndpoint {
+		if err := g.resolveEndpointHost(host); err != nil {
+			return err
+		}
 	}
 
 	// Validate client IP if provided
@@ -210,30 +229,24 @@ func (g *Generator) validateOptions(opts *GeneratorOptions) error {
 	return nil
 }
 
-// generatePostUpCommands generates PostUp commands for security
-func (g *Generator) generatePostUpCommands(opts *GeneratorOptions) []string {
-	var commands []string
-
-	// Add iptables rules for kill-switch (Linux)
-	commands = append(commands, []string{
-		"iptables -I OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
-		"ip6tables -I OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
-	}...)
-
-	return commands
+// killSwitchSpec confines traffic to the tunnel. wg-quick substitutes %i
+// with the interface, and WireGuard's own packets carry its fwmark.
+var killSwitchSpec = firewall.Spec{
+	Name: "wireguard",
+	KillSwitch: &firewall.KillSwitch{
+		Interface: "%i",
+		FWMark:    "$(wg show %i fwmark)",
+	},
 }
 
-// generatePostDownCommands generates PostDown commands
-func (g *Generator) generatePostDownCommands(opts *GeneratorOptions) []string {
-	var commands []string
-
-	// Remove iptables rules
-	commands = append(commands, []string{
-		"iptables -D OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
-		"ip6tables -D OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
-	}...)
-
-	return commands
+// killSwitchRules returns the Linux kill-switch rules run by PostUp and
+// PostDown
+func killSwitchRules() (*firewall.RuleSet, error) {
+	builder, err := firewall.NewRuleBuilder(firewall.PlatformLinux)
+	if err != nil {
+		return nil, err
+	}
+	return builder.Build(killSwitchSpec)
 }
 
 // String returns the configuration as a WireGuard config file string
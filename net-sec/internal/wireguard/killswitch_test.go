@@ -0,0 +1,36 @@
+package wireguard
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateConfigUsesKillSwitchRules(t *testing.T) {
+	dir := t.TempDir()
+	g := &Generator{keysDir: filepath.Join(dir, "keys"), configsDir: filepath.Join(dir, "configs")}
+
+	config, err := g.GenerateConfig(&GeneratorOptions{
+		ServerEndpoint: "vpn.example.com:51820",
+		MTU:            1420,
+		GenerateKeys:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantUp := []string{
+		"iptables -I OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
+		"ip6tables -I OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
+	}
+	wantDown := []string{
+		"iptables -D OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
+		"ip6tables -D OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT",
+	}
+	if !reflect.DeepEqual(config.Interface.PostUp, wantUp) {
+		t.Errorf("unexpected PostUp %q", config.Interface.PostUp)
+	}
+	if !reflect.DeepEqual(config.Interface.PostDown, wantDown) {
+		t.Errorf("unexpected PostDown %q", config.Interface.PostDown)
+	}
+}
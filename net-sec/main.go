@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/stealthguard/net-sec/cmd"
 	"github.com/stealthguard/net-sec/internal/config"
@@ -26,14 +28,35 @@ func main() {
 
 	// Initialize logger
 	logger.Init(config.Get().LogLevel, config.Get().LogFormat)
+	logger.Debug("Loaded configuration: %s", config.Get())
 
-	// Create root command with context
-	ctx := context.Background()
+	// Reload configuration on SIGHUP
+	go handleReloadSignals()
+
+	// Create root command with a context cancelled on Ctrl-C or SIGTERM, so
+	// commands can tear down their background work cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	rootCmd := cmd.NewRootCommand(version, commit, date)
 
 	// Execute command
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		log.Printf("Command execution failed: %v", err)
 		os.Exit(1)
 	}
 }
+
+// handleReloadSignals reloads the configuration each time SIGHUP is received
+func handleReloadSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		if err := config.Reload(); err != nil {
+			logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+			continue
+		}
+		logger.Info("Configuration reloaded")
+	}
+}